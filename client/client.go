@@ -0,0 +1,296 @@
+// Package client is a small MCP client for tests: it connects to a server
+// over WebSocket, Streamable HTTP, or an in-memory transport.Pipe,
+// initializes the session, lists and calls tools, and collects the
+// notifications the server sends.
+package client
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "net/http"
+        "strconv"
+        "sync"
+        "sync/atomic"
+
+        "github.com/gorilla/websocket"
+        "mcp-server/mcp"
+)
+
+// ProtocolVersion is the protocol version Initialize asks for.
+const ProtocolVersion = "2025-06-18"
+
+// notificationBuffer is how many notifications a client holds for its
+// reader; more are dropped.
+const notificationBuffer = 256
+
+// ErrClosed is returned for calls on a closed client, and for those
+// waiting when the connection closed.
+var ErrClosed = errors.New("client closed")
+
+// Transport carries a client's messages, one JSON-RPC message each. A
+// *transport.PipeClient is one.
+type Transport interface {
+        Send(message []byte) error
+        Receive(ctx context.Context) ([]byte, error)
+        Close() error
+}
+
+// Notification is a notification the server sent.
+type Notification struct {
+        Method string          `json:"method"`
+        Params json.RawMessage `json:"params,omitempty"`
+}
+
+// message is any message from the server: a response, a notification, or
+// a request.
+type message struct {
+        ID     string          `json:"id,omitempty"`
+        Method string          `json:"method,omitempty"`
+        Params json.RawMessage `json:"params,omitempty"`
+        Result json.RawMessage `json:"result,omitempty"`
+        Error  *mcp.Error      `json:"error,omitempty"`
+}
+
+// Client is a connection to an MCP server. Its methods may be called from
+// several goroutines.
+type Client struct {
+        t      Transport
+        nextID atomic.Int64
+
+        mu      sync.Mutex
+        pending map[string]chan message
+        closed  bool
+        // done is closed once the connection has closed.
+        done          chan struct{}
+        notifications chan Notification
+}
+
+// New returns a client speaking over t, for example the client end of a
+// transport.Pipe.
+func New(t Transport) *Client {
+        c := &Client{
+                t:             t,
+                pending:       make(map[string]chan message),
+                done:          make(chan struct{}),
+                notifications: make(chan Notification, notificationBuffer),
+        }
+        go c.read()
+        return c
+}
+
+// Connect dials the server's WebSocket endpoint at url, such as
+// ws://localhost:8080/ws, sending header with the handshake.
+func Connect(ctx context.Context, url string, header http.Header) (*Client, error) {
+        ws, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+        if err != nil {
+                return nil, err
+        }
+        return New(&wsTransport{ws: ws}), nil
+}
+
+// Notifications returns the notifications the server sends, in order. It
+// is closed when the connection closes. Notifications that arrive while
+// notificationBuffer of them are waiting to be read are dropped.
+func (c *Client) Notifications() <-chan Notification {
+        return c.notifications
+}
+
+// Initialize starts the session, presenting the client as info, and
+// tells the server the client is ready.
+func (c *Client) Initialize(ctx context.Context, info mcp.ClientInfo) (*mcp.InitializeResult, error) {
+        var result mcp.InitializeResult
+        params := mcp.InitializeParams{ProtocolVersion: ProtocolVersion, ClientInfo: info}
+        if err := c.Call(ctx, "initialize", params, &result); err != nil {
+                return nil, err
+        }
+        if err := c.Notify("notifications/initialized", nil); err != nil {
+                return nil, err
+        }
+        return &result, nil
+}
+
+// ListTools returns the tools the server offers the client.
+func (c *Client) ListTools(ctx context.Context) ([]mcp.ToolInfo, error) {
+        var result mcp.ListToolsResult
+        if err := c.Call(ctx, "tools/list", nil, &result); err != nil {
+                return nil, err
+        }
+        return result.Tools, nil
+}
+
+// CallTool calls the tool name with args and returns its structured
+// content, which for the server's own tools is their output; it is nil for
+// a tool that returns none. Call tools/call with an mcp.CallToolResult for
+// the whole result.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+        var result struct {
+                StructuredContent json.RawMessage `json:"structuredContent"`
+        }
+        if err := c.Call(ctx, "tools/call", mcp.ToolCallParams{Name: name, Arguments: args}, &result); err != nil {
+                return nil, err
+        }
+        return result.StructuredContent, nil
+}
+
+// Call sends a request and decodes its result into result, unless result
+// is nil. A JSON-RPC error is returned as an *mcp.Error. If ctx ends
+// first, the server is told the request is cancelled.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+        raw, err := encodeParams(params)
+        if err != nil {
+                return err
+        }
+        id := strconv.FormatInt(c.nextID.Add(1), 10)
+        ch := make(chan message, 1)
+        c.mu.Lock()
+        if c.closed {
+                c.mu.Unlock()
+                return ErrClosed
+        }
+        c.pending[id] = ch
+        c.mu.Unlock()
+
+        if err := c.send(message{ID: id, Method: method, Params: raw}); err != nil {
+                c.forget(id)
+                return err
+        }
+        select {
+        case resp, ok := <-ch:
+                if !ok {
+                        return ErrClosed
+                }
+                if resp.Error != nil {
+                        return resp.Error
+                }
+                if result == nil {
+                        return nil
+                }
+                if err := json.Unmarshal(resp.Result, result); err != nil {
+                        return fmt.Errorf("decoding %s result: %w", method, err)
+                }
+                return nil
+        case <-ctx.Done():
+                c.forget(id)
+                c.Notify("notifications/cancelled", mcp.CancelledParams{RequestID: id, Reason: ctx.Err().Error()})
+                return ctx.Err()
+        }
+}
+
+// Notify sends a notification.
+func (c *Client) Notify(method string, params interface{}) error {
+        raw, err := encodeParams(params)
+        if err != nil {
+                return err
+        }
+        return c.send(message{Method: method, Params: raw})
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+        err := c.t.Close()
+        <-c.done
+        return err
+}
+
+// encodeParams returns params as JSON, or nothing for nil params.
+func encodeParams(params interface{}) (json.RawMessage, error) {
+        if params == nil {
+                return nil, nil
+        }
+        data, err := json.Marshal(params)
+        if err != nil {
+                return nil, fmt.Errorf("encoding params: %w", err)
+        }
+        return data, nil
+}
+
+func (c *Client) send(msg message) error {
+        data, err := json.Marshal(struct {
+                JSONRPC string `json:"jsonrpc"`
+                message
+        }{"2.0", msg})
+        if err != nil {
+                return err
+        }
+        return c.t.Send(data)
+}
+
+func (c *Client) forget(id string) {
+        c.mu.Lock()
+        delete(c.pending, id)
+        c.mu.Unlock()
+}
+
+// read delivers the server's messages until the connection closes, then
+// fails the calls still waiting.
+func (c *Client) read() {
+        defer func() {
+                c.mu.Lock()
+                c.closed = true
+                for id, ch := range c.pending {
+                        close(ch)
+                        delete(c.pending, id)
+                }
+                c.mu.Unlock()
+                close(c.notifications)
+                close(c.done)
+        }()
+        for {
+                data, err := c.t.Receive(context.Background())
+                if err != nil {
+                        return
+                }
+                var msg message
+                if err := json.Unmarshal(data, &msg); err != nil {
+                        continue
+                }
+                switch {
+                case msg.Method != "" && msg.ID != "":
+                        // The client offers no capabilities, so it has no
+                        // requests to answer.
+                        c.send(message{ID: msg.ID, Error: &mcp.Error{Code: -32601, Message: "Method not found: " + msg.Method}})
+                case msg.Method != "":
+                        select {
+                        case c.notifications <- Notification{Method: msg.Method, Params: msg.Params}:
+                        default:
+                        }
+                default:
+                        c.mu.Lock()
+                        ch, ok := c.pending[msg.ID]
+                        delete(c.pending, msg.ID)
+                        c.mu.Unlock()
+                        if ok {
+                                ch <- msg
+                        }
+                }
+        }
+}
+
+// wsTransport carries messages as WebSocket text frames.
+type wsTransport struct {
+        ws *websocket.Conn
+        // mu serializes writes, as gorilla/websocket allows one writer at
+        // a time.
+        mu sync.Mutex
+}
+
+func (t *wsTransport) Send(message []byte) error {
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        return t.ws.WriteMessage(websocket.TextMessage, message)
+}
+
+// Receive ignores ctx: the read ends when the socket closes.
+func (t *wsTransport) Receive(ctx context.Context) ([]byte, error) {
+        _, data, err := t.ws.ReadMessage()
+        return data, err
+}
+
+func (t *wsTransport) Close() error {
+        t.mu.Lock()
+        t.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+        t.mu.Unlock()
+        return t.ws.Close()
+}
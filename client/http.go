@@ -0,0 +1,171 @@
+package client
+
+import (
+        "bufio"
+        "bytes"
+        "context"
+        "fmt"
+        "io"
+        "net/http"
+        "strings"
+        "sync"
+)
+
+// sessionHeader carries the Streamable HTTP session ID.
+const sessionHeader = "Mcp-Session-Id"
+
+// ConnectHTTP returns a client of the server's Streamable HTTP endpoint at
+// url, such as http://localhost:8080/mcp, sending header with every
+// request. Nothing is sent until the first call, normally Initialize.
+func ConnectHTTP(url string, header http.Header) *Client {
+        ctx, cancel := context.WithCancel(context.Background())
+        return New(&httpTransport{
+                url:      url,
+                header:   header,
+                ctx:      ctx,
+                cancel:   cancel,
+                incoming: make(chan []byte, notificationBuffer),
+        })
+}
+
+// httpTransport POSTs each message and receives responses in the POST's
+// reply. Once the session has started, it also holds an event stream open
+// for the server's notifications and requests.
+type httpTransport struct {
+        url    string
+        header http.Header
+        // ctx ends the event stream when the transport closes.
+        ctx      context.Context
+        cancel   context.CancelFunc
+        incoming chan []byte
+
+        mu        sync.Mutex
+        sessionID string
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+        req, err := http.NewRequestWithContext(ctx, method, t.url, body)
+        if err != nil {
+                return nil, err
+        }
+        for name, values := range t.header {
+                req.Header[name] = values
+        }
+        t.mu.Lock()
+        if t.sessionID != "" {
+                req.Header.Set(sessionHeader, t.sessionID)
+        }
+        t.mu.Unlock()
+        return req, nil
+}
+
+func (t *httpTransport) Send(message []byte) error {
+        req, err := t.newRequest(t.ctx, http.MethodPost, bytes.NewReader(message))
+        if err != nil {
+                return err
+        }
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set("Accept", "application/json, text/event-stream")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+                return err
+        }
+        defer resp.Body.Close()
+        body, err := io.ReadAll(resp.Body)
+        if err != nil {
+                return err
+        }
+
+        if id := resp.Header.Get(sessionHeader); id != "" {
+                t.mu.Lock()
+                started := t.sessionID == ""
+                t.sessionID = id
+                t.mu.Unlock()
+                if started {
+                        go t.streamEvents()
+                }
+        }
+        if resp.StatusCode == http.StatusAccepted {
+                return nil
+        }
+        // Errors the server reports in JSON-RPC, such as a parse error,
+        // come with the failing status.
+        if resp.Header.Get("Content-Type") != "application/json" {
+                return fmt.Errorf("POST %s: %s: %s", t.url, resp.Status, strings.TrimSpace(string(body)))
+        }
+        return t.queue(body)
+}
+
+func (t *httpTransport) queue(message []byte) error {
+        select {
+        case t.incoming <- message:
+                return nil
+        case <-t.ctx.Done():
+                return ErrClosed
+        }
+}
+
+// streamEvents queues the messages sent on the session's event stream
+// until the transport closes.
+func (t *httpTransport) streamEvents() {
+        req, err := t.newRequest(t.ctx, http.MethodGet, nil)
+        if err != nil {
+                return
+        }
+        req.Header.Set("Accept", "text/event-stream")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+                return
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+                return
+        }
+        var data bytes.Buffer
+        scanner := bufio.NewScanner(resp.Body)
+        scanner.Buffer(nil, 1<<24)
+        for scanner.Scan() {
+                line := scanner.Text()
+                switch {
+                case strings.HasPrefix(line, "data:"):
+                        data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+                case line == "" && data.Len() > 0:
+                        if t.queue(bytes.Clone(data.Bytes())) != nil {
+                                return
+                        }
+                        data.Reset()
+                }
+        }
+}
+
+func (t *httpTransport) Receive(ctx context.Context) ([]byte, error) {
+        select {
+        case message := <-t.incoming:
+                return message, nil
+        case <-t.ctx.Done():
+                return nil, io.EOF
+        case <-ctx.Done():
+                return nil, ctx.Err()
+        }
+}
+
+// Close ends the session, if it started, and the event stream.
+func (t *httpTransport) Close() error {
+        defer t.cancel()
+        t.mu.Lock()
+        started := t.sessionID != ""
+        t.mu.Unlock()
+        if !started {
+                return nil
+        }
+        req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+        if err != nil {
+                return err
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+                return err
+        }
+        resp.Body.Close()
+        return nil
+}
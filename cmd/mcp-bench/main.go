@@ -0,0 +1,131 @@
+// Command mcp-bench measures an MCP server's capacity: it opens many
+// connections at once, makes tool calls on each, and reports the latency
+// percentiles and error rates it saw.
+package main
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "flag"
+        "fmt"
+        "net/http"
+        "os"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/client"
+        "mcp-server/mcp"
+)
+
+func main() {
+        url := flag.String("url", "ws://localhost:8080/ws", "server endpoint: ws:// or wss:// for WebSocket, http:// or https:// for Streamable HTTP")
+        connections := flag.Int("connections", 10, "connections to open at once")
+        calls := flag.Int("calls", 100, "tool calls to make on each connection, one after another")
+        tool := flag.String("tool", "search_tickets", "tool to call")
+        argsJSON := flag.String("args", "{}", "arguments of each call, as a JSON object")
+        payloadArg := flag.String("payload-arg", "query", "argument that carries the -payload-size filler")
+        payloadSize := flag.Int("payload-size", 0, "bytes of filler to send in -payload-arg with each call (0 sends none)")
+        apiKey := flag.String("api-key", os.Getenv("MCP_API_KEY"), "API key to present, if the server requires one (default: MCP_API_KEY)")
+        timeout := flag.Duration("timeout", 30*time.Second, "how long each call may take before it counts as an error")
+        flag.Parse()
+
+        if *connections < 1 || *calls < 1 {
+                fatalf("-connections and -calls must be at least 1")
+        }
+        if *payloadSize < 0 {
+                fatalf("-payload-size must not be negative")
+        }
+        var args map[string]interface{}
+        if err := json.Unmarshal([]byte(*argsJSON), &args); err != nil || args == nil {
+                fatalf("-args must be a JSON object")
+        }
+        if *payloadSize > 0 {
+                args[*payloadArg] = strings.Repeat("x", *payloadSize)
+        }
+        header := http.Header{}
+        if *apiKey != "" {
+                header.Set("Authorization", "Bearer "+*apiKey)
+        }
+        b := &bench{url: *url, header: header, calls: *calls, tool: *tool, args: args, timeout: *timeout}
+
+        fmt.Printf("Calling %s %d times on each of %d connections to %s\n", *tool, *calls, *connections, *url)
+        start := time.Now()
+        var wg sync.WaitGroup
+        results := make([]*stats, *connections)
+        for i := range results {
+                results[i] = newStats()
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        b.run(results[i])
+                }()
+        }
+        wg.Wait()
+
+        total := newStats()
+        for _, s := range results {
+                total.merge(s)
+        }
+        total.report(os.Stdout, time.Since(start))
+        if total.failed > 0 || total.connectErrors > 0 {
+                os.Exit(1)
+        }
+}
+
+// bench is what each connection does.
+type bench struct {
+        url     string
+        header  http.Header
+        calls   int
+        tool    string
+        args    map[string]interface{}
+        timeout time.Duration
+}
+
+// run connects, initializes, and makes b.calls calls, recording them in s.
+func (b *bench) run(s *stats) {
+        c, err := b.connect()
+        if err != nil {
+                s.connectError(err)
+                return
+        }
+        defer c.Close()
+        ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+        _, err = c.Initialize(ctx, mcp.ClientInfo{Name: "mcp-bench", Version: "1.0"})
+        cancel()
+        if err != nil {
+                s.connectError(err)
+                return
+        }
+        for i := 0; i < b.calls; i++ {
+                ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+                start := time.Now()
+                _, err := c.CallTool(ctx, b.tool, b.args)
+                s.call(time.Since(start), err)
+                cancel()
+                if errors.Is(err, client.ErrClosed) {
+                        // The rest would fail the same way.
+                        s.closed(b.calls - i - 1)
+                        return
+                }
+        }
+}
+
+func (b *bench) connect() (*client.Client, error) {
+        switch {
+        case strings.HasPrefix(b.url, "ws://"), strings.HasPrefix(b.url, "wss://"):
+                ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+                defer cancel()
+                return client.Connect(ctx, b.url, b.header)
+        case strings.HasPrefix(b.url, "http://"), strings.HasPrefix(b.url, "https://"):
+                return client.ConnectHTTP(b.url, b.header), nil
+        }
+        return nil, fmt.Errorf("-url must start with ws://, wss://, http://, or https://")
+}
+
+func fatalf(format string, args ...interface{}) {
+        fmt.Fprintf(os.Stderr, format+"\n", args...)
+        os.Exit(2)
+}
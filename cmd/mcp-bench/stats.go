@@ -0,0 +1,123 @@
+package main
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "io"
+        "math"
+        "slices"
+        "sort"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// stats are the outcomes of the calls made on a connection, or on all of
+// them once merged.
+type stats struct {
+        latencies []time.Duration
+        failed    int
+        // errors counts failed calls by JSON-RPC error code, or by a
+        // description for failures without one.
+        errors        map[string]int
+        connectErrors int
+        // connectFailures counts failed connections by error message.
+        connectFailures map[string]int
+}
+
+func newStats() *stats {
+        return &stats{errors: map[string]int{}, connectFailures: map[string]int{}}
+}
+
+// call records a call that took d and failed with err, if not nil.
+func (s *stats) call(d time.Duration, err error) {
+        if err == nil {
+                s.latencies = append(s.latencies, d)
+                return
+        }
+        s.failed++
+        var mcpErr *mcp.Error
+        switch {
+        case errors.As(err, &mcpErr):
+                s.errors[fmt.Sprintf("%d %s", mcpErr.Code, mcpErr.Message)]++
+        case errors.Is(err, context.DeadlineExceeded):
+                s.errors["timeout"]++
+        default:
+                s.errors[err.Error()]++
+        }
+}
+
+// closed records n calls not made because the connection closed.
+func (s *stats) closed(n int) {
+        s.failed += n
+        s.errors["connection closed"] += n
+}
+
+// connectError records a connection that couldn't be opened or
+// initialized.
+func (s *stats) connectError(err error) {
+        s.connectErrors++
+        s.connectFailures[err.Error()]++
+}
+
+func (s *stats) merge(other *stats) {
+        s.latencies = append(s.latencies, other.latencies...)
+        s.failed += other.failed
+        for k, n := range other.errors {
+                s.errors[k] += n
+        }
+        s.connectErrors += other.connectErrors
+        for k, n := range other.connectFailures {
+                s.connectFailures[k] += n
+        }
+}
+
+// percentile returns the latency below which p percent of the successful
+// calls fell, by the nearest-rank method; latencies must be sorted.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+        if len(latencies) == 0 {
+                return 0
+        }
+        rank := int(math.Ceil(p / 100 * float64(len(latencies))))
+        return latencies[max(rank, 1)-1]
+}
+
+// report writes the results of a run that took elapsed.
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+        slices.Sort(s.latencies)
+        succeeded := len(s.latencies)
+        total := succeeded + s.failed
+        fmt.Fprintf(w, "\nConnections failed: %d\n", s.connectErrors)
+        printCounts(w, s.connectFailures)
+        fmt.Fprintf(w, "Calls: %d in %s (%.1f/s)\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+        if total > 0 {
+                fmt.Fprintf(w, "Errors: %d (%.2f%%)\n", s.failed, 100*float64(s.failed)/float64(total))
+        }
+        printCounts(w, s.errors)
+        if succeeded == 0 {
+                return
+        }
+        fmt.Fprintf(w, "Latency of successful calls:\n")
+        for _, p := range []float64{50, 90, 95, 99, 99.9} {
+                fmt.Fprintf(w, "  p%-5g %s\n", p, percentile(s.latencies, p).Round(time.Microsecond))
+        }
+        fmt.Fprintf(w, "  max    %s\n", s.latencies[succeeded-1].Round(time.Microsecond))
+}
+
+// printCounts writes counts, most frequent first.
+func printCounts(w io.Writer, counts map[string]int) {
+        keys := make([]string, 0, len(counts))
+        for k := range counts {
+                keys = append(keys, k)
+        }
+        sort.Slice(keys, func(i, j int) bool {
+                if counts[keys[i]] != counts[keys[j]] {
+                        return counts[keys[i]] > counts[keys[j]]
+                }
+                return keys[i] < keys[j]
+        })
+        for _, k := range keys {
+                fmt.Fprintf(w, "  %6d  %s\n", counts[k], k)
+        }
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+        "flag"
+        "fmt"
+        "log/slog"
+        "os"
+        "path/filepath"
+        "reflect"
+        "sort"
+        "strings"
+        "time"
+
+        "github.com/BurntSushi/toml"
+        "gopkg.in/yaml.v3"
+        "mcp-server/mcp"
+        "mcp-server/tickets"
+        "mcp-server/transport"
+)
+
+// Every setting is a flag. Each can also be given in a YAML, JSON, or TOML
+// config file (-config), keyed by flag name, or in an environment variable named
+// after the flag (MCP_SERVER_LOG_LEVEL for -log-level), or come from the
+// -profile in force. The command line overrides the environment, which
+// overrides the file, which overrides the profile.
+
+// envPrefix starts the environment variable for each flag.
+const envPrefix = "MCP_SERVER_"
+
+// configFlag names the flag that points at the config file, which is read
+// before the file is, so it can't itself be set there.
+const configFlag = "config"
+
+func envName(flagName string) string {
+        return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// loadConfig sets every flag in fs that wasn't given on the command line
+// from the environment or, failing that, from the config file at path, if
+// any, and then from the selected profile. It returns a description of
+// each problem found, so they can all be fixed at once.
+func loadConfig(fs *flag.FlagSet, path string) []string {
+        var problems []string
+        file := map[string]interface{}{}
+        fileProfiles := map[string]map[string]interface{}{}
+        if path != "" {
+                data, err := os.ReadFile(path)
+                if err != nil {
+                        return []string{err.Error()}
+                }
+                raw, err := parseConfigFile(path, data)
+                if err != nil {
+                        return []string{fmt.Sprintf("parsing %s: %v", path, err)}
+                }
+                for key, value := range raw {
+                        if key == profilesKey {
+                                problems = append(problems, readProfiles(fs, path, value, fileProfiles)...)
+                                continue
+                        }
+                        name := strings.ReplaceAll(key, "_", "-")
+                        if fs.Lookup(name) == nil || name == configFlag {
+                                problems = append(problems, fmt.Sprintf("%s: unknown setting %q%s", path, key, suggestFlag(fs, name)))
+                                continue
+                        }
+                        file[name] = value
+                }
+        }
+
+        explicit := map[string]bool{}
+        fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+        set := func(f *flag.Flag, settings map[string]interface{}, source func(name string) string) {
+                if explicit[f.Name] || f.Name == configFlag {
+                        return
+                }
+                if value, ok := os.LookupEnv(envName(f.Name)); ok {
+                        if err := fs.Set(f.Name, value); err != nil {
+                                problems = append(problems, fmt.Sprintf("%s: invalid value %q: %v", envName(f.Name), value, err))
+                        }
+                        return
+                }
+                value, ok := settings[f.Name]
+                if !ok {
+                        return
+                }
+                values, err := configValues(f, value)
+                if err == nil {
+                        for _, v := range values {
+                                if err = fs.Set(f.Name, v); err != nil {
+                                        err = fmt.Errorf("invalid value %q: %w", v, err)
+                                        break
+                                }
+                        }
+                }
+                if err != nil {
+                        problems = append(problems, fmt.Sprintf("%s: %s: %v", source(f.Name), f.Name, err))
+                }
+        }
+
+        // The profile is chosen first, since it decides what the other
+        // flags default to.
+        fileSource := func(string) string { return path }
+        profile := ""
+        if f := fs.Lookup(profileFlag); f != nil {
+                set(f, file, fileSource)
+                profile = f.Value.String()
+        }
+        settings, sources := map[string]interface{}{}, map[string]string{}
+        if profile != "" {
+                builtin, ok := builtinProfiles[profile]
+                if _, inFile := fileProfiles[profile]; !ok && !inFile {
+                        problems = append(problems, fmt.Sprintf("unknown profile %q (built in: %s)", profile, strings.Join(profileNames(), ", ")))
+                }
+                for name, value := range builtin {
+                        settings[name], sources[name] = value, "profile "+profile
+                }
+        }
+        for name, value := range file {
+                settings[name], sources[name] = value, path
+        }
+        for name, value := range fileProfiles[profile] {
+                settings[name], sources[name] = value, fmt.Sprintf("%s: profiles: %s", path, profile)
+        }
+        fs.VisitAll(func(f *flag.Flag) {
+                if f.Name != profileFlag {
+                        set(f, settings, func(name string) string { return sources[name] })
+                }
+        })
+        sort.Strings(problems)
+        return problems
+}
+
+// parseConfigFile parses a config file as TOML if its name ends in .toml,
+// and otherwise as YAML, which JSON is a subset of.
+func parseConfigFile(path string, data []byte) (map[string]interface{}, error) {
+        var raw map[string]interface{}
+        if strings.EqualFold(filepath.Ext(path), ".toml") {
+                _, err := toml.Decode(string(data), &raw)
+                return raw, err
+        }
+        err := yaml.Unmarshal(data, &raw)
+        return raw, err
+}
+
+// configValues converts a config file value into the strings to set f to.
+// A list sets a repeatable flag once per item and any other flag to the
+// items joined by commas.
+func configValues(f *flag.Flag, value interface{}) ([]string, error) {
+        switch value := value.(type) {
+        case nil:
+                return []string{""}, nil
+        case map[string]interface{}:
+                return nil, fmt.Errorf("expected a value or a list, not a map")
+        case []interface{}:
+                items := make([]string, len(value))
+                for i, item := range value {
+                        switch item.(type) {
+                        case map[string]interface{}, []interface{}:
+                                return nil, fmt.Errorf("list items must be plain values")
+                        }
+                        items[i] = fmt.Sprint(item)
+                }
+                if repeatable(f) {
+                        return items, nil
+                }
+                return []string{strings.Join(items, ",")}, nil
+        default:
+                return []string{fmt.Sprint(value)}, nil
+        }
+}
+
+// repeatable reports whether each use of f adds a value, as for -log-sink,
+// rather than replacing the last. Such flags hold slices or maps.
+func repeatable(f *flag.Flag) bool {
+        kind := reflect.Indirect(reflect.ValueOf(f.Value)).Kind()
+        return kind == reflect.Slice || kind == reflect.Map
+}
+
+// suggestFlag returns a hint naming the flag closest to the unknown name,
+// or nothing if none is close.
+func suggestFlag(fs *flag.FlagSet, name string) string {
+        best, bestDistance := "", 4
+        fs.VisitAll(func(f *flag.Flag) {
+                if d := editDistance(name, f.Name); d < bestDistance && f.Name != configFlag {
+                        best, bestDistance = f.Name, d
+                }
+        })
+        if best == "" {
+                return ""
+        }
+        return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+        prev := make([]int, len(b)+1)
+        for j := range prev {
+                prev[j] = j
+        }
+        for i := 1; i <= len(a); i++ {
+                cur := make([]int, len(b)+1)
+                cur[0] = i
+                for j := 1; j <= len(b); j++ {
+                        cost := 1
+                        if a[i-1] == b[j-1] {
+                                cost = 0
+                        }
+                        cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+                }
+                prev = cur
+        }
+        return prev[len(b)]
+}
+
+// validateSettings checks timeouts and the settings held in package
+// variables for values that parse but make no sense.
+func validateSettings(timeouts transport.Timeouts) []string {
+        var problems []string
+        check := func(ok bool, format string, args ...interface{}) {
+                if !ok {
+                        problems = append(problems, fmt.Sprintf(format, args...))
+                }
+        }
+        check(mcp.RequestWorkers >= 1, "request-workers must be at least 1, not %d", mcp.RequestWorkers)
+        check(mcp.MaxConnections >= 0, "max-connections must not be negative")
+        check(mcp.MaxInFlight >= 1, "max-in-flight must be at least 1, not %d", mcp.MaxInFlight)
+        check(mcp.ToolWorkers >= 0, "tool-workers must not be negative")
+        check(mcp.SessionToolWorkers >= 0, "session-tool-workers must not be negative")
+        check(mcp.MaxMessageSize > 0, "max-message-size must be positive")
+        check(mcp.MaxJSONDepth > 0, "max-json-depth must be positive")
+        for _, d := range []struct {
+                name  string
+                value time.Duration
+        }{
+                {"ping-interval", timeouts.Ping},
+                {"pong-timeout", timeouts.Pong},
+                {"read-timeout", timeouts.Read},
+                {"write-timeout", timeouts.Write},
+                {"handshake-timeout", timeouts.Handshake},
+                {"body-timeout", timeouts.Body},
+                {"idle-timeout", timeouts.Idle},
+                {"request-timeout", mcp.RequestTimeout},
+        } {
+                check(d.value >= 0, "%s must not be negative, not %s", d.name, d.value)
+        }
+        check(transport.SessionRetention > 0, "session-retention must be positive")
+        check(transport.MaxSessionsPerClient >= 0, "max-sessions-per-client must not be negative")
+        check(tickets.RetainedEvents >= 0, "retained-events must not be negative")
+        for _, p := range []struct{ name, value string }{{"ws-path", transport.WSPath}, {"http-path", transport.HTTPPath}} {
+                check(strings.HasPrefix(p.value, "/") && !strings.HasPrefix(p.value, "/.well-known/"),
+                        "%s must be a path starting with /, outside /.well-known/, not %q", p.name, p.value)
+        }
+        check(transport.WSPath != transport.HTTPPath, "ws-path and http-path must differ")
+        check(timeouts.Read == 0 || timeouts.Ping == 0 || timeouts.Read > timeouts.Ping,
+                "read-timeout (%s) must be longer than ping-interval (%s), or quiet clients are dropped between pings", timeouts.Read, timeouts.Ping)
+        return problems
+}
+
+// exitInvalidConfig reports configuration problems and exits with the
+// status the flag package uses for bad flags.
+func exitInvalidConfig(problems []string) {
+        fmt.Fprintln(os.Stderr, "Invalid configuration:")
+        for _, p := range problems {
+                fmt.Fprintln(os.Stderr, "  "+p)
+        }
+        os.Exit(2)
+}
+
+// splitList splits a comma-separated setting, trimming spaces and dropping
+// empty items.
+func splitList(value string) []string {
+        var list []string
+        for _, item := range strings.Split(value, ",") {
+                if item = strings.TrimSpace(item); item != "" {
+                        list = append(list, item)
+                }
+        }
+        return list
+}
+
+// fatalf logs an error that prevents the server from running and exits.
+func fatalf(format string, args ...interface{}) {
+        slog.Error(fmt.Sprintf(format, args...))
+        os.Exit(1)
+}
@@ -0,0 +1,437 @@
+// Command mcp-server runs the ticket tracker MCP server, configured by
+// flags, a config file, and the environment.
+package main
+
+import (
+        "crypto/tls"
+        "flag"
+        "fmt"
+        "io"
+        "log/slog"
+        "net"
+        "net/http"
+        "os"
+        "os/signal"
+        "strconv"
+        "strings"
+        "syscall"
+        "time"
+
+        "mcp-server/mcp"
+        "mcp-server/server"
+        "mcp-server/tickets"
+        "mcp-server/transport"
+)
+
+// closeOnSignal closes c and exits when the process is interrupted, so
+// stores can flush pending writes and release connections.
+func closeOnSignal(c io.Closer) {
+        signals := make(chan os.Signal, 1)
+        signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+        go func() {
+                <-signals
+                for _, client := range mcp.ConnectedClients() {
+                        client.Shutdown()
+                }
+                if err := c.Close(); err != nil {
+                        slog.Error("Closing ticket store", "error", err)
+                }
+                mcp.Leader.Resign()
+                mcp.StopPlugins()
+                mcp.ShutdownTracing()
+                os.Exit(0)
+        }()
+}
+
+func main() {
+        promptsDir := flag.String("prompts-dir", "", "directory of prompt templates (.md, .yaml) loaded at startup and reloaded on change")
+        transitions := flag.String("status-transitions", tickets.DefaultStatusTransitions, "comma-separated from>to ticket status transitions")
+        reopenTransitions := flag.String("reopen-transitions", tickets.DefaultReopenTransitions, "comma-separated from>to transitions that require reopen=true")
+        storeDSN := flag.String("store", "memory", "ticket store DSN: memory, file:<path>, bolt:<path>, postgres://..., redis://..., mongodb://..., jira:<url>, github:<url>, gitlab:<url>, linear:<url>, trello:<url>, or rest:<mapping file>; see README for options")
+        eventLogPath := flag.String("event-log", "", "with the memory store, append ticket change events to this JSON Lines file and rebuild tickets from it on start")
+        flag.IntVar(&tickets.RetainedEvents, "retained-events", tickets.RetainedEvents, "keep this many of the latest ticket change events in memory for history and undo (0 for all)")
+        snapshotPath := flag.String("snapshot", "", "with the memory store, restore tickets from this JSON/YAML file on start and write them back on snapshot_now and shutdown")
+        slackWebhook := flag.String("slack-webhook", "", "post ticket creations and status changes to this Slack incoming webhook URL")
+        slackChannel := flag.String("slack-channel", "", "post ticket notifications to this Slack channel using the SLACK_BOT_TOKEN bot token")
+        slackCreated := flag.String("slack-created-template", tickets.DefaultSlackCreatedTemplate, "Slack message for created tickets; see README for placeholders")
+        slackStatus := flag.String("slack-status-template", tickets.DefaultSlackStatusTemplate, "Slack message for ticket status changes; see README for placeholders")
+        smtpAddr := flag.String("smtp-addr", "", "SMTP server (host:port) for assignment and overdue emails; credentials come from SMTP_USERNAME and SMTP_PASSWORD")
+        smtpFrom := flag.String("smtp-from", "", "sender address for ticket emails")
+        emailDomain := flag.String("email-domain", "", "domain appended to assignees that aren't email addresses")
+        emailBatchWindow := flag.Duration("email-batch-window", time.Minute, "collect a person's assignments for this long and send them as one email")
+        overdueReminders := flag.Duration("overdue-reminder-interval", 24*time.Hour, "how often to email assignees their overdue tickets (0 disables)")
+        openAPISpec := flag.String("openapi", "", "OpenAPI 3 spec (file or URL) whose operations are registered as tools that call the API")
+        openAPIBaseURL := flag.String("openapi-base-url", "", "base URL for -openapi calls (default: the spec's first server)")
+        toolManifestPath := flag.String("tool-manifest", "", "YAML/JSON file defining tools backed by a command or an HTTP call; see README")
+        var pluginCommands mcp.PluginsFlag
+        flag.Var(&pluginCommands, "plugin", "command, with space-separated arguments, of a plugin serving tools over stdin and stdout (repeatable; see README)")
+        openAPIPrefix := flag.String("openapi-prefix", "", "prefix for the names of tools generated from -openapi")
+        openAPIHeaders := mcp.HeaderFlag{}
+        flag.Var(openAPIHeaders, "openapi-header", "header sent with -openapi calls, as 'Name: value' (repeatable; ${VAR} reads the environment)")
+        host := flag.String("host", "localhost", "interface to listen on; use 0.0.0.0 to accept connections from other machines")
+        port := flag.Int("port", 8080, "port to listen on")
+        flag.StringVar(&transport.WSPath, "ws-path", transport.WSPath, "path of the WebSocket endpoint")
+        flag.StringVar(&transport.HTTPPath, "http-path", transport.HTTPPath, "path of the Streamable HTTP endpoint")
+        allowedOrigins := flag.String("allowed-origins", "", "comma-separated browser origins allowed to connect, or * for any (default: the server's own origin and localhost)")
+        apiKeysFile := flag.String("api-keys", "", "YAML/JSON file mapping identities to API keys that clients must present (also MCP_API_KEYS, MCP_API_KEY)")
+        apiKeyQuery := flag.Bool("api-key-query", false, "also accept API keys in the api_key query parameter, for browser WebSocket clients; keys then appear in URLs and access logs")
+        oauthIssuer := flag.String("oauth-issuer", "", "OAuth 2.1 authorization server whose access tokens clients may present")
+        oauthResource := flag.String("oauth-resource", "", "canonical URI of this server that access tokens must be issued for, e.g. https://mcp.example.com/ws; required with -oauth-issuer")
+        oauthScopes := flag.String("oauth-scopes", "", "comma-separated scopes an access token (introspected or JWT) must grant")
+        oauthIntrospection := flag.String("oauth-introspection-url", "", "token introspection endpoint (default: discovered from the issuer); credentials come from OAUTH_CLIENT_ID and OAUTH_CLIENT_SECRET")
+        jwtIssuer := flag.String("jwt-issuer", "", "accept JWT access tokens from this issuer, fetching its signing keys from the jwks_uri in its metadata")
+        jwtAudience := flag.String("jwt-audience", "", "audience JWT access tokens must be issued for (default: -oauth-resource); one of them is required with -jwt-issuer or -jwks-url")
+        jwksURL := flag.String("jwks-url", "", "JWKS endpoint with the keys JWT access tokens are signed with (default: discovered from -jwt-issuer)")
+        tlsCert := flag.String("tls-cert", "", "serve wss:// and https:// with this PEM certificate (chain), reloaded when it changes")
+        tlsKey := flag.String("tls-key", "", "PEM private key for -tls-cert")
+        acmeDomains := flag.String("acme-domains", "", "comma-separated domains to obtain certificates for from Let's Encrypt, instead of -tls-cert")
+        acmeCache := flag.String("acme-cache", "acme-cache", "directory where -acme-domains certificates and the account key are kept")
+        acmeEmail := flag.String("acme-email", "", "contact address for the Let's Encrypt account, for expiry and policy notices")
+        acmeHTTPAddr := flag.String("acme-http-addr", "", "answer ACME HTTP-01 challenges on this address, e.g. :80 (default: TLS-ALPN-01 challenges on -port, which must then be 443)")
+        tlsClientCA := flag.String("tls-client-ca", "", "require client certificates signed by a CA in this PEM bundle (mutual TLS)")
+        clientIdentities := flag.String("client-cert-identities", "", "YAML/JSON file mapping client certificate subjects to identities; unmapped certificates are refused")
+        clientOverridesPath := flag.String("client-overrides", "", "YAML/JSON map of clientInfo name to tool visibility and rate limit overrides; see README")
+        rbacPath := flag.String("rbac", "", "YAML/JSON policy granting identities roles and roles the scopes tools require; see README")
+        flag.Var(&mcp.ConnRequestLimit, "rate-limit", "requests each connection may make, as RATE[/s|/m|/h][:BURST] (e.g. 20/s:40; default unlimited)")
+        flag.Var(&mcp.ConnToolLimit, "tool-rate-limit", "tool calls each connection may make, as RATE[/s|/m|/h][:BURST]")
+        flag.Var(&mcp.IdentityRequestLimit, "identity-rate-limit", "requests each authenticated identity may make across its connections")
+        flag.Var(&mcp.IdentityToolLimit, "identity-tool-rate-limit", "tool calls each authenticated identity may make across its connections")
+        flag.Int64Var(&mcp.MaxMessageSize, "max-message-size", mcp.MaxMessageSize, "largest WebSocket message accepted, in bytes; larger ones close the connection with status 1009")
+        flag.IntVar(&mcp.MaxJSONDepth, "max-json-depth", mcp.MaxJSONDepth, "deepest nesting of objects and arrays accepted in a message")
+        timeouts := transport.DefaultTimeouts
+        flag.DurationVar(&timeouts.Ping, "ping-interval", timeouts.Ping, "how often to ping each client to detect dead connections (0 disables)")
+        flag.DurationVar(&timeouts.Pong, "pong-timeout", timeouts.Pong, "how long after a ping is due a client may go without answering before it is disconnected")
+        flag.DurationVar(&timeouts.Read, "read-timeout", timeouts.Read, "disconnect clients that send nothing, not even a pong, for this long (default: -ping-interval plus -pong-timeout)")
+        flag.DurationVar(&timeouts.Write, "write-timeout", timeouts.Write, "disconnect clients that take longer than this to accept a message (0 disables)")
+        flag.DurationVar(&timeouts.Handshake, "handshake-timeout", timeouts.Handshake, "how long clients have to send request headers and complete the WebSocket upgrade (0 disables)")
+        flag.DurationVar(&timeouts.Body, "body-timeout", timeouts.Body, "how long clients have to send the body of a Streamable HTTP POST (0 disables)")
+        flag.DurationVar(&timeouts.Idle, "idle-timeout", timeouts.Idle, "close connections that send nothing for this long while no request is running (0 disables)")
+        flag.IntVar(&mcp.MaxConnections, "max-connections", mcp.MaxConnections, "most clients connected at once; more are refused with 503 (0 for no limit)")
+        flag.IntVar(&transport.MaxSessionsPerClient, "max-sessions-per-client", transport.MaxSessionsPerClient, "most Streamable HTTP sessions one client (identity, or IP address when unauthenticated) may hold; more are refused with 429 (0 for no limit)")
+        flag.DurationVar(&transport.SessionRetention, "session-retention", transport.SessionRetention, "keep Streamable HTTP sessions, and messages for clients to resume, this long after the client was last seen")
+        flag.DurationVar(&mcp.RequestTimeout, "request-timeout", mcp.RequestTimeout, "cancel the context of requests that run longer than this; they fail with a timeout error (0 for no limit)")
+        flag.IntVar(&mcp.MaxInFlight, "max-in-flight", mcp.MaxInFlight, "most requests each connection may have queued or running; more fail with a server busy error")
+        flag.IntVar(&mcp.RequestWorkers, "request-workers", mcp.RequestWorkers, "requests each connection handles concurrently; more are queued")
+        flag.IntVar(&mcp.ToolWorkers, "tool-workers", mcp.ToolWorkers, "tool calls the server runs concurrently; more wait their turn (0 for no limit)")
+        flag.IntVar(&mcp.SessionToolWorkers, "session-tool-workers", mcp.SessionToolWorkers, "tool calls each connection runs concurrently; more wait their turn (0 for no limit)")
+        secretsSpec := flag.String("secrets", "env", "where credentials are read from, in order: env, file:<dir>, vault:<KV path>; see README")
+        secretsRefresh := flag.Duration("secrets-refresh", 5*time.Minute, "how long secrets read from vault are cached before being fetched again")
+        otlpEndpoint := flag.String("otlp-endpoint", "", "export traces over OTLP/HTTP to this collector, e.g. http://localhost:4318 (default: OTEL_EXPORTER_OTLP_ENDPOINT, if set)")
+        logFormat := flag.String("log-format", "text", "log record format: text (key=value pairs) or json; the default for -log-sink")
+        adminAddr := flag.String("admin-addr", "", "serve pprof profiles on this address, e.g. localhost:6060 (unauthenticated; keep it private)")
+        logLevelName := flag.String("log-level", "info", "least severe log records written: debug, info, notice, warning, error, critical, alert, or emergency; the default for -log-sink")
+        var logSinks mcp.LogSinksFlag
+        flag.Var(&logSinks, "log-sink", "where logs go: stderr (default), stdout, file:<path>, or syslog[:udp://host:port], with ?level=...&format=... and, for files, max-size, max-age, and max-backups (repeatable; see README)")
+        logPayloads := flag.Bool("log-payloads", false, "log every message exchanged with clients, after redacting -redact-fields and -redact-values")
+        redactFields := flag.String("redact-fields", mcp.DefaultRedactFields, "comma-separated JSON keys whose values -log-payloads hides, at any depth")
+        var redactValues mcp.PatternsFlag
+        flag.Var(&redactValues, "redact-values", "regular expression whose matches -log-payloads hides in any string (repeatable; default: email addresses and JWTs)")
+        recordDir := flag.String("record-dir", "", "record every message of each session, unredacted, to <session ID>.jsonl in this directory, for -replay")
+        replayPath := flag.String("replay", "", "instead of serving, replay the client's side of a session recorded with -record-dir, print the server's messages, and report responses that differ")
+        disabledToolNames := flag.String("disable-tools", "", "comma-separated tools to turn off; they are hidden from every client and can't be called")
+        disabledCapabilityNames := flag.String("disable-capabilities", "", "comma-separated capability groups to turn off: tools, resources, prompts, logging, completions, or mutations (tools that change anything)")
+        leaderElection := flag.String("leader-election", "", "with several replicas, run scheduled jobs such as overdue reminders on one only, chosen through a lock in Redis (redis://...) or a Postgres advisory lock (postgres://...)")
+        sessionStoreDSN := flag.String("session-store", "", "keep Streamable HTTP sessions, their event streams, and resource subscriptions in Redis (redis://...) so that any replica can serve them")
+        profile := flag.String(profileFlag, "", "bundle of settings to start from: dev (debug logging with payloads, in-memory store), staging (JSON logs, -require-auth), or prod (JSON logs, -require-auth, and -store must be given), or one defined under profiles in the config file; the config file, environment, and command line override it")
+        requireAuth := flag.Bool("require-auth", false, "refuse to start unless clients must authenticate with API keys, OAuth, JWTs, or client certificates")
+        configPath := flag.String(configFlag, "", "YAML/JSON file of settings keyed by flag name; the environment (MCP_SERVER_<FLAG>) and command line override it. Some settings are reloaded when it changes or on SIGHUP; see README")
+        flag.Parse()
+
+        if *configPath == "" {
+                *configPath = os.Getenv(envName(configFlag))
+        }
+        commandLine := map[string]bool{}
+        flag.Visit(func(f *flag.Flag) { commandLine[f.Name] = true })
+        problems := loadConfig(flag.CommandLine, *configPath)
+        problems = append(problems, checkProfileRequires(flag.CommandLine, *profile)...)
+        problems = append(problems, validateSettings(timeouts)...)
+        if err := mcp.DisableCapabilities(splitList(*disabledCapabilityNames)); err != nil {
+                problems = append(problems, "disable-capabilities: "+err.Error())
+        }
+        if *port < 1 || *port > 65535 {
+                problems = append(problems, fmt.Sprintf("port must be between 1 and 65535, not %d", *port))
+        }
+        if (*tlsCert == "") != (*tlsKey == "") {
+                problems = append(problems, "tls-cert and tls-key must be set together")
+        }
+        if *oauthIssuer != "" && *oauthResource == "" {
+                problems = append(problems, "oauth-issuer requires oauth-resource, the URI access tokens must be issued for")
+        }
+        if (*jwtIssuer != "" || *jwksURL != "") && *jwtAudience == "" && *oauthResource == "" {
+                problems = append(problems, "jwt-issuer and jwks-url require jwt-audience or oauth-resource, the audience JWTs must be issued for")
+        }
+        if *tlsCert != "" && *acmeDomains != "" {
+                problems = append(problems, "tls-cert and acme-domains are alternatives; set one")
+        }
+        if *tlsClientCA != "" && *tlsCert == "" && *acmeDomains == "" {
+                problems = append(problems, "tls-client-ca requires tls-cert and tls-key, or acme-domains")
+        }
+        if *sessionStoreDSN != "" && !strings.HasPrefix(*sessionStoreDSN, "redis://") && !strings.HasPrefix(*sessionStoreDSN, "rediss://") {
+                problems = append(problems, "session-store must be a redis:// or rediss:// URL")
+        }
+        if *leaderElection != "" && !strings.HasPrefix(*leaderElection, "redis://") && !strings.HasPrefix(*leaderElection, "rediss://") &&
+                !strings.HasPrefix(*leaderElection, "postgres://") && !strings.HasPrefix(*leaderElection, "postgresql://") {
+                problems = append(problems, "leader-election must be a redis://, rediss://, or postgres:// URL")
+        }
+        if *replayPath != "" && (*storeDSN != "memory" || *snapshotPath != "" || *eventLogPath != "") {
+                problems = append(problems, "replay runs against a fresh in-memory store, so it can't be combined with a store other than memory, snapshot, or event-log")
+        }
+        if len(problems) > 0 {
+                exitInvalidConfig(problems)
+        }
+
+        if err := mcp.SetupLogging(*logFormat, *logLevelName, logSinks); err != nil {
+                fatalf("Configuring logging: %v", err)
+        }
+        if *profile != "" {
+                slog.Info("Using the " + *profile + " profile")
+        }
+        if *logPayloads {
+                redactor, err := mcp.NewRedactor(*redactFields, redactValues)
+                if err != nil {
+                        fatalf("Configuring payload redaction: %v", err)
+                }
+                mcp.PayloadLog = redactor
+        }
+        if *recordDir != "" {
+                if err := mcp.RecordSessions(*recordDir); err != nil {
+                        fatalf("Recording sessions: %v", err)
+                }
+                slog.Warn("Recording sessions, unredacted, to " + *recordDir)
+        }
+        if err := mcp.SetupTracing(*otlpEndpoint); err != nil {
+                fatalf("Configuring tracing: %v", err)
+        }
+
+        providers, err := mcp.ParseSecretProviders(*secretsSpec, *secretsRefresh)
+        if err != nil {
+                fatalf("Configuring secrets: %v", err)
+        }
+        mcp.Secrets = providers
+
+        machine, err := tickets.NewStatusMachine(*transitions, *reopenTransitions)
+        if err != nil {
+                fatalf("Invalid status transitions: %v", err)
+        }
+
+        if *replayPath != "" {
+                // A replay changes nothing real: it runs against a fresh
+                // in-memory store, without notifiers, leader election, or the
+                // tools that call out to other systems.
+                memStore, _ := tickets.OpenStore("memory")
+                store := tickets.NewEventStore(memStore, tickets.NewEventLog(tickets.SystemClock))
+                os.Exit(replay(server.New(server.WithStore(store), server.WithTimeouts(timeouts), server.WithStatusMachine(machine)), *replayPath))
+        }
+
+        ticketStore, err := tickets.OpenStore(*storeDSN)
+        if err != nil {
+                fatalf("Opening ticket store: %v", err)
+        }
+        if *snapshotPath != "" {
+                memStore, ok := ticketStore.(*tickets.MemoryStore)
+                if !ok {
+                        fatalf("-snapshot requires the memory store")
+                }
+                if ticketStore, err = tickets.NewSnapshotStore(memStore, *snapshotPath); err != nil {
+                        fatalf("Restoring snapshot: %v", err)
+                }
+        }
+        events := tickets.NewEventLog(tickets.SystemClock)
+        if *eventLogPath != "" {
+                memStore, ok := ticketStore.(*tickets.MemoryStore)
+                if !ok {
+                        fatalf("-event-log requires the memory store without -snapshot")
+                }
+                if events, err = tickets.OpenEventLog(*eventLogPath, tickets.SystemClock); err != nil {
+                        fatalf("Opening event log: %v", err)
+                }
+                events.Replay(memStore)
+        }
+        tickets.TicketStoreKind = tickets.StoreKind(*storeDSN)
+        store := tickets.NewEventStore(tickets.NewTracedStore(ticketStore, tickets.TicketStoreKind), events)
+        closeOnSignal(store)
+        if *slackWebhook != "" || *slackChannel != "" {
+                slack, err := tickets.NewSlackNotifier(*slackWebhook, "SLACK_BOT_TOKEN", *slackChannel, *slackCreated, *slackStatus)
+                if err != nil {
+                        fatalf("Configuring Slack: %v", err)
+                }
+                events.Subscribe(slack.Notify)
+        }
+        if *leaderElection != "" {
+                if err := mcp.Leader.Elect(*leaderElection); err != nil {
+                        fatalf("Configuring leader election: %v", err)
+                }
+        }
+        if *smtpAddr != "" {
+                email, err := tickets.NewEmailNotifier(*smtpAddr, *smtpFrom, *emailDomain, "SMTP_USERNAME", "SMTP_PASSWORD", *emailBatchWindow)
+                if err != nil {
+                        fatalf("Configuring email: %v", err)
+                }
+                events.Subscribe(email.Notify)
+                if *overdueReminders > 0 {
+                        email.RemindEvery(store, *overdueReminders)
+                }
+        }
+
+        if *openAPISpec != "" {
+                spec, err := mcp.LoadOpenAPISpec(*openAPISpec)
+                if err != nil {
+                        fatalf("Loading OpenAPI spec: %v", err)
+                }
+                n, err := mcp.RegisterOpenAPITools(spec, *openAPIBaseURL, *openAPIPrefix, http.Header(openAPIHeaders))
+                if err != nil {
+                        fatalf("Registering OpenAPI tools: %v", err)
+                }
+                slog.Info(fmt.Sprintf("Registered %d tools from %s", n, *openAPISpec))
+        }
+        if *toolManifestPath != "" {
+                n, err := mcp.LoadToolManifest(*toolManifestPath)
+                if err != nil {
+                        fatalf("Loading tool manifest: %v", err)
+                }
+                slog.Info(fmt.Sprintf("Registered %d tools from %s", n, *toolManifestPath))
+        }
+        for _, command := range pluginCommands {
+                n, err := mcp.LoadPlugin(command)
+                if err != nil {
+                        fatalf("Loading plugin: %v", err)
+                }
+                slog.Info(fmt.Sprintf("Registered %d tools from plugin %s", n, command))
+        }
+
+        if *disabledToolNames != "" {
+                names := splitList(*disabledToolNames)
+                if err := mcp.DisableTools(names); err != nil {
+                        fatalf("-disable-tools: %v", err)
+                }
+                slog.Info("Disabled tools: " + strings.Join(names, ", "))
+        }
+
+        if *promptsDir != "" {
+                mcp.WatchPromptDir(*promptsDir)
+        }
+
+        if *adminAddr != "" {
+                mcp.ServeAdmin(*adminAddr)
+        }
+
+        mux := http.NewServeMux()
+        keys, err := transport.LoadAPIKeys(*apiKeysFile)
+        if err != nil {
+                fatalf("Loading API keys: %v", err)
+        }
+        auth := transport.Auth{APIKeys: keys, APIKeyQuery: *apiKeyQuery}
+        if len(auth.APIKeys) > 0 {
+                slog.Info(fmt.Sprintf("Requiring one of %d API keys", len(auth.APIKeys)))
+        }
+
+        if *oauthIssuer != "" {
+                auth.OAuth, err = transport.NewOAuthResourceServer(*oauthIssuer, *oauthResource, *oauthScopes, *oauthIntrospection, "OAUTH_CLIENT_ID", "OAUTH_CLIENT_SECRET")
+                if err != nil {
+                        fatalf("Configuring OAuth: %v", err)
+                }
+                mux.HandleFunc("/.well-known/oauth-protected-resource", auth.OAuth.HandleMetadata)
+                mux.HandleFunc("/.well-known/oauth-protected-resource"+transport.WSPath, auth.OAuth.HandleMetadata)
+                mux.HandleFunc("/.well-known/oauth-protected-resource"+transport.HTTPPath, auth.OAuth.HandleMetadata)
+                slog.Info("Accepting access tokens from " + *oauthIssuer)
+        }
+
+        if *jwtIssuer != "" || *jwksURL != "" {
+                if *jwtAudience == "" {
+                        *jwtAudience = *oauthResource
+                }
+                auth.JWT, err = transport.NewJWTVerifier(*jwksURL, *jwtIssuer, *jwtAudience, transport.OAuthScopeList(*oauthScopes))
+                if err != nil {
+                        fatalf("Configuring JWT validation: %v", err)
+                }
+                slog.Info("Accepting JWTs signed with keys from " + auth.JWT.JWKSURL)
+        }
+        if *requireAuth && len(auth.APIKeys) == 0 && auth.OAuth == nil && auth.JWT == nil && *tlsClientCA == "" {
+                fatalf("-require-auth is set, but no API keys, -oauth-issuer, -jwt-issuer, -jwks-url, or -tls-client-ca is configured")
+        }
+
+        if *clientOverridesPath != "" {
+                overrides, err := mcp.LoadClientOverrides(*clientOverridesPath)
+                if err != nil {
+                        fatalf("Loading client overrides: %v", err)
+                }
+                mcp.SetClientOverrides(overrides)
+        }
+
+        if *rbacPath != "" {
+                if mcp.RBAC, err = mcp.LoadRBACPolicy(*rbacPath); err != nil {
+                        fatalf("Loading RBAC policy: %v", err)
+                }
+        }
+
+        if *sessionStoreDSN != "" {
+                if transport.SharedSessions, err = transport.NewSessionStore(*sessionStoreDSN); err != nil {
+                        fatalf("Connecting to the session store: %v", err)
+                }
+                go transport.SharedSessions.Listen()
+                mcp.JoinCluster(transport.SharedSessions)
+                slog.Info("Sharing Streamable HTTP sessions through Redis")
+        }
+
+        var clientCAConfig *tls.Config
+        if *tlsClientCA != "" {
+                if clientCAConfig, err = transport.ClientCertTLSConfig(*tlsClientCA); err != nil {
+                        fatalf("Loading client CA bundle: %v", err)
+                }
+                if auth.ClientCerts, err = transport.LoadClientCertAuth(*clientIdentities); err != nil {
+                        fatalf("Loading client certificate identities: %v", err)
+                }
+                slog.Info("Requiring client certificates signed by " + *tlsClientCA)
+        }
+
+        srv := server.New(
+                server.WithStore(store),
+                server.WithTimeouts(timeouts),
+                server.WithAuth(auth),
+                server.WithAllowedOrigins(*allowedOrigins, transport.IsLoopbackHost(*host)),
+                server.WithStatusMachine(machine),
+        )
+        mux.Handle("/", srv.Handler())
+
+        reloader := newConfigReloader(srv, *configPath, *promptsDir, commandLine, liveSettings{
+                disabledTools:        *disabledToolNames,
+                logLevel:             *logLevelName,
+                clientOverrides:      *clientOverridesPath,
+                requestLimit:         mcp.ConnRequestLimit,
+                toolLimit:            mcp.ConnToolLimit,
+                identityRequestLimit: mcp.IdentityRequestLimit,
+                identityToolLimit:    mcp.IdentityToolLimit,
+        })
+        go reloader.watch()
+
+        addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+        // No ReadTimeout: it would cut off WebSockets and event streams.
+        // Bodies are bounded by -body-timeout in the transport instead, and
+        // by -max-message-size here, for every handler.
+        httpServer := &http.Server{
+                Addr:              addr,
+                Handler:           http.MaxBytesHandler(mux, mcp.MaxMessageSize),
+                ReadHeaderTimeout: timeouts.Handshake,
+        }
+        if *tlsCert == "" && *acmeDomains == "" {
+                slog.Info("MCP Server running on ws://" + addr + transport.WSPath + " and http://" + addr + transport.HTTPPath)
+                fatalf("Serving: %v", httpServer.ListenAndServe())
+        }
+        if *acmeDomains != "" {
+                manager := transport.NewACMEManager(splitList(*acmeDomains), *acmeCache, *acmeEmail)
+                if *acmeHTTPAddr != "" {
+                        transport.ServeACMEChallenges(manager, *acmeHTTPAddr)
+                }
+                httpServer.TLSConfig = transport.ServerTLSConfig(clientCAConfig, nil, manager)
+                slog.Info("Obtaining certificates from Let's Encrypt for " + *acmeDomains)
+        } else {
+                certs, err := transport.NewCertReloader(*tlsCert, *tlsKey)
+                if err != nil {
+                        fatalf("Loading TLS certificate: %v", err)
+                }
+                go certs.Watch()
+                httpServer.TLSConfig = transport.ServerTLSConfig(clientCAConfig, certs, nil)
+        }
+        slog.Info("MCP Server running on wss://" + addr + transport.WSPath + " and https://" + addr + transport.HTTPPath)
+        fatalf("Serving: %v", httpServer.ListenAndServeTLS("", ""))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+        "flag"
+        "fmt"
+        "maps"
+        "slices"
+        "strings"
+)
+
+// profileFlag names the flag that selects a profile: a bundle of settings
+// for an environment, applied beneath the config file and the environment.
+const profileFlag = "profile"
+
+// profilesKey is the config file key holding per-profile settings, which
+// override the built-in ones and the rest of the file when that profile is
+// selected. It may also define new profiles.
+const profilesKey = "profiles"
+
+// builtinProfiles are the profiles available without a config file, keyed
+// by flag name.
+var builtinProfiles = map[string]map[string]interface{}{
+        // dev logs everything, payloads included, and keeps tickets in memory
+        // so each run starts afresh.
+        "dev": {
+                "log-level":    "debug",
+                "log-payloads": true,
+                "store":        "memory",
+        },
+        // staging and prod log JSON and refuse to start without a way to
+        // authenticate clients. prod has to be told where its tickets are
+        // kept; see profileRequires.
+        "staging": {
+                "log-format":   "json",
+                "require-auth": true,
+        },
+        "prod": {
+                "log-format":   "json",
+                "log-level":    "info",
+                "require-auth": true,
+        },
+}
+
+// profileRequires lists the settings a built-in profile has no safe value
+// for, which must be given on the command line, in the environment, or in
+// the config file when that profile is selected.
+var profileRequires = map[string][]string{
+        // A default database would be one nobody chose, so a prod server
+        // could start on an empty store.
+        "prod": {"store"},
+}
+
+// checkProfileRequires returns a problem for each setting the profile
+// requires that fs didn't get from anywhere. It runs at startup only, since
+// a reload doesn't see settings given on the command line.
+func checkProfileRequires(fs *flag.FlagSet, profile string) []string {
+        set := map[string]bool{}
+        fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+        var problems []string
+        for _, name := range profileRequires[profile] {
+                if !set[name] {
+                        problems = append(problems, fmt.Sprintf("profile %s needs -%s (or %s) to be set", profile, name, envName(name)))
+                }
+        }
+        return problems
+}
+
+func profileNames() []string {
+        return slices.Sorted(maps.Keys(builtinProfiles))
+}
+
+// readProfiles adds the profiles section of the config file at path to
+// profiles, keyed by flag name, and returns the problems with it.
+func readProfiles(fs *flag.FlagSet, path string, value interface{}, profiles map[string]map[string]interface{}) []string {
+        sections, ok := value.(map[string]interface{})
+        if !ok {
+                return []string{fmt.Sprintf("%s: %s: expected a map of profile names to settings", path, profilesKey)}
+        }
+        var problems []string
+        for profile, section := range sections {
+                if section == nil {
+                        section = map[string]interface{}{}
+                }
+                settings, ok := section.(map[string]interface{})
+                if !ok {
+                        problems = append(problems, fmt.Sprintf("%s: %s: %s: expected a map of settings", path, profilesKey, profile))
+                        continue
+                }
+                profiles[profile] = map[string]interface{}{}
+                for key, value := range settings {
+                        name := strings.ReplaceAll(key, "_", "-")
+                        if fs.Lookup(name) == nil || name == configFlag || name == profileFlag {
+                                problems = append(problems, fmt.Sprintf("%s: %s: %s: unknown setting %q%s", path, profilesKey, profile, key, suggestFlag(fs, name)))
+                                continue
+                        }
+                        profiles[profile][name] = value
+                }
+        }
+        return problems
+}
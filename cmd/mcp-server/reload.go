@@ -0,0 +1,245 @@
+package main
+
+import (
+        "context"
+        "flag"
+        "fmt"
+        "log/slog"
+        "os"
+        "os/signal"
+        "slices"
+        "strings"
+        "sync"
+        "syscall"
+        "time"
+
+        "mcp-server/mcp"
+        "mcp-server/server"
+)
+
+// configReloadInterval is how often the config file and the
+// -client-overrides file are checked for changes.
+const configReloadInterval = 2 * time.Second
+
+// reloadableSettings are the flags whose values can change while the
+// server runs. Changes to any other setting wait for a restart.
+var reloadableSettings = []string{
+        "disable-tools",
+        "log-level",
+        "rate-limit",
+        "tool-rate-limit",
+        "identity-rate-limit",
+        "identity-tool-rate-limit",
+        "client-overrides",
+}
+
+// liveSettings holds the values of reloadableSettings.
+type liveSettings struct {
+        disabledTools   string
+        logLevel        string
+        clientOverrides string
+
+        requestLimit, toolLimit                 mcp.RateLimit
+        identityRequestLimit, identityToolLimit mcp.RateLimit
+}
+
+// values returns the flag value of each of s's settings, by name.
+func (s *liveSettings) values() map[string]string {
+        return map[string]string{
+                "disable-tools":            s.disabledTools,
+                "log-level":                s.logLevel,
+                "rate-limit":               s.requestLimit.String(),
+                "tool-rate-limit":          s.toolLimit.String(),
+                "identity-rate-limit":      s.identityRequestLimit.String(),
+                "identity-tool-rate-limit": s.identityToolLimit.String(),
+                "client-overrides":         s.clientOverrides,
+        }
+}
+
+// ignoredValue stands in for settings that aren't reloaded, so the config
+// file can still mention them.
+type ignoredValue struct{}
+
+func (ignoredValue) String() string   { return "" }
+func (ignoredValue) Set(string) error { return nil }
+
+// configReloader re-reads the configuration on SIGHUP or when the config
+// file changes, and applies the reloadable settings without dropping any
+// connections. SIGHUP also reloads the prompt templates and the
+// -client-overrides file; the latter is watched for changes as well.
+type configReloader struct {
+        // srv is the server whose rate limits and clients are reloaded.
+        srv        *server.Server
+        path       string
+        promptsDir string
+        // commandLine holds the flags given on the command line, which
+        // override the config file.
+        commandLine map[string]bool
+
+        mu       sync.Mutex
+        settings liveSettings
+        // signature identifies the versions of the files settings came from.
+        signature string
+}
+
+// newConfigReloader returns a reloader of srv's settings from the config
+// file at path, if any, starting from the settings srv was started with.
+func newConfigReloader(srv *server.Server, path, promptsDir string, commandLine map[string]bool, settings liveSettings) *configReloader {
+        r := &configReloader{srv: srv, path: path, promptsDir: promptsDir, commandLine: commandLine, settings: settings}
+        r.signature = r.fileSignature()
+        return r
+}
+
+// fileSignature changes whenever the config or client overrides file is
+// replaced or modified.
+func (r *configReloader) fileSignature() string {
+        signature := ""
+        for _, path := range []string{r.path, r.settings.clientOverrides} {
+                if path == "" {
+                        continue
+                }
+                if info, err := os.Stat(path); err == nil {
+                        signature += fmt.Sprintf("%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+                }
+        }
+        return signature
+}
+
+// watch reloads the configuration on SIGHUP and when its files change.
+func (r *configReloader) watch() {
+        hangups := make(chan os.Signal, 1)
+        signal.Notify(hangups, syscall.SIGHUP)
+        ticker := time.NewTicker(configReloadInterval)
+        for {
+                select {
+                case <-hangups:
+                        slog.Info("Reloading configuration on SIGHUP")
+                        r.reload()
+                        if r.promptsDir != "" && mcp.ReloadPromptDir(r.promptsDir) {
+                                mcp.NotifyAll(context.Background(), "notifications/prompts/list_changed", nil)
+                        }
+                case <-ticker.C:
+                        r.mu.Lock()
+                        unchanged := r.fileSignature() == r.signature
+                        r.mu.Unlock()
+                        if !unchanged {
+                                r.reload()
+                        }
+                }
+        }
+}
+
+// reload reads the settings again and applies them. If any of them is
+// invalid, none are applied and the server carries on as it was.
+func (r *configReloader) reload() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        // Take the signature first so changes made while reading are seen
+        // on the next poll.
+        signature := r.fileSignature()
+        next, problems := r.read()
+        if len(problems) == 0 {
+                if err := r.apply(next); err != nil {
+                        problems = []string{err.Error()}
+                }
+        }
+        // Record the files as seen either way, so a bad edit is reported once
+        // rather than on every poll.
+        r.signature = signature
+        if len(problems) > 0 {
+                slog.Error("Configuration not reloaded; keeping the current settings", "problems", problems)
+        }
+}
+
+// read loads the reloadable settings from the config file and environment
+// as at startup. Settings given on the command line keep their values.
+func (r *configReloader) read() (liveSettings, []string) {
+        var next liveSettings
+        fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+        values := map[string]flag.Value{
+                "rate-limit":               &next.requestLimit,
+                "tool-rate-limit":          &next.toolLimit,
+                "identity-rate-limit":      &next.identityRequestLimit,
+                "identity-tool-rate-limit": &next.identityToolLimit,
+        }
+        flag.VisitAll(func(f *flag.Flag) {
+                switch {
+                case f.Name == profileFlag:
+                        // The profile chosen at startup stays in force, so
+                        // its settings still apply to those reloaded.
+                        fs.String(profileFlag, "", f.Usage)
+                        fs.Set(profileFlag, f.Value.String())
+                case !slices.Contains(reloadableSettings, f.Name):
+                        fs.Var(ignoredValue{}, f.Name, f.Usage)
+                case values[f.Name] != nil:
+                        fs.Var(values[f.Name], f.Name, f.Usage)
+                        values[f.Name].Set(f.DefValue)
+                }
+        })
+        fs.StringVar(&next.disabledTools, "disable-tools", flag.Lookup("disable-tools").DefValue, "")
+        fs.StringVar(&next.logLevel, "log-level", flag.Lookup("log-level").DefValue, "")
+        fs.StringVar(&next.clientOverrides, "client-overrides", flag.Lookup("client-overrides").DefValue, "")
+
+        for name, value := range r.settings.values() {
+                if r.commandLine[name] {
+                        fs.Set(name, value)
+                }
+        }
+        problems := loadConfig(fs, r.path)
+        if _, ok := mcp.ParseLogLevel(next.logLevel); !ok {
+                problems = append(problems, fmt.Sprintf("unknown log level %q", next.logLevel))
+        }
+        return next, problems
+}
+
+// apply puts next into force. Connected clients keep their connections
+// but get the new rate limits and tool visibility.
+func (r *configReloader) apply(next liveSettings) error {
+        var overrides map[string]*mcp.ClientOverride
+        if next.clientOverrides != "" {
+                var err error
+                if overrides, err = mcp.LoadClientOverrides(next.clientOverrides); err != nil {
+                        return fmt.Errorf("loading client overrides: %w", err)
+                }
+        }
+        if err := mcp.DisableTools(splitList(next.disabledTools)); err != nil {
+                return fmt.Errorf("disable-tools: %w", err)
+        }
+        mcp.SetDefaultLogLevel(next.logLevel)
+        r.srv.SetRateLimits(mcp.RateLimits{
+                ConnRequests:     next.requestLimit,
+                ConnTools:        next.toolLimit,
+                IdentityRequests: next.identityRequestLimit,
+                IdentityTools:    next.identityToolLimit,
+        })
+        overridesChanged := mcp.SetClientOverrides(overrides)
+
+        before, after := r.settings.values(), next.values()
+        var changed []string
+        for _, name := range reloadableSettings {
+                if before[name] != after[name] {
+                        changed = append(changed, name)
+                }
+        }
+        if overridesChanged && !slices.Contains(changed, "client-overrides") {
+                changed = append(changed, "client-overrides")
+        }
+        r.settings = next
+
+        limitsChanged := overridesChanged || slices.ContainsFunc(changed, func(name string) bool {
+                return strings.HasSuffix(name, "rate-limit")
+        })
+        if limitsChanged {
+                for _, c := range r.srv.Clients() {
+                        if _, info := c.Session.Negotiated(); info.Name != "" {
+                                c.ApplyClientOverride(info)
+                        }
+                        c.ResetLimiter()
+                }
+        }
+        if slices.Contains(changed, "disable-tools") || overridesChanged {
+                mcp.NotifyAll(context.Background(), "notifications/tools/list_changed", nil)
+        }
+        slog.Info("Reloaded configuration", "changed", changed)
+        return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "os"
+        "reflect"
+        "time"
+
+        "mcp-server/mcp"
+        "mcp-server/server"
+)
+
+// replayTimeout bounds a -replay run.
+const replayTimeout = 5 * time.Minute
+
+// replay plays the session recorded at path back to srv. It writes the
+// frames the server sends to stdout, in the recording's format, and each
+// response that differs from the recorded one to stderr, returning the exit
+// status: 0 if every response matched.
+func replay(srv *server.Server, path string) int {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                fatalf("Reading recording: %v", err)
+        }
+        recorded, err := mcp.ReadRecording(bytes.NewReader(data))
+        if err != nil {
+                fatalf("Reading recording %s: %v", path, err)
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+        defer cancel()
+        replayed, err := srv.Replay(ctx, recorded)
+        encoder := json.NewEncoder(os.Stdout)
+        for _, f := range replayed {
+                encoder.Encode(f)
+        }
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "Replay stopped: %v\n", err)
+                return 1
+        }
+        if differences := compareResponses(os.Stderr, recorded, replayed); differences > 0 {
+                fmt.Fprintf(os.Stderr, "%d of the responses differ from the recording\n", differences)
+                return 1
+        }
+        return 0
+}
+
+// compareResponses writes to w how each response to a client request in
+// recorded differs from the one in replayed, returning how many differ.
+func compareResponses(w io.Writer, recorded, replayed []mcp.Frame) int {
+        responses := func(frames []mcp.Frame) ([]string, map[string]interface{}) {
+                var ids []string
+                byID := map[string]interface{}{}
+                for _, f := range frames {
+                        var msg map[string]interface{}
+                        if f.Direction != "out" || json.Unmarshal(f.Data(), &msg) != nil {
+                                continue
+                        }
+                        id, ok := msg["id"].(string)
+                        if _, isRequest := msg["method"]; !ok || isRequest {
+                                continue
+                        }
+                        if _, seen := byID[id]; !seen {
+                                ids = append(ids, id)
+                        }
+                        byID[id] = msg
+                }
+                return ids, byID
+        }
+        ids, want := responses(recorded)
+        _, got := responses(replayed)
+        differences := 0
+        for _, id := range ids {
+                switch replayedResponse, ok := got[id]; {
+                case !ok:
+                        fmt.Fprintf(w, "Request %s: no response\n", id)
+                case !reflect.DeepEqual(want[id], replayedResponse):
+                        recordedJSON, _ := json.Marshal(want[id])
+                        replayedJSON, _ := json.Marshal(replayedResponse)
+                        fmt.Fprintf(w, "Request %s: response differs\n  recorded: %s\n  replayed: %s\n", id, recordedJSON, replayedJSON)
+                default:
+                        continue
+                }
+                differences++
+        }
+        return differences
+}
@@ -0,0 +1,255 @@
+// Package conformance checks that an MCP server follows the protocol over
+// a given transport: the initialize handshake and version negotiation,
+// the methods its capabilities promise, JSON-RPC error codes, pagination,
+// notifications, and cancellation. Run it from a test with a function
+// that connects a fresh client.
+package conformance
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "testing"
+        "time"
+
+        "mcp-server/client"
+        "mcp-server/mcp"
+)
+
+// timeout bounds each check.
+const timeout = 10 * time.Second
+
+// maxPages stops a walk through a list whose cursors never end.
+const maxPages = 1000
+
+// Connect returns a new client of the server under test, not yet
+// initialized. The suite closes it.
+type Connect func(t *testing.T) *client.Client
+
+// Run runs every check, each on its own connection.
+func Run(t *testing.T, connect Connect) {
+        checks := []struct {
+                name  string
+                check func(t *testing.T, ctx context.Context, c *client.Client)
+        }{
+                {"initialize", checkInitialize},
+                {"version negotiation", checkVersionNegotiation},
+                {"ping", checkPing},
+                {"capabilities", checkCapabilities},
+                {"error codes", checkErrorCodes},
+                {"pagination", checkPagination},
+                {"notifications", checkNotifications},
+                {"cancellation", checkCancellation},
+        }
+        for _, tc := range checks {
+                t.Run(tc.name, func(t *testing.T) {
+                        ctx, cancel := context.WithTimeout(context.Background(), timeout)
+                        defer cancel()
+                        c := connect(t)
+                        defer c.Close()
+                        tc.check(t, ctx, c)
+                })
+        }
+}
+
+var clientInfo = mcp.ClientInfo{Name: "conformance", Version: "1.0"}
+
+// initialize starts the session or fails the test.
+func initialize(t *testing.T, ctx context.Context, c *client.Client) *mcp.InitializeResult {
+        t.Helper()
+        result, err := c.Initialize(ctx, clientInfo)
+        if err != nil {
+                t.Fatalf("initialize: %v", err)
+        }
+        return result
+}
+
+// wantCode fails the test unless err is a JSON-RPC error with code.
+func wantCode(t *testing.T, what string, err error, code int) {
+        t.Helper()
+        var mcpErr *mcp.Error
+        switch {
+        case err == nil:
+                t.Errorf("%s: succeeded, want error %d", what, code)
+        case !errors.As(err, &mcpErr):
+                t.Errorf("%s: %v, want error %d", what, err, code)
+        case mcpErr.Code != code:
+                t.Errorf("%s: error %d (%s), want %d", what, mcpErr.Code, mcpErr.Message, code)
+        case mcpErr.Message == "":
+                t.Errorf("%s: error %d has no message", what, code)
+        }
+}
+
+func checkInitialize(t *testing.T, ctx context.Context, c *client.Client) {
+        result := initialize(t, ctx, c)
+        if result.ProtocolVersion != client.ProtocolVersion {
+                t.Errorf("protocolVersion: got %q, want %q as requested", result.ProtocolVersion, client.ProtocolVersion)
+        }
+        if result.ServerInfo.Name == "" || result.ServerInfo.Version == "" {
+                t.Errorf("serverInfo: got %+v, want a name and version", result.ServerInfo)
+        }
+}
+
+func checkVersionNegotiation(t *testing.T, ctx context.Context, c *client.Client) {
+        var result mcp.InitializeResult
+        params := mcp.InitializeParams{ProtocolVersion: "1999-01-01", ClientInfo: clientInfo}
+        if err := c.Call(ctx, "initialize", params, &result); err != nil {
+                t.Fatalf("initialize: %v", err)
+        }
+        if result.ProtocolVersion == "" || result.ProtocolVersion == params.ProtocolVersion {
+                t.Errorf("protocolVersion: got %q for an unsupported version, want one the server supports", result.ProtocolVersion)
+        }
+}
+
+func checkPing(t *testing.T, ctx context.Context, c *client.Client) {
+        initialize(t, ctx, c)
+        var result map[string]interface{}
+        if err := c.Call(ctx, "ping", nil, &result); err != nil {
+                t.Fatalf("ping: %v", err)
+        }
+        if len(result) != 0 {
+                t.Errorf("ping: got %v, want an empty result", result)
+        }
+}
+
+// checkCapabilities calls a method of each capability group the server
+// declares.
+func checkCapabilities(t *testing.T, ctx context.Context, c *client.Client) {
+        caps := initialize(t, ctx, c).Capabilities
+        if caps.Tools != nil {
+                if _, err := c.ListTools(ctx); err != nil {
+                        t.Errorf("tools/list: %v", err)
+                }
+        }
+        if caps.Resources != nil {
+                for _, method := range []string{"resources/list", "resources/templates/list"} {
+                        if err := c.Call(ctx, method, nil, nil); err != nil {
+                                t.Errorf("%s: %v", method, err)
+                        }
+                }
+        }
+        if caps.Prompts != nil {
+                if err := c.Call(ctx, "prompts/list", nil, nil); err != nil {
+                        t.Errorf("prompts/list: %v", err)
+                }
+        }
+        if caps.Logging != nil {
+                if err := c.Call(ctx, "logging/setLevel", map[string]string{"level": "error"}, nil); err != nil {
+                        t.Errorf("logging/setLevel: %v", err)
+                }
+        }
+        if caps.Completions != nil {
+                params := mcp.CompleteParams{
+                        Ref:      mcp.CompletionReference{Type: "ref/prompt", Name: "no-such-prompt"},
+                        Argument: mcp.CompletionArgument{Name: "x"},
+                }
+                var mcpErr *mcp.Error
+                if err := c.Call(ctx, "completion/complete", params, nil); errors.As(err, &mcpErr) && mcpErr.Code == -32601 {
+                        t.Errorf("completion/complete: declared but not found")
+                }
+        }
+}
+
+func checkErrorCodes(t *testing.T, ctx context.Context, c *client.Client) {
+        initialize(t, ctx, c)
+        wantCode(t, "unknown method", c.Call(ctx, "no/such/method", nil, nil), -32601)
+        wantCode(t, "tools/call with params that aren't an object", c.Call(ctx, "tools/call", json.RawMessage(`"x"`), nil), -32602)
+        _, err := c.CallTool(ctx, "no_such_tool", nil)
+        wantCode(t, "tools/call of an unknown tool", err, -32602)
+        wantCode(t, "logging/setLevel with an unknown level", c.Call(ctx, "logging/setLevel", map[string]string{"level": "loud"}, nil), -32602)
+        wantCode(t, "prompts/list with an invalid cursor", c.Call(ctx, "prompts/list", mcp.PaginatedParams{Cursor: "not a cursor"}, nil), -32602)
+}
+
+// checkPagination walks every paginated list, checking that the pages end
+// and no item appears twice.
+func checkPagination(t *testing.T, ctx context.Context, c *client.Client) {
+        initialize(t, ctx, c)
+        lists := []struct {
+                method string
+                items  string
+                key    string
+        }{
+                {"resources/list", "resources", "uri"},
+                {"resources/templates/list", "resourceTemplates", "uriTemplate"},
+                {"prompts/list", "prompts", "name"},
+        }
+        for _, list := range lists {
+                seen := map[string]bool{}
+                cursor := ""
+                for page := 0; ; page++ {
+                        if page == maxPages {
+                                t.Errorf("%s: still has more after %d pages", list.method, maxPages)
+                                break
+                        }
+                        var result map[string]json.RawMessage
+                        if err := c.Call(ctx, list.method, mcp.PaginatedParams{Cursor: cursor}, &result); err != nil {
+                                t.Errorf("%s page %d: %v", list.method, page+1, err)
+                                break
+                        }
+                        var items []map[string]interface{}
+                        if err := json.Unmarshal(result[list.items], &items); err != nil || items == nil {
+                                t.Errorf("%s page %d: %s is %s, want an array", list.method, page+1, list.items, result[list.items])
+                                break
+                        }
+                        for _, item := range items {
+                                key, _ := item[list.key].(string)
+                                if seen[key] {
+                                        t.Errorf("%s: %s appears twice", list.method, key)
+                                }
+                                seen[key] = true
+                        }
+                        if raw, ok := result["nextCursor"]; !ok || json.Unmarshal(raw, &cursor) != nil || cursor == "" {
+                                break
+                        }
+                }
+        }
+}
+
+// checkNotifications turns on logging and expects the server to send log
+// messages.
+func checkNotifications(t *testing.T, ctx context.Context, c *client.Client) {
+        if initialize(t, ctx, c).Capabilities.Logging == nil {
+                t.Skip("the server declares no logging capability")
+        }
+        if err := c.Call(ctx, "logging/setLevel", map[string]string{"level": "debug"}, nil); err != nil {
+                t.Fatalf("logging/setLevel: %v", err)
+        }
+        if err := c.Call(ctx, "ping", nil, nil); err != nil {
+                t.Fatalf("ping: %v", err)
+        }
+        for {
+                select {
+                case n, ok := <-c.Notifications():
+                        if !ok {
+                                t.Fatal("connection closed before a log message")
+                        }
+                        if n.Method != "notifications/message" {
+                                continue
+                        }
+                        var params mcp.LoggingMessageParams
+                        if err := json.Unmarshal(n.Params, &params); err != nil {
+                                t.Fatalf("notifications/message params: %v", err)
+                        }
+                        if _, ok := mcp.ParseLogLevel(params.Level); !ok || params.Data == nil {
+                                t.Errorf("notifications/message: got %s, want a level and data", n.Params)
+                        }
+                        return
+                case <-ctx.Done():
+                        t.Fatal("no notifications/message after logging/setLevel debug")
+                }
+        }
+}
+
+// checkCancellation cancels a request the server doesn't know and one it
+// has already answered, both of which it must ignore.
+func checkCancellation(t *testing.T, ctx context.Context, c *client.Client) {
+        initialize(t, ctx, c)
+        for _, id := range []string{"no-such-request", "1"} {
+                if err := c.Notify("notifications/cancelled", mcp.CancelledParams{RequestID: id, Reason: "conformance"}); err != nil {
+                        t.Fatal(err)
+                }
+        }
+        if err := c.Call(ctx, "ping", nil, nil); err != nil {
+                t.Errorf("ping after cancellations: %v", err)
+        }
+}
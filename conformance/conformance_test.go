@@ -0,0 +1,41 @@
+package conformance
+
+import (
+        "context"
+        "net/http/httptest"
+        "strings"
+        "testing"
+
+        "mcp-server/client"
+        "mcp-server/server"
+        "mcp-server/transport"
+)
+
+func TestPipe(t *testing.T) {
+        srv := server.New()
+        Run(t, func(t *testing.T) *client.Client {
+                pipe, _ := srv.Pipe()
+                return client.New(pipe)
+        })
+}
+
+func TestWebSocket(t *testing.T) {
+        httpServer := httptest.NewServer(server.New().Handler())
+        defer httpServer.Close()
+        url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + transport.WSPath
+        Run(t, func(t *testing.T) *client.Client {
+                c, err := client.Connect(context.Background(), url, nil)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                return c
+        })
+}
+
+func TestStreamableHTTP(t *testing.T) {
+        httpServer := httptest.NewServer(server.New().Handler())
+        defer httpServer.Close()
+        Run(t, func(t *testing.T) *client.Client {
+                return client.ConnectHTTP(httpServer.URL+transport.HTTPPath, nil)
+        })
+}
@@ -0,0 +1,18 @@
+// Command fakejira serves an in-memory fake of Jira's REST API for the
+// integration tests; see package fakejira.
+package main
+
+import (
+        "flag"
+        "log"
+        "net/http"
+
+        "mcp-server/integration/fakejira"
+)
+
+func main() {
+        addr := flag.String("addr", ":8089", "address to listen on")
+        flag.Parse()
+        log.Printf("Fake Jira listening on %s", *addr)
+        log.Fatal(http.ListenAndServe(*addr, fakejira.New()))
+}
@@ -0,0 +1,367 @@
+// Package fakejira serves the part of Jira's REST API the jira: ticket store
+// uses, keeping issues in memory, so the store can be tested end to end
+// without a Jira site. Every project key exists, every user search finds a
+// user of that name, and the workflow lets any issue move to To Do, In
+// Progress, or Done.
+package fakejira
+
+import (
+        "encoding/json"
+        "fmt"
+        "net/http"
+        "regexp"
+        "slices"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+)
+
+const timeLayout = "2006-01-02T15:04:05.000-0700"
+
+type status struct {
+        Name           string `json:"name"`
+        StatusCategory struct {
+                Key string `json:"key"`
+        } `json:"statusCategory"`
+}
+
+func newStatus(name, category string) status {
+        s := status{Name: name}
+        s.StatusCategory.Key = category
+        return s
+}
+
+// workflow is the statuses issues move between, by transition ID.
+var workflow = map[string]status{
+        "11": newStatus("To Do", "new"),
+        "21": newStatus("In Progress", "indeterminate"),
+        "31": newStatus("Done", "done"),
+}
+
+type user struct {
+        AccountID   string `json:"accountId"`
+        DisplayName string `json:"displayName"`
+}
+
+type comment struct {
+        ID      string `json:"id"`
+        Body    string `json:"body"`
+        Author  user   `json:"author"`
+        Created string `json:"created"`
+}
+
+type issue struct {
+        project  string
+        key      string
+        fields   map[string]interface{}
+        status   status
+        created  time.Time
+        updated  time.Time
+        comments []comment
+}
+
+func (i *issue) MarshalJSON() ([]byte, error) {
+        fields := map[string]interface{}{
+                "status":  i.status,
+                "project": map[string]string{"key": i.project},
+                "created": i.created.Format(timeLayout),
+                "updated": i.updated.Format(timeLayout),
+        }
+        for k, v := range i.fields {
+                fields[k] = v
+        }
+        return json.Marshal(map[string]interface{}{"key": i.key, "fields": fields})
+}
+
+// Server is a fake Jira site.
+type Server struct {
+        mu     sync.Mutex
+        issues []*issue
+        // next numbers each project's issues.
+        next        map[string]int
+        nextComment int
+        // last is the latest update time handed out, so every change moves
+        // an issue's updated time forward, as the store's version checks
+        // expect.
+        last time.Time
+        mux  *http.ServeMux
+}
+
+// New returns an empty Jira site.
+func New() *Server {
+        s := &Server{next: map[string]int{}, mux: http.NewServeMux()}
+        s.mux.HandleFunc("GET /rest/api/2/project/{key}", s.getProject)
+        s.mux.HandleFunc("GET /rest/api/2/search/jql", s.search)
+        s.mux.HandleFunc("POST /rest/api/2/issue", s.createIssue)
+        s.mux.HandleFunc("GET /rest/api/2/issue/{key}", s.getIssue)
+        s.mux.HandleFunc("PUT /rest/api/2/issue/{key}", s.editIssue)
+        s.mux.HandleFunc("GET /rest/api/2/issue/{key}/transitions", s.listTransitions)
+        s.mux.HandleFunc("POST /rest/api/2/issue/{key}/transitions", s.transition)
+        s.mux.HandleFunc("GET /rest/api/2/issue/{key}/comment", s.listComments)
+        s.mux.HandleFunc("POST /rest/api/2/issue/{key}/comment", s.addComment)
+        s.mux.HandleFunc("DELETE /rest/api/2/issue/{key}/comment/{id}", s.deleteComment)
+        s.mux.HandleFunc("GET /rest/api/2/user/search", s.searchUsers)
+        return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+        s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(status)
+        json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+        writeJSON(w, status, map[string]interface{}{"errorMessages": []string{fmt.Sprintf(format, args...)}})
+}
+
+// now returns the time of a change, later than any before it; s.mu must
+// be held.
+func (s *Server) now() time.Time {
+        t := time.Now().UTC().Truncate(time.Millisecond)
+        if !t.After(s.last) {
+                t = s.last.Add(time.Millisecond)
+        }
+        s.last = t
+        return t
+}
+
+// issue returns the issue key names; s.mu must be held.
+func (s *Server) issue(key string) *issue {
+        for _, i := range s.issues {
+                if i.key == key {
+                        return i
+                }
+        }
+        return nil
+}
+
+// withIssue runs f on the issue the request names, with s.mu held, or
+// answers 404.
+func (s *Server) withIssue(w http.ResponseWriter, r *http.Request, f func(i *issue)) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        i := s.issue(r.PathValue("key"))
+        if i == nil {
+                writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+                return
+        }
+        f(i)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, r *http.Request) {
+        key := r.PathValue("key")
+        writeJSON(w, http.StatusOK, map[string]string{"key": key, "name": key})
+}
+
+// jqlClause matches the clauses of the JQL the store sends.
+var jqlClause = regexp.MustCompile(`^(project|labels|statusCategory) = "?([^"]*)"?$`)
+
+// search supports the project, labels, and statusCategory clauses, joined
+// with AND; it ignores any others, which only widens the results the store
+// filters again. Results are ordered by creation and come in one page.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+        jql, _, _ := strings.Cut(r.URL.Query().Get("jql"), " ORDER BY ")
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        matches := []*issue{}
+        for _, i := range s.issues {
+                ok := true
+                for _, clause := range strings.Split(jql, " AND ") {
+                        m := jqlClause.FindStringSubmatch(strings.TrimSpace(clause))
+                        if m == nil {
+                                continue
+                        }
+                        switch m[1] {
+                        case "project":
+                                ok = ok && i.project == m[2]
+                        case "labels":
+                                labels, _ := i.fields["labels"].([]interface{})
+                                ok = ok && slices.Contains(labels, interface{}(m[2]))
+                        case "statusCategory":
+                                ok = ok && i.status.StatusCategory.Key == m[2]
+                        }
+                }
+                if ok {
+                        matches = append(matches, i)
+                }
+        }
+        writeJSON(w, http.StatusOK, map[string]interface{}{"issues": matches, "isLast": true})
+}
+
+// fieldsBody decodes a request's {"fields": {...}}.
+func fieldsBody(r *http.Request) (map[string]interface{}, error) {
+        var body struct {
+                Fields map[string]interface{} `json:"fields"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+                return nil, err
+        }
+        return body.Fields, nil
+}
+
+// setFields copies the editable fields into i.
+func setFields(i *issue, fields map[string]interface{}) {
+        for k, v := range fields {
+                switch k {
+                case "project", "issuetype":
+                        continue
+                case "assignee":
+                        if u, ok := v.(map[string]interface{}); ok {
+                                id, _ := u["accountId"].(string)
+                                v = user{AccountID: id, DisplayName: strings.TrimPrefix(id, "account-")}
+                        }
+                case "labels":
+                        if v == nil {
+                                v = []interface{}{}
+                        }
+                }
+                i.fields[k] = v
+        }
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request) {
+        fields, err := fieldsBody(r)
+        if err != nil {
+                writeError(w, http.StatusBadRequest, "%v", err)
+                return
+        }
+        project, _ := fields["project"].(map[string]interface{})
+        key, _ := project["key"].(string)
+        summary, _ := fields["summary"].(string)
+        if key == "" || summary == "" {
+                writeError(w, http.StatusBadRequest, "project and summary are required")
+                return
+        }
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        s.next[key]++
+        now := s.now()
+        i := &issue{
+                project: key,
+                key:     key + "-" + strconv.Itoa(s.next[key]),
+                fields:  map[string]interface{}{"labels": []interface{}{}},
+                status:  workflow["11"],
+                created: now,
+                updated: now,
+        }
+        setFields(i, fields)
+        s.issues = append(s.issues, i)
+        writeJSON(w, http.StatusCreated, map[string]string{"key": i.key})
+}
+
+func (s *Server) getIssue(w http.ResponseWriter, r *http.Request) {
+        s.withIssue(w, r, func(i *issue) {
+                writeJSON(w, http.StatusOK, i)
+        })
+}
+
+func (s *Server) editIssue(w http.ResponseWriter, r *http.Request) {
+        fields, err := fieldsBody(r)
+        if err != nil {
+                writeError(w, http.StatusBadRequest, "%v", err)
+                return
+        }
+        s.withIssue(w, r, func(i *issue) {
+                setFields(i, fields)
+                i.updated = s.now()
+                w.WriteHeader(http.StatusNoContent)
+        })
+}
+
+func (s *Server) listTransitions(w http.ResponseWriter, r *http.Request) {
+        s.withIssue(w, r, func(i *issue) {
+                type transition struct {
+                        ID string `json:"id"`
+                        To status `json:"to"`
+                }
+                var transitions []transition
+                for _, id := range []string{"11", "21", "31"} {
+                        if workflow[id].Name != i.status.Name {
+                                transitions = append(transitions, transition{ID: id, To: workflow[id]})
+                        }
+                }
+                writeJSON(w, http.StatusOK, map[string]interface{}{"transitions": transitions})
+        })
+}
+
+func (s *Server) transition(w http.ResponseWriter, r *http.Request) {
+        var body struct {
+                Transition struct {
+                        ID string `json:"id"`
+                } `json:"transition"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+                writeError(w, http.StatusBadRequest, "%v", err)
+                return
+        }
+        to, ok := workflow[body.Transition.ID]
+        if !ok {
+                writeError(w, http.StatusBadRequest, "no transition %q", body.Transition.ID)
+                return
+        }
+        s.withIssue(w, r, func(i *issue) {
+                i.status = to
+                i.updated = s.now()
+                w.WriteHeader(http.StatusNoContent)
+        })
+}
+
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request) {
+        startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+        s.withIssue(w, r, func(i *issue) {
+                page := []comment{}
+                if startAt >= 0 && startAt < len(i.comments) {
+                        page = i.comments[startAt:]
+                }
+                writeJSON(w, http.StatusOK, map[string]interface{}{"comments": page, "total": len(i.comments)})
+        })
+}
+
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request) {
+        var body struct {
+                Body string `json:"body"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+                writeError(w, http.StatusBadRequest, "%v", err)
+                return
+        }
+        s.withIssue(w, r, func(i *issue) {
+                s.nextComment++
+                c := comment{
+                        ID:      strconv.Itoa(10000 + s.nextComment),
+                        Body:    body.Body,
+                        Author:  user{AccountID: "account-fake", DisplayName: "Fake Jira"},
+                        Created: s.now().Format(timeLayout),
+                }
+                i.comments = append(i.comments, c)
+                writeJSON(w, http.StatusCreated, c)
+        })
+}
+
+func (s *Server) deleteComment(w http.ResponseWriter, r *http.Request) {
+        s.withIssue(w, r, func(i *issue) {
+                n := slices.IndexFunc(i.comments, func(c comment) bool { return c.ID == r.PathValue("id") })
+                if n < 0 {
+                        writeError(w, http.StatusNotFound, "Comment does not exist")
+                        return
+                }
+                i.comments = slices.Delete(i.comments, n, n+1)
+                w.WriteHeader(http.StatusNoContent)
+        })
+}
+
+// searchUsers finds one user for any name, whose account ID is derived
+// from it.
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+        name := r.URL.Query().Get("query")
+        if name == "" {
+                writeJSON(w, http.StatusOK, []user{})
+                return
+        }
+        writeJSON(w, http.StatusOK, []user{{AccountID: "account-" + name, DisplayName: name}})
+}
@@ -0,0 +1,238 @@
+//go:build integration
+
+// Package integration runs every ticket tool end to end, through the
+// server's dispatcher, against each ticket store backend. The memory, file,
+// bolt, and jira (an in-process fake Jira) backends always run; Postgres,
+// Redis, MongoDB, and the containerized fake Jira run when their DSN is in
+// the environment, as run.sh arranges:
+//
+//	MCP_TEST_POSTGRES  postgres://...
+//	MCP_TEST_REDIS     redis://...
+//	MCP_TEST_MONGO     mongodb://...
+//	MCP_TEST_JIRA      jira:http://...?project=...
+//
+//	go test -tags integration ./integration
+package integration
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "net/http/httptest"
+        "os"
+        "path/filepath"
+        "slices"
+        "testing"
+        "time"
+
+        "mcp-server/client"
+        "mcp-server/integration/fakejira"
+        "mcp-server/mcp"
+        "mcp-server/server"
+        "mcp-server/tickets"
+)
+
+type backend struct {
+        name string
+        dsn  string
+}
+
+func backends(t *testing.T) []backend {
+        dir := t.TempDir()
+        list := []backend{
+                {"memory", "memory"},
+                {"file", "file://" + filepath.Join(dir, "tickets.json")},
+                {"bolt", "bolt://" + filepath.Join(dir, "tickets.db")},
+        }
+        for _, b := range []struct{ name, env string }{
+                {"postgres", "MCP_TEST_POSTGRES"},
+                {"redis", "MCP_TEST_REDIS"},
+                {"mongo", "MCP_TEST_MONGO"},
+        } {
+                if dsn := os.Getenv(b.env); dsn != "" {
+                        list = append(list, backend{b.name, dsn})
+                } else {
+                        t.Logf("%s not set; skipping the %s backend", b.env, b.name)
+                }
+        }
+        jira := os.Getenv("MCP_TEST_JIRA")
+        if jira == "" {
+                fake := httptest.NewServer(fakejira.New())
+                t.Cleanup(fake.Close)
+                jira = "jira:" + fake.URL + "?project=MCP"
+        }
+        return append(list, backend{"jira", jira})
+}
+
+func TestTools(t *testing.T) {
+        for _, b := range backends(t) {
+                t.Run(b.name, func(t *testing.T) {
+                        store, err := tickets.OpenStore(b.dsn)
+                        if err != nil {
+                                t.Fatalf("opening %s: %v", b.dsn, err)
+                        }
+                        if closer, ok := store.(io.Closer); ok {
+                                defer closer.Close()
+                        }
+                        ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+                        defer cancel()
+                        pipe, _ := server.New(server.WithStore(store)).Pipe()
+                        c := client.New(pipe)
+                        defer c.Close()
+                        if _, err := c.Initialize(ctx, mcp.ClientInfo{Name: "integration", Version: "1"}); err != nil {
+                                t.Fatal(err)
+                        }
+                        runTools(t, &session{t: t, ctx: ctx, c: c})
+                })
+        }
+}
+
+// result holds the fields the ticket tools respond with.
+type result struct {
+        Ticket   tickets.Ticket    `json:"ticket"`
+        Tickets  []tickets.Ticket  `json:"tickets"`
+        Total    int               `json:"total"`
+        Comment  tickets.Comment   `json:"comment"`
+        Comments []tickets.Comment `json:"comments"`
+}
+
+type session struct {
+        t   *testing.T
+        ctx context.Context
+        c   *client.Client
+}
+
+// call calls tool and fails the test if it fails.
+func (s *session) call(tool string, args map[string]interface{}) result {
+        s.t.Helper()
+        data, err := s.c.CallTool(s.ctx, tool, args)
+        if err != nil {
+                s.t.Fatalf("%s: %v", tool, err)
+        }
+        var r result
+        if err := json.Unmarshal(data, &r); err != nil {
+                s.t.Fatalf("%s: decoding %s: %v", tool, data, err)
+        }
+        return r
+}
+
+// update calls a tool that changes ticket, passing its id and version, and
+// returns the ticket as changed.
+func (s *session) update(tool string, ticket tickets.Ticket, args map[string]interface{}) tickets.Ticket {
+        s.t.Helper()
+        args["id"] = ticket.ID
+        args["version"] = ticket.Version
+        updated := s.call(tool, args).Ticket
+        if updated.ID != ticket.ID {
+                s.t.Fatalf("%s: got ticket %q, want %q", tool, updated.ID, ticket.ID)
+        }
+        return updated
+}
+
+// fail calls tool and fails the test unless it fails with code.
+func (s *session) fail(tool string, args map[string]interface{}, code int) {
+        s.t.Helper()
+        _, err := s.c.CallTool(s.ctx, tool, args)
+        var mcpErr *mcp.Error
+        if !errors.As(err, &mcpErr) || mcpErr.Code != code {
+                s.t.Errorf("%s: got %v, want error %d", tool, err, code)
+        }
+}
+
+func runTools(t *testing.T, s *session) {
+        // The run's label keeps its tickets apart from others in a shared
+        // database.
+        run := fmt.Sprintf("run-%d", time.Now().UnixNano())
+        byRun := func(extra map[string]interface{}) map[string]interface{} {
+                args := map[string]interface{}{"labels": []string{run}}
+                for k, v := range extra {
+                        args[k] = v
+                }
+                return args
+        }
+
+        ticket := s.call("create_ticket", map[string]interface{}{
+                "title":       "Integration " + run,
+                "description": "Created by the integration tests",
+                "labels":      []string{run},
+                "priority":    "P1",
+                "assignee":    "alice",
+        }).Ticket
+        if ticket.ID == "" || ticket.Status != "todo" || ticket.Priority != "P1" || ticket.Assignee != "alice" || !slices.Equal(ticket.Labels, []string{run}) {
+                t.Fatalf("create_ticket: got %+v", ticket)
+        }
+
+        if found := s.call("search_tickets", byRun(nil)); found.Total != 1 || len(found.Tickets) != 1 || found.Tickets[0].ID != ticket.ID {
+                t.Fatalf("search_tickets by label: got %+v, want only %s", found, ticket.ID)
+        }
+
+        stale := ticket
+        ticket = s.update("update_ticket_status", ticket, map[string]interface{}{"status": "pending"})
+        if ticket.Status != "pending" {
+                t.Errorf("update_ticket_status: status %q, want pending", ticket.Status)
+        }
+        s.fail("update_ticket_status", map[string]interface{}{"id": stale.ID, "status": "done", "version": stale.Version}, -32010)
+        s.fail("update_ticket_status", map[string]interface{}{"id": "NO-SUCH-1", "status": "done", "version": 1}, -32602)
+        if pending := s.call("search_tickets", byRun(map[string]interface{}{"status": "pending"})); pending.Total != 1 {
+                t.Errorf("search_tickets by status pending: got %d tickets, want 1", pending.Total)
+        }
+
+        ticket = s.update("assign_ticket", ticket, map[string]interface{}{"assignee": "bob"})
+        if ticket.Assignee != "bob" {
+                t.Errorf("assign_ticket: assignee %q, want bob", ticket.Assignee)
+        }
+        ticket = s.update("unassign_ticket", ticket, map[string]interface{}{})
+        if ticket.Assignee != "" {
+                t.Errorf("unassign_ticket: assignee %q, want none", ticket.Assignee)
+        }
+
+        ticket = s.update("set_ticket_priority", ticket, map[string]interface{}{"priority": "P0"})
+        if ticket.Priority != "P0" {
+                t.Errorf("set_ticket_priority: priority %q, want P0", ticket.Priority)
+        }
+        ticket = s.update("set_due_date", ticket, map[string]interface{}{"due_date": "2030-01-31"})
+        if ticket.DueDate.Format(time.DateOnly) != "2030-01-31" {
+                t.Errorf("set_due_date: due %v, want 2030-01-31", ticket.DueDate)
+        }
+
+        ticket = s.update("add_labels", ticket, map[string]interface{}{"labels": []string{"extra"}})
+        if !slices.Contains(ticket.Labels, "extra") {
+                t.Errorf("add_labels: labels %v, want extra among them", ticket.Labels)
+        }
+        ticket = s.update("remove_labels", ticket, map[string]interface{}{"labels": []string{"extra"}})
+        if slices.Contains(ticket.Labels, "extra") {
+                t.Errorf("remove_labels: labels %v still has extra", ticket.Labels)
+        }
+
+        comment := s.call("add_comment", map[string]interface{}{"ticket_id": ticket.ID, "body": "Looks good"}).Comment
+        if comment.ID == "" || comment.Body != "Looks good" {
+                t.Errorf("add_comment: got %+v", comment)
+        }
+        if comments := s.call("list_comments", map[string]interface{}{"ticket_id": ticket.ID}).Comments; len(comments) != 1 || comments[0].ID != comment.ID {
+                t.Errorf("list_comments: got %+v, want the one added", comments)
+        }
+        s.call("delete_comment", map[string]interface{}{"ticket_id": ticket.ID, "comment_id": comment.ID})
+        if comments := s.call("list_comments", map[string]interface{}{"ticket_id": ticket.ID}).Comments; len(comments) != 0 {
+                t.Errorf("list_comments after delete_comment: got %+v", comments)
+        }
+        s.fail("delete_comment", map[string]interface{}{"ticket_id": ticket.ID, "comment_id": comment.ID}, -32602)
+
+        ticket = s.update("update_ticket_status", ticket, map[string]interface{}{"status": "done"})
+        if ticket.Status != "done" {
+                t.Errorf("update_ticket_status: status %q, want done", ticket.Status)
+        }
+
+        ticket = s.update("delete_ticket", ticket, map[string]interface{}{})
+        if found := s.call("search_tickets", byRun(nil)); found.Total != 0 {
+                t.Errorf("search_tickets after delete_ticket: got %d tickets, want none", found.Total)
+        }
+        if found := s.call("search_tickets", byRun(map[string]interface{}{"deleted": "only"})); found.Total != 1 {
+                t.Errorf("search_tickets for deleted tickets: got %d tickets, want 1", found.Total)
+        }
+        s.update("restore_ticket", ticket, map[string]interface{}{})
+        if found := s.call("search_tickets", byRun(nil)); found.Total != 1 {
+                t.Errorf("search_tickets after restore_ticket: got %d tickets, want 1", found.Total)
+        }
+}
@@ -0,0 +1,30 @@
+package mcp
+
+import (
+        "log/slog"
+        "net/http"
+        "net/http/pprof"
+)
+
+// adminMux serves diagnostics (profiles and metrics) and operator actions
+// such as broadcasts on the -admin-addr port, kept apart from the MCP
+// endpoint so it can stay on a private interface.
+var adminMux = http.NewServeMux()
+
+func init() {
+        adminMux.HandleFunc("/debug/pprof/", pprof.Index)
+        adminMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+        adminMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+        adminMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+        adminMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// ServeAdmin serves adminMux on addr in the background. It has no
+// authentication, so addr should not be reachable by clients.
+func ServeAdmin(addr string) {
+        slog.Info("Admin endpoints on http://" + addr + "/metrics, /broadcast, and /debug/pprof/")
+        go func() {
+                err := http.ListenAndServe(addr, adminMux)
+                slog.Error("Admin server stopped", "error", err)
+        }()
+}
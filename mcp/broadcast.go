@@ -0,0 +1,171 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "log/slog"
+        "net/http"
+        "slices"
+)
+
+var ErrOutboxFull = errors.New("outbox full")
+
+// clientFilter selects the clients a broadcast reaches.
+type clientFilter func(c *Conn) bool
+
+// withIdentity selects the clients that authenticated as one of
+// identities.
+func withIdentity(identities ...string) clientFilter {
+        return func(c *Conn) bool {
+                return slices.Contains(identities, c.Session.Identity)
+        }
+}
+
+// withScope selects the clients granted scope; without an -rbac policy
+// that is every client.
+func withScope(scope string) clientFilter {
+        return func(c *Conn) bool {
+                return c.scopes == nil || c.scopes.grants(scope)
+        }
+}
+
+// withSessions selects the clients with the given session IDs.
+func withSessions(ids ...string) clientFilter {
+        return func(c *Conn) bool {
+                return slices.Contains(ids, c.Session.ID)
+        }
+}
+
+// BroadcastRequest is a notification to broadcast, and the body of a POST
+// to the admin /broadcast endpoint. The filters are optional; a client
+// must match every one given.
+type BroadcastRequest struct {
+        Method     string          `json:"method"`
+        Params     json.RawMessage `json:"params,omitempty"`
+        Identities []string        `json:"identities,omitempty"`
+        Scope      string          `json:"scope,omitempty"`
+        Sessions   []string        `json:"sessions,omitempty"`
+}
+
+func (req BroadcastRequest) filters() []clientFilter {
+        var filters []clientFilter
+        if len(req.Identities) > 0 {
+                filters = append(filters, withIdentity(req.Identities...))
+        }
+        if req.Scope != "" {
+                filters = append(filters, withScope(req.Scope))
+        }
+        if len(req.Sessions) > 0 {
+                filters = append(filters, withSessions(req.Sessions...))
+        }
+        return filters
+}
+
+// publishBroadcast delivers req to the clients of groups on this replica
+// and, when sessions are shared, on every other, which can't tell whose
+// clients it was for. It returns how many clients here it was queued for.
+func publishBroadcast(req BroadcastRequest, groups []*Group) int {
+        id := newSessionID()
+        if cluster != nil {
+                cluster.PublishBroadcast(id, req)
+        }
+        return broadcast(id, req, groups)
+}
+
+// Broadcast sends req's notification to every initialized client on this
+// replica that req's filters select, and returns how many it was queued
+// for. The notification is encoded once and queued on each client's write
+// pump; a client whose outbox is full misses it rather than holding up the
+// others. id identifies the broadcast, so that a shared session gets it
+// once however many replicas serve it.
+func Broadcast(id string, req BroadcastRequest) int {
+        return broadcast(id, req, allGroups())
+}
+
+// broadcast is Broadcast to the clients of groups.
+func broadcast(id string, req BroadcastRequest, groups []*Group) int {
+        if !methodEnabled(req.Method) {
+                return 0
+        }
+        msg := Notification{Method: req.Method}
+        if len(req.Params) > 0 {
+                msg.Params = req.Params
+        }
+        data, err := encodeMessage(msg)
+        if err != nil {
+                slog.Error("Encoding broadcast", "notification", req.Method, "error", err)
+                return 0
+        }
+        filters := req.filters()
+        var targets []*Conn
+        for _, g := range groups {
+                targets = append(targets, g.Clients()...)
+        }
+        sent := 0
+clients:
+        for _, c := range targets {
+                if version, _ := c.Session.Negotiated(); version == "" {
+                        continue
+                }
+                for _, accept := range filters {
+                        if !accept(c) {
+                                continue clients
+                        }
+                }
+                if c.transport.Shared() {
+                        c.LogPayload("out", data)
+                        err = cluster.AppendEvent(c.Session.ID, id, data)
+                } else {
+                        err = c.deliver(msg, data, false)
+                }
+                switch {
+                case err == nil:
+                        sent++
+                case errors.Is(err, ErrOutboxFull):
+                        notificationsDropped.Add(1, req.Method)
+                        c.logger.Warn("Dropped broadcast: outbox full", "notification", req.Method)
+                }
+        }
+        return sent
+}
+
+// NotifyAll sends a notification to every client of the server whose
+// connection ctx derives from, or, for a context from no server, to every
+// connected client.
+func NotifyAll(ctx context.Context, method string, params interface{}) {
+        req := BroadcastRequest{Method: method}
+        if params != nil {
+                data, err := json.Marshal(params)
+                if err != nil {
+                        slog.Error("Encoding broadcast", "notification", method, "error", err)
+                        return
+                }
+                req.Params = data
+        }
+        publishBroadcast(req, groupsFor(ctx))
+}
+
+func init() {
+        adminMux.HandleFunc("/broadcast", handleBroadcast)
+}
+
+// handleBroadcast lets operators send a notification, such as
+// notifications/tools/list_changed or a custom event, to connected clients.
+func handleBroadcast(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+                w.Header().Set("Allow", "POST")
+                http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+                return
+        }
+        var req BroadcastRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method == "" {
+                http.Error(w, "Bad Request: expected a JSON object with a method", http.StatusBadRequest)
+                return
+        }
+        sent := publishBroadcast(req, allGroups())
+        slog.Info(fmt.Sprintf("Broadcast %s to %d clients", req.Method, sent))
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(map[string]int{"delivered": sent})
+}
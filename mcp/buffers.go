@@ -0,0 +1,44 @@
+package mcp
+
+import (
+        "bytes"
+        "encoding/json"
+        "sync"
+)
+
+// maxPooledBuffer is the capacity beyond which a buffer is dropped rather
+// than pooled, so that one large message doesn't pin its memory for good.
+const maxPooledBuffer = 1 << 20
+
+// pooledEncoder is an encoder along with the buffer it writes to, reused
+// from one message to the next.
+type pooledEncoder struct {
+        buf bytes.Buffer
+        enc *json.Encoder
+}
+
+var encoders = sync.Pool{New: func() interface{} {
+        e := &pooledEncoder{}
+        e.enc = json.NewEncoder(&e.buf)
+        return e
+}}
+
+// marshalPooled encodes v in a pooled buffer and returns a copy of the
+// encoding, so the only allocation that grows with the message is the one
+// it is returned in. v should be a pointer, which encoding/json needn't
+// copy to encode.
+func marshalPooled(v interface{}) ([]byte, error) {
+        e := encoders.Get().(*pooledEncoder)
+        defer func() {
+                if e.buf.Cap() <= maxPooledBuffer {
+                        e.buf.Reset()
+                        encoders.Put(e)
+                }
+        }()
+        if err := e.enc.Encode(v); err != nil {
+                return nil, err
+        }
+        // Leave out the newline Encode ends with.
+        data := e.buf.Bytes()
+        return bytes.Clone(data[:len(data)-1]), nil
+}
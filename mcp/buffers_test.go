@@ -0,0 +1,98 @@
+package mcp
+
+import (
+        "encoding/json"
+        "fmt"
+        "testing"
+        "time"
+)
+
+// benchmarkTicket is shaped like the tickets tools respond with.
+type benchmarkTicket struct {
+        ID          string    `json:"id"`
+        Title       string    `json:"title"`
+        Description string    `json:"description"`
+        Status      string    `json:"status"`
+        Priority    string    `json:"priority"`
+        Assignee    string    `json:"assignee"`
+        Labels      []string  `json:"labels"`
+        Version     int       `json:"version"`
+        CreatedAt   time.Time `json:"createdAt"`
+}
+
+func benchmarkResponses() map[string]Response {
+        responses := map[string]Response{}
+        for _, n := range []int{1, 100} {
+                tickets := make([]benchmarkTicket, n)
+                for i := range tickets {
+                        tickets[i] = benchmarkTicket{
+                                ID:          fmt.Sprintf("T%d", i),
+                                Title:       "Login page times out behind the proxy",
+                                Description: "Users behind the corporate proxy see a timeout after entering their password.",
+                                Status:      "todo",
+                                Priority:    "P1",
+                                Assignee:    "alice",
+                                Labels:      []string{"auth", "proxy"},
+                                Version:     3,
+                                CreatedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+                        }
+                }
+                responses[fmt.Sprintf("%d tickets", n)] = Response{
+                        ID:     "42",
+                        Result: map[string]interface{}{"tickets": tickets, "total": n},
+                }
+        }
+        return responses
+}
+
+// unpooledResponse encodes as Response did before pooled buffers: its
+// envelope with json.Marshal, which the server called through json.Marshal.
+type unpooledResponse Response
+
+func (r unpooledResponse) MarshalJSON() ([]byte, error) {
+        type response Response
+        return json.Marshal(struct {
+                JSONRPC string `json:"jsonrpc"`
+                response
+        }{jsonRPCVersion, response(r)})
+}
+
+// BenchmarkEncodeResponse compares encoding a response the way the server
+// does, in a pooled buffer, with the way it did before, in fresh buffers
+// and with json.Marshal scanning and copying the envelope once more.
+func BenchmarkEncodeResponse(b *testing.B) {
+        for name, resp := range benchmarkResponses() {
+                b.Run(name+"/before", func(b *testing.B) {
+                        b.ReportAllocs()
+                        for i := 0; i < b.N; i++ {
+                                if _, err := json.Marshal(unpooledResponse(resp)); err != nil {
+                                        b.Fatal(err)
+                                }
+                        }
+                })
+                b.Run(name+"/pooled", func(b *testing.B) {
+                        b.ReportAllocs()
+                        for i := 0; i < b.N; i++ {
+                                if _, err := encodeMessage(resp); err != nil {
+                                        b.Fatal(err)
+                                }
+                        }
+                })
+        }
+}
+
+func TestMarshalPooledReuse(t *testing.T) {
+        // A failed message leaves nothing behind for the next one.
+        if _, err := encodeMessage(Notification{Method: "bad", Params: json.RawMessage(`{`)}); err == nil {
+                t.Fatal("invalid params: got no error")
+        }
+        for i := 0; i < 3; i++ {
+                data, err := encodeMessage(ServerRequest{ID: "1", Method: "ping"})
+                if err != nil {
+                        t.Fatal(err)
+                }
+                if want := `{"jsonrpc":"2.0","id":"1","method":"ping"}`; string(data) != want {
+                        t.Fatalf("got %s, want %s", data, want)
+                }
+        }
+}
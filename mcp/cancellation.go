@@ -0,0 +1,106 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "time"
+)
+
+// RequestTimeout bounds how long each request may run, set by
+// -request-timeout, for DefaultSettings. Zero means no limit.
+var RequestTimeout time.Duration
+
+// runningRequest is a request of the client's that is queued or running.
+type runningRequest struct {
+        method string
+        cancel context.CancelFunc
+        // cancelled is set once the client cancels the request, after which
+        // it gets no response.
+        cancelled bool
+}
+
+// CancelledParams are the params of notifications/cancelled.
+type CancelledParams struct {
+        RequestID string `json:"requestId"`
+        Reason    string `json:"reason,omitempty"`
+}
+
+// runningContextKey holds the runningRequest a context was started for.
+type runningContextKey struct{}
+
+// startRequest returns the context req runs in: derived from ctx, bounded
+// by its group's RequestTimeout, and cancelled when the client cancels req.
+// The request must be ended with endRequest, passing that context.
+func (c *Conn) startRequest(ctx context.Context, req Request) context.Context {
+        var cancel context.CancelFunc
+        if timeout := c.Group().Settings().RequestTimeout; timeout > 0 {
+                ctx, cancel = context.WithTimeout(ctx, timeout)
+        } else {
+                ctx, cancel = context.WithCancel(ctx)
+        }
+        r := &runningRequest{method: req.Method, cancel: cancel}
+        c.mu.Lock()
+        // With a reused ID the earlier request can no longer be cancelled
+        // by ID, but still runs to completion.
+        c.running[req.ID] = r
+        c.mu.Unlock()
+        return context.WithValue(ctx, runningContextKey{}, r)
+}
+
+// endRequest releases ctx, the context startRequest returned for the
+// request with id, and reports whether the client cancelled the request.
+// A later request that reused the ID keeps its entry.
+func (c *Conn) endRequest(ctx context.Context, id string) (cancelled bool) {
+        r, ok := ctx.Value(runningContextKey{}).(*runningRequest)
+        if !ok {
+                return false
+        }
+        c.mu.Lock()
+        if c.running[id] == r {
+                delete(c.running, id)
+        }
+        cancelled = r.cancelled
+        c.mu.Unlock()
+        r.cancel()
+        return cancelled
+}
+
+// handleCancelled cancels the request a notifications/cancelled names. A
+// request that has already finished, or that the server doesn't know, is
+// ignored, as is initialize.
+func handleCancelled(c *Conn, req Request) {
+        var params CancelledParams
+        if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == "" {
+                c.Logf(c.ctx, LevelWarning, "Ignoring malformed cancellation")
+                return
+        }
+        c.mu.Lock()
+        r, ok := c.running[params.RequestID]
+        if ok && r.method == "initialize" {
+                ok = false
+        }
+        if ok {
+                r.cancelled = true
+        }
+        c.mu.Unlock()
+        if !ok {
+                return
+        }
+        r.cancel()
+        reason := params.Reason
+        if reason == "" {
+                reason = "no reason given"
+        }
+        c.Logf(c.ctx, LevelDebug, "Client cancelled request id=%s: %s", params.RequestID, reason)
+}
+
+// timeoutError is the reply to a request that ran past timeout and failed
+// with cause.
+func timeoutError(timeout time.Duration, cause *Error) *Error {
+        return &Error{
+                Code:    -32014,
+                Message: fmt.Sprintf("Request timed out after %s", timeout),
+                Data:    map[string]interface{}{"timeout": timeout.String(), "error": cause.Message},
+        }
+}
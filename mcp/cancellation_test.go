@@ -0,0 +1,57 @@
+package mcp
+
+import (
+        "context"
+        "testing"
+)
+
+func TestReusedRequestID(t *testing.T) {
+        c := &Conn{running: make(map[string]*runningRequest)}
+        req := Request{ID: "1", Method: "tools/call"}
+        first := c.startRequest(context.Background(), req)
+        second := c.startRequest(context.Background(), req)
+        if first.Err() != nil {
+                t.Fatal("reusing the ID cancelled the first request")
+        }
+
+        // Ending the first request leaves the second running and
+        // cancellable.
+        if c.endRequest(first, req.ID) {
+                t.Error("first request reported as cancelled")
+        }
+        if first.Err() == nil {
+                t.Error("first request's context wasn't released")
+        }
+        if second.Err() != nil {
+                t.Fatal("ending the first request cancelled the second")
+        }
+        c.mu.Lock()
+        r, ok := c.running[req.ID]
+        c.mu.Unlock()
+        if !ok {
+                t.Fatal("ending the first request removed the second")
+        }
+        r.cancelled = true
+        if !c.endRequest(second, req.ID) {
+                t.Error("second request not reported as cancelled")
+        }
+        if len(c.running) != 0 {
+                t.Errorf("%d requests still running", len(c.running))
+        }
+}
+
+func TestRepeatedResponseIsDropped(t *testing.T) {
+        ch := make(chan ClientResponse, 1)
+        c := &Conn{pending: map[string]chan ClientResponse{"srv-1": ch}}
+        if !c.DeliverResponse(ClientResponse{ID: "srv-1"}) {
+                t.Fatal("first response not delivered")
+        }
+        // Nobody has taken the first response yet, so delivering a second
+        // would block if it reached the channel.
+        if c.DeliverResponse(ClientResponse{ID: "srv-1"}) {
+                t.Error("second response delivered")
+        }
+        if len(c.pending) != 0 {
+                t.Errorf("%d requests still pending", len(c.pending))
+        }
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+        "context"
+        "fmt"
+        "slices"
+        "strings"
+)
+
+// capabilityGroups can each be turned off with -disable-capabilities.
+// "mutations" covers the tools that change anything, so turning it off
+// leaves a read-only server.
+var capabilityGroups = []string{"tools", "resources", "prompts", "logging", "completions", "mutations"}
+
+// disabledCapabilities holds the groups turned off by -disable-capabilities.
+var disabledCapabilities = map[string]bool{}
+
+// DisableCapabilities turns off the named groups.
+func DisableCapabilities(names []string) error {
+        for _, name := range names {
+                if !slices.Contains(capabilityGroups, name) {
+                        return fmt.Errorf("unknown capability %q (want one of %s)", name, strings.Join(capabilityGroups, ", "))
+                }
+                disabledCapabilities[name] = true
+        }
+        // Turning off mutations turns off tools.
+        registryChanged()
+        return nil
+}
+
+// capabilityEnabled reports whether group is on. Completions need prompts
+// or resources to complete arguments for.
+func capabilityEnabled(group string) bool {
+        if group == "completions" && !capabilityEnabled("prompts") && !capabilityEnabled("resources") {
+                return false
+        }
+        return !disabledCapabilities[group]
+}
+
+// methodEnabled reports whether the capability group a request or
+// notification method belongs to is on. Methods outside every group, such
+// as initialize and ping, always are.
+func methodEnabled(method string) bool {
+        group, _, _ := strings.Cut(strings.TrimPrefix(method, "notifications/"), "/")
+        switch group {
+        case "tools", "resources", "prompts", "logging":
+        case "message":
+                group = "logging"
+        case "completion":
+                group = "completions"
+        default:
+                return true
+        }
+        return capabilityEnabled(group)
+}
+
+// mutates reports whether t changes anything, judged by the scopes it
+// requires. A tool without scopes gives no hint, so it is taken to mutate
+// rather than be left on in a read-only server.
+func (t *Tool) mutates() bool {
+        return len(t.Scopes) == 0 || slices.ContainsFunc(t.Scopes, func(scope string) bool {
+                return strings.HasSuffix(scope, ":write") || strings.HasSuffix(scope, ":admin")
+        })
+}
+
+// serverCapabilities builds the capabilities advertised in the initialize
+// result from what is actually registered, so a capability is only
+// advertised when the server can serve it.
+func serverCapabilities(ctx context.Context) ServerCapabilities {
+        var capabilities ServerCapabilities
+
+        if capabilityEnabled("logging") {
+                // Log forwarding is built into every connection.
+                capabilities.Logging = &struct{}{}
+        }
+
+        if capabilityEnabled("tools") && len(registeredTools()) > 0 {
+                capabilities.Tools = &ToolsCapability{
+                        Call: ToolsCallCapability{Enabled: true},
+                        List: ToolsListCapability{Enabled: true, ListChanged: true},
+                }
+        }
+
+        if capabilityEnabled("resources") && len(resourceProviders) > 0 {
+                // Ticket resources come and go as tickets are created.
+                capabilities.Resources = &ResourcesCapability{Subscribe: true, ListChanged: true}
+        }
+
+        if capabilityEnabled("prompts") && (len(listPrompts()) > 0 || promptsListChanged) {
+                capabilities.Prompts = &PromptsCapability{ListChanged: promptsListChanged}
+        }
+
+        if capabilityEnabled("completions") &&
+                (capabilityEnabled("prompts") && len(promptArgumentCompleters) > 0 || capabilityEnabled("resources") && len(resourceTemplateCompleters) > 0) {
+                capabilities.Completions = &struct{}{}
+        }
+
+        return capabilities
+}
@@ -0,0 +1,20 @@
+package mcp
+
+import "testing"
+
+func TestToolMutates(t *testing.T) {
+        for _, tt := range []struct {
+                tool *Tool
+                want bool
+        }{
+                {&Tool{Name: "get_ticket", Scopes: []string{ScopeTicketsRead}}, false},
+                {&Tool{Name: "delete_ticket", Scopes: []string{ScopeTicketsRead, ScopeTicketsWrite}}, true},
+                {&Tool{Name: "purge", Scopes: []string{"tickets:admin"}}, true},
+                {&Tool{Name: "unscoped"}, true},
+                {&Tool{Name: "public", Public: true}, true},
+        } {
+                if got := tt.tool.mutates(); got != tt.want {
+                        t.Errorf("%s: got %v, want %v", tt.tool.Name, got, tt.want)
+                }
+        }
+}
@@ -0,0 +1,178 @@
+package mcp
+
+import (
+        "fmt"
+        "os"
+        "reflect"
+        "slices"
+        "strings"
+        "sync"
+
+        "gopkg.in/yaml.v3"
+)
+
+// ClientOverride adjusts how the server treats the clients that give a
+// particular clientInfo name at initialize. Clients report their own name,
+// so overrides tailor the server to known clients; they are not access
+// control, which is what -rbac is for, and they can only hold a client to
+// less than it would otherwise get, never more.
+type ClientOverride struct {
+        // Tools, if set, are the only tools the client sees, and HideTools
+        // are hidden from it. Calls to hidden tools fail as unknown tools.
+        Tools     []string `yaml:"tools"`
+        HideTools []string `yaml:"hide_tools"`
+        // RateLimit and ToolRateLimit tighten -rate-limit and
+        // -tool-rate-limit for each of the client's connections; where they
+        // are looser, the flags still apply.
+        RateLimit     string `yaml:"rate_limit"`
+        ToolRateLimit string `yaml:"tool_rate_limit"`
+
+        requestLimit, toolLimit *RateLimit
+}
+
+// clientOverrides maps lower-cased client names to their overrides, as
+// loaded from -client-overrides.
+var clientOverrides = struct {
+        sync.RWMutex
+        byName map[string]*ClientOverride
+}{}
+
+// SetClientOverrides replaces the overrides and reports whether they
+// changed.
+func SetClientOverrides(overrides map[string]*ClientOverride) bool {
+        clientOverrides.Lock()
+        defer clientOverrides.Unlock()
+        changed := !reflect.DeepEqual(overrides, clientOverrides.byName)
+        clientOverrides.byName = overrides
+        return changed
+}
+
+// LoadClientOverrides reads a YAML or JSON map of client name to override.
+func LoadClientOverrides(path string) (map[string]*ClientOverride, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return nil, err
+        }
+        var byName map[string]*ClientOverride
+        if err := yaml.Unmarshal(data, &byName); err != nil {
+                return nil, fmt.Errorf("parsing %s: %w", path, err)
+        }
+        overrides := make(map[string]*ClientOverride, len(byName))
+        for name, o := range byName {
+                if o == nil {
+                        o = &ClientOverride{}
+                }
+                if o.requestLimit, err = parseOverrideLimit(o.RateLimit); err != nil {
+                        return nil, fmt.Errorf("%s: %s: rate_limit: %w", path, name, err)
+                }
+                if o.toolLimit, err = parseOverrideLimit(o.ToolRateLimit); err != nil {
+                        return nil, fmt.Errorf("%s: %s: tool_rate_limit: %w", path, name, err)
+                }
+                overrides[strings.ToLower(name)] = o
+        }
+        return overrides, nil
+}
+
+// parseOverrideLimit parses a rate in the -rate-limit format, or returns
+// nil if spec is empty.
+func parseOverrideLimit(spec string) (*RateLimit, error) {
+        if spec == "" {
+                return nil, nil
+        }
+        limit := new(RateLimit)
+        if err := limit.Set(spec); err != nil {
+                return nil, err
+        }
+        return limit, nil
+}
+
+// showsTool reports whether a client with override o sees the tool named
+// name. A nil override shows every tool.
+func (o *ClientOverride) showsTool(name string) bool {
+        if o == nil {
+                return true
+        }
+        if len(o.Tools) > 0 && !slices.Contains(o.Tools, name) {
+                return false
+        }
+        return !slices.Contains(o.HideTools, name)
+}
+
+// ApplyClientOverride looks up the override for the client named in info,
+// which then governs which tools c sees and, if it sets any, c's rate
+// limits.
+func (c *Conn) ApplyClientOverride(info ClientInfo) {
+        clientOverrides.RLock()
+        o := clientOverrides.byName[strings.ToLower(info.Name)]
+        clientOverrides.RUnlock()
+        c.override.Store(o)
+        if o == nil {
+                return
+        }
+        if o.requestLimit != nil || o.toolLimit != nil {
+                c.ResetLimiter()
+        }
+        c.Logf(c.ctx, LevelDebug, "Applying overrides for client %s", info.Name)
+}
+
+// ResetLimiter gives c fresh buckets for the rate limits in force,
+// tightened by its client override.
+func (c *Conn) ResetLimiter() {
+        limits := c.Group().Settings().RateLimits
+        requests, tools := limits.ConnRequests, limits.ConnTools
+        if o := c.override.Load(); o != nil {
+                if o.requestLimit != nil {
+                        requests = tighterLimit(requests, *o.requestLimit)
+                }
+                if o.toolLimit != nil {
+                        tools = tighterLimit(tools, *o.toolLimit)
+                }
+        }
+        c.limiter.Store(newConnLimiter(requests, tools))
+}
+
+// tighterLimit returns the stricter of two limits, rate and burst each.
+func tighterLimit(a, b RateLimit) RateLimit {
+        switch {
+        case !a.enabled():
+                return b
+        case !b.enabled():
+                return a
+        }
+        return RateLimit{perSecond: min(a.perSecond, b.perSecond), burst: min(a.burst, b.burst)}
+}
+
+// hidesTool reports whether c's client override hides the tool named name.
+func (c *Conn) hidesTool(name string) bool {
+        return !c.override.Load().showsTool(name)
+}
+
+// maxClientLabels bounds how many client names appear in metric labels.
+// Clients report their own names, so beyond that they are counted as
+// "other".
+const maxClientLabels = 50
+
+var clientLabels = struct {
+        sync.Mutex
+        seen map[string]bool
+}{seen: make(map[string]bool)}
+
+// clientLabel returns c's client name for metric labels: lower-cased,
+// "unknown" if it gave none, or "other" once maxClientLabels names have
+// been seen.
+func (c *Conn) clientLabel() string {
+        _, info := c.Session.Negotiated()
+        name := strings.ToLower(info.Name)
+        if name == "" {
+                return "unknown"
+        }
+        clientLabels.Lock()
+        defer clientLabels.Unlock()
+        if !clientLabels.seen[name] {
+                if len(clientLabels.seen) >= maxClientLabels {
+                        return "other"
+                }
+                clientLabels.seen[name] = true
+        }
+        return name
+}
@@ -0,0 +1,29 @@
+package mcp
+
+// Cluster connects the replicas serving the same clients when sessions are
+// kept in a shared store (see -session-store), so that a session can move
+// between replicas and what one replica sends reaches every client.
+type Cluster interface {
+        // PublishBroadcast has the other replicas deliver broadcast id.
+        PublishBroadcast(id string, req BroadcastRequest)
+        // ResourceUpdated tells the shared sessions subscribed to uri, and
+        // the other replicas' subscribers, that it changed.
+        ResourceUpdated(uri string) error
+        // AppendEvent adds data to shared session id's event stream. A
+        // non-empty broadcast ID is added only once, however many replicas
+        // deliver it.
+        AppendEvent(id, broadcast string, data []byte) error
+        // RelayResponse passes a client's reply to a server request on to
+        // the replica that sent the request.
+        RelayResponse(id string, resp ClientResponse)
+        // Subscribe adds or removes shared session id's subscription to uri.
+        Subscribe(id, uri string, subscribe bool) error
+}
+
+// cluster is nil when sessions live only in this process.
+var cluster Cluster
+
+// JoinCluster shares sessions with the other replicas through c.
+func JoinCluster(c Cluster) {
+        cluster = c
+}
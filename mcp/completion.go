@@ -0,0 +1,146 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "sort"
+        "strings"
+)
+
+// maxCompletionValues is the most values a completion/complete response may
+// carry, per the MCP specification.
+const maxCompletionValues = 100
+
+type CompletionReference struct {
+        Type string `json:"type"`
+        Name string `json:"name,omitempty"`
+        URI  string `json:"uri,omitempty"`
+}
+
+type CompletionArgument struct {
+        Name  string `json:"name"`
+        Value string `json:"value"`
+}
+
+type CompleteParams struct {
+        Ref      CompletionReference `json:"ref"`
+        Argument CompletionArgument  `json:"argument"`
+}
+
+type Completion struct {
+        Values  []string `json:"values"`
+        Total   int      `json:"total"`
+        HasMore bool     `json:"hasMore"`
+}
+
+// completer returns every candidate value for an argument; filtering by the
+// typed prefix happens in complete.
+type completer func(ctx context.Context) []string
+
+// promptArgumentCompleters completes prompt arguments by name, so templates
+// loaded from disk get completion for well-known argument names too.
+var promptArgumentCompleters = map[string]completer{}
+
+// resourceTemplateCompleters completes the variables of resource templates,
+// keyed by URI template and then variable name.
+var resourceTemplateCompleters = map[string]map[string]completer{}
+
+// RegisterArgumentCompleter completes prompt arguments called name with
+// fn. Like the other registries it is filled from init functions.
+func RegisterArgumentCompleter(name string, fn completer) {
+        promptArgumentCompleters[name] = fn
+}
+
+// RegisterTemplateCompleter completes variable in the resource template
+// uriTemplate with fn.
+func RegisterTemplateCompleter(uriTemplate, variable string, fn completer) {
+        if resourceTemplateCompleters[uriTemplate] == nil {
+                resourceTemplateCompleters[uriTemplate] = map[string]completer{}
+        }
+        resourceTemplateCompleters[uriTemplate][variable] = fn
+}
+
+// complete filters candidates to those starting with prefix,
+// case-insensitively, and truncates the result to maxCompletionValues.
+func complete(candidates []string, prefix string) Completion {
+        prefix = strings.ToLower(prefix)
+        values := []string{}
+        for _, candidate := range candidates {
+                if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+                        values = append(values, candidate)
+                }
+        }
+        sort.Strings(values)
+
+        completion := Completion{Values: values, Total: len(values)}
+        if len(values) > maxCompletionValues {
+                completion.Values = values[:maxCompletionValues]
+                completion.HasMore = true
+        }
+        return completion
+}
+
+func findCompleter(ref CompletionReference, argument string) (completer, error) {
+        switch {
+        case ref.Type == "ref/prompt" && capabilityEnabled("prompts"):
+                prompt, ok := findPrompt(ref.Name)
+                if !ok {
+                        return nil, fmt.Errorf("Unknown prompt: %s", ref.Name)
+                }
+                for _, arg := range prompt.Arguments {
+                        if arg.Name == argument {
+                                return promptArgumentCompleters[argument], nil
+                        }
+                }
+                return nil, fmt.Errorf("Prompt %s has no argument %s", ref.Name, argument)
+        case ref.Type == "ref/resource" && capabilityEnabled("resources"):
+                variables, ok := resourceTemplateCompleters[ref.URI]
+                if !ok {
+                        for _, tmpl := range listResourceTemplates() {
+                                if tmpl.URITemplate == ref.URI {
+                                        return nil, nil
+                                }
+                        }
+                        return nil, fmt.Errorf("Unknown resource template: %s", ref.URI)
+                }
+                return variables[argument], nil
+        default:
+                return nil, fmt.Errorf("Unknown reference type: %s", ref.Type)
+        }
+}
+
+func handleComplete(ctx context.Context, req Request) Response {
+        var params CompleteParams
+        if err := json.Unmarshal(req.Params, &params); err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        fn, err := findCompleter(params.Ref, params.Argument.Name)
+        if err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: err.Error(),
+                        },
+                }
+        }
+
+        // Arguments without a completer are valid but have no suggestions.
+        var candidates []string
+        if fn != nil {
+                candidates = fn(ctx)
+        }
+
+        return Response{
+                ID:     req.ID,
+                Result: CompleteResult{Completion: complete(candidates, params.Argument.Value)},
+        }
+}
@@ -0,0 +1,434 @@
+package mcp
+
+import (
+        "context"
+        "crypto/rand"
+        "encoding/hex"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "log/slog"
+        "sync"
+        "sync/atomic"
+        "time"
+)
+
+// Notification is a one-way message sent from the server to a client.
+type Notification struct {
+        Method string      `json:"method"`
+        Params interface{} `json:"params,omitempty"`
+}
+
+// MarshalJSON adds the "jsonrpc" member, like Response.MarshalJSON.
+func (n Notification) MarshalJSON() ([]byte, error) {
+        type notification Notification
+        return marshalPooled(&struct {
+                JSONRPC string `json:"jsonrpc"`
+                notification
+        }{jsonRPCVersion, notification(n)})
+}
+
+// ServerRequest is a request sent from the server to a client, such as
+// sampling/createMessage.
+type ServerRequest struct {
+        ID     string      `json:"id"`
+        Method string      `json:"method"`
+        Params interface{} `json:"params,omitempty"`
+}
+
+// MarshalJSON adds the "jsonrpc" member, like Response.MarshalJSON.
+func (r ServerRequest) MarshalJSON() ([]byte, error) {
+        type serverRequest ServerRequest
+        return marshalPooled(&struct {
+                JSONRPC string `json:"jsonrpc"`
+                serverRequest
+        }{jsonRPCVersion, serverRequest(r)})
+}
+
+// ClientResponse is a client's reply to a ServerRequest.
+type ClientResponse struct {
+        ID     string          `json:"id"`
+        Result json.RawMessage `json:"result,omitempty"`
+        Error  *Error          `json:"error,omitempty"`
+}
+
+// IncomingMessage is any frame read from a client. Frames with a method are
+// requests or notifications; frames without one are responses to requests
+// the server sent.
+type IncomingMessage struct {
+        Request
+        Result json.RawMessage `json:"result,omitempty"`
+        Error  *Error          `json:"error,omitempty"`
+}
+
+func (m IncomingMessage) IsResponse() bool {
+        return m.Method == "" && (m.Result != nil || m.Error != nil)
+}
+
+var ErrClientDisconnected = errors.New("client disconnected")
+
+// Transport carries a connection's messages to its client.
+type Transport interface {
+        // Deliver sends data, the encoding of v, to the client. When the
+        // transport is behind, it waits for room if wait is set and fails
+        // with ErrOutboxFull otherwise.
+        Deliver(v interface{}, data []byte, wait bool) error
+        // Shutdown tells the client the server is going away.
+        Shutdown()
+        // Shared reports whether the session is kept in the cluster's
+        // shared store, where other replicas may serve it too.
+        Shared() bool
+}
+
+// Conn is the server-side state of one connected client, whichever
+// transport it arrived on.
+type Conn struct {
+        transport Transport
+
+        Session *Session
+        // group is that of the server the client connected to.
+        group *Group
+        // scopes are those the -rbac policy grants the client; nil when no
+        // policy is loaded.
+        scopes scopeSet
+
+        limiter atomic.Pointer[connLimiter]
+        // override is the -client-overrides entry for the client's name,
+        // if any, set at initialize.
+        override atomic.Pointer[ClientOverride]
+
+        // id distinguishes the connection in logs.
+        id          string
+        logger      *slog.Logger
+        connectedAt time.Time
+        closeOnce   sync.Once
+        // gone is set once the connection is recorded as closed.
+        gone atomic.Bool
+        // initialized is set once initialize has succeeded, which it may
+        // only do once.
+        initialized atomic.Bool
+        // removed is set by RemoveClient, before it drops the connection's
+        // resource subscriptions, so none can be added after.
+        removed atomic.Bool
+
+        // lastActivity is when the client last sent a message, in Unix
+        // nanoseconds; inFlight counts its requests queued or running.
+        lastActivity atomic.Int64
+        inFlight     atomic.Int32
+        // toolWorkers holds the connection's slots under
+        // SessionToolWorkers.
+        toolWorkers workerPool
+
+        // ctx is cancelled when the client disconnects.
+        ctx    context.Context
+        cancel context.CancelFunc
+
+        // requests holds requests for the workers.
+        requests chan queuedRequest
+        // handler answers the requests; nil means DefaultMux.
+        requestHandler Handler
+        hooks          Hooks
+        // recorder records the connection's messages with -record-dir.
+        recorder *recorder
+
+        mu            sync.Mutex
+        capabilities  map[string]interface{}
+        roots         []string
+        logLevel      logLevel
+        logEnabled    bool
+        pending       map[string]chan ClientResponse
+        running       map[string]*runningRequest
+        nextRequestID atomic.Int64
+}
+
+// NewConn returns the connection of a client that reached the server
+// from remoteAddr, whose messages t carries. Its context derives from ctx,
+// so values the server put there reach every handler, and its logger from
+// logger.
+func NewConn(ctx context.Context, logger *slog.Logger, s *Session, remoteAddr string, t Transport) *Conn {
+        ctx, cancel := context.WithCancel(ctx)
+        id := NewConnID()
+        c := &Conn{
+                transport:   t,
+                id:          id,
+                Session:     s,
+                group:       GroupFrom(ctx),
+                connectedAt: time.Now(),
+                logger:      logger.With("conn", id, "session", s.ID, "remote", remoteAddr),
+                ctx:         ctx,
+                cancel:      cancel,
+                pending:     make(map[string]chan ClientResponse),
+                running:     make(map[string]*runningRequest),
+                requests:    make(chan queuedRequest, max(RequestWorkers, MaxInFlight)),
+        }
+        c.ResetLimiter()
+        c.startRecording()
+        return c
+}
+
+// Close cancels the connection context, which fails any server-initiated
+// requests still waiting for a reply, and ends its recording.
+func (c *Conn) Close() {
+        c.cancel()
+        c.stopRecording()
+}
+
+// Causes of a connection closing, as reported in logs and metrics.
+const (
+        CloseByClient      = "client_close"
+        CloseReadError     = "read_error"
+        CloseWriteError    = "write_error"
+        CloseIdleTimeout   = "idle_timeout"
+        CloseDeadPeer      = "heartbeat_timeout"
+        CloseMessageTooBig = "message_too_big"
+        // CloseInitializeFailed ends a Streamable HTTP session whose
+        // initialize request failed.
+        CloseInitializeFailed = "initialize_failed"
+        closeServerShutdown   = "server_shutdown"
+)
+
+// Connected records that c was accepted.
+func (c *Conn) Connected() {
+        connectionsOpened.Add(1)
+        c.logger.Info("Client connected", "event", "connection.opened")
+}
+
+// Disconnected records why c closed and how long it was open. Only the
+// first call counts, so a connection closed at shutdown isn't also counted
+// when its read loop fails.
+func (c *Conn) Disconnected(cause string) {
+        c.closeOnce.Do(func() {
+                c.gone.Store(true)
+                lifetime := time.Since(c.connectedAt)
+                connectionsClosed.Add(1, cause)
+                connectionDuration.observe(lifetime.Seconds())
+                c.logger.Info("Client disconnected", "event", "connection.closed", "cause", cause, "duration", lifetime)
+                if c.hooks.OnDisconnect != nil {
+                        c.hooks.OnDisconnect(c, cause)
+                }
+        })
+}
+
+// Shutdown tells the client the server is going away and records the
+// connection as closed by the shutdown.
+func (c *Conn) Shutdown() {
+        c.Disconnected(closeServerShutdown)
+        c.transport.Shutdown()
+}
+
+// Touch records that the client sent a message, which resets its idle
+// timer.
+func (c *Conn) Touch() {
+        c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// LastSeen is when the client last sent a message.
+func (c *Conn) LastSeen() time.Time {
+        return time.Unix(0, c.lastActivity.Load())
+}
+
+// Busy reports whether any of the client's requests is queued or running.
+func (c *Conn) Busy() bool {
+        return c.inFlight.Load() > 0
+}
+
+// SetHandler makes h answer the client's requests in place of DefaultMux.
+// Call it before the connection handles any message.
+func (c *Conn) SetHandler(h Handler) {
+        c.requestHandler = h
+}
+
+func (c *Conn) handler() Handler {
+        if c.requestHandler != nil {
+                return c.requestHandler
+        }
+        return DefaultMux
+}
+
+// Closed reports whether the connection has been recorded as closed.
+func (c *Conn) Closed() bool {
+        return c.gone.Load()
+}
+
+// Group returns the group of the server the client connected to, or
+// DefaultGroup for a connection made without NewConn.
+func (c *Conn) Group() *Group {
+        if c.group == nil {
+                return DefaultGroup
+        }
+        return c.group
+}
+
+// Context returns the connection's context, which is cancelled when the
+// client disconnects.
+func (c *Conn) Context() context.Context {
+        return c.ctx
+}
+
+// Logger returns the logger for the connection's own events, tagged with
+// the connection, session, and client identity.
+func (c *Conn) Logger() *slog.Logger {
+        return c.logger
+}
+
+// NewConnID returns a random connection ID, unique across restarts and
+// replicas so logs from several servers can be merged.
+func NewConnID() string {
+        var b [6]byte
+        rand.Read(b[:])
+        return hex.EncodeToString(b[:])
+}
+
+type connContextKey struct{}
+
+func contextWithConn(ctx context.Context, c *Conn) context.Context {
+        return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// ConnFromContext returns the connection a request or tool call arrived
+// on.
+func ConnFromContext(ctx context.Context) (*Conn, bool) {
+        c, ok := ctx.Value(connContextKey{}).(*Conn)
+        return c, ok
+}
+
+// claimsFromContext returns the access token claims of the client a tool
+// call arrived on, for handlers that make authorization decisions. ok is
+// false if the client didn't authenticate with a token.
+func claimsFromContext(ctx context.Context) (claims map[string]interface{}, ok bool) {
+        s, ok := sessionFromContext(ctx)
+        if !ok || s.claims == nil {
+                return nil, false
+        }
+        return s.claims, true
+}
+
+func (c *Conn) SetCapabilities(capabilities map[string]interface{}) {
+        c.mu.Lock()
+        c.capabilities = capabilities
+        c.mu.Unlock()
+}
+
+// ClientCapabilities returns the capabilities the client declared during
+// initialize.
+func (c *Conn) ClientCapabilities() map[string]interface{} {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        return c.capabilities
+}
+
+// supports reports whether the client declared capability during initialize.
+func (c *Conn) supports(capability string) bool {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        _, ok := c.capabilities[capability]
+        return ok
+}
+
+// request sends a server-initiated request and waits for the client's reply
+// until ctx is done or the client disconnects.
+func (c *Conn) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+        // The connection ID keeps IDs unique across the replicas serving a
+        // shared session, so a reply reaches the one that asked.
+        id := fmt.Sprintf("srv-%s-%d", c.id, c.nextRequestID.Add(1))
+        ch := make(chan ClientResponse, 1)
+
+        c.mu.Lock()
+        c.pending[id] = ch
+        c.mu.Unlock()
+        defer func() {
+                c.mu.Lock()
+                delete(c.pending, id)
+                c.mu.Unlock()
+        }()
+
+        if err := c.send(ServerRequest{ID: id, Method: method, Params: params}); err != nil {
+                return nil, err
+        }
+
+        select {
+        case resp := <-ch:
+                if resp.Error != nil {
+                        return nil, fmt.Errorf("%s failed: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+                }
+                return resp.Result, nil
+        case <-ctx.Done():
+                return nil, ctx.Err()
+        case <-c.ctx.Done():
+                return nil, ErrClientDisconnected
+        }
+}
+
+// DeliverResponse hands a client's reply to the request waiting for it.
+// Replies to unknown or abandoned requests are dropped, as are repeated
+// replies: the first one takes the request off the pending list, so a
+// client can't block the connection by answering twice.
+func (c *Conn) DeliverResponse(resp ClientResponse) bool {
+        c.mu.Lock()
+        ch, ok := c.pending[resp.ID]
+        delete(c.pending, resp.ID)
+        c.mu.Unlock()
+        if ok {
+                ch <- resp
+        }
+        return ok
+}
+
+func (c *Conn) send(v interface{}) error {
+        data, err := encodeMessage(v)
+        if err != nil {
+                return err
+        }
+        return c.deliver(v, data, true)
+}
+
+// encodeMessage returns the encoding of a message to a client. Messages
+// that encode themselves are asked directly, since json.Marshal would scan
+// and copy what they return once more.
+func encodeMessage(v interface{}) ([]byte, error) {
+        if m, ok := v.(json.Marshaler); ok {
+                return m.MarshalJSON()
+        }
+        return json.Marshal(v)
+}
+
+// deliver hands data, the encoding of v, to the client's transport. When
+// the transport is behind, it waits for room if wait is set and fails with
+// ErrOutboxFull otherwise.
+func (c *Conn) deliver(v interface{}, data []byte, wait bool) error {
+        c.LogPayload("out", data)
+        return c.transport.Deliver(v, data, wait)
+}
+
+func (c *Conn) notify(method string, params interface{}) error {
+        return c.send(Notification{Method: method, Params: params})
+}
+
+// AddClient adds c to the clients of its group.
+func AddClient(c *Conn) {
+        g := c.Group()
+        g.clientsMu.Lock()
+        g.clients[c] = true
+        g.clientsMu.Unlock()
+}
+
+// RemoveClient forgets c, along with its resource subscriptions.
+func RemoveClient(c *Conn) {
+        g := c.Group()
+        g.clientsMu.Lock()
+        delete(g.clients, c)
+        g.clientsMu.Unlock()
+        c.removed.Store(true)
+        if n := subscriptions.drop(c); n > 0 {
+                c.logger.Debug("Dropped resource subscriptions", "count", n)
+        }
+}
+
+// ConnectedClients returns the clients of every group in the process.
+func ConnectedClients() []*Conn {
+        var list []*Conn
+        for _, g := range allGroups() {
+                list = append(list, g.Clients()...)
+        }
+        return list
+}
@@ -0,0 +1,298 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "strings"
+        "time"
+
+        "go.opentelemetry.io/otel/trace"
+)
+
+// Handle acts on a message from the client, whichever transport it
+// arrived on: it delivers responses, handles notifications, and queues
+// requests for the workers. size is the message's length in bytes.
+func (c *Conn) Handle(msg IncomingMessage, size int) {
+        if msg.IsResponse() {
+                resp := ClientResponse{ID: msg.ID, Result: msg.Result, Error: msg.Error}
+                switch {
+                case c.DeliverResponse(resp):
+                case c.transport.Shared():
+                        // The request may have come from another replica.
+                        cluster.RelayResponse(c.Session.ID, resp)
+                default:
+                        c.Logf(c.ctx, LevelWarning, "Dropping response to unknown request id=%s", msg.ID)
+                }
+                return
+        }
+
+        req := msg.Request
+        if strings.HasPrefix(req.Method, "notifications/") {
+                handleNotification(c, req)
+                return
+        }
+
+        logger := c.logger.With("request_id", req.ID, "method", req.Method)
+        if _, info := c.Session.Negotiated(); info.Name != "" {
+                logger = logger.With("client", info.Name)
+        }
+        ctx := contextWithLogger(c.ctx, logger)
+        c.Logf(ctx, LevelDebug, "Received request")
+
+        if rateErr := c.checkRateLimit(req.Method); rateErr != nil {
+                c.Logf(ctx, LevelWarning, "%s", rateErr.Message)
+                c.send(Response{ID: req.ID, Error: rateErr})
+                return
+        }
+
+        if limit := c.Group().Settings().MaxInFlight; limit > 0 && c.inFlight.Load() >= int32(limit) {
+                c.Logf(ctx, LevelWarning, "Rejecting request: %d already in progress", limit)
+                c.send(Response{ID: req.ID, Error: serverBusyError(limit)})
+                return
+        }
+        c.inFlight.Add(1)
+
+        ctx, span := startRequestSpan(ctx, c, req, size)
+        ctx = c.startRequest(ctx, req)
+        if req.Method == "initialize" {
+                // Set the session up before anything else runs.
+                respond(ctx, span, c, req)
+                return
+        }
+        c.dispatch(ctx, span, req)
+}
+
+// Authorize records who the client authenticated as and grants it the
+// scopes its group's RBAC policy allows.
+func (c *Conn) Authorize(p Principal) {
+        if p.Identity != "" {
+                c.logger = c.logger.With("identity", p.Identity)
+        }
+        if policy := c.Group().Settings().RBAC; policy != nil {
+                c.scopes = policy.scopesFor(p)
+                c.Logf(c.ctx, LevelDebug, "Granted scopes: %s", c.scopes)
+        }
+}
+
+func handleNotification(c *Conn, req Request) {
+        c.Logf(c.ctx, LevelDebug, "Received notification: method=%s", req.Method)
+
+        switch req.Method {
+        case "notifications/initialized", "notifications/roots/list_changed":
+                go c.refreshRoots()
+        case "notifications/cancelled":
+                handleCancelled(c, req)
+        }
+}
+
+// respond handles req and sends the response, ending span, the request's
+// trace span, once it is sent.
+func respond(ctx context.Context, span trace.Span, c *Conn, req Request) error {
+        defer c.inFlight.Add(-1)
+        start := time.Now()
+        response := handleRequest(ctx, c, req)
+        timedOut := ctx.Err() == context.DeadlineExceeded
+        if c.endRequest(ctx, req.ID) {
+                // The client has given up on the request and expects no
+                // response.
+                c.Logf(ctx, LevelDebug, "Dropping response to cancelled request")
+                span.End()
+                return nil
+        }
+        if timedOut && response.Error != nil {
+                // The handler gave up because it ran out of time.
+                response = Response{ID: req.ID, Error: timeoutError(c.Group().Settings().RequestTimeout, response.Error)}
+        }
+        defer endRequestSpan(span, response)
+
+        if err := c.send(response); err != nil {
+                RecordSpanError(span, err)
+                return err
+        }
+
+        recordRequest(req.Method, c.clientLabel(), time.Since(start), response)
+        logRequest(ctx, c, start, response)
+        return nil
+}
+
+// handleRequest answers req with the connection's handler, as if the
+// method were unknown when its capability group is turned off.
+func handleRequest(ctx context.Context, c *Conn, req Request) Response {
+        if !methodEnabled(req.Method) {
+                return methodNotFound(req)
+        }
+        return c.handler().Handle(contextWithConn(ctx, c), req)
+}
+
+func init() {
+        DefaultMux.Register("initialize", connHandler(handleInitialize))
+        DefaultMux.RegisterFunc("ping", handlePing)
+        DefaultMux.Register("tools/list", connHandler(handleToolsList))
+        DefaultMux.Register("tools/call", connHandler(handleToolCall))
+        DefaultMux.Register("resources/list", connHandler(handleResourcesList))
+        DefaultMux.RegisterFunc("resources/templates/list", handleResourceTemplatesList)
+        DefaultMux.Register("resources/read", connHandler(handleResourcesRead))
+        DefaultMux.Register("resources/subscribe", connHandler(func(ctx context.Context, c *Conn, req Request) Response {
+                return handleResourcesSubscribe(ctx, c, req, true)
+        }))
+        DefaultMux.Register("resources/unsubscribe", connHandler(func(ctx context.Context, c *Conn, req Request) Response {
+                return handleResourcesSubscribe(ctx, c, req, false)
+        }))
+        DefaultMux.RegisterFunc("prompts/list", handlePromptsList)
+        DefaultMux.Register("prompts/get", connHandler(handlePromptsGet))
+        DefaultMux.Register("logging/setLevel", connHandler(handleSetLevel))
+        DefaultMux.RegisterFunc("completion/complete", handleComplete)
+}
+
+func handleInitialize(ctx context.Context, c *Conn, req Request) Response {
+        var params InitializeParams
+        if len(req.Params) > 0 {
+                if err := json.Unmarshal(req.Params, &params); err != nil {
+                        return Response{
+                                ID: req.ID,
+                                Error: &Error{
+                                        Code:    -32602,
+                                        Message: "Invalid params",
+                                },
+                        }
+                }
+        }
+        if c.hooks.OnInitialize != nil {
+                if err := c.hooks.OnInitialize(ctx, c, params); err != nil {
+                        return Response{ID: req.ID, Error: ToError(err)}
+                }
+        }
+        if !c.initialized.CompareAndSwap(false, true) {
+                // Initializing again would pick another client override and
+                // refill the rate limit buckets.
+                return Response{ID: req.ID, Error: &Error{Code: -32600, Message: "Invalid Request: the connection is already initialized"}}
+        }
+        c.SetCapabilities(params.Capabilities)
+        version := negotiateProtocolVersion(params.ProtocolVersion)
+        c.Session.Initialized(version, params.ClientInfo)
+        c.ApplyClientOverride(params.ClientInfo)
+        if params.ClientInfo.Name != "" {
+                c.Logf(ctx, LevelInfo, "Initialized with protocol %s by %s %s", version, params.ClientInfo.Name, params.ClientInfo.Version)
+        } else {
+                c.Logf(ctx, LevelInfo, "Initialized with protocol %s", version)
+        }
+
+        return Response{
+                ID: req.ID,
+                Result: InitializeResult{
+                        ProtocolVersion: version,
+                        ServerInfo:      serverInfo,
+                        Capabilities:    serverCapabilities(ctx),
+                },
+        }
+}
+
+// handlePing answers ping, which either side may send to check that the
+// other is still there.
+func handlePing(_ context.Context, req Request) Response {
+        return Response{ID: req.ID, Result: EmptyResult{}}
+}
+
+// handleToolsList answers tools/list from the list cache, keyed by which
+// of the registered tools c may see.
+func handleToolsList(_ context.Context, c *Conn, req Request) Response {
+        registered := registeredTools()
+        visible := make([]*Tool, 0, len(registered))
+        key := make([]byte, len(registered))
+        for i, t := range registered {
+                key[i] = '0'
+                if c.canUseTool(t) && !c.hidesTool(t.Name) {
+                        key[i] = '1'
+                        visible = append(visible, t)
+                }
+        }
+        return cachedListResponse(req, "tools/list?"+string(key), func() (interface{}, *Response) {
+                tools := make([]toolListing, 0, len(visible))
+                for _, t := range visible {
+                        schema, err := t.encodedSchema()
+                        if err != nil {
+                                return nil, &Response{ID: req.ID, Error: ToError(Errorf(ErrInternal, "Encoding the input schema of %s: %v", t.Name, err))}
+                        }
+                        tools = append(tools, toolListing{
+                                Name:        t.Name,
+                                Description: t.Description,
+                                InputSchema: schema,
+                        })
+                }
+                return listToolsResult{Tools: tools}, nil
+        })
+}
+
+func handleToolCall(ctx context.Context, c *Conn, req Request) Response {
+        var params ToolCallParams
+        if err := json.Unmarshal(req.Params, &params); err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        t, ok := findTool(params.Name)
+        if !ok || c.hidesTool(t.Name) {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: fmt.Sprintf("Unknown tool: %s", params.Name),
+                        },
+                }
+        }
+        if !c.canUseTool(t) {
+                c.Logf(ctx, LevelWarning, "Denied call to %s", t.Name)
+                return Response{ID: req.ID, Error: toolPermissionError(c, t)}
+        }
+
+        if params.Arguments == nil {
+                params.Arguments = map[string]interface{}{}
+        }
+        if c.hooks.OnToolCall != nil {
+                if err := c.hooks.OnToolCall(ctx, c, params); err != nil {
+                        return Response{ID: req.ID, Error: ToError(err)}
+                }
+        }
+        release, err := c.acquireToolWorker(ctx)
+        if err != nil {
+                return Response{ID: req.ID, Error: ToError(err)}
+        }
+        defer release()
+        ctx = contextWithLogger(ctx, loggerFrom(ctx, c.logger).With("tool", t.Name))
+        ctx, span := startToolSpan(ctx, t.Name)
+        start := time.Now()
+        output, err := t.Handler(ctx, params.Arguments)
+        var result CallToolResult
+        if err == nil {
+                result, err = toolResult(output)
+        }
+        mcpErr := ToError(err)
+        recordToolCall(t.Name, c.clientLabel(), time.Since(start), mcpErr)
+        endToolSpan(span, mcpErr)
+        if mcpErr != nil {
+                return Response{
+                        ID:    req.ID,
+                        Error: mcpErr,
+                }
+        }
+        return Response{
+                ID:     req.ID,
+                Result: result,
+        }
+}
+
+func SendError(c *Conn, id string, code int, message string) {
+        c.send(Response{
+                ID: id,
+                Error: &Error{
+                        Code:    code,
+                        Message: message,
+                },
+        })
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "strings"
+        "time"
+)
+
+// elicitationTimeout bounds how long a tool waits for the user to answer an
+// elicitation. It is generous because a person is filling in a form.
+const elicitationTimeout = 5 * time.Minute
+
+var errElicitationUnsupported = errors.New("Client does not support elicitation")
+
+type ElicitParams struct {
+        Message string `json:"message"`
+        // RequestedSchema is a flat JSON Schema object whose properties are
+        // primitive types, as required by the MCP specification.
+        RequestedSchema map[string]interface{} `json:"requestedSchema"`
+}
+
+type ElicitResult struct {
+        Action  string                 `json:"action"`
+        Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// elicit asks the client to collect input from the user via
+// elicitation/create.
+func (c *Conn) elicit(ctx context.Context, params ElicitParams) (*ElicitResult, error) {
+        if !c.supports("elicitation") {
+                return nil, errElicitationUnsupported
+        }
+
+        ctx, cancel := context.WithTimeout(ctx, elicitationTimeout)
+        defer cancel()
+
+        raw, err := c.request(ctx, "elicitation/create", params)
+        if err != nil {
+                if errors.Is(err, context.DeadlineExceeded) {
+                        return nil, fmt.Errorf("elicitation/create timed out after %s", elicitationTimeout)
+                }
+                return nil, err
+        }
+
+        var result ElicitResult
+        if err := json.Unmarshal(raw, &result); err != nil {
+                return nil, fmt.Errorf("invalid elicitation/create result: %v", err)
+        }
+        return &result, nil
+}
+
+// ElicitMissingArgs fills in required tool arguments that are absent from
+// args by asking the user through the client. properties holds the JSON
+// Schema of each field that may be requested; when a required field is
+// missing, any other absent fields from properties are asked for as well,
+// as optional fields. When the client cannot elicit or the user declines,
+// the missing required arguments are reported as an error.
+func ElicitMissingArgs(ctx context.Context, args map[string]interface{}, message string, properties map[string]interface{}, required []string) error {
+        var missing []string
+        for _, name := range required {
+                if v, ok := args[name]; !ok || v == "" {
+                        missing = append(missing, name)
+                }
+        }
+        if len(missing) == 0 {
+                return nil
+        }
+
+        missingErr := Errorf(ErrInvalidArgument, "Missing required argument(s): %s", strings.Join(missing, ", "))
+
+        c, ok := ConnFromContext(ctx)
+        if !ok || !c.supports("elicitation") {
+                return missingErr
+        }
+
+        requested := map[string]interface{}{}
+        for name, schema := range properties {
+                if v, ok := args[name]; !ok || v == "" {
+                        requested[name] = schema
+                }
+        }
+        result, err := c.elicit(ctx, ElicitParams{
+                Message: message,
+                RequestedSchema: map[string]interface{}{
+                        "type":       "object",
+                        "properties": requested,
+                        "required":   missing,
+                },
+        })
+        if err != nil {
+                return err
+        }
+        if result.Action != "accept" {
+                return Errorf(ErrInvalidArgument, "User did not provide %s (%s)", strings.Join(missing, ", "), result.Action)
+        }
+
+        for name := range requested {
+                if v, ok := result.Content[name]; ok && v != "" {
+                        args[name] = v
+                }
+        }
+        for _, name := range missing {
+                if v, ok := args[name]; !ok || v == "" {
+                        return missingErr
+                }
+        }
+        return nil
+}
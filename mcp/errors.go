@@ -0,0 +1,83 @@
+package mcp
+
+import (
+        "errors"
+        "fmt"
+)
+
+// Error is itself an error, so a handler that needs a particular code or
+// data can return one directly.
+func (e *Error) Error() string {
+        return e.Message
+}
+
+// The kinds of failure a handler can report with a plain Go error. Return
+// one wrapped by Errorf, or wrap one with fmt.Errorf's %w, and ToError
+// answers the client with the kind's code and the error's message.
+var (
+        // ErrInvalidArgument is for missing or malformed arguments.
+        ErrInvalidArgument = errors.New("invalid argument")
+        // ErrNotFound is for arguments naming something that doesn't exist.
+        ErrNotFound = errors.New("not found")
+        // ErrPermissionDenied is for callers not allowed to do what they
+        // asked.
+        ErrPermissionDenied = errors.New("permission denied")
+        // ErrConflict is for changes based on state that has since changed.
+        ErrConflict = errors.New("conflict")
+        // ErrUnavailable is for failures of a service a handler depends on,
+        // such as an HTTP API or a command.
+        ErrUnavailable = errors.New("unavailable")
+        // ErrInternal is for failures of the server itself. Errors of no
+        // known kind are reported as internal too.
+        ErrInternal = errors.New("internal error")
+)
+
+// errorCodes maps each kind of error to its JSON-RPC code. Not-found
+// arguments are invalid params, as the MCP specification reports unknown
+// tools and prompts.
+var errorCodes = []struct {
+        kind error
+        code int
+}{
+        {ErrInvalidArgument, -32602},
+        {ErrNotFound, -32602},
+        {ErrPermissionDenied, -32011},
+        {ErrConflict, -32010},
+        {ErrUnavailable, -32000},
+        {ErrInternal, -32603},
+}
+
+// kindError is an error of a kind whose message is its own, without the
+// kind's name.
+type kindError struct {
+        kind    error
+        message string
+}
+
+func (e *kindError) Error() string { return e.message }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// Errorf returns an error of kind, one of the Err kinds above, with the
+// formatted message.
+func Errorf(kind error, format string, args ...interface{}) error {
+        return &kindError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// ToError returns the JSON-RPC error to answer a client with for err: an
+// *Error in err's chain as is, and otherwise err's message with the code
+// of its kind. It returns nil for a nil err.
+func ToError(err error) *Error {
+        if err == nil {
+                return nil
+        }
+        var mcpErr *Error
+        if errors.As(err, &mcpErr) {
+                return mcpErr
+        }
+        for _, c := range errorCodes {
+                if errors.Is(err, c.kind) {
+                        return &Error{Code: c.code, Message: err.Error()}
+                }
+        }
+        return &Error{Code: -32603, Message: err.Error()}
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+        "bytes"
+        "encoding/json"
+        "io"
+        "testing"
+)
+
+// jsonDepth returns the deepest nesting of objects and arrays in valid
+// JSON data.
+func jsonDepth(t *testing.T, data []byte) int {
+        decoder := json.NewDecoder(bytes.NewReader(data))
+        depth, deepest := 0, 0
+        for {
+                token, err := decoder.Token()
+                if err == io.EOF {
+                        return deepest
+                }
+                if err != nil {
+                        t.Fatalf("decoding valid JSON %q: %v", data, err)
+                }
+                switch token {
+                case json.Delim('{'), json.Delim('['):
+                        depth++
+                        deepest = max(deepest, depth)
+                case json.Delim('}'), json.Delim(']'):
+                        depth--
+                }
+        }
+}
+
+func FuzzJSONDepthExceeds(f *testing.F) {
+        for _, seed := range []string{`{}`, `[[["a"]]]`, `{"a":"[[[[","b":{"c":[]}}`, `"\"{"`, `[{"\\":"}"}]`, `{[`} {
+                f.Add([]byte(seed), 2)
+        }
+        f.Fuzz(func(t *testing.T, data []byte, limit int) {
+                if limit < 0 {
+                        // -max-json-depth can't be negative.
+                        return
+                }
+                exceeds := JSONDepthExceeds(data, limit)
+                if !json.Valid(data) {
+                        return
+                }
+                if depth := jsonDepth(t, data); exceeds != (depth > limit) {
+                        t.Errorf("JSONDepthExceeds(%q, %d) = %v, but the depth is %d", data, limit, exceeds, depth)
+                }
+        })
+}
+
+// FuzzIncomingMessage checks that any message that decodes is classified
+// consistently.
+func FuzzIncomingMessage(f *testing.F) {
+        for _, seed := range []string{
+                `{"jsonrpc":"2.0","id":"1","method":"tools/call","params":{"name":"x"}}`,
+                `{"id":"1","result":null}`,
+                `{"id":"1","error":{"code":-32601,"message":"Method not found"}}`,
+                `{"method":"notifications/initialized"}`,
+                `{"id":1,"method":2}`,
+        } {
+                f.Add([]byte(seed))
+        }
+        f.Fuzz(func(t *testing.T, data []byte) {
+                var msg IncomingMessage
+                if json.Unmarshal(data, &msg) != nil {
+                        return
+                }
+                if msg.IsResponse() && msg.Method != "" {
+                        t.Errorf("%q: a response with method %q", data, msg.Method)
+                }
+                if _, err := json.Marshal(Response{ID: msg.ID, Error: msg.Error}); err != nil {
+                        t.Errorf("%q: echoing the error: %v", data, err)
+                }
+        })
+}
+
+// FuzzArgs checks the argument helpers tool handlers use on any arguments
+// a client can send.
+func FuzzArgs(f *testing.F) {
+        for _, seed := range []string{
+                `{"s":"x","n":1.5,"i":3,"b":true,"z":null}`,
+                `{"s":["a","b"],"n":[1,"a"],"i":{"x":1}}`,
+                `{"s":1e300,"n":-0,"i":9007199254740993}`,
+        } {
+                f.Add([]byte(seed))
+        }
+        f.Fuzz(func(t *testing.T, data []byte) {
+                var args map[string]interface{}
+                if json.Unmarshal(data, &args) != nil {
+                        return
+                }
+                for name, value := range args {
+                        StringArg(args, name)
+                        JSONString(value)
+                        values, ok := StringsArg(args, name)
+                        if !ok && values != nil {
+                                t.Errorf("StringsArg(%s) returned values for an invalid argument", name)
+                        }
+                        if items, isArray := value.([]interface{}); ok && isArray && len(values) != len(items) {
+                                t.Errorf("StringsArg(%s) = %d values from %d items", name, len(values), len(items))
+                        }
+                }
+                checkRequired(args, []string{"s", "n", "missing"})
+        })
+}
@@ -0,0 +1,190 @@
+package mcp
+
+import (
+        "context"
+        "fmt"
+        "log/slog"
+        "net/http"
+        "sync"
+        "sync/atomic"
+        "time"
+)
+
+// Settings are the limits and policy a Group applies to its connections.
+// Zero means no limit.
+type Settings struct {
+        // MaxConnections caps connected clients; further upgrade requests
+        // get 503 Service Unavailable.
+        MaxConnections int
+        // MaxInFlight caps each connection's queued and running requests;
+        // further requests fail with a "server busy" error. It must be at
+        // least 1.
+        MaxInFlight int
+        // RequestTimeout bounds how long each request may run. Handlers see
+        // it as their context's deadline.
+        RequestTimeout time.Duration
+        // ToolWorkers caps tool calls running at once across the group, and
+        // SessionToolWorkers those on each connection.
+        ToolWorkers        int
+        SessionToolWorkers int
+        // RateLimits limit the requests and tool calls of each connection
+        // and identity.
+        RateLimits RateLimits
+        // RBAC grants clients their scopes; nil lets every client see and
+        // call every tool.
+        RBAC *RBACPolicy
+}
+
+// DefaultSettings returns the settings held in package variables, which
+// flags set.
+func DefaultSettings() Settings {
+        return Settings{
+                MaxConnections:     MaxConnections,
+                MaxInFlight:        MaxInFlight,
+                RequestTimeout:     RequestTimeout,
+                ToolWorkers:        ToolWorkers,
+                SessionToolWorkers: SessionToolWorkers,
+                RateLimits: RateLimits{
+                        ConnRequests:     ConnRequestLimit,
+                        ConnTools:        ConnToolLimit,
+                        IdentityRequests: IdentityRequestLimit,
+                        IdentityTools:    IdentityToolLimit,
+                },
+                RBAC: RBAC,
+        }
+}
+
+// A Group is the connections of one server. They share the group's
+// settings, connection and tool call limits, identity rate limits, and
+// resource read cache, and notifications about the server's store reach
+// only them, so servers in one process neither limit nor see each other's
+// clients. A connection whose context carries no group is in DefaultGroup.
+type Group struct {
+        // settings are the group's own, or nil to follow DefaultSettings.
+        settings atomic.Pointer[Settings]
+
+        readCache *resourceCache
+
+        clientsMu sync.Mutex
+        clients   map[*Conn]bool
+
+        // connections counts connections holding a slot under
+        // MaxConnections, including those still upgrading.
+        connections atomic.Int64
+        // toolWorkers holds the slots under ToolWorkers.
+        toolWorkers workerPool
+        // identityBuckets holds the buckets shared by all of an identity's
+        // connections.
+        identityBuckets bucketTable
+}
+
+// groups are every group made, for changes another replica reports, which
+// don't say whose store they were made in.
+var groups struct {
+        sync.Mutex
+        list []*Group
+}
+
+// NewGroup returns an empty group with settings s.
+func NewGroup(s Settings) *Group {
+        g := newGroup()
+        g.settings.Store(&s)
+        return g
+}
+
+func newGroup() *Group {
+        g := &Group{readCache: newResourceCache(resourceCacheTTL), clients: make(map[*Conn]bool)}
+        groups.Lock()
+        groups.list = append(groups.list, g)
+        groups.Unlock()
+        return g
+}
+
+// DefaultGroup holds the connections of servers that don't have their
+// own group. Its settings are DefaultSettings until set otherwise.
+var DefaultGroup = newGroup()
+
+// Settings returns the settings in force.
+func (g *Group) Settings() Settings {
+        if s := g.settings.Load(); s != nil {
+                return *s
+        }
+        return DefaultSettings()
+}
+
+// SetRateLimits puts limits into force for new connections. Identity
+// buckets are dropped so they refill at the new rates; connected clients
+// are left to the caller.
+func (g *Group) SetRateLimits(limits RateLimits) {
+        s := g.Settings()
+        s.RateLimits = limits
+        g.settings.Store(&s)
+        g.identityBuckets.clear()
+}
+
+// Clients returns the group's connected clients.
+func (g *Group) Clients() []*Conn {
+        g.clientsMu.Lock()
+        defer g.clientsMu.Unlock()
+        list := make([]*Conn, 0, len(g.clients))
+        for c := range g.clients {
+                list = append(list, c)
+        }
+        return list
+}
+
+// AcquireConnectionSlot reserves room for a new connection, or reports
+// false if the group is full. A reserved slot must be released.
+func (g *Group) AcquireConnectionSlot() bool {
+        limit := g.Settings().MaxConnections
+        if n := g.connections.Add(1); limit > 0 && n > int64(limit) {
+                g.connections.Add(-1)
+                return false
+        }
+        return true
+}
+
+// ReleaseConnectionSlot frees a slot AcquireConnectionSlot reserved.
+func (g *Group) ReleaseConnectionSlot() {
+        g.connections.Add(-1)
+}
+
+// RejectBusy refuses a connection because the group is full.
+func (g *Group) RejectBusy(w http.ResponseWriter, r *http.Request) {
+        ConnectionsRejected.Add(1, "busy")
+        slog.Warn(fmt.Sprintf("Rejected connection: %d clients already connected", g.Settings().MaxConnections), "remote", r.RemoteAddr)
+        w.Header().Set("Retry-After", "5")
+        http.Error(w, "Server busy: too many connections", http.StatusServiceUnavailable)
+}
+
+type groupContextKey struct{}
+
+// ContextWithGroup returns ctx for the connections of group g.
+func ContextWithGroup(ctx context.Context, g *Group) context.Context {
+        return context.WithValue(ctx, groupContextKey{}, g)
+}
+
+// GroupFrom returns the group ctx carries, or DefaultGroup.
+func GroupFrom(ctx context.Context) *Group {
+        if g, ok := ctx.Value(groupContextKey{}).(*Group); ok {
+                return g
+        }
+        return DefaultGroup
+}
+
+// groupsFor returns the group ctx carries, or every group if it carries
+// none, for records and notifications that may concern one server or the
+// whole process.
+func groupsFor(ctx context.Context) []*Group {
+        if g, ok := ctx.Value(groupContextKey{}).(*Group); ok {
+                return []*Group{g}
+        }
+        return allGroups()
+}
+
+// allGroups returns every group made.
+func allGroups() []*Group {
+        groups.Lock()
+        defer groups.Unlock()
+        return append([]*Group(nil), groups.list...)
+}
@@ -0,0 +1,107 @@
+package mcp
+
+import (
+        "context"
+        "fmt"
+        "sort"
+        "sync"
+)
+
+// Handler answers a request, as http.Handler answers an HTTP request. ctx
+// carries the connection the request arrived on (see ConnFromContext) and
+// is cancelled when the client disconnects.
+type Handler interface {
+        Handle(ctx context.Context, req Request) Response
+}
+
+// HandlerFunc lets an ordinary function serve as a Handler.
+type HandlerFunc func(ctx context.Context, req Request) Response
+
+func (f HandlerFunc) Handle(ctx context.Context, req Request) Response {
+        return f(ctx, req)
+}
+
+// Mux is a Handler that dispatches each request to the handler registered
+// for its method, answering methods without one with "Method not found".
+type Mux struct {
+        mu       sync.RWMutex
+        handlers map[string]Handler
+}
+
+// NewMux returns a mux with no methods registered.
+func NewMux() *Mux {
+        return &Mux{handlers: map[string]Handler{}}
+}
+
+// DefaultMux serves the built-in MCP methods, and is what connections use
+// unless their server was given a handler of its own.
+var DefaultMux = NewMux()
+
+// Register makes h handle method, replacing any handler already registered
+// for it. To wrap a registered handler, look it up with Handler first.
+func (m *Mux) Register(method string, h Handler) {
+        m.mu.Lock()
+        m.handlers[method] = h
+        m.mu.Unlock()
+}
+
+// RegisterFunc makes f handle method.
+func (m *Mux) RegisterFunc(method string, f func(ctx context.Context, req Request) Response) {
+        m.Register(method, HandlerFunc(f))
+}
+
+// Handler returns the handler registered for method.
+func (m *Mux) Handler(method string) (Handler, bool) {
+        m.mu.RLock()
+        defer m.mu.RUnlock()
+        h, ok := m.handlers[method]
+        return h, ok
+}
+
+// Methods returns the registered methods in sorted order.
+func (m *Mux) Methods() []string {
+        m.mu.RLock()
+        defer m.mu.RUnlock()
+        methods := make([]string, 0, len(m.handlers))
+        for method := range m.handlers {
+                methods = append(methods, method)
+        }
+        sort.Strings(methods)
+        return methods
+}
+
+func (m *Mux) Handle(ctx context.Context, req Request) Response {
+        h, ok := m.Handler(req.Method)
+        if !ok {
+                return methodNotFound(req)
+        }
+        return h.Handle(ctx, req)
+}
+
+func methodNotFound(req Request) Response {
+        return Response{
+                ID: req.ID,
+                Error: &Error{
+                        Code:    -32601,
+                        Message: fmt.Sprintf("Method not found: %s", req.Method),
+                },
+        }
+}
+
+// connHandler adapts a handler that needs the connection the request
+// arrived on.
+func connHandler(h func(ctx context.Context, c *Conn, req Request) Response) HandlerFunc {
+        return func(ctx context.Context, req Request) Response {
+                c, ok := ConnFromContext(ctx)
+                if !ok {
+                        return Response{
+                                ID: req.ID,
+                                Error: &Error{
+                                        Code:    -32603,
+                                        Message: fmt.Sprintf("%s needs a client connection", req.Method),
+                                },
+                        }
+                }
+                return h(ctx, c, req)
+        }
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import "context"
+
+// Hooks are callbacks a server runs at points in a connection's life, to
+// seed session state, turn clients away, or record telemetry of its own.
+// Any of them may be nil. A hook that returns an error stops what it was
+// called for, and the client is told why as ToError reports it.
+type Hooks struct {
+        // OnConnect runs when a client connects, before any of its messages
+        // are handled. An error refuses the connection.
+        OnConnect func(ctx context.Context, c *Conn) error
+        // OnInitialize runs on the client's initialize request, before the
+        // session is set up. An error fails the request.
+        OnInitialize func(ctx context.Context, c *Conn, params InitializeParams) error
+        // OnToolCall runs before each call of a tool the client may use.
+        // An error fails the call without running the tool.
+        OnToolCall func(ctx context.Context, c *Conn, params ToolCallParams) error
+        // OnDisconnect runs once when a connection OnConnect accepted
+        // closes, with why it closed.
+        OnDisconnect func(c *Conn, cause string)
+}
+
+// SetHooks makes c run h. Set them before the connection is admitted.
+func (c *Conn) SetHooks(h Hooks) {
+        c.hooks = h
+}
+
+// Admit runs the OnConnect hook, if any, and reports whether it refused
+// the connection.
+func (c *Conn) Admit() error {
+        if c.hooks.OnConnect == nil {
+                return nil
+        }
+        if err := c.hooks.OnConnect(c.ctx, c); err != nil {
+                ConnectionsRejected.Add(1, "refused")
+                c.logger.Info("Connection refused", "event", "connection.refused", "error", err)
+                return err
+        }
+        return nil
+}
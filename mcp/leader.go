@@ -0,0 +1,212 @@
+package mcp
+
+import (
+        "context"
+        "fmt"
+        "log/slog"
+        "strings"
+        "sync"
+        "sync/atomic"
+        "time"
+
+        "github.com/jackc/pgx/v5"
+        "github.com/redis/go-redis/v9"
+)
+
+// leaderTTL is how long a replica stays leader after it last renewed its
+// lock, and so how long the others wait before taking over from one that
+// died. Leaders renew every leaderRenewInterval.
+const (
+        leaderTTL            = 15 * time.Second
+        leaderRenewInterval  = 5 * time.Second
+        leaderLockKey        = "mcp:leader"
+        leaderAdvisoryLockID = 0x6d6370 // "mcp"
+)
+
+// leaderLock is held by at most one replica at a time.
+type leaderLock interface {
+        // acquire takes the lock, or keeps it if this replica already holds
+        // it, and reports whether this replica holds it now.
+        acquire(ctx context.Context) (bool, error)
+        // release gives the lock up so another replica can take it at once.
+        release(ctx context.Context) error
+}
+
+// Leader decides which replica runs the scheduled jobs. Without
+// -leader-election it has no lock and this replica always leads.
+var Leader = &leadership{}
+
+type leadership struct {
+        lock leaderLock
+        // leading is whether this replica held the lock when last checked.
+        leading atomic.Bool
+}
+
+func (l *leadership) isLeader() bool {
+        return l.lock == nil || l.leading.Load()
+}
+
+// newLeaderLock returns the lock described by dsn: a redis:// URL for a
+// key with a TTL, or a postgres:// URL for a session advisory lock.
+func newLeaderLock(dsn string) (leaderLock, error) {
+        switch {
+        case strings.HasPrefix(dsn, "redis://"), strings.HasPrefix(dsn, "rediss://"):
+                client, err := NewRedisClient(dsn)
+                if err != nil {
+                        return nil, err
+                }
+                return &redisLeaderLock{client: client, holder: NewConnID()}, nil
+        case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+                config, err := pgx.ParseConfig(dsn)
+                if err != nil {
+                        return nil, fmt.Errorf("parsing postgres DSN: %w", err)
+                }
+                return &postgresLeaderLock{config: config}, nil
+        }
+        return nil, fmt.Errorf("leader election needs a redis:// or postgres:// URL, not %q", dsn)
+}
+
+// Elect takes part in leader election through the lock described by dsn,
+// as newLeaderLock does.
+func (l *leadership) Elect(dsn string) error {
+        lock, err := newLeaderLock(dsn)
+        if err != nil {
+                return err
+        }
+        l.lock = lock
+        go l.campaign()
+        return nil
+}
+
+// campaign keeps trying to become leader, and to stay leader, until the
+// process exits.
+func (l *leadership) campaign() {
+        for {
+                ctx, cancel := context.WithTimeout(context.Background(), leaderRenewInterval)
+                leading, err := l.lock.acquire(ctx)
+                cancel()
+                if err != nil {
+                        slog.Warn("Leader election failed", "error", err)
+                }
+                if was := l.leading.Swap(leading); was != leading {
+                        if leading {
+                                slog.Info("Became leader; running scheduled jobs")
+                        } else {
+                                slog.Info("No longer leader; scheduled jobs run on another replica")
+                        }
+                }
+                time.Sleep(leaderRenewInterval)
+        }
+}
+
+// Resign gives up leadership, if held, at shutdown.
+func (l *leadership) Resign() {
+        if l.lock == nil || !l.leading.Swap(false) {
+                return
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+        defer cancel()
+        if err := l.lock.release(ctx); err != nil {
+                slog.Warn("Releasing leadership", "error", err)
+        }
+}
+
+// RunScheduled runs job every interval, on the leader only.
+func RunScheduled(name string, interval time.Duration, job func()) {
+        go func() {
+                for range time.Tick(interval) {
+                        if !Leader.isLeader() {
+                                slog.Debug("Skipping scheduled job on a follower", "job", name)
+                                continue
+                        }
+                        job()
+                }
+        }()
+}
+
+// redisLeaderLock is a key holding the leader's random ID, which expires
+// unless the leader renews it.
+type redisLeaderLock struct {
+        client *redis.Client
+        holder string
+}
+
+// renewLeaderScript extends the lock if holder still has it.
+var renewLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+        return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaderScript deletes the lock if holder still has it.
+var releaseLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+        return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (l *redisLeaderLock) acquire(ctx context.Context) (bool, error) {
+        renewed, err := renewLeaderScript.Run(ctx, l.client, []string{leaderLockKey}, l.holder, leaderTTL.Milliseconds()).Int()
+        if err != nil {
+                return false, err
+        }
+        if renewed == 1 {
+                return true, nil
+        }
+        return l.client.SetNX(ctx, leaderLockKey, l.holder, leaderTTL).Result()
+}
+
+func (l *redisLeaderLock) release(ctx context.Context) error {
+        return releaseLeaderScript.Run(ctx, l.client, []string{leaderLockKey}, l.holder).Err()
+}
+
+// postgresLeaderLock is a session-level advisory lock, held for as long as
+// the connection that took it stays open.
+type postgresLeaderLock struct {
+        config *pgx.ConnConfig
+
+        mu   sync.Mutex
+        conn *pgx.Conn
+        held bool
+}
+
+func (l *postgresLeaderLock) acquire(ctx context.Context) (bool, error) {
+        l.mu.Lock()
+        defer l.mu.Unlock()
+        if l.conn != nil && l.conn.Ping(ctx) != nil {
+                // The lock went with the connection.
+                l.conn.Close(ctx)
+                l.conn, l.held = nil, false
+        }
+        if l.conn == nil {
+                // Read the password on each connect so a rotated one is used.
+                if password := Secret("POSTGRES_PASSWORD"); password != "" {
+                        l.config.Password = password
+                }
+                conn, err := pgx.ConnectConfig(ctx, l.config)
+                if err != nil {
+                        return false, fmt.Errorf("connecting to postgres: %w", err)
+                }
+                l.conn = conn
+        }
+        if l.held {
+                return true, nil
+        }
+        if err := l.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", leaderAdvisoryLockID).Scan(&l.held); err != nil {
+                return false, err
+        }
+        return l.held, nil
+}
+
+func (l *postgresLeaderLock) release(ctx context.Context) error {
+        l.mu.Lock()
+        defer l.mu.Unlock()
+        if l.conn == nil || !l.held {
+                return nil
+        }
+        l.held = false
+        _, err := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", leaderAdvisoryLockID)
+        return err
+}
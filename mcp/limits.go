@@ -0,0 +1,64 @@
+package mcp
+
+import (
+        "errors"
+        "fmt"
+)
+
+// Limits on incoming messages, set by flags, that keep a client from making
+// the server buffer or decode arbitrarily large or deep input.
+var (
+        MaxMessageSize int64 = 8 << 20
+        MaxJSONDepth         = 64
+)
+
+// JSONDepthExceeds reports whether the JSON in data nests objects and
+// arrays more than max levels deep. It only tracks brackets and strings, so
+// it runs before, and is much cheaper than, decoding.
+func JSONDepthExceeds(data []byte, max int) bool {
+        depth := 0
+        inString, escaped := false, false
+        for _, b := range data {
+                switch {
+                case escaped:
+                        escaped = false
+                case inString:
+                        switch b {
+                        case '\\':
+                                escaped = true
+                        case '"':
+                                inString = false
+                        }
+                case b == '"':
+                        inString = true
+                case b == '{' || b == '[':
+                        depth++
+                        if depth > max {
+                                return true
+                        }
+                case b == '}' || b == ']':
+                        depth--
+                }
+        }
+        return false
+}
+
+// Limits on concurrent work, set by flags, for DefaultSettings; see
+// Settings. Zero means unlimited.
+var (
+        MaxConnections int
+        MaxInFlight    = 32
+)
+
+// ErrServerFull is returned when a connection would exceed MaxConnections.
+var ErrServerFull = errors.New("too many connections")
+
+// serverBusyError is the reply to a request beyond the limit of a
+// connection's requests in progress.
+func serverBusyError(limit int) *Error {
+        return &Error{
+                Code:    -32013,
+                Message: fmt.Sprintf("Server busy: %d requests already in progress on this connection; retry when some finish", limit),
+                Data:    map[string]interface{}{"maxInFlight": limit},
+        }
+}
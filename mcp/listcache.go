@@ -0,0 +1,81 @@
+package mcp
+
+import (
+        "encoding/json"
+        "sync"
+        "sync/atomic"
+)
+
+// registryGeneration counts changes to the tool, prompt, and resource
+// registries, and to what turns tools on and off. Cached list results
+// belong to the generation they were built in and are ignored once it
+// has passed.
+var registryGeneration atomic.Int64
+
+// registryChanged invalidates the cached list results. It is called once
+// the change has been made, so a result built from the old registry is
+// never cached under the new generation.
+func registryChanged() {
+        registryGeneration.Add(1)
+}
+
+// maxListCacheEntries bounds the list cache. Each combination of tools a
+// client may see has its own tools/list entry, and there are few; if
+// there are ever more the cache starts over rather than growing.
+const maxListCacheEntries = 256
+
+// listCache holds encoded tools/list, prompts/list, and static resource
+// list results by key, for one registry generation, so clients that list
+// on every turn don't have the schemas and descriptions encoded again each
+// time.
+var listCache = struct {
+        sync.Mutex
+        generation int64
+        results    map[string]json.RawMessage
+}{}
+
+// cachedListResponse answers req with the result cached under key, or
+// builds it with build and caches it. Error responses aren't cached.
+func cachedListResponse(req Request, key string, build func() (interface{}, *Response)) Response {
+        generation := registryGeneration.Load()
+        listCache.Lock()
+        result, ok := listCache.results[key]
+        ok = ok && listCache.generation == generation
+        listCache.Unlock()
+        if ok {
+                return Response{ID: req.ID, Result: result}
+        }
+
+        v, errResp := build()
+        if errResp != nil {
+                return *errResp
+        }
+        data, err := json.Marshal(v)
+        if err != nil {
+                return Response{ID: req.ID, Error: ToError(err)}
+        }
+        listCache.Lock()
+        defer listCache.Unlock()
+        switch {
+        case generation < listCache.generation:
+                // The registry changed while the result was built.
+        case generation > listCache.generation || len(listCache.results) >= maxListCacheEntries:
+                listCache.generation = generation
+                listCache.results = map[string]json.RawMessage{key: data}
+        default:
+                listCache.results[key] = data
+        }
+        return Response{ID: req.ID, Result: json.RawMessage(data)}
+}
+
+// cachedPage is cachedListResponse for a paginated list method, caching
+// each page by its cursor and by variant, which tells apart lists that
+// differ between clients.
+func cachedPage(req Request, variant string, build func() (interface{}, *Response)) Response {
+        cursor, err := parseCursorParams(req.Params)
+        if err != nil {
+                _, _, _, errResp := paginateRequest(req, 0)
+                return *errResp
+        }
+        return cachedListResponse(req, req.Method+"?"+variant+"&cursor="+cursor, build)
+}
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "log/slog"
+        "time"
+)
+
+// logLevel is a syslog severity as used by the MCP logging capability,
+// ordered from least to most severe.
+type logLevel int
+
+const (
+        LevelDebug logLevel = iota
+        LevelInfo
+        LevelNotice
+        LevelWarning
+        LevelError
+        levelCritical
+        levelAlert
+        levelEmergency
+)
+
+var logLevelNames = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+func (l logLevel) String() string {
+        return logLevelNames[l]
+}
+
+// slogLevel places l on slog's scale, where debug, info, warning, and error
+// are the standard levels and the others fall in between or above.
+func (l logLevel) slogLevel() slog.Level {
+        switch l {
+        case LevelDebug:
+                return slog.LevelDebug
+        case LevelInfo:
+                return slog.LevelInfo
+        case LevelNotice:
+                return slog.LevelInfo + 2
+        case LevelWarning:
+                return slog.LevelWarn
+        case LevelError:
+                return slog.LevelError
+        }
+        return slog.LevelError + slog.Level(l-LevelError)*2
+}
+
+// logLevelOf is the inverse of slogLevel, rounding down levels in between.
+func logLevelOf(level slog.Level) logLevel {
+        for l := levelEmergency; l > LevelDebug; l-- {
+                if level >= l.slogLevel() {
+                        return l
+                }
+        }
+        return LevelDebug
+}
+
+func ParseLogLevel(name string) (logLevel, bool) {
+        for i, n := range logLevelNames {
+                if n == name {
+                        return logLevel(i), true
+                }
+        }
+        return 0, false
+}
+
+// defaultLogLevel is the least severe level written by log sinks that
+// don't set their own, from -log-level. Reloading the configuration
+// changes it in place.
+var defaultLogLevel slog.LevelVar
+
+// SetDefaultLogLevel sets defaultLogLevel to the level named name.
+func SetDefaultLogLevel(name string) error {
+        level, ok := ParseLogLevel(name)
+        if !ok {
+                return fmt.Errorf("unknown log level %q (want one of %v)", name, logLevelNames)
+        }
+        defaultLogLevel.Set(level.slogLevel())
+        return nil
+}
+
+// SetupLogging makes the default slog logger write records to each of
+// sinks (see openLogSink), or to stderr if there are none. format and level
+// are the defaults for sinks that don't set their own.
+func SetupLogging(format, level string, sinks []string) error {
+        if err := SetDefaultLogLevel(level); err != nil {
+                return err
+        }
+        if len(sinks) == 0 {
+                sinks = []string{"stderr"}
+        }
+        var handlers fanoutHandler
+        for _, spec := range sinks {
+                handler, err := openLogSink(spec, format)
+                if err != nil {
+                        return err
+                }
+                handlers = append(handlers, handler)
+        }
+        if len(handlers) == 1 {
+                slog.SetDefault(slog.New(handlers[0]))
+        } else {
+                slog.SetDefault(slog.New(handlers))
+        }
+        return nil
+}
+
+type loggerContextKey struct{}
+
+// contextWithLogger returns ctx carrying l, which records logged with ctx
+// go to. Requests carry a logger that adds their ID and method to each
+// record.
+func contextWithLogger(ctx context.Context, l *slog.Logger) context.Context {
+        return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// loggerFrom returns the logger ctx carries, or fallback.
+func loggerFrom(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+        if l, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+                return l
+        }
+        return fallback
+}
+
+// serverLoggerName identifies this server in notifications/message.
+const serverLoggerName = "mcp-server"
+
+type SetLevelParams struct {
+        Level string `json:"level"`
+}
+
+type LoggingMessageParams struct {
+        Level  string      `json:"level"`
+        Logger string      `json:"logger,omitempty"`
+        Data   interface{} `json:"data"`
+}
+
+func (c *Conn) setLogLevel(level logLevel) {
+        c.mu.Lock()
+        c.logLevel = level
+        c.logEnabled = true
+        c.mu.Unlock()
+}
+
+// wantsLog reports whether the client asked for records at level. Clients
+// receive nothing until they call logging/setLevel.
+func (c *Conn) wantsLog(level logLevel) bool {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        return c.logEnabled && level >= c.logLevel
+}
+
+func (c *Conn) forwardLog(level logLevel, message string) {
+        if !c.wantsLog(level) {
+                return
+        }
+        // Send errors are ignored: logging them would recurse.
+        c.notify("notifications/message", LoggingMessageParams{
+                Level:  level.String(),
+                Logger: serverLoggerName,
+                Data:   message,
+        })
+}
+
+// Logf logs a record about this connection, tagged with its ID and identity
+// (and the request's, if ctx is a request's), and forwards it to the client
+// if it is at or above the client's requested level.
+func (c *Conn) Logf(ctx context.Context, level logLevel, format string, args ...interface{}) {
+        message := fmt.Sprintf(format, args...)
+        loggerFrom(ctx, c.logger).Log(ctx, level.slogLevel(), message)
+        c.forwardLog(level, message)
+}
+
+// LogAll logs a server-wide record and forwards it to every client that
+// asked for records at level: those of the server whose connection ctx
+// derives from, or, for a context from no server, those of every server.
+func LogAll(ctx context.Context, level logLevel, format string, args ...interface{}) {
+        message := fmt.Sprintf(format, args...)
+        loggerFrom(ctx, slog.Default()).Log(ctx, level.slogLevel(), message)
+        for _, g := range groupsFor(ctx) {
+                for _, c := range g.Clients() {
+                        c.forwardLog(level, message)
+                }
+        }
+}
+
+// logRequest records that a request was handled, how long it took, and the
+// error it failed with, if any.
+func logRequest(ctx context.Context, c *Conn, start time.Time, response Response) {
+        attrs := []slog.Attr{slog.Duration("duration", time.Since(start))}
+        level := slog.LevelInfo
+        if response.Error != nil {
+                attrs = append(attrs, slog.Int("error_code", response.Error.Code))
+                level = slog.LevelWarn
+        }
+        loggerFrom(ctx, c.logger).LogAttrs(ctx, level, "Handled request", attrs...)
+}
+
+func handleSetLevel(_ context.Context, c *Conn, req Request) Response {
+        var params SetLevelParams
+        if err := json.Unmarshal(req.Params, &params); err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        level, ok := ParseLogLevel(params.Level)
+        if !ok {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: fmt.Sprintf("Invalid log level: %s", params.Level),
+                        },
+                }
+        }
+
+        c.setLogLevel(level)
+        return Response{
+                ID:     req.ID,
+                Result: EmptyResult{},
+        }
+}
@@ -0,0 +1,401 @@
+package mcp
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "io"
+        "log/slog"
+        "net/url"
+        "os"
+        "path/filepath"
+        "sort"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+)
+
+// A log sink is one destination for log records, given to -log-sink as
+// <kind>[:<target>][?option=value&...]:
+//
+//	stderr, stdout
+//	file:<path>?max-size=100MB&max-age=7d&max-backups=5
+//	syslog, syslog:udp://host:514, syslog:tcp://host:601 (with tag=...)
+//
+// Every sink also takes format (text or json) and level, which default to
+// -log-format and -log-level.
+
+// LogSinksFlag collects the specs of a repeated -log-sink flag.
+type LogSinksFlag []string
+
+func (f *LogSinksFlag) String() string { return strings.Join(*f, " ") }
+
+func (f *LogSinksFlag) Set(spec string) error {
+        *f = append(*f, spec)
+        return nil
+}
+
+// openLogSink returns a handler writing to the sink described by spec.
+// Sinks without a level option follow defaultLogLevel.
+func openLogSink(spec, defaultFormat string) (slog.Handler, error) {
+        base, rawQuery, _ := strings.Cut(spec, "?")
+        options, err := url.ParseQuery(rawQuery)
+        if err != nil {
+                return nil, fmt.Errorf("log sink %s: %w", spec, err)
+        }
+        option := func(name, fallback string) string {
+                if v := options.Get(name); v != "" {
+                        return v
+                }
+                return fallback
+        }
+        var minLevel slog.Leveler = &defaultLogLevel
+        if name := options.Get("level"); name != "" {
+                level, ok := ParseLogLevel(name)
+                if !ok {
+                        return nil, fmt.Errorf("log sink %s: unknown level %q (want one of %v)", spec, name, logLevelNames)
+                }
+                minLevel = level.slogLevel()
+        }
+        format := option("format", defaultFormat)
+        opts := &slog.HandlerOptions{Level: minLevel, ReplaceAttr: mcpLevelNames}
+
+        kind, target, _ := strings.Cut(base, ":")
+        var w io.Writer
+        switch kind {
+        case "stderr":
+                w = os.Stderr
+        case "stdout":
+                w = os.Stdout
+        case "file":
+                if target == "" {
+                        return nil, fmt.Errorf("log sink %s: missing file path", spec)
+                }
+                rotation, err := parseRotation(options)
+                if err != nil {
+                        return nil, fmt.Errorf("log sink %s: %w", spec, err)
+                }
+                if w, err = openRotatingFile(target, rotation); err != nil {
+                        return nil, fmt.Errorf("log sink %s: %w", spec, err)
+                }
+        case "syslog":
+                writer, err := openSyslog(target, option("tag", "mcp-server"))
+                if err != nil {
+                        return nil, fmt.Errorf("log sink %s: %w", spec, err)
+                }
+                // Syslog stamps records itself.
+                opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+                        if a.Key == slog.TimeKey && len(groups) == 0 {
+                                return slog.Attr{}
+                        }
+                        return mcpLevelNames(groups, a)
+                }
+                return newSyslogHandler(writer, format, opts)
+        default:
+                return nil, fmt.Errorf("log sink %s: unknown kind %q (want stderr, stdout, file, or syslog)", spec, kind)
+        }
+        return newFormatHandler(w, format, opts)
+}
+
+func newFormatHandler(w io.Writer, format string, opts *slog.HandlerOptions) (slog.Handler, error) {
+        switch format {
+        case "text":
+                return slog.NewTextHandler(w, opts), nil
+        case "json":
+                return slog.NewJSONHandler(w, opts), nil
+        }
+        return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+}
+
+// mcpLevelNames names levels as the MCP logging capability does.
+func mcpLevelNames(groups []string, a slog.Attr) slog.Attr {
+        if a.Key == slog.LevelKey && len(groups) == 0 {
+                a.Value = slog.StringValue(logLevelOf(a.Value.Any().(slog.Level)).String())
+        }
+        return a
+}
+
+// fanoutHandler sends each record to every handler enabled for its level.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+        for _, handler := range h {
+                if handler.Enabled(ctx, level) {
+                        return true
+                }
+        }
+        return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+        var errs []error
+        for _, handler := range h {
+                if handler.Enabled(ctx, r.Level) {
+                        errs = append(errs, handler.Handle(ctx, r.Clone()))
+                }
+        }
+        return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+        out := make(fanoutHandler, len(h))
+        for i, handler := range h {
+                out[i] = handler.WithAttrs(attrs)
+        }
+        return out
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+        out := make(fanoutHandler, len(h))
+        for i, handler := range h {
+                out[i] = handler.WithGroup(name)
+        }
+        return out
+}
+
+// rotation says when a log file is rotated and how many old files are
+// kept. Zero values disable each limit.
+type rotation struct {
+        maxSize    int64
+        maxAge     time.Duration
+        maxBackups int
+}
+
+func parseRotation(options url.Values) (rotation, error) {
+        var r rotation
+        var err error
+        if v := options.Get("max-size"); v != "" {
+                if r.maxSize, err = parseByteSize(v); err != nil {
+                        return r, fmt.Errorf("max-size: %w", err)
+                }
+        }
+        if v := options.Get("max-age"); v != "" {
+                if r.maxAge, err = parseLongDuration(v); err != nil {
+                        return r, fmt.Errorf("max-age: %w", err)
+                }
+        }
+        if v := options.Get("max-backups"); v != "" {
+                if r.maxBackups, err = strconv.Atoi(v); err != nil || r.maxBackups < 0 {
+                        return r, fmt.Errorf("max-backups: invalid count %q", v)
+                }
+        }
+        return r, nil
+}
+
+// parseByteSize parses sizes such as 500000, 512KB, 100MB, or 1GB.
+func parseByteSize(s string) (int64, error) {
+        units := []struct {
+                suffix string
+                scale  int64
+        }{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+        upper := strings.ToUpper(strings.TrimSpace(s))
+        scale := int64(1)
+        for _, u := range units {
+                if strings.HasSuffix(upper, u.suffix) {
+                        upper, scale = strings.TrimSuffix(upper, u.suffix), u.scale
+                        break
+                }
+        }
+        n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+        if err != nil || n <= 0 {
+                return 0, fmt.Errorf("invalid size %q", s)
+        }
+        return n * scale, nil
+}
+
+// parseLongDuration is time.ParseDuration plus a d (day) unit, e.g. 7d.
+func parseLongDuration(s string) (time.Duration, error) {
+        if days, ok := strings.CutSuffix(s, "d"); ok {
+                n, err := strconv.Atoi(days)
+                if err != nil || n <= 0 {
+                        return 0, fmt.Errorf("invalid duration %q", s)
+                }
+                return time.Duration(n) * 24 * time.Hour, nil
+        }
+        d, err := time.ParseDuration(s)
+        if err != nil || d <= 0 {
+                return 0, fmt.Errorf("invalid duration %q", s)
+        }
+        return d, nil
+}
+
+// rotatingFile appends to a log file, moving it aside to
+// <path>.<timestamp> once it reaches maxSize or maxAge and deleting all but
+// the newest maxBackups of those.
+type rotatingFile struct {
+        path string
+        rotation
+
+        mu       sync.Mutex
+        file     *os.File
+        size     int64
+        openedAt time.Time
+}
+
+func openRotatingFile(path string, r rotation) (*rotatingFile, error) {
+        f := &rotatingFile{path: path, rotation: r}
+        if err := f.open(); err != nil {
+                return nil, err
+        }
+        return f, nil
+}
+
+func (f *rotatingFile) open() error {
+        if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+                return err
+        }
+        file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+        if err != nil {
+                return err
+        }
+        info, err := file.Stat()
+        if err != nil {
+                file.Close()
+                return err
+        }
+        f.file, f.size, f.openedAt = file, info.Size(), time.Now()
+        if f.size > 0 {
+                // Age an existing file from when it was last written, so a
+                // restart doesn't keep it open past max-age.
+                f.openedAt = info.ModTime()
+        }
+        return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+        f.mu.Lock()
+        defer f.mu.Unlock()
+        if f.due(len(p)) {
+                if err := f.rotate(); err != nil {
+                        // Keep logging to the current file rather than lose records.
+                        fmt.Fprintf(os.Stderr, "Rotating %s: %v\n", f.path, err)
+                }
+        }
+        n, err := f.file.Write(p)
+        f.size += int64(n)
+        return n, err
+}
+
+// due reports whether the file should be rotated before writing n bytes.
+func (f *rotatingFile) due(n int) bool {
+        if f.size == 0 {
+                return false
+        }
+        if f.maxSize > 0 && f.size+int64(n) > f.maxSize {
+                return true
+        }
+        return f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge
+}
+
+func (f *rotatingFile) rotate() error {
+        if err := f.file.Close(); err != nil {
+                return err
+        }
+        backup := f.path + "." + time.Now().UTC().Format("20060102-150405.000")
+        if err := os.Rename(f.path, backup); err != nil {
+                return err
+        }
+        if err := f.open(); err != nil {
+                return err
+        }
+        return f.prune()
+}
+
+// prune deletes the oldest rotated files beyond maxBackups.
+func (f *rotatingFile) prune() error {
+        if f.maxBackups == 0 {
+                return nil
+        }
+        backups, err := filepath.Glob(f.path + ".*")
+        if err != nil {
+                return err
+        }
+        // Timestamps sort chronologically.
+        sort.Strings(backups)
+        var errs []error
+        for len(backups) > f.maxBackups {
+                errs = append(errs, os.Remove(backups[0]))
+                backups = backups[1:]
+        }
+        return errors.Join(errs...)
+}
+
+// syslogWriter is the part of log/syslog's Writer that syslog sinks use.
+type syslogWriter interface {
+        Debug(m string) error
+        Info(m string) error
+        Notice(m string) error
+        Warning(m string) error
+        Err(m string) error
+        Crit(m string) error
+        Alert(m string) error
+        Emerg(m string) error
+}
+
+// syslogHandler formats records like the text or JSON handlers and sends
+// each to syslog at the severity matching its level.
+type syslogHandler struct {
+        inner slog.Handler
+        out   *syslogOutput
+}
+
+// syslogOutput receives one formatted record at a time from the inner
+// handler and passes it on at the level being handled.
+type syslogOutput struct {
+        mu     sync.Mutex
+        writer syslogWriter
+        level  logLevel
+}
+
+func (o *syslogOutput) Write(p []byte) (int, error) {
+        message := strings.TrimSuffix(string(p), "\n")
+        var err error
+        switch o.level {
+        case LevelDebug:
+                err = o.writer.Debug(message)
+        case LevelInfo:
+                err = o.writer.Info(message)
+        case LevelNotice:
+                err = o.writer.Notice(message)
+        case LevelWarning:
+                err = o.writer.Warning(message)
+        case LevelError:
+                err = o.writer.Err(message)
+        case levelCritical:
+                err = o.writer.Crit(message)
+        case levelAlert:
+                err = o.writer.Alert(message)
+        default:
+                err = o.writer.Emerg(message)
+        }
+        return len(p), err
+}
+
+func newSyslogHandler(writer syslogWriter, format string, opts *slog.HandlerOptions) (slog.Handler, error) {
+        out := &syslogOutput{writer: writer}
+        inner, err := newFormatHandler(out, format, opts)
+        if err != nil {
+                return nil, err
+        }
+        return &syslogHandler{inner: inner, out: out}, nil
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+        return h.inner.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+        h.out.mu.Lock()
+        defer h.out.mu.Unlock()
+        h.out.level = logLevelOf(r.Level)
+        return h.inner.Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+        return &syslogHandler{inner: h.inner.WithAttrs(attrs), out: h.out}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+        return &syslogHandler{inner: h.inner.WithGroup(name), out: h.out}
+}
@@ -0,0 +1,399 @@
+package mcp
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "os"
+        "os/exec"
+        "regexp"
+        "strings"
+        "time"
+
+        "gopkg.in/yaml.v3"
+)
+
+// manifestCommandTimeout bounds a manifest command that sets no timeout.
+const manifestCommandTimeout = 30 * time.Second
+
+// manifestStderrLimit is how much of a failed command's stderr is kept
+// for its error.
+const manifestStderrLimit = 4 << 10
+
+// manifestPlaceholder matches {{name}} in command arguments and HTTP
+// templates; name is a tool argument.
+var manifestPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+// toolManifest is the -tool-manifest file: tools defined without code,
+// each backed by a command or an HTTP call.
+type toolManifest struct {
+        Tools []manifestTool `yaml:"tools"`
+}
+
+type manifestTool struct {
+        Name        string                 `yaml:"name"`
+        Description string                 `yaml:"description"`
+        InputSchema map[string]interface{} `yaml:"input_schema"`
+        // Scopes default to manifest:read for read-only tools and
+        // manifest:write for the rest.
+        Scopes   []string         `yaml:"scopes"`
+        ReadOnly bool             `yaml:"read_only"`
+        Command  *manifestCommand `yaml:"command"`
+        HTTP     *manifestHTTP    `yaml:"http"`
+}
+
+// manifestCommand runs a program directly, without a shell, so arguments
+// can't inject commands. The program itself can't come from a placeholder,
+// and an argument that would start with - is refused, so it can't pass
+// itself off as an option. The tool's arguments are also passed on stdin
+// as a JSON object.
+type manifestCommand struct {
+        Args    []string      `yaml:"args"`
+        Dir     string        `yaml:"dir"`
+        Timeout time.Duration `yaml:"timeout"`
+}
+
+// manifestHTTP calls a URL. Placeholders in the URL are path-escaped;
+// header values may also read secrets as ${NAME}, from the template only,
+// never from the arguments filled into it. Without a body template,
+// methods other than GET and HEAD send the tool's arguments as JSON.
+type manifestHTTP struct {
+        Method  string            `yaml:"method"`
+        URL     string            `yaml:"url"`
+        Query   map[string]string `yaml:"query"`
+        Headers map[string]string `yaml:"headers"`
+        Body    interface{}       `yaml:"body"`
+}
+
+// LoadToolManifest reads the tool manifest at path and registers its
+// tools, returning how many.
+func LoadToolManifest(path string) (int, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return 0, err
+        }
+        var manifest toolManifest
+        decoder := yaml.NewDecoder(bytes.NewReader(data))
+        decoder.KnownFields(true)
+        if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+                return 0, fmt.Errorf("parsing %s: %w", path, err)
+        }
+        var defined []*Tool
+        for i, mt := range manifest.Tools {
+                t, err := mt.tool()
+                if err != nil {
+                        if mt.Name != "" {
+                                return 0, fmt.Errorf("%s: tool %s: %w", path, mt.Name, err)
+                        }
+                        return 0, fmt.Errorf("%s: tool %d: %w", path, i+1, err)
+                }
+                if _, exists := findTool(t.Name); exists {
+                        return 0, fmt.Errorf("%s: tool %s already exists", path, t.Name)
+                }
+                for _, other := range defined {
+                        if other.Name == t.Name {
+                                return 0, fmt.Errorf("%s: tool %s is defined twice", path, t.Name)
+                        }
+                }
+                defined = append(defined, t)
+        }
+        for _, t := range defined {
+                RegisterTool(t)
+        }
+        return len(defined), nil
+}
+
+// tool checks mt and builds the tool it defines.
+func (mt manifestTool) tool() (*Tool, error) {
+        switch {
+        case mt.Name == "":
+                return nil, fmt.Errorf("name is required")
+        case toolNameUnsafe.MatchString(mt.Name):
+                return nil, fmt.Errorf("names may only contain letters, digits, _ and -")
+        case (mt.Command == nil) == (mt.HTTP == nil):
+                return nil, fmt.Errorf("set one of command or http")
+        }
+        schema := mt.InputSchema
+        if schema == nil {
+                schema = NoArgsSchema()
+        } else if schema["type"] != "object" {
+                return nil, fmt.Errorf("input_schema must have type object")
+        }
+        required, err := schemaRequired(schema)
+        if err != nil {
+                return nil, err
+        }
+
+        t := &Tool{Name: mt.Name, Description: mt.Description, InputSchema: schema, Scopes: mt.Scopes}
+        readOnly := mt.ReadOnly
+        if mt.Command != nil {
+                if len(mt.Command.Args) == 0 {
+                        return nil, fmt.Errorf("command needs args, starting with the program to run")
+                }
+                if manifestPlaceholder.MatchString(mt.Command.Args[0]) {
+                        return nil, fmt.Errorf("the program to run can't be a placeholder")
+                }
+                t.Handler = mt.Command.handler(required)
+        } else {
+                h := mt.HTTP
+                h.Method = strings.ToUpper(h.Method)
+                if h.Method == "" {
+                        h.Method = http.MethodGet
+                }
+                if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+                        return nil, fmt.Errorf("http needs an http or https url")
+                }
+                readOnly = readOnly || h.Method == http.MethodGet || h.Method == http.MethodHead
+                t.Handler = h.handler(required, &http.Client{Timeout: RemoteRequestTimeout})
+        }
+        if t.Scopes == nil {
+                t.Scopes = []string{scopeManifestWrite}
+                if readOnly {
+                        t.Scopes = []string{scopeManifestRead}
+                }
+        }
+        return t, nil
+}
+
+// schemaRequired returns the arguments schema lists as required.
+func schemaRequired(schema map[string]interface{}) ([]string, error) {
+        list, ok := schema["required"].([]interface{})
+        if !ok && schema["required"] != nil {
+                return nil, fmt.Errorf("input_schema required must be a list")
+        }
+        var required []string
+        for _, name := range list {
+                s, ok := name.(string)
+                if !ok {
+                        return nil, fmt.Errorf("input_schema required must list argument names")
+                }
+                required = append(required, s)
+        }
+        return required, nil
+}
+
+func checkRequired(args map[string]interface{}, required []string) error {
+        for _, name := range required {
+                if v, ok := args[name]; !ok || v == nil {
+                        return Errorf(ErrInvalidArgument, "%s is required", name)
+                }
+        }
+        return nil
+}
+
+// expandPlaceholders replaces each {{name}} in s with the argument name,
+// passed through escape.
+func expandPlaceholders(s string, args map[string]interface{}, escape func(string) string) string {
+        return manifestPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+                name := manifestPlaceholder.FindStringSubmatch(m)[1]
+                return escape(JSONString(args[name]))
+        })
+}
+
+func noEscape(s string) string { return s }
+
+// expandURL fills in a URL template, escaping each argument as a path
+// segment. Escaping leaves "." and ".." as they are, which would move the
+// request up the path, and an empty value would drop a segment, so those
+// are refused.
+func expandURL(s string, args map[string]interface{}) (string, error) {
+        var err error
+        target := manifestPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+                name := manifestPlaceholder.FindStringSubmatch(m)[1]
+                value := JSONString(args[name])
+                if (value == "" || value == "." || value == "..") && err == nil {
+                        err = Errorf(ErrInvalidArgument, "%s can't be %q in the URL", name, value)
+                }
+                return url.PathEscape(value)
+        })
+        return target, err
+}
+
+// expandHeader fills in a header template: ${NAME} secrets in the template
+// itself, then {{name}} arguments as plain text, so an argument can't name
+// a secret for the call to send out.
+func expandHeader(s string, args map[string]interface{}) string {
+        var b strings.Builder
+        last := 0
+        for _, m := range manifestPlaceholder.FindAllStringSubmatchIndex(s, -1) {
+                b.WriteString(ExpandSecrets(s[last:m[0]]))
+                b.WriteString(JSONString(args[s[m[2]:m[3]]]))
+                last = m[1]
+        }
+        b.WriteString(ExpandSecrets(s[last:]))
+        return b.String()
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the
+// rest, so a command can't make the server hold unbounded output.
+type cappedBuffer struct {
+        bytes.Buffer
+        max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+        if room := b.max - b.Len(); room > 0 {
+                b.Buffer.Write(p[:min(len(p), room)])
+        }
+        return len(p), nil
+}
+
+// expandBody fills in a body template. A string that is only a
+// placeholder becomes the argument's value, whatever its type.
+func expandBody(v interface{}, args map[string]interface{}) interface{} {
+        switch v := v.(type) {
+        case string:
+                if m := manifestPlaceholder.FindStringSubmatch(v); m != nil && m[0] == strings.TrimSpace(v) {
+                        return args[m[1]]
+                }
+                return expandPlaceholders(v, args, noEscape)
+        case map[string]interface{}:
+                out := make(map[string]interface{}, len(v))
+                for k, item := range v {
+                        out[k] = expandBody(item, args)
+                }
+                return out
+        case []interface{}:
+                out := make([]interface{}, len(v))
+                for i, item := range v {
+                        out[i] = expandBody(item, args)
+                }
+                return out
+        }
+        return v
+}
+
+// handler returns the tool handler that runs c.
+func (c *manifestCommand) handler(required []string) ToolHandler {
+        return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+                if mcpErr := checkRequired(args, required); mcpErr != nil {
+                        return nil, mcpErr
+                }
+                timeout := c.Timeout
+                if timeout <= 0 {
+                        timeout = manifestCommandTimeout
+                }
+                ctx, cancel := context.WithTimeout(ctx, timeout)
+                defer cancel()
+
+                argv := make([]string, len(c.Args))
+                for i, arg := range c.Args {
+                        argv[i] = expandPlaceholders(arg, args, noEscape)
+                        if strings.HasPrefix(argv[i], "-") && !strings.HasPrefix(arg, "-") {
+                                return nil, Errorf(ErrInvalidArgument, "Argument %d of %s would start with -", i, argv[0])
+                        }
+                }
+                input, err := json.Marshal(args)
+                if err != nil {
+                        return nil, Errorf(ErrInvalidArgument, "Invalid arguments: %v", err)
+                }
+                cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+                cmd.Dir = c.Dir
+                cmd.Stdin = bytes.NewReader(input)
+                // One byte over the limit shows decodeOutput the output was
+                // cut short.
+                stdout := &cappedBuffer{max: openAPIResponseLimit + 1}
+                stderr := &cappedBuffer{max: manifestStderrLimit}
+                cmd.Stdout, cmd.Stderr = stdout, stderr
+                err = cmd.Run()
+                if ctx.Err() == context.DeadlineExceeded {
+                        return nil, Errorf(ErrUnavailable, "%s timed out after %s", argv[0], timeout)
+                }
+                var exitErr *exec.ExitError
+                if err != nil && !errors.As(err, &exitErr) {
+                        return nil, Errorf(ErrUnavailable, "%v", err)
+                }
+                if err != nil {
+                        return nil, Errorf(ErrUnavailable, "%s exited with status %d: %s", argv[0], exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+                }
+                return map[string]interface{}{"output": decodeOutput(stdout.Bytes())}, nil
+        }
+}
+
+// handler returns the tool handler that makes h's request.
+func (h *manifestHTTP) handler(required []string, client *http.Client) ToolHandler {
+        return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+                if mcpErr := checkRequired(args, required); mcpErr != nil {
+                        return nil, mcpErr
+                }
+                target, err := expandURL(h.URL, args)
+                if err != nil {
+                        return nil, err
+                }
+                if len(h.Query) > 0 {
+                        query := url.Values{}
+                        for name, value := range h.Query {
+                                if value = expandPlaceholders(value, args, noEscape); value != "" {
+                                        query.Set(name, value)
+                                }
+                        }
+                        if encoded := query.Encode(); encoded != "" {
+                                target += "?" + encoded
+                        }
+                }
+
+                var body interface{}
+                switch {
+                case h.Body != nil:
+                        body = expandBody(h.Body, args)
+                case h.Method != http.MethodGet && h.Method != http.MethodHead:
+                        body = args
+                }
+                var reader io.Reader
+                if body != nil {
+                        data, err := json.Marshal(body)
+                        if err != nil {
+                                return nil, Errorf(ErrInvalidArgument, "Invalid body: %v", err)
+                        }
+                        reader = bytes.NewReader(data)
+                }
+                req, err := http.NewRequestWithContext(ctx, h.Method, target, reader)
+                if err != nil {
+                        return nil, Errorf(ErrInvalidArgument, "%v", err)
+                }
+                if reader != nil {
+                        req.Header.Set("Content-Type", "application/json")
+                }
+                req.Header.Set("Accept", "application/json")
+                for name, value := range h.Headers {
+                        req.Header.Set(name, expandHeader(value, args))
+                }
+
+                req, span := StartClientSpan(req)
+                resp, err := client.Do(req)
+                EndClientSpan(span, resp, err)
+                if err != nil {
+                        return nil, Errorf(ErrUnavailable, "%s %s: %v", h.Method, req.URL.Redacted(), err)
+                }
+                defer resp.Body.Close()
+                data, err := io.ReadAll(io.LimitReader(resp.Body, openAPIResponseLimit))
+                if err != nil {
+                        return nil, Errorf(ErrUnavailable, "%v", err)
+                }
+                if resp.StatusCode < 200 || resp.StatusCode > 299 {
+                        return nil, Errorf(ErrUnavailable, "%s %s: %s: %s", h.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(data)))
+                }
+                result := map[string]interface{}{"status": resp.StatusCode}
+                if len(data) > 0 {
+                        result["body"] = decodeOutput(data)
+                }
+                return result, nil
+        }
+}
+
+// decodeOutput returns data as JSON, to be sent as it is, if it is JSON,
+// otherwise as text, truncated to openAPIResponseLimit.
+func decodeOutput(data []byte) interface{} {
+        if len(data) > openAPIResponseLimit {
+                data = data[:openAPIResponseLimit]
+        }
+        if json.Valid(data) {
+                return json.RawMessage(data)
+        }
+        return string(data)
+}
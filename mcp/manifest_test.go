@@ -0,0 +1,75 @@
+package mcp
+
+import (
+        "context"
+        "net/http"
+        "net/http/httptest"
+        "os"
+        "path/filepath"
+        "reflect"
+        "testing"
+)
+
+func TestManifestArgumentsCantReadSecrets(t *testing.T) {
+        t.Setenv("MANIFEST_TEST_TOKEN", "s3cret")
+        args := map[string]interface{}{"user": "${MANIFEST_TEST_TOKEN}"}
+        got := expandHeader("Bearer ${MANIFEST_TEST_TOKEN} for {{user}}", args)
+        if want := "Bearer s3cret for ${MANIFEST_TEST_TOKEN}"; got != want {
+                t.Errorf("got %q, want %q", got, want)
+        }
+
+        dir := t.TempDir()
+        if err := os.WriteFile(filepath.Join(dir, "TOKEN"), []byte("ok\n"), 0o600); err != nil {
+                t.Fatal(err)
+        }
+        secrets := fileSecrets{dir: filepath.Join(dir, "secrets")}
+        os.Mkdir(secrets.dir, 0o700)
+        for _, name := range []string{"../TOKEN", `..\TOKEN`, "/etc/hostname", ".."} {
+                if value, ok, _ := secrets.lookup(name); ok {
+                        t.Errorf("lookup(%q) read %q", name, value)
+                }
+        }
+}
+
+func TestManifestCommandArguments(t *testing.T) {
+        if _, err := (manifestTool{Name: "run", Command: &manifestCommand{Args: []string{"{{program}}"}}}).tool(); err == nil {
+                t.Error("placeholder as the program: got no error")
+        }
+        tool, err := (manifestTool{Name: "print", Command: &manifestCommand{Args: []string{"printf", "%s", "{{text}}"}}}).tool()
+        if err != nil {
+                t.Fatal(err)
+        }
+        if _, err := tool.Handler(context.Background(), map[string]interface{}{"text": "-n"}); err == nil {
+                t.Error("argument starting with -: got no error")
+        }
+        result, err := tool.Handler(context.Background(), map[string]interface{}{"text": "hi"})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if output := result.(map[string]interface{})["output"]; output != "hi" {
+                t.Errorf("got output %q", output)
+        }
+}
+
+func TestManifestURLArguments(t *testing.T) {
+        var paths []string
+        srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                paths = append(paths, r.URL.EscapedPath())
+        }))
+        defer srv.Close()
+        tool, err := (manifestTool{Name: "get", HTTP: &manifestHTTP{URL: srv.URL + "/items/{{id}}/detail"}}).tool()
+        if err != nil {
+                t.Fatal(err)
+        }
+        for _, id := range []string{"..", ".", ""} {
+                if _, err := tool.Handler(context.Background(), map[string]interface{}{"id": id}); err == nil {
+                        t.Errorf("id %q: got no error", id)
+                }
+        }
+        if _, err := tool.Handler(context.Background(), map[string]interface{}{"id": "../a b"}); err != nil {
+                t.Fatal(err)
+        }
+        if want := []string{"/items/..%2Fa%20b/detail"}; !reflect.DeepEqual(paths, want) {
+                t.Errorf("requested %q, want %q", paths, want)
+        }
+}
@@ -0,0 +1,267 @@
+package mcp
+
+import (
+        "fmt"
+        "io"
+        "math"
+        "net/http"
+        "sort"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+)
+
+// Metrics are served in the Prometheus text format at /metrics on the
+// -admin-addr port.
+
+var (
+        requestsTotal = newCounterVec("mcp_requests_total",
+                "Requests handled, by method, outcome (ok or error), and client name.", "method", "outcome", "client")
+        requestDuration = newHistogramVec("mcp_request_duration_seconds",
+                "Time from receiving a request to sending its response, by method.", durationBuckets, "method")
+        toolCallsTotal = newCounterVec("mcp_tool_calls_total",
+                "Tool calls, by tool, outcome (ok or error), and client name.", "tool", "outcome", "client")
+        toolCallDuration = newHistogramVec("mcp_tool_call_duration_seconds",
+                "Time spent in tool handlers, by tool and outcome.", durationBuckets, "tool", "outcome")
+        toolErrorsTotal = newCounterVec("mcp_tool_errors_total",
+                "Failed tool calls, by tool and JSON-RPC error code.", "tool", "code")
+        toolQueueDepth = newGaugeFunc("mcp_tool_queue_depth",
+                "Tool calls waiting for a tool worker.", func() float64 { return float64(toolsQueued.Load()) })
+        toolWorkersBusy = newGaugeFunc("mcp_tool_workers_busy",
+                "Tool calls running, out of -tool-workers per server.", func() float64 {
+                        busy := 0
+                        for _, g := range allGroups() {
+                                busy += g.toolWorkers.busy()
+                        }
+                        return float64(busy)
+                })
+
+        connectionsCurrent = newGaugeFunc("mcp_connections",
+                "Clients currently connected.", func() float64 { return float64(len(ConnectedClients())) })
+        connectionsOpened = newCounterVec("mcp_connections_opened_total",
+                "Connections accepted.")
+        ConnectionsRejected = newCounterVec("mcp_connections_rejected_total",
+                "Connection attempts refused, by reason (unauthenticated, forbidden, busy, too_many_sessions, or upgrade_failed).", "reason")
+        connectionsClosed = newCounterVec("mcp_connections_closed_total",
+                "Connections closed, by cause.", "cause")
+        connectionDuration = newHistogramVec("mcp_connection_duration_seconds",
+                "How long connections stayed open.", connectionBuckets)
+        notificationsDropped = newCounterVec("mcp_notifications_dropped_total",
+                "Broadcast notifications not sent to a client because its outbox was full, by method.", "method")
+        resourceSubscriptions = newGaugeFunc("mcp_resource_subscriptions",
+                "Resource subscriptions held by connected clients.", func() float64 { return float64(subscriptions.count()) })
+        leaderGauge = newGaugeFunc("mcp_leader",
+                "1 if this replica runs the scheduled jobs, otherwise 0.", func() float64 {
+                        if Leader.isLeader() {
+                                return 1
+                        }
+                        return 0
+                })
+)
+
+// durationBuckets are the bucket upper bounds, in seconds, for request
+// latencies; connectionBuckets are those for connection lifetimes.
+var (
+        durationBuckets   = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+        connectionBuckets = []float64{1, 10, 60, 300, 900, 3600, 4 * 3600, 24 * 3600}
+)
+
+func init() {
+        adminMux.HandleFunc("/metrics", handleMetrics)
+}
+
+// metric is a family of series written in the Prometheus text format.
+type metric interface {
+        writeTo(w io.Writer)
+}
+
+var (
+        metricsMu sync.Mutex
+        metrics   []metric
+)
+
+func registerMetric(m metric) {
+        metricsMu.Lock()
+        metrics = append(metrics, m)
+        metricsMu.Unlock()
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+        metricsMu.Lock()
+        defer metricsMu.Unlock()
+        for _, m := range metrics {
+                m.writeTo(w)
+        }
+}
+
+// outcome labels a request or tool call by whether it failed.
+func outcome(mcpErr *Error) string {
+        if mcpErr != nil {
+                return "error"
+        }
+        return "ok"
+}
+
+// recordRequest records a handled request. Methods the server doesn't
+// implement are counted together, so clients can't create new series.
+func recordRequest(method, client string, elapsed time.Duration, response Response) {
+        if response.Error != nil && response.Error.Code == -32601 {
+                method = "unknown"
+        }
+        requestsTotal.Add(1, method, outcome(response.Error), client)
+        requestDuration.observe(elapsed.Seconds(), method)
+}
+
+// recordToolCall records a call to a registered tool.
+func recordToolCall(tool, client string, elapsed time.Duration, mcpErr *Error) {
+        toolCallsTotal.Add(1, tool, outcome(mcpErr), client)
+        toolCallDuration.observe(elapsed.Seconds(), tool, outcome(mcpErr))
+        if mcpErr != nil {
+                toolErrorsTotal.Add(1, tool, strconv.Itoa(mcpErr.Code))
+        }
+}
+
+// seriesKey joins label values into a map key.
+func seriesKey(values []string) string {
+        return strings.Join(values, "\x00")
+}
+
+// labelPairs formats names and the values joined in key as {a="x",b="y"},
+// with extra appended (e.g. le for histogram buckets).
+func labelPairs(names []string, key string, extra ...string) string {
+        var values []string
+        if len(names) > 0 {
+                values = strings.Split(key, "\x00")
+        }
+        var pairs []string
+        for i, name := range names {
+                pairs = append(pairs, fmt.Sprintf("%s=%q", name, values[i]))
+        }
+        for i := 0; i+1 < len(extra); i += 2 {
+                pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+        }
+        if len(pairs) == 0 {
+                return ""
+        }
+        return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+        if math.IsInf(v, 1) {
+                return "+Inf"
+        }
+        return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns m's keys in order, so series are written stably.
+func sortedKeys[V any](m map[string]V) []string {
+        keys := make([]string, 0, len(m))
+        for k := range m {
+                keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        return keys
+}
+
+// counterVec is a counter with one series per combination of label values.
+type counterVec struct {
+        name, help string
+        labels     []string
+
+        mu     sync.Mutex
+        values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+        c := &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+        registerMetric(c)
+        return c
+}
+
+func (c *counterVec) Add(v float64, labelValues ...string) {
+        c.mu.Lock()
+        c.values[seriesKey(labelValues)] += v
+        c.mu.Unlock()
+}
+
+func (c *counterVec) writeTo(w io.Writer) {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+        for _, key := range sortedKeys(c.values) {
+                fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, key), formatFloat(c.values[key]))
+        }
+}
+
+// histogramVec is a histogram with one series per combination of label
+// values.
+type histogramVec struct {
+        name, help string
+        buckets    []float64
+        labels     []string
+
+        mu     sync.Mutex
+        series map[string]*histogram
+}
+
+type histogram struct {
+        // counts[i] counts observations in bucket i alone; they are summed
+        // into cumulative buckets when written.
+        counts []uint64
+        sum    float64
+        count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+        h := &histogramVec{name: name, help: help, buckets: buckets, labels: labels, series: map[string]*histogram{}}
+        registerMetric(h)
+        return h
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+        key := seriesKey(labelValues)
+        h.mu.Lock()
+        defer h.mu.Unlock()
+        s, ok := h.series[key]
+        if !ok {
+                s = &histogram{counts: make([]uint64, len(h.buckets)+1)}
+                h.series[key] = s
+        }
+        s.counts[sort.SearchFloat64s(h.buckets, v)]++
+        s.sum += v
+        s.count++
+}
+
+func (h *histogramVec) writeTo(w io.Writer) {
+        h.mu.Lock()
+        defer h.mu.Unlock()
+        fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+        for _, key := range sortedKeys(h.series) {
+                s := h.series[key]
+                var cumulative uint64
+                for i, upper := range append(h.buckets[:len(h.buckets):len(h.buckets)], math.Inf(1)) {
+                        cumulative += s.counts[i]
+                        fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, "le", formatFloat(upper)), cumulative)
+                }
+                fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, key), formatFloat(s.sum))
+                fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, key), s.count)
+        }
+}
+
+// gaugeFunc is a gauge whose value is read when metrics are scraped.
+type gaugeFunc struct {
+        name, help string
+        value      func() float64
+}
+
+func newGaugeFunc(name, help string, value func() float64) *gaugeFunc {
+        g := &gaugeFunc{name: name, help: help, value: value}
+        registerMetric(g)
+        return g
+}
+
+func (g *gaugeFunc) writeTo(w io.Writer) {
+        fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+}
@@ -0,0 +1,16 @@
+package mcp
+
+// Middleware wraps a Handler with behavior that applies to every request
+// before it reaches its method's handler, such as authorization, logging,
+// metrics, or validation. A middleware may answer the request itself
+// without calling next.
+type Middleware func(next Handler) Handler
+
+// Chain returns h wrapped in mw, the first outermost, so requests pass
+// through the middleware in the order given.
+func Chain(h Handler, mw ...Middleware) Handler {
+        for i := len(mw) - 1; i >= 0; i-- {
+                h = mw[i](h)
+        }
+        return h
+}
@@ -0,0 +1,362 @@
+package mcp
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "os"
+        "regexp"
+        "sort"
+        "strings"
+        "time"
+
+        "gopkg.in/yaml.v3"
+)
+
+// openAPIResponseLimit caps how much of an upstream response a generated
+// tool returns.
+const openAPIResponseLimit = 1 << 20
+
+// openAPIMaxRefDepth stops $ref expansion of recursive schemas.
+const openAPIMaxRefDepth = 8
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var toolNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// openAPIParam is an operation parameter that becomes a tool argument.
+type openAPIParam struct {
+        Name     string
+        In       string // path, query, or header
+        Required bool
+}
+
+// openAPIOperation is one operation of the spec, registered as a tool that
+// proxies calls to the upstream API.
+type openAPIOperation struct {
+        Method  string
+        Path    string
+        Params  []openAPIParam
+        HasBody bool
+}
+
+// openAPIProxy sends generated tools' calls to the upstream API.
+type openAPIProxy struct {
+        baseURL string
+        headers http.Header
+        client  *http.Client
+}
+
+// LoadOpenAPISpec reads an OpenAPI 3 document in JSON or YAML from a file
+// or an http(s) URL.
+func LoadOpenAPISpec(location string) (map[string]interface{}, error) {
+        var data []byte
+        var err error
+        if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+                client := &http.Client{Timeout: 30 * time.Second}
+                resp, err := client.Get(location)
+                if err != nil {
+                        return nil, err
+                }
+                defer resp.Body.Close()
+                if resp.StatusCode != http.StatusOK {
+                        return nil, fmt.Errorf("fetching %s: %s", location, resp.Status)
+                }
+                if data, err = io.ReadAll(resp.Body); err != nil {
+                        return nil, err
+                }
+        } else if data, err = os.ReadFile(location); err != nil {
+                return nil, err
+        }
+        var spec map[string]interface{}
+        if err := yaml.Unmarshal(data, &spec); err != nil {
+                return nil, fmt.Errorf("parsing %s: %w", location, err)
+        }
+        if _, ok := spec["openapi"]; !ok {
+                return nil, fmt.Errorf("%s is not an OpenAPI 3 document", location)
+        }
+        return spec, nil
+}
+
+// RegisterOpenAPITools registers a tool for every operation in spec. Tools
+// are named after operationId, or the method and path if there is none,
+// prefixed with prefix. baseURL overrides the spec's first server URL.
+func RegisterOpenAPITools(spec map[string]interface{}, baseURL, prefix string, headers http.Header) (int, error) {
+        if baseURL == "" {
+                if servers, ok := spec["servers"].([]interface{}); ok && len(servers) > 0 {
+                        server, _ := servers[0].(map[string]interface{})
+                        baseURL, _ = server["url"].(string)
+                }
+        }
+        if _, err := url.Parse(baseURL); err != nil || !strings.HasPrefix(baseURL, "http") {
+                return 0, fmt.Errorf("the spec has no absolute server URL; set one with -openapi-base-url")
+        }
+        proxy := &openAPIProxy{
+                baseURL: strings.TrimSuffix(baseURL, "/"),
+                headers: headers,
+                client:  &http.Client{Timeout: RemoteRequestTimeout},
+        }
+
+        paths, _ := spec["paths"].(map[string]interface{})
+        pathNames := make([]string, 0, len(paths))
+        for p := range paths {
+                pathNames = append(pathNames, p)
+        }
+        sort.Strings(pathNames)
+
+        count := 0
+        for _, p := range pathNames {
+                item, _ := paths[p].(map[string]interface{})
+                shared, _ := item["parameters"].([]interface{})
+                for _, method := range openAPIMethods {
+                        op, ok := item[method].(map[string]interface{})
+                        if !ok {
+                                continue
+                        }
+                        name, _ := op["operationId"].(string)
+                        if name == "" {
+                                name = method + "_" + p
+                        }
+                        name = strings.Trim(toolNameUnsafe.ReplaceAllString(prefix+name, "_"), "_")
+                        if _, exists := findTool(name); exists {
+                                return count, fmt.Errorf("tool %s already exists; set -openapi-prefix", name)
+                        }
+
+                        params, _ := op["parameters"].([]interface{})
+                        operation, schema := openAPIToolSchema(spec, append(append([]interface{}{}, shared...), params...), op["requestBody"])
+                        operation.Method, operation.Path = strings.ToUpper(method), p
+                        scope := scopeOpenAPIWrite
+                        if operation.Method == http.MethodGet || operation.Method == http.MethodHead {
+                                scope = scopeOpenAPIRead
+                        }
+                        RegisterTool(&Tool{
+                                Name:        name,
+                                Description: openAPIDescription(op, operation),
+                                InputSchema: schema,
+                                Scopes:      []string{scope},
+                                Handler:     proxy.handler(operation),
+                        })
+                        count++
+                }
+        }
+        return count, nil
+}
+
+func openAPIDescription(op map[string]interface{}, operation openAPIOperation) string {
+        summary, _ := op["summary"].(string)
+        description, _ := op["description"].(string)
+        text := strings.TrimSpace(strings.Join([]string{summary, description}, "\n\n"))
+        if text == "" {
+                text = "Calls the upstream API"
+        }
+        return fmt.Sprintf("%s (%s %s)", text, operation.Method, operation.Path)
+}
+
+// openAPIToolSchema builds a tool's input schema from an operation's
+// parameters, which become arguments of the same name, and its JSON
+// request body, which becomes the "body" argument.
+func openAPIToolSchema(spec map[string]interface{}, params []interface{}, requestBody interface{}) (openAPIOperation, map[string]interface{}) {
+        var operation openAPIOperation
+        properties := map[string]interface{}{}
+        required := []string{}
+        seen := map[string]int{}
+        for _, raw := range params {
+                param, _ := resolveOpenAPIRef(spec, raw, 0).(map[string]interface{})
+                name, _ := param["name"].(string)
+                in, _ := param["in"].(string)
+                if name == "" || (in != "path" && in != "query" && in != "header") {
+                        continue
+                }
+                isRequired, _ := param["required"].(bool)
+                p := openAPIParam{Name: name, In: in, Required: isRequired || in == "path"}
+                // Operation parameters override path-level ones of the same name.
+                if i, ok := seen[name]; ok {
+                        operation.Params[i] = p
+                } else {
+                        seen[name] = len(operation.Params)
+                        operation.Params = append(operation.Params, p)
+                }
+
+                prop := map[string]interface{}{"type": "string"}
+                if schema, ok := resolveOpenAPIRef(spec, param["schema"], 0).(map[string]interface{}); ok {
+                        prop = schema
+                }
+                if description, ok := param["description"].(string); ok {
+                        prop = copyMap(prop)
+                        prop["description"] = description
+                }
+                properties[name] = prop
+        }
+        for _, p := range operation.Params {
+                if p.Required {
+                        required = append(required, p.Name)
+                }
+        }
+
+        if body, ok := resolveOpenAPIRef(spec, requestBody, 0).(map[string]interface{}); ok {
+                content, _ := body["content"].(map[string]interface{})
+                if media, ok := content["application/json"].(map[string]interface{}); ok {
+                        operation.HasBody = true
+                        prop := map[string]interface{}{}
+                        if schema, ok := resolveOpenAPIRef(spec, media["schema"], 0).(map[string]interface{}); ok {
+                                prop = copyMap(schema)
+                        }
+                        prop["description"] = "JSON request body"
+                        properties["body"] = prop
+                        if isRequired, _ := body["required"].(bool); isRequired {
+                                required = append(required, "body")
+                        }
+                }
+        }
+
+        schema := map[string]interface{}{
+                "type":       "object",
+                "properties": properties,
+        }
+        if len(required) > 0 {
+                schema["required"] = required
+        }
+        return operation, schema
+}
+
+// resolveOpenAPIRef replaces local "#/..." $refs in v with what they point
+// to, recursively, up to openAPIMaxRefDepth levels deep.
+func resolveOpenAPIRef(spec map[string]interface{}, v interface{}, depth int) interface{} {
+        switch node := v.(type) {
+        case map[string]interface{}:
+                if ref, ok := node["$ref"].(string); ok {
+                        if depth >= openAPIMaxRefDepth || !strings.HasPrefix(ref, "#/") {
+                                return map[string]interface{}{}
+                        }
+                        var target interface{} = spec
+                        for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+                                key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+                                m, _ := target.(map[string]interface{})
+                                target = m[key]
+                        }
+                        return resolveOpenAPIRef(spec, target, depth+1)
+                }
+                out := make(map[string]interface{}, len(node))
+                for k, child := range node {
+                        out[k] = resolveOpenAPIRef(spec, child, depth)
+                }
+                return out
+        case []interface{}:
+                out := make([]interface{}, len(node))
+                for i, child := range node {
+                        out[i] = resolveOpenAPIRef(spec, child, depth)
+                }
+                return out
+        }
+        return v
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+        out := make(map[string]interface{}, len(m))
+        for k, v := range m {
+                out[k] = v
+        }
+        return out
+}
+
+// handler returns the tool handler that performs operation upstream.
+func (p *openAPIProxy) handler(operation openAPIOperation) ToolHandler {
+        return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+                path := operation.Path
+                query := url.Values{}
+                header := http.Header{}
+                for name, values := range p.headers {
+                        for _, v := range values {
+                                header.Add(name, ExpandSecrets(v))
+                        }
+                }
+                for _, param := range operation.Params {
+                        raw, ok := args[param.Name]
+                        if !ok || raw == nil {
+                                if param.Required {
+                                        return nil, Errorf(ErrInvalidArgument, "%s is required", param.Name)
+                                }
+                                continue
+                        }
+                        value := JSONString(raw)
+                        switch param.In {
+                        case "path":
+                                path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(value))
+                        case "query":
+                                if items, ok := raw.([]interface{}); ok {
+                                        for _, item := range items {
+                                                query.Add(param.Name, JSONString(item))
+                                        }
+                                } else {
+                                        query.Set(param.Name, value)
+                                }
+                        case "header":
+                                header.Set(param.Name, value)
+                        }
+                }
+
+                target := p.baseURL + path
+                if len(query) > 0 {
+                        target += "?" + query.Encode()
+                }
+                var reader io.Reader
+                if body, ok := args["body"]; ok && operation.HasBody {
+                        data, err := json.Marshal(body)
+                        if err != nil {
+                                return nil, Errorf(ErrInvalidArgument, "Invalid body: %v", err)
+                        }
+                        reader = bytes.NewReader(data)
+                        header.Set("Content-Type", "application/json")
+                }
+                req, err := http.NewRequestWithContext(ctx, operation.Method, target, reader)
+                if err != nil {
+                        return nil, Errorf(ErrInvalidArgument, "%v", err)
+                }
+                req.Header = header
+                if req.Header.Get("Accept") == "" {
+                        req.Header.Set("Accept", "application/json")
+                }
+
+                req, span := StartClientSpan(req)
+                resp, err := p.client.Do(req)
+                EndClientSpan(span, resp, err)
+                if err != nil {
+                        return nil, Errorf(ErrUnavailable, "%s %s: %v", operation.Method, operation.Path, err)
+                }
+                defer resp.Body.Close()
+                data, err := io.ReadAll(io.LimitReader(resp.Body, openAPIResponseLimit))
+                if err != nil {
+                        return nil, Errorf(ErrUnavailable, "%v", err)
+                }
+                if resp.StatusCode < 200 || resp.StatusCode > 299 {
+                        return nil, Errorf(ErrUnavailable, "%s %s: %s: %s", operation.Method, operation.Path, resp.Status, strings.TrimSpace(string(data)))
+                }
+
+                result := map[string]interface{}{"status": resp.StatusCode}
+                if json.Valid(data) {
+                        result["body"] = json.RawMessage(data)
+                } else if len(data) > 0 {
+                        result["body"] = string(data)
+                }
+                return result, nil
+        }
+}
+
+// HeaderFlag collects repeated "Name: value" flags; values may reference
+// secrets as ${NAME}, which are expanded on each request.
+type HeaderFlag http.Header
+
+func (h HeaderFlag) String() string { return "" }
+
+func (h HeaderFlag) Set(value string) error {
+        name, v, ok := strings.Cut(value, ":")
+        if !ok || strings.TrimSpace(name) == "" {
+                return fmt.Errorf("want Name: value")
+        }
+        http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+        return nil
+}
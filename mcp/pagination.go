@@ -0,0 +1,76 @@
+package mcp
+
+import (
+        "encoding/base64"
+        "encoding/json"
+        "errors"
+        "strconv"
+)
+
+// listPageSize is the number of items returned per page by paginated list
+// methods.
+const listPageSize = 50
+
+type PaginatedParams struct {
+        Cursor string `json:"cursor,omitempty"`
+}
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// parseCursorParams extracts the cursor from a list request. List requests
+// may omit params entirely.
+func parseCursorParams(raw json.RawMessage) (string, error) {
+        if len(raw) == 0 {
+                return "", nil
+        }
+        var params PaginatedParams
+        if err := json.Unmarshal(raw, &params); err != nil {
+                return "", err
+        }
+        return params.Cursor, nil
+}
+
+// paginate returns the [start, end) window of a list of total items selected
+// by cursor, along with the cursor for the following page ("" on the last
+// page). Cursors are opaque to clients and encode the start offset.
+func paginate(total int, cursor string) (start, end int, next string, err error) {
+        if cursor != "" {
+                data, err := base64.RawURLEncoding.DecodeString(cursor)
+                if err != nil {
+                        return 0, 0, "", errInvalidCursor
+                }
+                start, err = strconv.Atoi(string(data))
+                if err != nil || start < 0 || start > total {
+                        return 0, 0, "", errInvalidCursor
+                }
+        }
+
+        end = start + listPageSize
+        if end >= total {
+                return start, total, "", nil
+        }
+        return start, end, base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end))), nil
+}
+
+// paginateRequest applies paginate to the cursor carried by req, returning a
+// ready-made error response when the params or cursor are invalid.
+func paginateRequest(req Request, total int) (start, end int, next string, errResp *Response) {
+        cursor, err := parseCursorParams(req.Params)
+        if err == nil {
+                start, end, next, err = paginate(total, cursor)
+        }
+        if err != nil {
+                message := "Invalid params"
+                if err == errInvalidCursor {
+                        message = err.Error()
+                }
+                return 0, 0, "", &Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: message,
+                        },
+                }
+        }
+        return start, end, next, nil
+}
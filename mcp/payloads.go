@@ -0,0 +1,146 @@
+package mcp
+
+import (
+        "bytes"
+        "encoding/json"
+        "regexp"
+        "strings"
+)
+
+// PayloadLog, set by -log-payloads, logs every message exchanged with
+// clients once it has been redacted. It is nil when payloads aren't logged.
+var PayloadLog *redactor
+
+// DefaultRedactFields are the object keys whose values are hidden by
+// default: credentials, email addresses, and free text such as ticket
+// descriptions and comment bodies.
+const DefaultRedactFields = "token,access_token,refresh_token,id_token,password,secret,client_secret,api_key,apikey,authorization,cookie,email,description,body"
+
+// defaultRedactValues match credentials and email addresses wherever they
+// appear in string values.
+var defaultRedactValues = []string{
+        `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+        `eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+}
+
+const redactedValue = "[REDACTED]"
+
+// redactor hides sensitive parts of JSON messages.
+type redactor struct {
+        // fields are lowercased object keys whose values are replaced
+        // entirely, at any depth.
+        fields map[string]bool
+        // values are patterns replaced within any string.
+        values []*regexp.Regexp
+}
+
+// NewRedactor hides the values of the comma-separated fields and the
+// matches of the patterns (defaultRedactValues if nil).
+func NewRedactor(fields string, patterns []string) (*redactor, error) {
+        r := &redactor{fields: map[string]bool{}}
+        for _, f := range strings.Split(fields, ",") {
+                if f = strings.TrimSpace(f); f != "" {
+                        r.fields[strings.ToLower(f)] = true
+                }
+        }
+        if patterns == nil {
+                patterns = defaultRedactValues
+        }
+        for _, p := range patterns {
+                if p == "" {
+                        continue
+                }
+                re, err := regexp.Compile(p)
+                if err != nil {
+                        return nil, err
+                }
+                r.values = append(r.values, re)
+        }
+        return r, nil
+}
+
+// redact returns data with sensitive fields and values replaced, also
+// within strings that hold JSON. Data that isn't valid JSON is treated as
+// a single string.
+func (r *redactor) redact(data []byte) json.RawMessage {
+        decoder := json.NewDecoder(bytes.NewReader(data))
+        decoder.UseNumber()
+        var v interface{}
+        if err := decoder.Decode(&v); err != nil {
+                v = string(data)
+        }
+        out, err := json.Marshal(r.redactValue(v))
+        if err != nil {
+                return json.RawMessage(`"` + redactedValue + `"`)
+        }
+        return out
+}
+
+func (r *redactor) redactValue(v interface{}) interface{} {
+        switch v := v.(type) {
+        case map[string]interface{}:
+                for k, field := range v {
+                        if r.fields[strings.ToLower(k)] {
+                                v[k] = redactedValue
+                        } else {
+                                v[k] = r.redactValue(field)
+                        }
+                }
+        case []interface{}:
+                for i, item := range v {
+                        v[i] = r.redactValue(item)
+                }
+        case string:
+                // Tool results carry their output as JSON text; redact it
+                // like the rest of the message.
+                if embedded, ok := embeddedJSON(v); ok {
+                        if out, err := json.Marshal(r.redactValue(embedded)); err == nil {
+                                return string(out)
+                        }
+                }
+                for _, re := range r.values {
+                        v = re.ReplaceAllString(v, redactedValue)
+                }
+                return v
+        }
+        return v
+}
+
+// embeddedJSON decodes s if it is a JSON object or array and nothing else.
+func embeddedJSON(s string) (interface{}, bool) {
+        trimmed := strings.TrimSpace(s)
+        if trimmed == "" || trimmed[0] != '{' && trimmed[0] != '[' {
+                return nil, false
+        }
+        decoder := json.NewDecoder(strings.NewReader(trimmed))
+        decoder.UseNumber()
+        var v interface{}
+        if decoder.Decode(&v) != nil || decoder.More() {
+                return nil, false
+        }
+        return v, true
+}
+
+// LogPayload logs a message received from (direction "in") or sent to
+// ("out") c, if payload logging is on, and records it if sessions are
+// recorded.
+func (c *Conn) LogPayload(direction string, data []byte) {
+        c.record(direction, data)
+        if PayloadLog == nil {
+                return
+        }
+        c.logger.Info("Payload", "direction", direction, "payload", PayloadLog.redact(data))
+}
+
+// PatternsFlag collects the patterns of a repeated flag.
+type PatternsFlag []string
+
+func (p *PatternsFlag) String() string { return strings.Join(*p, " ") }
+
+func (p *PatternsFlag) Set(value string) error {
+        if _, err := regexp.Compile(value); err != nil {
+                return err
+        }
+        *p = append(*p, value)
+        return nil
+}
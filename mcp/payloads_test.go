@@ -0,0 +1,36 @@
+package mcp
+
+import (
+        "encoding/json"
+        "strings"
+        "testing"
+)
+
+func TestRedactEmbeddedJSON(t *testing.T) {
+        r, err := NewRedactor(DefaultRedactFields, nil)
+        if err != nil {
+                t.Fatal(err)
+        }
+        output, _ := json.Marshal(map[string]interface{}{"ticket": map[string]interface{}{
+                "id": "T1", "description": "Call me at 555-0100", "assignee": "ana@example.com",
+        }})
+        message, _ := json.Marshal(Response{ID: "1", Result: CallToolResult{
+                Content:           []ToolContent{{Type: "text", Text: string(output)}},
+                StructuredContent: json.RawMessage(output),
+        }})
+
+        got := string(r.redact(message))
+        for _, secret := range []string{"555-0100", "ana@example.com"} {
+                if strings.Contains(got, secret) {
+                        t.Errorf("%q not redacted from %s", secret, got)
+                }
+        }
+        if !strings.Contains(got, `\"id\":\"T1\"`) {
+                t.Errorf("embedded result mangled: %s", got)
+        }
+
+        // Text that only starts like JSON is left as text.
+        if got, want := string(r.redact([]byte(`"[draft] mail bob@example.com"`))), `"[draft] mail [REDACTED]"`; got != want {
+                t.Errorf("got %s, want %s", got, want)
+        }
+}
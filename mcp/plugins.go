@@ -0,0 +1,375 @@
+package mcp
+
+import (
+        "bufio"
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "log/slog"
+        "os"
+        "os/exec"
+        "strings"
+        "sync"
+        "time"
+)
+
+// pluginStartTimeout bounds how long a plugin has to list its tools.
+const pluginStartTimeout = 10 * time.Second
+
+// pluginStopTimeout is how long a plugin has to exit once its stdin is
+// closed before it is killed.
+const pluginStopTimeout = 5 * time.Second
+
+// A plugin that exits is started again by the next call. If it exits
+// within pluginStableAfter of starting more than once in a row, the next
+// start waits pluginRestartDelay, doubling with each further such exit up
+// to pluginMaxRestartDelay, so one that crashes at once isn't run again on
+// every call.
+const (
+        pluginStableAfter     = time.Minute
+        pluginRestartDelay    = time.Second
+        pluginMaxRestartDelay = time.Minute
+)
+
+// PluginsFlag collects the commands of a repeated -plugin flag.
+type PluginsFlag []string
+
+func (p *PluginsFlag) String() string { return strings.Join(*p, " ") }
+
+func (p *PluginsFlag) Set(value string) error {
+        if strings.TrimSpace(value) == "" {
+                return fmt.Errorf("plugin command is empty")
+        }
+        *p = append(*p, value)
+        return nil
+}
+
+// plugins are those loaded, to be stopped at shutdown.
+var plugins struct {
+        sync.Mutex
+        list []*plugin
+}
+
+// plugin is an executable serving tools over its stdin and stdout, one
+// JSON-RPC message per line. It is started when loaded and again on the
+// next call if it exits.
+type plugin struct {
+        name   string
+        argv   []string
+        logger *slog.Logger
+
+        mu    sync.Mutex
+        cmd   *exec.Cmd
+        stdin io.WriteCloser
+        // writeMu keeps requests to stdin whole. It isn't mu, which a plugin
+        // that stops reading would otherwise keep held, stalling its
+        // responses and restart along with every other call.
+        writeMu sync.Mutex
+        // exited is closed once the running plugin has exited.
+        exited  chan struct{}
+        pending map[int64]chan pluginResponse
+        nextID  int64
+        // started is when the running plugin was started. crashes counts
+        // the exits in a row that came sooner than pluginStableAfter, and
+        // the plugin isn't started again before restartAt.
+        started   time.Time
+        crashes   int
+        restartAt time.Time
+}
+
+type pluginRequest struct {
+        JSONRPC string      `json:"jsonrpc"`
+        ID      int64       `json:"id"`
+        Method  string      `json:"method"`
+        Params  interface{} `json:"params,omitempty"`
+}
+
+type pluginResponse struct {
+        ID     int64           `json:"id"`
+        Result json.RawMessage `json:"result,omitempty"`
+        Error  *Error          `json:"error,omitempty"`
+}
+
+// pluginTool is a tool as a plugin lists it.
+type pluginTool struct {
+        Name        string                 `json:"name"`
+        Description string                 `json:"description"`
+        InputSchema map[string]interface{} `json:"inputSchema"`
+        // Scopes default to plugin:read for read-only tools and
+        // plugin:write for the rest.
+        Scopes   []string `json:"scopes"`
+        ReadOnly bool     `json:"readOnly"`
+}
+
+// LoadPlugin starts the plugin run by command, a program and its
+// arguments separated by spaces, and registers the tools it lists,
+// returning how many.
+func LoadPlugin(command string) (int, error) {
+        argv := strings.Fields(command)
+        if len(argv) == 0 {
+                return 0, fmt.Errorf("plugin command is empty")
+        }
+        name := strings.Join(argv, " ")
+        p := &plugin{name: name, argv: argv, logger: slog.Default().With("plugin", name)}
+
+        ctx, cancel := context.WithTimeout(context.Background(), pluginStartTimeout)
+        defer cancel()
+        data, err := p.call(ctx, "tools/list", nil)
+        if err != nil {
+                p.stop()
+                return 0, fmt.Errorf("%s: listing tools: %w", name, err)
+        }
+        var listed struct {
+                Tools []pluginTool `json:"tools"`
+        }
+        if err := json.Unmarshal(data, &listed); err != nil {
+                p.stop()
+                return 0, fmt.Errorf("%s: listing tools: %w", name, err)
+        }
+
+        var defined []*Tool
+        for i, pt := range listed.Tools {
+                t, err := p.tool(pt)
+                if err == nil {
+                        if _, exists := findTool(t.Name); exists {
+                                err = fmt.Errorf("already exists")
+                        }
+                        for _, other := range defined {
+                                if other.Name == t.Name {
+                                        err = fmt.Errorf("is listed twice")
+                                }
+                        }
+                }
+                if err != nil {
+                        p.stop()
+                        if pt.Name != "" {
+                                return 0, fmt.Errorf("%s: tool %s: %w", name, pt.Name, err)
+                        }
+                        return 0, fmt.Errorf("%s: tool %d: %w", name, i+1, err)
+                }
+                defined = append(defined, t)
+        }
+        for _, t := range defined {
+                RegisterTool(t)
+        }
+        plugins.Lock()
+        plugins.list = append(plugins.list, p)
+        plugins.Unlock()
+        return len(defined), nil
+}
+
+// StopPlugins closes the stdin of every plugin and waits for them to exit,
+// killing those that take too long.
+func StopPlugins() {
+        plugins.Lock()
+        defer plugins.Unlock()
+        var wg sync.WaitGroup
+        for _, p := range plugins.list {
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        p.stop()
+                }()
+        }
+        wg.Wait()
+}
+
+// tool checks pt and builds the tool that calls it.
+func (p *plugin) tool(pt pluginTool) (*Tool, error) {
+        switch {
+        case pt.Name == "":
+                return nil, fmt.Errorf("name is required")
+        case toolNameUnsafe.MatchString(pt.Name):
+                return nil, fmt.Errorf("names may only contain letters, digits, _ and -")
+        }
+        schema := pt.InputSchema
+        if schema == nil {
+                schema = NoArgsSchema()
+        } else if schema["type"] != "object" {
+                return nil, fmt.Errorf("inputSchema must have type object")
+        }
+        scopes := pt.Scopes
+        if scopes != nil && len(scopes) == 0 {
+                return nil, fmt.Errorf("scopes must not be empty; leave it out for the default")
+        }
+        if scopes == nil {
+                scopes = []string{scopePluginWrite}
+                if pt.ReadOnly {
+                        scopes = []string{scopePluginRead}
+                }
+        }
+        name := pt.Name
+        return &Tool{
+                Name:        name,
+                Description: pt.Description,
+                InputSchema: schema,
+                Scopes:      scopes,
+                Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+                        raw, err := p.call(ctx, "tools/call", ToolCallParams{Name: name, Arguments: args})
+                        if err != nil {
+                                return nil, err
+                        }
+                        var result CallToolResult
+                        if err := json.Unmarshal(raw, &result); err != nil || result.Content == nil {
+                                return nil, Errorf(ErrUnavailable, "Plugin tool %s returned no content", name)
+                        }
+                        return result, nil
+                },
+        }, nil
+}
+
+// call sends the plugin a request and waits for its result, starting the
+// plugin first if it isn't running. An error the plugin returns is passed
+// to the client as is.
+func (p *plugin) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+        p.mu.Lock()
+        if p.cmd == nil {
+                if wait := time.Until(p.restartAt); wait > 0 {
+                        p.mu.Unlock()
+                        return nil, Errorf(ErrUnavailable, "plugin %s keeps exiting; restarting it in %s", p.name, wait.Round(time.Second))
+                }
+                if err := p.start(); err != nil {
+                        p.mu.Unlock()
+                        return nil, Errorf(ErrUnavailable, "starting plugin %s: %v", p.name, err)
+                }
+        }
+        p.nextID++
+        id := p.nextID
+        ch := make(chan pluginResponse, 1)
+        p.pending[id] = ch
+        stdin := p.stdin
+        p.mu.Unlock()
+
+        data, err := json.Marshal(pluginRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+        if err == nil {
+                p.writeMu.Lock()
+                _, err = stdin.Write(append(data, '\n'))
+                p.writeMu.Unlock()
+        }
+        if err != nil {
+                p.mu.Lock()
+                delete(p.pending, id)
+                p.mu.Unlock()
+                return nil, Errorf(ErrUnavailable, "plugin %s: %v", p.name, err)
+        }
+
+        select {
+        case resp := <-ch:
+                if resp.Error != nil {
+                        return nil, resp.Error
+                }
+                return resp.Result, nil
+        case <-ctx.Done():
+                p.mu.Lock()
+                delete(p.pending, id)
+                p.mu.Unlock()
+                return nil, Errorf(ErrUnavailable, "plugin %s: %v", p.name, ctx.Err())
+        }
+}
+
+// start runs the plugin; p.mu must be held.
+func (p *plugin) start() error {
+        cmd := exec.Command(p.argv[0], p.argv[1:]...)
+        stdin, err := cmd.StdinPipe()
+        if err != nil {
+                return err
+        }
+        stdout, err := cmd.StdoutPipe()
+        if err != nil {
+                return err
+        }
+        cmd.Stderr = pluginStderr{p.logger}
+        if err := cmd.Start(); err != nil {
+                return err
+        }
+        p.cmd, p.stdin, p.exited = cmd, stdin, make(chan struct{})
+        p.started = time.Now()
+        p.pending = make(map[int64]chan pluginResponse)
+        p.logger.Info("Started plugin", "pid", cmd.Process.Pid)
+        go p.readResponses(stdout)
+        return nil
+}
+
+// readResponses delivers the plugin's responses until it exits, then
+// fails the calls still waiting so the next call starts it again.
+func (p *plugin) readResponses(stdout io.Reader) {
+        reader := bufio.NewReader(stdout)
+        for {
+                line, err := reader.ReadBytes('\n')
+                if len(strings.TrimSpace(string(line))) > 0 {
+                        var resp pluginResponse
+                        if jsonErr := json.Unmarshal(line, &resp); jsonErr != nil {
+                                p.logger.Warn("Ignoring invalid plugin output", "error", jsonErr)
+                        } else {
+                                p.mu.Lock()
+                                ch, ok := p.pending[resp.ID]
+                                delete(p.pending, resp.ID)
+                                p.mu.Unlock()
+                                if ok {
+                                        ch <- resp
+                                }
+                        }
+                }
+                if err != nil {
+                        break
+                }
+        }
+
+        p.mu.Lock()
+        cmd := p.cmd
+        p.mu.Unlock()
+        waitErr := cmd.Wait()
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        for id, ch := range p.pending {
+                ch <- pluginResponse{ID: id, Error: &Error{Code: -32000, Message: fmt.Sprintf("plugin %s exited", p.name)}}
+        }
+        close(p.exited)
+        p.cmd, p.stdin, p.exited, p.pending = nil, nil, nil, nil
+        if time.Since(p.started) >= pluginStableAfter {
+                p.crashes = 0
+        }
+        p.crashes++
+        var delay time.Duration
+        if p.crashes > 1 {
+                delay = min(pluginRestartDelay<<min(p.crashes-2, 6), pluginMaxRestartDelay)
+        }
+        p.restartAt = time.Now().Add(delay)
+        if waitErr != nil {
+                p.logger.Warn("Plugin exited", "error", waitErr, "restart_delay", delay)
+        } else {
+                p.logger.Info("Plugin exited", "restart_delay", delay)
+        }
+}
+
+// pluginStderr logs what a plugin writes to stderr.
+type pluginStderr struct{ logger *slog.Logger }
+
+func (w pluginStderr) Write(data []byte) (int, error) {
+        for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+                w.logger.Info("Plugin output", "stderr", line)
+        }
+        return len(data), nil
+}
+
+// stop closes the plugin's stdin, which asks it to exit, and kills it if
+// it is still running after pluginStopTimeout.
+func (p *plugin) stop() {
+        p.mu.Lock()
+        cmd, stdin, exited := p.cmd, p.stdin, p.exited
+        p.mu.Unlock()
+        if cmd == nil {
+                return
+        }
+        stdin.Close()
+        select {
+        case <-exited:
+        case <-time.After(pluginStopTimeout):
+                if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+                        p.logger.Warn("Killing plugin", "error", err)
+                }
+                <-exited
+        }
+}
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+        "context"
+        "log/slog"
+        "os"
+        "path/filepath"
+        "strings"
+        "testing"
+)
+
+func TestPluginToolScopes(t *testing.T) {
+        p := &plugin{name: "test"}
+        if _, err := p.tool(pluginTool{Name: "shout", Scopes: []string{}}); err == nil {
+                t.Error("empty scopes: got no error")
+        }
+        tool, err := p.tool(pluginTool{Name: "shout", ReadOnly: true})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if len(tool.Scopes) != 1 || tool.Scopes[0] != scopePluginRead {
+                t.Errorf("read-only tool: got scopes %q", tool.Scopes)
+        }
+}
+
+func TestPluginRestartDelay(t *testing.T) {
+        // The plugin records each start, then exits on its first request.
+        dir := t.TempDir()
+        script := filepath.Join(dir, "crash.sh")
+        starts := filepath.Join(dir, "starts")
+        if err := os.WriteFile(script, []byte("#!/bin/sh\necho >> "+starts+"\nread line\nexit 1\n"), 0o755); err != nil {
+                t.Fatal(err)
+        }
+        p := &plugin{name: "crash", argv: []string{script}, logger: slog.Default()}
+        defer p.stop()
+
+        countStarts := func() int {
+                data, _ := os.ReadFile(starts)
+                return strings.Count(string(data), "\n")
+        }
+        ctx := context.Background()
+        // The first exit is followed by an immediate restart, the second
+        // isn't.
+        for i := 1; i <= 3; i++ {
+                _, err := p.call(ctx, "tools/call", nil)
+                if err == nil {
+                        t.Fatalf("call %d: got no error", i)
+                }
+                if i == 3 && !strings.Contains(err.Error(), "restarting it in") {
+                        t.Errorf("call %d: got %v, want a restart delay", i, err)
+                }
+                waitFor(t, func() bool {
+                        p.mu.Lock()
+                        defer p.mu.Unlock()
+                        return p.cmd == nil
+                })
+        }
+        if n := countStarts(); n != 2 {
+                t.Errorf("plugin started %d times, want 2", n)
+        }
+}
+
+func TestPluginNotReadingDoesntHoldLock(t *testing.T) {
+        script := filepath.Join(t.TempDir(), "deaf.sh")
+        if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+                t.Fatal(err)
+        }
+        p := &plugin{name: "deaf", argv: []string{script}, logger: slog.Default()}
+
+        // The request is larger than a pipe holds, so writing it blocks
+        // until the plugin exits.
+        done := make(chan error, 1)
+        go func() {
+                _, err := p.call(context.Background(), "tools/call", strings.Repeat("x", 1<<20))
+                done <- err
+        }()
+        waitFor(t, func() bool {
+                if !p.mu.TryLock() {
+                        return false
+                }
+                defer p.mu.Unlock()
+                return len(p.pending) == 1
+        })
+
+        p.mu.Lock()
+        cmd := p.cmd
+        p.mu.Unlock()
+        cmd.Process.Kill()
+        if err := <-done; err == nil {
+                t.Error("call to killed plugin: got no error")
+        }
+}
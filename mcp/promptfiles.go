@@ -0,0 +1,210 @@
+package mcp
+
+import (
+        "bytes"
+        "context"
+        "fmt"
+        "os"
+        "path/filepath"
+        "sort"
+        "strings"
+        "time"
+
+        "gopkg.in/yaml.v3"
+)
+
+// promptReloadInterval is how often the prompt directory is checked for
+// added, removed, or modified templates.
+const promptReloadInterval = 2 * time.Second
+
+// promptFile is the on-disk form of a prompt template. Markdown files carry
+// these fields as YAML front matter and use the body as a single message;
+// YAML files list their messages explicitly.
+type promptFile struct {
+        Name        string           `yaml:"name"`
+        Description string           `yaml:"description"`
+        Role        string           `yaml:"role"`
+        Arguments   []PromptArgument `yaml:"arguments"`
+        // Resources are embedded ahead of the body of Markdown templates.
+        Resources []string `yaml:"resources"`
+        Messages  []struct {
+                Role     string `yaml:"role"`
+                Text     string `yaml:"text"`
+                Resource string `yaml:"resource"`
+        } `yaml:"messages"`
+}
+
+func isPromptFile(name string) bool {
+        switch strings.ToLower(filepath.Ext(name)) {
+        case ".md", ".yaml", ".yml":
+                return true
+        }
+        return false
+}
+
+func loadPromptFile(path string) (*PromptTemplate, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return nil, err
+        }
+
+        var pf promptFile
+        var body string
+        if strings.ToLower(filepath.Ext(path)) == ".md" {
+                frontMatter, rest, err := splitFrontMatter(data)
+                if err != nil {
+                        return nil, err
+                }
+                if err := yaml.Unmarshal(frontMatter, &pf); err != nil {
+                        return nil, fmt.Errorf("front matter: %v", err)
+                }
+                body = strings.TrimSpace(string(rest))
+        } else if err := yaml.Unmarshal(data, &pf); err != nil {
+                return nil, err
+        }
+
+        if pf.Name == "" {
+                pf.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+        }
+
+        p := &PromptTemplate{
+                Prompt: Prompt{
+                        Name:        pf.Name,
+                        Description: pf.Description,
+                        Arguments:   pf.Arguments,
+                },
+        }
+        for _, arg := range pf.Arguments {
+                if arg.Name == "" {
+                        return nil, fmt.Errorf("argument without a name")
+                }
+        }
+        role := pf.Role
+        if role == "" {
+                role = "user"
+        }
+        for _, uri := range pf.Resources {
+                p.Messages = append(p.Messages, PromptMessageTemplate{Role: role, ResourceURI: uri})
+        }
+        if body != "" {
+                p.Messages = append(p.Messages, PromptMessageTemplate{Role: role, Text: body})
+        }
+        for _, m := range pf.Messages {
+                role := m.Role
+                if role == "" {
+                        role = "user"
+                }
+                if m.Text == "" && m.Resource == "" {
+                        return nil, fmt.Errorf("message needs text or a resource")
+                }
+                p.Messages = append(p.Messages, PromptMessageTemplate{Role: role, Text: m.Text, ResourceURI: m.Resource})
+        }
+        if len(p.Messages) == 0 {
+                return nil, fmt.Errorf("template has no messages")
+        }
+        return p, nil
+}
+
+// splitFrontMatter separates a leading "---" delimited YAML block from the
+// Markdown body. Files without front matter are returned as body only.
+func splitFrontMatter(data []byte) (frontMatter, body []byte, err error) {
+        data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+        if !bytes.HasPrefix(data, []byte("---")) {
+                return nil, data, nil
+        }
+        rest := data[3:]
+        end := bytes.Index(rest, []byte("\n---"))
+        if end < 0 {
+                return nil, nil, fmt.Errorf("unterminated front matter")
+        }
+        body = rest[end+4:]
+        if i := bytes.IndexByte(body, '\n'); i >= 0 {
+                body = body[i+1:]
+        } else {
+                body = nil
+        }
+        return rest[:end], body, nil
+}
+
+// loadPromptDir parses every template in dir. Invalid files are logged and
+// skipped so one bad template doesn't take the others down with it.
+func loadPromptDir(dir string) ([]*PromptTemplate, error) {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+                return nil, err
+        }
+
+        var templates []*PromptTemplate
+        for _, entry := range entries {
+                if entry.IsDir() || !isPromptFile(entry.Name()) {
+                        continue
+                }
+                path := filepath.Join(dir, entry.Name())
+                p, err := loadPromptFile(path)
+                if err != nil {
+                        LogAll(context.Background(), LevelWarning, "Skipping prompt template %s: %v", path, err)
+                        continue
+                }
+                templates = append(templates, p)
+        }
+        return templates, nil
+}
+
+// promptDirSignature summarizes the template files in dir so changes can be
+// detected without re-parsing everything on each poll.
+func promptDirSignature(dir string) string {
+        entries, err := os.ReadDir(dir)
+        if err != nil {
+                return "error: " + err.Error()
+        }
+        var parts []string
+        for _, entry := range entries {
+                if entry.IsDir() || !isPromptFile(entry.Name()) {
+                        continue
+                }
+                info, err := entry.Info()
+                if err != nil {
+                        continue
+                }
+                parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+        }
+        sort.Strings(parts)
+        return strings.Join(parts, "|")
+}
+
+// promptsListChanged reports whether the prompt list can change at runtime,
+// which is advertised to clients in the prompts capability.
+var promptsListChanged bool
+
+func ReloadPromptDir(dir string) bool {
+        templates, err := loadPromptDir(dir)
+        if err != nil {
+                LogAll(context.Background(), LevelError, "Prompt directory error: %v", err)
+                return false
+        }
+        setFilePrompts(templates)
+        LogAll(context.Background(), LevelInfo, "Loaded %d prompt template(s) from %s", len(templates), dir)
+        return true
+}
+
+// WatchPromptDir loads the templates in dir and reloads them whenever the
+// directory contents change, telling connected clients that the prompt list
+// changed.
+func WatchPromptDir(dir string) {
+        promptsListChanged = true
+        signature := promptDirSignature(dir)
+        ReloadPromptDir(dir)
+
+        go func() {
+                for range time.Tick(promptReloadInterval) {
+                        current := promptDirSignature(dir)
+                        if current == signature {
+                                continue
+                        }
+                        signature = current
+                        if ReloadPromptDir(dir) {
+                                NotifyAll(context.Background(), "notifications/prompts/list_changed", nil)
+                        }
+                }
+        }()
+}
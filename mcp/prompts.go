@@ -0,0 +1,212 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "sort"
+        "strings"
+        "sync"
+)
+
+type PromptArgument struct {
+        Name        string `json:"name"`
+        Description string `json:"description,omitempty"`
+        Required    bool   `json:"required,omitempty"`
+}
+
+type Prompt struct {
+        Name        string           `json:"name"`
+        Description string           `json:"description,omitempty"`
+        Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptContent struct {
+        Type     string            `json:"type"`
+        Text     string            `json:"text,omitempty"`
+        Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+type PromptMessage struct {
+        Role    string        `json:"role"`
+        Content PromptContent `json:"content"`
+}
+
+type PromptGetParams struct {
+        Name      string            `json:"name"`
+        Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptMessageTemplate is a message whose text may reference prompt
+// arguments as {{name}}. A message with a ResourceURI embeds that resource
+// instead of text; the URI may reference arguments too.
+type PromptMessageTemplate struct {
+        Role        string
+        Text        string
+        ResourceURI string
+}
+
+type PromptTemplate struct {
+        Prompt
+        Messages []PromptMessageTemplate
+}
+
+// render substitutes args into the template's messages and inlines embedded
+// resources loaded with resolve. Optional arguments that were not supplied
+// render as empty strings.
+func (p *PromptTemplate) render(args map[string]string, resolve func(uri string) (ResourceContents, error)) ([]PromptMessage, error) {
+        pairs := make([]string, 0, len(p.Arguments)*2)
+        for _, arg := range p.Arguments {
+                value, ok := args[arg.Name]
+                if !ok && arg.Required {
+                        return nil, fmt.Errorf("Missing required argument: %s", arg.Name)
+                }
+                pairs = append(pairs, "{{"+arg.Name+"}}", value)
+        }
+        replacer := strings.NewReplacer(pairs...)
+
+        messages := make([]PromptMessage, 0, len(p.Messages))
+        for _, m := range p.Messages {
+                if m.ResourceURI != "" {
+                        contents, err := resolve(replacer.Replace(m.ResourceURI))
+                        if err != nil {
+                                return nil, err
+                        }
+                        messages = append(messages, PromptMessage{
+                                Role: m.Role,
+                                Content: PromptContent{
+                                        Type:     "resource",
+                                        Resource: &contents,
+                                },
+                        })
+                        continue
+                }
+                messages = append(messages, PromptMessage{
+                        Role: m.Role,
+                        Content: PromptContent{
+                                Type: "text",
+                                Text: replacer.Replace(m.Text),
+                        },
+                })
+        }
+        return messages, nil
+}
+
+// prompts holds the built-in templates in m and the templates loaded from the
+// prompt directory in files. File templates take precedence over built-ins
+// with the same name.
+var prompts = struct {
+        sync.RWMutex
+        m     map[string]*PromptTemplate
+        files map[string]*PromptTemplate
+}{
+        m:     make(map[string]*PromptTemplate),
+        files: make(map[string]*PromptTemplate),
+}
+
+func RegisterPrompt(p *PromptTemplate) {
+        prompts.Lock()
+        prompts.m[p.Name] = p
+        prompts.Unlock()
+        registryChanged()
+}
+
+// setFilePrompts replaces the full set of directory-loaded templates.
+func setFilePrompts(templates []*PromptTemplate) {
+        files := make(map[string]*PromptTemplate, len(templates))
+        for _, p := range templates {
+                files[p.Name] = p
+        }
+        prompts.Lock()
+        prompts.files = files
+        prompts.Unlock()
+        registryChanged()
+}
+
+func findPrompt(name string) (*PromptTemplate, bool) {
+        prompts.RLock()
+        defer prompts.RUnlock()
+        if p, ok := prompts.files[name]; ok {
+                return p, true
+        }
+        p, ok := prompts.m[name]
+        return p, ok
+}
+
+// listPrompts returns the registered prompts ordered by name so pagination
+// cursors stay stable between calls.
+func listPrompts() []Prompt {
+        prompts.RLock()
+        defer prompts.RUnlock()
+        list := make([]Prompt, 0, len(prompts.m)+len(prompts.files))
+        for _, p := range prompts.files {
+                list = append(list, p.Prompt)
+        }
+        for name, p := range prompts.m {
+                if _, ok := prompts.files[name]; !ok {
+                        list = append(list, p.Prompt)
+                }
+        }
+        sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+        return list
+}
+
+func handlePromptsList(_ context.Context, req Request) Response {
+        return cachedPage(req, "", func() (interface{}, *Response) {
+                list := listPrompts()
+                start, end, next, errResp := paginateRequest(req, len(list))
+                if errResp != nil {
+                        return nil, errResp
+                }
+                return ListPromptsResult{Prompts: list[start:end], NextCursor: next}, nil
+        })
+}
+
+func handlePromptsGet(ctx context.Context, c *Conn, req Request) Response {
+        var params PromptGetParams
+        if err := json.Unmarshal(req.Params, &params); err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        prompt, ok := findPrompt(params.Name)
+        if !ok {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: fmt.Sprintf("Unknown prompt: %s", params.Name),
+                        },
+                }
+        }
+
+        messages, err := prompt.render(params.Arguments, func(uri string) (ResourceContents, error) {
+                entry, err := resolveResource(ctx, c, uri)
+                if err != nil {
+                        return ResourceContents{}, err
+                }
+                return entry.contents, nil
+        })
+        if err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: err.Error(),
+                        },
+                }
+        }
+
+        return Response{
+                ID: req.ID,
+                Result: GetPromptResult{
+                        Description: prompt.Description,
+                        Messages:    messages,
+                },
+        }
+}
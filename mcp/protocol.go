@@ -0,0 +1,52 @@
+// Package mcp implements the Model Context Protocol server side: sessions
+// and their connections, request dispatch, and the tool, resource, prompt,
+// completion, logging, and sampling registries, along with the metrics,
+// tracing, and limits around them. Transports hand it client messages
+// through Conn.Handle and deliver what it sends through a Transport.
+package mcp
+
+import "encoding/json"
+
+type Request struct {
+        ID     string          `json:"id"`
+        Method string          `json:"method"`
+        Params json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+        ID     string      `json:"id"`
+        Result interface{} `json:"result,omitempty"`
+        Error  *Error      `json:"error,omitempty"`
+}
+
+// jsonRPCVersion is the "jsonrpc" member of every message the server
+// sends.
+const jsonRPCVersion = "2.0"
+
+// MarshalJSON encodes the response, result and all, in one pass into a
+// pooled buffer. A json.RawMessage result is taken as already encoded and
+// only compacted.
+func (r Response) MarshalJSON() ([]byte, error) {
+        type response Response
+        return marshalPooled(&struct {
+                JSONRPC string `json:"jsonrpc"`
+                response
+        }{jsonRPCVersion, response(r)})
+}
+
+type Error struct {
+        Code    int         `json:"code"`
+        Message string      `json:"message"`
+        Data    interface{} `json:"data,omitempty"`
+}
+
+type InitializeParams struct {
+        ProtocolVersion string                 `json:"protocolVersion,omitempty"`
+        ClientInfo      ClientInfo             `json:"clientInfo"`
+        Capabilities    map[string]interface{} `json:"capabilities,omitempty"`
+}
+
+type ToolCallParams struct {
+        Name      string                 `json:"name"`
+        Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "testing"
+)
+
+func TestMessagesHaveJSONRPCVersion(t *testing.T) {
+        for _, msg := range []interface{}{
+                Response{ID: "1", Result: EmptyResult{}},
+                Response{ID: "2", Error: &Error{Code: -32601, Message: "Method not found"}},
+                Notification{Method: "notifications/tools/list_changed"},
+                ServerRequest{ID: "s1", Method: "ping"},
+        } {
+                data, err := json.Marshal(msg)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                var got struct {
+                        JSONRPC string `json:"jsonrpc"`
+                }
+                if err := json.Unmarshal(data, &got); err != nil || got.JSONRPC != "2.0" {
+                        t.Errorf("%s: got jsonrpc %q (%v)", data, got.JSONRPC, err)
+                }
+        }
+}
+
+func TestPing(t *testing.T) {
+        resp := DefaultMux.Handle(context.Background(), Request{ID: "7", Method: "ping"})
+        data, err := json.Marshal(resp)
+        if err != nil {
+                t.Fatal(err)
+        }
+        if want := `{"jsonrpc":"2.0","id":"7","result":{}}`; string(data) != want {
+                t.Errorf("got  %s\nwant %s", data, want)
+        }
+}
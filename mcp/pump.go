@@ -0,0 +1,53 @@
+package mcp
+
+import (
+        "context"
+
+        "go.opentelemetry.io/otel/trace"
+)
+
+// RequestWorkers is how many requests each connection handles at once,
+// set by -request-workers. Further requests queue, up to MaxInFlight.
+var RequestWorkers = 8
+
+// queuedRequest is a request waiting for a worker.
+type queuedRequest struct {
+        ctx  context.Context
+        span trace.Span
+        req  Request
+}
+
+// StartWorkers starts the connection's request workers. They stop when the
+// connection closes.
+func (c *Conn) StartWorkers() {
+        for i := 0; i < RequestWorkers; i++ {
+                go c.serveRequests()
+        }
+}
+
+// dispatch queues req for a worker, or fails it as busy if the queue is
+// full. It never waits: the read loop calls it, and the replies that
+// running requests wait for, to sampling and elicitation, come in through
+// that same loop.
+func (c *Conn) dispatch(ctx context.Context, span trace.Span, req Request) {
+        select {
+        case c.requests <- queuedRequest{ctx: ctx, span: span, req: req}:
+        default:
+                c.inFlight.Add(-1)
+                c.endRequest(ctx, req.ID)
+                span.End()
+                c.Logf(ctx, LevelWarning, "Rejecting request: %d already queued", cap(c.requests))
+                c.send(Response{ID: req.ID, Error: serverBusyError(cap(c.requests))})
+        }
+}
+
+func (c *Conn) serveRequests() {
+        for {
+                select {
+                case q := <-c.requests:
+                        respond(q.ctx, q.span, c, q.req)
+                case <-c.ctx.Done():
+                        return
+                }
+        }
+}
@@ -0,0 +1,227 @@
+package mcp
+
+import (
+        "fmt"
+        "math"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+)
+
+// Rate limits set by flags, for DefaultSettings; a zero rate disables the
+// limit.
+var (
+        ConnRequestLimit     RateLimit
+        ConnToolLimit        RateLimit
+        IdentityRequestLimit RateLimit
+        IdentityToolLimit    RateLimit
+)
+
+// RateLimits are the rates a connection, and all the connections of an
+// identity, may make requests and tool calls at.
+type RateLimits struct {
+        ConnRequests, ConnTools         RateLimit
+        IdentityRequests, IdentityTools RateLimit
+}
+
+// RateLimit is a token bucket rate: perSecond tokens are added each second
+// up to burst. It is set from flags as RATE[/s|/m|/h][:BURST], e.g. "20/s"
+// or "600/m:50"; the burst defaults to one second's worth of tokens, and at
+// least one.
+type RateLimit struct {
+        perSecond float64
+        burst     float64
+}
+
+func (l *RateLimit) String() string {
+        if l.perSecond == 0 {
+                return "0"
+        }
+        return fmt.Sprintf("%g/s:%g", l.perSecond, l.burst)
+}
+
+func (l *RateLimit) Set(value string) error {
+        spec, burst, hasBurst := strings.Cut(value, ":")
+        count, per, _ := strings.Cut(spec, "/")
+        n, err := strconv.ParseFloat(count, 64)
+        if err != nil || n < 0 {
+                return fmt.Errorf("invalid rate %q", value)
+        }
+        switch per {
+        case "", "s":
+        case "m":
+                n /= 60
+        case "h":
+                n /= 3600
+        default:
+                return fmt.Errorf("invalid rate unit %q; use s, m, or h", per)
+        }
+        l.perSecond, l.burst = n, math.Max(1, math.Ceil(n))
+        if hasBurst {
+                b, err := strconv.ParseFloat(burst, 64)
+                if err != nil || b < 1 {
+                        return fmt.Errorf("invalid burst %q", burst)
+                }
+                l.burst = b
+        }
+        return nil
+}
+
+func (l RateLimit) enabled() bool {
+        return l.perSecond > 0
+}
+
+// tokenBucket limits a stream of events to a RateLimit.
+type tokenBucket struct {
+        limit RateLimit
+
+        mu     sync.Mutex
+        tokens float64
+        last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+        return &tokenBucket{limit: limit, tokens: limit.burst, last: time.Now()}
+}
+
+// refill adds the tokens accrued since the last call; b.mu must be held.
+func (b *tokenBucket) refill(now time.Time) {
+        b.tokens = math.Min(b.limit.burst, b.tokens+now.Sub(b.last).Seconds()*b.limit.perSecond)
+        b.last = now
+}
+
+// wait returns how long until a token is available, without taking it.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+        b.mu.Lock()
+        defer b.mu.Unlock()
+        b.refill(now)
+        if b.tokens >= 1 {
+                return 0
+        }
+        return time.Duration((1 - b.tokens) / b.limit.perSecond * float64(time.Second))
+}
+
+func (b *tokenBucket) take() {
+        b.mu.Lock()
+        b.tokens--
+        b.mu.Unlock()
+}
+
+// full reports whether the bucket has been idle long enough to refill, so
+// dropping it loses nothing.
+func (b *tokenBucket) full(now time.Time) bool {
+        b.mu.Lock()
+        defer b.mu.Unlock()
+        b.refill(now)
+        return b.tokens >= b.limit.burst
+}
+
+// bucketTable holds buckets by key, dropping those that have refilled.
+// Its zero value is empty.
+type bucketTable struct {
+        mu      sync.Mutex
+        buckets map[string]*tokenBucket
+        swept   time.Time
+}
+
+// get returns the bucket for key, making one for limit if there is none.
+func (t *bucketTable) get(key string, limit RateLimit) *tokenBucket {
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        now := time.Now()
+        if now.Sub(t.swept) > time.Minute {
+                for k, b := range t.buckets {
+                        if b.full(now) {
+                                delete(t.buckets, k)
+                        }
+                }
+                t.swept = now
+        }
+        b, ok := t.buckets[key]
+        if !ok {
+                if t.buckets == nil {
+                        t.buckets = make(map[string]*tokenBucket)
+                }
+                b = newTokenBucket(limit)
+                t.buckets[key] = b
+        }
+        return b
+}
+
+// clear drops every bucket, so each starts full again.
+func (t *bucketTable) clear() {
+        t.mu.Lock()
+        clear(t.buckets)
+        t.mu.Unlock()
+}
+
+// connLimiter holds a connection's own buckets.
+type connLimiter struct {
+        requests *tokenBucket
+        tools    *tokenBucket
+}
+
+func newConnLimiter(requests, tools RateLimit) *connLimiter {
+        l := &connLimiter{}
+        if requests.enabled() {
+                l.requests = newTokenBucket(requests)
+        }
+        if tools.enabled() {
+                l.tools = newTokenBucket(tools)
+        }
+        return l
+}
+
+// limitedBucket is a bucket a request draws from and the name of the limit
+// it enforces, for error messages.
+type limitedBucket struct {
+        bucket *tokenBucket
+        name   string
+}
+
+// checkRateLimit takes a token from every bucket that applies to a request
+// for method, or returns a rate limited error if any of them is empty.
+// Tokens are only taken when all buckets allow the request.
+func (c *Conn) checkRateLimit(method string) *Error {
+        limiter, limits := c.limiter.Load(), c.Group().Settings().RateLimits
+        identities := &c.Group().identityBuckets
+        var buckets []limitedBucket
+        if limiter.requests != nil {
+                buckets = append(buckets, limitedBucket{limiter.requests, "requests per connection"})
+        }
+        if limits.IdentityRequests.enabled() && c.Session.Identity != "" {
+                buckets = append(buckets, limitedBucket{identities.get("requests\x00"+c.Session.Identity, limits.IdentityRequests), "requests per identity"})
+        }
+        if method == "tools/call" {
+                if limiter.tools != nil {
+                        buckets = append(buckets, limitedBucket{limiter.tools, "tool calls per connection"})
+                }
+                if limits.IdentityTools.enabled() && c.Session.Identity != "" {
+                        buckets = append(buckets, limitedBucket{identities.get("tools\x00"+c.Session.Identity, limits.IdentityTools), "tool calls per identity"})
+                }
+        }
+
+        now := time.Now()
+        var longest time.Duration
+        var exceeded string
+        for _, b := range buckets {
+                if wait := b.bucket.wait(now); wait > longest {
+                        longest, exceeded = wait, b.name
+                }
+        }
+        if longest > 0 {
+                return &Error{
+                        Code:    -32012,
+                        Message: fmt.Sprintf("Rate limited: too many %s; retry in %s", exceeded, longest.Round(time.Millisecond)),
+                        Data: map[string]interface{}{
+                                "limit":      exceeded,
+                                "retryAfter": math.Ceil(longest.Seconds()*1000) / 1000,
+                        },
+                }
+        }
+        for _, b := range buckets {
+                b.bucket.take()
+        }
+        return nil
+}
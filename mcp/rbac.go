@@ -0,0 +1,173 @@
+package mcp
+
+import (
+        "fmt"
+        "os"
+        "sort"
+        "strings"
+
+        "gopkg.in/yaml.v3"
+)
+
+// Scopes that tools require. A client may call a tool only if it holds
+// every scope the tool lists.
+const (
+        ScopeTicketsRead   = "tickets:read"
+        ScopeTicketsWrite  = "tickets:write"
+        ScopeTicketsAdmin  = "tickets:admin"
+        ScopeFilesRead     = "files:read"
+        scopeOpenAPIRead   = "openapi:read"
+        scopeOpenAPIWrite  = "openapi:write"
+        scopeManifestRead  = "manifest:read"
+        scopeManifestWrite = "manifest:write"
+        scopePluginRead    = "plugin:read"
+        scopePluginWrite   = "plugin:write"
+        ScopeServerRead    = "server:read"
+)
+
+// RBAC is the authorization policy loaded from -rbac, for DefaultSettings;
+// when nil every client may see and call every tool.
+var RBAC *RBACPolicy
+
+// RBACPolicy grants scopes to clients through roles. An identity's roles
+// come from the policy file and from a "roles" claim in its access token;
+// the scopes its token grants count too.
+type RBACPolicy struct {
+        Roles      map[string][]string `yaml:"roles"`
+        Identities map[string][]string `yaml:"identities"`
+        // Default lists the roles of identities the policy doesn't name,
+        // including unauthenticated clients.
+        Default []string `yaml:"default"`
+}
+
+func LoadRBACPolicy(path string) (*RBACPolicy, error) {
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return nil, err
+        }
+        var p RBACPolicy
+        if err := yaml.Unmarshal(data, &p); err != nil {
+                return nil, fmt.Errorf("parsing %s: %w", path, err)
+        }
+        for identity, roles := range p.Identities {
+                for _, role := range roles {
+                        if _, ok := p.Roles[role]; !ok {
+                                return nil, fmt.Errorf("%s: identity %s has undefined role %s", path, identity, role)
+                        }
+                }
+        }
+        for _, role := range p.Default {
+                if _, ok := p.Roles[role]; !ok {
+                        return nil, fmt.Errorf("%s: default role %s is undefined", path, role)
+                }
+        }
+        return &p, nil
+}
+
+// scopesFor returns the scopes policy p grants principal.
+func (p *RBACPolicy) scopesFor(pr Principal) scopeSet {
+        roles, ok := p.Identities[pr.Identity]
+        if !ok || pr.Identity == "" {
+                roles = p.Default
+        }
+        if claimed, ok := pr.Claims["roles"].([]interface{}); ok {
+                for _, role := range claimed {
+                        if role, ok := role.(string); ok {
+                                roles = append(roles, role)
+                        }
+                }
+        }
+
+        scopes := scopeSet{}
+        for _, role := range roles {
+                for _, scope := range p.Roles[role] {
+                        scopes[scope] = true
+                }
+        }
+        if scope, ok := pr.Claims["scope"].(string); ok {
+                for _, s := range strings.Fields(scope) {
+                        scopes[s] = true
+                }
+        }
+        if scp, ok := pr.Claims["scp"].([]interface{}); ok {
+                for _, s := range scp {
+                        if s, ok := s.(string); ok {
+                                scopes[s] = true
+                        }
+                }
+        }
+        return scopes
+}
+
+// scopeSet is the scopes a client holds. "*" grants every scope and
+// "tickets:*" every scope starting with "tickets:".
+type scopeSet map[string]bool
+
+func (s scopeSet) grants(scope string) bool {
+        if s[scope] || s["*"] {
+                return true
+        }
+        for held := range s {
+                if prefix, ok := strings.CutSuffix(held, "*"); ok && strings.HasPrefix(scope, prefix) {
+                        return true
+                }
+        }
+        return false
+}
+
+// missing returns the scopes in required that s doesn't grant.
+func (s scopeSet) missing(required []string) []string {
+        var missing []string
+        for _, scope := range required {
+                if !s.grants(scope) {
+                        missing = append(missing, scope)
+                }
+        }
+        return missing
+}
+
+func (s scopeSet) String() string {
+        list := make([]string, 0, len(s))
+        for scope := range s {
+                list = append(list, scope)
+        }
+        sort.Strings(list)
+        return strings.Join(list, " ")
+}
+
+// allows reports whether a client holding s may use something that
+// requires scopes. Something that requires none is only allowed if it is
+// public, so forgetting to declare scopes doesn't open it to everyone.
+func (s scopeSet) allows(scopes []string, public bool) bool {
+        if len(scopes) == 0 {
+                return public
+        }
+        return len(s.missing(scopes)) == 0
+}
+
+// canUseTool reports whether c may see and call t.
+func (c *Conn) canUseTool(t *Tool) bool {
+        return c.scopes == nil || c.scopes.allows(t.Scopes, t.Public)
+}
+
+// canReadResources reports whether c may list, read, and subscribe to p's
+// resources.
+func (c *Conn) canReadResources(p *ResourceProvider) bool {
+        return c.scopes == nil || c.scopes.allows(p.Scopes, p.Public)
+}
+
+// permissionError is returned when a client lacks the scopes for what it
+// asked to do.
+func permissionError(c *Conn, what string, scopes []string) *Error {
+        missing := c.scopes.missing(scopes)
+        if len(missing) == 0 {
+                return &Error{Code: -32011, Message: fmt.Sprintf("Permission denied: %s declares no scopes and isn't public", what)}
+        }
+        return &Error{Code: -32011, Message: fmt.Sprintf("Permission denied: %s requires scope %s", what, strings.Join(missing, ", "))}
+}
+
+// toolPermissionError is returned when a client calls a tool it lacks the
+// scopes for.
+func toolPermissionError(c *Conn, t *Tool) *Error {
+        return permissionError(c, t.Name, t.Scopes)
+}
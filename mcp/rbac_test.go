@@ -0,0 +1,32 @@
+package mcp
+
+import (
+        "log/slog"
+        "testing"
+)
+
+func TestScopesGuardToolsAndResources(t *testing.T) {
+        c := &Conn{scopes: scopeSet{ScopeTicketsRead: true}, logger: slog.New(slog.DiscardHandler)}
+        for _, tc := range []struct {
+                tool *Tool
+                want bool
+        }{
+                {&Tool{Name: "get_ticket", Scopes: []string{ScopeTicketsRead}}, true},
+                {&Tool{Name: "delete_ticket", Scopes: []string{ScopeTicketsWrite}}, false},
+                {&Tool{Name: "unscoped"}, false},
+                {&Tool{Name: "public", Public: true}, true},
+        } {
+                if got := c.canUseTool(tc.tool); got != tc.want {
+                        t.Errorf("canUseTool(%s) = %v, want %v", tc.tool.Name, got, tc.want)
+                }
+        }
+
+        files, _ := findResourceProvider(FileURIPrefix + "/etc/hosts")
+        if c.canReadResources(files) {
+                t.Error("file:// resources readable without files:read")
+        }
+        resp := handleResourcesRead(t.Context(), c, Request{ID: "1", Params: []byte(`{"uri":"file:///etc/hosts"}`)})
+        if resp.Error == nil || resp.Error.Code != -32011 {
+                t.Errorf("resources/read without files:read: got %+v, want error -32011", resp.Error)
+        }
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+        "bufio"
+        "encoding/json"
+        "fmt"
+        "io"
+        "os"
+        "path/filepath"
+        "sync"
+        "time"
+)
+
+// recordDir, set by -record-dir, is where sessions are recorded; empty
+// when they aren't.
+var recordDir string
+
+// RecordSessions makes every connection from now on append each message
+// it receives and sends to dir/<session ID>.jsonl, creating dir if need
+// be. Messages are recorded as they are, without redaction, so the files
+// are readable only by their owner. An empty dir stops recording new
+// connections.
+func RecordSessions(dir string) error {
+        if dir == "" {
+                recordDir = ""
+                return nil
+        }
+        if err := os.MkdirAll(dir, 0o700); err != nil {
+                return err
+        }
+        recordDir = dir
+        return nil
+}
+
+// Frame is a message of a recorded session, one per line of the recording.
+type Frame struct {
+        Time time.Time `json:"time"`
+        // Direction is "in" for a message from the client and "out" for
+        // one to it.
+        Direction string `json:"direction"`
+        // Message is the message if it is valid JSON; Text holds any other
+        // frame the client sent.
+        Message json.RawMessage `json:"message,omitempty"`
+        Text    string          `json:"text,omitempty"`
+}
+
+// NewFrame returns the frame of data sent in direction at t.
+func NewFrame(t time.Time, direction string, data []byte) Frame {
+        f := Frame{Time: t, Direction: direction}
+        if json.Valid(data) {
+                f.Message = json.RawMessage(data)
+        } else {
+                f.Text = string(data)
+        }
+        return f
+}
+
+// Data returns the frame as it was sent.
+func (f Frame) Data() []byte {
+        if f.Message != nil {
+                return f.Message
+        }
+        return []byte(f.Text)
+}
+
+// ReadRecording reads the frames of a session recorded by RecordSessions.
+func ReadRecording(r io.Reader) ([]Frame, error) {
+        var frames []Frame
+        scanner := bufio.NewScanner(r)
+        scanner.Buffer(nil, 1<<30)
+        for line := 1; scanner.Scan(); line++ {
+                if len(scanner.Bytes()) == 0 {
+                        continue
+                }
+                var f Frame
+                if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+                        return nil, fmt.Errorf("line %d: %w", line, err)
+                }
+                if f.Direction != "in" && f.Direction != "out" {
+                        return nil, fmt.Errorf("line %d: direction must be in or out, not %q", line, f.Direction)
+                }
+                frames = append(frames, f)
+        }
+        return frames, scanner.Err()
+}
+
+// recorder appends a connection's frames to its session's recording.
+type recorder struct {
+        mu   sync.Mutex
+        file *os.File
+        // failed stops recording after the first write error, which is
+        // logged.
+        failed bool
+}
+
+// startRecording opens the recording of c's session, if sessions are
+// recorded. A failure is logged, and the connection goes unrecorded.
+func (c *Conn) startRecording() {
+        if recordDir == "" {
+                return
+        }
+        path := filepath.Join(recordDir, filepath.Base(c.Session.ID)+".jsonl")
+        file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+        if err != nil {
+                c.logger.Error("Recording session", "error", err)
+                return
+        }
+        c.recorder = &recorder{file: file}
+}
+
+// record appends data, sent in direction, to c's recording.
+func (c *Conn) record(direction string, data []byte) {
+        r := c.recorder
+        if r == nil {
+                return
+        }
+        line, err := json.Marshal(NewFrame(time.Now().UTC(), direction, data))
+        if err != nil {
+                return
+        }
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        if r.failed {
+                return
+        }
+        if _, err := r.file.Write(append(line, '\n')); err != nil {
+                r.failed = true
+                c.logger.Error("Recording session", "error", err)
+        }
+}
+
+// stopRecording closes c's recording.
+func (c *Conn) stopRecording() {
+        r := c.recorder
+        if r == nil {
+                return
+        }
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.failed = true
+        r.file.Close()
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+        "context"
+        "fmt"
+        "time"
+
+        "github.com/redis/go-redis/v9"
+)
+
+// RedisQueryTimeout bounds each Redis operation.
+const RedisQueryTimeout = 5 * time.Second
+
+// NewRedisClient connects to the Redis server at dsn, a redis:// or
+// rediss:// URL, with the password from REDIS_PASSWORD if it is set.
+func NewRedisClient(dsn string) (*redis.Client, error) {
+        opts, err := redis.ParseURL(dsn)
+        if err != nil {
+                return nil, fmt.Errorf("parsing redis DSN: %w", err)
+        }
+        if Secret("REDIS_PASSWORD") != "" {
+                username := opts.Username
+                opts.CredentialsProvider = func() (string, string) {
+                        return username, Secret("REDIS_PASSWORD")
+                }
+        }
+        client := redis.NewClient(opts)
+        ctx, cancel := context.WithTimeout(context.Background(), RedisQueryTimeout)
+        defer cancel()
+        if err := client.Ping(ctx).Err(); err != nil {
+                client.Close()
+                return nil, fmt.Errorf("connecting to redis: %w", err)
+        }
+        return client, nil
+}
@@ -0,0 +1,375 @@
+package mcp
+
+import (
+        "context"
+        "crypto/sha256"
+        "encoding/hex"
+        "encoding/json"
+        "fmt"
+        "log/slog"
+        "strings"
+        "sync"
+        "time"
+)
+
+// resourceCacheTTL bounds how long a resources/read result is served from the
+// cache before the backend is consulted again.
+const resourceCacheTTL = 30 * time.Second
+
+type Resource struct {
+        URI         string `json:"uri"`
+        Name        string `json:"name"`
+        Description string `json:"description,omitempty"`
+        MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceTemplate struct {
+        URITemplate string `json:"uriTemplate"`
+        Name        string `json:"name"`
+        Description string `json:"description,omitempty"`
+        MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceContents struct {
+        URI      string            `json:"uri"`
+        MimeType string            `json:"mimeType,omitempty"`
+        Text     string            `json:"text,omitempty"`
+        Blob     string            `json:"blob,omitempty"`
+        Meta     *ResourceReadMeta `json:"_meta,omitempty"`
+}
+
+type ResourceReadParams struct {
+        URI string `json:"uri"`
+        // IfNoneMatch lets a client skip the payload when it already holds the
+        // contents for the given ETag.
+        IfNoneMatch string `json:"ifNoneMatch,omitempty"`
+}
+
+type ResourceSubscribeParams struct {
+        URI string `json:"uri"`
+}
+
+// cachedResource is a resources/read result together with its validators.
+type cachedResource struct {
+        contents ResourceContents
+        etag     string
+        version  int
+        expires  time.Time
+}
+
+// resourceCache memoizes resources/read results by URI. Entries expire after
+// ttl and are dropped early when the resource is reported as updated. The
+// version of a URI only increases when its contents actually change, so
+// clients can compare versions across invalidations.
+type resourceCache struct {
+        mu       sync.Mutex
+        ttl      time.Duration
+        entries  map[string]*cachedResource
+        versions map[string]int
+        etags    map[string]string
+}
+
+func newResourceCache(ttl time.Duration) *resourceCache {
+        return &resourceCache{
+                ttl:      ttl,
+                entries:  make(map[string]*cachedResource),
+                versions: make(map[string]int),
+                etags:    make(map[string]string),
+        }
+}
+
+func (rc *resourceCache) get(uri string, load func(string) (ResourceContents, error)) (*cachedResource, error) {
+        rc.mu.Lock()
+        if entry, ok := rc.entries[uri]; ok && time.Now().Before(entry.expires) {
+                rc.mu.Unlock()
+                return entry, nil
+        }
+        rc.mu.Unlock()
+
+        contents, err := load(uri)
+        if err != nil {
+                return nil, err
+        }
+
+        sum := sha256.Sum256([]byte(contents.Text))
+        etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+        rc.mu.Lock()
+        defer rc.mu.Unlock()
+        if rc.etags[uri] != etag {
+                rc.versions[uri]++
+                rc.etags[uri] = etag
+        }
+        entry := &cachedResource{
+                contents: contents,
+                etag:     etag,
+                version:  rc.versions[uri],
+                expires:  time.Now().Add(rc.ttl),
+        }
+        rc.entries[uri] = entry
+        return entry, nil
+}
+
+func (rc *resourceCache) invalidate(uri string) {
+        rc.mu.Lock()
+        delete(rc.entries, uri)
+        rc.mu.Unlock()
+}
+
+// NotifyResourceUpdated drops the contents of uri cached by the group of
+// the server ctx belongs to, and tells its subscribed clients that the
+// resource changed, and those on every replica when sessions are shared.
+func NotifyResourceUpdated(ctx context.Context, uri string) {
+        g := GroupFrom(ctx)
+        g.readCache.invalidate(uri)
+        notifyLocalSubscribers(g, uri)
+        if cluster != nil {
+                if err := cluster.ResourceUpdated(uri); err != nil {
+                        slog.Error("Notifying shared sessions of a resource update", "uri", uri, "error", err)
+                }
+        }
+}
+
+// LocalResourceUpdated drops any cached contents for uri and tells this
+// replica's subscribers that it changed, for a change another replica
+// reported. Which server's store it was made in isn't known, so every
+// group is told.
+func LocalResourceUpdated(uri string) {
+        for _, g := range allGroups() {
+                g.readCache.invalidate(uri)
+        }
+        notifyLocalSubscribers(nil, uri)
+}
+
+// notifyLocalSubscribers tells the clients of g subscribed to uri on this
+// replica, other than shared sessions, that it changed; with a nil g, those
+// of every group.
+func notifyLocalSubscribers(g *Group, uri string) {
+        for _, c := range subscriptions.subscribers(uri) {
+                if g != nil && c.Group() != g {
+                        continue
+                }
+                c.notify("notifications/resources/updated", map[string]interface{}{
+                        "uri": uri,
+                })
+        }
+}
+
+// ResourceProvider serves the resources whose URIs start with prefix.
+type ResourceProvider struct {
+        Prefix string
+        // List returns the resources resources/list includes; nil if the
+        // provider only has templates.
+        List func(ctx context.Context) ([]Resource, error)
+        // Templates are those resources/templates/list includes.
+        Templates []ResourceTemplate
+        // Read loads the current contents of uri for c.
+        Read func(ctx context.Context, c *Conn, uri string) (ResourceContents, error)
+        // Cached is set if reads may be served from the shared read cache.
+        // Contents that depend on the reader, or that change with every
+        // read, aren't cached.
+        Cached bool
+        // Static is set if List always returns the same resources, so
+        // resources/list may be answered from the list cache.
+        Static bool
+        // Scopes are those a client must hold to list, read, or subscribe
+        // to the resources when an -rbac policy is loaded; as with tools,
+        // a provider without scopes is then denied unless it is Public.
+        Scopes []string
+        Public bool
+}
+
+// resourceProviders are consulted in the order they were registered.
+var resourceProviders []*ResourceProvider
+
+// RegisterResourceProvider makes p's resources available to clients. It is
+// meant to be called from init functions.
+func RegisterResourceProvider(p *ResourceProvider) {
+        resourceProviders = append(resourceProviders, p)
+        registryChanged()
+}
+
+// listResources lists the resources c may read.
+func listResources(ctx context.Context, c *Conn) []Resource {
+        resources := []Resource{}
+        for _, p := range resourceProviders {
+                if p.List == nil || !c.canReadResources(p) {
+                        continue
+                }
+                list, err := p.List(ctx)
+                if err != nil {
+                        LogAll(ctx, LevelError, "Listing resources: %v", err)
+                        continue
+                }
+                resources = append(resources, list...)
+        }
+        return resources
+}
+
+func listResourceTemplates() []ResourceTemplate {
+        templates := []ResourceTemplate{}
+        for _, p := range resourceProviders {
+                templates = append(templates, p.Templates...)
+        }
+        return templates
+}
+
+// findResourceProvider returns the provider of uri.
+func findResourceProvider(uri string) (*ResourceProvider, bool) {
+        for _, p := range resourceProviders {
+                if strings.HasPrefix(uri, p.Prefix) {
+                        return p, true
+                }
+        }
+        return nil, false
+}
+
+// resolveResource reads uri on behalf of c. Shared resources go through the
+// read cache; the others are read every time, and a throwaway cache only
+// computes the ETag.
+func resolveResource(ctx context.Context, c *Conn, uri string) (*cachedResource, error) {
+        p, ok := findResourceProvider(uri)
+        if !ok {
+                return nil, fmt.Errorf("Unknown resource: %s", uri)
+        }
+        read := func(uri string) (ResourceContents, error) {
+                return p.Read(ctx, c, uri)
+        }
+        if !p.Cached {
+                return newResourceCache(0).get(uri, read)
+        }
+        return c.Group().readCache.get(uri, read)
+}
+
+// handleResourcesList answers resources/list, from the list cache, keyed
+// by which providers c may read, if every provider's list is static.
+func handleResourcesList(ctx context.Context, c *Conn, req Request) Response {
+        build := func() (interface{}, *Response) {
+                resources := listResources(ctx, c)
+                start, end, next, errResp := paginateRequest(req, len(resources))
+                if errResp != nil {
+                        return nil, errResp
+                }
+                return ListResourcesResult{Resources: resources[start:end], NextCursor: next}, nil
+        }
+        if !resourceListsStatic() {
+                result, errResp := build()
+                if errResp != nil {
+                        return *errResp
+                }
+                return Response{ID: req.ID, Result: result}
+        }
+        key := make([]byte, len(resourceProviders))
+        for i, p := range resourceProviders {
+                key[i] = '0'
+                if c.canReadResources(p) {
+                        key[i] = '1'
+                }
+        }
+        return cachedPage(req, string(key), build)
+}
+
+// resourceListsStatic reports whether every provider that lists resources
+// lists the same ones every time.
+func resourceListsStatic() bool {
+        for _, p := range resourceProviders {
+                if p.List != nil && !p.Static {
+                        return false
+                }
+        }
+        return true
+}
+
+func handleResourceTemplatesList(_ context.Context, req Request) Response {
+        return cachedPage(req, "", func() (interface{}, *Response) {
+                templates := listResourceTemplates()
+                start, end, next, errResp := paginateRequest(req, len(templates))
+                if errResp != nil {
+                        return nil, errResp
+                }
+                return ListResourceTemplatesResult{ResourceTemplates: templates[start:end], NextCursor: next}, nil
+        })
+}
+
+func handleResourcesRead(ctx context.Context, c *Conn, req Request) Response {
+        var params ResourceReadParams
+        if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        if p, ok := findResourceProvider(params.URI); ok && !c.canReadResources(p) {
+                c.Logf(ctx, LevelWarning, "Denied read of %s", params.URI)
+                return Response{ID: req.ID, Error: permissionError(c, params.URI, p.Scopes)}
+        }
+        entry, err := resolveResource(ctx, c, params.URI)
+        if err != nil {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32002,
+                                Message: err.Error(),
+                        },
+                }
+        }
+
+        meta := ResourceReadMeta{ETag: entry.etag, Version: entry.version}
+        if params.IfNoneMatch != "" && params.IfNoneMatch == entry.etag {
+                meta.NotModified = true
+                return Response{
+                        ID:     req.ID,
+                        Result: ReadResourceResult{Contents: []ResourceContents{}, Meta: &meta},
+                }
+        }
+
+        contents := entry.contents
+        contents.Meta = &meta
+        return Response{
+                ID:     req.ID,
+                Result: ReadResourceResult{Contents: []ResourceContents{contents}},
+        }
+}
+
+func handleResourcesSubscribe(ctx context.Context, c *Conn, req Request, subscribe bool) Response {
+        var params ResourceSubscribeParams
+        if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+                return Response{
+                        ID: req.ID,
+                        Error: &Error{
+                                Code:    -32602,
+                                Message: "Invalid params",
+                        },
+                }
+        }
+
+        if p, ok := findResourceProvider(params.URI); ok && subscribe && !c.canReadResources(p) {
+                return Response{ID: req.ID, Error: permissionError(c, params.URI, p.Scopes)}
+        }
+        switch {
+        case c.transport.Shared():
+                if err := cluster.Subscribe(c.Session.ID, params.URI, subscribe); err != nil {
+                        c.Logf(ctx, LevelError, "Updating shared subscriptions: %v", err)
+                        return Response{
+                                ID: req.ID,
+                                Error: &Error{
+                                        Code:    -32603,
+                                        Message: "Subscription could not be saved",
+                                },
+                        }
+                }
+        case subscribe:
+                subscriptions.add(c, params.URI)
+        default:
+                subscriptions.remove(c, params.URI)
+        }
+
+        return Response{
+                ID:     req.ID,
+                Result: EmptyResult{},
+        }
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import "encoding/json"
+
+// The results of the methods the server answers, as they go on the wire.
+// Handlers build these rather than maps, so a misspelled field fails to
+// compile instead of reaching clients.
+
+// Implementation names a client or server and its version.
+type Implementation struct {
+        Name    string `json:"name"`
+        Version string `json:"version"`
+}
+
+// serverInfo is how the server introduces itself in initialize.
+var serverInfo = Implementation{Name: "go-mcp-demo", Version: "1.0.0"}
+
+type InitializeResult struct {
+        ProtocolVersion string             `json:"protocolVersion"`
+        ServerInfo      Implementation     `json:"serverInfo"`
+        Capabilities    ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities lists the capability groups the server offers; a nil
+// group isn't offered.
+type ServerCapabilities struct {
+        Logging     *struct{}            `json:"logging,omitempty"`
+        Tools       *ToolsCapability     `json:"tools,omitempty"`
+        Resources   *ResourcesCapability `json:"resources,omitempty"`
+        Prompts     *PromptsCapability   `json:"prompts,omitempty"`
+        Completions *struct{}            `json:"completions,omitempty"`
+}
+
+type ToolsCapability struct {
+        Call ToolsCallCapability `json:"call"`
+        List ToolsListCapability `json:"list"`
+}
+
+type ToolsCallCapability struct {
+        Enabled bool `json:"enabled"`
+}
+
+type ToolsListCapability struct {
+        Enabled     bool `json:"enabled"`
+        ListChanged bool `json:"listChanged"`
+}
+
+type ResourcesCapability struct {
+        Subscribe   bool `json:"subscribe"`
+        ListChanged bool `json:"listChanged"`
+}
+
+type PromptsCapability struct {
+        ListChanged bool `json:"listChanged"`
+}
+
+// ToolInfo describes a tool in tools/list.
+type ToolInfo struct {
+        Name        string                 `json:"name"`
+        Description string                 `json:"description"`
+        InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+type ListToolsResult struct {
+        Tools []ToolInfo `json:"tools"`
+}
+
+// toolListing is how the server sends a ToolInfo, with the input schema
+// already encoded.
+type toolListing struct {
+        Name        string          `json:"name"`
+        Description string          `json:"description"`
+        InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+type listToolsResult struct {
+        Tools []toolListing `json:"tools"`
+}
+
+// CallToolResult is the result of tools/call. A tool's output is sent as
+// its structured content and, for clients that only read content, as JSON
+// text; see toolResult.
+type CallToolResult struct {
+        Content           []ToolContent `json:"content"`
+        StructuredContent interface{}   `json:"structuredContent,omitempty"`
+        IsError           bool          `json:"isError,omitempty"`
+}
+
+// ToolContent is one item of a tool result's content: text, an image or
+// audio clip, or an embedded resource.
+type ToolContent struct {
+        Type     string            `json:"type"`
+        Text     string            `json:"text,omitempty"`
+        Data     string            `json:"data,omitempty"`
+        MIMEType string            `json:"mimeType,omitempty"`
+        Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+// toolResult returns the result of a call whose tool returned output. A
+// CallToolResult, such as a plugin's, is sent as it is. Output that
+// encodes as a JSON object is also the structured content, which the
+// protocol requires to be an object.
+func toolResult(output interface{}) (CallToolResult, error) {
+        switch r := output.(type) {
+        case CallToolResult:
+                return r, nil
+        case *CallToolResult:
+                return *r, nil
+        }
+        data, err := json.Marshal(output)
+        if err != nil {
+                return CallToolResult{}, Errorf(ErrInternal, "Encoding the tool's result: %v", err)
+        }
+        result := CallToolResult{Content: []ToolContent{{Type: "text", Text: string(data)}}}
+        if len(data) > 0 && data[0] == '{' {
+                result.StructuredContent = json.RawMessage(data)
+        }
+        return result, nil
+}
+
+type ListResourcesResult struct {
+        Resources  []Resource `json:"resources"`
+        NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ListResourceTemplatesResult struct {
+        ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+        NextCursor        string             `json:"nextCursor,omitempty"`
+}
+
+type ReadResourceResult struct {
+        Contents []ResourceContents `json:"contents"`
+        // Meta is only set when the contents are left out because the
+        // client already holds them.
+        Meta *ResourceReadMeta `json:"_meta,omitempty"`
+}
+
+// ResourceReadMeta carries the validators of the contents resources/read
+// returns, in the result's or the contents' _meta.
+type ResourceReadMeta struct {
+        ETag        string `json:"etag"`
+        Version     int    `json:"version"`
+        NotModified bool   `json:"notModified,omitempty"`
+}
+
+type ListPromptsResult struct {
+        Prompts    []Prompt `json:"prompts"`
+        NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+type GetPromptResult struct {
+        Description string          `json:"description"`
+        Messages    []PromptMessage `json:"messages"`
+}
+
+type CompleteResult struct {
+        Completion Completion `json:"completion"`
+}
+
+// EmptyResult answers requests that succeed with nothing to report, such
+// as resources/subscribe and logging/setLevel.
+type EmptyResult struct{}
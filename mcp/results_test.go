@@ -0,0 +1,255 @@
+package mcp
+
+import (
+        "encoding/json"
+        "reflect"
+        "testing"
+)
+
+func TestResultsMarshal(t *testing.T) {
+        tests := []struct {
+                name   string
+                result interface{}
+                want   string
+        }{
+                {
+                        name: "initialize",
+                        result: InitializeResult{
+                                ProtocolVersion: "2025-06-18",
+                                ServerInfo:      serverInfo,
+                                Capabilities: ServerCapabilities{
+                                        Logging: &struct{}{},
+                                        Tools: &ToolsCapability{
+                                                Call: ToolsCallCapability{Enabled: true},
+                                                List: ToolsListCapability{Enabled: true, ListChanged: true},
+                                        },
+                                        Prompts: &PromptsCapability{},
+                                },
+                        },
+                        want: `{"protocolVersion":"2025-06-18","serverInfo":{"name":"go-mcp-demo","version":"1.0.0"},` +
+                                `"capabilities":{"logging":{},"tools":{"call":{"enabled":true},"list":{"enabled":true,"listChanged":true}},"prompts":{"listChanged":false}}}`,
+                },
+                {
+                        name:   "tools/list",
+                        result: ListToolsResult{Tools: []ToolInfo{{Name: "get_todo_tickets", InputSchema: NoArgsSchema()}}},
+                        want:   `{"tools":[{"name":"get_todo_tickets","description":"","inputSchema":{"properties":{},"type":"object"}}]}`,
+                },
+                {
+                        name:   "resources/list last page",
+                        result: ListResourcesResult{Resources: []Resource{}},
+                        want:   `{"resources":[]}`,
+                },
+                {
+                        name:   "resources/templates/list with more",
+                        result: ListResourceTemplatesResult{ResourceTemplates: []ResourceTemplate{{URITemplate: "ticket://{id}", Name: "Ticket"}}, NextCursor: "NTA"},
+                        want:   `{"resourceTemplates":[{"uriTemplate":"ticket://{id}","name":"Ticket"}],"nextCursor":"NTA"}`,
+                },
+                {
+                        name: "resources/read",
+                        result: ReadResourceResult{Contents: []ResourceContents{{
+                                URI:  "ticket://T1",
+                                Text: "{}",
+                                Meta: &ResourceReadMeta{ETag: "abc", Version: 2},
+                        }}},
+                        want: `{"contents":[{"uri":"ticket://T1","text":"{}","_meta":{"etag":"abc","version":2}}]}`,
+                },
+                {
+                        name:   "resources/read not modified",
+                        result: ReadResourceResult{Contents: []ResourceContents{}, Meta: &ResourceReadMeta{ETag: "abc", Version: 2, NotModified: true}},
+                        want:   `{"contents":[],"_meta":{"etag":"abc","version":2,"notModified":true}}`,
+                },
+                {
+                        name: "prompts/get",
+                        result: GetPromptResult{Messages: []PromptMessage{{
+                                Role:    "user",
+                                Content: PromptContent{Type: "text", Text: "Summarize T1"},
+                        }}},
+                        want: `{"description":"","messages":[{"role":"user","content":{"type":"text","text":"Summarize T1"}}]}`,
+                },
+                {
+                        name:   "completion/complete",
+                        result: CompleteResult{Completion: Completion{Values: []string{"T1"}, Total: 1}},
+                        want:   `{"completion":{"values":["T1"],"total":1,"hasMore":false}}`,
+                },
+                {
+                        name: "tools/call",
+                        result: CallToolResult{
+                                Content:           []ToolContent{{Type: "text", Text: `{"total":1}`}},
+                                StructuredContent: map[string]interface{}{"total": 1.0},
+                        },
+                        want: `{"content":[{"type":"text","text":"{\"total\":1}"}],"structuredContent":{"total":1}}`,
+                },
+                {
+                        name:   "empty",
+                        result: EmptyResult{},
+                        want:   `{}`,
+                },
+        }
+        for _, tt := range tests {
+                t.Run(tt.name, func(t *testing.T) {
+                        data, err := json.Marshal(tt.result)
+                        if err != nil {
+                                t.Fatal(err)
+                        }
+                        if string(data) != tt.want {
+                                t.Errorf("got  %s\nwant %s", data, tt.want)
+                        }
+
+                        decoded := reflect.New(reflect.TypeOf(tt.result))
+                        if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+                                t.Fatal(err)
+                        }
+                        if got := decoded.Elem().Interface(); !reflect.DeepEqual(got, tt.result) {
+                                t.Errorf("round trip: got %#v, want %#v", got, tt.result)
+                        }
+                })
+        }
+}
+
+func TestParamsUnmarshal(t *testing.T) {
+        tests := []struct {
+                name string
+                data string
+                into interface{}
+                want interface{}
+        }{
+                {
+                        name: "initialize",
+                        data: `{"protocolVersion":"2025-03-26","clientInfo":{"name":"cli","version":"0.1"},"capabilities":{"roots":{}}}`,
+                        into: &InitializeParams{},
+                        want: &InitializeParams{
+                                ProtocolVersion: "2025-03-26",
+                                ClientInfo:      ClientInfo{Name: "cli", Version: "0.1"},
+                                Capabilities:    map[string]interface{}{"roots": map[string]interface{}{}},
+                        },
+                },
+                {
+                        name: "tools/call",
+                        data: `{"name":"create_ticket","arguments":{"title":"Broken build"}}`,
+                        into: &ToolCallParams{},
+                        want: &ToolCallParams{Name: "create_ticket", Arguments: map[string]interface{}{"title": "Broken build"}},
+                },
+                {
+                        name: "resources/read",
+                        data: `{"uri":"ticket://T1","ifNoneMatch":"abc"}`,
+                        into: &ResourceReadParams{},
+                        want: &ResourceReadParams{URI: "ticket://T1", IfNoneMatch: "abc"},
+                },
+                {
+                        name: "prompts/get",
+                        data: `{"name":"triage","arguments":{"id":"T1"}}`,
+                        into: &PromptGetParams{},
+                        want: &PromptGetParams{Name: "triage", Arguments: map[string]string{"id": "T1"}},
+                },
+                {
+                        name: "completion/complete",
+                        data: `{"ref":{"type":"ref/prompt","name":"triage"},"argument":{"name":"id","value":"T"}}`,
+                        into: &CompleteParams{},
+                        want: &CompleteParams{
+                                Ref:      CompletionReference{Type: "ref/prompt", Name: "triage"},
+                                Argument: CompletionArgument{Name: "id", Value: "T"},
+                        },
+                },
+                {
+                        name: "notifications/cancelled",
+                        data: `{"requestId":"7","reason":"user aborted"}`,
+                        into: &CancelledParams{},
+                        want: &CancelledParams{RequestID: "7", Reason: "user aborted"},
+                },
+        }
+        for _, tt := range tests {
+                t.Run(tt.name, func(t *testing.T) {
+                        if err := json.Unmarshal([]byte(tt.data), tt.into); err != nil {
+                                t.Fatal(err)
+                        }
+                        if !reflect.DeepEqual(tt.into, tt.want) {
+                                t.Errorf("got %#v, want %#v", tt.into, tt.want)
+                        }
+                })
+        }
+}
+
+func TestResponseMarshal(t *testing.T) {
+        tests := []struct {
+                name     string
+                response Response
+                want     string
+        }{
+                {
+                        name:     "result",
+                        response: Response{ID: "1", Result: map[string]interface{}{"title": "<b>&"}},
+                        want:     `{"jsonrpc":"2.0","id":"1","result":{"title":"\u003cb\u003e\u0026"}}`,
+                },
+                {
+                        name:     "raw result",
+                        response: Response{ID: "2", Result: json.RawMessage("{\n  \"ticket\": {\"id\": \"T1\"}\n}\n")},
+                        want:     `{"jsonrpc":"2.0","id":"2","result":{"ticket":{"id":"T1"}}}`,
+                },
+                {
+                        name:     "error",
+                        response: Response{ID: "3", Error: &Error{Code: -32602, Message: "Unknown tool: x"}},
+                        want:     `{"jsonrpc":"2.0","id":"3","error":{"code":-32602,"message":"Unknown tool: x"}}`,
+                },
+                {
+                        name:     "nothing",
+                        response: Response{ID: "4"},
+                        want:     `{"jsonrpc":"2.0","id":"4"}`,
+                },
+        }
+        for _, tt := range tests {
+                t.Run(tt.name, func(t *testing.T) {
+                        data, err := encodeMessage(tt.response)
+                        if err != nil {
+                                t.Fatal(err)
+                        }
+                        if string(data) != tt.want {
+                                t.Errorf("got  %s\nwant %s", data, tt.want)
+                        }
+                        // Nested in other values it encodes the same.
+                        nested, err := json.Marshal([]Response{tt.response})
+                        if err != nil {
+                                t.Fatal(err)
+                        }
+                        if string(nested) != "["+tt.want+"]" {
+                                t.Errorf("nested: got %s", nested)
+                        }
+                })
+        }
+
+        if _, err := encodeMessage(Response{ID: "5", Result: json.RawMessage(`{"truncated":`)}); err == nil {
+                t.Error("invalid raw result: got no error")
+        }
+}
+
+func TestToolListingMarshal(t *testing.T) {
+        tool := &Tool{
+                Name:        "search",
+                Description: "Find <things> & more",
+                InputSchema: map[string]interface{}{
+                        "type":       "object",
+                        "properties": map[string]interface{}{"query": map[string]interface{}{"type": "string"}},
+                        "required":   []string{"query"},
+                },
+        }
+        schema, err := tool.encodedSchema()
+        if err != nil {
+                t.Fatal(err)
+        }
+        got, err := json.Marshal(listToolsResult{Tools: []toolListing{{tool.Name, tool.Description, schema}}})
+        if err != nil {
+                t.Fatal(err)
+        }
+        want, err := json.Marshal(ListToolsResult{Tools: []ToolInfo{{tool.Name, tool.Description, tool.InputSchema}}})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if string(got) != string(want) {
+                t.Errorf("got  %s\nwant %s", got, want)
+        }
+
+        // The schema is encoded once.
+        tool.InputSchema = nil
+        if again, _ := tool.encodedSchema(); string(again) != string(schema) {
+                t.Errorf("encoded again: got %s, want %s", again, schema)
+        }
+}
@@ -0,0 +1,214 @@
+package mcp
+
+import (
+        "context"
+        "encoding/base64"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "mime"
+        "net/url"
+        "os"
+        "path/filepath"
+        "strings"
+        "time"
+        "unicode/utf8"
+)
+
+const FileURIPrefix = "file://"
+
+// rootsTimeout bounds how long the server waits for a roots/list reply.
+const rootsTimeout = 10 * time.Second
+
+// maxFileResourceSize caps the size of files served as resources.
+const maxFileResourceSize = 1 << 20
+
+var errOutsideRoots = errors.New("Access denied: path is outside the client's roots")
+
+type Root struct {
+        URI  string `json:"uri"`
+        Name string `json:"name,omitempty"`
+}
+
+type ListRootsResult struct {
+        Roots []Root `json:"roots"`
+}
+
+// refreshRoots asks the client for its roots and replaces the cached set.
+// Clients without the roots capability get no filesystem access.
+func (c *Conn) refreshRoots() {
+        if !c.supports("roots") {
+                return
+        }
+
+        ctx, cancel := context.WithTimeout(c.ctx, rootsTimeout)
+        defer cancel()
+
+        raw, err := c.request(ctx, "roots/list", map[string]interface{}{})
+        if err != nil {
+                c.Logf(ctx, LevelWarning, "roots/list error: %v", err)
+                return
+        }
+        var result ListRootsResult
+        if err := json.Unmarshal(raw, &result); err != nil {
+                c.Logf(ctx, LevelWarning, "Invalid roots/list result: %v", err)
+                return
+        }
+
+        var paths []string
+        for _, root := range result.Roots {
+                path, err := fileURIToPath(root.URI)
+                if err != nil {
+                        c.Logf(ctx, LevelWarning, "Ignoring root %q: %v", root.URI, err)
+                        continue
+                }
+                if resolved, err := filepath.EvalSymlinks(path); err == nil {
+                        path = resolved
+                }
+                paths = append(paths, path)
+        }
+
+        c.mu.Lock()
+        c.roots = paths
+        c.mu.Unlock()
+        c.Logf(ctx, LevelInfo, "Client declared %d root(s)", len(paths))
+}
+
+func (c *Conn) rootPaths() []string {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        return append([]string(nil), c.roots...)
+}
+
+// resolveInRoots cleans path, follows symlinks, and returns the result only
+// if it lies within one of the client's roots.
+func (c *Conn) resolveInRoots(path string) (string, error) {
+        if !filepath.IsAbs(path) {
+                return "", fmt.Errorf("path must be absolute: %s", path)
+        }
+        resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+        if err != nil {
+                return "", err
+        }
+        for _, root := range c.rootPaths() {
+                rel, err := filepath.Rel(root, resolved)
+                if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+                        return resolved, nil
+                }
+        }
+        return "", errOutsideRoots
+}
+
+func fileURIToPath(uri string) (string, error) {
+        u, err := url.Parse(uri)
+        if err != nil {
+                return "", err
+        }
+        if u.Scheme != "file" {
+                return "", fmt.Errorf("not a file URI")
+        }
+        if u.Host != "" && u.Host != "localhost" {
+                return "", fmt.Errorf("remote file URIs are not supported")
+        }
+        return filepath.FromSlash(u.Path), nil
+}
+
+func init() {
+        RegisterResourceProvider(&ResourceProvider{
+                Prefix: FileURIPrefix,
+                Templates: []ResourceTemplate{
+                        {
+                                URITemplate: FileURIPrefix + "{path}",
+                                Name:        "file",
+                                Description: "A file within one of the client's declared roots",
+                        },
+                },
+                Read: func(_ context.Context, c *Conn, uri string) (ResourceContents, error) {
+                        return ReadFileResource(c, uri)
+                },
+                Scopes: []string{ScopeFilesRead},
+        })
+}
+
+// ReadFileResource reads a file:// resource on behalf of c, refusing paths
+// outside the client's roots.
+func ReadFileResource(c *Conn, uri string) (ResourceContents, error) {
+        path, err := fileURIToPath(uri)
+        if err != nil {
+                return ResourceContents{}, err
+        }
+        path, err = c.resolveInRoots(path)
+        if err != nil {
+                return ResourceContents{}, err
+        }
+
+        info, err := os.Stat(path)
+        if err != nil {
+                return ResourceContents{}, err
+        }
+        if info.IsDir() {
+                return ResourceContents{}, fmt.Errorf("%s is a directory", uri)
+        }
+        if info.Size() > maxFileResourceSize {
+                return ResourceContents{}, fmt.Errorf("%s exceeds the %d byte limit", uri, maxFileResourceSize)
+        }
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return ResourceContents{}, err
+        }
+
+        contents := ResourceContents{
+                URI:      uri,
+                MimeType: mime.TypeByExtension(filepath.Ext(path)),
+        }
+        if utf8.Valid(data) {
+                contents.Text = string(data)
+                if contents.MimeType == "" {
+                        contents.MimeType = "text/plain"
+                }
+        } else {
+                contents.Blob = base64.StdEncoding.EncodeToString(data)
+                if contents.MimeType == "" {
+                        contents.MimeType = "application/octet-stream"
+                }
+        }
+        return contents, nil
+}
+
+func HandleListFiles(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        c, ok := ConnFromContext(ctx)
+        if !ok {
+                return nil, Errorf(ErrInternal, "No client connection")
+        }
+
+        path := StringArg(args, "path")
+        if path == "" {
+                roots := c.rootPaths()
+                if len(roots) == 0 {
+                        return nil, Errorf(ErrInvalidArgument, "Client has not declared any roots")
+                }
+                return map[string]interface{}{"roots": roots}, nil
+        }
+
+        resolved, err := c.resolveInRoots(path)
+        if err != nil {
+                return nil, Errorf(ErrInvalidArgument, "%v", err)
+        }
+        entries, err := os.ReadDir(resolved)
+        if err != nil {
+                return nil, Errorf(ErrInvalidArgument, "%v", err)
+        }
+
+        files := []map[string]interface{}{}
+        for _, entry := range entries {
+                files = append(files, map[string]interface{}{
+                        "name": entry.Name(),
+                        "uri":  FileURIPrefix + filepath.ToSlash(filepath.Join(resolved, entry.Name())),
+                        "dir":  entry.IsDir(),
+                })
+        }
+        return map[string]interface{}{
+                "path":  resolved,
+                "files": files,
+        }, nil
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "time"
+)
+
+// samplingTimeout bounds how long a tool waits for the client to produce a
+// completion.
+const samplingTimeout = 60 * time.Second
+
+var errSamplingUnsupported = errors.New("Client does not support sampling")
+
+type SamplingMessage struct {
+        Role    string        `json:"role"`
+        Content PromptContent `json:"content"`
+}
+
+type ModelHint struct {
+        Name string `json:"name"`
+}
+
+// ModelPreferences expresses the server's priorities when the client selects
+// a model. Priorities range from 0 to 1.
+type ModelPreferences struct {
+        Hints                []ModelHint `json:"hints,omitempty"`
+        CostPriority         *float64    `json:"costPriority,omitempty"`
+        SpeedPriority        *float64    `json:"speedPriority,omitempty"`
+        IntelligencePriority *float64    `json:"intelligencePriority,omitempty"`
+}
+
+type CreateMessageParams struct {
+        Messages         []SamplingMessage `json:"messages"`
+        ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+        SystemPrompt     string            `json:"systemPrompt,omitempty"`
+        IncludeContext   string            `json:"includeContext,omitempty"`
+        Temperature      *float64          `json:"temperature,omitempty"`
+        MaxTokens        int               `json:"maxTokens"`
+        StopSequences    []string          `json:"stopSequences,omitempty"`
+}
+
+type CreateMessageResult struct {
+        Role       string        `json:"role"`
+        Content    PromptContent `json:"content"`
+        Model      string        `json:"model"`
+        StopReason string        `json:"stopReason,omitempty"`
+}
+
+// createMessage asks the client's LLM for a completion via
+// sampling/createMessage.
+func (c *Conn) createMessage(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error) {
+        if !c.supports("sampling") {
+                return nil, errSamplingUnsupported
+        }
+
+        ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+        defer cancel()
+
+        raw, err := c.request(ctx, "sampling/createMessage", params)
+        if err != nil {
+                if errors.Is(err, context.DeadlineExceeded) {
+                        return nil, fmt.Errorf("sampling/createMessage timed out after %s", samplingTimeout)
+                }
+                return nil, err
+        }
+
+        var result CreateMessageResult
+        if err := json.Unmarshal(raw, &result); err != nil {
+                return nil, fmt.Errorf("invalid sampling/createMessage result: %v", err)
+        }
+        return &result, nil
+}
+
+// RequestSampling is createMessage for the connection that made the current
+// tool call.
+func RequestSampling(ctx context.Context, params CreateMessageParams) (*CreateMessageResult, error) {
+        c, ok := ConnFromContext(ctx)
+        if !ok {
+                return nil, errSamplingUnsupported
+        }
+        return c.createMessage(ctx, params)
+}
@@ -0,0 +1,226 @@
+package mcp
+
+import (
+        "encoding/json"
+        "fmt"
+        "log/slog"
+        "net/http"
+        "os"
+        "path/filepath"
+        "strings"
+        "sync"
+        "time"
+)
+
+// RemoteRequestTimeout bounds each call to a remote API: an issue
+// tracker, an -openapi or -tool-manifest backend, or Vault.
+const RemoteRequestTimeout = 30 * time.Second
+
+// secretProvider looks up credentials by name, e.g. JIRA_API_TOKEN.
+type secretProvider interface {
+        // lookup returns the secret called name; ok is false if the provider
+        // doesn't have it.
+        lookup(name string) (value string, ok bool, err error)
+}
+
+// Secrets are consulted in order by secret; set from -secrets.
+var Secrets = []secretProvider{envSecrets{}}
+
+// Secret returns the named credential from the first provider that has it,
+// or "" if none does. Callers look secrets up each time they use them, so
+// a rotated secret takes effect without a restart.
+func Secret(name string) string {
+        for _, p := range Secrets {
+                value, ok, err := p.lookup(name)
+                if err != nil {
+                        slog.Error("Reading secret "+name, "error", err)
+                        continue
+                }
+                if ok {
+                        return value
+                }
+        }
+        return ""
+}
+
+// SecretRef names a secret that is looked up each time it is used.
+type SecretRef string
+
+func (r SecretRef) Value() string {
+        return Secret(string(r))
+}
+
+// ExpandSecrets replaces ${NAME} and $NAME in s with secrets.
+func ExpandSecrets(s string) string {
+        return os.Expand(s, Secret)
+}
+
+// ParseSecretProviders parses a comma-separated provider list: env,
+// file:<dir>, and vault:<path>.
+func ParseSecretProviders(spec string, refresh time.Duration) ([]secretProvider, error) {
+        var providers []secretProvider
+        for _, item := range strings.Split(spec, ",") {
+                kind, arg, _ := strings.Cut(strings.TrimSpace(item), ":")
+                switch kind {
+                case "env":
+                        providers = append(providers, envSecrets{})
+                case "file":
+                        if arg == "" {
+                                return nil, fmt.Errorf("file secrets need a directory, e.g. file:/run/secrets")
+                        }
+                        providers = append(providers, fileSecrets{dir: arg})
+                case "vault":
+                        v, err := newVaultSecrets(arg, refresh)
+                        if err != nil {
+                                return nil, err
+                        }
+                        providers = append(providers, v)
+                default:
+                        return nil, fmt.Errorf("unknown secret provider %q", item)
+                }
+        }
+        return providers, nil
+}
+
+// envSecrets reads secrets from environment variables of the same name.
+type envSecrets struct{}
+
+func (envSecrets) lookup(name string) (string, bool, error) {
+        value, ok := os.LookupEnv(name)
+        return value, ok, nil
+}
+
+// fileSecrets reads each secret from a file of the same name (or its
+// lowercase form) in dir, as Docker and Kubernetes mount them. Files are
+// read on every lookup, so replacing one rotates the secret.
+type fileSecrets struct {
+        dir string
+}
+
+func (f fileSecrets) lookup(name string) (string, bool, error) {
+        if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+                // Not a secret's name, and never a path out of dir.
+                return "", false, nil
+        }
+        for _, file := range []string{name, strings.ToLower(name)} {
+                data, err := os.ReadFile(filepath.Join(f.dir, file))
+                if os.IsNotExist(err) {
+                        continue
+                }
+                if err != nil {
+                        return "", false, err
+                }
+                return strings.TrimRight(string(data), "\r\n"), true, nil
+        }
+        return "", false, nil
+}
+
+// vaultSecrets reads secrets from the fields of one HashiCorp Vault KV
+// secret (version 1 or 2). It authenticates with VAULT_TOKEN, or the token
+// in VAULT_TOKEN_FILE (re-read on each fetch, as Vault Agent rewrites it),
+// against VAULT_ADDR. The secret is refetched once refresh has passed.
+type vaultSecrets struct {
+        url       string
+        tokenFile string
+        namespace string
+        refresh   time.Duration
+        client    *http.Client
+
+        mu      sync.Mutex
+        data    map[string]string
+        fetched time.Time
+}
+
+func newVaultSecrets(path string, refresh time.Duration) (*vaultSecrets, error) {
+        addr := os.Getenv("VAULT_ADDR")
+        if addr == "" || path == "" {
+                return nil, fmt.Errorf("vault secrets need VAULT_ADDR and a path, e.g. vault:secret/data/mcp-server")
+        }
+        v := &vaultSecrets{
+                url:       strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/"),
+                tokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+                namespace: os.Getenv("VAULT_NAMESPACE"),
+                refresh:   refresh,
+                client:    &http.Client{Timeout: RemoteRequestTimeout},
+        }
+        if _, err := v.fetch(); err != nil {
+                return nil, err
+        }
+        return v, nil
+}
+
+func (v *vaultSecrets) lookup(name string) (string, bool, error) {
+        v.mu.Lock()
+        data, fetched := v.data, v.fetched
+        v.mu.Unlock()
+        if time.Since(fetched) > v.refresh {
+                fresh, err := v.fetch()
+                if err != nil {
+                        // Keep serving the last values while Vault is unreachable.
+                        slog.Error("Refreshing secrets from vault", "error", err)
+                        v.mu.Lock()
+                        v.fetched = time.Now()
+                        v.mu.Unlock()
+                } else {
+                        data = fresh
+                }
+        }
+        value, ok := data[name]
+        return value, ok, nil
+}
+
+func (v *vaultSecrets) token() (string, error) {
+        if v.tokenFile == "" {
+                return os.Getenv("VAULT_TOKEN"), nil
+        }
+        data, err := os.ReadFile(v.tokenFile)
+        return strings.TrimSpace(string(data)), err
+}
+
+func (v *vaultSecrets) fetch() (map[string]string, error) {
+        token, err := v.token()
+        if err != nil {
+                return nil, err
+        }
+        req, err := http.NewRequest(http.MethodGet, v.url, nil)
+        if err != nil {
+                return nil, err
+        }
+        req.Header.Set("X-Vault-Token", token)
+        if v.namespace != "" {
+                req.Header.Set("X-Vault-Namespace", v.namespace)
+        }
+        resp, err := v.client.Do(req)
+        if err != nil {
+                return nil, err
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+                return nil, fmt.Errorf("reading %s: %s", v.url, resp.Status)
+        }
+        var body struct {
+                Data map[string]interface{} `json:"data"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+                return nil, fmt.Errorf("decoding vault response: %w", err)
+        }
+        fields := body.Data
+        // KV version 2 nests the fields under data.data, beside metadata.
+        if inner, ok := fields["data"].(map[string]interface{}); ok {
+                if _, ok := fields["metadata"]; ok {
+                        fields = inner
+                }
+        }
+        data := make(map[string]string, len(fields))
+        for k, value := range fields {
+                if s, ok := value.(string); ok {
+                        data[k] = s
+                } else {
+                        data[k] = fmt.Sprint(value)
+                }
+        }
+        v.mu.Lock()
+        v.data, v.fetched = data, time.Now()
+        v.mu.Unlock()
+        return data, nil
+}
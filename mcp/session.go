@@ -0,0 +1,124 @@
+package mcp
+
+import (
+        "context"
+        "crypto/rand"
+        "encoding/hex"
+        "sync"
+        "time"
+)
+
+// supportedProtocolVersions are the MCP protocol versions the server
+// speaks, newest first. "1.0" is what it answered before negotiating and
+// is kept for clients that don't ask for a version.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05", "1.0"}
+
+// negotiateProtocolVersion returns the version to use with a client that
+// requested requested: that version if the server supports it, "1.0" if
+// the client didn't ask, and otherwise the newest the server supports.
+func negotiateProtocolVersion(requested string) string {
+        if requested == "" {
+                return "1.0"
+        }
+        for _, v := range supportedProtocolVersions {
+                if v == requested {
+                        return v
+                }
+        }
+        return supportedProtocolVersions[0]
+}
+
+// ClientInfo is the implementation a client reported at initialize.
+type ClientInfo struct {
+        Name    string `json:"name"`
+        Title   string `json:"title,omitempty"`
+        Version string `json:"version"`
+}
+
+// Principal is an authenticated client.
+type Principal struct {
+        Identity string
+        // Claims are those of the client's access token, if it presented one.
+        Claims map[string]interface{}
+}
+
+// Session is the state of one client's MCP session: who the client is,
+// what it negotiated at initialize, and values tool handlers keep between
+// calls. It is created when the connection is accepted and lives as long
+// as the connection.
+type Session struct {
+        ID string
+        // Identity is who the client authenticated as; empty when
+        // authentication is off.
+        Identity string
+        // claims are those of the client's access token, if it presented one.
+        claims    map[string]interface{}
+        CreatedAt time.Time
+
+        mu              sync.Mutex
+        protocolVersion string
+        clientInfo      ClientInfo
+        values          map[string]interface{}
+}
+
+func NewSession(p Principal) *Session {
+        return &Session{
+                ID:        newSessionID(),
+                Identity:  p.Identity,
+                claims:    p.Claims,
+                CreatedAt: time.Now(),
+                values:    map[string]interface{}{},
+        }
+}
+
+// newSessionID returns a random, unguessable session ID.
+func newSessionID() string {
+        var b [16]byte
+        rand.Read(b[:])
+        return hex.EncodeToString(b[:])
+}
+
+// Initialized records what the client negotiated at initialize.
+func (s *Session) Initialized(protocolVersion string, info ClientInfo) {
+        s.mu.Lock()
+        s.protocolVersion = protocolVersion
+        s.clientInfo = info
+        s.mu.Unlock()
+}
+
+// Negotiated returns what the client negotiated at initialize; the version
+// is empty before then.
+func (s *Session) Negotiated() (protocolVersion string, info ClientInfo) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        return s.protocolVersion, s.clientInfo
+}
+
+// get returns a value a tool handler stored in the session.
+func (s *Session) get(key string) (interface{}, bool) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        v, ok := s.values[key]
+        return v, ok
+}
+
+// set stores value in the session under key until the session ends, or
+// removes the key if value is nil.
+func (s *Session) set(key string, value interface{}) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if value == nil {
+                delete(s.values, key)
+                return
+        }
+        s.values[key] = value
+}
+
+// sessionFromContext returns the session a tool call belongs to.
+func sessionFromContext(ctx context.Context) (*Session, bool) {
+        c, ok := ConnFromContext(ctx)
+        if !ok {
+                return nil, false
+        }
+        return c.Session, true
+}
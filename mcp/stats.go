@@ -0,0 +1,87 @@
+package mcp
+
+import (
+        "runtime"
+        "strings"
+        "time"
+)
+
+// serverStarted is when the process started, for server_stats' uptime.
+var serverStarted = time.Now()
+
+// sumBy totals c's series by the value of one label.
+func (c *counterVec) sumBy(label string) map[string]float64 {
+        index := -1
+        for i, name := range c.labels {
+                if name == label {
+                        index = i
+                }
+        }
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        sums := map[string]float64{}
+        for key, v := range c.values {
+                sums[strings.Split(key, "\x00")[index]] += v
+        }
+        return sums
+}
+
+// total sums every series of c.
+func (c *counterVec) total() float64 {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        var sum float64
+        for _, v := range c.values {
+                sum += v
+        }
+        return sum
+}
+
+// ServerStats reports the server's uptime, connections, requests, tool
+// calls, and runtime, for server_stats.
+func ServerStats() map[string]interface{} {
+        uptime := time.Since(serverStarted)
+
+        toolCalls := toolCallsTotal.sumBy("tool")
+        toolErrors := toolErrorsTotal.sumBy("tool")
+        tools := map[string]interface{}{}
+        for tool, calls := range toolCalls {
+                tools[tool] = map[string]interface{}{"calls": int(calls), "errors": int(toolErrors[tool])}
+        }
+
+        var memory runtime.MemStats
+        runtime.ReadMemStats(&memory)
+
+        return map[string]interface{}{
+                "startedAt":     serverStarted.UTC(),
+                "uptimeSeconds": int(uptime.Seconds()),
+                "uptime":        uptime.Round(time.Second).String(),
+                "connections": map[string]interface{}{
+                        "current":       len(ConnectedClients()),
+                        "opened":        int(connectionsOpened.total()),
+                        "rejected":      countsOf(ConnectionsRejected.sumBy("reason")),
+                        "closed":        countsOf(connectionsClosed.sumBy("cause")),
+                        "subscriptions": subscriptions.count(),
+                },
+                "requests": map[string]interface{}{
+                        "total":    int(requestsTotal.total()),
+                        "errors":   int(requestsTotal.sumBy("outcome")["error"]),
+                        "byMethod": countsOf(requestsTotal.sumBy("method")),
+                        "byClient": countsOf(requestsTotal.sumBy("client")),
+                },
+                "tools": tools,
+                "runtime": map[string]interface{}{
+                        "goVersion":      runtime.Version(),
+                        "goroutines":     runtime.NumGoroutine(),
+                        "heapAllocBytes": memory.HeapAlloc,
+                },
+        }
+}
+
+func countsOf(sums map[string]float64) map[string]int {
+        counts := make(map[string]int, len(sums))
+        for k, v := range sums {
+                counts[k] = int(v)
+        }
+        return counts
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import "sync"
+
+// subscriptionRegistry records which connections subscribed to which
+// resources. It is indexed both ways, so finding a resource's subscribers
+// and forgetting everything a connection subscribed to are both cheap.
+type subscriptionRegistry struct {
+        mu     sync.Mutex
+        byURI  map[string]map[*Conn]bool
+        byConn map[*Conn]map[string]bool
+}
+
+// subscriptions holds every connection's resource subscriptions. A
+// connection's entries are dropped when it is removed from the client list.
+var subscriptions = newSubscriptionRegistry()
+
+func newSubscriptionRegistry() *subscriptionRegistry {
+        return &subscriptionRegistry{
+                byURI:  make(map[string]map[*Conn]bool),
+                byConn: make(map[*Conn]map[string]bool),
+        }
+}
+
+// add subscribes c to uri, unless c has been removed from the client list,
+// whose drop would never come to clear it.
+func (r *subscriptionRegistry) add(c *Conn, uri string) {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        if c.removed.Load() {
+                return
+        }
+        if r.byURI[uri] == nil {
+                r.byURI[uri] = make(map[*Conn]bool)
+        }
+        r.byURI[uri][c] = true
+        if r.byConn[c] == nil {
+                r.byConn[c] = make(map[string]bool)
+        }
+        r.byConn[c][uri] = true
+}
+
+func (r *subscriptionRegistry) remove(c *Conn, uri string) {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        r.unlink(c, uri)
+}
+
+// unlink removes one subscription, and any index entry left empty, with
+// r.mu held.
+func (r *subscriptionRegistry) unlink(c *Conn, uri string) {
+        delete(r.byURI[uri], c)
+        if len(r.byURI[uri]) == 0 {
+                delete(r.byURI, uri)
+        }
+        delete(r.byConn[c], uri)
+        if len(r.byConn[c]) == 0 {
+                delete(r.byConn, c)
+        }
+}
+
+// drop removes all of c's subscriptions and returns how many there were.
+func (r *subscriptionRegistry) drop(c *Conn) int {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        n := len(r.byConn[c])
+        for uri := range r.byConn[c] {
+                r.unlink(c, uri)
+        }
+        return n
+}
+
+// subscribers returns the connections subscribed to uri.
+func (r *subscriptionRegistry) subscribers(uri string) []*Conn {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        list := make([]*Conn, 0, len(r.byURI[uri]))
+        for c := range r.byURI[uri] {
+                list = append(list, c)
+        }
+        return list
+}
+
+// count returns the number of subscriptions across all connections.
+func (r *subscriptionRegistry) count() int {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        n := 0
+        for _, uris := range r.byConn {
+                n += len(uris)
+        }
+        return n
+}
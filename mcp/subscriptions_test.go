@@ -0,0 +1,15 @@
+package mcp
+
+import "testing"
+
+func TestSubscribeAfterRemoval(t *testing.T) {
+        r := newSubscriptionRegistry()
+        c := &Conn{}
+        r.add(c, "ticket://1")
+        c.removed.Store(true)
+        r.drop(c)
+        r.add(c, "ticket://2")
+        if n := r.count(); n != 0 {
+                t.Errorf("a removed connection holds %d subscriptions", n)
+        }
+}
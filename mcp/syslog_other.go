@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package mcp
+
+import "errors"
+
+func openSyslog(target, tag string) (syslogWriter, error) {
+        return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+
+package mcp
+
+import (
+        "fmt"
+        "log/syslog"
+        "net/url"
+)
+
+// openSyslog connects to the local syslog daemon if target is empty, or to
+// a remote one at a udp:// or tcp:// URL.
+func openSyslog(target, tag string) (syslogWriter, error) {
+        if target == "" {
+                return syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+        }
+        u, err := url.Parse(target)
+        if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+                return nil, fmt.Errorf("syslog target must be udp://host:port or tcp://host:port, not %q", target)
+        }
+        return syslog.Dial(u.Scheme, u.Host, syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+        "context"
+        "slices"
+        "sync"
+        "sync/atomic"
+)
+
+// Limits on concurrent tool calls, set by flags, for DefaultSettings.
+// Zero means unlimited. Calls beyond them wait, in the order they came, for
+// one to finish, so a flood of calls can't run the server out of goroutines
+// or backend connections; a waiting call still counts toward MaxInFlight
+// and its request timeout.
+var (
+        // ToolWorkers caps tool calls running at once across a server.
+        ToolWorkers = 64
+        // SessionToolWorkers caps tool calls running at once on each
+        // connection, leaving its other request workers free for pings,
+        // lists, and cancellations.
+        SessionToolWorkers = 4
+)
+
+// workerPool hands out up to a limit of slots, queuing those who ask for
+// more. Its zero value has no slots taken.
+type workerPool struct {
+        mu      sync.Mutex
+        running int
+        // waiting holds a channel for each queued caller, oldest first,
+        // which is closed when the caller is handed a slot.
+        waiting []chan struct{}
+}
+
+// toolsQueued counts tool calls waiting for a slot, on their connection or
+// in their group.
+var toolsQueued atomic.Int64
+
+// acquire takes a slot, waiting while limit are taken and giving up when
+// ctx ends. With a limit of zero or less it never waits. A slot taken must
+// be released.
+func (p *workerPool) acquire(ctx context.Context, limit int) error {
+        p.mu.Lock()
+        if limit <= 0 || p.running < limit && len(p.waiting) == 0 {
+                p.running++
+                p.mu.Unlock()
+                return nil
+        }
+        ready := make(chan struct{})
+        p.waiting = append(p.waiting, ready)
+        p.mu.Unlock()
+
+        toolsQueued.Add(1)
+        defer toolsQueued.Add(-1)
+        select {
+        case <-ready:
+                return nil
+        case <-ctx.Done():
+        }
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        if i := slices.Index(p.waiting, ready); i >= 0 {
+                p.waiting = slices.Delete(p.waiting, i, i+1)
+        } else {
+                // The slot was handed over as ctx ended; pass it on.
+                p.releaseLocked()
+        }
+        return ctx.Err()
+}
+
+// release gives up a slot, handing it to the caller waiting longest.
+func (p *workerPool) release() {
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        p.releaseLocked()
+}
+
+func (p *workerPool) releaseLocked() {
+        if len(p.waiting) == 0 {
+                p.running--
+                return
+        }
+        close(p.waiting[0])
+        p.waiting = p.waiting[1:]
+}
+
+// busy returns the number of slots taken.
+func (p *workerPool) busy() int {
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        return p.running
+}
+
+// acquireToolWorker waits for c to be allowed another tool call, first
+// under SessionToolWorkers and then under its group's ToolWorkers, and
+// returns the function that ends it. It fails if ctx ends first.
+func (c *Conn) acquireToolWorker(ctx context.Context) (release func(), err error) {
+        settings := c.Group().Settings()
+        if err := c.toolWorkers.acquire(ctx, settings.SessionToolWorkers); err != nil {
+                return nil, err
+        }
+        if err := c.Group().toolWorkers.acquire(ctx, settings.ToolWorkers); err != nil {
+                c.toolWorkers.release()
+                return nil, err
+        }
+        return func() {
+                c.Group().toolWorkers.release()
+                c.toolWorkers.release()
+        }, nil
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+        "context"
+        "testing"
+        "time"
+)
+
+func TestWorkerPool(t *testing.T) {
+        var p workerPool
+        ctx := context.Background()
+        for i := 0; i < 2; i++ {
+                if err := p.acquire(ctx, 2); err != nil {
+                        t.Fatal(err)
+                }
+        }
+
+        // Callers beyond the limit queue in order.
+        got := make(chan int, 2)
+        for i := 1; i <= 2; i++ {
+                go func() {
+                        if err := p.acquire(ctx, 2); err == nil {
+                                got <- i
+                        }
+                }()
+                waitFor(t, func() bool { return toolsQueued.Load() == int64(i) })
+        }
+
+        // One that gives up leaves the queue.
+        cancelled, cancel := context.WithCancel(ctx)
+        done := make(chan error)
+        go func() { done <- p.acquire(cancelled, 2) }()
+        waitFor(t, func() bool { return toolsQueued.Load() == 3 })
+        cancel()
+        if err := <-done; err != context.Canceled {
+                t.Fatalf("cancelled acquire: got %v, want %v", err, context.Canceled)
+        }
+
+        for want := 1; want <= 2; want++ {
+                p.release()
+                if n := <-got; n != want {
+                        t.Fatalf("slot went to caller %d, want %d", n, want)
+                }
+        }
+        if busy, queued := p.busy(), toolsQueued.Load(); busy != 2 || queued != 0 {
+                t.Fatalf("got %d busy and %d queued, want 2 and 0", busy, queued)
+        }
+
+        // Without a limit nobody waits.
+        if err := p.acquire(ctx, 0); err != nil || p.busy() != 3 {
+                t.Fatalf("unlimited acquire: got %v with %d busy", err, p.busy())
+        }
+}
+
+// waitFor polls cond until it holds, failing the test after a second.
+func waitFor(t *testing.T, cond func() bool) {
+        t.Helper()
+        deadline := time.Now().Add(time.Second)
+        for !cond() {
+                if time.Now().After(deadline) {
+                        t.Fatal("timed out waiting")
+                }
+                time.Sleep(time.Millisecond)
+        }
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "math"
+        "slices"
+        "strconv"
+        "sync"
+)
+
+// ToolHandler executes a tool call. ctx carries the calling connection (see
+// ConnFromContext) and is cancelled when the client disconnects. The
+// result is encoded as the tools/call result, except that a json.RawMessage
+// is copied into the response as it is, so a handler that already holds
+// its result as JSON needn't decode it only to have it encoded again.
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+type Tool struct {
+        Name        string
+        Description string
+        // InputSchema must not change once the tool is registered: tools/list
+        // sends it as encoded the first time.
+        InputSchema map[string]interface{}
+        // Scopes are those a client must hold to see and call the tool when
+        // an -rbac policy is loaded. A tool without scopes is denied to
+        // every client then, unless it is Public.
+        Scopes  []string
+        Public  bool
+        Handler ToolHandler
+
+        schemaOnce sync.Once
+        schema     json.RawMessage
+        schemaErr  error
+}
+
+// encodedSchema returns t's input schema as JSON, encoding it the first
+// time it is asked for.
+func (t *Tool) encodedSchema() (json.RawMessage, error) {
+        t.schemaOnce.Do(func() {
+                t.schema, t.schemaErr = json.Marshal(t.InputSchema)
+        })
+        return t.schema, t.schemaErr
+}
+
+// NoArgsSchema is the input schema of tools that take no arguments.
+func NoArgsSchema() map[string]interface{} {
+        return map[string]interface{}{
+                "type":       "object",
+                "properties": map[string]interface{}{},
+        }
+}
+
+// tools holds the registered tools in registration order, which is the order
+// tools/list reports them in. Tools turned off with -disable-tools, or by
+// disabling mutations, stay registered but are skipped by findTool and
+// registeredTools.
+var tools = struct {
+        sync.RWMutex
+        list     []*Tool
+        disabled map[string]bool
+}{}
+
+func RegisterTool(t *Tool) {
+        tools.Lock()
+        tools.list = append(tools.list, t)
+        tools.Unlock()
+        registryChanged()
+}
+
+func findTool(name string) (*Tool, bool) {
+        tools.RLock()
+        defer tools.RUnlock()
+        for _, t := range tools.list {
+                if t.Name == name && t.enabled() {
+                        return t, true
+                }
+        }
+        return nil, false
+}
+
+func registeredTools() []*Tool {
+        tools.RLock()
+        defer tools.RUnlock()
+        list := make([]*Tool, 0, len(tools.list))
+        for _, t := range tools.list {
+                if t.enabled() {
+                        list = append(list, t)
+                }
+        }
+        return list
+}
+
+// enabled reports whether t is available; tools must be locked.
+func (t *Tool) enabled() bool {
+        return !tools.disabled[t.Name] && (capabilityEnabled("mutations") || !t.mutates())
+}
+
+// DisableTools turns off the named tools, replacing any turned off before.
+// Every name must be a registered tool.
+func DisableTools(names []string) error {
+        tools.Lock()
+        defer tools.Unlock()
+        disabled := map[string]bool{}
+        for _, name := range names {
+                if !slices.ContainsFunc(tools.list, func(t *Tool) bool { return t.Name == name }) {
+                        return fmt.Errorf("unknown tool %q", name)
+                }
+                disabled[name] = true
+        }
+        tools.disabled = disabled
+        registryChanged()
+        return nil
+}
+
+// JSONString renders a scalar JSON value as a string; integral numbers
+// have no decimal point.
+func JSONString(v interface{}) string {
+        switch x := v.(type) {
+        case nil:
+                return ""
+        case string:
+                return x
+        case float64:
+                if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+                        return strconv.FormatInt(int64(x), 10)
+                }
+                return strconv.FormatFloat(x, 'f', -1, 64)
+        }
+        return fmt.Sprint(v)
+}
+
+func StringArg(args map[string]interface{}, name string) string {
+        s, _ := args[name].(string)
+        return s
+}
+
+// StringsArg reads an optional array-of-strings argument. ok is false when
+// the argument is present but not an array of strings.
+func StringsArg(args map[string]interface{}, name string) (values []string, ok bool) {
+        raw, present := args[name]
+        if !present || raw == nil {
+                return nil, true
+        }
+        items, isArray := raw.([]interface{})
+        if !isArray {
+                return nil, false
+        }
+        for _, item := range items {
+                s, isString := item.(string)
+                if !isString {
+                        return nil, false
+                }
+                values = append(values, s)
+        }
+        return values, true
+}
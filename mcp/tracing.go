@@ -0,0 +1,163 @@
+package mcp
+
+import (
+        "context"
+        "encoding/json"
+        "log/slog"
+        "net/http"
+        "os"
+        "time"
+
+        "go.opentelemetry.io/otel"
+        "go.opentelemetry.io/otel/attribute"
+        "go.opentelemetry.io/otel/codes"
+        "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+        "go.opentelemetry.io/otel/propagation"
+        "go.opentelemetry.io/otel/sdk/resource"
+        sdktrace "go.opentelemetry.io/otel/sdk/trace"
+        "go.opentelemetry.io/otel/trace"
+)
+
+// Tracer records the spans of the request pipeline. Until SetupTracing
+// installs an exporter it is a no-op.
+var Tracer = otel.Tracer("mcp-server")
+
+// ShutdownTracing flushes spans still buffered for export.
+var ShutdownTracing = func() {}
+
+// SetupTracing exports spans over OTLP/HTTP to endpoint, e.g.
+// http://localhost:4318. With no endpoint, tracing is enabled only if the
+// standard OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+// variable is set; the other OTEL_* variables (service name, headers,
+// sampler) are honored too.
+func SetupTracing(endpoint string) error {
+        // Trace context is passed on to backends even when not exporting, so
+        // a trace started by the client continues past this server.
+        otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+        if endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+                return nil
+        }
+        var opts []otlptracehttp.Option
+        if endpoint != "" {
+                opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+        }
+        exporter, err := otlptracehttp.New(context.Background(), opts...)
+        if err != nil {
+                return err
+        }
+        res, err := resource.New(context.Background(),
+                resource.WithAttributes(attribute.String("service.name", "mcp-server")),
+                resource.WithFromEnv(),
+        )
+        if err != nil {
+                return err
+        }
+        provider := sdktrace.NewTracerProvider(
+                sdktrace.WithBatcher(exporter),
+                sdktrace.WithResource(res),
+        )
+        otel.SetTracerProvider(provider)
+        Tracer = provider.Tracer("mcp-server")
+        ShutdownTracing = func() {
+                ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+                defer cancel()
+                if err := provider.Shutdown(ctx); err != nil {
+                        slog.Error("Flushing traces", "error", err)
+                }
+        }
+        return nil
+}
+
+// startRequestSpan starts the server span covering one request from receipt
+// to response. A client can make it part of its own trace by sending W3C
+// trace context in the request's params._meta (traceparent, tracestate).
+func startRequestSpan(ctx context.Context, c *Conn, req Request, size int) (context.Context, trace.Span) {
+        var params struct {
+                Meta map[string]interface{} `json:"_meta"`
+        }
+        json.Unmarshal(req.Params, &params)
+        carrier := propagation.MapCarrier{}
+        for k, v := range params.Meta {
+                if s, ok := v.(string); ok {
+                        carrier[k] = s
+                }
+        }
+        ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+        attrs := []attribute.KeyValue{
+                attribute.String("rpc.system", "jsonrpc"),
+                attribute.String("rpc.method", req.Method),
+                attribute.String("rpc.jsonrpc.request_id", req.ID),
+                attribute.Int("messaging.message.body.size", size),
+        }
+        attrs = append(attrs, attribute.String("mcp.session.id", c.Session.ID))
+        if c.Session.Identity != "" {
+                attrs = append(attrs, attribute.String("enduser.id", c.Session.Identity))
+        }
+        return Tracer.Start(ctx, req.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// endRequestSpan records the outcome of response on span and ends it.
+func endRequestSpan(span trace.Span, response Response) {
+        if response.Error != nil {
+                span.SetAttributes(
+                        attribute.Int("rpc.jsonrpc.error_code", response.Error.Code),
+                        attribute.String("rpc.jsonrpc.error_message", response.Error.Message),
+                )
+                span.SetStatus(codes.Error, response.Error.Message)
+        }
+        span.End()
+}
+
+// startToolSpan starts the span covering one tool handler call.
+func startToolSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+        return Tracer.Start(ctx, "execute_tool "+name, trace.WithAttributes(attribute.String("mcp.tool.name", name)))
+}
+
+// endToolSpan records the tool's error, if it failed, and ends span.
+func endToolSpan(span trace.Span, mcpErr *Error) {
+        if mcpErr != nil {
+                span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", mcpErr.Code))
+                span.SetStatus(codes.Error, mcpErr.Message)
+        }
+        span.End()
+}
+
+// RecordSpanError marks span as failed with err, if err is non-nil.
+func RecordSpanError(span trace.Span, err error) {
+        if err != nil {
+                span.RecordError(err)
+                span.SetStatus(codes.Error, err.Error())
+        }
+}
+
+// StartClientSpan starts a client span for an outgoing HTTP request and
+// injects its trace context into the request headers, so the backend's
+// spans join the trace.
+func StartClientSpan(req *http.Request) (*http.Request, trace.Span) {
+        ctx, span := Tracer.Start(req.Context(), "HTTP "+req.Method,
+                trace.WithSpanKind(trace.SpanKindClient),
+                trace.WithAttributes(
+                        attribute.String("http.request.method", req.Method),
+                        attribute.String("server.address", req.URL.Hostname()),
+                        attribute.String("url.full", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path),
+                ),
+        )
+        req = req.WithContext(ctx)
+        otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+        return req, span
+}
+
+// EndClientSpan records the response status (or transport error) on span
+// and ends it.
+func EndClientSpan(span trace.Span, resp *http.Response, err error) {
+        if err != nil {
+                RecordSpanError(span, err)
+        } else {
+                span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+                if resp.StatusCode >= 400 {
+                        span.SetStatus(codes.Error, resp.Status)
+                }
+        }
+        span.End()
+}
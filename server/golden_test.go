@@ -0,0 +1,82 @@
+package server
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "flag"
+        "fmt"
+        "os"
+        "path/filepath"
+        "testing"
+        "time"
+
+        "mcp-server/tickets"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden with the responses the server sends now")
+
+// goldenExchanges are the requests whose responses are pinned, sent in
+// order on one connection.
+var goldenExchanges = []struct {
+        name    string
+        request string
+}{
+        {"initialize", `{"jsonrpc":"2.0","id":"1","method":"initialize","params":{"protocolVersion":"2025-06-18","clientInfo":{"name":"golden","version":"1.0"}}}`},
+        {"tools-list", `{"jsonrpc":"2.0","id":"2","method":"tools/list"}`},
+        {"get-todo-tickets", `{"jsonrpc":"2.0","id":"3","method":"tools/call","params":{"name":"get_todo_tickets"}}`},
+        {"create-ticket", `{"jsonrpc":"2.0","id":"4","method":"tools/call","params":{"name":"create_ticket","arguments":{"title":"Broken build","priority":"P1","labels":["ci"]}}}`},
+        {"update-ticket-status", `{"jsonrpc":"2.0","id":"5","method":"tools/call","params":{"name":"update_ticket_status","arguments":{"id":"T21","status":"pending","version":1}}}`},
+        {"update-ticket-status-invalid", `{"jsonrpc":"2.0","id":"6","method":"tools/call","params":{"name":"update_ticket_status","arguments":{"id":"T21","status":"someday"}}}`},
+        {"search-tickets", `{"jsonrpc":"2.0","id":"7","method":"tools/call","params":{"name":"search_tickets","arguments":{"query":"build"}}}`},
+        {"unknown-tool", `{"jsonrpc":"2.0","id":"8","method":"tools/call","params":{"name":"no_such_tool"}}`},
+        {"unknown-method", `{"jsonrpc":"2.0","id":"9","method":"no/such/method"}`},
+}
+
+// TestGoldenResponses checks that the server's responses are byte for byte
+// what testdata/golden holds, so changes to the wire format show up in
+// review. Run with -update to accept new responses.
+func TestGoldenResponses(t *testing.T) {
+        now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+        srv := New(WithClock(tickets.ClockFunc(func() time.Time { return now })))
+        pipe, _ := srv.Pipe()
+        defer pipe.Close()
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+
+        for i, exchange := range goldenExchanges {
+                if err := pipe.Send([]byte(exchange.request)); err != nil {
+                        t.Fatal(err)
+                }
+                var got []byte
+                for got == nil {
+                        data, err := pipe.Receive(ctx)
+                        if err != nil {
+                                t.Fatalf("%s: %v", exchange.name, err)
+                        }
+                        // Notifications, such as list changes, aren't
+                        // pinned.
+                        var msg struct {
+                                Method string `json:"method"`
+                        }
+                        if json.Unmarshal(data, &msg); msg.Method == "" {
+                                got = append(data, '\n')
+                        }
+                }
+
+                path := filepath.Join("testdata", "golden", fmt.Sprintf("%02d-%s.json", i+1, exchange.name))
+                if *update {
+                        if err := os.WriteFile(path, got, 0o644); err != nil {
+                                t.Fatal(err)
+                        }
+                        continue
+                }
+                want, err := os.ReadFile(path)
+                if err != nil {
+                        t.Fatalf("%v (run with -update to create it)", err)
+                }
+                if !bytes.Equal(got, want) {
+                        t.Errorf("%s: response differs from %s (run with -update to accept it)\ngot  %s\nwant %s", exchange.name, path, got, want)
+                }
+        }
+}
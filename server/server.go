@@ -0,0 +1,203 @@
+// Package server assembles the ticket tracker MCP server from the protocol
+// in package mcp, the transports in package transport, and the ticket tools
+// in package tickets. Each Server has its own store, clients, limits,
+// authorization policy, endpoints, and status transitions, so several can
+// run in one process. What the process loads once is shared by them all:
+// the tools, prompts, plugins, and client overrides, the tools and
+// capabilities disabled, message size limits, and shared sessions.
+package server
+
+import (
+        "context"
+        "log/slog"
+        "net/http"
+
+        "github.com/gorilla/websocket"
+        "mcp-server/mcp"
+        "mcp-server/tickets"
+        "mcp-server/transport"
+)
+
+// Server serves the ticket tools over WebSocket and Streamable HTTP.
+type Server struct {
+        store      tickets.TicketStore
+        clock      tickets.Clock
+        machine    *tickets.StatusMachine
+        settings   mcp.Settings
+        group      *mcp.Group
+        handler    mcp.Handler
+        middleware []mcp.Middleware
+        wsPath     string
+        httpPath   string
+        transport  transport.Server
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithStore makes the server's tools and resources work on store. Without
+// it they work on an in-memory store of the demo tickets, recording
+// changes for history and undo.
+func WithStore(store tickets.TicketStore) Option {
+        return func(s *Server) { s.store = store }
+}
+
+// WithClock makes the server's tools, and its default store, read the time
+// from clock rather than the wall clock.
+func WithClock(clock tickets.Clock) Option {
+        return func(s *Server) { s.clock = clock }
+}
+
+// WithLogger logs the server's connections to logger rather than
+// slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+        return func(s *Server) { s.transport.Logger = logger }
+}
+
+// WithUpgrader upgrades WebSocket requests with a copy of u. Unless u says
+// otherwise, origins are checked against -allowed-origins and the upgrade
+// is bounded by the handshake timeout.
+func WithUpgrader(u *websocket.Upgrader) Option {
+        return func(s *Server) { s.transport.Upgrader = *u }
+}
+
+// WithHandler answers the clients' requests with h rather than
+// mcp.DefaultMux, to add methods or wrap the built-in ones.
+func WithHandler(h mcp.Handler) Option {
+        return func(s *Server) { s.handler = h }
+}
+
+// WithMiddleware passes every request through mw, in order, before the
+// server's handler. Each use adds to the middleware already given.
+func WithMiddleware(mw ...mcp.Middleware) Option {
+        return func(s *Server) { s.middleware = append(s.middleware, mw...) }
+}
+
+// WithHooks runs h at points in each connection's life.
+func WithHooks(h mcp.Hooks) Option {
+        return func(s *Server) { s.transport.Hooks = h }
+}
+
+// WithAuth authenticates clients as a says. Without it the server accepts
+// unauthenticated connections.
+func WithAuth(a transport.Auth) Option {
+        return func(s *Server) { s.transport.Auth = a }
+}
+
+// WithAllowedOrigins admits browsers from the origins in list, a
+// comma-separated allowlist in which "*" allows any origin, and with
+// loopbackOnly refuses requests whose Host isn't a loopback name. Without
+// it the server admits its own origin and localhost.
+func WithAllowedOrigins(list string, loopbackOnly bool) Option {
+        return func(s *Server) { s.transport.Origins = transport.NewOriginPolicy(list, loopbackOnly) }
+}
+
+// WithTimeouts replaces transport.DefaultTimeouts.
+func WithTimeouts(t transport.Timeouts) Option {
+        return func(s *Server) { s.transport.Timeouts = t }
+}
+
+// WithSettings limits the server's clients, and authorizes them, as
+// settings says, rather than as mcp.DefaultSettings does.
+func WithSettings(settings mcp.Settings) Option {
+        return func(s *Server) { s.settings = settings }
+}
+
+// WithPaths serves the WebSocket transport at ws and Streamable HTTP at
+// http, rather than at transport.WSPath and transport.HTTPPath.
+func WithPaths(ws, http string) Option {
+        return func(s *Server) { s.wsPath, s.httpPath = ws, http }
+}
+
+// WithMaxSessionsPerClient caps the Streamable HTTP sessions each client
+// may hold at n, rather than at transport.MaxSessionsPerClient. Zero means
+// no limit.
+func WithMaxSessionsPerClient(n int) Option {
+        return func(s *Server) { s.transport.MaxSessionsPerClient = n }
+}
+
+// WithStatusMachine makes the server's tools allow the status changes m
+// does, rather than the default transitions.
+func WithStatusMachine(m *tickets.StatusMachine) Option {
+        return func(s *Server) { s.machine = m }
+}
+
+// New returns a server configured by opts.
+func New(opts ...Option) *Server {
+        s := &Server{
+                clock:    tickets.SystemClock,
+                settings: mcp.DefaultSettings(),
+                wsPath:   transport.WSPath,
+                httpPath: transport.HTTPPath,
+                transport: transport.Server{
+                        Timeouts:             transport.DefaultTimeouts,
+                        MaxSessionsPerClient: transport.MaxSessionsPerClient,
+                },
+        }
+        for _, opt := range opts {
+                opt(s)
+        }
+        if s.store == nil {
+                store := tickets.NewMemoryStore(tickets.DemoTickets(), s.clock)
+                s.store = tickets.NewEventStore(store, tickets.NewEventLog(s.clock))
+        }
+        if s.handler != nil || len(s.middleware) > 0 {
+                handler := s.handler
+                if handler == nil {
+                        handler = mcp.DefaultMux
+                }
+                s.transport.Handler = mcp.Chain(handler, s.middleware...)
+        }
+        // The server's connections share a group, which holds its limits
+        // and the resource read cache that must not serve another server's
+        // store.
+        s.group = mcp.NewGroup(s.settings)
+        store, clock, machine, group := s.store, s.clock, s.machine, s.group
+        s.transport.BaseContext = func() context.Context {
+                ctx := mcp.ContextWithGroup(context.Background(), group)
+                ctx = tickets.ContextWithClock(tickets.ContextWithStore(ctx, store), clock)
+                if machine != nil {
+                        ctx = tickets.ContextWithStatusMachine(ctx, machine)
+                }
+                return ctx
+        }
+        return s
+}
+
+// Store returns the store the server's tools work on.
+func (s *Server) Store() tickets.TicketStore {
+        return s.store
+}
+
+// Clients returns the server's connected clients.
+func (s *Server) Clients() []*mcp.Conn {
+        return s.group.Clients()
+}
+
+// SetRateLimits puts limits into force for the server's new connections;
+// see mcp.Group.SetRateLimits.
+func (s *Server) SetRateLimits(limits mcp.RateLimits) {
+        s.group.SetRateLimits(limits)
+}
+
+// Handler serves the WebSocket transport and Streamable HTTP at the
+// server's paths.
+func (s *Server) Handler() http.Handler {
+        mux := http.NewServeMux()
+        mux.HandleFunc(s.wsPath, s.transport.HandleWebSocket)
+        mux.HandleFunc(s.httpPath, s.transport.HandleStreamableHTTP)
+        return mux
+}
+
+// Pipe connects a client to the server in memory, returning the client's
+// end and the server's connection, for tests that need no network.
+func (s *Server) Pipe() (*transport.PipeClient, *mcp.Conn) {
+        return s.transport.Pipe()
+}
+
+// Replay plays the client's side of a recorded session back to the server
+// and returns the frames the server sends in reply; see
+// transport.Server.Replay.
+func (s *Server) Replay(ctx context.Context, recorded []mcp.Frame) ([]mcp.Frame, error) {
+        return s.transport.Replay(ctx, recorded)
+}
@@ -0,0 +1,489 @@
+package server
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "io"
+        "net/http"
+        "net/http/httptest"
+        "os"
+        "path/filepath"
+        "reflect"
+        "slices"
+        "strings"
+        "testing"
+        "time"
+
+        "mcp-server/client"
+        "mcp-server/mcp"
+        "mcp-server/tickets"
+        "mcp-server/transport"
+)
+
+func testContext(t *testing.T) context.Context {
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        t.Cleanup(cancel)
+        return ctx
+}
+
+// exercise initializes a session with c and creates a ticket through it.
+func exercise(t *testing.T, ctx context.Context, c *client.Client) {
+        init, err := c.Initialize(ctx, mcp.ClientInfo{Name: "server-test", Version: "1"})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if init.Capabilities.Tools == nil {
+                t.Error("initialize: no tools capability")
+        }
+        _, err = c.Initialize(ctx, mcp.ClientInfo{Name: "other-client", Version: "1"})
+        if mcpErr := (*mcp.Error)(nil); !errors.As(err, &mcpErr) || mcpErr.Code != -32600 {
+                t.Errorf("second initialize: got %v, want a -32600 error", err)
+        }
+
+        tools, err := c.ListTools(ctx)
+        if err != nil {
+                t.Fatal(err)
+        }
+        if !slices.ContainsFunc(tools, func(tool mcp.ToolInfo) bool { return tool.Name == "create_ticket" }) {
+                t.Errorf("tools/list: create_ticket missing from %d tools", len(tools))
+        }
+
+        data, err := c.CallTool(ctx, "create_ticket", map[string]interface{}{"title": "Broken build"})
+        if err != nil {
+                t.Fatal(err)
+        }
+        var created struct {
+                Ticket tickets.Ticket `json:"ticket"`
+        }
+        if err := json.Unmarshal(data, &created); err != nil {
+                t.Fatal(err)
+        }
+        if created.Ticket.Title != "Broken build" || created.Ticket.Status != "todo" {
+                t.Errorf("create_ticket: got %+v", created.Ticket)
+        }
+
+        _, err = c.CallTool(ctx, "create_ticket", map[string]interface{}{"title": "x", "status": "bogus"})
+        var mcpErr *mcp.Error
+        if !errors.As(err, &mcpErr) || mcpErr.Code != -32602 {
+                t.Errorf("create_ticket with a bad status: got %v, want a -32602 error", err)
+        }
+}
+
+func TestPipeClient(t *testing.T) {
+        ctx := testContext(t)
+        now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+        srv := New(WithClock(tickets.ClockFunc(func() time.Time { return now })))
+        pipe, _ := srv.Pipe()
+        c := client.New(pipe)
+        defer c.Close()
+        exercise(t, ctx, c)
+
+        list, _ := srv.Store().ListTickets(ctx, tickets.TicketFilter{})
+        var found bool
+        for _, ticket := range list {
+                if ticket.Title == "Broken build" {
+                        found = true
+                        if !ticket.CreatedAt.Equal(now) {
+                                t.Errorf("created at %v, want %v from the server's clock", ticket.CreatedAt, now)
+                        }
+                }
+        }
+        if !found {
+                t.Error("created ticket missing from the server's store")
+        }
+}
+
+func TestWebSocketClient(t *testing.T) {
+        ctx := testContext(t)
+        httpServer := httptest.NewServer(New().Handler())
+        defer httpServer.Close()
+        url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + transport.WSPath
+        c, err := client.Connect(ctx, url, nil)
+        if err != nil {
+                t.Fatal(err)
+        }
+        defer c.Close()
+        exercise(t, ctx, c)
+}
+
+// postHTTP sends a Streamable HTTP initialize, or a ping on session if it
+// is set, with API key key if it is set.
+func postHTTP(t *testing.T, url, key, session string) *http.Response {
+        t.Helper()
+        body := `{"jsonrpc":"2.0","id":"1","method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+        if session != "" {
+                body = `{"jsonrpc":"2.0","id":"2","method":"ping"}`
+        }
+        req, _ := http.NewRequest(http.MethodPost, url+transport.HTTPPath, strings.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        req.Header.Set("Accept", "application/json, text/event-stream")
+        if key != "" {
+                req.Header.Set("X-API-Key", key)
+        }
+        if session != "" {
+                req.Header.Set("Mcp-Session-Id", session)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+                t.Fatal(err)
+        }
+        resp.Body.Close()
+        return resp
+}
+
+func TestServersDontShareAuthOrSessions(t *testing.T) {
+        open := httptest.NewServer(New().Handler())
+        defer open.Close()
+        keyed := httptest.NewServer(New(WithAuth(transport.Auth{APIKeys: map[string]string{"k": "alice"}})).Handler())
+        defer keyed.Close()
+        if resp := postHTTP(t, keyed.URL, "", ""); resp.StatusCode != http.StatusUnauthorized {
+                t.Errorf("keyed server without a key: got status %d", resp.StatusCode)
+        }
+        resp := postHTTP(t, open.URL, "", "")
+        if resp.StatusCode != http.StatusOK {
+                t.Fatalf("open server: got status %d", resp.StatusCode)
+        }
+        session := resp.Header.Get("Mcp-Session-Id")
+        if resp := postHTTP(t, open.URL, "", session); resp.StatusCode != http.StatusOK {
+                t.Errorf("session on its own server: got status %d", resp.StatusCode)
+        }
+        if resp := postHTTP(t, keyed.URL, "k", session); resp.StatusCode != http.StatusNotFound {
+                t.Errorf("session on another server: got status %d, want %d", resp.StatusCode, http.StatusNotFound)
+        }
+}
+
+// TestCompletionsAndListsUseTheServersStore checks that completions and
+// resource lists run in the request's context, which carries its server's
+// store.
+func TestCompletionsAndListsUseTheServersStore(t *testing.T) {
+        ctx := testContext(t)
+        for _, id := range []string{"A1", "B1"} {
+                store := tickets.NewMemoryStore([]tickets.Ticket{{ID: id, Title: "Only here", Status: "todo"}}, nil)
+                c := stressClient(t, ctx, New(WithStore(store)))
+                defer c.Close()
+
+                var completed mcp.CompleteResult
+                params := mcp.CompleteParams{
+                        Ref:      mcp.CompletionReference{Type: "ref/resource", URI: "ticket://{id}"},
+                        Argument: mcp.CompletionArgument{Name: "id"},
+                }
+                if err := c.Call(ctx, "completion/complete", params, &completed); err != nil {
+                        t.Fatal(err)
+                }
+                if !slices.Equal(completed.Completion.Values, []string{id}) {
+                        t.Errorf("server with %s: completed ticket IDs %q", id, completed.Completion.Values)
+                }
+
+                var listed mcp.ListResourcesResult
+                if err := c.Call(ctx, "resources/list", nil, &listed); err != nil {
+                        t.Fatal(err)
+                }
+                if !slices.ContainsFunc(listed.Resources, func(r mcp.Resource) bool { return r.URI == "ticket://"+id }) ||
+                        slices.ContainsFunc(listed.Resources, func(r mcp.Resource) bool { return strings.HasPrefix(r.URI, "ticket://") && r.URI != "ticket://"+id }) {
+                        t.Errorf("server with %s: listed %+v", id, listed.Resources)
+                }
+        }
+}
+
+func TestServersDontShareResourceReads(t *testing.T) {
+        ctx := testContext(t)
+        const uri = "ticket://T1"
+        read := func(c *client.Client) tickets.Ticket {
+                t.Helper()
+                var result mcp.ReadResourceResult
+                if err := c.Call(ctx, "resources/read", mcp.ResourceReadParams{URI: uri}, &result); err != nil {
+                        t.Fatal(err)
+                }
+                var ticket tickets.Ticket
+                if err := json.Unmarshal([]byte(result.Contents[0].Text), &ticket); err != nil {
+                        t.Fatal(err)
+                }
+                return ticket
+        }
+        var clients []*client.Client
+        for _, title := range []string{"In store A", "In store B"} {
+                store := tickets.NewMemoryStore([]tickets.Ticket{{ID: "T1", Title: title, Status: "todo"}}, tickets.SystemClock)
+                c := stressClient(t, ctx, New(WithStore(store)))
+                defer c.Close()
+                if got := read(c).Title; got != title {
+                        t.Errorf("server with %q read %q", title, got)
+                }
+                clients = append(clients, c)
+        }
+
+        // A change on one server refreshes its reads, not the other's.
+        version := read(clients[0]).Version
+        if _, err := clients[0].CallTool(ctx, "update_ticket_status", map[string]interface{}{"id": "T1", "status": "pending", "version": version}); err != nil {
+                t.Fatal(err)
+        }
+        if got := read(clients[0]).Status; got != "pending" {
+                t.Errorf("server A after the update: status %q", got)
+        }
+        if got := read(clients[1]); got.Title != "In store B" || got.Status != "todo" {
+                t.Errorf("server B after A's update: got %+v", got)
+        }
+}
+
+func TestServersDontShareNotificationsOrLimits(t *testing.T) {
+        ctx := testContext(t)
+        a, b := stressClient(t, ctx, New()), stressClient(t, ctx, New())
+        defer a.Close()
+        defer b.Close()
+        if _, err := a.CallTool(ctx, "create_ticket", map[string]interface{}{"title": "Only on A"}); err != nil {
+                t.Fatal(err)
+        }
+        listChanged := func(c *client.Client, wait time.Duration) bool {
+                timeout := time.After(wait)
+                for {
+                        select {
+                        case n := <-c.Notifications():
+                                if n.Method == "notifications/resources/list_changed" {
+                                        return true
+                                }
+                        case <-timeout:
+                                return false
+                        }
+                }
+        }
+        if !listChanged(a, 2*time.Second) {
+                t.Error("server A's client wasn't told its resources changed")
+        }
+        if listChanged(b, 100*time.Millisecond) {
+                t.Error("server B's client was told of a change on server A")
+        }
+
+        full := httptest.NewServer(New(WithSettings(mcp.Settings{MaxConnections: 1, MaxInFlight: 1})).Handler())
+        defer full.Close()
+        other := httptest.NewServer(New().Handler())
+        defer other.Close()
+        if resp := postHTTP(t, full.URL, "", ""); resp.StatusCode != http.StatusOK {
+                t.Fatalf("first session: got status %d", resp.StatusCode)
+        }
+        if resp := postHTTP(t, full.URL, "", ""); resp.StatusCode != http.StatusServiceUnavailable {
+                t.Errorf("second session on the full server: got status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+        }
+        if resp := postHTTP(t, other.URL, "", ""); resp.StatusCode != http.StatusOK {
+                t.Errorf("session on another server: got status %d", resp.StatusCode)
+        }
+}
+
+func TestStatusMachinePerServer(t *testing.T) {
+        ctx := testContext(t)
+        machine, err := tickets.NewStatusMachine("todo>done", "")
+        if err != nil {
+                t.Fatal(err)
+        }
+        store := func() tickets.TicketStore {
+                return tickets.NewMemoryStore([]tickets.Ticket{{ID: "T1", Title: "Flaky test", Status: "todo"}}, tickets.SystemClock)
+        }
+        strict := stressClient(t, ctx, New(WithStore(store()), WithStatusMachine(machine)))
+        defer strict.Close()
+        lenient := stressClient(t, ctx, New(WithStore(store())))
+        defer lenient.Close()
+        args := map[string]interface{}{"id": "T1", "status": "pending", "version": 1}
+        var mcpErr *mcp.Error
+        if _, err := strict.CallTool(ctx, "update_ticket_status", args); !errors.As(err, &mcpErr) || mcpErr.Code != -32602 {
+                t.Errorf("todo>pending on the strict server: got %v, want a -32602 error", err)
+        }
+        if _, err := lenient.CallTool(ctx, "update_ticket_status", args); err != nil {
+                t.Errorf("todo>pending on the default server: %v", err)
+        }
+}
+
+func TestSessionsPerClient(t *testing.T) {
+        httpServer := httptest.NewServer(New(WithMaxSessionsPerClient(2)).Handler())
+        defer httpServer.Close()
+        var sessions []string
+        for i := 0; i < 2; i++ {
+                resp := postHTTP(t, httpServer.URL, "", "")
+                if resp.StatusCode != http.StatusOK {
+                        t.Fatalf("session %d: got status %d", i+1, resp.StatusCode)
+                }
+                sessions = append(sessions, resp.Header.Get("Mcp-Session-Id"))
+        }
+        if resp := postHTTP(t, httpServer.URL, "", ""); resp.StatusCode != http.StatusTooManyRequests {
+                t.Fatalf("third session: got status %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+        }
+
+        req, _ := http.NewRequest(http.MethodDelete, httpServer.URL+transport.HTTPPath, nil)
+        req.Header.Set("Mcp-Session-Id", sessions[0])
+        if resp, err := http.DefaultClient.Do(req); err != nil {
+                t.Fatal(err)
+        } else {
+                resp.Body.Close()
+        }
+        if resp := postHTTP(t, httpServer.URL, "", ""); resp.StatusCode != http.StatusOK {
+                t.Errorf("after ending one: got status %d", resp.StatusCode)
+        }
+}
+
+func TestFailedInitializeEndsSession(t *testing.T) {
+        httpServer := httptest.NewServer(New(WithMaxSessionsPerClient(1)).Handler())
+        defer httpServer.Close()
+        for i := 0; i < 2; i++ {
+                req, _ := http.NewRequest(http.MethodPost, httpServer.URL+transport.HTTPPath, strings.NewReader(`{"jsonrpc":"2.0","id":"1","method":"initialize","params":"bad"}`))
+                req.Header.Set("Content-Type", "application/json")
+                req.Header.Set("Accept", "application/json, text/event-stream")
+                resp, err := http.DefaultClient.Do(req)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                resp.Body.Close()
+                if resp.StatusCode != http.StatusOK || resp.Header.Get("Mcp-Session-Id") != "" {
+                        t.Fatalf("failed initialize %d: got status %d, session %q", i+1, resp.StatusCode, resp.Header.Get("Mcp-Session-Id"))
+                }
+        }
+        if resp := postHTTP(t, httpServer.URL, "", ""); resp.StatusCode != http.StatusOK {
+                t.Errorf("after failed initializes: got status %d", resp.StatusCode)
+        }
+}
+
+func TestSlowBody(t *testing.T) {
+        timeouts := transport.DefaultTimeouts
+        timeouts.Body = 50 * time.Millisecond
+        httpServer := httptest.NewServer(New(WithTimeouts(timeouts)).Handler())
+        defer httpServer.Close()
+        body, stall := io.Pipe()
+        defer stall.Close()
+        go stall.Write([]byte(`{"jsonrpc":"2.0",`))
+        req, _ := http.NewRequest(http.MethodPost, httpServer.URL+transport.HTTPPath, body)
+        req.Header.Set("Content-Type", "application/json")
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+                t.Fatal(err)
+        }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusRequestTimeout {
+                t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusRequestTimeout)
+        }
+}
+
+func TestNotifications(t *testing.T) {
+        ctx := testContext(t)
+        pipe, _ := New().Pipe()
+        c := client.New(pipe)
+        defer c.Close()
+        if _, err := c.Initialize(ctx, mcp.ClientInfo{Name: "server-test"}); err != nil {
+                t.Fatal(err)
+        }
+        if err := c.Call(ctx, "logging/setLevel", map[string]string{"level": "debug"}, nil); err != nil {
+                t.Fatal(err)
+        }
+        if _, err := c.ListTools(ctx); err != nil {
+                t.Fatal(err)
+        }
+        select {
+        case n := <-c.Notifications():
+                if n.Method != "notifications/message" {
+                        t.Errorf("got notification %s, want notifications/message", n.Method)
+                }
+        case <-ctx.Done():
+                t.Fatal("no log notification")
+        }
+}
+
+// responses returns the responses among frames sent to the client, by ID.
+func responses(t *testing.T, frames []mcp.Frame) map[string]map[string]interface{} {
+        byID := map[string]map[string]interface{}{}
+        for _, f := range frames {
+                var msg map[string]interface{}
+                if f.Direction != "out" {
+                        continue
+                }
+                if err := json.Unmarshal(f.Data(), &msg); err != nil {
+                        t.Fatal(err)
+                }
+                if id, ok := msg["id"].(string); ok && msg["method"] == nil {
+                        byID[id] = msg
+                }
+        }
+        return byID
+}
+
+func TestRecordAndReplay(t *testing.T) {
+        ctx := testContext(t)
+        now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+        clock := tickets.ClockFunc(func() time.Time { return now })
+
+        dir := t.TempDir()
+        if err := mcp.RecordSessions(dir); err != nil {
+                t.Fatal(err)
+        }
+        pipe, conn := New(WithClock(clock)).Pipe()
+        mcp.RecordSessions("")
+        c := client.New(pipe)
+        exercise(t, ctx, c)
+        c.Close()
+
+        file, err := os.Open(filepath.Join(dir, conn.Session.ID+".jsonl"))
+        if err != nil {
+                t.Fatal(err)
+        }
+        defer file.Close()
+        recorded, err := mcp.ReadRecording(file)
+        if err != nil {
+                t.Fatal(err)
+        }
+        want := responses(t, recorded)
+        if len(want) != 5 {
+                t.Fatalf("recorded %d responses, want 5", len(want))
+        }
+
+        replayed, err := New(WithClock(clock)).Replay(ctx, recorded)
+        if err != nil {
+                t.Fatal(err)
+        }
+        got := responses(t, replayed)
+        for id, resp := range want {
+                if !reflect.DeepEqual(got[id], resp) {
+                        t.Errorf("response to %s: got %v, want %v as recorded", id, got[id], resp)
+                }
+        }
+}
+
+func TestListCacheInvalidation(t *testing.T) {
+        ctx := testContext(t)
+        c := stressClient(t, ctx, New())
+        defer c.Close()
+        hasTool := func(name string) bool {
+                t.Helper()
+                tools, err := c.ListTools(ctx)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                return slices.ContainsFunc(tools, func(tool mcp.ToolInfo) bool { return tool.Name == name })
+        }
+        hasPrompt := func(name string) bool {
+                t.Helper()
+                var result mcp.ListPromptsResult
+                if err := c.Call(ctx, "prompts/list", nil, &result); err != nil {
+                        t.Fatal(err)
+                }
+                return slices.ContainsFunc(result.Prompts, func(p mcp.Prompt) bool { return p.Name == name })
+        }
+
+        t.Cleanup(func() { mcp.DisableTools(nil) })
+        for _, disabled := range []bool{false, true, true, false, false} {
+                if disabled {
+                        mcp.DisableTools([]string{"search_tickets"})
+                } else {
+                        mcp.DisableTools(nil)
+                }
+                if hasTool("search_tickets") == disabled {
+                        t.Errorf("tools/list with search_tickets disabled %v: got it listed %v", disabled, !disabled)
+                }
+        }
+
+        dir := t.TempDir()
+        t.Cleanup(func() { mcp.ReloadPromptDir(t.TempDir()) })
+        if !mcp.ReloadPromptDir(dir) || hasPrompt("cache_test") {
+                t.Fatal("prompts/list: cache_test listed before it was written")
+        }
+        if err := os.WriteFile(filepath.Join(dir, "cache_test.md"), []byte("Hello"), 0o600); err != nil {
+                t.Fatal(err)
+        }
+        if !mcp.ReloadPromptDir(dir) || !hasPrompt("cache_test") {
+                t.Error("prompts/list: cache_test missing after the prompt directory was reloaded")
+        }
+}
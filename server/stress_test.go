@@ -0,0 +1,235 @@
+package server
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "net/http/httptest"
+        "strings"
+        "sync"
+        "testing"
+        "time"
+
+        "mcp-server/client"
+        "mcp-server/mcp"
+        "mcp-server/transport"
+)
+
+// The stress tests run many clients at once to flush out data races in the
+// connection, session, and registry code. They are only useful under the
+// race detector:
+//
+//        go test -race -run Stress ./server
+//
+// and are skipped with -short.
+
+const (
+        stressClients = 32
+        stressCalls   = 20
+)
+
+// stress runs f(i) on n goroutines, releasing them together, and waits for
+// them to return.
+func stress(t *testing.T, n int, f func(i int)) {
+        t.Helper()
+        if testing.Short() {
+                t.Skip("stress test")
+        }
+        start := make(chan struct{})
+        var wg sync.WaitGroup
+        for i := 0; i < n; i++ {
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        <-start
+                        f(i)
+                }()
+        }
+        close(start)
+        wg.Wait()
+}
+
+// background runs f repeatedly until the test ends.
+func background(t *testing.T, f func(i int)) {
+        done, stopped := make(chan struct{}), make(chan struct{})
+        go func() {
+                defer close(stopped)
+                for i := 0; ; i++ {
+                        select {
+                        case <-done:
+                                return
+                        default:
+                                f(i)
+                        }
+                }
+        }()
+        t.Cleanup(func() {
+                close(done)
+                <-stopped
+        })
+}
+
+// stressClient returns an initialized client of srv over a pipe.
+func stressClient(t *testing.T, ctx context.Context, srv *Server) *client.Client {
+        pipe, _ := srv.Pipe()
+        c := client.New(pipe)
+        if _, err := c.Initialize(ctx, mcp.ClientInfo{Name: "stress", Version: "1"}); err != nil {
+                t.Error(err)
+        }
+        return c
+}
+
+// wantCodes fails the test unless err is nil or an *mcp.Error with one of
+// codes.
+func wantCodes(t *testing.T, what string, err error, codes ...int) {
+        t.Helper()
+        var mcpErr *mcp.Error
+        if err == nil {
+                return
+        }
+        if errors.As(err, &mcpErr) {
+                for _, code := range codes {
+                        if mcpErr.Code == code {
+                                return
+                        }
+                }
+        }
+        t.Errorf("%s: %v", what, err)
+}
+
+func TestStressConnects(t *testing.T) {
+        ctx := testContext(t)
+        httpServer := httptest.NewServer(New().Handler())
+        defer httpServer.Close()
+        wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + transport.WSPath
+        stress(t, stressClients, func(i int) {
+                var c *client.Client
+                if i%2 == 0 {
+                        var err error
+                        if c, err = client.Connect(ctx, wsURL, nil); err != nil {
+                                t.Error(err)
+                                return
+                        }
+                } else {
+                        c = client.ConnectHTTP(httpServer.URL+transport.HTTPPath, nil)
+                }
+                defer c.Close()
+                if _, err := c.Initialize(ctx, mcp.ClientInfo{Name: fmt.Sprintf("stress-%d", i), Version: "1"}); err != nil {
+                        t.Error(err)
+                        return
+                }
+                if _, err := c.ListTools(ctx); err != nil {
+                        t.Error(err)
+                }
+        })
+}
+
+func TestStressToolCalls(t *testing.T) {
+        ctx := testContext(t)
+        srv := New()
+        calls := []struct {
+                tool string
+                args map[string]interface{}
+        }{
+                {"create_ticket", map[string]interface{}{"title": "Stress"}},
+                {"search_tickets", map[string]interface{}{"query": "stress"}},
+                {"get_todo_tickets", nil},
+                {"add_labels", map[string]interface{}{"id": "T20", "labels": []interface{}{"stress"}, "version": 1}},
+                {"add_comment", map[string]interface{}{"ticket_id": "T20", "body": "stress"}},
+                {"server_stats", nil},
+        }
+        stress(t, stressClients/4, func(int) {
+                c := stressClient(t, ctx, srv)
+                defer c.Close()
+                stress(t, stressCalls, func(i int) {
+                        call := calls[i%len(calls)]
+                        _, err := c.CallTool(ctx, call.tool, call.args)
+                        // Concurrent updates of one ticket may conflict, and
+                        // server_stats needs the admin scope.
+                        wantCodes(t, call.tool, err, -32010, -32011)
+                })
+        })
+}
+
+func TestStressRegistryChurn(t *testing.T) {
+        ctx := testContext(t)
+        srv := New()
+        // Cleanups run last first, so the tools are enabled again once the
+        // churn has stopped.
+        t.Cleanup(func() { mcp.DisableTools(nil) })
+        background(t, func(i int) {
+                if i%2 == 0 {
+                        mcp.DisableTools([]string{"search_tickets"})
+                } else {
+                        mcp.DisableTools(nil)
+                }
+        })
+        stress(t, stressClients/4, func(int) {
+                c := stressClient(t, ctx, srv)
+                defer c.Close()
+                for i := 0; i < stressCalls; i++ {
+                        if _, err := c.ListTools(ctx); err != nil {
+                                t.Error(err)
+                        }
+                        _, err := c.CallTool(ctx, "search_tickets", map[string]interface{}{"query": "login"})
+                        wantCodes(t, "search_tickets", err, -32602)
+                }
+        })
+}
+
+func TestStressSubscriptions(t *testing.T) {
+        ctx := testContext(t)
+        srv := New()
+        const uri = "ticket://T1"
+        background(t, func(int) { mcp.LocalResourceUpdated(uri) })
+        stress(t, stressClients/2, func(int) {
+                c := stressClient(t, ctx, srv)
+                for i := 0; i < stressCalls; i++ {
+                        if err := c.Call(ctx, "resources/subscribe", map[string]string{"uri": uri}, nil); err != nil {
+                                t.Error(err)
+                        }
+                        if err := c.Call(ctx, "resources/unsubscribe", map[string]string{"uri": uri}, nil); err != nil {
+                                t.Error(err)
+                        }
+                }
+                // Disconnect while subscribed, which drops the subscription.
+                if err := c.Call(ctx, "resources/subscribe", map[string]string{"uri": uri}, nil); err != nil {
+                        t.Error(err)
+                }
+                c.Close()
+        })
+}
+
+func TestStressShutdown(t *testing.T) {
+        ctx := testContext(t)
+        srv := New()
+        clients := make([]*client.Client, stressClients/2)
+        for i := range clients {
+                clients[i] = stressClient(t, ctx, srv)
+        }
+        stress(t, len(clients)+1, func(i int) {
+                if i == len(clients) {
+                        time.Sleep(10 * time.Millisecond)
+                        for _, c := range mcp.ConnectedClients() {
+                                go c.Shutdown()
+                        }
+                        return
+                }
+                c := clients[i]
+                defer c.Close()
+                for {
+                        _, err := c.CallTool(ctx, "search_tickets", map[string]interface{}{"query": "login"})
+                        switch {
+                        case errors.Is(err, client.ErrClosed):
+                                return
+                        case errors.Is(err, context.DeadlineExceeded):
+                                t.Error("still connected at the deadline")
+                                return
+                        case err != nil && !strings.Contains(err.Error(), "closed"):
+                                // Sending fails once the pipe has closed.
+                                t.Error(err)
+                                return
+                        }
+                }
+        })
+}
@@ -0,0 +1,104 @@
+package tickets
+
+import (
+        "context"
+        "sort"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+func handleAssignTicket(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        assignee := strings.TrimSpace(mcp.StringArg(args, "assignee"))
+        if assignee == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "assignee is required")
+        }
+        return setTicketAssignee(ctx, args, assignee)
+}
+
+func handleUnassignTicket(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        return setTicketAssignee(ctx, args, "")
+}
+
+func setTicketAssignee(ctx context.Context, args map[string]interface{}, assignee string) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is deleted; restore it first", id)
+        }
+        if t.Assignee == assignee {
+                return map[string]interface{}{"ticket": t}, nil
+        }
+
+        t.Assignee = assignee
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if assignee == "" {
+                ticketUpdated(ctx, updated, "unassigned")
+        } else {
+                ticketUpdated(ctx, updated, "assigned to %s", assignee)
+        }
+        return map[string]interface{}{"ticket": updated}, nil
+}
+
+func handleTicketsByAssignee(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        filter := TicketFilter{
+                Assignee: strings.TrimSpace(mcp.StringArg(args, "assignee")),
+                Status:   mcp.StringArg(args, "status"),
+        }
+        if filter.Assignee == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "assignee is required")
+        }
+        if filter.Status != "" && !isValidStatus(filter.Status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", filter.Status)
+        }
+        if err := applyDeletedFilter(&filter, args); err != nil {
+                return nil, err
+        }
+        labels, mcpErr := labelsFilterArg(args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+        filter.Labels = labels
+        tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        sortByPriority(tickets)
+        return TicketsResponse{Tickets: tickets}, nil
+}
+
+// ticketAssignees returns the distinct assignees of non-deleted tickets.
+func ticketAssignees(ctx context.Context) []string {
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                mcp.LogAll(ctx, mcp.LevelError, "Completion error: %v", err)
+                return nil
+        }
+        seen := make(map[string]bool)
+        var assignees []string
+        for _, t := range tickets {
+                if t.Assignee != "" && !seen[t.Assignee] {
+                        seen[t.Assignee] = true
+                        assignees = append(assignees, t.Assignee)
+                }
+        }
+        sort.Strings(assignees)
+        return assignees
+}
+
+var assigneeSchema = map[string]interface{}{
+        "type":        "string",
+        "description": "Person the ticket is assigned to",
+}
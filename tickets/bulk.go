@@ -0,0 +1,296 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+// bulkUpdateLimit caps how many tickets one bulk_update_tickets call may
+// touch, so an overly broad filter can't rewrite the whole store.
+const bulkUpdateLimit = 200
+
+// BulkResult reports the outcome of a bulk update for one ticket.
+type BulkResult struct {
+        ID     string  `json:"id"`
+        OK     bool    `json:"ok"`
+        Ticket *Ticket `json:"ticket,omitempty"`
+        Error  string  `json:"error,omitempty"`
+}
+
+// bulkChange is one per-ticket step of a bulk update, applied through the
+// same code path as the corresponding single-ticket tool. target holds the
+// ticket's id and expected version.
+type bulkChange func(ctx context.Context, target map[string]interface{}) (interface{}, error)
+
+func handleBulkUpdateTickets(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        changes, mcpErr := bulkChanges(args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+        ids, mcpErr := bulkTargets(ctx, args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+        versions, ok := args["versions"].(map[string]interface{})
+        if _, present := args["versions"]; present && !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "versions must be an object mapping ticket IDs to versions")
+        }
+
+        results := make([]BulkResult, 0, len(ids))
+        succeeded := 0
+        var firstErr *mcp.Error
+        var stopped error
+        for _, id := range ids {
+                if stopped = ctx.Err(); stopped != nil {
+                        // The client went away or the request ran out of
+                        // time; what was done so far is reported below.
+                        break
+                }
+                // Tickets without an expected version are updated from
+                // whatever version they are at; later steps expect the
+                // version the previous step produced.
+                version, ok := versions[id]
+                if !ok {
+                        version = currentVersion(ctx, id)
+                }
+                result := BulkResult{ID: id, OK: true}
+                for _, change := range changes {
+                        out, err := change(ctx, map[string]interface{}{"id": id, "version": version})
+                        if err != nil {
+                                result = BulkResult{ID: id, Error: err.Error()}
+                                if firstErr == nil {
+                                        firstErr = mcp.ToError(err)
+                                }
+                                break
+                        }
+                        if t, ok := out.(map[string]interface{})["ticket"].(Ticket); ok {
+                                result.Ticket = &t
+                                version = float64(t.Version)
+                        }
+                }
+                if result.OK {
+                        succeeded++
+                }
+                results = append(results, result)
+        }
+
+        summary := map[string]interface{}{
+                "results":   results,
+                "succeeded": succeeded,
+                "failed":    len(results) - succeeded,
+        }
+        if stopped != nil {
+                summary["stopped"] = stopped.Error()
+                summary["skipped"] = ids[len(results):]
+        }
+        // The call only fails when nothing was applied; otherwise the
+        // failures are in the per-ticket results.
+        switch {
+        case succeeded > 0:
+                return summary, nil
+        case stopped != nil:
+                mcpErr := mcp.ToError(mcp.Errorf(mcp.ErrInternal, "Stopped before updating any of %d tickets: %v", len(ids), stopped))
+                mcpErr.Data = summary
+                return nil, mcpErr
+        case firstErr != nil:
+                return nil, &mcp.Error{
+                        Code:    firstErr.Code,
+                        Message: fmt.Sprintf("None of the %d tickets were updated; the first failed with: %s", len(ids), firstErr.Message),
+                        Data:    summary,
+                }
+        }
+        return summary, nil
+}
+
+// bulkChanges validates the requested changes up front, so a malformed call
+// fails as a whole instead of once per ticket.
+func bulkChanges(args map[string]interface{}) ([]bulkChange, error) {
+        var changes []bulkChange
+
+        if status := mcp.StringArg(args, "status"); status != "" {
+                if !isValidStatus(status) {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", status)
+                }
+                reopen, _ := args["reopen"].(bool)
+                changes = append(changes, func(ctx context.Context, target map[string]interface{}) (interface{}, error) {
+                        target["status"], target["reopen"] = status, reopen
+                        return handleUpdateTicketStatus(ctx, target)
+                })
+        }
+        if raw, ok := args["assignee"]; ok {
+                assignee, isString := raw.(string)
+                if !isString {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "assignee must be a string")
+                }
+                assignee = strings.TrimSpace(assignee)
+                changes = append(changes, func(ctx context.Context, target map[string]interface{}) (interface{}, error) {
+                        return setTicketAssignee(ctx, target, assignee)
+                })
+        }
+        if _, ok := args["priority"]; ok {
+                priority, err := normalizePriority(mcp.StringArg(args, "priority"))
+                if err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+                }
+                changes = append(changes, func(ctx context.Context, target map[string]interface{}) (interface{}, error) {
+                        target["priority"] = priority
+                        return handleSetTicketPriority(ctx, target)
+                })
+        }
+        for _, name := range []string{"add_labels", "remove_labels"} {
+                labels, ok := mcp.StringsArg(args, name)
+                if !ok {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%s must be an array of strings", name)
+                }
+                if labels = normalizeLabels(labels); len(labels) == 0 {
+                        continue
+                }
+                add := name == "add_labels"
+                changes = append(changes, func(ctx context.Context, target map[string]interface{}) (interface{}, error) {
+                        target["labels"] = toInterfaces(labels)
+                        return changeLabels(ctx, target, add)
+                })
+        }
+
+        if len(changes) == 0 {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "At least one of status, assignee, priority, add_labels, or remove_labels is required")
+        }
+        return changes, nil
+}
+
+// bulkTargets resolves the tickets to update from either an explicit ids
+// list or a filter expression.
+func bulkTargets(ctx context.Context, args map[string]interface{}) ([]string, error) {
+        ids, ok := mcp.StringsArg(args, "ids")
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "ids must be an array of strings")
+        }
+        raw, hasFilter := args["filter"]
+        if (len(ids) > 0) == hasFilter {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Exactly one of ids or filter is required")
+        }
+
+        if !hasFilter {
+                if len(ids) > bulkUpdateLimit {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "At most %d tickets can be updated at once", bulkUpdateLimit)
+                }
+                return ids, nil
+        }
+
+        expr, isObject := raw.(map[string]interface{})
+        if !isObject {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "filter must be an object")
+        }
+        filter := TicketFilter{
+                Status:   mcp.StringArg(expr, "status"),
+                Query:    mcp.StringArg(expr, "query"),
+                Assignee: strings.TrimSpace(mcp.StringArg(expr, "assignee")),
+        }
+        if filter.Status != "" && !isValidStatus(filter.Status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", filter.Status)
+        }
+        var mcpErr error
+        if filter.Labels, mcpErr = labelsFilterArg(expr); mcpErr != nil {
+                return nil, mcpErr
+        }
+        priority, err := normalizePriority(mcp.StringArg(expr, "priority"))
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+        filter.Priority = priority
+
+        tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if len(tickets) > bulkUpdateLimit {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Filter matches %d tickets; at most %d can be updated at once", len(tickets), bulkUpdateLimit)
+        }
+        ids = make([]string, 0, len(tickets))
+        for _, t := range tickets {
+                ids = append(ids, t.ID)
+        }
+        return ids, nil
+}
+
+// currentVersion returns the ticket's version as a tool argument, or 0 if it
+// can't be read (the update then reports why).
+func currentVersion(ctx context.Context, id string) float64 {
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return 0
+        }
+        return float64(t.Version)
+}
+
+func toInterfaces(values []string) []interface{} {
+        items := make([]interface{}, len(values))
+        for i, v := range values {
+                items[i] = v
+        }
+        return items
+}
+
+var bulkUpdateSchema = map[string]interface{}{
+        "type": "object",
+        "properties": map[string]interface{}{
+                "ids": map[string]interface{}{
+                        "type":        "array",
+                        "items":       map[string]interface{}{"type": "string"},
+                        "description": "IDs of the tickets to update",
+                },
+                "versions": map[string]interface{}{
+                        "type":                 "object",
+                        "additionalProperties": map[string]interface{}{"type": "integer"},
+                        "description":          "Expected version per ticket ID; listed tickets that have changed since fail with a conflict, others are updated at their current version",
+                },
+                "filter": map[string]interface{}{
+                        "type":        "object",
+                        "description": "Update every non-deleted ticket matching these criteria instead of listing ids",
+                        "properties": map[string]interface{}{
+                                "query": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Text to match in the title or description",
+                                },
+                                "status": map[string]interface{}{
+                                        "type": "string",
+                                        "enum": ticketStatuses(),
+                                },
+                                "assignee": assigneeSchema,
+                                "priority": prioritySchema,
+                                "labels":   labelsFilterSchema,
+                        },
+                },
+                "status": map[string]interface{}{
+                        "type":        "string",
+                        "enum":        ticketStatuses(),
+                        "description": "New status, subject to the status transition rules",
+                },
+                "reopen": map[string]interface{}{
+                        "type":        "boolean",
+                        "description": "Allow moving done tickets back",
+                },
+                "assignee": map[string]interface{}{
+                        "type":        "string",
+                        "description": "New assignee; an empty string unassigns",
+                },
+                "priority": map[string]interface{}{
+                        "type":        "string",
+                        "enum":        append([]string{""}, ticketPriorities...),
+                        "description": "New priority; an empty string clears it",
+                },
+                "add_labels": map[string]interface{}{
+                        "type":        "array",
+                        "items":       map[string]interface{}{"type": "string"},
+                        "description": "Labels to add",
+                },
+                "remove_labels": map[string]interface{}{
+                        "type":        "array",
+                        "items":       map[string]interface{}{"type": "string"},
+                        "description": "Labels to remove",
+                },
+        },
+}
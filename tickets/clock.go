@@ -0,0 +1,44 @@
+package tickets
+
+import (
+        "context"
+        "slices"
+        "time"
+)
+
+// Clock tells the time. Stores and tools read "now" from one, so tests can
+// fix it and due dates and timestamps come out the same on every run.
+type Clock interface {
+        Now() time.Time
+}
+
+// ClockFunc lets an ordinary function serve as a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+        return f()
+}
+
+// SystemClock is the wall clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+type clockContextKey struct{}
+
+// ContextWithClock returns a copy of ctx carrying clock, which the tools
+// of connections whose context derives from it read the time from.
+func ContextWithClock(ctx context.Context, clock Clock) context.Context {
+        return context.WithValue(ctx, clockContextKey{}, clock)
+}
+
+// clockFrom returns the clock ctx carries, or SystemClock.
+func clockFrom(ctx context.Context) Clock {
+        if clock, ok := ctx.Value(clockContextKey{}).(Clock); ok {
+                return clock
+        }
+        return SystemClock
+}
+
+// DemoTickets returns the tickets the default in-memory store starts with.
+func DemoTickets() []Ticket {
+        return slices.Clone(demoTickets)
+}
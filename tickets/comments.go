@@ -0,0 +1,81 @@
+package tickets
+
+import (
+        "context"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+// defaultCommentAuthor is recorded when add_comment is called without an
+// author.
+const defaultCommentAuthor = "anonymous"
+
+func handleAddComment(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        ticketID := mcp.StringArg(args, "ticket_id")
+        body := strings.TrimSpace(mcp.StringArg(args, "body"))
+        if ticketID == "" || body == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "ticket_id and a non-empty body are required")
+        }
+        author := strings.TrimSpace(mcp.StringArg(args, "author"))
+        if author == "" {
+                author = defaultCommentAuthor
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, ticketID)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Cannot comment on a deleted ticket")
+        }
+
+        comment, err := storeFrom(ctx).AddComment(ctx, Comment{TicketID: ticketID, Author: author, Body: body})
+        if err != nil {
+                return nil, storeError(err)
+        }
+        mcp.LogAll(ctx, mcp.LevelInfo, "Added comment %s to ticket %s", comment.ID, ticketID)
+        return map[string]interface{}{"comment": comment}, nil
+}
+
+func handleListComments(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        ticketID := mcp.StringArg(args, "ticket_id")
+        if ticketID == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "ticket_id is required")
+        }
+        comments, err := storeFrom(ctx).ListComments(ctx, ticketID)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        return map[string]interface{}{"comments": comments}, nil
+}
+
+func handleDeleteComment(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        ticketID := mcp.StringArg(args, "ticket_id")
+        commentID := mcp.StringArg(args, "comment_id")
+        if ticketID == "" || commentID == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "ticket_id and comment_id are required")
+        }
+        if err := storeFrom(ctx).DeleteComment(ctx, ticketID, commentID); err != nil {
+                return nil, storeError(err)
+        }
+        mcp.LogAll(ctx, mcp.LevelInfo, "Deleted comment %s from ticket %s", commentID, ticketID)
+        return map[string]interface{}{"deleted": commentID}, nil
+}
+
+func commentToolSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+        props := map[string]interface{}{
+                "ticket_id": map[string]interface{}{
+                        "type":        "string",
+                        "description": "Ticket ID",
+                },
+        }
+        for name, schema := range properties {
+                props[name] = schema
+        }
+        return map[string]interface{}{
+                "type":       "object",
+                "properties": props,
+                "required":   append([]string{"ticket_id"}, required...),
+        }
+}
@@ -0,0 +1,119 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// loadLocation resolves the optional IANA "timezone" argument, defaulting
+// to UTC. Dates without a time of day are interpreted in this zone.
+func loadLocation(args map[string]interface{}) (*time.Location, error) {
+        name := mcp.StringArg(args, "timezone")
+        if name == "" {
+                return time.UTC, nil
+        }
+        loc, err := time.LoadLocation(name)
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Unknown timezone: %s", name)
+        }
+        return loc, nil
+}
+
+// parseDueDate accepts an RFC 3339 timestamp or a YYYY-MM-DD date. A date is
+// due by the end of that day in loc.
+func parseDueDate(value string, loc *time.Location) (time.Time, error) {
+        if t, err := time.Parse(time.RFC3339, value); err == nil {
+                return t.UTC(), nil
+        }
+        day, err := time.ParseInLocation("2006-01-02", value, loc)
+        if err != nil {
+                return time.Time{}, fmt.Errorf("due_date must be an RFC 3339 timestamp or YYYY-MM-DD date")
+        }
+        return endOfDay(day, loc).UTC(), nil
+}
+
+// endOfDay returns the last second of t's calendar day in loc.
+func endOfDay(t time.Time, loc *time.Location) time.Time {
+        y, m, d := t.In(loc).Date()
+        return time.Date(y, m, d, 23, 59, 59, 0, loc)
+}
+
+// dueWithinBound returns the cut-off for "due within days days": the end of
+// the day that is days days from now in loc.
+func dueWithinBound(now time.Time, days int, loc *time.Location) time.Time {
+        return endOfDay(now.In(loc).AddDate(0, 0, days), loc)
+}
+
+func handleSetDueDate(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        loc, mcpErr := loadLocation(args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+
+        var due time.Time
+        if value := mcp.StringArg(args, "due_date"); value != "" {
+                var err error
+                if due, err = parseDueDate(value, loc); err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+                }
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is deleted; restore it first", id)
+        }
+
+        t.DueDate = due
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if due.IsZero() {
+                ticketUpdated(ctx, updated, "due date cleared")
+        } else {
+                ticketUpdated(ctx, updated, "due %s", due.Format(time.RFC3339))
+        }
+        return map[string]interface{}{"ticket": updated}, nil
+}
+
+// handleOverdueTickets lists open tickets whose due date has passed.
+func handleOverdueTickets(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        loc, mcpErr := loadLocation(args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+        now := clockFrom(ctx).Now()
+        filter := TicketFilter{
+                Assignee:  mcp.StringArg(args, "assignee"),
+                DueBefore: now,
+                OpenOnly:  true,
+        }
+        tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        sortTickets(tickets, "due", false)
+        sortByPriority(tickets)
+        return map[string]interface{}{
+                "tickets": tickets,
+                "asOf":    now.In(loc).Format(time.RFC3339),
+        }, nil
+}
+
+var timezoneSchema = map[string]interface{}{
+        "type":        "string",
+        "description": "IANA time zone for interpreting dates, e.g. Europe/Paris (default UTC)",
+}
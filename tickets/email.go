@@ -0,0 +1,194 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "mime"
+        "net"
+        "net/mail"
+        "net/smtp"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// emailNotifier emails assignees when tickets are assigned to them and
+// reminds them of their overdue tickets. Assignments are batched per
+// recipient: the first one starts a window, and everything assigned to that
+// person within it goes out as one email, so a bulk update sends one
+// message per assignee rather than one per ticket.
+type emailNotifier struct {
+        addr string
+        host string
+        // from is the From header; sender is its bare address.
+        from     string
+        sender   string
+        domain   string
+        username mcp.SecretRef
+        password mcp.SecretRef
+        window   time.Duration
+
+        mu      sync.Mutex
+        pending map[string][]Ticket // recipient to newly assigned tickets
+}
+
+// NewEmailNotifier sends through the SMTP server at addr (host:port),
+// authenticating with username and password if username is set. Assignees
+// that aren't email addresses are mailed at domain.
+func NewEmailNotifier(addr, from, domain string, username, password mcp.SecretRef, window time.Duration) (*emailNotifier, error) {
+        host, _, err := net.SplitHostPort(addr)
+        if err != nil {
+                return nil, fmt.Errorf("SMTP address must be host:port: %w", err)
+        }
+        sender, err := mail.ParseAddress(from)
+        if err != nil {
+                return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+        }
+        n := &emailNotifier{
+                addr:     addr,
+                host:     host,
+                username: username,
+                password: password,
+                from:     sender.String(),
+                sender:   sender.Address,
+                domain:   strings.TrimPrefix(domain, "@"),
+                window:   window,
+                pending:  make(map[string][]Ticket),
+        }
+        return n, nil
+}
+
+// recipient returns the email address of an assignee, or "" if it has none.
+func (n *emailNotifier) recipient(assignee string) string {
+        switch {
+        case strings.Contains(assignee, "@"):
+                return assignee
+        case assignee != "" && n.domain != "":
+                return assignee + "@" + n.domain
+        }
+        return ""
+}
+
+// Notify is an event log subscriber.
+func (n *emailNotifier) Notify(e TicketEvent) {
+        if e.Ticket == nil || e.Ticket.Assignee == "" || e.Ticket.Deleted {
+                return
+        }
+        if e.Type != eventCreated && (e.Previous == nil || e.Previous.Assignee == e.Ticket.Assignee) {
+                return
+        }
+        to := n.recipient(e.Ticket.Assignee)
+        if to == "" {
+                mcp.LogAll(context.Background(), mcp.LevelWarning, "No email address for assignee %s; set -email-domain", e.Ticket.Assignee)
+                return
+        }
+
+        n.mu.Lock()
+        defer n.mu.Unlock()
+        if len(n.pending[to]) == 0 {
+                time.AfterFunc(n.window, func() { n.flushAssignments(to) })
+        }
+        n.pending[to] = append(n.pending[to], *e.Ticket)
+}
+
+// flushAssignments sends the assignments batched for one recipient. A
+// ticket assigned several times in the window is listed once.
+func (n *emailNotifier) flushAssignments(to string) {
+        n.mu.Lock()
+        batch := n.pending[to]
+        delete(n.pending, to)
+        n.mu.Unlock()
+
+        latest := make(map[string]Ticket, len(batch))
+        for _, t := range batch {
+                latest[t.ID] = t
+        }
+        tickets := make([]Ticket, 0, len(latest))
+        for _, t := range latest {
+                if n.recipient(t.Assignee) == to {
+                        tickets = append(tickets, t)
+                }
+        }
+        if len(tickets) == 0 {
+                return
+        }
+        sortByPriority(tickets)
+
+        subject := fmt.Sprintf("Ticket %s assigned to you: %s", tickets[0].ID, tickets[0].Title)
+        if len(tickets) > 1 {
+                subject = fmt.Sprintf("%d tickets assigned to you", len(tickets))
+        }
+        body := "The following tickets were assigned to you:\n\n" + ticketLines(tickets)
+        if err := n.send(to, subject, body); err != nil {
+                mcp.LogAll(context.Background(), mcp.LevelError, "Emailing %s: %v", to, err)
+        }
+}
+
+// remindOverdue emails every assignee the list of their open tickets in
+// store that are past due.
+func (n *emailNotifier) remindOverdue(store TicketStore) {
+        ctx := context.Background()
+        tickets, err := store.ListTickets(ctx, TicketFilter{DueBefore: time.Now(), OpenOnly: true})
+        if err != nil {
+                mcp.LogAll(ctx, mcp.LevelError, "Listing overdue tickets: %v", err)
+                return
+        }
+        byRecipient := make(map[string][]Ticket)
+        for _, t := range tickets {
+                if to := n.recipient(t.Assignee); to != "" {
+                        byRecipient[to] = append(byRecipient[to], t)
+                }
+        }
+        for to, tickets := range byRecipient {
+                sortTickets(tickets, "due", false)
+                sortByPriority(tickets)
+                subject := fmt.Sprintf("%d overdue tickets", len(tickets))
+                if len(tickets) == 1 {
+                        subject = fmt.Sprintf("Ticket %s is overdue: %s", tickets[0].ID, tickets[0].Title)
+                }
+                body := "These tickets assigned to you are past their due date:\n\n" + ticketLines(tickets)
+                if err := n.send(to, subject, body); err != nil {
+                        mcp.LogAll(ctx, mcp.LevelError, "Emailing %s: %v", to, err)
+                }
+        }
+}
+
+// RemindEvery sends reminders of the overdue tickets in store every
+// interval, from the leader only when several replicas run.
+func (n *emailNotifier) RemindEvery(store TicketStore, interval time.Duration) {
+        mcp.RunScheduled("overdue-reminders", interval, func() { n.remindOverdue(store) })
+}
+
+func ticketLines(tickets []Ticket) string {
+        var b strings.Builder
+        for _, t := range tickets {
+                fmt.Fprintf(&b, "- %s: %s [%s", t.ID, t.Title, t.Status)
+                if t.Priority != "" {
+                        fmt.Fprintf(&b, ", %s", t.Priority)
+                }
+                if !t.DueDate.IsZero() {
+                        fmt.Fprintf(&b, ", due %s", t.DueDate.Format(time.DateOnly))
+                }
+                b.WriteString("]\n")
+        }
+        return b.String()
+}
+
+func (n *emailNotifier) send(to, subject, body string) error {
+        header := []string{
+                "From: " + n.from,
+                "To: " + to,
+                "Subject: " + mime.QEncoding.Encode("utf-8", subject),
+                "Date: " + time.Now().Format(time.RFC1123Z),
+                "MIME-Version: 1.0",
+                "Content-Type: text/plain; charset=utf-8",
+        }
+        msg := strings.Join(header, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+        var auth smtp.Auth
+        if username := n.username.Value(); username != "" {
+                auth = smtp.PlainAuth("", username, n.password.Value(), n.host)
+        }
+        return smtp.SendMail(n.addr, auth, n.sender, []string{to}, []byte(msg))
+}
@@ -0,0 +1,482 @@
+package tickets
+
+import (
+        "bufio"
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "os"
+        "slices"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// Ticket event types. Updates are classified by the field that changed;
+// an update touching several fields is recorded as eventUpdated.
+const (
+        eventCreated         = "created"
+        eventUpdated         = "updated"
+        eventStatusChanged   = "status_changed"
+        eventAssigned        = "assigned"
+        eventUnassigned      = "unassigned"
+        eventPriorityChanged = "priority_changed"
+        eventLabelsChanged   = "labels_changed"
+        eventDueDateChanged  = "due_date_changed"
+        eventDeleted         = "deleted"
+        eventRestored        = "restored"
+        eventCommented       = "commented"
+        eventCommentDeleted  = "comment_deleted"
+)
+
+// TicketEvent is one entry in the append-only ticket change log. Ticket
+// events carry the full ticket after the change and, for updates, the
+// ticket before it, so state can be rebuilt and changes undone from the log
+// alone.
+type TicketEvent struct {
+        Seq      int64     `json:"seq"`
+        Type     string    `json:"type"`
+        TicketID string    `json:"ticketId"`
+        At       time.Time `json:"at"`
+        Changes  []string  `json:"changes,omitempty"`
+        Ticket   *Ticket   `json:"ticket,omitempty"`
+        Previous *Ticket   `json:"previous,omitempty"`
+        Comment  *Comment  `json:"comment,omitempty"`
+        // Undoes is the sequence number of the event this one reverted.
+        Undoes int64 `json:"undoes,omitempty"`
+}
+
+var errNothingToUndo = errors.New("nothing to undo")
+
+// RetainedEvents is how many of the latest events a new event log keeps
+// in memory for get_ticket_history and undo_ticket_change, set by
+// -retained-events; older ones are dropped, though an -event-log file
+// keeps them all. Zero keeps every event.
+var RetainedEvents = 10000
+
+// eventLog holds ticket events in memory, optionally appending each one to
+// a JSON Lines file, and fans them out to subscribers.
+type eventLog struct {
+        clock Clock
+        // retain is the log's own RetainedEvents.
+        retain      int
+        mu          sync.Mutex
+        events      []TicketEvent
+        file        *os.File
+        subscribers []func(TicketEvent)
+}
+
+// NewEventLog returns an event log kept in memory only, which stamps
+// events with the time clock tells.
+func NewEventLog(clock Clock) *eventLog {
+        return &eventLog{clock: clock, retain: RetainedEvents}
+}
+
+// OpenEventLog reads the events already in path and appends new events,
+// stamped with the time clock tells, to it.
+func OpenEventLog(path string, clock Clock) (*eventLog, error) {
+        f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+        if err != nil {
+                return nil, err
+        }
+        l := &eventLog{file: f, clock: clock, retain: RetainedEvents}
+        scanner := bufio.NewScanner(f)
+        scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+        for line := 1; scanner.Scan(); line++ {
+                if len(strings.TrimSpace(scanner.Text())) == 0 {
+                        continue
+                }
+                var e TicketEvent
+                if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+                        f.Close()
+                        return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+                }
+                l.events = append(l.events, e)
+        }
+        if err := scanner.Err(); err != nil {
+                f.Close()
+                return nil, err
+        }
+        return l, nil
+}
+
+// SetRetained makes the log keep the latest n events in memory, rather
+// than RetainedEvents; zero keeps every event. Events already beyond n are
+// dropped the next time one is recorded.
+func (l *eventLog) SetRetained(n int) {
+        l.mu.Lock()
+        l.retain = n
+        l.mu.Unlock()
+}
+
+// Subscribe registers fn to be called with every event recorded from now
+// on, in order. fn runs on the goroutine that made the change.
+func (l *eventLog) Subscribe(fn func(TicketEvent)) {
+        l.mu.Lock()
+        l.subscribers = append(l.subscribers, fn)
+        l.mu.Unlock()
+}
+
+func (l *eventLog) record(e TicketEvent) TicketEvent {
+        l.mu.Lock()
+        e.Seq = 1
+        if n := len(l.events); n > 0 {
+                e.Seq = l.events[n-1].Seq + 1
+        }
+        e.At = l.clock.Now().UTC()
+        l.events = append(l.events, e)
+        l.trim()
+        if l.file != nil {
+                data, err := json.Marshal(e)
+                if err == nil {
+                        _, err = l.file.Write(append(data, '\n'))
+                }
+                if err != nil {
+                        mcp.LogAll(context.Background(), mcp.LevelError, "Appending to event log: %v", err)
+                }
+        }
+        subscribers := slices.Clone(l.subscribers)
+        l.mu.Unlock()
+
+        for _, fn := range subscribers {
+                fn(e)
+        }
+        return e
+}
+
+// history returns a ticket's events, oldest first.
+func (l *eventLog) history(ticketID string) []TicketEvent {
+        l.mu.Lock()
+        defer l.mu.Unlock()
+        events := []TicketEvent{}
+        for _, e := range l.events {
+                if e.TicketID == ticketID {
+                        events = append(events, e)
+                }
+        }
+        return events
+}
+
+// lastUndoable returns the ticket's most recent event that can be undone
+// and hasn't been, so repeated undos step further back in its history.
+// Deleted comments can't be brought back, so their deletion is passed
+// over, as is the adding of a comment deleted since.
+func (l *eventLog) lastUndoable(ticketID string) (TicketEvent, bool) {
+        l.mu.Lock()
+        defer l.mu.Unlock()
+        undone := make(map[int64]bool)
+        deletedComments := make(map[string]bool)
+        for i := len(l.events) - 1; i >= 0; i-- {
+                e := l.events[i]
+                if e.TicketID != ticketID {
+                        continue
+                }
+                if e.Undoes != 0 {
+                        undone[e.Undoes] = true
+                        continue
+                }
+                switch {
+                case undone[e.Seq]:
+                case e.Type == eventCommentDeleted:
+                        if e.Comment != nil {
+                                deletedComments[e.Comment.ID] = true
+                        }
+                case e.Type == eventCommented:
+                        if e.Comment != nil && !deletedComments[e.Comment.ID] {
+                                return e, true
+                        }
+                case e.Previous != nil:
+                        return e, true
+                default:
+                        // The ticket's creation, before which there is
+                        // nothing to undo.
+                        return TicketEvent{}, false
+                }
+        }
+        return TicketEvent{}, false
+}
+
+// trim drops the oldest events beyond those the log retains. The slice's
+// backing array is replaced, freeing the dropped events, the next time
+// append grows it.
+func (l *eventLog) trim() {
+        if n := len(l.events) - l.retain; l.retain > 0 && n > 0 {
+                clear(l.events[:n])
+                l.events = l.events[n:]
+        }
+}
+
+func (l *eventLog) Close() error {
+        if l.file == nil {
+                return nil
+        }
+        return l.file.Close()
+}
+
+// Replay rebuilds s from the log, replacing whatever it held, and then
+// drops the events beyond those it retains. A new log instead records the
+// tickets s was seeded with, so that replaying it later reproduces them.
+func (l *eventLog) Replay(s *MemoryStore) {
+        l.mu.Lock()
+        events := slices.Clone(l.events)
+        l.trim()
+        l.mu.Unlock()
+        if len(events) == 0 {
+                for _, t := range s.snapshot().Tickets {
+                        l.record(TicketEvent{Type: eventCreated, TicketID: t.ID, Ticket: &t})
+                }
+                return
+        }
+        s.restore(memorySnapshot{})
+        for _, e := range events {
+                s.apply(e)
+        }
+}
+
+// apply makes the change an event records.
+func (s *MemoryStore) apply(e TicketEvent) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        switch {
+        case e.Type == eventCommented && e.Comment != nil:
+                s.comments[e.TicketID] = append(s.comments[e.TicketID], *e.Comment)
+                if n, err := strconv.Atoi(strings.TrimPrefix(e.Comment.ID, "C")); err == nil && n >= s.nextCommentID {
+                        s.nextCommentID = n + 1
+                }
+        case e.Type == eventCommentDeleted && e.Comment != nil:
+                s.comments[e.TicketID] = slices.DeleteFunc(s.comments[e.TicketID], func(c Comment) bool {
+                        return c.ID == e.Comment.ID
+                })
+        case e.Ticket != nil:
+                for i := range s.tickets {
+                        if s.tickets[i].ID == e.Ticket.ID {
+                                s.tickets[i] = *e.Ticket
+                                return
+                        }
+                }
+                s.tickets = append(s.tickets, *e.Ticket)
+                if n := ticketNumber(e.Ticket.ID); n >= s.nextID {
+                        s.nextID = n + 1
+                }
+        }
+}
+
+// eventStore records every mutation made through it in an eventLog before
+// returning.
+type eventStore struct {
+        TicketStore
+        log *eventLog
+        // mu is held across each change and the recording of its event, so
+        // events are logged in the order the changes were made and each
+        // update's Previous is the ticket it replaced.
+        mu sync.Mutex
+}
+
+func NewEventStore(inner TicketStore, log *eventLog) *eventStore {
+        return &eventStore{TicketStore: inner, log: log}
+}
+
+// classifyUpdate names the event for a change from prev to next and lists
+// the fields that changed.
+func classifyUpdate(prev, next Ticket) (string, []string) {
+        var changes []string
+        var kind string
+        changed := func(field, eventType string) {
+                changes = append(changes, field)
+                kind = eventType
+        }
+        if prev.Title != next.Title {
+                changed("title", eventUpdated)
+        }
+        if prev.Description != next.Description {
+                changed("description", eventUpdated)
+        }
+        if prev.Status != next.Status {
+                changed("status", eventStatusChanged)
+        }
+        if prev.Assignee != next.Assignee {
+                if next.Assignee == "" {
+                        changed("assignee", eventUnassigned)
+                } else {
+                        changed("assignee", eventAssigned)
+                }
+        }
+        if prev.Priority != next.Priority {
+                changed("priority", eventPriorityChanged)
+        }
+        if !slices.Equal(prev.Labels, next.Labels) {
+                changed("labels", eventLabelsChanged)
+        }
+        if !prev.DueDate.Equal(next.DueDate) {
+                changed("dueDate", eventDueDateChanged)
+        }
+        if prev.Deleted != next.Deleted {
+                if next.Deleted {
+                        changed("deleted", eventDeleted)
+                } else {
+                        changed("deleted", eventRestored)
+                }
+        }
+        if len(changes) != 1 {
+                kind = eventUpdated
+        }
+        return kind, changes
+}
+
+func (s *eventStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        created, err := s.TicketStore.CreateTicket(ctx, t)
+        if err != nil {
+                return created, err
+        }
+        s.log.record(TicketEvent{Type: eventCreated, TicketID: created.ID, Ticket: &created})
+        return created, nil
+}
+
+func (s *eventStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        return s.update(ctx, t, 0)
+}
+
+// update changes t and records it; s.mu must be held.
+func (s *eventStore) update(ctx context.Context, t Ticket, undoes int64) (Ticket, error) {
+        prev, err := s.TicketStore.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        updated, err := s.TicketStore.UpdateTicket(ctx, t)
+        if err != nil {
+                return updated, err
+        }
+        kind, changes := classifyUpdate(prev, updated)
+        s.log.record(TicketEvent{
+                Type:     kind,
+                TicketID: updated.ID,
+                Changes:  changes,
+                Ticket:   &updated,
+                Previous: &prev,
+                Undoes:   undoes,
+        })
+        return updated, nil
+}
+
+func (s *eventStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        added, err := s.TicketStore.AddComment(ctx, c)
+        if err != nil {
+                return added, err
+        }
+        s.log.record(TicketEvent{Type: eventCommented, TicketID: added.TicketID, Comment: &added})
+        return added, nil
+}
+
+func (s *eventStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        return s.deleteComment(ctx, ticketID, commentID, 0)
+}
+
+// deleteComment removes a comment and records it; s.mu must be held.
+func (s *eventStore) deleteComment(ctx context.Context, ticketID, commentID string, undoes int64) error {
+        comments, err := s.TicketStore.ListComments(ctx, ticketID)
+        if err != nil {
+                return err
+        }
+        i := slices.IndexFunc(comments, func(c Comment) bool { return c.ID == commentID })
+        if i < 0 {
+                return ErrCommentNotFound
+        }
+        if err := s.TicketStore.DeleteComment(ctx, ticketID, commentID); err != nil {
+                return err
+        }
+        s.log.record(TicketEvent{Type: eventCommentDeleted, TicketID: ticketID, Comment: &comments[i], Undoes: undoes})
+        return nil
+}
+
+// undo reverts the ticket's most recent change that hasn't been undone: an
+// update is rolled back to the previous ticket and a comment is removed.
+func (s *eventStore) undo(ctx context.Context, ticketID string) (TicketEvent, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        e, ok := s.log.lastUndoable(ticketID)
+        if !ok {
+                return TicketEvent{}, errNothingToUndo
+        }
+        if e.Type == eventCommented {
+                return e, s.deleteComment(ctx, ticketID, e.Comment.ID, e.Seq)
+        }
+        current, err := s.TicketStore.GetTicket(ctx, ticketID)
+        if err != nil {
+                return e, err
+        }
+        prev := *e.Previous
+        prev.Version = current.Version
+        _, err = s.update(ctx, prev, e.Seq)
+        return e, err
+}
+
+// Snapshot and Close pass through to the wrapped store, so wrapping doesn't
+// hide its optional capabilities.
+func (s *eventStore) Snapshot() (string, error) {
+        snap, ok := s.TicketStore.(snapshotter)
+        if !ok {
+                return "", errSnapshotsUnsupported
+        }
+        return snap.Snapshot()
+}
+
+func (s *eventStore) Close() error {
+        err := s.log.Close()
+        if closer, ok := s.TicketStore.(io.Closer); ok {
+                err = errors.Join(closer.Close(), err)
+        }
+        return err
+}
+
+func handleTicketHistory(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        events, ok := storeFrom(ctx).(*eventStore)
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInternal, "The ticket store does not record changes")
+        }
+        if _, err := events.GetTicket(ctx, id); err != nil {
+                return nil, storeError(err)
+        }
+        return map[string]interface{}{"events": events.log.history(id)}, nil
+}
+
+func handleUndoTicketChange(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        events, ok := storeFrom(ctx).(*eventStore)
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInternal, "The ticket store does not record changes")
+        }
+        undone, err := events.undo(ctx, id)
+        if err == errNothingToUndo {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s has no change to undo", id)
+        }
+        if err != nil {
+                return nil, storeError(err)
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        ticketUpdated(ctx, t, "undid %s (event %d)", undone.Type, undone.Seq)
+        return map[string]interface{}{"undone": undone, "ticket": t}, nil
+}
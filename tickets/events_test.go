@@ -0,0 +1,119 @@
+package tickets
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "reflect"
+        "sync"
+        "testing"
+        "time"
+)
+
+func TestEventLogKeepsRetainedEvents(t *testing.T) {
+        l := NewEventLog(ClockFunc(func() time.Time { return time.Unix(0, 0) }))
+        l.SetRetained(3)
+        for i := 0; i < 10; i++ {
+                l.record(TicketEvent{Type: eventCommented, TicketID: "1"})
+        }
+        history := l.history("1")
+        if len(history) != 3 || history[0].Seq != 8 || history[2].Seq != 10 {
+                t.Fatalf("got %d events, from %d, want 3 from 8", len(history), history[0].Seq)
+        }
+        if e := l.record(TicketEvent{Type: eventCommented, TicketID: "1"}); e.Seq != 11 {
+                t.Errorf("next event got seq %d, want 11", e.Seq)
+        }
+}
+
+func TestConcurrentUpdatesReplayToLiveStore(t *testing.T) {
+        ctx := context.Background()
+        clock := ClockFunc(func() time.Time { return time.Unix(0, 0) })
+        live := NewMemoryStore([]Ticket{{ID: "T1", Title: "Racy", Status: "todo"}}, clock)
+        log := NewEventLog(clock)
+        log.Replay(live)
+        store := NewEventStore(yieldingStore{live}, log)
+
+        var wg sync.WaitGroup
+        for i := 0; i < 8; i++ {
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        for n := 0; n < 50; n++ {
+                                for {
+                                        ticket, err := store.GetTicket(ctx, "T1")
+                                        if err != nil {
+                                                t.Error(err)
+                                                return
+                                        }
+                                        ticket.Title = fmt.Sprintf("Writer %d, update %d", i, n)
+                                        if _, err := store.UpdateTicket(ctx, ticket); err == nil {
+                                                break
+                                        } else if !errors.Is(err, ErrVersionConflict) {
+                                                t.Error(err)
+                                                return
+                                        }
+                                }
+                        }
+                }()
+        }
+        wg.Wait()
+
+        // Each event's Previous is the ticket the event before it left.
+        history := log.history("T1")
+        for i := 1; i < len(history); i++ {
+                if !reflect.DeepEqual(history[i].Previous, history[i-1].Ticket) {
+                        t.Fatalf("event %d: previous is version %d, want %d", history[i].Seq, history[i].Previous.Version, history[i-1].Ticket.Version)
+                }
+        }
+        replayed := NewMemoryStore(nil, clock)
+        log.Replay(replayed)
+        want, _ := live.GetTicket(ctx, "T1")
+        got, _ := replayed.GetTicket(ctx, "T1")
+        if !reflect.DeepEqual(got, want) {
+                t.Errorf("replayed %+v, want %+v", got, want)
+        }
+}
+
+// yieldingStore lets other goroutines run between an update's commit and
+// its return, where a change's event could otherwise be overtaken.
+type yieldingStore struct{ TicketStore }
+
+func (s yieldingStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        updated, err := s.TicketStore.UpdateTicket(ctx, t)
+        time.Sleep(10 * time.Microsecond)
+        return updated, err
+}
+
+func TestUndoSkipsDeletedComment(t *testing.T) {
+        ctx := context.Background()
+        clock := ClockFunc(func() time.Time { return time.Unix(0, 0) })
+        log := NewEventLog(clock)
+        store := NewEventStore(NewMemoryStore([]Ticket{{ID: "T1", Title: "Undo", Status: "todo"}}, clock), log)
+
+        ticket, _ := store.GetTicket(ctx, "T1")
+        ticket.Status = "in_progress"
+        if _, err := store.UpdateTicket(ctx, ticket); err != nil {
+                t.Fatal(err)
+        }
+        c, err := store.AddComment(ctx, Comment{TicketID: "T1", Author: "a", Body: "oops"})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if err := store.DeleteComment(ctx, "T1", c.ID); err != nil {
+                t.Fatal(err)
+        }
+
+        e, err := store.undo(ctx, "T1")
+        if err != nil {
+                t.Fatal(err)
+        }
+        if e.Type != eventStatusChanged {
+                t.Errorf("undid a %s event, want %s", e.Type, eventStatusChanged)
+        }
+        if ticket, _ := store.GetTicket(ctx, "T1"); ticket.Status != "todo" {
+                t.Errorf("status is %q after undo, want todo", ticket.Status)
+        }
+        if _, err := store.undo(ctx, "T1"); !errors.Is(err, errNothingToUndo) {
+                t.Errorf("second undo got %v, want %v", err, errNothingToUndo)
+        }
+}
@@ -0,0 +1,82 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "testing"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// fuzzedTools are the tools whose arguments FuzzToolArguments varies; they
+// touch nothing outside the ticket store and the bounded list of exports.
+// Without a connection, import_tickets refuses a uri rather than read it.
+var fuzzedTools = map[string]mcp.ToolHandler{
+        "create_ticket":        handleCreateTicket,
+        "update_ticket_status": handleUpdateTicketStatus,
+        "delete_ticket":        handleDeleteTicket,
+        "restore_ticket":       handleRestoreTicket,
+        "search_tickets":       handleSearchTickets,
+        "add_comment":          handleAddComment,
+        "list_comments":        handleListComments,
+        "delete_comment":       handleDeleteComment,
+        "assign_ticket":        handleAssignTicket,
+        "unassign_ticket":      handleUnassignTicket,
+        "set_due_date":         handleSetDueDate,
+        "get_overdue_tickets":  handleOverdueTickets,
+        "add_labels":           handleAddLabels,
+        "remove_labels":        handleRemoveLabels,
+        "set_ticket_priority":  handleSetTicketPriority,
+        "bulk_update_tickets":  handleBulkUpdateTickets,
+        "get_ticket_history":   handleTicketHistory,
+        "undo_ticket_change":   handleUndoTicketChange,
+        "import_tickets":       handleImportTickets,
+        "export_tickets":       handleExportTickets,
+}
+
+// FuzzToolArguments calls ticket tools with arbitrary arguments. Each must
+// return a result that encodes as JSON or an error, never panic.
+func FuzzToolArguments(f *testing.F) {
+        for _, seed := range []struct{ tool, args string }{
+                {"create_ticket", `{"title":"Broken build","labels":["ci"],"priority":"high"}`},
+                {"update_ticket_status", `{"id":"1","status":"done","expected_version":1}`},
+                {"search_tickets", `{"query":"build","status":"todo","limit":5}`},
+                {"add_comment", `{"id":"1","text":"seen"}`},
+                {"assign_ticket", `{"id":"2","assignee":"ana@example.com"}`},
+                {"set_due_date", `{"id":"1","due_date":"2025-02-30"}`},
+                {"add_labels", `{"id":"1","labels":"not a list"}`},
+                {"bulk_update_tickets", `{"ids":["1","2",3],"status":"done"}`},
+                {"undo_ticket_change", `{"id":"1","version":-1}`},
+                {"get_ticket_history", `{"id":null}`},
+                {"import_tickets", `{"content":"title,priority,labels\nBroken build,P1,\"ci,build\"\n","dry_run":true}`},
+                {"import_tickets", `{"content_base64":"UEsDBA==","format":"xlsx"}`},
+                {"export_tickets", `{"status":"todo","labels":["ci"],"priority":"P2"}`},
+        } {
+                f.Add(seed.tool, []byte(seed.args))
+        }
+        now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+        clock := ClockFunc(func() time.Time { return now })
+        f.Fuzz(func(t *testing.T, tool string, data []byte) {
+                handler, ok := fuzzedTools[tool]
+                if !ok {
+                        return
+                }
+                var args map[string]interface{}
+                if json.Unmarshal(data, &args) != nil || args == nil {
+                        return
+                }
+                store := NewEventStore(NewMemoryStore(DemoTickets(), clock), NewEventLog(clock))
+                ctx := ContextWithClock(ContextWithStore(context.Background(), store), clock)
+                result, err := handler(ctx, args)
+                if err != nil {
+                        if mcp.ToError(err).Message == "" {
+                                t.Errorf("%s(%s): error without a message", tool, data)
+                        }
+                        return
+                }
+                if _, err := json.Marshal(result); err != nil {
+                        t.Errorf("%s(%s): encoding result: %v", tool, data, err)
+                }
+        })
+}
@@ -0,0 +1,318 @@
+package tickets
+
+import (
+        "bytes"
+        "context"
+        "encoding/base64"
+        "encoding/csv"
+        "fmt"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// exportURIPrefix is the scheme of CSV export resources. The live export of
+// every ticket is exportAllURI; export_tickets snapshots are numbered.
+const (
+        exportURIPrefix = "export://"
+        exportAllURI    = exportURIPrefix + "tickets.csv"
+)
+
+// maxExports is how many export_tickets snapshots are kept readable; older
+// ones are dropped.
+const maxExports = 20
+
+// maxImportSize and maxImportRows bound what one import_tickets call reads.
+const (
+        maxImportSize = 5 << 20
+        maxImportRows = 1000
+)
+
+// ticketCSVColumns are the columns export_tickets writes and import_tickets
+// reads. Labels are separated by semicolons.
+var ticketCSVColumns = []string{"id", "title", "status", "priority", "assignee", "labels", "due_date", "created_at", "description"}
+
+// exports holds the CSV snapshots made by export_tickets, oldest first.
+var exports = struct {
+        sync.Mutex
+        next int
+        list []mcp.ResourceContents
+}{}
+
+func ticketsCSV(tickets []Ticket) (string, error) {
+        var buf bytes.Buffer
+        w := csv.NewWriter(&buf)
+        w.Write(ticketCSVColumns)
+        for _, t := range tickets {
+                var due, created string
+                if !t.DueDate.IsZero() {
+                        due = t.DueDate.Format(time.RFC3339)
+                }
+                if !t.CreatedAt.IsZero() {
+                        created = t.CreatedAt.Format(time.RFC3339)
+                }
+                w.Write([]string{t.ID, t.Title, t.Status, t.Priority, t.Assignee, strings.Join(t.Labels, ";"), due, created, t.Description})
+        }
+        w.Flush()
+        return buf.String(), w.Error()
+}
+
+// readExportResource serves the live export and export_tickets snapshots.
+func readExportResource(ctx context.Context, uri string) (mcp.ResourceContents, error) {
+        if uri == exportAllURI {
+                tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+                if err != nil {
+                        return mcp.ResourceContents{}, err
+                }
+                sortTickets(tickets, "id", false)
+                text, err := ticketsCSV(tickets)
+                if err != nil {
+                        return mcp.ResourceContents{}, err
+                }
+                return mcp.ResourceContents{URI: uri, MimeType: "text/csv", Text: text}, nil
+        }
+        exports.Lock()
+        defer exports.Unlock()
+        for _, e := range exports.list {
+                if e.URI == uri {
+                        return e, nil
+                }
+        }
+        return mcp.ResourceContents{}, fmt.Errorf("Resource not found: %s", uri)
+}
+
+func handleExportTickets(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        filter := TicketFilter{
+                Status:   mcp.StringArg(args, "status"),
+                Query:    mcp.StringArg(args, "query"),
+                Assignee: strings.TrimSpace(mcp.StringArg(args, "assignee")),
+        }
+        if filter.Status != "" && !isValidStatus(filter.Status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", filter.Status)
+        }
+        if err := applyDeletedFilter(&filter, args); err != nil {
+                return nil, err
+        }
+        var mcpErr error
+        if filter.Labels, mcpErr = labelsFilterArg(args); mcpErr != nil {
+                return nil, mcpErr
+        }
+        priority, err := normalizePriority(mcp.StringArg(args, "priority"))
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+        filter.Priority = priority
+
+        tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        sortTickets(tickets, "id", false)
+        sortByPriority(tickets)
+        text, err := ticketsCSV(tickets)
+        if err != nil {
+                return nil, err
+        }
+
+        exports.Lock()
+        exports.next++
+        contents := mcp.ResourceContents{
+                URI:      fmt.Sprintf("%stickets-%d.csv", exportURIPrefix, exports.next),
+                MimeType: "text/csv",
+                Text:     text,
+        }
+        exports.list = append(exports.list, contents)
+        if len(exports.list) > maxExports {
+                exports.list = exports.list[len(exports.list)-maxExports:]
+        }
+        exports.Unlock()
+
+        return map[string]interface{}{
+                "uri":      contents.URI,
+                "mimeType": contents.MimeType,
+                "rows":     len(tickets),
+        }, nil
+}
+
+// ImportResult reports what import_tickets did, or would do, with one row.
+// Row numbers count the header as row 1.
+type ImportResult struct {
+        Row    int     `json:"row"`
+        OK     bool    `json:"ok"`
+        Ticket *Ticket `json:"ticket,omitempty"`
+        Error  string  `json:"error,omitempty"`
+}
+
+func handleImportTickets(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        rows, mcpErr := importRows(ctx, args)
+        if mcpErr != nil {
+                return nil, mcpErr
+        }
+        if len(rows) == 0 {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "The file has no header row")
+        }
+        if len(rows)-1 > maxImportRows {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "At most %d rows can be imported at once", maxImportRows)
+        }
+        columns := make(map[string]int)
+        for i, name := range rows[0] {
+                columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+        }
+        if _, ok := columns["title"]; !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "The header row needs a title column")
+        }
+        dryRun, _ := args["dry_run"].(bool)
+
+        results := make([]ImportResult, 0, len(rows)-1)
+        succeeded := 0
+        for i, row := range rows[1:] {
+                if err := ctx.Err(); err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInternal, "Stopped at row %d after creating %d tickets: %v", i+2, succeeded, err)
+                }
+                result := ImportResult{Row: i + 2}
+                cell := func(name string) string {
+                        if col, ok := columns[name]; ok && col < len(row) {
+                                return strings.TrimSpace(row[col])
+                        }
+                        return ""
+                }
+                if strings.Join(row, "") == "" {
+                        continue
+                }
+                t, err := ticketFromRow(cell)
+                switch {
+                case err != nil:
+                        result.Error = err.Error()
+                case dryRun:
+                        result.OK, result.Ticket = true, &t
+                default:
+                        created, err := storeFrom(ctx).CreateTicket(ctx, t)
+                        if err != nil {
+                                result.Error = storeError(err).Error()
+                                break
+                        }
+                        ticketCreated(ctx, created)
+                        result.OK, result.Ticket = true, &created
+                }
+                if result.OK {
+                        succeeded++
+                }
+                results = append(results, result)
+        }
+
+        return map[string]interface{}{
+                "dryRun":    dryRun,
+                "results":   results,
+                "succeeded": succeeded,
+                "failed":    len(results) - succeeded,
+        }, nil
+}
+
+// importRows reads the rows to import from the content, content_base64, or
+// uri argument.
+func importRows(ctx context.Context, args map[string]interface{}) ([][]string, error) {
+        content, encoded, uri := mcp.StringArg(args, "content"), mcp.StringArg(args, "content_base64"), mcp.StringArg(args, "uri")
+        given := 0
+        for _, s := range []string{content, encoded, uri} {
+                if s != "" {
+                        given++
+                }
+        }
+        if given != 1 {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Exactly one of content, content_base64, or uri is required")
+        }
+
+        data := []byte(content)
+        switch {
+        case encoded != "":
+                var err error
+                if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "content_base64 is not valid base64")
+                }
+        case uri != "":
+                c, ok := mcp.ConnFromContext(ctx)
+                if !ok || !strings.HasPrefix(uri, mcp.FileURIPrefix) {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "uri must be a file:// URI within the client's roots")
+                }
+                contents, err := mcp.ReadFileResource(c, uri)
+                if err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+                }
+                data = []byte(contents.Text)
+                if contents.Blob != "" {
+                        if data, err = base64.StdEncoding.DecodeString(contents.Blob); err != nil {
+                                return nil, err
+                        }
+                }
+        }
+        if len(data) > maxImportSize {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Imports are limited to %d bytes", maxImportSize)
+        }
+
+        format := mcp.StringArg(args, "format")
+        if format == "" {
+                format = "csv"
+                // XLSX workbooks are zip archives.
+                if bytes.HasPrefix(data, []byte("PK\x03\x04")) || strings.HasSuffix(strings.ToLower(uri), ".xlsx") {
+                        format = "xlsx"
+                }
+        }
+        switch format {
+        case "csv":
+                r := csv.NewReader(bytes.NewReader(data))
+                r.FieldsPerRecord = -1
+                rows, err := r.ReadAll()
+                if err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid CSV: %v", err)
+                }
+                return rows, nil
+        case "xlsx":
+                rows, err := readXLSXRows(data)
+                if err != nil {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid XLSX: %v", err)
+                }
+                return rows, nil
+        }
+        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "format must be csv or xlsx")
+}
+
+// ticketFromRow validates one import row, read through cell, the way
+// create_ticket validates its arguments. The id and created_at columns are
+// ignored: imported tickets are new tickets.
+func ticketFromRow(cell func(name string) string) (Ticket, error) {
+        t := Ticket{
+                Title:       cell("title"),
+                Description: cell("description"),
+                Status:      strings.ToLower(cell("status")),
+                Assignee:    cell("assignee"),
+        }
+        if t.Title == "" {
+                return Ticket{}, fmt.Errorf("title must not be empty")
+        }
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        if !isValidStatus(t.Status) {
+                return Ticket{}, fmt.Errorf("Invalid status: %s", t.Status)
+        }
+        priority, err := normalizePriority(cell("priority"))
+        if err != nil {
+                return Ticket{}, err
+        }
+        t.Priority = priority
+        if labels := normalizeLabels(strings.Split(cell("labels"), ";")); len(labels) > 0 {
+                t.Labels = labels
+        }
+        if due := cell("due_date"); due != "" {
+                if serial, err := strconv.ParseFloat(due, 64); err == nil {
+                        // A spreadsheet date cell.
+                        t.DueDate = endOfDay(xlsxSerialDate(serial), time.UTC).UTC()
+                } else if t.DueDate, err = parseDueDate(due, time.UTC); err != nil {
+                        return Ticket{}, err
+                }
+        }
+        return t, nil
+}
@@ -0,0 +1,165 @@
+package tickets
+
+import (
+        "context"
+        "sort"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+// normalizeLabels trims and lowercases labels, dropping empties and
+// duplicates, and returns them sorted.
+func normalizeLabels(labels []string) []string {
+        seen := make(map[string]bool)
+        normalized := []string{}
+        for _, label := range labels {
+                label = strings.ToLower(strings.TrimSpace(label))
+                if label != "" && !seen[label] {
+                        seen[label] = true
+                        normalized = append(normalized, label)
+                }
+        }
+        sort.Strings(normalized)
+        return normalized
+}
+
+func hasLabel(t Ticket, label string) bool {
+        for _, l := range t.Labels {
+                if l == label {
+                        return true
+                }
+        }
+        return false
+}
+
+// labelsFilterArg reads the optional "labels" filter argument.
+func labelsFilterArg(args map[string]interface{}) ([]string, error) {
+        labels, ok := mcp.StringsArg(args, "labels")
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "labels must be an array of strings")
+        }
+        return normalizeLabels(labels), nil
+}
+
+func handleAddLabels(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        return changeLabels(ctx, args, true)
+}
+
+func handleRemoveLabels(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        return changeLabels(ctx, args, false)
+}
+
+func changeLabels(ctx context.Context, args map[string]interface{}, add bool) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        labels, ok := mcp.StringsArg(args, "labels")
+        labels = normalizeLabels(labels)
+        if id == "" || !ok || len(labels) == 0 {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id and a non-empty labels array are required")
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is deleted; restore it first", id)
+        }
+
+        var next []string
+        if add {
+                next = normalizeLabels(append(append([]string{}, t.Labels...), labels...))
+        } else {
+                remove := make(map[string]bool)
+                for _, l := range labels {
+                        remove[l] = true
+                }
+                for _, l := range t.Labels {
+                        if !remove[l] {
+                                next = append(next, l)
+                        }
+                }
+        }
+        if len(next) == 0 {
+                next = nil
+        }
+        t.Labels = next
+
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if add {
+                ticketUpdated(ctx, updated, "labels added: %s", strings.Join(labels, ", "))
+        } else {
+                ticketUpdated(ctx, updated, "labels removed: %s", strings.Join(labels, ", "))
+        }
+        return map[string]interface{}{"ticket": updated}, nil
+}
+
+type LabelCount struct {
+        Label string `json:"label"`
+        Count int    `json:"count"`
+}
+
+// handleListLabels enumerates every label in use on non-deleted tickets.
+func handleListLabels(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                return nil, storeError(err)
+        }
+        counts := make(map[string]int)
+        for _, t := range tickets {
+                for _, l := range t.Labels {
+                        counts[l]++
+                }
+        }
+        labels := make([]LabelCount, 0, len(counts))
+        for l, n := range counts {
+                labels = append(labels, LabelCount{Label: l, Count: n})
+        }
+        sort.Slice(labels, func(i, j int) bool { return labels[i].Label < labels[j].Label })
+        return map[string]interface{}{"labels": labels}, nil
+}
+
+func ticketLabels(ctx context.Context) []string {
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                mcp.LogAll(ctx, mcp.LevelError, "Completion error: %v", err)
+                return nil
+        }
+        var labels []string
+        for _, t := range tickets {
+                labels = append(labels, t.Labels...)
+        }
+        return normalizeLabels(labels)
+}
+
+var labelsSchema = map[string]interface{}{
+        "type":        "array",
+        "items":       map[string]interface{}{"type": "string"},
+        "description": "Labels (case-insensitive)",
+}
+
+var labelsFilterSchema = map[string]interface{}{
+        "type":        "array",
+        "items":       map[string]interface{}{"type": "string"},
+        "description": "Only tickets carrying all of these labels",
+}
+
+func labelChangeSchema() map[string]interface{} {
+        return map[string]interface{}{
+                "type": "object",
+                "properties": map[string]interface{}{
+                        "id": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Ticket ID",
+                        },
+                        "labels": labelsSchema,
+                },
+                "required": []string{"id", "labels"},
+        }
+}
@@ -0,0 +1,32 @@
+CREATE SEQUENCE ticket_ids;
+CREATE SEQUENCE comment_ids;
+
+CREATE TABLE tickets (
+    seq         bigserial PRIMARY KEY,
+    id          text NOT NULL UNIQUE,
+    title       text NOT NULL,
+    status      text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    priority    text NOT NULL DEFAULT '',
+    assignee    text NOT NULL DEFAULT '',
+    labels      text[] NOT NULL DEFAULT '{}',
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    due_date    timestamptz,
+    deleted     boolean NOT NULL DEFAULT false,
+    deleted_at  timestamptz
+);
+
+CREATE INDEX tickets_status_idx ON tickets (status) WHERE NOT deleted;
+CREATE INDEX tickets_assignee_idx ON tickets (lower(assignee)) WHERE NOT deleted;
+CREATE INDEX tickets_labels_idx ON tickets USING gin (labels);
+
+CREATE TABLE comments (
+    seq        bigserial PRIMARY KEY,
+    id         text NOT NULL UNIQUE,
+    ticket_id  text NOT NULL REFERENCES tickets (id) ON DELETE CASCADE,
+    author     text NOT NULL,
+    body       text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX comments_ticket_idx ON comments (ticket_id, seq);
@@ -0,0 +1,9 @@
+ALTER TABLE tickets
+    ADD COLUMN version    integer NOT NULL DEFAULT 1,
+    ADD COLUMN updated_at timestamptz;
+
+UPDATE tickets SET updated_at = created_at;
+
+ALTER TABLE tickets
+    ALTER COLUMN updated_at SET NOT NULL,
+    ALTER COLUMN updated_at SET DEFAULT now();
@@ -0,0 +1,173 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "sync"
+)
+
+// mockMethods are the TicketStore methods a MockStore records and can fail.
+var mockMethods = map[string]bool{
+        "ListTickets":   true,
+        "GetTicket":     true,
+        "CreateTicket":  true,
+        "UpdateTicket":  true,
+        "AddComment":    true,
+        "ListComments":  true,
+        "DeleteComment": true,
+}
+
+// MockCall is a call made to a MockStore: the method's name and its
+// arguments after the context.
+type MockCall struct {
+        Method string
+        Args   []interface{}
+}
+
+// MockStore is a TicketStore for testing tools. It records every call, and
+// answers each with the next error queued for its method by FailNext, else
+// with the method's Func field if set, else by calling Store. Its methods
+// and those of Store may be called from several goroutines; the Func fields
+// must be set before the store is used.
+type MockStore struct {
+        // Store answers the calls nothing else does. NewMockStore sets it
+        // to an in-memory store.
+        Store TicketStore
+
+        ListTicketsFunc   func(ctx context.Context, filter TicketFilter) ([]Ticket, error)
+        GetTicketFunc     func(ctx context.Context, id string) (Ticket, error)
+        CreateTicketFunc  func(ctx context.Context, t Ticket) (Ticket, error)
+        UpdateTicketFunc  func(ctx context.Context, t Ticket) (Ticket, error)
+        AddCommentFunc    func(ctx context.Context, c Comment) (Comment, error)
+        ListCommentsFunc  func(ctx context.Context, ticketID string) ([]Comment, error)
+        DeleteCommentFunc func(ctx context.Context, ticketID, commentID string) error
+
+        mu       sync.Mutex
+        calls    []MockCall
+        failures map[string][]error
+}
+
+// NewMockStore returns a MockStore whose calls go to an in-memory store
+// holding tickets.
+func NewMockStore(tickets ...Ticket) *MockStore {
+        return &MockStore{Store: NewMemoryStore(tickets, SystemClock)}
+}
+
+// FailNext makes the next call of method, such as "UpdateTicket", return
+// err. Errors queued for the same method are returned in order, one per
+// call. It panics if TicketStore has no such method.
+func (m *MockStore) FailNext(method string, err error) {
+        if !mockMethods[method] {
+                panic(fmt.Sprintf("tickets: TicketStore has no method %s", method))
+        }
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        if m.failures == nil {
+                m.failures = make(map[string][]error)
+        }
+        m.failures[method] = append(m.failures[method], err)
+}
+
+// Calls returns the calls made so far, oldest first, or only those of
+// method if it isn't empty.
+func (m *MockStore) Calls(method string) []MockCall {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        var calls []MockCall
+        for _, call := range m.calls {
+                if method == "" || call.Method == method {
+                        calls = append(calls, call)
+                }
+        }
+        return calls
+}
+
+// Reset forgets the calls recorded and the errors still queued.
+func (m *MockStore) Reset() {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        m.calls, m.failures = nil, nil
+}
+
+// record records a call and returns the error queued for it, if any.
+func (m *MockStore) record(method string, args ...interface{}) error {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        m.calls = append(m.calls, MockCall{Method: method, Args: args})
+        queued := m.failures[method]
+        if len(queued) == 0 {
+                return nil
+        }
+        m.failures[method] = queued[1:]
+        return queued[0]
+}
+
+func (m *MockStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        if err := m.record("ListTickets", filter); err != nil {
+                return nil, err
+        }
+        if m.ListTicketsFunc != nil {
+                return m.ListTicketsFunc(ctx, filter)
+        }
+        return m.Store.ListTickets(ctx, filter)
+}
+
+func (m *MockStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        if err := m.record("GetTicket", id); err != nil {
+                return Ticket{}, err
+        }
+        if m.GetTicketFunc != nil {
+                return m.GetTicketFunc(ctx, id)
+        }
+        return m.Store.GetTicket(ctx, id)
+}
+
+func (m *MockStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if err := m.record("CreateTicket", t); err != nil {
+                return Ticket{}, err
+        }
+        if m.CreateTicketFunc != nil {
+                return m.CreateTicketFunc(ctx, t)
+        }
+        return m.Store.CreateTicket(ctx, t)
+}
+
+func (m *MockStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if err := m.record("UpdateTicket", t); err != nil {
+                return Ticket{}, err
+        }
+        if m.UpdateTicketFunc != nil {
+                return m.UpdateTicketFunc(ctx, t)
+        }
+        return m.Store.UpdateTicket(ctx, t)
+}
+
+func (m *MockStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        if err := m.record("AddComment", c); err != nil {
+                return Comment{}, err
+        }
+        if m.AddCommentFunc != nil {
+                return m.AddCommentFunc(ctx, c)
+        }
+        return m.Store.AddComment(ctx, c)
+}
+
+func (m *MockStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        if err := m.record("ListComments", ticketID); err != nil {
+                return nil, err
+        }
+        if m.ListCommentsFunc != nil {
+                return m.ListCommentsFunc(ctx, ticketID)
+        }
+        return m.Store.ListComments(ctx, ticketID)
+}
+
+func (m *MockStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        if err := m.record("DeleteComment", ticketID, commentID); err != nil {
+                return err
+        }
+        if m.DeleteCommentFunc != nil {
+                return m.DeleteCommentFunc(ctx, ticketID, commentID)
+        }
+        return m.Store.DeleteComment(ctx, ticketID, commentID)
+}
@@ -0,0 +1,116 @@
+package tickets
+
+import (
+        "context"
+        "errors"
+        "reflect"
+        "testing"
+
+        "mcp-server/mcp"
+)
+
+func mockContext(store TicketStore) context.Context {
+        return ContextWithStore(context.Background(), store)
+}
+
+func TestCreateTicketStoresNormalizedArguments(t *testing.T) {
+        store := NewMockStore()
+        _, err := handleCreateTicket(mockContext(store), map[string]interface{}{
+                "title":    "  Broken build ",
+                "labels":   []interface{}{"CI", "ci"},
+                "priority": " p1",
+        })
+        if err != nil {
+                t.Fatal(err)
+        }
+        calls := store.Calls("CreateTicket")
+        if len(calls) != 1 {
+                t.Fatalf("got %d CreateTicket calls, want 1", len(calls))
+        }
+        created := calls[0].Args[0].(Ticket)
+        want := Ticket{Title: "Broken build", Status: "todo", Labels: []string{"ci"}, Priority: "P1"}
+        if !reflect.DeepEqual(created, want) {
+                t.Errorf("CreateTicket(%+v), want %+v", created, want)
+        }
+}
+
+func TestUpdateTicketStatusConflict(t *testing.T) {
+        store := NewMockStore(Ticket{ID: "1", Title: "Broken build", Status: "todo"})
+        store.FailNext("UpdateTicket", ErrVersionConflict)
+        _, err := handleUpdateTicketStatus(mockContext(store), map[string]interface{}{"id": "1", "status": "pending", "version": float64(1)})
+        if code := mcp.ToError(err).Code; code != -32010 {
+                t.Errorf("got error %v (code %d), want a conflict (-32010)", err, code)
+        }
+        var methods []string
+        for _, call := range store.Calls("") {
+                methods = append(methods, call.Method)
+        }
+        if want := []string{"GetTicket", "UpdateTicket"}; !reflect.DeepEqual(methods, want) {
+                t.Errorf("calls: got %v, want %v", methods, want)
+        }
+}
+
+func TestStoreErrors(t *testing.T) {
+        for _, tc := range []struct {
+                name string
+                err  error
+                code int
+        }{
+                {"not found", ErrTicketNotFound, -32602},
+                {"unavailable", errors.New("connection refused"), -32603},
+        } {
+                t.Run(tc.name, func(t *testing.T) {
+                        store := NewMockStore()
+                        store.GetTicketFunc = func(ctx context.Context, id string) (Ticket, error) {
+                                return Ticket{}, tc.err
+                        }
+                        _, err := handleAddComment(mockContext(store), map[string]interface{}{"ticket_id": "1", "body": "seen"})
+                        if code := mcp.ToError(err).Code; code != tc.code {
+                                t.Errorf("got error %v (code %d), want code %d", err, code, tc.code)
+                        }
+                        if calls := store.Calls("AddComment"); len(calls) != 0 {
+                                t.Errorf("comment added to a ticket that couldn't be read: %v", calls)
+                        }
+                })
+        }
+}
+
+func TestMockStoreFailNextOrder(t *testing.T) {
+        store := NewMockStore()
+        first, second := errors.New("first"), errors.New("second")
+        store.FailNext("ListTickets", first)
+        store.FailNext("ListTickets", second)
+        ctx := context.Background()
+        for _, want := range []error{first, second, nil} {
+                if _, err := store.ListTickets(ctx, TicketFilter{}); err != want {
+                        t.Errorf("ListTickets: got %v, want %v", err, want)
+                }
+        }
+        store.Reset()
+        if calls := store.Calls(""); len(calls) != 0 {
+                t.Errorf("calls after Reset: %v", calls)
+        }
+}
+
+func TestBulkUpdateReportsPartialFailure(t *testing.T) {
+        store := NewMockStore(Ticket{ID: "1", Title: "a", Status: "todo", Version: 1}, Ticket{ID: "2", Title: "b", Status: "todo", Version: 1})
+        args := map[string]interface{}{"ids": []interface{}{"1", "2", "3"}, "priority": "P1"}
+        result, err := handleBulkUpdateTickets(mockContext(store), args)
+        if err != nil {
+                t.Fatalf("one of three missing: got error %v", err)
+        }
+        summary := result.(map[string]interface{})
+        if summary["succeeded"] != 2 || summary["failed"] != 1 {
+                t.Errorf("got %v succeeded and %v failed, want 2 and 1", summary["succeeded"], summary["failed"])
+        }
+
+        args["ids"] = []interface{}{"3", "4"}
+        _, err = handleBulkUpdateTickets(mockContext(store), args)
+        mcpErr := mcp.ToError(err)
+        if err == nil || mcpErr.Code != -32602 {
+                t.Fatalf("none found: got %v, want a not-found error", err)
+        }
+        if results := mcpErr.Data.(map[string]interface{})["results"].([]BulkResult); len(results) != 2 || results[0].OK {
+                t.Errorf("none found: got results %+v", results)
+        }
+}
@@ -0,0 +1,94 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "sort"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+// ticketPriorities lists the priorities from most to least urgent.
+var ticketPriorities = []string{"P0", "P1", "P2", "P3"}
+
+// normalizePriority canonicalizes a priority such as "p1" to "P1". The empty
+// string (no priority) is valid.
+func normalizePriority(priority string) (string, error) {
+        priority = strings.ToUpper(strings.TrimSpace(priority))
+        if priority == "" {
+                return "", nil
+        }
+        for _, p := range ticketPriorities {
+                if p == priority {
+                        return p, nil
+                }
+        }
+        return "", fmt.Errorf("Invalid priority: %s (want one of %s)", priority, strings.Join(ticketPriorities, ", "))
+}
+
+// priorityRank orders priorities for sorting; tickets without a priority
+// sort after P3.
+func priorityRank(priority string) int {
+        for i, p := range ticketPriorities {
+                if p == priority {
+                        return i
+                }
+        }
+        return len(ticketPriorities)
+}
+
+func comparePriorities(a, b Ticket) int {
+        return priorityRank(a.Priority) - priorityRank(b.Priority)
+}
+
+// sortByPriority stably orders tickets highest priority first, keeping the
+// existing order within each priority.
+func sortByPriority(tickets []Ticket) {
+        sort.SliceStable(tickets, func(i, j int) bool {
+                return comparePriorities(tickets[i], tickets[j]) < 0
+        })
+}
+
+func handleSetTicketPriority(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        priority, err := normalizePriority(mcp.StringArg(args, "priority"))
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is deleted; restore it first", id)
+        }
+        if t.Priority == priority {
+                return map[string]interface{}{"ticket": t}, nil
+        }
+
+        t.Priority = priority
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if priority == "" {
+                ticketUpdated(ctx, updated, "priority cleared")
+        } else {
+                ticketUpdated(ctx, updated, "priority %s", priority)
+        }
+        return map[string]interface{}{"ticket": updated}, nil
+}
+
+var prioritySchema = map[string]interface{}{
+        "type":        "string",
+        "enum":        ticketPriorities,
+        "description": "Priority from P0 (most urgent) to P3",
+}
@@ -0,0 +1,59 @@
+package tickets
+
+import "mcp-server/mcp"
+
+func init() {
+        mcp.RegisterPrompt(&mcp.PromptTemplate{
+                Prompt: mcp.Prompt{
+                        Name:        "triage_ticket",
+                        Description: "Triage a ticket: assess impact, suggest a status and next steps",
+                        Arguments: []mcp.PromptArgument{
+                                {Name: "ticket_id", Description: "ID of the ticket to triage", Required: true},
+                        },
+                },
+                Messages: []mcp.PromptMessageTemplate{
+                        {
+                                Role:        "user",
+                                ResourceURI: ticketURIPrefix + "{{ticket_id}}",
+                        },
+                        {
+                                Role: "user",
+                                Text: "Please triage ticket {{ticket_id}}, attached above. " +
+                                        "Summarize the problem, estimate its impact, and recommend whether it should be todo, pending, or done, " +
+                                        "along with concrete next steps.",
+                        },
+                },
+        })
+        mcp.RegisterPrompt(&mcp.PromptTemplate{
+                Prompt: mcp.Prompt{
+                        Name:        "summarize_tickets",
+                        Description: "Summarize the tickets in a given status",
+                        Arguments: []mcp.PromptArgument{
+                                {Name: "status", Description: "Ticket status: todo, pending, or done", Required: true},
+                        },
+                },
+                Messages: []mcp.PromptMessageTemplate{
+                        {
+                                Role: "user",
+                                Text: "Use the get_{{status}}_tickets tool to fetch all {{status}} tickets and write a short status report " +
+                                        "grouping related work and calling out anything that looks blocked.",
+                        },
+                },
+        })
+        mcp.RegisterPrompt(&mcp.PromptTemplate{
+                Prompt: mcp.Prompt{
+                        Name:        "assignee_workload",
+                        Description: "Review what is on one person's plate",
+                        Arguments: []mcp.PromptArgument{
+                                {Name: "assignee", Description: "Person whose tickets to review", Required: true},
+                        },
+                },
+                Messages: []mcp.PromptMessageTemplate{
+                        {
+                                Role: "user",
+                                Text: "Use the get_tickets_by_assignee tool to list the tickets assigned to {{assignee}}. " +
+                                        "Summarize their workload, flag anything at risk, and suggest what they should pick up next.",
+                        },
+                },
+        })
+}
@@ -0,0 +1,281 @@
+package tickets
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// remoteDeletedLabel marks tickets soft-deleted through the MCP tools in
+// trackers that have no reversible delete of their own.
+const remoteDeletedLabel = "mcp-deleted"
+
+// remoteAPI is the JSON-over-HTTP client shared by the issue tracker stores.
+type remoteAPI struct {
+        baseURL   string
+        client    *http.Client
+        authorize func(*http.Request)
+        // pacer, if set, spaces requests out to stay within the tracker's
+        // advertised rate limit.
+        pacer *ratePacer
+}
+
+func newRemoteAPI(baseURL string, authorize func(*http.Request)) *remoteAPI {
+        return &remoteAPI{
+                baseURL:   strings.TrimSuffix(baseURL, "/"),
+                client:    &http.Client{Timeout: mcp.RemoteRequestTimeout},
+                authorize: authorize,
+        }
+}
+
+// remoteError is a non-2xx response from a tracker.
+type remoteError struct {
+        Method string
+        URL    string
+        Status int
+        Body   string
+}
+
+func (e *remoteError) Error() string {
+        return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.URL, e.Status, http.StatusText(e.Status), e.Body)
+}
+
+// do sends body (if non-nil) as JSON and decodes a JSON response into out
+// (if non-nil). A 404 is reported as ErrTicketNotFound.
+func (c *remoteAPI) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+        resp, err := c.send(ctx, method, path, query, body)
+        if err != nil {
+                return err
+        }
+        defer resp.Body.Close()
+        if out == nil {
+                io.Copy(io.Discard, resp.Body)
+                return nil
+        }
+        return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send performs the request and returns the response if its status is 2xx.
+func (c *remoteAPI) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+        target := c.baseURL + path
+        if len(query) > 0 {
+                target += "?" + query.Encode()
+        }
+        var reader io.Reader
+        if body != nil {
+                data, err := json.Marshal(body)
+                if err != nil {
+                        return nil, err
+                }
+                reader = bytes.NewReader(data)
+        }
+        req, err := http.NewRequestWithContext(ctx, method, target, reader)
+        if err != nil {
+                return nil, err
+        }
+        req.Header.Set("Accept", "application/json")
+        if body != nil {
+                req.Header.Set("Content-Type", "application/json")
+        }
+        if c.authorize != nil {
+                c.authorize(req)
+        }
+
+        req, span := mcp.StartClientSpan(req)
+        resp, err := c.doPaced(req)
+        mcp.EndClientSpan(span, resp, err)
+        if err != nil {
+                return nil, err
+        }
+        if resp.StatusCode == http.StatusNotFound {
+                resp.Body.Close()
+                return nil, ErrTicketNotFound
+        }
+        if resp.StatusCode < 200 || resp.StatusCode > 299 {
+                data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+                resp.Body.Close()
+                return nil, &remoteError{Method: method, URL: target, Status: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+        }
+        return resp, nil
+}
+
+// doPaced sends req, waiting as the pacer asks and retrying once if the
+// tracker says the rate limit was hit.
+func (c *remoteAPI) doPaced(req *http.Request) (*http.Response, error) {
+        if c.pacer == nil {
+                return c.client.Do(req)
+        }
+        for attempt := 0; ; attempt++ {
+                c.pacer.wait()
+                resp, err := c.client.Do(req)
+                if err != nil {
+                        return nil, err
+                }
+                retryAfter := c.pacer.update(resp)
+                if retryAfter == 0 || attempt > 0 {
+                        return resp, nil
+                }
+                resp.Body.Close()
+                if retryAfter > maxRateLimitWait {
+                        return nil, fmt.Errorf("%s rate limit exceeded; retry in %s", req.URL.Host, retryAfter.Round(time.Second))
+                }
+                time.Sleep(retryAfter)
+                if req.GetBody != nil {
+                        if req.Body, err = req.GetBody(); err != nil {
+                                return nil, err
+                        }
+                }
+        }
+}
+
+// maxRateLimitWait is the longest a request waits for a rate limit window
+// to reset before failing instead.
+const maxRateLimitWait = time.Minute
+
+// ratePacer tracks the X-RateLimit-* (GitHub) or RateLimit-* (GitLab)
+// response headers. Requests go out freely while plenty of budget remains;
+// once less than a fifth is left, they are spread evenly over the rest of
+// the window.
+type ratePacer struct {
+        mu   sync.Mutex
+        next time.Time
+}
+
+func (p *ratePacer) wait() {
+        p.mu.Lock()
+        delay := time.Until(p.next)
+        p.mu.Unlock()
+        if delay > 0 {
+                time.Sleep(min(delay, maxRateLimitWait))
+        }
+}
+
+// update records the budget reported by resp and returns how long to wait
+// before retrying if resp is a rate-limit rejection.
+func (p *ratePacer) update(resp *http.Response) time.Duration {
+        h := resp.Header
+        header := func(name string) string {
+                if v := h.Get("X-" + name); v != "" {
+                        return v
+                }
+                return h.Get(name)
+        }
+        limit, _ := strconv.Atoi(header("RateLimit-Limit"))
+        remaining, errRemaining := strconv.Atoi(header("RateLimit-Remaining"))
+        resetUnix, _ := strconv.ParseInt(header("RateLimit-Reset"), 10, 64)
+        reset := time.Unix(resetUnix, 0)
+
+        p.mu.Lock()
+        defer p.mu.Unlock()
+        now := time.Now()
+        switch {
+        case errRemaining != nil || resetUnix == 0:
+        case remaining <= 0:
+                p.next = reset
+        case limit > 0 && remaining < limit/5:
+                p.next = now.Add(reset.Sub(now) / time.Duration(remaining))
+        default:
+                p.next = time.Time{}
+        }
+
+        if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+                return 0
+        }
+        if seconds, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+                return time.Duration(seconds) * time.Second
+        }
+        if errRemaining == nil && remaining <= 0 {
+                return max(time.Until(reset), time.Second)
+        }
+        return 0
+}
+
+// parseRemoteDSN splits a "<scheme>:<url>" store DSN such as
+// "jira:https://acme.atlassian.net?project=OPS" into the tracker URL
+// (without its query) and the query options.
+func parseRemoteDSN(dsn, scheme string) (string, url.Values, error) {
+        u, err := url.Parse(strings.TrimPrefix(dsn, scheme+":"))
+        if err != nil {
+                return "", nil, fmt.Errorf("parsing %s DSN: %w", scheme, err)
+        }
+        if u.Scheme != "http" && u.Scheme != "https" {
+                return "", nil, fmt.Errorf("%s DSN must contain an http(s) URL: %s", scheme, dsn)
+        }
+        query := u.Query()
+        u.RawQuery = ""
+        return u.String(), query, nil
+}
+
+// remoteVersion derives a ticket version from a tracker's last-modified
+// time, so stale updates can be detected without a version counter.
+func remoteVersion(updated time.Time) int {
+        return int(updated.UnixMilli())
+}
+
+// splitDeletedLabel separates the soft-delete marker from a tracker's
+// labels.
+func splitDeletedLabel(labels []string) ([]string, bool) {
+        var kept []string
+        deleted := false
+        for _, l := range labels {
+                if l == remoteDeletedLabel {
+                        deleted = true
+                        continue
+                }
+                kept = append(kept, l)
+        }
+        return normalizeLabels(kept), deleted
+}
+
+// withDeletedLabel returns the labels to store remotely for t.
+func withDeletedLabel(t Ticket) []string {
+        labels := append([]string{}, t.Labels...)
+        if t.Deleted {
+                labels = append(labels, remoteDeletedLabel)
+        }
+        return labels
+}
+
+// priorityLabelPrefix prefixes the labels that carry a ticket's priority
+// in trackers without a priority field, e.g. "priority:P1".
+const priorityLabelPrefix = "priority:"
+
+func isPriorityLabel(name string) bool {
+        return strings.HasPrefix(strings.ToLower(name), priorityLabelPrefix)
+}
+
+// priorityNames maps P0-P3 onto the names most trackers use.
+var priorityNames = map[string]string{
+        "P0": "Highest",
+        "P1": "High",
+        "P2": "Medium",
+        "P3": "Low",
+}
+
+// priorityFromName maps a tracker's priority name back to P0-P3.
+func priorityFromName(name string) string {
+        switch strings.ToLower(name) {
+        case "highest", "blocker", "critical", "urgent":
+                return "P0"
+        case "high", "major":
+                return "P1"
+        case "medium", "normal":
+                return "P2"
+        case "low", "lowest", "minor", "trivial":
+                return "P3"
+        }
+        if p, err := normalizePriority(name); err == nil {
+                return p
+        }
+        return ""
+}
@@ -0,0 +1,60 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+func handleDraftStatusReport(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        if err := mcp.ElicitMissingArgs(ctx, args, "Which tickets should the status report cover?", map[string]interface{}{
+                "status": map[string]interface{}{
+                        "type":        "string",
+                        "description": "Ticket status",
+                        "enum":        ticketStatuses(),
+                },
+        }, []string{"status"}); err != nil {
+                return nil, err
+        }
+
+        status := mcp.StringArg(args, "status")
+        if !isValidStatus(status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "status must be one of todo, pending, done")
+        }
+
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{Status: status})
+        if err != nil {
+                return nil, storeError(err)
+        }
+        var lines []string
+        for _, t := range tickets {
+                lines = append(lines, fmt.Sprintf("- %s: %s", t.ID, t.Title))
+        }
+
+        intelligence := 0.7
+        result, err := mcp.RequestSampling(ctx, mcp.CreateMessageParams{
+                Messages: []mcp.SamplingMessage{
+                        {
+                                Role: "user",
+                                Content: mcp.PromptContent{
+                                        Type: "text",
+                                        Text: fmt.Sprintf("Write a brief status report for these %s tickets:\n%s", status, strings.Join(lines, "\n")),
+                                },
+                        },
+                },
+                ModelPreferences: &mcp.ModelPreferences{IntelligencePriority: &intelligence},
+                SystemPrompt:     "You are a concise engineering project assistant.",
+                MaxTokens:        500,
+        })
+        if err != nil {
+                return nil, err
+        }
+
+        return map[string]interface{}{
+                "status": status,
+                "report": result.Content.Text,
+                "model":  result.Model,
+        }, nil
+}
@@ -0,0 +1,104 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+const ticketURIPrefix = "ticket://"
+
+func init() {
+        // The live export changes with every ticket, so exports aren't
+        // cached.
+        mcp.RegisterResourceProvider(&mcp.ResourceProvider{
+                Prefix: exportURIPrefix,
+                List: func(context.Context) ([]mcp.Resource, error) {
+                        return []mcp.Resource{{
+                                URI:         exportAllURI,
+                                Name:        "All tickets (CSV)",
+                                Description: "Every ticket as CSV, in the format import_tickets reads",
+                                MimeType:    "text/csv",
+                        }}, nil
+                },
+                Read: func(ctx context.Context, _ *mcp.Conn, uri string) (mcp.ResourceContents, error) {
+                        return readExportResource(ctx, uri)
+                },
+                Static: true,
+                Scopes: []string{mcp.ScopeTicketsRead},
+        })
+        mcp.RegisterResourceProvider(&mcp.ResourceProvider{
+                Prefix: ticketURIPrefix,
+                List:   listTicketResources,
+                Templates: []mcp.ResourceTemplate{
+                        {
+                                URITemplate: ticketURIPrefix + "{id}",
+                                Name:        "ticket",
+                                Description: "A single ticket by ID",
+                                MimeType:    "application/json",
+                        },
+                },
+                Read:   readTicketResource,
+                Cached: true,
+                Scopes: []string{mcp.ScopeTicketsRead},
+        })
+        mcp.RegisterArgumentCompleter("ticket_id", ticketIDs)
+        mcp.RegisterArgumentCompleter("status", func(context.Context) []string { return ticketStatuses() })
+        mcp.RegisterArgumentCompleter("assignee", ticketAssignees)
+        mcp.RegisterArgumentCompleter("label", ticketLabels)
+        mcp.RegisterTemplateCompleter(ticketURIPrefix+"{id}", "id", ticketIDs)
+}
+
+func listTicketResources(ctx context.Context) ([]mcp.Resource, error) {
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                return nil, err
+        }
+        resources := make([]mcp.Resource, 0, len(tickets))
+        for _, t := range tickets {
+                resources = append(resources, mcp.Resource{
+                        URI:         ticketURIPrefix + t.ID,
+                        Name:        t.Title,
+                        Description: fmt.Sprintf("Ticket %s (%s)", t.ID, t.Status),
+                        MimeType:    "application/json",
+                })
+        }
+        return resources, nil
+}
+
+// readTicketResource loads the current contents of uri from the store,
+// bypassing the cache.
+func readTicketResource(ctx context.Context, _ *mcp.Conn, uri string) (mcp.ResourceContents, error) {
+        ticket, err := storeFrom(ctx).GetTicket(ctx, strings.TrimPrefix(uri, ticketURIPrefix))
+        if err == ErrTicketNotFound {
+                return mcp.ResourceContents{}, fmt.Errorf("Resource not found: %s", uri)
+        }
+        if err != nil {
+                return mcp.ResourceContents{}, err
+        }
+        data, err := json.Marshal(ticket)
+        if err != nil {
+                return mcp.ResourceContents{}, err
+        }
+        return mcp.ResourceContents{
+                URI:      uri,
+                MimeType: "application/json",
+                Text:     string(data),
+        }, nil
+}
+
+func ticketIDs(ctx context.Context) []string {
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                mcp.LogAll(ctx, mcp.LevelError, "Completion error: %v", err)
+                return nil
+        }
+        ids := make([]string, 0, len(tickets))
+        for _, t := range tickets {
+                ids = append(ids, t.ID)
+        }
+        return ids
+}
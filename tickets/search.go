@@ -0,0 +1,205 @@
+package tickets
+
+import (
+        "context"
+        "sort"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+const (
+        defaultSearchLimit = 50
+        maxSearchLimit     = 200
+)
+
+// matchesQuery reports whether every whitespace-separated term of query
+// occurs, case-insensitively, in the ticket's ID, title, or description.
+func matchesQuery(t Ticket, query string) bool {
+        haystack := strings.ToLower(t.ID + "\n" + t.Title + "\n" + t.Description)
+        for _, term := range strings.Fields(strings.ToLower(query)) {
+                if !strings.Contains(haystack, term) {
+                        return false
+                }
+        }
+        return true
+}
+
+// parseTimeArg accepts RFC 3339 timestamps or plain YYYY-MM-DD dates
+// (interpreted as midnight UTC).
+func parseTimeArg(args map[string]interface{}, name string) (time.Time, error) {
+        value := mcp.StringArg(args, name)
+        if value == "" {
+                return time.Time{}, nil
+        }
+        for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+                if t, err := time.Parse(layout, value); err == nil {
+                        return t, nil
+                }
+        }
+        return time.Time{}, mcp.Errorf(mcp.ErrInvalidArgument, "%s must be an RFC 3339 timestamp or YYYY-MM-DD date", name)
+}
+
+// ticketSortFields compares tickets by each sortable field. sortTickets
+// breaks ties by ID so the ordering is stable across calls.
+var ticketSortFields = map[string]func(a, b Ticket) int{
+        "priority": comparePriorities,
+        "created":  func(a, b Ticket) int { return a.CreatedAt.Compare(b.CreatedAt) },
+        "due":      compareDueDates,
+        "id":       func(a, b Ticket) int { return ticketNumber(a.ID) - ticketNumber(b.ID) },
+        "title":    func(a, b Ticket) int { return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)) },
+        "status":   func(a, b Ticket) int { return strings.Compare(a.Status, b.Status) },
+}
+
+// compareDueDates orders tickets by due date, with undated tickets last.
+func compareDueDates(a, b Ticket) int {
+        switch {
+        case a.DueDate.IsZero() && b.DueDate.IsZero():
+                return 0
+        case a.DueDate.IsZero():
+                return 1
+        case b.DueDate.IsZero():
+                return -1
+        }
+        return a.DueDate.Compare(b.DueDate)
+}
+
+func sortTickets(tickets []Ticket, field string, descending bool) {
+        cmp := ticketSortFields[field]
+        sort.SliceStable(tickets, func(i, j int) bool {
+                c := cmp(tickets[i], tickets[j])
+                if c == 0 {
+                        c = strings.Compare(tickets[i].ID, tickets[j].ID)
+                }
+                if descending {
+                        return c > 0
+                }
+                return c < 0
+        })
+}
+
+func handleSearchTickets(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        filter := TicketFilter{
+                Status:   mcp.StringArg(args, "status"),
+                Query:    mcp.StringArg(args, "query"),
+                Assignee: strings.TrimSpace(mcp.StringArg(args, "assignee")),
+        }
+        if filter.Status != "" && !isValidStatus(filter.Status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", filter.Status)
+        }
+        if err := applyDeletedFilter(&filter, args); err != nil {
+                return nil, err
+        }
+        var mcpErr error
+        if filter.Labels, mcpErr = labelsFilterArg(args); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if filter.CreatedAfter, mcpErr = parseTimeArg(args, "created_after"); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if filter.CreatedBefore, mcpErr = parseTimeArg(args, "created_before"); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if days, ok := args["due_within_days"].(float64); ok {
+                if days < 0 {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "due_within_days must not be negative")
+                }
+                loc, mcpErr := loadLocation(args)
+                if mcpErr != nil {
+                        return nil, mcpErr
+                }
+                filter.DueBefore = dueWithinBound(clockFrom(ctx).Now(), int(days), loc).Add(time.Second)
+                filter.OpenOnly = true
+        }
+
+        priority, err := normalizePriority(mcp.StringArg(args, "priority"))
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+        filter.Priority = priority
+
+        sortBy := mcp.StringArg(args, "sort_by")
+        if sortBy == "" {
+                sortBy = "priority"
+        }
+        if _, ok := ticketSortFields[sortBy]; !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid sort_by: %s", sortBy)
+        }
+        order := mcp.StringArg(args, "order")
+        if order != "" && order != "asc" && order != "desc" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "order must be asc or desc")
+        }
+
+        limit := defaultSearchLimit
+        if n, ok := args["limit"].(float64); ok {
+                if n < 1 || n > maxSearchLimit {
+                        return nil, mcp.Errorf(mcp.ErrInvalidArgument, "limit must be between 1 and %d", maxSearchLimit)
+                }
+                limit = int(n)
+        }
+
+        tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        sortTickets(tickets, "id", false)
+        sortTickets(tickets, sortBy, order == "desc")
+
+        total := len(tickets)
+        if total > limit {
+                tickets = tickets[:limit]
+        }
+        return map[string]interface{}{
+                "tickets": tickets,
+                "total":   total,
+        }, nil
+}
+
+func searchTicketsSchema() map[string]interface{} {
+        return map[string]interface{}{
+                "type": "object",
+                "properties": map[string]interface{}{
+                        "query": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Free-text terms, all of which must appear in the ticket ID, title, or description",
+                        },
+                        "status": map[string]interface{}{
+                                "type": "string",
+                                "enum": ticketStatuses(),
+                        },
+                        "assignee": assigneeSchema,
+                        "priority": prioritySchema,
+                        "labels":   labelsFilterSchema,
+                        "created_after": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Only tickets created at or after this time (RFC 3339 or YYYY-MM-DD)",
+                        },
+                        "created_before": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Only tickets created before this time (RFC 3339 or YYYY-MM-DD)",
+                        },
+                        "due_within_days": map[string]interface{}{
+                                "type":        "integer",
+                                "minimum":     0,
+                                "description": "Only open tickets due by the end of the day N days from now (0 = today), including overdue ones",
+                        },
+                        "timezone": timezoneSchema,
+                        "deleted":  deletedFilterSchema,
+                        "sort_by": map[string]interface{}{
+                                "type":        "string",
+                                "enum":        []string{"priority", "id", "created", "due", "title", "status"},
+                                "description": "Sort field (default priority, highest first)",
+                        },
+                        "order": map[string]interface{}{
+                                "type": "string",
+                                "enum": []string{"asc", "desc"},
+                        },
+                        "limit": map[string]interface{}{
+                                "type":    "integer",
+                                "minimum": 1,
+                                "maximum": maxSearchLimit,
+                        },
+                },
+        }
+}
@@ -0,0 +1,151 @@
+package tickets
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "fmt"
+        "net/http"
+        "slices"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// Default Slack message templates. Placeholders: {{id}}, {{title}},
+// {{status}}, {{previous_status}}, {{priority}}, {{assignee}}, and
+// {{description}}.
+const (
+        DefaultSlackCreatedTemplate = ":new: *{{id}}* {{title}} ({{status}})"
+        DefaultSlackStatusTemplate  = ":arrows_counterclockwise: *{{id}}* {{title}}: {{previous_status}} → {{status}}"
+)
+
+// slackQueueSize bounds the messages waiting to be posted; further
+// messages are dropped so a slow Slack never holds up tool calls.
+const slackQueueSize = 100
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// slackNotifier posts a message to a Slack channel when a ticket is created
+// or changes status. Messages go either to an incoming webhook, which is
+// bound to its channel, or through chat.postMessage with a bot token.
+type slackNotifier struct {
+        webhookURL string
+        token      mcp.SecretRef
+        channel    string
+        created    string
+        status     string
+        client     *http.Client
+        queue      chan string
+}
+
+func NewSlackNotifier(webhookURL string, token mcp.SecretRef, channel, createdTemplate, statusTemplate string) (*slackNotifier, error) {
+        if webhookURL == "" && (token.Value() == "" || channel == "") {
+                return nil, fmt.Errorf("slack needs a webhook URL, or a bot token and a channel")
+        }
+        n := &slackNotifier{
+                webhookURL: webhookURL,
+                token:      token,
+                channel:    channel,
+                created:    createdTemplate,
+                status:     statusTemplate,
+                client:     &http.Client{Timeout: 10 * time.Second},
+                queue:      make(chan string, slackQueueSize),
+        }
+        go n.run()
+        return n, nil
+}
+
+// Notify is an event log subscriber.
+func (n *slackNotifier) Notify(e TicketEvent) {
+        if e.Ticket == nil {
+                return
+        }
+        var text string
+        switch {
+        case e.Type == eventCreated:
+                text = renderTicketTemplate(n.created, *e.Ticket, nil)
+        case e.Previous != nil && slices.Contains(e.Changes, "status"):
+                text = renderTicketTemplate(n.status, *e.Ticket, e.Previous)
+        default:
+                return
+        }
+        select {
+        case n.queue <- text:
+        default:
+                mcp.LogAll(context.Background(), mcp.LevelWarning, "Slack queue full; dropping notification for ticket %s", e.TicketID)
+        }
+}
+
+func (n *slackNotifier) run() {
+        for text := range n.queue {
+                if err := n.post(text); err != nil {
+                        mcp.LogAll(context.Background(), mcp.LevelError, "Posting to Slack: %v", err)
+                }
+        }
+}
+
+func (n *slackNotifier) post(text string) error {
+        body := map[string]string{"text": text}
+        target := n.webhookURL
+        if target == "" {
+                target = slackPostMessageURL
+        }
+        if n.channel != "" {
+                body["channel"] = n.channel
+        }
+        data, err := json.Marshal(body)
+        if err != nil {
+                return err
+        }
+        req, err := http.NewRequest("POST", target, bytes.NewReader(data))
+        if err != nil {
+                return err
+        }
+        req.Header.Set("Content-Type", "application/json; charset=utf-8")
+        if n.webhookURL == "" {
+                req.Header.Set("Authorization", "Bearer "+n.token.Value())
+        }
+        resp, err := n.client.Do(req)
+        if err != nil {
+                return err
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+                return fmt.Errorf("slack responded %s", resp.Status)
+        }
+        if n.webhookURL != "" {
+                return nil
+        }
+        // chat.postMessage reports failures in the body with a 200 status.
+        var result struct {
+                OK    bool   `json:"ok"`
+                Error string `json:"error"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+                return err
+        }
+        if !result.OK {
+                return fmt.Errorf("chat.postMessage: %s", result.Error)
+        }
+        return nil
+}
+
+// renderTicketTemplate substitutes a ticket's fields into a notification
+// template. previous, if non-nil, supplies {{previous_status}}.
+func renderTicketTemplate(tmpl string, t Ticket, previous *Ticket) string {
+        previousStatus := ""
+        if previous != nil {
+                previousStatus = previous.Status
+        }
+        return strings.NewReplacer(
+                "{{id}}", t.ID,
+                "{{title}}", t.Title,
+                "{{status}}", t.Status,
+                "{{previous_status}}", previousStatus,
+                "{{priority}}", t.Priority,
+                "{{assignee}}", t.Assignee,
+                "{{description}}", t.Description,
+        ).Replace(tmpl)
+}
@@ -0,0 +1,41 @@
+package tickets
+
+import (
+        "context"
+
+        "mcp-server/mcp"
+)
+
+// TicketStoreKind names the backend behind store, e.g. "postgres".
+var TicketStoreKind = "memory"
+
+func handleServerStats(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        stats := mcp.ServerStats()
+        stats["store"] = storeStats(ctx)
+        return stats, nil
+}
+
+// storeStats counts the store's tickets by status. Remote backends list
+// every ticket to do so, so a failure is reported rather than failing the
+// whole call.
+func storeStats(ctx context.Context) map[string]interface{} {
+        stats := map[string]interface{}{"backend": TicketStoreKind}
+        tickets, err := storeFrom(ctx).ListTickets(ctx, TicketFilter{IncludeDeleted: true})
+        if err != nil {
+                stats["error"] = err.Error()
+                return stats
+        }
+        byStatus := map[string]int{}
+        deleted := 0
+        for _, t := range tickets {
+                if t.Deleted {
+                        deleted++
+                        continue
+                }
+                byStatus[t.Status]++
+        }
+        stats["tickets"] = len(tickets) - deleted
+        stats["deletedTickets"] = deleted
+        stats["ticketsByStatus"] = byStatus
+        return stats
+}
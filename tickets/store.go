@@ -0,0 +1,315 @@
+package tickets
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+)
+
+// Errors a TicketStore returns, which tools report as not found or as a
+// conflict.
+var (
+        ErrTicketNotFound  = errors.New("ticket not found")
+        ErrCommentNotFound = errors.New("comment not found")
+        ErrVersionConflict = errors.New("ticket version conflict")
+)
+
+// TicketFilter selects tickets in TicketStore.ListTickets. Zero-valued
+// fields match every ticket, except that soft-deleted tickets are excluded
+// unless IncludeDeleted or DeletedOnly is set.
+type TicketFilter struct {
+        Status         string
+        IncludeDeleted bool
+        DeletedOnly    bool
+        // Assignee matches case-insensitively.
+        Assignee string
+        Priority string
+        // Labels matches tickets carrying every listed (normalized) label.
+        Labels []string
+        // Query is matched as free text; see matchesQuery.
+        Query string
+        // CreatedAfter and CreatedBefore bound the creation time to
+        // [CreatedAfter, CreatedBefore).
+        CreatedAfter  time.Time
+        CreatedBefore time.Time
+        // DueBefore matches tickets with a due date before it; tickets
+        // without a due date never match.
+        DueBefore time.Time
+        // OpenOnly excludes done tickets.
+        OpenOnly bool
+}
+
+func (f TicketFilter) matches(t Ticket) bool {
+        if t.Deleted && !f.IncludeDeleted && !f.DeletedOnly {
+                return false
+        }
+        if f.DeletedOnly && !t.Deleted {
+                return false
+        }
+        if f.Status != "" && t.Status != f.Status {
+                return false
+        }
+        if f.Assignee != "" && !strings.EqualFold(t.Assignee, f.Assignee) {
+                return false
+        }
+        if f.Priority != "" && t.Priority != f.Priority {
+                return false
+        }
+        for _, label := range f.Labels {
+                if !hasLabel(t, label) {
+                        return false
+                }
+        }
+        if f.Query != "" && !matchesQuery(t, f.Query) {
+                return false
+        }
+        if !f.CreatedAfter.IsZero() && t.CreatedAt.Before(f.CreatedAfter) {
+                return false
+        }
+        if !f.CreatedBefore.IsZero() && !t.CreatedAt.Before(f.CreatedBefore) {
+                return false
+        }
+        if !f.DueBefore.IsZero() && (t.DueDate.IsZero() || !t.DueDate.Before(f.DueBefore)) {
+                return false
+        }
+        if f.OpenOnly && t.Status == "done" {
+                return false
+        }
+        return true
+}
+
+// TicketStore persists tickets. Implementations must be safe for concurrent
+// use and return ErrTicketNotFound for unknown IDs. Stored tickets start at
+// version 1.
+type TicketStore interface {
+        ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error)
+        GetTicket(ctx context.Context, id string) (Ticket, error)
+        // CreateTicket assigns the ticket an ID and creation time and returns
+        // the stored ticket.
+        CreateTicket(ctx context.Context, t Ticket) (Ticket, error)
+        // UpdateTicket replaces the stored ticket with the same ID, provided
+        // its version still equals t.Version; otherwise it returns
+        // ErrVersionConflict. The stored ticket's version is incremented.
+        UpdateTicket(ctx context.Context, t Ticket) (Ticket, error)
+
+        // AddComment assigns the comment an ID and creation time and stores
+        // it with its ticket.
+        AddComment(ctx context.Context, c Comment) (Comment, error)
+        // ListComments returns a ticket's comments, oldest first.
+        ListComments(ctx context.Context, ticketID string) ([]Comment, error)
+        DeleteComment(ctx context.Context, ticketID, commentID string) error
+}
+
+type storeContextKey struct{}
+
+// ContextWithStore returns a copy of ctx carrying s, the store the tools
+// and resources of connections whose context derives from it work on.
+func ContextWithStore(ctx context.Context, s TicketStore) context.Context {
+        return context.WithValue(ctx, storeContextKey{}, s)
+}
+
+// storeFrom returns the ticket store ctx carries.
+func storeFrom(ctx context.Context) TicketStore {
+        if s, ok := ctx.Value(storeContextKey{}).(TicketStore); ok {
+                return s
+        }
+        return noStore{}
+}
+
+var errNoStore = errors.New("no ticket store is configured")
+
+// noStore stands in for the store of a context that carries none.
+type noStore struct{}
+
+func (noStore) ListTickets(context.Context, TicketFilter) ([]Ticket, error) { return nil, errNoStore }
+func (noStore) GetTicket(context.Context, string) (Ticket, error)           { return Ticket{}, errNoStore }
+func (noStore) CreateTicket(context.Context, Ticket) (Ticket, error)        { return Ticket{}, errNoStore }
+func (noStore) UpdateTicket(context.Context, Ticket) (Ticket, error)        { return Ticket{}, errNoStore }
+func (noStore) AddComment(context.Context, Comment) (Comment, error)        { return Comment{}, errNoStore }
+func (noStore) ListComments(context.Context, string) ([]Comment, error)     { return nil, errNoStore }
+func (noStore) DeleteComment(context.Context, string, string) error         { return errNoStore }
+
+// OpenStore returns the store selected by dsn: "memory" (or empty) for the
+// in-memory demo store, a file: or bolt: path, a postgres://, redis://, or
+// mongodb:// URL, or an issue tracker such as jira:https://....
+func OpenStore(dsn string) (TicketStore, error) {
+        switch {
+        case dsn == "" || dsn == "memory":
+                return NewMemoryStore(demoTickets, SystemClock), nil
+        case strings.HasPrefix(dsn, "file:"):
+                return newFileStore(dsn)
+        case strings.HasPrefix(dsn, "bolt:"):
+                return newBoltStore(dsn)
+        case strings.HasPrefix(dsn, "github:"):
+                return newGithubStore(dsn)
+        case strings.HasPrefix(dsn, "gitlab:"):
+                return newGitlabStore(dsn)
+        case strings.HasPrefix(dsn, "jira:"):
+                return newJiraStore(dsn)
+        case strings.HasPrefix(dsn, "linear:"):
+                return newLinearStore(dsn)
+        case strings.HasPrefix(dsn, "trello:"):
+                return newTrelloStore(dsn)
+        case strings.HasPrefix(dsn, "mongodb://"), strings.HasPrefix(dsn, "mongodb+srv://"):
+                return newMongoStore(dsn)
+        case strings.HasPrefix(dsn, "rest:"):
+                return newRESTStore(dsn)
+        case strings.HasPrefix(dsn, "redis://"), strings.HasPrefix(dsn, "rediss://"):
+                return newRedisStore(dsn)
+        case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+                return newPostgresStore(dsn)
+        }
+        return nil, fmt.Errorf("unsupported store DSN: %s", dsn)
+}
+
+// MemoryStore keeps tickets in memory in creation order.
+type MemoryStore struct {
+        clock         Clock
+        mu            sync.RWMutex
+        tickets       []Ticket
+        nextID        int
+        comments      map[string][]Comment
+        nextCommentID int
+}
+
+// NewMemoryStore returns a store holding seed, which stamps tickets and
+// comments with the time clock tells.
+func NewMemoryStore(seed []Ticket, clock Clock) *MemoryStore {
+        s := &MemoryStore{
+                clock:         clock,
+                tickets:       append([]Ticket(nil), seed...),
+                nextID:        1,
+                comments:      make(map[string][]Comment),
+                nextCommentID: 1,
+        }
+        for i, t := range s.tickets {
+                s.tickets[i] = withInitialVersion(t)
+                if n := ticketNumber(t.ID); n >= s.nextID {
+                        s.nextID = n + 1
+                }
+        }
+        return s
+}
+
+// withInitialVersion versions a ticket that predates versioning.
+func withInitialVersion(t Ticket) Ticket {
+        if t.Version == 0 {
+                t.Version = 1
+        }
+        if t.UpdatedAt.IsZero() {
+                t.UpdatedAt = t.CreatedAt
+        }
+        return t
+}
+
+// ticketNumber returns the numeric part of IDs of the form "T<n>", or 0.
+func ticketNumber(id string) int {
+        n, err := strconv.Atoi(strings.TrimPrefix(id, "T"))
+        if err != nil || !strings.HasPrefix(id, "T") {
+                return 0
+        }
+        return n
+}
+
+func (s *MemoryStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        tickets := []Ticket{}
+        for _, t := range s.tickets {
+                if filter.matches(t) {
+                        tickets = append(tickets, t)
+                }
+        }
+        return tickets, nil
+}
+
+func (s *MemoryStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        for _, t := range s.tickets {
+                if t.ID == id {
+                        return t, nil
+                }
+        }
+        return Ticket{}, ErrTicketNotFound
+}
+
+func (s *MemoryStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        t.ID = fmt.Sprintf("T%d", s.nextID)
+        s.nextID++
+        t.CreatedAt = s.clock.Now().UTC()
+        t.Version = 1
+        t.UpdatedAt = t.CreatedAt
+        s.tickets = append(s.tickets, t)
+        return t, nil
+}
+
+func (s *MemoryStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        for i := range s.tickets {
+                if s.tickets[i].ID == t.ID {
+                        if s.tickets[i].Version != t.Version {
+                                return Ticket{}, ErrVersionConflict
+                        }
+                        t.Version++
+                        t.UpdatedAt = s.clock.Now().UTC()
+                        s.tickets[i] = t
+                        return t, nil
+                }
+        }
+        return Ticket{}, ErrTicketNotFound
+}
+
+func (s *MemoryStore) hasTicket(id string) bool {
+        for _, t := range s.tickets {
+                if t.ID == id {
+                        return true
+                }
+        }
+        return false
+}
+
+func (s *MemoryStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if !s.hasTicket(c.TicketID) {
+                return Comment{}, ErrTicketNotFound
+        }
+        c.ID = fmt.Sprintf("C%d", s.nextCommentID)
+        s.nextCommentID++
+        c.CreatedAt = s.clock.Now().UTC()
+        s.comments[c.TicketID] = append(s.comments[c.TicketID], c)
+        return c, nil
+}
+
+func (s *MemoryStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        if !s.hasTicket(ticketID) {
+                return nil, ErrTicketNotFound
+        }
+        return append([]Comment{}, s.comments[ticketID]...), nil
+}
+
+func (s *MemoryStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if !s.hasTicket(ticketID) {
+                return ErrTicketNotFound
+        }
+        comments := s.comments[ticketID]
+        for i, c := range comments {
+                if c.ID == commentID {
+                        s.comments[ticketID] = append(comments[:i:i], comments[i+1:]...)
+                        return nil
+                }
+        }
+        return ErrCommentNotFound
+}
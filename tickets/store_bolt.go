@@ -0,0 +1,256 @@
+package tickets
+
+import (
+        "context"
+        "encoding/binary"
+        "encoding/json"
+        "fmt"
+        "net/url"
+        "strconv"
+        "strings"
+        "time"
+
+        bolt "go.etcd.io/bbolt"
+)
+
+// boltStore keeps tickets in an embedded bbolt database. Each project gets
+// its own top-level bucket holding a "tickets" bucket keyed by ticket
+// number and a "comments" bucket with one nested bucket per ticket, so
+// listings are ordered cursor scans.
+type boltStore struct {
+        db      *bolt.DB
+        project []byte
+}
+
+var (
+        boltTicketsBucket  = []byte("tickets")
+        boltCommentsBucket = []byte("comments")
+)
+
+// newBoltStore opens the database named by a bolt: DSN such as
+// "bolt:tickets.db" or "bolt:///var/lib/mcp/tickets.db?project=web",
+// creating it and the project's buckets as needed.
+func newBoltStore(dsn string) (*boltStore, error) {
+        u, err := url.Parse(dsn)
+        if err != nil {
+                return nil, fmt.Errorf("parsing bolt DSN: %w", err)
+        }
+        path := u.Path
+        if path == "" {
+                path = u.Opaque
+        }
+        if path == "" {
+                return nil, fmt.Errorf("bolt DSN has no path: %s", dsn)
+        }
+        project := u.Query().Get("project")
+        if project == "" {
+                project = "default"
+        }
+
+        db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+        if err != nil {
+                return nil, fmt.Errorf("opening %s: %w", path, err)
+        }
+        s := &boltStore{db: db, project: []byte(project)}
+        err = db.Update(func(tx *bolt.Tx) error {
+                b, err := tx.CreateBucketIfNotExists(s.project)
+                if err != nil {
+                        return err
+                }
+                if _, err := b.CreateBucketIfNotExists(boltTicketsBucket); err != nil {
+                        return err
+                }
+                _, err = b.CreateBucketIfNotExists(boltCommentsBucket)
+                return err
+        })
+        if err != nil {
+                db.Close()
+                return nil, err
+        }
+        return s, nil
+}
+
+func (s *boltStore) Close() error {
+        return s.db.Close()
+}
+
+// boltKey encodes a sequence number so keys sort in creation order.
+func boltKey(n uint64) []byte {
+        key := make([]byte, 8)
+        binary.BigEndian.PutUint64(key, n)
+        return key
+}
+
+// boltTicketKey returns the key for a "T<n>" ID, or nil for IDs this store
+// could not have issued.
+func boltTicketKey(id string) []byte {
+        n := ticketNumber(id)
+        if n <= 0 {
+                return nil
+        }
+        return boltKey(uint64(n))
+}
+
+func (s *boltStore) buckets(tx *bolt.Tx) (tickets, comments *bolt.Bucket) {
+        b := tx.Bucket(s.project)
+        return b.Bucket(boltTicketsBucket), b.Bucket(boltCommentsBucket)
+}
+
+func (s *boltStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        tickets := []Ticket{}
+        err := s.db.View(func(tx *bolt.Tx) error {
+                b, _ := s.buckets(tx)
+                return b.ForEach(func(_, v []byte) error {
+                        var t Ticket
+                        if err := json.Unmarshal(v, &t); err != nil {
+                                return err
+                        }
+                        if filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                        return nil
+                })
+        })
+        return tickets, err
+}
+
+func getBoltTicket(b *bolt.Bucket, id string) (Ticket, error) {
+        key := boltTicketKey(id)
+        if key == nil {
+                return Ticket{}, ErrTicketNotFound
+        }
+        v := b.Get(key)
+        if v == nil {
+                return Ticket{}, ErrTicketNotFound
+        }
+        var t Ticket
+        err := json.Unmarshal(v, &t)
+        return t, err
+}
+
+func (s *boltStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        var t Ticket
+        err := s.db.View(func(tx *bolt.Tx) error {
+                b, _ := s.buckets(tx)
+                var err error
+                t, err = getBoltTicket(b, id)
+                return err
+        })
+        return t, err
+}
+
+func (s *boltStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        err := s.db.Update(func(tx *bolt.Tx) error {
+                b, _ := s.buckets(tx)
+                n, err := b.NextSequence()
+                if err != nil {
+                        return err
+                }
+                t.ID = "T" + strconv.FormatUint(n, 10)
+                t.CreatedAt = time.Now().UTC()
+                t.Version = 1
+                t.UpdatedAt = t.CreatedAt
+                data, err := json.Marshal(t)
+                if err != nil {
+                        return err
+                }
+                return b.Put(boltKey(n), data)
+        })
+        if err != nil {
+                return Ticket{}, err
+        }
+        return t, nil
+}
+
+func (s *boltStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        err := s.db.Update(func(tx *bolt.Tx) error {
+                b, _ := s.buckets(tx)
+                current, err := getBoltTicket(b, t.ID)
+                if err != nil {
+                        return err
+                }
+                if current.Version != t.Version {
+                        return ErrVersionConflict
+                }
+                t.Version++
+                t.UpdatedAt = time.Now().UTC()
+                data, err := json.Marshal(t)
+                if err != nil {
+                        return err
+                }
+                return b.Put(boltTicketKey(t.ID), data)
+        })
+        if err != nil {
+                return Ticket{}, err
+        }
+        return t, nil
+}
+
+func (s *boltStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        err := s.db.Update(func(tx *bolt.Tx) error {
+                tickets, comments := s.buckets(tx)
+                if _, err := getBoltTicket(tickets, c.TicketID); err != nil {
+                        return err
+                }
+                n, err := comments.NextSequence()
+                if err != nil {
+                        return err
+                }
+                b, err := comments.CreateBucketIfNotExists([]byte(c.TicketID))
+                if err != nil {
+                        return err
+                }
+                c.ID = "C" + strconv.FormatUint(n, 10)
+                c.CreatedAt = time.Now().UTC()
+                data, err := json.Marshal(c)
+                if err != nil {
+                        return err
+                }
+                return b.Put(boltKey(n), data)
+        })
+        if err != nil {
+                return Comment{}, err
+        }
+        return c, nil
+}
+
+func (s *boltStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        list := []Comment{}
+        err := s.db.View(func(tx *bolt.Tx) error {
+                tickets, comments := s.buckets(tx)
+                if _, err := getBoltTicket(tickets, ticketID); err != nil {
+                        return err
+                }
+                b := comments.Bucket([]byte(ticketID))
+                if b == nil {
+                        return nil
+                }
+                return b.ForEach(func(_, v []byte) error {
+                        var c Comment
+                        if err := json.Unmarshal(v, &c); err != nil {
+                                return err
+                        }
+                        list = append(list, c)
+                        return nil
+                })
+        })
+        if err != nil {
+                return nil, err
+        }
+        return list, nil
+}
+
+func (s *boltStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        return s.db.Update(func(tx *bolt.Tx) error {
+                tickets, comments := s.buckets(tx)
+                if _, err := getBoltTicket(tickets, ticketID); err != nil {
+                        return err
+                }
+                b := comments.Bucket([]byte(ticketID))
+                n, err := strconv.ParseUint(strings.TrimPrefix(commentID, "C"), 10, 64)
+                if b == nil || err != nil || !strings.HasPrefix(commentID, "C") || b.Get(boltKey(n)) == nil {
+                        return ErrCommentNotFound
+                }
+                return b.Delete(boltKey(n))
+        })
+}
@@ -0,0 +1,274 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "net/url"
+        "os"
+        "path/filepath"
+        "strings"
+        "sync"
+        "time"
+
+        "gopkg.in/yaml.v3"
+        "mcp-server/mcp"
+)
+
+// memorySnapshot is the persisted form of a MemoryStore.
+type memorySnapshot struct {
+        Tickets       []Ticket             `json:"tickets"`
+        Comments      map[string][]Comment `json:"comments,omitempty"`
+        NextID        int                  `json:"nextId"`
+        NextCommentID int                  `json:"nextCommentId"`
+}
+
+func (s *MemoryStore) snapshot() memorySnapshot {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        snap := memorySnapshot{
+                Tickets:       append([]Ticket{}, s.tickets...),
+                Comments:      make(map[string][]Comment, len(s.comments)),
+                NextID:        s.nextID,
+                NextCommentID: s.nextCommentID,
+        }
+        for id, comments := range s.comments {
+                snap.Comments[id] = append([]Comment(nil), comments...)
+        }
+        return snap
+}
+
+func (s *MemoryStore) restore(snap memorySnapshot) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        s.tickets = make([]Ticket, 0, len(snap.Tickets))
+        for _, t := range snap.Tickets {
+                s.tickets = append(s.tickets, withInitialVersion(t))
+        }
+        s.comments = make(map[string][]Comment, len(snap.Comments))
+        for id, comments := range snap.Comments {
+                s.comments[id] = append([]Comment(nil), comments...)
+        }
+        s.nextID, s.nextCommentID = max(snap.NextID, 1), max(snap.NextCommentID, 1)
+        for _, t := range s.tickets {
+                if n := ticketNumber(t.ID); n >= s.nextID {
+                        s.nextID = n + 1
+                }
+        }
+}
+
+// encodeSnapshot renders snap as YAML for .yaml/.yml paths and as indented
+// JSON otherwise. YAML goes through JSON first so both formats share the
+// json field names.
+func encodeSnapshot(path string, snap memorySnapshot) ([]byte, error) {
+        data, err := json.MarshalIndent(snap, "", "  ")
+        if err != nil || !isYAMLPath(path) {
+                return data, err
+        }
+        var doc interface{}
+        if err := json.Unmarshal(data, &doc); err != nil {
+                return nil, err
+        }
+        return yaml.Marshal(doc)
+}
+
+func decodeSnapshot(path string, data []byte) (memorySnapshot, error) {
+        var snap memorySnapshot
+        if isYAMLPath(path) {
+                var doc interface{}
+                if err := yaml.Unmarshal(data, &doc); err != nil {
+                        return snap, err
+                }
+                var err error
+                if data, err = json.Marshal(doc); err != nil {
+                        return snap, err
+                }
+        }
+        err := json.Unmarshal(data, &snap)
+        return snap, err
+}
+
+func isYAMLPath(path string) bool {
+        ext := strings.ToLower(filepath.Ext(path))
+        return ext == ".yaml" || ext == ".yml"
+}
+
+// writeFileAtomic replaces path with data by writing a temporary file in the
+// same directory and renaming it over path, so readers never observe a
+// partially written file.
+func writeFileAtomic(path string, data []byte) error {
+        tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+        if err != nil {
+                return err
+        }
+        defer os.Remove(tmp.Name())
+        if _, err := tmp.Write(data); err != nil {
+                tmp.Close()
+                return err
+        }
+        if err := tmp.Sync(); err != nil {
+                tmp.Close()
+                return err
+        }
+        if err := tmp.Close(); err != nil {
+                return err
+        }
+        return os.Rename(tmp.Name(), path)
+}
+
+// readSnapshotFile loads a snapshot written by writeFileAtomic. A missing
+// file yields ok == false.
+func readSnapshotFile(path string) (snap memorySnapshot, ok bool, err error) {
+        data, err := os.ReadFile(path)
+        if os.IsNotExist(err) {
+                return snap, false, nil
+        }
+        if err != nil {
+                return snap, false, err
+        }
+        snap, err = decodeSnapshot(path, data)
+        if err != nil {
+                return snap, false, fmt.Errorf("reading %s: %w", path, err)
+        }
+        return snap, true, nil
+}
+
+// fileStore is a MemoryStore persisted to a single JSON or YAML file. With
+// no flush interval every change is written through; otherwise changes are
+// written at most once per interval and on Close.
+type fileStore struct {
+        *MemoryStore
+        path          string
+        flushInterval time.Duration
+
+        flushMu sync.Mutex
+        dirty   bool
+        done    chan struct{}
+}
+
+// newFileStore opens the store described by a file: DSN such as
+// "file:tickets.json" or "file:///var/lib/mcp/tickets.yaml?flush=5s". A
+// missing file starts an empty store.
+func newFileStore(dsn string) (*fileStore, error) {
+        u, err := url.Parse(dsn)
+        if err != nil {
+                return nil, fmt.Errorf("parsing file DSN: %w", err)
+        }
+        path := u.Path
+        if path == "" {
+                path = u.Opaque
+        }
+        if path == "" {
+                return nil, fmt.Errorf("file DSN has no path: %s", dsn)
+        }
+        var flushInterval time.Duration
+        if flush := u.Query().Get("flush"); flush != "" {
+                if flushInterval, err = time.ParseDuration(flush); err != nil || flushInterval < 0 {
+                        return nil, fmt.Errorf("invalid flush interval: %s", flush)
+                }
+        }
+
+        s := &fileStore{
+                MemoryStore:   NewMemoryStore(nil, SystemClock),
+                path:          path,
+                flushInterval: flushInterval,
+                done:          make(chan struct{}),
+        }
+        snap, ok, err := readSnapshotFile(path)
+        if err != nil {
+                return nil, err
+        }
+        if ok {
+                s.restore(snap)
+        }
+        if flushInterval > 0 {
+                go s.flushLoop()
+        }
+        return s, nil
+}
+
+func (s *fileStore) flush() error {
+        s.flushMu.Lock()
+        defer s.flushMu.Unlock()
+        data, err := encodeSnapshot(s.path, s.snapshot())
+        if err != nil {
+                return err
+        }
+        if err := writeFileAtomic(s.path, data); err != nil {
+                return err
+        }
+        s.dirty = false
+        return nil
+}
+
+// changed persists a mutation, either immediately or at the next periodic
+// flush.
+func (s *fileStore) changed() error {
+        if s.flushInterval == 0 {
+                return s.flush()
+        }
+        s.flushMu.Lock()
+        s.dirty = true
+        s.flushMu.Unlock()
+        return nil
+}
+
+func (s *fileStore) flushLoop() {
+        ticker := time.NewTicker(s.flushInterval)
+        defer ticker.Stop()
+        for {
+                select {
+                case <-ticker.C:
+                        s.flushMu.Lock()
+                        dirty := s.dirty
+                        s.flushMu.Unlock()
+                        if dirty {
+                                if err := s.flush(); err != nil {
+                                        mcp.LogAll(context.Background(), mcp.LevelError, "Flushing %s: %v", s.path, err)
+                                }
+                        }
+                case <-s.done:
+                        return
+                }
+        }
+}
+
+// Close stops periodic flushing and writes any pending changes.
+func (s *fileStore) Close() error {
+        if s.flushInterval == 0 {
+                return nil
+        }
+        close(s.done)
+        return s.flush()
+}
+
+func (s *fileStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        t, err := s.MemoryStore.CreateTicket(ctx, t)
+        if err != nil {
+                return t, err
+        }
+        return t, s.changed()
+}
+
+func (s *fileStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        t, err := s.MemoryStore.UpdateTicket(ctx, t)
+        if err != nil {
+                return t, err
+        }
+        return t, s.changed()
+}
+
+func (s *fileStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        c, err := s.MemoryStore.AddComment(ctx, c)
+        if err != nil {
+                return c, err
+        }
+        return c, s.changed()
+}
+
+func (s *fileStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        if err := s.MemoryStore.DeleteComment(ctx, ticketID, commentID); err != nil {
+                return err
+        }
+        return s.changed()
+}
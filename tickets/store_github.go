@@ -0,0 +1,341 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "net/http"
+        "net/url"
+        "strconv"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// githubStore maps tickets onto the issues of one GitHub repository. Ticket
+// IDs are issue numbers. Closed issues are done; open issues are pending if
+// they carry the pending label and todo otherwise. Priorities are kept as
+// "priority:P0".."priority:P3" labels. Pull requests are skipped.
+type githubStore struct {
+        api *remoteAPI
+        // statusLabels maps the open statuses to the labels that mark them.
+        statusLabels map[string]string
+}
+
+// newGithubStore opens a store described by a DSN such as
+// "github:https://api.github.com?repo=acme/ops". Use the instance's API URL
+// (e.g. https://github.example.com/api/v3) for GitHub Enterprise Server.
+// Options: repo (required), and todo/pending to rename the status labels
+// (default status:todo and status:pending). The token comes from
+// GITHUB_TOKEN. Requests are paced by the X-RateLimit-* response headers.
+func newGithubStore(dsn string) (*githubStore, error) {
+        apiURL, opts, err := parseRemoteDSN(dsn, "github")
+        if err != nil {
+                return nil, err
+        }
+        repo := opts.Get("repo")
+        owner, name, ok := strings.Cut(repo, "/")
+        if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+                return nil, fmt.Errorf("github DSN needs a repo=owner/name option: %s", dsn)
+        }
+        s := &githubStore{statusLabels: map[string]string{
+                "todo":    "status:todo",
+                "pending": "status:pending",
+        }}
+        for status := range s.statusLabels {
+                if label := strings.TrimSpace(opts.Get(status)); label != "" {
+                        s.statusLabels[status] = label
+                }
+        }
+
+        s.api = newRemoteAPI(apiURL+"/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name), func(req *http.Request) {
+                req.Header.Set("Accept", "application/vnd.github+json")
+                req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+                if token := mcp.Secret("GITHUB_TOKEN"); token != "" {
+                        req.Header.Set("Authorization", "Bearer "+token)
+                }
+        })
+        s.api.pacer = &ratePacer{}
+        if err := s.api.do(context.Background(), "GET", "", nil, nil, nil); err != nil {
+                return nil, fmt.Errorf("connecting to github: %w", err)
+        }
+        return s, nil
+}
+
+type githubIssue struct {
+        Number int    `json:"number"`
+        Title  string `json:"title"`
+        Body   string `json:"body"`
+        State  string `json:"state"`
+        Labels []struct {
+                Name string `json:"name"`
+        } `json:"labels"`
+        Assignees []struct {
+                Login string `json:"login"`
+        } `json:"assignees"`
+        CreatedAt   time.Time `json:"created_at"`
+        UpdatedAt   time.Time `json:"updated_at"`
+        PullRequest *struct{} `json:"pull_request"`
+}
+
+func (s *githubStore) ticket(issue githubIssue) Ticket {
+        t := Ticket{
+                ID:          strconv.Itoa(issue.Number),
+                Title:       issue.Title,
+                Status:      "todo",
+                Description: issue.Body,
+                CreatedAt:   issue.CreatedAt.UTC(),
+                UpdatedAt:   issue.UpdatedAt.UTC(),
+                Version:     remoteVersion(issue.UpdatedAt),
+        }
+        if issue.State == "closed" {
+                t.Status = "done"
+        }
+        var labels []string
+        for _, l := range issue.Labels {
+                switch {
+                case strings.EqualFold(l.Name, s.statusLabels["todo"]):
+                case strings.EqualFold(l.Name, s.statusLabels["pending"]):
+                        if t.Status != "done" {
+                                t.Status = "pending"
+                        }
+                case isPriorityLabel(l.Name):
+                        t.Priority, _ = normalizePriority(l.Name[len(priorityLabelPrefix):])
+                default:
+                        labels = append(labels, l.Name)
+                }
+        }
+        t.Labels, t.Deleted = splitDeletedLabel(labels)
+        if len(issue.Assignees) > 0 {
+                t.Assignee = issue.Assignees[0].Login
+        }
+        return t
+}
+
+// issuePath returns the API path of the issue with the given ticket ID.
+func issuePath(id string) (string, error) {
+        if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+                return "", ErrTicketNotFound
+        }
+        return "/issues/" + id, nil
+}
+
+func (s *githubStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        query := url.Values{
+                "state":     {"all"},
+                "sort":      {"created"},
+                "direction": {"asc"},
+                "per_page":  {"100"},
+        }
+        switch filter.Status {
+        case "done":
+                query.Set("state", "closed")
+        case "todo":
+                query.Set("state", "open")
+        case "pending":
+                query.Set("state", "open")
+                query.Set("labels", s.statusLabels["pending"])
+        }
+        if filter.OpenOnly {
+                query.Set("state", "open")
+        }
+        if len(filter.Labels) > 0 {
+                labels := filter.Labels
+                if l := query.Get("labels"); l != "" {
+                        labels = append([]string{l}, labels...)
+                }
+                query.Set("labels", strings.Join(labels, ","))
+        }
+        if filter.Assignee != "" {
+                query.Set("assignee", filter.Assignee)
+        }
+
+        tickets := []Ticket{}
+        for page := 1; ; page++ {
+                query.Set("page", strconv.Itoa(page))
+                var issues []githubIssue
+                if err := s.api.do(ctx, "GET", "/issues", query, nil, &issues); err != nil {
+                        return nil, err
+                }
+                for _, issue := range issues {
+                        if issue.PullRequest != nil {
+                                continue
+                        }
+                        if t := s.ticket(issue); filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+                if len(issues) < 100 {
+                        return tickets, nil
+                }
+        }
+}
+
+func (s *githubStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        path, err := issuePath(id)
+        if err != nil {
+                return Ticket{}, err
+        }
+        var issue githubIssue
+        if err := s.api.do(ctx, "GET", path, nil, nil, &issue); err != nil {
+                return Ticket{}, err
+        }
+        if issue.PullRequest != nil {
+                return Ticket{}, ErrTicketNotFound
+        }
+        return s.ticket(issue), nil
+}
+
+// fields builds the editable issue fields for t.
+func (s *githubStore) fields(t Ticket) (map[string]interface{}, error) {
+        if !t.DueDate.IsZero() {
+                return nil, fmt.Errorf("GitHub issues have no due dates")
+        }
+        labels := withDeletedLabel(t)
+        state := "open"
+        if t.Status == "done" {
+                state = "closed"
+        } else if label := s.statusLabels[t.Status]; label != "" {
+                labels = append(labels, label)
+        }
+        if t.Priority != "" {
+                labels = append(labels, priorityLabelPrefix+t.Priority)
+        }
+        assignees := []string{}
+        if t.Assignee != "" {
+                assignees = append(assignees, t.Assignee)
+        }
+        return map[string]interface{}{
+                "title":     t.Title,
+                "body":      t.Description,
+                "state":     state,
+                "labels":    nonNilLabels(labels),
+                "assignees": assignees,
+        }, nil
+}
+
+func (s *githubStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        fields, err := s.fields(t)
+        if err != nil {
+                return Ticket{}, err
+        }
+        // Issues are always created open.
+        state := fields["state"]
+        delete(fields, "state")
+
+        var created githubIssue
+        if err := s.api.do(ctx, "POST", "/issues", nil, fields, &created); err != nil {
+                return Ticket{}, err
+        }
+        if state == "closed" {
+                if err := s.api.do(ctx, "PATCH", "/issues/"+strconv.Itoa(created.Number), nil, map[string]string{"state": "closed"}, &created); err != nil {
+                        return Ticket{}, err
+                }
+        }
+        return s.ticket(created), nil
+}
+
+// UpdateTicket edits the issue. As with the Jira store, the version check
+// compares last-modified times and isn't atomic with the update; GitHub
+// timestamps have one-second resolution.
+func (s *githubStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        current, err := s.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        fields, err := s.fields(t)
+        if err != nil {
+                return Ticket{}, err
+        }
+        var updated githubIssue
+        if err := s.api.do(ctx, "PATCH", "/issues/"+t.ID, nil, fields, &updated); err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(updated), nil
+}
+
+type githubComment struct {
+        ID   int64  `json:"id"`
+        Body string `json:"body"`
+        User struct {
+                Login string `json:"login"`
+        } `json:"user"`
+        IssueURL  string    `json:"issue_url"`
+        CreatedAt time.Time `json:"created_at"`
+}
+
+func (c githubComment) comment(ticketID string) Comment {
+        return Comment{
+                ID:        strconv.FormatInt(c.ID, 10),
+                TicketID:  ticketID,
+                Author:    c.User.Login,
+                Body:      c.Body,
+                CreatedAt: c.CreatedAt.UTC(),
+        }
+}
+
+func (s *githubStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        path, err := issuePath(c.TicketID)
+        if err != nil {
+                return Comment{}, err
+        }
+        body := c.Body
+        if c.Author != "" && c.Author != defaultCommentAuthor {
+                body = c.Author + ": " + body
+        }
+        var created githubComment
+        if err := s.api.do(ctx, "POST", path+"/comments", nil, map[string]string{"body": body}, &created); err != nil {
+                return Comment{}, err
+        }
+        return created.comment(c.TicketID), nil
+}
+
+func (s *githubStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        path, err := issuePath(ticketID)
+        if err != nil {
+                return nil, err
+        }
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return nil, err
+        }
+        comments := []Comment{}
+        for page := 1; ; page++ {
+                var batch []githubComment
+                query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+                if err := s.api.do(ctx, "GET", path+"/comments", query, nil, &batch); err != nil {
+                        return nil, err
+                }
+                for _, c := range batch {
+                        comments = append(comments, c.comment(ticketID))
+                }
+                if len(batch) < 100 {
+                        return comments, nil
+                }
+        }
+}
+
+// DeleteComment deletes a comment after checking that it belongs to the
+// ticket, since GitHub addresses comments by ID alone.
+func (s *githubStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return err
+        }
+        if _, err := strconv.ParseInt(commentID, 10, 64); err != nil {
+                return ErrCommentNotFound
+        }
+        var c githubComment
+        err := s.api.do(ctx, "GET", "/issues/comments/"+commentID, nil, nil, &c)
+        if err == ErrTicketNotFound || (err == nil && !strings.HasSuffix(c.IssueURL, "/issues/"+ticketID)) {
+                return ErrCommentNotFound
+        }
+        if err != nil {
+                return err
+        }
+        return s.api.do(ctx, "DELETE", "/issues/comments/"+commentID, nil, nil, nil)
+}
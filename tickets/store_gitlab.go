@@ -0,0 +1,387 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "net/http"
+        "net/url"
+        "strconv"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// gitlabStore maps tickets onto the issues of one GitLab project, on
+// gitlab.com or a self-managed instance. Ticket IDs are issue IIDs. Closed
+// issues are done; open issues are pending if they carry the pending label
+// and todo otherwise. Priorities are kept as "priority:P0".."priority:P3"
+// labels.
+type gitlabStore struct {
+        // api addresses the project; instance addresses the rest of the API.
+        api      *remoteAPI
+        instance *remoteAPI
+        // statusLabels maps the open statuses to the labels that mark them.
+        statusLabels map[string]string
+        // milestone, if set, scopes the store to one milestone: listings only
+        // include its issues and created issues are added to it.
+        milestone   string
+        milestoneID int
+}
+
+// newGitlabStore opens a store described by a DSN such as
+// "gitlab:https://gitlab.example.com?project=group/app". Options: project
+// (the project's path or numeric ID, required), milestone (a milestone
+// title), and todo/pending to rename the status labels (default
+// status::todo and status::pending). The token comes from GITLAB_TOKEN.
+// Requests are paced by the RateLimit-* response headers.
+func newGitlabStore(dsn string) (*gitlabStore, error) {
+        instanceURL, opts, err := parseRemoteDSN(dsn, "gitlab")
+        if err != nil {
+                return nil, err
+        }
+        project := strings.Trim(opts.Get("project"), "/")
+        if project == "" {
+                return nil, fmt.Errorf("gitlab DSN needs a project: %s", dsn)
+        }
+        s := &gitlabStore{
+                statusLabels: map[string]string{
+                        "todo":    "status::todo",
+                        "pending": "status::pending",
+                },
+                milestone: opts.Get("milestone"),
+        }
+        for status := range s.statusLabels {
+                if label := strings.TrimSpace(opts.Get(status)); label != "" {
+                        s.statusLabels[status] = label
+                }
+        }
+
+        authorize := func(req *http.Request) {
+                if token := mcp.Secret("GITLAB_TOKEN"); token != "" {
+                        req.Header.Set("PRIVATE-TOKEN", token)
+                }
+        }
+        pacer := &ratePacer{}
+        s.instance = newRemoteAPI(instanceURL+"/api/v4", authorize)
+        s.instance.pacer = pacer
+        s.api = newRemoteAPI(s.instance.baseURL+"/projects/"+url.PathEscape(project), authorize)
+        s.api.pacer = pacer
+        if err := s.api.do(context.Background(), "GET", "", nil, nil, nil); err != nil {
+                return nil, fmt.Errorf("connecting to gitlab: %w", err)
+        }
+        if s.milestone != "" {
+                var milestones []struct {
+                        ID int `json:"id"`
+                }
+                if err := s.api.do(context.Background(), "GET", "/milestones", url.Values{"title": {s.milestone}}, nil, &milestones); err != nil {
+                        return nil, fmt.Errorf("looking up gitlab milestone: %w", err)
+                }
+                if len(milestones) == 0 {
+                        return nil, fmt.Errorf("the gitlab project has no milestone %q", s.milestone)
+                }
+                s.milestoneID = milestones[0].ID
+        }
+        return s, nil
+}
+
+type gitlabIssue struct {
+        IID         int      `json:"iid"`
+        Title       string   `json:"title"`
+        Description string   `json:"description"`
+        State       string   `json:"state"`
+        Labels      []string `json:"labels"`
+        Assignees   []struct {
+                Username string `json:"username"`
+        } `json:"assignees"`
+        CreatedAt time.Time `json:"created_at"`
+        UpdatedAt time.Time `json:"updated_at"`
+        DueDate   string    `json:"due_date"`
+}
+
+func (s *gitlabStore) ticket(issue gitlabIssue) Ticket {
+        t := Ticket{
+                ID:          strconv.Itoa(issue.IID),
+                Title:       issue.Title,
+                Status:      "todo",
+                Description: issue.Description,
+                CreatedAt:   issue.CreatedAt.UTC(),
+                UpdatedAt:   issue.UpdatedAt.UTC(),
+                Version:     remoteVersion(issue.UpdatedAt),
+        }
+        if issue.State == "closed" {
+                t.Status = "done"
+        }
+        var labels []string
+        for _, l := range issue.Labels {
+                switch {
+                case strings.EqualFold(l, s.statusLabels["todo"]):
+                case strings.EqualFold(l, s.statusLabels["pending"]):
+                        if t.Status != "done" {
+                                t.Status = "pending"
+                        }
+                case isPriorityLabel(l):
+                        t.Priority, _ = normalizePriority(l[len(priorityLabelPrefix):])
+                default:
+                        labels = append(labels, l)
+                }
+        }
+        t.Labels, t.Deleted = splitDeletedLabel(labels)
+        if len(issue.Assignees) > 0 {
+                t.Assignee = issue.Assignees[0].Username
+        }
+        if issue.DueDate != "" {
+                if due, err := time.Parse(time.DateOnly, issue.DueDate); err == nil {
+                        t.DueDate = endOfDay(due, time.UTC)
+                }
+        }
+        return t
+}
+
+// gitlabIssuePath returns the API path of the issue with the given ticket
+// ID.
+func gitlabIssuePath(id string) (string, error) {
+        if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+                return "", ErrTicketNotFound
+        }
+        return "/issues/" + id, nil
+}
+
+func (s *gitlabStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        query := url.Values{
+                "state":    {"all"},
+                "order_by": {"created_at"},
+                "sort":     {"asc"},
+                "per_page": {"100"},
+        }
+        labels := append([]string{}, filter.Labels...)
+        switch filter.Status {
+        case "done":
+                query.Set("state", "closed")
+        case "todo":
+                query.Set("state", "opened")
+        case "pending":
+                query.Set("state", "opened")
+                labels = append(labels, s.statusLabels["pending"])
+        }
+        if filter.OpenOnly {
+                query.Set("state", "opened")
+        }
+        if len(labels) > 0 {
+                query.Set("labels", strings.Join(labels, ","))
+        }
+        if filter.Assignee != "" {
+                query.Set("assignee_username", filter.Assignee)
+        }
+        if s.milestone != "" {
+                query.Set("milestone", s.milestone)
+        }
+
+        tickets := []Ticket{}
+        for page := 1; ; page++ {
+                query.Set("page", strconv.Itoa(page))
+                var issues []gitlabIssue
+                if err := s.api.do(ctx, "GET", "/issues", query, nil, &issues); err != nil {
+                        return nil, err
+                }
+                for _, issue := range issues {
+                        if t := s.ticket(issue); filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+                if len(issues) < 100 {
+                        return tickets, nil
+                }
+        }
+}
+
+func (s *gitlabStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        path, err := gitlabIssuePath(id)
+        if err != nil {
+                return Ticket{}, err
+        }
+        var issue gitlabIssue
+        if err := s.api.do(ctx, "GET", path, nil, nil, &issue); err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(issue), nil
+}
+
+// userID resolves an assignee to a GitLab user by username.
+func (s *gitlabStore) userID(ctx context.Context, username string) (int, error) {
+        var users []struct {
+                ID int `json:"id"`
+        }
+        if err := s.instance.do(ctx, "GET", "/users", url.Values{"username": {username}}, nil, &users); err != nil {
+                return 0, err
+        }
+        if len(users) == 0 {
+                return 0, fmt.Errorf("no GitLab user is called %q", username)
+        }
+        return users[0].ID, nil
+}
+
+// fields builds the editable issue fields for t.
+func (s *gitlabStore) fields(ctx context.Context, t Ticket, assigneeChanged bool) (map[string]interface{}, error) {
+        labels := withDeletedLabel(t)
+        if label := s.statusLabels[t.Status]; label != "" {
+                labels = append(labels, label)
+        }
+        if t.Priority != "" {
+                labels = append(labels, priorityLabelPrefix+t.Priority)
+        }
+        fields := map[string]interface{}{
+                "title":       t.Title,
+                "description": t.Description,
+                "labels":      strings.Join(labels, ","),
+                "due_date":    nil,
+        }
+        if !t.DueDate.IsZero() {
+                fields["due_date"] = t.DueDate.Format(time.DateOnly)
+        }
+        if assigneeChanged {
+                assignees := []int{}
+                if t.Assignee != "" {
+                        id, err := s.userID(ctx, t.Assignee)
+                        if err != nil {
+                                return nil, err
+                        }
+                        assignees = append(assignees, id)
+                }
+                fields["assignee_ids"] = assignees
+        }
+        return fields, nil
+}
+
+func (s *gitlabStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        fields, err := s.fields(ctx, t, t.Assignee != "")
+        if err != nil {
+                return Ticket{}, err
+        }
+        if s.milestoneID != 0 {
+                fields["milestone_id"] = s.milestoneID
+        }
+        var created gitlabIssue
+        if err := s.api.do(ctx, "POST", "/issues", nil, fields, &created); err != nil {
+                return Ticket{}, err
+        }
+        if t.Status == "done" {
+                path := "/issues/" + strconv.Itoa(created.IID)
+                if err := s.api.do(ctx, "PUT", path, nil, map[string]string{"state_event": "close"}, &created); err != nil {
+                        return Ticket{}, err
+                }
+        }
+        return s.ticket(created), nil
+}
+
+// UpdateTicket edits the issue, closing or reopening it if its status moved
+// to or from done. As with the other tracker stores, the version check
+// compares last-modified times and isn't atomic with the update.
+func (s *gitlabStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        current, err := s.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        fields, err := s.fields(ctx, t, current.Assignee != t.Assignee)
+        if err != nil {
+                return Ticket{}, err
+        }
+        switch {
+        case t.Status == "done" && current.Status != "done":
+                fields["state_event"] = "close"
+        case t.Status != "done" && current.Status == "done":
+                fields["state_event"] = "reopen"
+        }
+        var updated gitlabIssue
+        if err := s.api.do(ctx, "PUT", "/issues/"+t.ID, nil, fields, &updated); err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(updated), nil
+}
+
+type gitlabNote struct {
+        ID     int    `json:"id"`
+        Body   string `json:"body"`
+        System bool   `json:"system"`
+        Author struct {
+                Username string `json:"username"`
+        } `json:"author"`
+        CreatedAt time.Time `json:"created_at"`
+}
+
+func (n gitlabNote) comment(ticketID string) Comment {
+        return Comment{
+                ID:        strconv.Itoa(n.ID),
+                TicketID:  ticketID,
+                Author:    n.Author.Username,
+                Body:      n.Body,
+                CreatedAt: n.CreatedAt.UTC(),
+        }
+}
+
+func (s *gitlabStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        path, err := gitlabIssuePath(c.TicketID)
+        if err != nil {
+                return Comment{}, err
+        }
+        body := c.Body
+        if c.Author != "" && c.Author != defaultCommentAuthor {
+                body = c.Author + ": " + body
+        }
+        var created gitlabNote
+        if err := s.api.do(ctx, "POST", path+"/notes", nil, map[string]string{"body": body}, &created); err != nil {
+                return Comment{}, err
+        }
+        return created.comment(c.TicketID), nil
+}
+
+// ListComments returns the issue's notes, leaving out the system notes
+// GitLab adds for label, assignee, and state changes.
+func (s *gitlabStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        path, err := gitlabIssuePath(ticketID)
+        if err != nil {
+                return nil, err
+        }
+        comments := []Comment{}
+        for page := 1; ; page++ {
+                var notes []gitlabNote
+                query := url.Values{"order_by": {"created_at"}, "sort": {"asc"}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+                if err := s.api.do(ctx, "GET", path+"/notes", query, nil, &notes); err != nil {
+                        return nil, err
+                }
+                for _, n := range notes {
+                        if !n.System {
+                                comments = append(comments, n.comment(ticketID))
+                        }
+                }
+                if len(notes) < 100 {
+                        return comments, nil
+                }
+        }
+}
+
+func (s *gitlabStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        path, err := gitlabIssuePath(ticketID)
+        if err != nil {
+                return err
+        }
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return err
+        }
+        if _, err := strconv.Atoi(commentID); err != nil {
+                return ErrCommentNotFound
+        }
+        // Notes are addressed through their issue, so a note on another issue
+        // is not found.
+        err = s.api.do(ctx, "DELETE", path+"/notes/"+commentID, nil, nil, nil)
+        if err == ErrTicketNotFound {
+                return ErrCommentNotFound
+        }
+        return err
+}
@@ -0,0 +1,433 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "net/http"
+        "net/url"
+        "slices"
+        "strings"
+        "sync/atomic"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// jiraTimeLayout is the timestamp format of Jira's REST API.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+const jiraIssueFields = "summary,status,description,priority,assignee,labels,created,updated,duedate,project"
+
+// jiraStore maps tickets onto the issues of one Jira project. Ticket IDs are
+// issue keys. Statuses map onto Jira's status categories (To Do, In
+// Progress, Done) unless explicit status names are configured, and status
+// changes are made by taking a workflow transition into a matching status.
+type jiraStore struct {
+        api       *remoteAPI
+        project   string
+        jql       string
+        issueType string
+        // statusNames maps ticket statuses to the Jira status names that count
+        // as them; empty means map by status category.
+        statusNames map[string][]string
+        // offsetSearch is set once the enhanced search endpoint, which only
+        // Jira Cloud has, turns out to be missing; searches then use the
+        // older one, paged by offset, which Data Center has.
+        offsetSearch atomic.Bool
+}
+
+// newJiraStore opens a store described by a DSN such as
+// "jira:https://acme.atlassian.net?project=OPS". Options: project
+// (required), jql (an extra clause ANDed into every search), issuetype
+// (default Task), and todo/pending/done (comma-separated Jira status
+// names). Credentials come from JIRA_EMAIL and JIRA_API_TOKEN (Jira Cloud)
+// or JIRA_TOKEN (a Data Center personal access token).
+func newJiraStore(dsn string) (*jiraStore, error) {
+        baseURL, opts, err := parseRemoteDSN(dsn, "jira")
+        if err != nil {
+                return nil, err
+        }
+        s := &jiraStore{
+                project:     opts.Get("project"),
+                jql:         opts.Get("jql"),
+                issueType:   opts.Get("issuetype"),
+                statusNames: make(map[string][]string),
+        }
+        if s.project == "" {
+                return nil, fmt.Errorf("jira DSN needs a project: %s", dsn)
+        }
+        if s.issueType == "" {
+                s.issueType = "Task"
+        }
+        for _, status := range ticketStatuses() {
+                for _, name := range strings.Split(opts.Get(status), ",") {
+                        if name = strings.TrimSpace(name); name != "" {
+                                s.statusNames[status] = append(s.statusNames[status], name)
+                        }
+                }
+        }
+
+        s.api = newRemoteAPI(baseURL, func(req *http.Request) {
+                if token := mcp.Secret("JIRA_TOKEN"); token != "" {
+                        req.Header.Set("Authorization", "Bearer "+token)
+                } else if email := mcp.Secret("JIRA_EMAIL"); email != "" {
+                        req.SetBasicAuth(email, mcp.Secret("JIRA_API_TOKEN"))
+                }
+        })
+        if err := s.api.do(context.Background(), "GET", "/rest/api/2/project/"+url.PathEscape(s.project), nil, nil, nil); err != nil {
+                return nil, fmt.Errorf("connecting to jira: %w", err)
+        }
+        return s, nil
+}
+
+type jiraIssue struct {
+        Key    string `json:"key"`
+        Fields struct {
+                Summary     string `json:"summary"`
+                Description string `json:"description"`
+                Status      struct {
+                        Name           string `json:"name"`
+                        StatusCategory struct {
+                                Key string `json:"key"`
+                        } `json:"statusCategory"`
+                } `json:"status"`
+                Priority *struct {
+                        Name string `json:"name"`
+                } `json:"priority"`
+                Assignee *struct {
+                        AccountID   string `json:"accountId"`
+                        Name        string `json:"name"`
+                        DisplayName string `json:"displayName"`
+                } `json:"assignee"`
+                Project struct {
+                        Key string `json:"key"`
+                } `json:"project"`
+                Labels  []string `json:"labels"`
+                Created string   `json:"created"`
+                Updated string   `json:"updated"`
+                DueDate string   `json:"duedate"`
+        } `json:"fields"`
+}
+
+// statusOf maps a Jira status to a ticket status.
+func (s *jiraStore) statusOf(name, category string) string {
+        for status, names := range s.statusNames {
+                if slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) }) {
+                        return status
+                }
+        }
+        switch category {
+        case "new":
+                return "todo"
+        case "done":
+                return "done"
+        }
+        return "pending"
+}
+
+func (s *jiraStore) ticket(issue jiraIssue) Ticket {
+        f := issue.Fields
+        t := Ticket{
+                ID:          issue.Key,
+                Title:       f.Summary,
+                Status:      s.statusOf(f.Status.Name, f.Status.StatusCategory.Key),
+                Description: f.Description,
+        }
+        if f.Priority != nil {
+                t.Priority = priorityFromName(f.Priority.Name)
+        }
+        if f.Assignee != nil {
+                t.Assignee = f.Assignee.DisplayName
+        }
+        t.Labels, t.Deleted = splitDeletedLabel(f.Labels)
+        t.CreatedAt, _ = time.Parse(jiraTimeLayout, f.Created)
+        t.CreatedAt = t.CreatedAt.UTC()
+        t.UpdatedAt, _ = time.Parse(jiraTimeLayout, f.Updated)
+        t.UpdatedAt = t.UpdatedAt.UTC()
+        t.Version = remoteVersion(t.UpdatedAt)
+        if f.DueDate != "" {
+                if due, err := time.Parse(time.DateOnly, f.DueDate); err == nil {
+                        t.DueDate = endOfDay(due, time.UTC)
+                }
+        }
+        return t
+}
+
+// jqlQuote quotes a JQL string literal.
+func jqlQuote(s string) string {
+        return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (s *jiraStore) statusClause(status string) string {
+        if names := s.statusNames[status]; len(names) > 0 {
+                quoted := make([]string, len(names))
+                for i, n := range names {
+                        quoted[i] = jqlQuote(n)
+                }
+                return "status in (" + strings.Join(quoted, ", ") + ")"
+        }
+        category := map[string]string{"todo": "new", "pending": "indeterminate", "done": "done"}[status]
+        return "statusCategory = " + category
+}
+
+func (s *jiraStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        clauses := []string{"project = " + jqlQuote(s.project)}
+        if s.jql != "" {
+                clauses = append(clauses, "("+s.jql+")")
+        }
+        if filter.Status != "" {
+                clauses = append(clauses, s.statusClause(filter.Status))
+        }
+        for _, label := range filter.Labels {
+                clauses = append(clauses, "labels = "+jqlQuote(label))
+        }
+        jql := strings.Join(clauses, " AND ") + " ORDER BY created ASC"
+
+        tickets := []Ticket{}
+        add := func(issues []jiraIssue) {
+                for _, issue := range issues {
+                        if t := s.ticket(issue); filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+        }
+        query := url.Values{"jql": {jql}, "fields": {jiraIssueFields}, "maxResults": {"100"}}
+        for !s.offsetSearch.Load() {
+                var page struct {
+                        Issues        []jiraIssue `json:"issues"`
+                        NextPageToken string      `json:"nextPageToken"`
+                        IsLast        bool        `json:"isLast"`
+                }
+                err := s.api.do(ctx, "GET", "/rest/api/2/search/jql", query, nil, &page)
+                if err == ErrTicketNotFound && query.Get("nextPageToken") == "" {
+                        s.offsetSearch.Store(true)
+                        break
+                }
+                if err != nil {
+                        return nil, err
+                }
+                add(page.Issues)
+                if page.IsLast || page.NextPageToken == "" {
+                        return tickets, nil
+                }
+                query.Set("nextPageToken", page.NextPageToken)
+        }
+
+        for startAt := 0; ; {
+                var page struct {
+                        Issues []jiraIssue `json:"issues"`
+                        Total  int         `json:"total"`
+                }
+                query.Set("startAt", fmt.Sprint(startAt))
+                if err := s.api.do(ctx, "GET", "/rest/api/2/search", query, nil, &page); err != nil {
+                        return nil, err
+                }
+                add(page.Issues)
+                startAt += len(page.Issues)
+                if len(page.Issues) == 0 || startAt >= page.Total {
+                        return tickets, nil
+                }
+        }
+}
+
+// GetTicket returns the issue id names, which must be in the store's
+// project: Jira looks issues up by key alone, whatever project they are in.
+func (s *jiraStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        var issue jiraIssue
+        err := s.api.do(ctx, "GET", "/rest/api/2/issue/"+url.PathEscape(id), url.Values{"fields": {jiraIssueFields}}, nil, &issue)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if !strings.EqualFold(issue.Fields.Project.Key, s.project) {
+                return Ticket{}, ErrTicketNotFound
+        }
+        return s.ticket(issue), nil
+}
+
+// accountID resolves an assignee name to a Jira user.
+func (s *jiraStore) accountID(ctx context.Context, name string) (map[string]string, error) {
+        var users []struct {
+                AccountID string `json:"accountId"`
+                Name      string `json:"name"`
+        }
+        if err := s.api.do(ctx, "GET", "/rest/api/2/user/search", url.Values{"query": {name}, "username": {name}}, nil, &users); err != nil {
+                return nil, err
+        }
+        if len(users) == 0 {
+                return nil, fmt.Errorf("no Jira user matches %q", name)
+        }
+        if users[0].AccountID != "" {
+                return map[string]string{"accountId": users[0].AccountID}, nil
+        }
+        return map[string]string{"name": users[0].Name}, nil
+}
+
+// fields builds the editable Jira fields for t.
+func (s *jiraStore) fields(ctx context.Context, t Ticket, assigneeChanged bool) (map[string]interface{}, error) {
+        fields := map[string]interface{}{
+                "summary":     t.Title,
+                "description": t.Description,
+                "labels":      nonNilLabels(withDeletedLabel(t)),
+        }
+        if name, ok := priorityNames[t.Priority]; ok {
+                fields["priority"] = map[string]string{"name": name}
+        }
+        if t.DueDate.IsZero() {
+                fields["duedate"] = nil
+        } else {
+                fields["duedate"] = t.DueDate.Format(time.DateOnly)
+        }
+        if assigneeChanged {
+                if t.Assignee == "" {
+                        fields["assignee"] = nil
+                } else {
+                        user, err := s.accountID(ctx, t.Assignee)
+                        if err != nil {
+                                return nil, err
+                        }
+                        fields["assignee"] = user
+                }
+        }
+        return fields, nil
+}
+
+func (s *jiraStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        fields, err := s.fields(ctx, t, t.Assignee != "")
+        if err != nil {
+                return Ticket{}, err
+        }
+        if t.DueDate.IsZero() {
+                delete(fields, "duedate")
+        }
+        fields["project"] = map[string]string{"key": s.project}
+        fields["issuetype"] = map[string]string{"name": s.issueType}
+
+        var created struct {
+                Key string `json:"key"`
+        }
+        if err := s.api.do(ctx, "POST", "/rest/api/2/issue", nil, map[string]interface{}{"fields": fields}, &created); err != nil {
+                return Ticket{}, err
+        }
+        if t.Status != "" && t.Status != "todo" {
+                if err := s.transition(ctx, created.Key, t.Status); err != nil {
+                        return Ticket{}, err
+                }
+        }
+        return s.GetTicket(ctx, created.Key)
+}
+
+// UpdateTicket edits the issue's fields and, if the status changed, takes a
+// transition into it. The version check compares last-modified times, so it
+// catches edits made before the update starts but isn't atomic with it.
+func (s *jiraStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        current, err := s.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        fields, err := s.fields(ctx, t, current.Assignee != t.Assignee)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if err := s.api.do(ctx, "PUT", "/rest/api/2/issue/"+url.PathEscape(t.ID), nil, map[string]interface{}{"fields": fields}, nil); err != nil {
+                return Ticket{}, err
+        }
+        if current.Status != t.Status {
+                if err := s.transition(ctx, t.ID, t.Status); err != nil {
+                        return Ticket{}, err
+                }
+        }
+        return s.GetTicket(ctx, t.ID)
+}
+
+// transition moves an issue into a Jira status that maps to status.
+func (s *jiraStore) transition(ctx context.Context, key, status string) error {
+        var available struct {
+                Transitions []struct {
+                        ID string `json:"id"`
+                        To struct {
+                                Name           string `json:"name"`
+                                StatusCategory struct {
+                                        Key string `json:"key"`
+                                } `json:"statusCategory"`
+                        } `json:"to"`
+                } `json:"transitions"`
+        }
+        path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+        if err := s.api.do(ctx, "GET", path, nil, nil, &available); err != nil {
+                return err
+        }
+        for _, tr := range available.Transitions {
+                if s.statusOf(tr.To.Name, tr.To.StatusCategory.Key) == status {
+                        return s.api.do(ctx, "POST", path, nil, map[string]interface{}{"transition": map[string]string{"id": tr.ID}}, nil)
+                }
+        }
+        return fmt.Errorf("the Jira workflow has no transition from %s to a %s status", key, status)
+}
+
+type jiraComment struct {
+        ID     string `json:"id"`
+        Body   string `json:"body"`
+        Author struct {
+                DisplayName string `json:"displayName"`
+        } `json:"author"`
+        Created string `json:"created"`
+}
+
+func (c jiraComment) comment(ticketID string) Comment {
+        created, _ := time.Parse(jiraTimeLayout, c.Created)
+        return Comment{ID: c.ID, TicketID: ticketID, Author: c.Author.DisplayName, Body: c.Body, CreatedAt: created.UTC()}
+}
+
+func (s *jiraStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        body := c.Body
+        if c.Author != "" && c.Author != defaultCommentAuthor {
+                body = c.Author + ": " + body
+        }
+        if _, err := s.GetTicket(ctx, c.TicketID); err != nil {
+                return Comment{}, err
+        }
+        var created jiraComment
+        path := "/rest/api/2/issue/" + url.PathEscape(c.TicketID) + "/comment"
+        if err := s.api.do(ctx, "POST", path, nil, map[string]string{"body": body}, &created); err != nil {
+                return Comment{}, err
+        }
+        return created.comment(c.TicketID), nil
+}
+
+func (s *jiraStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return nil, err
+        }
+        comments := []Comment{}
+        path := "/rest/api/2/issue/" + url.PathEscape(ticketID) + "/comment"
+        for startAt := 0; ; {
+                var page struct {
+                        Comments []jiraComment `json:"comments"`
+                        Total    int           `json:"total"`
+                }
+                if err := s.api.do(ctx, "GET", path, url.Values{"startAt": {fmt.Sprint(startAt)}, "maxResults": {"100"}}, nil, &page); err != nil {
+                        return nil, err
+                }
+                for _, c := range page.Comments {
+                        comments = append(comments, c.comment(ticketID))
+                }
+                startAt += len(page.Comments)
+                if len(page.Comments) == 0 || startAt >= page.Total {
+                        return comments, nil
+                }
+        }
+}
+
+func (s *jiraStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return err
+        }
+        err := s.api.do(ctx, "DELETE", "/rest/api/2/issue/"+url.PathEscape(ticketID)+"/comment/"+url.PathEscape(commentID), nil, nil, nil)
+        if err == ErrTicketNotFound {
+                return ErrCommentNotFound
+        }
+        return err
+}
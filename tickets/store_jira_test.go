@@ -0,0 +1,55 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "net/http"
+        "net/http/httptest"
+        "testing"
+)
+
+// TestJiraDataCenter runs against an API without Jira Cloud's enhanced
+// search.
+func TestJiraDataCenter(t *testing.T) {
+        issue := func(key, project string) map[string]interface{} {
+                return map[string]interface{}{"key": key, "fields": map[string]interface{}{
+                        "summary": key, "project": map[string]string{"key": project},
+                        "status": map[string]interface{}{"name": "To Do", "statusCategory": map[string]string{"key": "new"}},
+                }}
+        }
+        api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                switch r.URL.Path {
+                case "/rest/api/2/project/OPS":
+                        w.Write([]byte(`{}`))
+                case "/rest/api/2/search":
+                        issues := []interface{}{issue("OPS-1", "OPS"), issue("OPS-2", "OPS"), issue("OPS-3", "OPS")}
+                        start := map[string]int{"": 0, "0": 0, "2": 2}[r.URL.Query().Get("startAt")]
+                        json.NewEncoder(w).Encode(map[string]interface{}{"issues": issues[start:min(start+2, 3)], "total": 3})
+                case "/rest/api/2/issue/OPS-1":
+                        json.NewEncoder(w).Encode(issue("OPS-1", "OPS"))
+                case "/rest/api/2/issue/HR-1":
+                        json.NewEncoder(w).Encode(issue("HR-1", "HR"))
+                default:
+                        http.NotFound(w, r)
+                }
+        }))
+        defer api.Close()
+        s, err := newJiraStore("jira:" + api.URL + "?project=OPS")
+        if err != nil {
+                t.Fatal(err)
+        }
+        ctx := context.Background()
+        tickets, err := s.ListTickets(ctx, TicketFilter{})
+        if err != nil {
+                t.Fatal(err)
+        }
+        if len(tickets) != 3 {
+                t.Errorf("got %d tickets, want 3", len(tickets))
+        }
+        if _, err := s.GetTicket(ctx, "OPS-1"); err != nil {
+                t.Errorf("issue in the project: %v", err)
+        }
+        if _, err := s.GetTicket(ctx, "HR-1"); err != ErrTicketNotFound {
+                t.Errorf("issue in another project: got %v, want %v", err, ErrTicketNotFound)
+        }
+}
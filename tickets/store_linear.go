@@ -0,0 +1,544 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "net/http"
+        "slices"
+        "sort"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// linearIssueFields selects the issue fields a ticket is built from.
+const linearIssueFields = `identifier title description priority dueDate createdAt updatedAt
+        state { id name type } assignee { id displayName } labels { nodes { id name } }`
+
+// linearPriorities maps P0-P3 onto Linear's priority numbers (1 urgent to
+// 4 low; 0 means no priority).
+var linearPriorities = map[string]int{"P0": 1, "P1": 2, "P2": 3, "P3": 4}
+
+// linearStateTypes lists, for each ticket status, the Linear workflow state
+// types that count as it, preferred first when choosing a state to move to.
+var linearStateTypes = map[string][]string{
+        "todo":    {"unstarted", "backlog", "triage"},
+        "pending": {"started"},
+        "done":    {"completed", "canceled"},
+}
+
+// linearStore maps tickets onto the issues of one Linear team through the
+// GraphQL API. Ticket IDs are issue identifiers such as ENG-42. Statuses map
+// onto workflow state types unless explicit state names are configured.
+type linearStore struct {
+        api    *remoteAPI
+        teamID string
+        // states are the team's workflow states, ordered by position.
+        states []linearState
+        // statusNames maps ticket statuses to the Linear state names that count
+        // as them; empty means map by state type.
+        statusNames map[string][]string
+}
+
+type linearState struct {
+        ID       string  `json:"id"`
+        Name     string  `json:"name"`
+        Type     string  `json:"type"`
+        Position float64 `json:"position"`
+}
+
+// newLinearStore opens a store described by a DSN such as
+// "linear:https://api.linear.app/graphql?team=ENG". Options: team (the team
+// key, required) and todo/pending/done (comma-separated workflow state
+// names). The API key comes from LINEAR_API_KEY.
+func newLinearStore(dsn string) (*linearStore, error) {
+        endpoint, opts, err := parseRemoteDSN(dsn, "linear")
+        if err != nil {
+                return nil, err
+        }
+        team := opts.Get("team")
+        if team == "" {
+                return nil, fmt.Errorf("linear DSN needs a team: %s", dsn)
+        }
+        s := &linearStore{statusNames: make(map[string][]string)}
+        for _, status := range ticketStatuses() {
+                for _, name := range strings.Split(opts.Get(status), ",") {
+                        if name = strings.TrimSpace(name); name != "" {
+                                s.statusNames[status] = append(s.statusNames[status], name)
+                        }
+                }
+        }
+
+        s.api = newRemoteAPI(endpoint, func(req *http.Request) {
+                if apiKey := mcp.Secret("LINEAR_API_KEY"); apiKey != "" {
+                        req.Header.Set("Authorization", apiKey)
+                }
+        })
+
+        var data struct {
+                Teams struct {
+                        Nodes []struct {
+                                ID     string `json:"id"`
+                                States struct {
+                                        Nodes []linearState `json:"nodes"`
+                                } `json:"states"`
+                        } `json:"nodes"`
+                } `json:"teams"`
+        }
+        err = s.graphql(context.Background(), "Team", `query Team($key: String!) {
+                teams(filter: { key: { eq: $key } }) { nodes { id states { nodes { id name type position } } } }
+        }`, map[string]interface{}{"key": team}, &data)
+        if err != nil {
+                return nil, fmt.Errorf("connecting to linear: %w", err)
+        }
+        if len(data.Teams.Nodes) == 0 {
+                return nil, fmt.Errorf("linear has no team with key %s", team)
+        }
+        s.teamID = data.Teams.Nodes[0].ID
+        s.states = data.Teams.Nodes[0].States.Nodes
+        sort.SliceStable(s.states, func(i, j int) bool { return s.states[i].Position < s.states[j].Position })
+        return s, nil
+}
+
+// graphql runs one GraphQL operation and decodes its data into out. Errors
+// reporting a missing entity are returned as ErrTicketNotFound.
+func (s *linearStore) graphql(ctx context.Context, operation, query string, vars map[string]interface{}, out interface{}) error {
+        var resp struct {
+                Data   json.RawMessage `json:"data"`
+                Errors []struct {
+                        Message string `json:"message"`
+                } `json:"errors"`
+        }
+        body := map[string]interface{}{"operationName": operation, "query": query, "variables": vars}
+        if err := s.api.do(ctx, "POST", "", nil, body, &resp); err != nil {
+                return err
+        }
+        if len(resp.Errors) > 0 {
+                msg := resp.Errors[0].Message
+                if strings.Contains(strings.ToLower(msg), "not found") {
+                        return ErrTicketNotFound
+                }
+                return fmt.Errorf("linear %s: %s", operation, msg)
+        }
+        if len(resp.Data) == 0 || out == nil {
+                return nil
+        }
+        return json.Unmarshal(resp.Data, out)
+}
+
+type linearIssue struct {
+        Identifier  string      `json:"identifier"`
+        Title       string      `json:"title"`
+        Description string      `json:"description"`
+        Priority    int         `json:"priority"`
+        DueDate     string      `json:"dueDate"`
+        CreatedAt   time.Time   `json:"createdAt"`
+        UpdatedAt   time.Time   `json:"updatedAt"`
+        State       linearState `json:"state"`
+        Assignee    *struct {
+                ID          string `json:"id"`
+                DisplayName string `json:"displayName"`
+        } `json:"assignee"`
+        Labels struct {
+                Nodes []linearLabel `json:"nodes"`
+        } `json:"labels"`
+}
+
+type linearLabel struct {
+        ID   string `json:"id"`
+        Name string `json:"name"`
+}
+
+// statusOf maps a Linear workflow state to a ticket status.
+func (s *linearStore) statusOf(state linearState) string {
+        for status, names := range s.statusNames {
+                if slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, state.Name) }) {
+                        return status
+                }
+        }
+        for status, types := range linearStateTypes {
+                if slices.Contains(types, state.Type) {
+                        return status
+                }
+        }
+        return "todo"
+}
+
+// stateFor picks the workflow state to move an issue into for status.
+func (s *linearStore) stateFor(status string) (string, error) {
+        for _, name := range s.statusNames[status] {
+                for _, st := range s.states {
+                        if strings.EqualFold(st.Name, name) {
+                                return st.ID, nil
+                        }
+                }
+        }
+        for _, typ := range linearStateTypes[status] {
+                for _, st := range s.states {
+                        if st.Type == typ {
+                                return st.ID, nil
+                        }
+                }
+        }
+        return "", fmt.Errorf("the Linear team has no workflow state for %s", status)
+}
+
+func (s *linearStore) ticket(issue linearIssue) Ticket {
+        t := Ticket{
+                ID:          issue.Identifier,
+                Title:       issue.Title,
+                Status:      s.statusOf(issue.State),
+                Description: issue.Description,
+                CreatedAt:   issue.CreatedAt.UTC(),
+                UpdatedAt:   issue.UpdatedAt.UTC(),
+                Version:     remoteVersion(issue.UpdatedAt),
+        }
+        for p, n := range linearPriorities {
+                if n == issue.Priority {
+                        t.Priority = p
+                }
+        }
+        if issue.Assignee != nil {
+                t.Assignee = issue.Assignee.DisplayName
+        }
+        labels := make([]string, 0, len(issue.Labels.Nodes))
+        for _, l := range issue.Labels.Nodes {
+                labels = append(labels, l.Name)
+        }
+        t.Labels, t.Deleted = splitDeletedLabel(labels)
+        if issue.DueDate != "" {
+                if due, err := time.Parse(time.DateOnly, issue.DueDate); err == nil {
+                        t.DueDate = endOfDay(due, time.UTC)
+                }
+        }
+        return t
+}
+
+// ListTickets pages through the team's issues, narrowing by state type and
+// labels on the server and applying the full filter to the results.
+func (s *linearStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        issueFilter := map[string]interface{}{
+                "team": map[string]interface{}{"id": map[string]string{"eq": s.teamID}},
+        }
+        if filter.Status != "" && len(s.statusNames) == 0 {
+                issueFilter["state"] = map[string]interface{}{"type": map[string]interface{}{"in": linearStateTypes[filter.Status]}}
+        }
+        if len(filter.Labels) > 0 {
+                var every []interface{}
+                for _, label := range filter.Labels {
+                        every = append(every, map[string]interface{}{
+                                "labels": map[string]interface{}{"some": map[string]interface{}{"name": map[string]string{"eqIgnoreCase": label}}},
+                        })
+                }
+                issueFilter["and"] = every
+        }
+
+        tickets := []Ticket{}
+        vars := map[string]interface{}{"filter": issueFilter}
+        for {
+                var data struct {
+                        Issues struct {
+                                Nodes    []linearIssue `json:"nodes"`
+                                PageInfo struct {
+                                        HasNextPage bool   `json:"hasNextPage"`
+                                        EndCursor   string `json:"endCursor"`
+                                } `json:"pageInfo"`
+                        } `json:"issues"`
+                }
+                err := s.graphql(ctx, "Issues", `query Issues($filter: IssueFilter, $after: String) {
+                        issues(filter: $filter, first: 100, after: $after) {
+                                nodes { `+linearIssueFields+` }
+                                pageInfo { hasNextPage endCursor }
+                        }
+                }`, vars, &data)
+                if err != nil {
+                        return nil, err
+                }
+                for _, issue := range data.Issues.Nodes {
+                        if t := s.ticket(issue); filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+                if !data.Issues.PageInfo.HasNextPage {
+                        break
+                }
+                vars["after"] = data.Issues.PageInfo.EndCursor
+        }
+        sort.SliceStable(tickets, func(i, j int) bool { return tickets[i].CreatedAt.Before(tickets[j].CreatedAt) })
+        return tickets, nil
+}
+
+func (s *linearStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        var data struct {
+                Issue *linearIssue `json:"issue"`
+        }
+        err := s.graphql(ctx, "Issue", `query Issue($id: String!) { issue(id: $id) { `+linearIssueFields+` } }`,
+                map[string]interface{}{"id": id}, &data)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if data.Issue == nil {
+                return Ticket{}, ErrTicketNotFound
+        }
+        return s.ticket(*data.Issue), nil
+}
+
+// userID resolves an assignee to a Linear user by display name, name, or
+// email.
+func (s *linearStore) userID(ctx context.Context, name string) (string, error) {
+        var data struct {
+                Users struct {
+                        Nodes []struct {
+                                ID string `json:"id"`
+                        } `json:"nodes"`
+                } `json:"users"`
+        }
+        err := s.graphql(ctx, "User", `query User($name: String!) {
+                users(filter: { or: [
+                        { displayName: { eqIgnoreCase: $name } },
+                        { name: { eqIgnoreCase: $name } },
+                        { email: { eqIgnoreCase: $name } }
+                ] }) { nodes { id } }
+        }`, map[string]interface{}{"name": name}, &data)
+        if err != nil {
+                return "", err
+        }
+        if len(data.Users.Nodes) == 0 {
+                return "", fmt.Errorf("no Linear user matches %q", name)
+        }
+        return data.Users.Nodes[0].ID, nil
+}
+
+// labelIDs resolves label names to the team's labels, creating any that
+// don't exist yet.
+func (s *linearStore) labelIDs(ctx context.Context, names []string) ([]string, error) {
+        var data struct {
+                IssueLabels struct {
+                        Nodes []linearLabel `json:"nodes"`
+                } `json:"issueLabels"`
+        }
+        if len(names) > 0 {
+                err := s.graphql(ctx, "Labels", `query Labels($names: [String!]) {
+                        issueLabels(first: 250, filter: { name: { in: $names } }) { nodes { id name } }
+                }`, map[string]interface{}{"names": names}, &data)
+                if err != nil {
+                        return nil, err
+                }
+        }
+        ids := []string{}
+        for _, name := range names {
+                i := slices.IndexFunc(data.IssueLabels.Nodes, func(l linearLabel) bool { return strings.EqualFold(l.Name, name) })
+                if i >= 0 {
+                        ids = append(ids, data.IssueLabels.Nodes[i].ID)
+                        continue
+                }
+                var created struct {
+                        IssueLabelCreate struct {
+                                IssueLabel linearLabel `json:"issueLabel"`
+                        } `json:"issueLabelCreate"`
+                }
+                err := s.graphql(ctx, "CreateLabel", `mutation CreateLabel($input: IssueLabelCreateInput!) {
+                        issueLabelCreate(input: $input) { issueLabel { id name } }
+                }`, map[string]interface{}{"input": map[string]string{"name": name, "teamId": s.teamID}}, &created)
+                if err != nil {
+                        return nil, err
+                }
+                ids = append(ids, created.IssueLabelCreate.IssueLabel.ID)
+        }
+        return ids, nil
+}
+
+// input builds the issue fields to write for t. Fields that are expensive
+// to resolve are only included when they differ from current.
+func (s *linearStore) input(ctx context.Context, t Ticket, current *Ticket) (map[string]interface{}, error) {
+        input := map[string]interface{}{
+                "title":       t.Title,
+                "description": t.Description,
+                "priority":    linearPriorities[t.Priority],
+                "dueDate":     nil,
+        }
+        if !t.DueDate.IsZero() {
+                input["dueDate"] = t.DueDate.Format(time.DateOnly)
+        }
+        if current == nil || current.Status != t.Status {
+                stateID, err := s.stateFor(t.Status)
+                if err != nil {
+                        return nil, err
+                }
+                input["stateId"] = stateID
+        }
+        if current == nil || current.Assignee != t.Assignee {
+                input["assigneeId"] = nil
+                if t.Assignee != "" {
+                        userID, err := s.userID(ctx, t.Assignee)
+                        if err != nil {
+                                return nil, err
+                        }
+                        input["assigneeId"] = userID
+                }
+        }
+        if labels := withDeletedLabel(t); current == nil || !slices.Equal(labels, withDeletedLabel(*current)) {
+                ids, err := s.labelIDs(ctx, labels)
+                if err != nil {
+                        return nil, err
+                }
+                input["labelIds"] = ids
+        }
+        return input, nil
+}
+
+func (s *linearStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        input, err := s.input(ctx, t, nil)
+        if err != nil {
+                return Ticket{}, err
+        }
+        input["teamId"] = s.teamID
+        var data struct {
+                IssueCreate struct {
+                        Issue linearIssue `json:"issue"`
+                } `json:"issueCreate"`
+        }
+        err = s.graphql(ctx, "CreateIssue", `mutation CreateIssue($input: IssueCreateInput!) {
+                issueCreate(input: $input) { issue { `+linearIssueFields+` } }
+        }`, map[string]interface{}{"input": input}, &data)
+        if err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(data.IssueCreate.Issue), nil
+}
+
+// UpdateTicket edits the issue. As with the other tracker stores, the
+// version check compares last-modified times and isn't atomic with the
+// update.
+func (s *linearStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        current, err := s.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        input, err := s.input(ctx, t, &current)
+        if err != nil {
+                return Ticket{}, err
+        }
+        var data struct {
+                IssueUpdate struct {
+                        Issue linearIssue `json:"issue"`
+                } `json:"issueUpdate"`
+        }
+        err = s.graphql(ctx, "UpdateIssue", `mutation UpdateIssue($id: String!, $input: IssueUpdateInput!) {
+                issueUpdate(id: $id, input: $input) { issue { `+linearIssueFields+` } }
+        }`, map[string]interface{}{"id": t.ID, "input": input}, &data)
+        if err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(data.IssueUpdate.Issue), nil
+}
+
+type linearComment struct {
+        ID   string `json:"id"`
+        Body string `json:"body"`
+        User *struct {
+                DisplayName string `json:"displayName"`
+        } `json:"user"`
+        CreatedAt time.Time `json:"createdAt"`
+        Issue     struct {
+                Identifier string `json:"identifier"`
+        } `json:"issue"`
+}
+
+const linearCommentFields = `id body user { displayName } createdAt issue { identifier }`
+
+func (c linearComment) comment(ticketID string) Comment {
+        comment := Comment{ID: c.ID, TicketID: ticketID, Body: c.Body, CreatedAt: c.CreatedAt.UTC()}
+        if c.User != nil {
+                comment.Author = c.User.DisplayName
+        }
+        return comment
+}
+
+func (s *linearStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        body := c.Body
+        if c.Author != "" && c.Author != defaultCommentAuthor {
+                body = c.Author + ": " + body
+        }
+        var data struct {
+                CommentCreate struct {
+                        Comment linearComment `json:"comment"`
+                } `json:"commentCreate"`
+        }
+        err := s.graphql(ctx, "CreateComment", `mutation CreateComment($input: CommentCreateInput!) {
+                commentCreate(input: $input) { comment { `+linearCommentFields+` } }
+        }`, map[string]interface{}{"input": map[string]string{"issueId": c.TicketID, "body": body}}, &data)
+        if err != nil {
+                return Comment{}, err
+        }
+        return data.CommentCreate.Comment.comment(c.TicketID), nil
+}
+
+func (s *linearStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        comments := []Comment{}
+        vars := map[string]interface{}{"id": ticketID}
+        for {
+                var data struct {
+                        Issue *struct {
+                                Comments struct {
+                                        Nodes    []linearComment `json:"nodes"`
+                                        PageInfo struct {
+                                                HasNextPage bool   `json:"hasNextPage"`
+                                                EndCursor   string `json:"endCursor"`
+                                        } `json:"pageInfo"`
+                                } `json:"comments"`
+                        } `json:"issue"`
+                }
+                err := s.graphql(ctx, "Comments", `query Comments($id: String!, $after: String) {
+                        issue(id: $id) { comments(first: 100, after: $after) {
+                                nodes { `+linearCommentFields+` }
+                                pageInfo { hasNextPage endCursor }
+                        } }
+                }`, vars, &data)
+                if err != nil {
+                        return nil, err
+                }
+                if data.Issue == nil {
+                        return nil, ErrTicketNotFound
+                }
+                for _, c := range data.Issue.Comments.Nodes {
+                        comments = append(comments, c.comment(ticketID))
+                }
+                if !data.Issue.Comments.PageInfo.HasNextPage {
+                        break
+                }
+                vars["after"] = data.Issue.Comments.PageInfo.EndCursor
+        }
+        sort.SliceStable(comments, func(i, j int) bool { return comments[i].CreatedAt.Before(comments[j].CreatedAt) })
+        return comments, nil
+}
+
+// DeleteComment deletes a comment after checking that it belongs to the
+// ticket, since Linear addresses comments by ID alone.
+func (s *linearStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        ticket, err := s.GetTicket(ctx, ticketID)
+        if err != nil {
+                return err
+        }
+        var data struct {
+                Comment *linearComment `json:"comment"`
+        }
+        err = s.graphql(ctx, "Comment", `query Comment($id: String!) { comment(id: $id) { `+linearCommentFields+` } }`,
+                map[string]interface{}{"id": commentID}, &data)
+        if err == ErrTicketNotFound || (err == nil && (data.Comment == nil || data.Comment.Issue.Identifier != ticket.ID)) {
+                return ErrCommentNotFound
+        }
+        if err != nil {
+                return err
+        }
+        return s.graphql(ctx, "DeleteComment", `mutation DeleteComment($id: String!) { commentDelete(id: $id) { success } }`,
+                map[string]interface{}{"id": commentID}, nil)
+}
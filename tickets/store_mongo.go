@@ -0,0 +1,359 @@
+package tickets
+
+import (
+        "context"
+        "errors"
+        "fmt"
+        "strings"
+        "time"
+
+        "go.mongodb.org/mongo-driver/v2/bson"
+        "go.mongodb.org/mongo-driver/v2/mongo"
+        "go.mongodb.org/mongo-driver/v2/mongo/options"
+        "go.mongodb.org/mongo-driver/v2/mongo/readpref"
+)
+
+// mongoQueryTimeout bounds each store operation.
+const mongoQueryTimeout = 10 * time.Second
+
+// mongoPageSize is how many tickets ListTickets fetches per query while it
+// pages through a collection by sequence number.
+const mongoPageSize = 500
+
+// mongoTicket is the stored form of a Ticket. Seq records creation order and
+// AssigneeKey is the lowercased assignee, so both can be indexed.
+type mongoTicket struct {
+        Seq         int64      `bson:"seq"`
+        ID          string     `bson:"id"`
+        Title       string     `bson:"title"`
+        Status      string     `bson:"status"`
+        Description string     `bson:"description,omitempty"`
+        Priority    string     `bson:"priority,omitempty"`
+        Assignee    string     `bson:"assignee,omitempty"`
+        AssigneeKey string     `bson:"assigneeKey,omitempty"`
+        Labels      []string   `bson:"labels,omitempty"`
+        CreatedAt   time.Time  `bson:"createdAt"`
+        DueDate     *time.Time `bson:"dueDate,omitempty"`
+        Deleted     bool       `bson:"deleted"`
+        DeletedAt   *time.Time `bson:"deletedAt,omitempty"`
+        Version     int        `bson:"version"`
+        UpdatedAt   time.Time  `bson:"updatedAt"`
+}
+
+type mongoComment struct {
+        Seq       int64     `bson:"seq"`
+        ID        string    `bson:"id"`
+        TicketID  string    `bson:"ticketId"`
+        Author    string    `bson:"author"`
+        Body      string    `bson:"body"`
+        CreatedAt time.Time `bson:"createdAt"`
+}
+
+func toMongoTicket(t Ticket, seq int64) mongoTicket {
+        return mongoTicket{
+                Seq:         seq,
+                ID:          t.ID,
+                Title:       t.Title,
+                Status:      t.Status,
+                Description: t.Description,
+                Priority:    t.Priority,
+                Assignee:    t.Assignee,
+                AssigneeKey: strings.ToLower(t.Assignee),
+                Labels:      t.Labels,
+                CreatedAt:   t.CreatedAt,
+                DueDate:     nullTime(t.DueDate),
+                Deleted:     t.Deleted,
+                DeletedAt:   nullTime(t.DeletedAt),
+                Version:     t.Version,
+                UpdatedAt:   t.UpdatedAt,
+        }
+}
+
+func (m mongoTicket) ticket() Ticket {
+        t := Ticket{
+                ID:          m.ID,
+                Title:       m.Title,
+                Status:      m.Status,
+                Description: m.Description,
+                Priority:    m.Priority,
+                Assignee:    m.Assignee,
+                Labels:      m.Labels,
+                CreatedAt:   m.CreatedAt.UTC(),
+                Deleted:     m.Deleted,
+                Version:     m.Version,
+                UpdatedAt:   m.UpdatedAt.UTC(),
+        }
+        if m.DueDate != nil {
+                t.DueDate = m.DueDate.UTC()
+        }
+        if m.DeletedAt != nil {
+                t.DeletedAt = m.DeletedAt.UTC()
+        }
+        return t
+}
+
+// mongoStore keeps tickets and comments in MongoDB collections, with ID
+// sequences in a counters collection.
+type mongoStore struct {
+        client   *mongo.Client
+        tickets  *mongo.Collection
+        comments *mongo.Collection
+        counters *mongo.Collection
+}
+
+// newMongoStore connects to the mongodb:// URI and ensures the indexes the
+// store queries by. The database defaults to "mcp" unless the URI names one.
+func newMongoStore(uri string) (*mongoStore, error) {
+        opts := options.Client().ApplyURI(uri)
+        if err := opts.Validate(); err != nil {
+                return nil, fmt.Errorf("parsing mongodb URI: %w", err)
+        }
+        client, err := mongo.Connect(opts)
+        if err != nil {
+                return nil, fmt.Errorf("connecting to mongodb: %w", err)
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), mongoQueryTimeout)
+        defer cancel()
+        if err := client.Ping(ctx, readpref.Primary()); err != nil {
+                client.Disconnect(context.Background())
+                return nil, fmt.Errorf("connecting to mongodb: %w", err)
+        }
+
+        db := client.Database(mongoDatabaseName(uri))
+        s := &mongoStore{
+                client:   client,
+                tickets:  db.Collection("tickets"),
+                comments: db.Collection("comments"),
+                counters: db.Collection("counters"),
+        }
+        _, err = s.tickets.Indexes().CreateMany(ctx, []mongo.IndexModel{
+                {Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+                {Keys: bson.D{{Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+                {Keys: bson.D{{Key: "status", Value: 1}, {Key: "seq", Value: 1}}},
+                {Keys: bson.D{{Key: "assigneeKey", Value: 1}, {Key: "seq", Value: 1}}},
+                {Keys: bson.D{{Key: "labels", Value: 1}}},
+        })
+        if err == nil {
+                _, err = s.comments.Indexes().CreateOne(ctx, mongo.IndexModel{
+                        Keys: bson.D{{Key: "ticketId", Value: 1}, {Key: "seq", Value: 1}},
+                })
+        }
+        if err != nil {
+                client.Disconnect(context.Background())
+                return nil, fmt.Errorf("creating mongodb indexes: %w", err)
+        }
+        return s, nil
+}
+
+// mongoDatabaseName returns the database named in the URI path, or "mcp".
+func mongoDatabaseName(uri string) string {
+        rest := uri[strings.Index(uri, "://")+3:]
+        slash := strings.Index(rest, "/")
+        if slash < 0 {
+                return "mcp"
+        }
+        name := rest[slash+1:]
+        if q := strings.Index(name, "?"); q >= 0 {
+                name = name[:q]
+        }
+        if name == "" {
+                return "mcp"
+        }
+        return name
+}
+
+func (s *mongoStore) Close() error {
+        return s.client.Disconnect(context.Background())
+}
+
+// nextSeq atomically increments and returns the named counter.
+func (s *mongoStore) nextSeq(ctx context.Context, name string) (int64, error) {
+        var counter struct {
+                Value int64 `bson:"value"`
+        }
+        err := s.counters.FindOneAndUpdate(ctx,
+                bson.M{"_id": name},
+                bson.M{"$inc": bson.M{"value": 1}},
+                options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+        ).Decode(&counter)
+        return counter.Value, err
+}
+
+// ListTickets pushes the indexed parts of the filter into the query and
+// pages through the matches in sequence order, resuming each page after the
+// last sequence number seen rather than skipping.
+func (s *mongoStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+
+        query := bson.M{}
+        switch {
+        case filter.DeletedOnly:
+                query["deleted"] = true
+        case !filter.IncludeDeleted:
+                query["deleted"] = false
+        }
+        if filter.Status != "" {
+                query["status"] = filter.Status
+        }
+        if filter.Assignee != "" {
+                query["assigneeKey"] = strings.ToLower(filter.Assignee)
+        }
+        if filter.Priority != "" {
+                query["priority"] = filter.Priority
+        }
+        if len(filter.Labels) > 0 {
+                query["labels"] = bson.M{"$all": filter.Labels}
+        }
+
+        tickets := []Ticket{}
+        opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetLimit(mongoPageSize)
+        var after int64
+        for {
+                query["seq"] = bson.M{"$gt": after}
+                cursor, err := s.tickets.Find(ctx, query, opts)
+                if err != nil {
+                        return nil, err
+                }
+                var page []mongoTicket
+                if err := cursor.All(ctx, &page); err != nil {
+                        return nil, err
+                }
+                for _, m := range page {
+                        if t := m.ticket(); filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+                if len(page) < mongoPageSize {
+                        return tickets, nil
+                }
+                after = page[len(page)-1].Seq
+        }
+}
+
+func (s *mongoStore) findTicket(ctx context.Context, id string) (mongoTicket, error) {
+        var m mongoTicket
+        err := s.tickets.FindOne(ctx, bson.M{"id": id}).Decode(&m)
+        if errors.Is(err, mongo.ErrNoDocuments) {
+                return m, ErrTicketNotFound
+        }
+        return m, err
+}
+
+func (s *mongoStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        m, err := s.findTicket(ctx, id)
+        if err != nil {
+                return Ticket{}, err
+        }
+        return m.ticket(), nil
+}
+
+func (s *mongoStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        seq, err := s.nextSeq(ctx, "tickets")
+        if err != nil {
+                return Ticket{}, err
+        }
+        t.ID = fmt.Sprintf("T%d", seq)
+        t.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+        t.Version = 1
+        t.UpdatedAt = t.CreatedAt
+        if _, err := s.tickets.InsertOne(ctx, toMongoTicket(t, seq)); err != nil {
+                return Ticket{}, err
+        }
+        return t, nil
+}
+
+func (s *mongoStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        m, err := s.findTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        expected := t.Version
+        t.Version++
+        t.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+        result, err := s.tickets.ReplaceOne(ctx, bson.M{"id": t.ID, "version": expected}, toMongoTicket(t, m.Seq))
+        if err != nil {
+                return Ticket{}, err
+        }
+        if result.MatchedCount == 0 {
+                return Ticket{}, ErrVersionConflict
+        }
+        return t, nil
+}
+
+func (s *mongoStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        if _, err := s.findTicket(ctx, c.TicketID); err != nil {
+                return Comment{}, err
+        }
+        seq, err := s.nextSeq(ctx, "comments")
+        if err != nil {
+                return Comment{}, err
+        }
+        c.ID = fmt.Sprintf("C%d", seq)
+        c.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+        _, err = s.comments.InsertOne(ctx, mongoComment{
+                Seq:       seq,
+                ID:        c.ID,
+                TicketID:  c.TicketID,
+                Author:    c.Author,
+                Body:      c.Body,
+                CreatedAt: c.CreatedAt,
+        })
+        if err != nil {
+                return Comment{}, err
+        }
+        return c, nil
+}
+
+func (s *mongoStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        if _, err := s.findTicket(ctx, ticketID); err != nil {
+                return nil, err
+        }
+        cursor, err := s.comments.Find(ctx, bson.M{"ticketId": ticketID},
+                options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+        if err != nil {
+                return nil, err
+        }
+        var docs []mongoComment
+        if err := cursor.All(ctx, &docs); err != nil {
+                return nil, err
+        }
+        comments := make([]Comment, 0, len(docs))
+        for _, d := range docs {
+                comments = append(comments, Comment{
+                        ID:        d.ID,
+                        TicketID:  d.TicketID,
+                        Author:    d.Author,
+                        Body:      d.Body,
+                        CreatedAt: d.CreatedAt.UTC(),
+                })
+        }
+        return comments, nil
+}
+
+func (s *mongoStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        ctx, cancel := context.WithTimeout(ctx, mongoQueryTimeout)
+        defer cancel()
+        if _, err := s.findTicket(ctx, ticketID); err != nil {
+                return err
+        }
+        result, err := s.comments.DeleteOne(ctx, bson.M{"ticketId": ticketID, "id": commentID})
+        if err != nil {
+                return err
+        }
+        if result.DeletedCount == 0 {
+                return ErrCommentNotFound
+        }
+        return nil
+}
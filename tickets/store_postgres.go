@@ -0,0 +1,357 @@
+package tickets
+
+import (
+        "context"
+        "embed"
+        "errors"
+        "fmt"
+        "io/fs"
+        "sort"
+        "strings"
+        "time"
+
+        "github.com/jackc/pgx/v5"
+        "github.com/jackc/pgx/v5/pgxpool"
+        "mcp-server/mcp"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresQueryTimeout bounds each store operation, since TicketStore
+// methods don't take a context of their own.
+const postgresQueryTimeout = 10 * time.Second
+
+const ticketColumns = "id, title, status, description, priority, assignee, labels, created_at, due_date, deleted, deleted_at, version, updated_at"
+
+// postgresStatements are prepared on every pooled connection as it is
+// opened; queries refer to them by name.
+var postgresStatements = map[string]string{
+        "get_ticket": "SELECT " + ticketColumns + " FROM tickets WHERE id = $1",
+        "create_ticket": `INSERT INTO tickets (id, title, status, description, priority, assignee, labels, due_date, deleted, deleted_at)
+                VALUES ('T' || nextval('ticket_ids'), $1, $2, $3, $4, $5, $6, $7, $8, $9)
+                RETURNING ` + ticketColumns,
+        "update_ticket": `UPDATE tickets SET title = $2, status = $3, description = $4, priority = $5, assignee = $6,
+                labels = $7, due_date = $8, deleted = $9, deleted_at = $10,
+                version = version + 1, updated_at = now()
+                WHERE id = $1 AND version = $11
+                RETURNING ` + ticketColumns,
+        "ticket_exists": "SELECT EXISTS (SELECT 1 FROM tickets WHERE id = $1)",
+        "add_comment": `INSERT INTO comments (id, ticket_id, author, body)
+                SELECT 'C' || nextval('comment_ids'), id, $2, $3 FROM tickets WHERE id = $1
+                RETURNING id, ticket_id, author, body, created_at`,
+        "list_comments":  "SELECT id, ticket_id, author, body, created_at FROM comments WHERE ticket_id = $1 ORDER BY seq",
+        "delete_comment": "DELETE FROM comments WHERE ticket_id = $1 AND id = $2",
+}
+
+// postgresStore keeps tickets in PostgreSQL. Connections come from a pool
+// configured by the DSN (e.g. pool_max_conns), and the schema is migrated
+// on open.
+type postgresStore struct {
+        pool *pgxpool.Pool
+}
+
+func newPostgresStore(dsn string) (*postgresStore, error) {
+        config, err := pgxpool.ParseConfig(dsn)
+        if err != nil {
+                return nil, fmt.Errorf("parsing postgres DSN: %w", err)
+        }
+        // Read the password for each new connection so a rotated one is
+        // used as pooled connections are replaced.
+        config.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+                if password := mcp.Secret("POSTGRES_PASSWORD"); password != "" {
+                        cc.Password = password
+                }
+                return nil
+        }
+        if password := mcp.Secret("POSTGRES_PASSWORD"); password != "" {
+                config.ConnConfig.Password = password
+        }
+
+        ctx, cancel := context.WithTimeout(context.Background(), postgresQueryTimeout)
+        defer cancel()
+
+        // Migrate over a single connection before the pool starts preparing
+        // statements against tables that may not exist yet.
+        conn, err := pgx.ConnectConfig(ctx, config.ConnConfig)
+        if err != nil {
+                return nil, fmt.Errorf("connecting to postgres: %w", err)
+        }
+        err = migratePostgres(ctx, conn)
+        conn.Close(ctx)
+        if err != nil {
+                return nil, err
+        }
+
+        config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+                for name, sql := range postgresStatements {
+                        if _, err := conn.Prepare(ctx, name, sql); err != nil {
+                                return fmt.Errorf("preparing %s: %w", name, err)
+                        }
+                }
+                return nil
+        }
+        pool, err := pgxpool.NewWithConfig(context.Background(), config)
+        if err != nil {
+                return nil, fmt.Errorf("opening postgres pool: %w", err)
+        }
+        if err := pool.Ping(ctx); err != nil {
+                pool.Close()
+                return nil, fmt.Errorf("connecting to postgres: %w", err)
+        }
+        return &postgresStore{pool: pool}, nil
+}
+
+// migratePostgres applies the embedded migrations that haven't run yet, in
+// file name order. An advisory lock keeps concurrent replicas from
+// migrating at the same time.
+func migratePostgres(ctx context.Context, conn *pgx.Conn) error {
+        names, err := fs.Glob(postgresMigrations, "migrations/postgres/*.sql")
+        if err != nil {
+                return err
+        }
+        sort.Strings(names)
+
+        tx, err := conn.Begin(ctx)
+        if err != nil {
+                return err
+        }
+        defer tx.Rollback(ctx)
+
+        if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('mcp-server migrations'))"); err != nil {
+                return err
+        }
+        if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+                version    text PRIMARY KEY,
+                applied_at timestamptz NOT NULL DEFAULT now()
+        )`); err != nil {
+                return err
+        }
+
+        for _, name := range names {
+                version := strings.TrimSuffix(name[strings.LastIndex(name, "/")+1:], ".sql")
+                var applied bool
+                if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&applied); err != nil {
+                        return err
+                }
+                if applied {
+                        continue
+                }
+                sql, err := postgresMigrations.ReadFile(name)
+                if err != nil {
+                        return err
+                }
+                if _, err := tx.Exec(ctx, string(sql)); err != nil {
+                        return fmt.Errorf("migration %s: %w", version, err)
+                }
+                if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+                        return err
+                }
+        }
+        return tx.Commit(ctx)
+}
+
+func (s *postgresStore) Close() error {
+        s.pool.Close()
+        return nil
+}
+
+func scanTicket(row pgx.Row) (Ticket, error) {
+        var t Ticket
+        var dueDate, deletedAt *time.Time
+        err := row.Scan(&t.ID, &t.Title, &t.Status, &t.Description, &t.Priority, &t.Assignee, &t.Labels,
+                &t.CreatedAt, &dueDate, &t.Deleted, &deletedAt, &t.Version, &t.UpdatedAt)
+        if errors.Is(err, pgx.ErrNoRows) {
+                return Ticket{}, ErrTicketNotFound
+        }
+        if err != nil {
+                return Ticket{}, err
+        }
+        t.CreatedAt = t.CreatedAt.UTC()
+        t.UpdatedAt = t.UpdatedAt.UTC()
+        if dueDate != nil {
+                t.DueDate = dueDate.UTC()
+        }
+        if deletedAt != nil {
+                t.DeletedAt = deletedAt.UTC()
+        }
+        if len(t.Labels) == 0 {
+                t.Labels = nil
+        }
+        return t, nil
+}
+
+// nullTime maps the zero time to SQL NULL.
+func nullTime(t time.Time) *time.Time {
+        if t.IsZero() {
+                return nil
+        }
+        return &t
+}
+
+func nonNilLabels(labels []string) []string {
+        if labels == nil {
+                return []string{}
+        }
+        return labels
+}
+
+// ListTickets narrows the query in SQL where the filter maps onto indexed
+// columns and applies the full filter to the rows returned, so results
+// match the in-memory store exactly.
+func (s *postgresStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+
+        var where []string
+        var args []interface{}
+        arg := func(v interface{}) string {
+                args = append(args, v)
+                return fmt.Sprintf("$%d", len(args))
+        }
+        switch {
+        case filter.DeletedOnly:
+                where = append(where, "deleted")
+        case !filter.IncludeDeleted:
+                where = append(where, "NOT deleted")
+        }
+        if filter.Status != "" {
+                where = append(where, "status = "+arg(filter.Status))
+        }
+        if filter.Assignee != "" {
+                where = append(where, "lower(assignee) = lower("+arg(filter.Assignee)+")")
+        }
+        if filter.Priority != "" {
+                where = append(where, "priority = "+arg(filter.Priority))
+        }
+        if len(filter.Labels) > 0 {
+                where = append(where, "labels @> "+arg(filter.Labels))
+        }
+
+        query := "SELECT " + ticketColumns + " FROM tickets"
+        if len(where) > 0 {
+                query += " WHERE " + strings.Join(where, " AND ")
+        }
+        query += " ORDER BY seq"
+
+        rows, err := s.pool.Query(ctx, query, args...)
+        if err != nil {
+                return nil, err
+        }
+        defer rows.Close()
+
+        tickets := []Ticket{}
+        for rows.Next() {
+                t, err := scanTicket(rows)
+                if err != nil {
+                        return nil, err
+                }
+                if filter.matches(t) {
+                        tickets = append(tickets, t)
+                }
+        }
+        return tickets, rows.Err()
+}
+
+func (s *postgresStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        return scanTicket(s.pool.QueryRow(ctx, "get_ticket", id))
+}
+
+func (s *postgresStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        return scanTicket(s.pool.QueryRow(ctx, "create_ticket",
+                t.Title, t.Status, t.Description, t.Priority, t.Assignee, nonNilLabels(t.Labels),
+                nullTime(t.DueDate), t.Deleted, nullTime(t.DeletedAt)))
+}
+
+func (s *postgresStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        updated, err := scanTicket(s.pool.QueryRow(ctx, "update_ticket",
+                t.ID, t.Title, t.Status, t.Description, t.Priority, t.Assignee, nonNilLabels(t.Labels),
+                nullTime(t.DueDate), t.Deleted, nullTime(t.DeletedAt), t.Version))
+        if err != ErrTicketNotFound {
+                return updated, err
+        }
+        // No row matched: either the ticket is gone or its version moved on.
+        exists, err := s.ticketExists(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if exists {
+                return Ticket{}, ErrVersionConflict
+        }
+        return Ticket{}, ErrTicketNotFound
+}
+
+func (s *postgresStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        err := s.pool.QueryRow(ctx, "add_comment", c.TicketID, c.Author, c.Body).
+                Scan(&c.ID, &c.TicketID, &c.Author, &c.Body, &c.CreatedAt)
+        if errors.Is(err, pgx.ErrNoRows) {
+                return Comment{}, ErrTicketNotFound
+        }
+        if err != nil {
+                return Comment{}, err
+        }
+        c.CreatedAt = c.CreatedAt.UTC()
+        return c, nil
+}
+
+func (s *postgresStore) ticketExists(ctx context.Context, id string) (bool, error) {
+        var exists bool
+        err := s.pool.QueryRow(ctx, "ticket_exists", id).Scan(&exists)
+        return exists, err
+}
+
+func (s *postgresStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        exists, err := s.ticketExists(ctx, ticketID)
+        if err != nil {
+                return nil, err
+        }
+        if !exists {
+                return nil, ErrTicketNotFound
+        }
+
+        rows, err := s.pool.Query(ctx, "list_comments", ticketID)
+        if err != nil {
+                return nil, err
+        }
+        defer rows.Close()
+        comments := []Comment{}
+        for rows.Next() {
+                var c Comment
+                if err := rows.Scan(&c.ID, &c.TicketID, &c.Author, &c.Body, &c.CreatedAt); err != nil {
+                        return nil, err
+                }
+                c.CreatedAt = c.CreatedAt.UTC()
+                comments = append(comments, c)
+        }
+        return comments, rows.Err()
+}
+
+func (s *postgresStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        ctx, cancel := context.WithTimeout(ctx, postgresQueryTimeout)
+        defer cancel()
+        exists, err := s.ticketExists(ctx, ticketID)
+        if err != nil {
+                return err
+        }
+        if !exists {
+                return ErrTicketNotFound
+        }
+        tag, err := s.pool.Exec(ctx, "delete_comment", ticketID, commentID)
+        if err != nil {
+                return err
+        }
+        if tag.RowsAffected() == 0 {
+                return ErrCommentNotFound
+        }
+        return nil
+}
@@ -0,0 +1,317 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "sort"
+        "strconv"
+        "strings"
+        "time"
+
+        "github.com/redis/go-redis/v9"
+        "mcp-server/mcp"
+)
+
+// redisStore keeps each ticket in a hash at ticket:<id>, with secondary
+// index sets per status and (lowercased) assignee. tickets:all orders
+// tickets by creation, and comments are JSON entries in a list per ticket.
+type redisStore struct {
+        client *redis.Client
+}
+
+const (
+        redisTicketSeqKey  = "tickets:seq"
+        redisCommentSeqKey = "comments:seq"
+        redisAllTicketsKey = "tickets:all"
+)
+
+func redisTicketKey(id string) string     { return "ticket:" + id }
+func redisCommentsKey(id string) string   { return "ticket:" + id + ":comments" }
+func redisStatusKey(status string) string { return "tickets:status:" + status }
+func redisAssigneeKey(assignee string) string {
+        return "tickets:assignee:" + strings.ToLower(assignee)
+}
+
+func newRedisStore(dsn string) (*redisStore, error) {
+        client, err := mcp.NewRedisClient(dsn)
+        if err != nil {
+                return nil, err
+        }
+        return &redisStore{client: client}, nil
+}
+
+func (s *redisStore) Close() error {
+        return s.client.Close()
+}
+
+func formatRedisTime(t time.Time) string {
+        if t.IsZero() {
+                return ""
+        }
+        return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseRedisTime(v string) time.Time {
+        t, _ := time.Parse(time.RFC3339Nano, v)
+        return t
+}
+
+func ticketToHash(t Ticket, seq int64) map[string]interface{} {
+        labels, _ := json.Marshal(t.Labels)
+        return map[string]interface{}{
+                "seq":         seq,
+                "id":          t.ID,
+                "title":       t.Title,
+                "status":      t.Status,
+                "description": t.Description,
+                "priority":    t.Priority,
+                "assignee":    t.Assignee,
+                "labels":      string(labels),
+                "createdAt":   formatRedisTime(t.CreatedAt),
+                "dueDate":     formatRedisTime(t.DueDate),
+                "deleted":     strconv.FormatBool(t.Deleted),
+                "deletedAt":   formatRedisTime(t.DeletedAt),
+                "version":     t.Version,
+                "updatedAt":   formatRedisTime(t.UpdatedAt),
+        }
+}
+
+func ticketFromHash(h map[string]string) Ticket {
+        t := Ticket{
+                ID:          h["id"],
+                Title:       h["title"],
+                Status:      h["status"],
+                Description: h["description"],
+                Priority:    h["priority"],
+                Assignee:    h["assignee"],
+                CreatedAt:   parseRedisTime(h["createdAt"]),
+                DueDate:     parseRedisTime(h["dueDate"]),
+                DeletedAt:   parseRedisTime(h["deletedAt"]),
+                UpdatedAt:   parseRedisTime(h["updatedAt"]),
+        }
+        t.Deleted, _ = strconv.ParseBool(h["deleted"])
+        t.Version, _ = strconv.Atoi(h["version"])
+        json.Unmarshal([]byte(h["labels"]), &t.Labels)
+        return t
+}
+
+// ListTickets reads candidates from the status and assignee index sets when
+// the filter names them, then applies the full filter.
+func (s *redisStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+
+        var ids []string
+        var err error
+        switch {
+        case filter.Status != "" && filter.Assignee != "":
+                ids, err = s.client.SInter(ctx, redisStatusKey(filter.Status), redisAssigneeKey(filter.Assignee)).Result()
+        case filter.Status != "":
+                ids, err = s.client.SMembers(ctx, redisStatusKey(filter.Status)).Result()
+        case filter.Assignee != "":
+                ids, err = s.client.SMembers(ctx, redisAssigneeKey(filter.Assignee)).Result()
+        default:
+                ids, err = s.client.ZRange(ctx, redisAllTicketsKey, 0, -1).Result()
+        }
+        if err != nil {
+                return nil, err
+        }
+
+        pipe := s.client.Pipeline()
+        cmds := make([]*redis.MapStringStringCmd, len(ids))
+        for i, id := range ids {
+                cmds[i] = pipe.HGetAll(ctx, redisTicketKey(id))
+        }
+        if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+                return nil, err
+        }
+
+        type seqTicket struct {
+                seq    int64
+                ticket Ticket
+        }
+        var matched []seqTicket
+        for _, cmd := range cmds {
+                h := cmd.Val()
+                if len(h) == 0 {
+                        continue
+                }
+                t := ticketFromHash(h)
+                if filter.matches(t) {
+                        seq, _ := strconv.ParseInt(h["seq"], 10, 64)
+                        matched = append(matched, seqTicket{seq, t})
+                }
+        }
+        sort.Slice(matched, func(i, j int) bool { return matched[i].seq < matched[j].seq })
+
+        tickets := make([]Ticket, 0, len(matched))
+        for _, m := range matched {
+                tickets = append(tickets, m.ticket)
+        }
+        return tickets, nil
+}
+
+func (s *redisStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        h, err := s.client.HGetAll(ctx, redisTicketKey(id)).Result()
+        if err != nil {
+                return Ticket{}, err
+        }
+        if len(h) == 0 {
+                return Ticket{}, ErrTicketNotFound
+        }
+        return ticketFromHash(h), nil
+}
+
+func (s *redisStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        seq, err := s.client.Incr(ctx, redisTicketSeqKey).Result()
+        if err != nil {
+                return Ticket{}, err
+        }
+        t.ID = fmt.Sprintf("T%d", seq)
+        t.CreatedAt = time.Now().UTC()
+        t.Version = 1
+        t.UpdatedAt = t.CreatedAt
+
+        _, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+                pipe.HSet(ctx, redisTicketKey(t.ID), ticketToHash(t, seq))
+                pipe.ZAdd(ctx, redisAllTicketsKey, redis.Z{Score: float64(seq), Member: t.ID})
+                pipe.SAdd(ctx, redisStatusKey(t.Status), t.ID)
+                if t.Assignee != "" {
+                        pipe.SAdd(ctx, redisAssigneeKey(t.Assignee), t.ID)
+                }
+                return nil
+        })
+        if err != nil {
+                return Ticket{}, err
+        }
+        return t, nil
+}
+
+// UpdateTicket rewrites the ticket hash and moves it between index sets in
+// one transaction. The version check and the write are guarded by WATCH, so
+// a concurrent change from another replica surfaces as a version conflict.
+func (s *redisStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        key := redisTicketKey(t.ID)
+
+        err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+                old, err := tx.HGetAll(ctx, key).Result()
+                if err != nil {
+                        return err
+                }
+                if len(old) == 0 {
+                        return ErrTicketNotFound
+                }
+                if old["version"] != strconv.Itoa(t.Version) {
+                        return ErrVersionConflict
+                }
+                t.Version++
+                t.UpdatedAt = time.Now().UTC()
+                seq, _ := strconv.ParseInt(old["seq"], 10, 64)
+                _, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+                        pipe.HSet(ctx, key, ticketToHash(t, seq))
+                        if old["status"] != t.Status {
+                                pipe.SRem(ctx, redisStatusKey(old["status"]), t.ID)
+                                pipe.SAdd(ctx, redisStatusKey(t.Status), t.ID)
+                        }
+                        if !strings.EqualFold(old["assignee"], t.Assignee) {
+                                if old["assignee"] != "" {
+                                        pipe.SRem(ctx, redisAssigneeKey(old["assignee"]), t.ID)
+                                }
+                                if t.Assignee != "" {
+                                        pipe.SAdd(ctx, redisAssigneeKey(t.Assignee), t.ID)
+                                }
+                        }
+                        return nil
+                })
+                return err
+        }, key)
+        if errors.Is(err, redis.TxFailedErr) {
+                return Ticket{}, ErrVersionConflict
+        }
+        if err != nil {
+                return Ticket{}, err
+        }
+        return t, nil
+}
+
+func (s *redisStore) ticketExists(ctx context.Context, id string) error {
+        n, err := s.client.Exists(ctx, redisTicketKey(id)).Result()
+        if err != nil {
+                return err
+        }
+        if n == 0 {
+                return ErrTicketNotFound
+        }
+        return nil
+}
+
+func (s *redisStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        if err := s.ticketExists(ctx, c.TicketID); err != nil {
+                return Comment{}, err
+        }
+        seq, err := s.client.Incr(ctx, redisCommentSeqKey).Result()
+        if err != nil {
+                return Comment{}, err
+        }
+        c.ID = fmt.Sprintf("C%d", seq)
+        c.CreatedAt = time.Now().UTC()
+        data, err := json.Marshal(c)
+        if err != nil {
+                return Comment{}, err
+        }
+        if err := s.client.RPush(ctx, redisCommentsKey(c.TicketID), data).Err(); err != nil {
+                return Comment{}, err
+        }
+        return c, nil
+}
+
+func (s *redisStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        if err := s.ticketExists(ctx, ticketID); err != nil {
+                return nil, err
+        }
+        entries, err := s.client.LRange(ctx, redisCommentsKey(ticketID), 0, -1).Result()
+        if err != nil {
+                return nil, err
+        }
+        comments := make([]Comment, 0, len(entries))
+        for _, entry := range entries {
+                var c Comment
+                if err := json.Unmarshal([]byte(entry), &c); err != nil {
+                        return nil, err
+                }
+                comments = append(comments, c)
+        }
+        return comments, nil
+}
+
+func (s *redisStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        ctx, cancel := context.WithTimeout(ctx, mcp.RedisQueryTimeout)
+        defer cancel()
+        if err := s.ticketExists(ctx, ticketID); err != nil {
+                return err
+        }
+        key := redisCommentsKey(ticketID)
+        entries, err := s.client.LRange(ctx, key, 0, -1).Result()
+        if err != nil {
+                return err
+        }
+        for _, entry := range entries {
+                var c Comment
+                if json.Unmarshal([]byte(entry), &c) == nil && c.ID == commentID {
+                        return s.client.LRem(ctx, key, 1, entry).Err()
+                }
+        }
+        return ErrCommentNotFound
+}
@@ -0,0 +1,480 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "io"
+        "net/http"
+        "net/url"
+        "os"
+        "slices"
+        "strconv"
+        "strings"
+        "time"
+
+        "gopkg.in/yaml.v3"
+        "mcp-server/mcp"
+)
+
+// maxRESTPages bounds how many pages a listing follows.
+const maxRESTPages = 1000
+
+// restMapping declares how the TicketStore operations map onto an
+// arbitrary REST API. It is read from a YAML or JSON file.
+type restMapping struct {
+        BaseURL string            `yaml:"base_url"`
+        Headers map[string]string `yaml:"headers"`
+        // Statuses maps the API's status values to ticket statuses. The first
+        // value listed for a ticket status is the one written back.
+        Statuses      yaml.Node         `yaml:"statuses"`
+        Fields        map[string]string `yaml:"fields"`
+        CommentFields map[string]string `yaml:"comment_fields"`
+        // BodyPath, if set, nests request bodies under this path, e.g. "issue"
+        // for APIs that expect {"issue": {...}}.
+        BodyPath   string                   `yaml:"body_path"`
+        Operations map[string]restOperation `yaml:"operations"`
+
+        // statusValues holds Statuses as (remote value, ticket status)
+        // pairs in file order.
+        statusValues [][2]string
+}
+
+// restOperation is one HTTP call. URL is relative to base_url and may use
+// {id}, {comment_id}, and {page}. Items is the JSON path of the result
+// array in list responses and Item the path of the object in single-item
+// responses; both default to the whole response.
+type restOperation struct {
+        Method string `yaml:"method"`
+        URL    string `yaml:"url"`
+        Items  string `yaml:"items"`
+        Item   string `yaml:"item"`
+}
+
+// restTicketFields are the ticket fields a mapping may map; id and title
+// are required.
+var restTicketFields = []string{"id", "title", "description", "status", "priority", "assignee", "labels", "due_date", "created_at", "updated_at", "version"}
+
+// restStore maps tickets onto any JSON REST API through a restMapping, so
+// trackers without a built-in store can be used without writing Go.
+type restStore struct {
+        api     *remoteAPI
+        mapping restMapping
+}
+
+// newRESTStore opens a store described by a DSN such as
+// "rest:tracker.yaml", naming the mapping file. Header values in the
+// mapping may reference environment variables as ${NAME}.
+func newRESTStore(dsn string) (*restStore, error) {
+        path := strings.TrimPrefix(dsn, "rest:")
+        data, err := os.ReadFile(path)
+        if err != nil {
+                return nil, err
+        }
+        var m restMapping
+        // YAML is a superset of JSON, so one decoder reads both.
+        if err := yaml.Unmarshal(data, &m); err != nil {
+                return nil, fmt.Errorf("%s: %w", path, err)
+        }
+        if err := m.validate(); err != nil {
+                return nil, fmt.Errorf("%s: %w", path, err)
+        }
+
+        s := &restStore{mapping: m}
+        s.api = newRemoteAPI(m.BaseURL, func(req *http.Request) {
+                for name, value := range m.Headers {
+                        req.Header.Set(name, mcp.ExpandSecrets(value))
+                }
+        })
+        s.api.pacer = &ratePacer{}
+        return s, nil
+}
+
+func (m *restMapping) validate() error {
+        if m.BaseURL == "" {
+                return fmt.Errorf("base_url is required")
+        }
+        for _, field := range []string{"id", "title"} {
+                if m.Fields[field] == "" {
+                        return fmt.Errorf("fields.%s is required", field)
+                }
+        }
+        for field := range m.Fields {
+                if !slices.Contains(restTicketFields, field) {
+                        return fmt.Errorf("unknown field %q (want one of %s)", field, strings.Join(restTicketFields, ", "))
+                }
+        }
+        for _, op := range []string{"list", "get", "create", "update"} {
+                if m.Operations[op].URL == "" {
+                        return fmt.Errorf("operations.%s.url is required", op)
+                }
+        }
+        for name, op := range m.Operations {
+                if op.Method == "" {
+                        op.Method = map[string]string{"create": "POST", "update": "PUT", "add_comment": "POST", "delete_comment": "DELETE"}[name]
+                        if op.Method == "" {
+                                op.Method = "GET"
+                        }
+                        m.Operations[name] = op
+                }
+        }
+
+        // statuses is decoded by hand to keep the file's order, which decides
+        // which remote value is written for a status.
+        if m.Statuses.Kind == yaml.MappingNode {
+                for i := 0; i+1 < len(m.Statuses.Content); i += 2 {
+                        remote, status := m.Statuses.Content[i].Value, m.Statuses.Content[i+1].Value
+                        if !isValidStatus(status) {
+                                return fmt.Errorf("statuses.%s: invalid ticket status %q", remote, status)
+                        }
+                        m.statusValues = append(m.statusValues, [2]string{remote, status})
+                }
+        }
+        return nil
+}
+
+// jsonPath reads a dot-separated path such as "fields.assignee.name" or
+// "assignees.0.login" from decoded JSON.
+func jsonPath(v interface{}, path string) interface{} {
+        if path == "" {
+                return v
+        }
+        for _, key := range strings.Split(path, ".") {
+                switch node := v.(type) {
+                case map[string]interface{}:
+                        v = node[key]
+                case []interface{}:
+                        i, err := strconv.Atoi(key)
+                        if err != nil || i < 0 || i >= len(node) {
+                                return nil
+                        }
+                        v = node[i]
+                default:
+                        return nil
+                }
+        }
+        return v
+}
+
+// setJSONPath sets a dot-separated path in obj, creating objects along the
+// way. Numeric segments index single-element arrays, so "assignees.0.login"
+// writes [{"login": ...}].
+func setJSONPath(obj map[string]interface{}, path string, value interface{}) {
+        keys := strings.Split(path, ".")
+        var node interface{} = obj
+        for i, key := range keys {
+                last := i == len(keys)-1
+                next := func() interface{} {
+                        if last {
+                                return value
+                        }
+                        if _, err := strconv.Atoi(keys[i+1]); err == nil {
+                                return []interface{}{nil}
+                        }
+                        return map[string]interface{}{}
+                }
+                switch n := node.(type) {
+                case map[string]interface{}:
+                        if _, ok := n[key]; !ok || last {
+                                n[key] = next()
+                        }
+                        node = n[key]
+                case []interface{}:
+                        idx, _ := strconv.Atoi(key)
+                        if idx >= len(n) {
+                                return
+                        }
+                        if n[idx] == nil || last {
+                                n[idx] = next()
+                        }
+                        node = n[idx]
+                }
+        }
+}
+
+// jsonTime parses an RFC 3339 timestamp, a date, or Unix seconds or
+// milliseconds.
+func jsonTime(v interface{}) time.Time {
+        switch x := v.(type) {
+        case float64:
+                if x > 1e11 {
+                        return time.UnixMilli(int64(x)).UTC()
+                }
+                return time.Unix(int64(x), 0).UTC()
+        case string:
+                for _, layout := range []string{time.RFC3339Nano, jiraTimeLayout, time.DateOnly} {
+                        if t, err := time.Parse(layout, x); err == nil {
+                                return t.UTC()
+                        }
+                }
+        }
+        return time.Time{}
+}
+
+func (s *restStore) field(item interface{}, name string) interface{} {
+        path := s.mapping.Fields[name]
+        if path == "" {
+                return nil
+        }
+        return jsonPath(item, path)
+}
+
+func (s *restStore) ticket(item interface{}) Ticket {
+        t := Ticket{
+                ID:          mcp.JSONString(s.field(item, "id")),
+                Title:       mcp.JSONString(s.field(item, "title")),
+                Description: mcp.JSONString(s.field(item, "description")),
+                Assignee:    mcp.JSONString(s.field(item, "assignee")),
+                Status:      "todo",
+                CreatedAt:   jsonTime(s.field(item, "created_at")),
+                UpdatedAt:   jsonTime(s.field(item, "updated_at")),
+        }
+        remoteStatus := mcp.JSONString(s.field(item, "status"))
+        for _, pair := range s.mapping.statusValues {
+                if strings.EqualFold(pair[0], remoteStatus) {
+                        t.Status = pair[1]
+                        break
+                }
+        }
+        if p := mcp.JSONString(s.field(item, "priority")); p != "" {
+                if t.Priority = priorityFromName(p); t.Priority == "" {
+                        // Numeric priorities count from 0 or 1 as the most urgent.
+                        if n, err := strconv.Atoi(p); err == nil && n >= 0 && n <= 4 {
+                                t.Priority = ticketPriorities[max(n-1, 0)]
+                        }
+                }
+        }
+        var labels []string
+        if items, ok := s.field(item, "labels").([]interface{}); ok {
+                for _, l := range items {
+                        labels = append(labels, mcp.JSONString(l))
+                }
+        }
+        t.Labels, t.Deleted = splitDeletedLabel(labels)
+        if due := jsonTime(s.field(item, "due_date")); !due.IsZero() {
+                t.DueDate = due
+                if due.Hour() == 0 && due.Minute() == 0 && due.Second() == 0 {
+                        t.DueDate = endOfDay(due, time.UTC)
+                }
+        }
+        switch v := s.field(item, "version").(type) {
+        case float64:
+                t.Version = int(v)
+        case string:
+                t.Version, _ = strconv.Atoi(v)
+        default:
+                if !t.UpdatedAt.IsZero() {
+                        t.Version = remoteVersion(t.UpdatedAt)
+                } else {
+                        // Without a version or last-modified field stale updates
+                        // can't be detected.
+                        t.Version = 1
+                }
+        }
+        return t
+}
+
+// body builds the request body for t from the field mapping. Empty
+// optional fields are left out of a new ticket; for an update they are
+// sent as null, so clearing a field clears it in the tracker too, which
+// leaving it out wouldn't for a PATCH.
+func (s *restStore) body(t Ticket, update bool) (map[string]interface{}, error) {
+        obj := map[string]interface{}{}
+        set := func(field string, value interface{}) {
+                if path := s.mapping.Fields[field]; path != "" {
+                        setJSONPath(obj, path, value)
+                }
+        }
+        set("title", t.Title)
+        set("description", t.Description)
+        for _, pair := range s.mapping.statusValues {
+                if pair[1] == t.Status {
+                        set("status", pair[0])
+                        break
+                }
+        }
+        optional := func(field string, value interface{}, empty bool) {
+                if !empty {
+                        set(field, value)
+                } else if update {
+                        set(field, nil)
+                }
+        }
+        optional("priority", t.Priority, t.Priority == "")
+        optional("assignee", t.Assignee, t.Assignee == "")
+        if s.mapping.Fields["labels"] != "" {
+                set("labels", nonNilLabels(withDeletedLabel(t)))
+        } else if t.Deleted {
+                return nil, fmt.Errorf("soft-deleting tickets needs a labels field in the REST mapping")
+        }
+        optional("due_date", t.DueDate.Format(time.DateOnly), t.DueDate.IsZero())
+        if s.mapping.BodyPath != "" {
+                wrapped := map[string]interface{}{}
+                setJSONPath(wrapped, s.mapping.BodyPath, obj)
+                return wrapped, nil
+        }
+        return obj, nil
+}
+
+// call performs a mapped operation, expanding the URL template with vars,
+// and returns the decoded response.
+func (s *restStore) call(ctx context.Context, name string, vars map[string]string, body interface{}) (interface{}, error) {
+        op, ok := s.mapping.Operations[name]
+        if !ok || op.URL == "" {
+                return nil, fmt.Errorf("the REST mapping has no %s operation", name)
+        }
+        pairs := make([]string, 0, len(vars)*2)
+        for k, v := range vars {
+                pairs = append(pairs, "{"+k+"}", url.PathEscape(v))
+        }
+        var out interface{}
+        err := s.api.do(ctx, op.Method, strings.NewReplacer(pairs...).Replace(op.URL), nil, body, &out)
+        if err != nil && err != io.EOF { // io.EOF: an empty response body
+                return nil, err
+        }
+        return out, nil
+}
+
+func (s *restStore) callItem(ctx context.Context, name string, vars map[string]string, body interface{}) (Ticket, error) {
+        out, err := s.call(ctx, name, vars, body)
+        if err != nil {
+                return Ticket{}, err
+        }
+        item := jsonPath(out, s.mapping.Operations[name].Item)
+        if item == nil {
+                return Ticket{}, ErrTicketNotFound
+        }
+        return s.ticket(item), nil
+}
+
+// ListTickets reads every page of the list operation (following {page}
+// from 1 until a page comes back empty) and applies the filter locally.
+func (s *restStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        op := s.mapping.Operations["list"]
+        paged := strings.Contains(op.URL, "{page}")
+        tickets := []Ticket{}
+        seen := make(map[string]bool)
+        for page := 1; page <= maxRESTPages; page++ {
+                out, err := s.call(ctx, "list", map[string]string{"page": strconv.Itoa(page)}, nil)
+                if err != nil {
+                        return nil, err
+                }
+                items, _ := jsonPath(out, op.Items).([]interface{})
+                for _, item := range items {
+                        t := s.ticket(item)
+                        if seen[t.ID] {
+                                continue
+                        }
+                        seen[t.ID] = true
+                        if filter.matches(t) {
+                                tickets = append(tickets, t)
+                        }
+                }
+                if !paged || len(items) == 0 {
+                        break
+                }
+        }
+        return tickets, nil
+}
+
+func (s *restStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        return s.callItem(ctx, "get", map[string]string{"id": id}, nil)
+}
+
+func (s *restStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        body, err := s.body(t, false)
+        if err != nil {
+                return Ticket{}, err
+        }
+        return s.callItem(ctx, "create", nil, body)
+}
+
+// UpdateTicket sends the mapped fields to the update operation. As with the
+// other tracker stores, the version check isn't atomic with the update.
+func (s *restStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        current, err := s.GetTicket(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        body, err := s.body(t, true)
+        if err != nil {
+                return Ticket{}, err
+        }
+        out, err := s.call(ctx, "update", map[string]string{"id": t.ID}, body)
+        if err != nil {
+                return Ticket{}, err
+        }
+        // Some APIs answer updates with no content; read the ticket back then.
+        if item := jsonPath(out, s.mapping.Operations["update"].Item); item != nil {
+                if updated := s.ticket(item); updated.ID != "" {
+                        return updated, nil
+                }
+        }
+        return s.GetTicket(ctx, t.ID)
+}
+
+func (s *restStore) comment(item interface{}, ticketID string) Comment {
+        get := func(name string) interface{} {
+                if path := s.mapping.CommentFields[name]; path != "" {
+                        return jsonPath(item, path)
+                }
+                return nil
+        }
+        return Comment{
+                ID:        mcp.JSONString(get("id")),
+                TicketID:  ticketID,
+                Author:    mcp.JSONString(get("author")),
+                Body:      mcp.JSONString(get("body")),
+                CreatedAt: jsonTime(get("created_at")),
+        }
+}
+
+func (s *restStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        path := s.mapping.CommentFields["body"]
+        if path == "" {
+                return Comment{}, fmt.Errorf("the REST mapping has no comment_fields.body")
+        }
+        body := map[string]interface{}{}
+        text := c.Body
+        if author := s.mapping.CommentFields["author"]; author != "" {
+                setJSONPath(body, author, c.Author)
+        } else if c.Author != "" && c.Author != defaultCommentAuthor {
+                text = c.Author + ": " + text
+        }
+        setJSONPath(body, path, text)
+        out, err := s.call(ctx, "add_comment", map[string]string{"id": c.TicketID}, body)
+        if err != nil {
+                return Comment{}, err
+        }
+        return s.comment(jsonPath(out, s.mapping.Operations["add_comment"].Item), c.TicketID), nil
+}
+
+func (s *restStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        out, err := s.call(ctx, "list_comments", map[string]string{"id": ticketID}, nil)
+        if err != nil {
+                return nil, err
+        }
+        items, _ := jsonPath(out, s.mapping.Operations["list_comments"].Items).([]interface{})
+        comments := make([]Comment, 0, len(items))
+        for _, item := range items {
+                comments = append(comments, s.comment(item, ticketID))
+        }
+        return comments, nil
+}
+
+func (s *restStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        comments, err := s.ListComments(ctx, ticketID)
+        if err != nil {
+                return err
+        }
+        if !slices.ContainsFunc(comments, func(c Comment) bool { return c.ID == commentID }) {
+                return ErrCommentNotFound
+        }
+        _, err = s.call(ctx, "delete_comment", map[string]string{"id": ticketID, "comment_id": commentID}, nil)
+        return err
+}
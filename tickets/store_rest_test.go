@@ -0,0 +1,34 @@
+package tickets
+
+import (
+        "reflect"
+        "testing"
+        "time"
+)
+
+func TestRESTBodyClearsFieldsOnUpdate(t *testing.T) {
+        s := &restStore{mapping: restMapping{Fields: map[string]string{
+                "title": "name", "priority": "urgency", "assignee": "owner", "due_date": "due_on",
+        }}}
+        ticket := Ticket{Title: "Broken build"}
+        created, err := s.body(ticket, false)
+        if err != nil {
+                t.Fatal(err)
+        }
+        if want := map[string]interface{}{"name": "Broken build"}; !reflect.DeepEqual(created, want) {
+                t.Errorf("create: got %v, want %v", created, want)
+        }
+        updated, err := s.body(ticket, true)
+        if err != nil {
+                t.Fatal(err)
+        }
+        if want := map[string]interface{}{"name": "Broken build", "urgency": nil, "owner": nil, "due_on": nil}; !reflect.DeepEqual(updated, want) {
+                t.Errorf("update: got %v, want %v", updated, want)
+        }
+
+        ticket.Assignee, ticket.DueDate = "ana", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+        updated, _ = s.body(ticket, true)
+        if updated["owner"] != "ana" || updated["due_on"] != "2025-03-01" {
+                t.Errorf("update with values: got %v", updated)
+        }
+}
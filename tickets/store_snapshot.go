@@ -0,0 +1,76 @@
+package tickets
+
+import (
+        "context"
+        "errors"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+var errSnapshotsUnsupported = errors.New("store does not support snapshots")
+
+// snapshotter is implemented by stores that can write their full state to
+// disk on demand.
+type snapshotter interface {
+        // Snapshot persists the current state and returns where it was written.
+        Snapshot() (string, error)
+}
+
+// snapshotStore is the in-memory store with its state restored from path on
+// start and written back by Snapshot and on Close.
+type snapshotStore struct {
+        *MemoryStore
+        path string
+}
+
+// NewSnapshotStore restores s from the snapshot at path, if there is one,
+// and persists it there from then on. Without a snapshot s keeps its seed.
+func NewSnapshotStore(s *MemoryStore, path string) (*snapshotStore, error) {
+        snap, ok, err := readSnapshotFile(path)
+        if err != nil {
+                return nil, err
+        }
+        if ok {
+                s.restore(snap)
+        }
+        return &snapshotStore{MemoryStore: s, path: path}, nil
+}
+
+func (s *snapshotStore) Snapshot() (string, error) {
+        data, err := encodeSnapshot(s.path, s.snapshot())
+        if err != nil {
+                return "", err
+        }
+        return s.path, writeFileAtomic(s.path, data)
+}
+
+func (s *snapshotStore) Close() error {
+        _, err := s.Snapshot()
+        return err
+}
+
+// Snapshot writes the file store's state immediately, ahead of its next
+// periodic flush.
+func (s *fileStore) Snapshot() (string, error) {
+        return s.path, s.flush()
+}
+
+func handleSnapshotNow(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        snap, ok := storeFrom(ctx).(snapshotter)
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInternal, "The ticket store does not support snapshots")
+        }
+        path, err := snap.Snapshot()
+        if errors.Is(err, errSnapshotsUnsupported) {
+                return nil, mcp.Errorf(mcp.ErrInternal, "The ticket store does not support snapshots")
+        }
+        if err != nil {
+                return nil, err
+        }
+        mcp.LogAll(ctx, mcp.LevelNotice, "Snapshot written to %s", path)
+        return map[string]interface{}{
+                "path":       path,
+                "snapshotAt": time.Now().UTC(),
+        }, nil
+}
@@ -0,0 +1,113 @@
+package tickets
+
+import (
+        "context"
+        "io"
+        "strings"
+
+        "go.opentelemetry.io/otel/attribute"
+        "go.opentelemetry.io/otel/trace"
+        "mcp-server/mcp"
+)
+
+// tracedStore records a span for each store operation.
+type tracedStore struct {
+        TicketStore
+        // kind names the backend, e.g. "postgres" or "jira".
+        kind string
+}
+
+func NewTracedStore(inner TicketStore, kind string) *tracedStore {
+        return &tracedStore{TicketStore: inner, kind: kind}
+}
+
+func (s *tracedStore) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+        attrs = append(attrs, attribute.String("mcp.store", s.kind))
+        return mcp.Tracer.Start(ctx, "store."+op, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+func (s *tracedStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        ctx, span := s.start(ctx, "ListTickets", attribute.String("ticket.status", filter.Status))
+        defer span.End()
+        tickets, err := s.TicketStore.ListTickets(ctx, filter)
+        mcp.RecordSpanError(span, err)
+        span.SetAttributes(attribute.Int("ticket.count", len(tickets)))
+        return tickets, err
+}
+
+func (s *tracedStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        ctx, span := s.start(ctx, "GetTicket", attribute.String("ticket.id", id))
+        defer span.End()
+        t, err := s.TicketStore.GetTicket(ctx, id)
+        mcp.RecordSpanError(span, err)
+        return t, err
+}
+
+func (s *tracedStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, span := s.start(ctx, "CreateTicket")
+        defer span.End()
+        created, err := s.TicketStore.CreateTicket(ctx, t)
+        mcp.RecordSpanError(span, err)
+        span.SetAttributes(attribute.String("ticket.id", created.ID))
+        return created, err
+}
+
+func (s *tracedStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        ctx, span := s.start(ctx, "UpdateTicket", attribute.String("ticket.id", t.ID))
+        defer span.End()
+        updated, err := s.TicketStore.UpdateTicket(ctx, t)
+        mcp.RecordSpanError(span, err)
+        return updated, err
+}
+
+func (s *tracedStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        ctx, span := s.start(ctx, "AddComment", attribute.String("ticket.id", c.TicketID))
+        defer span.End()
+        added, err := s.TicketStore.AddComment(ctx, c)
+        mcp.RecordSpanError(span, err)
+        return added, err
+}
+
+func (s *tracedStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        ctx, span := s.start(ctx, "ListComments", attribute.String("ticket.id", ticketID))
+        defer span.End()
+        comments, err := s.TicketStore.ListComments(ctx, ticketID)
+        mcp.RecordSpanError(span, err)
+        return comments, err
+}
+
+func (s *tracedStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        ctx, span := s.start(ctx, "DeleteComment", attribute.String("ticket.id", ticketID), attribute.String("comment.id", commentID))
+        defer span.End()
+        err := s.TicketStore.DeleteComment(ctx, ticketID, commentID)
+        mcp.RecordSpanError(span, err)
+        return err
+}
+
+// Snapshot and Close pass through to the wrapped store, like eventStore's.
+func (s *tracedStore) Snapshot() (string, error) {
+        snap, ok := s.TicketStore.(snapshotter)
+        if !ok {
+                return "", errSnapshotsUnsupported
+        }
+        return snap.Snapshot()
+}
+
+func (s *tracedStore) Close() error {
+        if closer, ok := s.TicketStore.(io.Closer); ok {
+                return closer.Close()
+        }
+        return nil
+}
+
+// StoreKind returns the backend named by a -store DSN without any
+// credentials it contains, e.g. "postgres" or "jira".
+func StoreKind(dsn string) string {
+        if kind, _, ok := strings.Cut(dsn, ":"); ok {
+                return kind
+        }
+        if dsn == "" {
+                return "memory"
+        }
+        return dsn
+}
@@ -0,0 +1,456 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "net/http"
+        "net/url"
+        "slices"
+        "sort"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// trelloDefaultLists names the lists that count as each status when the DSN
+// doesn't say otherwise.
+var trelloDefaultLists = map[string][]string{
+        "todo":    {"To Do", "Todo", "Backlog"},
+        "pending": {"Doing", "In Progress"},
+        "done":    {"Done"},
+}
+
+const trelloCardFields = "name,desc,idList,idMembers,labels,due,dateLastActivity,closed,shortLink"
+
+// trelloStore maps tickets onto the cards of one Trello board, which plays
+// the role of a project. Ticket IDs are card short links. A card's status is
+// that of its list, so moving a card between lists in Trello changes its
+// status and update_ticket_status moves the card. Archived cards are
+// soft-deleted tickets.
+type trelloStore struct {
+        api   *remoteAPI
+        board string
+        // boardID is the board's full ID, which cards refer to it by, where
+        // board may be its short link.
+        boardID string
+        // statusLists maps ticket statuses to the list names that count as them.
+        statusLists map[string][]string
+
+        mu      sync.Mutex
+        lists   []trelloList
+        members map[string]string // member ID to username
+        labels  []trelloLabel
+}
+
+type trelloList struct {
+        ID     string  `json:"id"`
+        Name   string  `json:"name"`
+        Pos    float64 `json:"pos"`
+        Closed bool    `json:"closed"`
+}
+
+type trelloLabel struct {
+        ID   string `json:"id"`
+        Name string `json:"name"`
+}
+
+// newTrelloStore opens a store described by a DSN such as
+// "trello:https://api.trello.com/1?board=AbCd1234". Options: board (the
+// board ID or short link, required) and todo/pending/done (comma-separated
+// list names). Credentials come from TRELLO_API_KEY and TRELLO_TOKEN.
+func newTrelloStore(dsn string) (*trelloStore, error) {
+        baseURL, opts, err := parseRemoteDSN(dsn, "trello")
+        if err != nil {
+                return nil, err
+        }
+        s := &trelloStore{board: opts.Get("board"), statusLists: make(map[string][]string)}
+        if s.board == "" {
+                return nil, fmt.Errorf("trello DSN needs a board: %s", dsn)
+        }
+        for _, status := range ticketStatuses() {
+                for _, name := range strings.Split(opts.Get(status), ",") {
+                        if name = strings.TrimSpace(name); name != "" {
+                                s.statusLists[status] = append(s.statusLists[status], name)
+                        }
+                }
+                if len(s.statusLists[status]) == 0 {
+                        s.statusLists[status] = trelloDefaultLists[status]
+                }
+        }
+
+        s.api = newRemoteAPI(baseURL, func(req *http.Request) {
+                if key := mcp.Secret("TRELLO_API_KEY"); key != "" {
+                        req.Header.Set("Authorization", fmt.Sprintf(`OAuth oauth_consumer_key="%s", oauth_token="%s"`, key, mcp.Secret("TRELLO_TOKEN")))
+                }
+        })
+        if err := s.refresh(context.Background()); err != nil {
+                return nil, fmt.Errorf("connecting to trello: %w", err)
+        }
+        return s, nil
+}
+
+// refresh reloads the board's lists, members, and labels.
+func (s *trelloStore) refresh(ctx context.Context) error {
+        var board struct {
+                ID      string        `json:"id"`
+                Lists   []trelloList  `json:"lists"`
+                Labels  []trelloLabel `json:"labels"`
+                Members []struct {
+                        ID       string `json:"id"`
+                        Username string `json:"username"`
+                } `json:"members"`
+        }
+        query := url.Values{"fields": {"name"}, "lists": {"open"}, "labels": {"all"}, "members": {"all"}}
+        if err := s.api.do(ctx, "GET", s.boardPath(""), query, nil, &board); err != nil {
+                return err
+        }
+        sort.SliceStable(board.Lists, func(i, j int) bool { return board.Lists[i].Pos < board.Lists[j].Pos })
+        members := make(map[string]string, len(board.Members))
+        for _, m := range board.Members {
+                members[m.ID] = m.Username
+        }
+        s.mu.Lock()
+        s.boardID, s.lists, s.labels, s.members = board.ID, board.Lists, board.Labels, members
+        s.mu.Unlock()
+        return nil
+}
+
+func (s *trelloStore) boardPath(sub string) string {
+        return "/boards/" + url.PathEscape(s.board) + sub
+}
+
+// statusOf maps a list to a ticket status. Lists that aren't named in the
+// mapping count as pending: work that has left the todo list but isn't done.
+func (s *trelloStore) statusOf(listID string) string {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        for _, l := range s.lists {
+                if l.ID != listID {
+                        continue
+                }
+                for status, names := range s.statusLists {
+                        if slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, l.Name) }) {
+                                return status
+                        }
+                }
+        }
+        return "pending"
+}
+
+// listFor picks the leftmost list that counts as status.
+func (s *trelloStore) listFor(status string) (string, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        for _, l := range s.lists {
+                if slices.ContainsFunc(s.statusLists[status], func(n string) bool { return strings.EqualFold(n, l.Name) }) {
+                        return l.ID, nil
+                }
+        }
+        return "", fmt.Errorf("the Trello board has no %s list (looked for %s)", status, strings.Join(s.statusLists[status], ", "))
+}
+
+type trelloCard struct {
+        ID               string        `json:"id"`
+        IDBoard          string        `json:"idBoard"`
+        ShortLink        string        `json:"shortLink"`
+        Name             string        `json:"name"`
+        Desc             string        `json:"desc"`
+        IDList           string        `json:"idList"`
+        IDMembers        []string      `json:"idMembers"`
+        Labels           []trelloLabel `json:"labels"`
+        Due              *time.Time    `json:"due"`
+        DateLastActivity time.Time     `json:"dateLastActivity"`
+        Closed           bool          `json:"closed"`
+}
+
+// createdAt recovers a card's creation time from its ID, whose first four
+// bytes are a Unix timestamp.
+func (c trelloCard) createdAt() time.Time {
+        if len(c.ID) < 8 {
+                return time.Time{}
+        }
+        secs, err := strconv.ParseInt(c.ID[:8], 16, 64)
+        if err != nil {
+                return time.Time{}
+        }
+        return time.Unix(secs, 0).UTC()
+}
+
+func (s *trelloStore) ticket(card trelloCard) Ticket {
+        t := Ticket{
+                ID:          card.ShortLink,
+                Title:       card.Name,
+                Status:      s.statusOf(card.IDList),
+                Description: card.Desc,
+                Deleted:     card.Closed,
+                CreatedAt:   card.createdAt(),
+                UpdatedAt:   card.DateLastActivity.UTC(),
+                Version:     remoteVersion(card.DateLastActivity),
+        }
+        var labels []string
+        for _, l := range card.Labels {
+                if isPriorityLabel(l.Name) {
+                        t.Priority, _ = normalizePriority(l.Name[len(priorityLabelPrefix):])
+                } else if l.Name != "" {
+                        labels = append(labels, l.Name)
+                }
+        }
+        t.Labels = normalizeLabels(labels)
+        if len(card.IDMembers) > 0 {
+                s.mu.Lock()
+                t.Assignee = s.members[card.IDMembers[0]]
+                s.mu.Unlock()
+        }
+        if card.Due != nil {
+                t.DueDate = card.Due.UTC()
+        }
+        if t.Deleted {
+                // Trello doesn't record when a card was archived.
+                t.DeletedAt = t.UpdatedAt
+        }
+        return t
+}
+
+func (s *trelloStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+        cardFilter := "open"
+        if filter.IncludeDeleted || filter.DeletedOnly {
+                cardFilter = "all"
+        }
+        var cards []trelloCard
+        query := url.Values{"filter": {cardFilter}, "fields": {trelloCardFields}}
+        if err := s.api.do(ctx, "GET", s.boardPath("/cards"), query, nil, &cards); err != nil {
+                return nil, err
+        }
+        tickets := []Ticket{}
+        for _, card := range cards {
+                if t := s.ticket(card); filter.matches(t) {
+                        tickets = append(tickets, t)
+                }
+        }
+        sort.SliceStable(tickets, func(i, j int) bool { return tickets[i].CreatedAt.Before(tickets[j].CreatedAt) })
+        return tickets, nil
+}
+
+// card returns the card id names, which must be on the store's board:
+// Trello looks cards up by ID alone, whatever board they are on.
+func (s *trelloStore) card(ctx context.Context, id string) (trelloCard, error) {
+        var card trelloCard
+        if err := s.api.do(ctx, "GET", "/cards/"+url.PathEscape(id), url.Values{"fields": {trelloCardFields + ",idBoard"}}, nil, &card); err != nil {
+                return trelloCard{}, err
+        }
+        s.mu.Lock()
+        boardID := s.boardID
+        s.mu.Unlock()
+        if card.IDBoard != boardID {
+                return trelloCard{}, ErrTicketNotFound
+        }
+        return card, nil
+}
+
+func (s *trelloStore) GetTicket(ctx context.Context, id string) (Ticket, error) {
+        card, err := s.card(ctx, id)
+        if err != nil {
+                return Ticket{}, err
+        }
+        return s.ticket(card), nil
+}
+
+// memberID resolves an assignee to a board member by username.
+func (s *trelloStore) memberID(ctx context.Context, username string) (string, error) {
+        for attempt := 0; attempt < 2; attempt++ {
+                s.mu.Lock()
+                for id, name := range s.members {
+                        if strings.EqualFold(name, username) {
+                                s.mu.Unlock()
+                                return id, nil
+                        }
+                }
+                s.mu.Unlock()
+                if attempt == 0 {
+                        if err := s.refresh(ctx); err != nil {
+                                return "", err
+                        }
+                }
+        }
+        return "", fmt.Errorf("no member of the Trello board is called %q", username)
+}
+
+// labelIDs resolves label names to the board's labels, creating any that
+// don't exist yet.
+func (s *trelloStore) labelIDs(ctx context.Context, names []string) ([]string, error) {
+        ids := []string{}
+        for _, name := range names {
+                s.mu.Lock()
+                i := slices.IndexFunc(s.labels, func(l trelloLabel) bool { return strings.EqualFold(l.Name, name) })
+                var id string
+                if i >= 0 {
+                        id = s.labels[i].ID
+                }
+                s.mu.Unlock()
+                if id == "" {
+                        var created trelloLabel
+                        if err := s.api.do(ctx, "POST", s.boardPath("/labels"), nil, map[string]interface{}{"name": name, "color": nil}, &created); err != nil {
+                                return nil, err
+                        }
+                        s.mu.Lock()
+                        s.labels = append(s.labels, created)
+                        s.mu.Unlock()
+                        id = created.ID
+                }
+                ids = append(ids, id)
+        }
+        return ids, nil
+}
+
+// fields builds the card fields to write for t.
+func (s *trelloStore) fields(ctx context.Context, t Ticket) (map[string]interface{}, error) {
+        listID, err := s.listFor(t.Status)
+        if err != nil {
+                return nil, err
+        }
+        labels := append([]string{}, t.Labels...)
+        if t.Priority != "" {
+                labels = append(labels, priorityLabelPrefix+t.Priority)
+        }
+        labelIDs, err := s.labelIDs(ctx, labels)
+        if err != nil {
+                return nil, err
+        }
+        members := []string{}
+        if t.Assignee != "" {
+                id, err := s.memberID(ctx, t.Assignee)
+                if err != nil {
+                        return nil, err
+                }
+                members = append(members, id)
+        }
+        fields := map[string]interface{}{
+                "name":      t.Title,
+                "desc":      t.Description,
+                "idList":    listID,
+                "idLabels":  strings.Join(labelIDs, ","),
+                "idMembers": strings.Join(members, ","),
+                "closed":    t.Deleted,
+                "due":       nil,
+        }
+        if !t.DueDate.IsZero() {
+                fields["due"] = t.DueDate.Format(time.RFC3339)
+        }
+        return fields, nil
+}
+
+func (s *trelloStore) CreateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        fields, err := s.fields(ctx, t)
+        if err != nil {
+                return Ticket{}, err
+        }
+        fields["pos"] = "bottom"
+        var created trelloCard
+        if err := s.api.do(ctx, "POST", "/cards", nil, fields, &created); err != nil {
+                return Ticket{}, err
+        }
+        return s.GetTicket(ctx, created.ShortLink)
+}
+
+// UpdateTicket edits the card, moving it to another list if its status
+// changed; a card whose status is unchanged stays in its list. As with the
+// other tracker stores, the version check compares last-activity times and
+// isn't atomic with the update.
+func (s *trelloStore) UpdateTicket(ctx context.Context, t Ticket) (Ticket, error) {
+        card, err := s.card(ctx, t.ID)
+        if err != nil {
+                return Ticket{}, err
+        }
+        current := s.ticket(card)
+        if current.Version != t.Version {
+                return Ticket{}, ErrVersionConflict
+        }
+        fields, err := s.fields(ctx, t)
+        if err != nil {
+                return Ticket{}, err
+        }
+        if current.Status == t.Status {
+                fields["idList"] = card.IDList
+        } else {
+                fields["pos"] = "bottom"
+        }
+        if err := s.api.do(ctx, "PUT", "/cards/"+url.PathEscape(card.ID), nil, fields, nil); err != nil {
+                return Ticket{}, err
+        }
+        return s.GetTicket(ctx, t.ID)
+}
+
+type trelloAction struct {
+        ID   string `json:"id"`
+        Date string `json:"date"`
+        Data struct {
+                Text string `json:"text"`
+                Card struct {
+                        ShortLink string `json:"shortLink"`
+                } `json:"card"`
+        } `json:"data"`
+        MemberCreator struct {
+                Username string `json:"username"`
+        } `json:"memberCreator"`
+}
+
+func (a trelloAction) comment(ticketID string) Comment {
+        created, _ := time.Parse(time.RFC3339, a.Date)
+        return Comment{ID: a.ID, TicketID: ticketID, Author: a.MemberCreator.Username, Body: a.Data.Text, CreatedAt: created.UTC()}
+}
+
+func (s *trelloStore) AddComment(ctx context.Context, c Comment) (Comment, error) {
+        text := c.Body
+        if c.Author != "" && c.Author != defaultCommentAuthor {
+                text = c.Author + ": " + text
+        }
+        if _, err := s.card(ctx, c.TicketID); err != nil {
+                return Comment{}, err
+        }
+        var created trelloAction
+        path := "/cards/" + url.PathEscape(c.TicketID) + "/actions/comments"
+        if err := s.api.do(ctx, "POST", path, url.Values{"text": {text}}, nil, &created); err != nil {
+                return Comment{}, err
+        }
+        return created.comment(c.TicketID), nil
+}
+
+func (s *trelloStore) ListComments(ctx context.Context, ticketID string) ([]Comment, error) {
+        if _, err := s.card(ctx, ticketID); err != nil {
+                return nil, err
+        }
+        var actions []trelloAction
+        path := "/cards/" + url.PathEscape(ticketID) + "/actions"
+        if err := s.api.do(ctx, "GET", path, url.Values{"filter": {"commentCard"}, "limit": {"1000"}}, nil, &actions); err != nil {
+                return nil, err
+        }
+        // Trello lists actions newest first.
+        comments := make([]Comment, 0, len(actions))
+        for i := len(actions) - 1; i >= 0; i-- {
+                comments = append(comments, actions[i].comment(ticketID))
+        }
+        return comments, nil
+}
+
+// DeleteComment deletes a comment after checking that it belongs to the
+// ticket, since Trello addresses comments by action ID alone.
+func (s *trelloStore) DeleteComment(ctx context.Context, ticketID, commentID string) error {
+        if _, err := s.GetTicket(ctx, ticketID); err != nil {
+                return err
+        }
+        var action trelloAction
+        err := s.api.do(ctx, "GET", "/actions/"+url.PathEscape(commentID), nil, nil, &action)
+        if err == ErrTicketNotFound || (err == nil && action.Data.Card.ShortLink != ticketID) {
+                return ErrCommentNotFound
+        }
+        if err != nil {
+                return err
+        }
+        return s.api.do(ctx, "DELETE", "/actions/"+url.PathEscape(commentID), nil, nil, nil)
+}
@@ -0,0 +1,39 @@
+package tickets
+
+import (
+        "context"
+        "encoding/json"
+        "net/http"
+        "net/http/httptest"
+        "testing"
+)
+
+func TestTrelloIgnoresCardsOnOtherBoards(t *testing.T) {
+        api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                switch r.URL.Path {
+                case "/boards/short":
+                        json.NewEncoder(w).Encode(map[string]interface{}{"id": "board1", "lists": []trelloList{{ID: "l1", Name: "To Do"}}})
+                case "/cards/mine":
+                        json.NewEncoder(w).Encode(trelloCard{ID: "5f0000000000000000000000", IDBoard: "board1", ShortLink: "mine", IDList: "l1"})
+                case "/cards/theirs":
+                        json.NewEncoder(w).Encode(trelloCard{ID: "5f0000000000000000000001", IDBoard: "board2", ShortLink: "theirs", IDList: "l1"})
+                default:
+                        http.NotFound(w, r)
+                }
+        }))
+        defer api.Close()
+        s, err := newTrelloStore("trello:" + api.URL + "?board=short")
+        if err != nil {
+                t.Fatal(err)
+        }
+        ctx := context.Background()
+        if _, err := s.GetTicket(ctx, "mine"); err != nil {
+                t.Errorf("card on the board: %v", err)
+        }
+        if _, err := s.GetTicket(ctx, "theirs"); err != ErrTicketNotFound {
+                t.Errorf("card on another board: got %v, want %v", err, ErrTicketNotFound)
+        }
+        if _, err := s.ListComments(ctx, "theirs"); err != ErrTicketNotFound {
+                t.Errorf("comments on another board's card: got %v, want %v", err, ErrTicketNotFound)
+        }
+}
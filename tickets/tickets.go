@@ -0,0 +1,642 @@
+// Package tickets is the ticket tracker the server exposes: the ticket
+// stores, the tools, prompts, and resources over them, the event log, and
+// the Slack and email notifications it feeds.
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "strings"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+type Ticket struct {
+        ID          string    `json:"id"`
+        Title       string    `json:"title"`
+        Status      string    `json:"status"`
+        Description string    `json:"description,omitempty"`
+        Priority    string    `json:"priority,omitempty"`
+        Assignee    string    `json:"assignee,omitempty"`
+        Labels      []string  `json:"labels,omitempty"`
+        CreatedAt   time.Time `json:"createdAt,omitzero"`
+        DueDate     time.Time `json:"dueDate,omitzero"`
+        Deleted     bool      `json:"deleted,omitempty"`
+        DeletedAt   time.Time `json:"deletedAt,omitzero"`
+        // Version starts at 1 and increases with every update.
+        Version   int       `json:"version"`
+        UpdatedAt time.Time `json:"updatedAt,omitzero"`
+}
+
+type Comment struct {
+        ID        string    `json:"id"`
+        TicketID  string    `json:"ticketId"`
+        Author    string    `json:"author"`
+        Body      string    `json:"body"`
+        CreatedAt time.Time `json:"createdAt"`
+}
+
+type TicketsResponse struct {
+        Tickets []Ticket `json:"tickets"`
+}
+
+// demoTickets seeds the default in-memory store.
+var demoTickets = []Ticket{
+        {ID: "T1", Title: "Fix login bug", Status: "pending", Priority: "P0", Assignee: "alice", Labels: []string{"auth", "bug"}, CreatedAt: demoDate(2025, 1, 6), DueDate: demoDate(2025, 1, 10)},
+        {ID: "T2", Title: "Database indexing", Status: "pending", Priority: "P2", Assignee: "bob", Labels: []string{"performance"}, CreatedAt: demoDate(2025, 1, 8)},
+        {ID: "T10", Title: "Payment integration", Status: "done", CreatedAt: demoDate(2024, 11, 4)},
+        {ID: "T11", Title: "Email system", Status: "done", CreatedAt: demoDate(2024, 11, 18)},
+        {ID: "T20", Title: "Create dashboard UI", Status: "todo", Priority: "P3", Assignee: "alice", CreatedAt: demoDate(2025, 1, 13)},
+        {ID: "T21", Title: "Add search filter", Status: "todo", Priority: "P1", CreatedAt: demoDate(2025, 1, 14)},
+}
+
+func demoDate(year int, month time.Month, day int) time.Time {
+        return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func isValidStatus(status string) bool {
+        for _, s := range ticketStatuses() {
+                if s == status {
+                        return true
+                }
+        }
+        return false
+}
+
+func ticketStatuses() []string {
+        return []string{"todo", "pending", "done"}
+}
+
+// storeError converts a ticket store error into a tool error.
+func storeError(err error) error {
+        switch err {
+        case ErrTicketNotFound:
+                return mcp.Errorf(mcp.ErrNotFound, "Ticket not found")
+        case ErrCommentNotFound:
+                return mcp.Errorf(mcp.ErrNotFound, "Comment not found")
+        case ErrVersionConflict:
+                return mcp.Errorf(mcp.ErrConflict, "Version conflict: the ticket changed while it was being updated; re-read it and retry")
+        }
+        return err
+}
+
+// deletedFilterSchema describes the "deleted" argument accepted by listing
+// tools.
+var deletedFilterSchema = map[string]interface{}{
+        "type":        "string",
+        "enum":        []string{"exclude", "include", "only"},
+        "description": "Whether to exclude (default), include, or only return soft-deleted tickets",
+}
+
+// applyDeletedFilter sets the soft-delete fields of filter from the
+// "deleted" tool argument.
+func applyDeletedFilter(filter *TicketFilter, args map[string]interface{}) error {
+        switch mcp.StringArg(args, "deleted") {
+        case "", "exclude":
+        case "include":
+                filter.IncludeDeleted = true
+        case "only":
+                filter.DeletedOnly = true
+        default:
+                return mcp.Errorf(mcp.ErrInvalidArgument, "deleted must be one of exclude, include, only")
+        }
+        return nil
+}
+
+func ticketsByStatusTool(status string) mcp.ToolHandler {
+        return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+                filter := TicketFilter{Status: status}
+                if err := applyDeletedFilter(&filter, args); err != nil {
+                        return nil, err
+                }
+                labels, mcpErr := labelsFilterArg(args)
+                if mcpErr != nil {
+                        return nil, mcpErr
+                }
+                filter.Labels = labels
+                tickets, err := storeFrom(ctx).ListTickets(ctx, filter)
+                if err != nil {
+                        return nil, storeError(err)
+                }
+                sortByPriority(tickets)
+                return TicketsResponse{Tickets: tickets}, nil
+        }
+}
+
+// ticketCreated tells clients about a ticket that was just added.
+func ticketCreated(ctx context.Context, t Ticket) {
+        mcp.LogAll(ctx, mcp.LevelInfo, "Created ticket %s", t.ID)
+        mcp.NotifyAll(ctx, "notifications/resources/list_changed", nil)
+}
+
+// ticketUpdated logs a change to an existing ticket and notifies subscribers
+// of its resource.
+func ticketUpdated(ctx context.Context, t Ticket, format string, args ...interface{}) {
+        mcp.LogAll(ctx, mcp.LevelInfo, "Updated ticket %s: %s", t.ID, fmt.Sprintf(format, args...))
+        mcp.NotifyResourceUpdated(ctx, ticketURIPrefix+t.ID)
+}
+
+func handleDeleteTicket(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        return setTicketDeleted(ctx, args, true)
+}
+
+func handleRestoreTicket(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        return setTicketDeleted(ctx, args, false)
+}
+
+// setTicketDeleted soft-deletes or restores a ticket. Deleted tickets keep
+// their data and can be restored; they are only hidden from listings.
+func setTicketDeleted(ctx context.Context, args map[string]interface{}, deleted bool) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        if id == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id is required")
+        }
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted == deleted {
+                state := "not deleted"
+                if deleted {
+                        state = "already deleted"
+                }
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is %s", id, state)
+        }
+
+        t.Deleted = deleted
+        t.DeletedAt = time.Time{}
+        if deleted {
+                t.DeletedAt = clockFrom(ctx).Now().UTC()
+        }
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if deleted {
+                ticketUpdated(ctx, updated, "deleted")
+        } else {
+                ticketUpdated(ctx, updated, "restored")
+        }
+        mcp.NotifyAll(ctx, "notifications/resources/list_changed", nil)
+        return map[string]interface{}{"ticket": updated}, nil
+}
+
+// ticketIDSchema is the input schema of tools that take only a ticket ID.
+func ticketIDSchema() map[string]interface{} {
+        return map[string]interface{}{
+                "type": "object",
+                "properties": map[string]interface{}{
+                        "id": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Ticket ID",
+                        },
+                },
+                "required": []string{"id"},
+        }
+}
+
+// listingSchema is the input schema of the get_*_tickets tools.
+func listingSchema() map[string]interface{} {
+        return map[string]interface{}{
+                "type": "object",
+                "properties": map[string]interface{}{
+                        "deleted": deletedFilterSchema,
+                        "labels":  labelsFilterSchema,
+                },
+        }
+}
+
+var createTicketProperties = map[string]interface{}{
+        "title": map[string]interface{}{
+                "type":        "string",
+                "description": "Short summary of the ticket",
+        },
+        "description": map[string]interface{}{
+                "type":        "string",
+                "description": "Longer description of the work",
+        },
+        "status": map[string]interface{}{
+                "type":        "string",
+                "enum":        ticketStatuses(),
+                "description": "Initial status (default todo)",
+        },
+        "priority": prioritySchema,
+        "assignee": assigneeSchema,
+        "labels":   labelsSchema,
+}
+
+func handleCreateTicket(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        if err := mcp.ElicitMissingArgs(ctx, args, "Please provide the details for the new ticket.", map[string]interface{}{
+                "title":    createTicketProperties["title"],
+                "priority": createTicketProperties["priority"],
+        }, []string{"title"}); err != nil {
+                return nil, err
+        }
+
+        t := Ticket{
+                Title:       strings.TrimSpace(mcp.StringArg(args, "title")),
+                Description: mcp.StringArg(args, "description"),
+                Status:      mcp.StringArg(args, "status"),
+                Assignee:    strings.TrimSpace(mcp.StringArg(args, "assignee")),
+        }
+        labels, ok := mcp.StringsArg(args, "labels")
+        if !ok {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "labels must be an array of strings")
+        }
+        if labels = normalizeLabels(labels); len(labels) > 0 {
+                t.Labels = labels
+        }
+        priority, err := normalizePriority(mcp.StringArg(args, "priority"))
+        if err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+        t.Priority = priority
+        if t.Title == "" {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "title must not be empty")
+        }
+        if t.Status == "" {
+                t.Status = "todo"
+        }
+        if !isValidStatus(t.Status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Invalid status: %s", t.Status)
+        }
+
+        created, err := storeFrom(ctx).CreateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        ticketCreated(ctx, created)
+        return map[string]interface{}{"ticket": created}, nil
+}
+
+func init() {
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_pending_tickets",
+                Description: "Returns a list of pending tickets",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: listingSchema(),
+                Handler:     ticketsByStatusTool("pending"),
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_done_tickets",
+                Description: "Returns a list of completed tickets",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: listingSchema(),
+                Handler:     ticketsByStatusTool("done"),
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_todo_tickets",
+                Description: "Returns a list of todo tickets",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: listingSchema(),
+                Handler:     ticketsByStatusTool("todo"),
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "draft_status_report",
+                Description: "Drafts a status report for tickets in a status using the client's LLM (requires sampling support)",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "status": map[string]interface{}{
+                                        "type":        "string",
+                                        "enum":        []string{"todo", "pending", "done"},
+                                        "description": "Ticket status; elicited from the user when omitted",
+                                },
+                        },
+                },
+                Handler: handleDraftStatusReport,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "list_files",
+                Description: "Lists a directory within the client's declared roots; without a path, returns the roots",
+                Scopes:      []string{mcp.ScopeFilesRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "path": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Absolute directory path inside one of the roots",
+                                },
+                        },
+                },
+                Handler: mcp.HandleListFiles,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "create_ticket",
+                Description: "Creates a ticket and returns it with its generated ID; a missing title is requested from the user when the client supports elicitation",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: map[string]interface{}{
+                        "type":       "object",
+                        "properties": createTicketProperties,
+                },
+                Handler: handleCreateTicket,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "update_ticket_status",
+                Description: "Moves a ticket to another status, enforcing the allowed status transitions; reopening a done ticket requires reopen=true",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                                "status": map[string]interface{}{
+                                        "type": "string",
+                                        "enum": ticketStatuses(),
+                                },
+                                "reopen": map[string]interface{}{
+                                        "type":        "boolean",
+                                        "description": "Allow moving a done ticket back to todo or pending",
+                                },
+                        },
+                        "required": []string{"id", "status"},
+                }),
+                Handler: handleUpdateTicketStatus,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "delete_ticket",
+                Description: "Soft-deletes a ticket: it is hidden from listings but can be restored",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(ticketIDSchema()),
+                Handler:     handleDeleteTicket,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "restore_ticket",
+                Description: "Restores a soft-deleted ticket",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(ticketIDSchema()),
+                Handler:     handleRestoreTicket,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "search_tickets",
+                Description: "Searches tickets by free text with optional status and creation date filters, sorting, and a result limit",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: searchTicketsSchema(),
+                Handler:     handleSearchTickets,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "add_comment",
+                Description: "Adds a comment to a ticket",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: commentToolSchema(map[string]interface{}{
+                        "body": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Comment text",
+                        },
+                        "author": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Who is commenting (default anonymous)",
+                        },
+                }, "body"),
+                Handler: handleAddComment,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "list_comments",
+                Description: "Lists a ticket's comments, oldest first",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: commentToolSchema(nil),
+                Handler:     handleListComments,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "delete_comment",
+                Description: "Deletes a comment from a ticket",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: commentToolSchema(map[string]interface{}{
+                        "comment_id": map[string]interface{}{
+                                "type":        "string",
+                                "description": "Comment ID",
+                        },
+                }, "comment_id"),
+                Handler: handleDeleteComment,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "assign_ticket",
+                Description: "Assigns a ticket to a person",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                                "assignee": assigneeSchema,
+                        },
+                        "required": []string{"id", "assignee"},
+                }),
+                Handler: handleAssignTicket,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "unassign_ticket",
+                Description: "Removes a ticket's assignee",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(ticketIDSchema()),
+                Handler:     handleUnassignTicket,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_tickets_by_assignee",
+                Description: "Returns the tickets assigned to a person, optionally limited to one status",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "assignee": assigneeSchema,
+                                "status": map[string]interface{}{
+                                        "type": "string",
+                                        "enum": ticketStatuses(),
+                                },
+                                "deleted": deletedFilterSchema,
+                                "labels":  labelsFilterSchema,
+                        },
+                        "required": []string{"assignee"},
+                },
+                Handler: handleTicketsByAssignee,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "set_due_date",
+                Description: "Sets or clears a ticket's due date; a plain date is due at the end of that day in the given timezone",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                                "due_date": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "YYYY-MM-DD or RFC 3339 timestamp; empty to clear",
+                                },
+                                "timezone": timezoneSchema,
+                        },
+                        "required": []string{"id"},
+                }),
+                Handler: handleSetDueDate,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_overdue_tickets",
+                Description: "Returns open tickets whose due date has passed, earliest first",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "assignee": assigneeSchema,
+                                "timezone": timezoneSchema,
+                        },
+                },
+                Handler: handleOverdueTickets,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "add_labels",
+                Description: "Adds labels to a ticket",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(labelChangeSchema()),
+                Handler:     handleAddLabels,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "remove_labels",
+                Description: "Removes labels from a ticket",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(labelChangeSchema()),
+                Handler:     handleRemoveLabels,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "list_labels",
+                Description: "Lists every label in use with the number of tickets carrying it",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: mcp.NoArgsSchema(),
+                Handler:     handleListLabels,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "set_ticket_priority",
+                Description: "Sets a ticket's priority (P0-P3), or clears it when priority is empty",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: versionedSchema(map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                                "priority": prioritySchema,
+                        },
+                        "required": []string{"id"},
+                }),
+                Handler: handleSetTicketPriority,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "bulk_update_tickets",
+                Description: "Applies a status, assignee, priority, or label change to a list of tickets or to every ticket matching a filter, reporting success or failure per ticket",
+                Scopes:      []string{mcp.ScopeTicketsAdmin},
+                InputSchema: bulkUpdateSchema,
+                Handler:     handleBulkUpdateTickets,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "snapshot_now",
+                Description: "Admin: writes the ticket store's current state to its snapshot file immediately",
+                Scopes:      []string{mcp.ScopeTicketsAdmin},
+                InputSchema: mcp.NoArgsSchema(),
+                Handler:     handleSnapshotNow,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "server_stats",
+                Description: "Reports the server's uptime, connections, request and tool call counts, and ticket store statistics",
+                Scopes:      []string{mcp.ScopeServerRead},
+                InputSchema: mcp.NoArgsSchema(),
+                Handler:     handleServerStats,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "get_ticket_history",
+                Description: "Lists the recorded changes to a ticket, oldest first",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                        },
+                        "required": []string{"id"},
+                },
+                Handler: handleTicketHistory,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "undo_ticket_change",
+                Description: "Reverts the most recent change to a ticket that hasn't been undone yet; call repeatedly to step further back",
+                Scopes:      []string{mcp.ScopeTicketsWrite},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "id": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Ticket ID",
+                                },
+                        },
+                        "required": []string{"id"},
+                },
+                Handler: handleUndoTicketChange,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "export_tickets",
+                Description: "Exports the tickets matching optional filters as CSV and returns the URI of an export:// resource to read it from",
+                Scopes:      []string{mcp.ScopeTicketsRead},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "query": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Text to match in the title or description",
+                                },
+                                "status": map[string]interface{}{
+                                        "type": "string",
+                                        "enum": ticketStatuses(),
+                                },
+                                "assignee": assigneeSchema,
+                                "priority": prioritySchema,
+                                "labels":   labelsFilterSchema,
+                                "deleted":  deletedFilterSchema,
+                        },
+                },
+                Handler: handleExportTickets,
+        })
+        mcp.RegisterTool(&mcp.Tool{
+                Name:        "import_tickets",
+                Description: "Creates tickets from the rows of a CSV or XLSX file with a header row (title required; status, priority, assignee, labels separated by semicolons, due_date, description). Use dry_run to validate without creating anything",
+                Scopes:      []string{mcp.ScopeTicketsAdmin},
+                InputSchema: map[string]interface{}{
+                        "type": "object",
+                        "properties": map[string]interface{}{
+                                "content": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "CSV text",
+                                },
+                                "content_base64": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "Base64-encoded CSV or XLSX file",
+                                },
+                                "uri": map[string]interface{}{
+                                        "type":        "string",
+                                        "description": "file:// URI of a CSV or XLSX file within the client's roots",
+                                },
+                                "format": map[string]interface{}{
+                                        "type":        "string",
+                                        "enum":        []string{"csv", "xlsx"},
+                                        "description": "File format; detected from the content when omitted",
+                                },
+                                "dry_run": map[string]interface{}{
+                                        "type":        "boolean",
+                                        "description": "Validate the rows and report the tickets that would be created without creating them",
+                                },
+                        },
+                },
+                Handler: handleImportTickets,
+        })
+}
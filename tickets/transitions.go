@@ -0,0 +1,142 @@
+package tickets
+
+import (
+        "context"
+        "fmt"
+        "strings"
+
+        "mcp-server/mcp"
+)
+
+// Default status transitions. Reopening a finished ticket is only allowed
+// when the caller asks for it explicitly.
+const (
+        DefaultStatusTransitions = "todo>pending,todo>done,pending>todo,pending>done"
+        DefaultReopenTransitions = "done>todo,done>pending"
+)
+
+// A StatusMachine lists the allowed status changes, keyed by current
+// status.
+type StatusMachine struct {
+        allowed map[string]map[string]bool
+        reopen  map[string]map[string]bool
+}
+
+// DefaultStatusMachine allows the default transitions. It governs the
+// tools of connections whose context carries no other.
+var DefaultStatusMachine = mustStatusMachine(DefaultStatusTransitions, DefaultReopenTransitions)
+
+type statusMachineContextKey struct{}
+
+// ContextWithStatusMachine returns a copy of ctx carrying m, which governs
+// the status changes made by the tools of connections whose context
+// derives from it.
+func ContextWithStatusMachine(ctx context.Context, m *StatusMachine) context.Context {
+        return context.WithValue(ctx, statusMachineContextKey{}, m)
+}
+
+// statusMachineFrom returns the status machine ctx carries, or
+// DefaultStatusMachine.
+func statusMachineFrom(ctx context.Context) *StatusMachine {
+        if m, ok := ctx.Value(statusMachineContextKey{}).(*StatusMachine); ok {
+                return m
+        }
+        return DefaultStatusMachine
+}
+
+// parseTransitions parses a comma-separated list of from>to pairs.
+func parseTransitions(spec string) (map[string]map[string]bool, error) {
+        transitions := make(map[string]map[string]bool)
+        for _, pair := range strings.Split(spec, ",") {
+                pair = strings.TrimSpace(pair)
+                if pair == "" {
+                        continue
+                }
+                from, to, ok := strings.Cut(pair, ">")
+                from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+                if !ok || from == "" || to == "" {
+                        return nil, fmt.Errorf("invalid transition %q: want from>to", pair)
+                }
+                for _, status := range []string{from, to} {
+                        if !isValidStatus(status) {
+                                return nil, fmt.Errorf("invalid transition %q: unknown status %q", pair, status)
+                        }
+                }
+                if transitions[from] == nil {
+                        transitions[from] = make(map[string]bool)
+                }
+                transitions[from][to] = true
+        }
+        return transitions, nil
+}
+
+// NewStatusMachine returns a machine allowing the comma-separated from>to
+// transitions, and the reopen ones when the caller asks to reopen.
+func NewStatusMachine(transitions, reopen string) (*StatusMachine, error) {
+        allowed, err := parseTransitions(transitions)
+        if err != nil {
+                return nil, err
+        }
+        reopenAllowed, err := parseTransitions(reopen)
+        if err != nil {
+                return nil, err
+        }
+        return &StatusMachine{allowed: allowed, reopen: reopenAllowed}, nil
+}
+
+func mustStatusMachine(transitions, reopen string) *StatusMachine {
+        m, err := NewStatusMachine(transitions, reopen)
+        if err != nil {
+                panic(err)
+        }
+        return m
+}
+
+// check reports whether a ticket may move from one status to another.
+func (m *StatusMachine) check(from, to string, reopen bool) error {
+        if m.allowed[from][to] {
+                return nil
+        }
+        if m.reopen[from][to] {
+                if reopen {
+                        return nil
+                }
+                return fmt.Errorf("Moving a ticket from %s to %s requires reopen=true", from, to)
+        }
+        return fmt.Errorf("Illegal status transition: %s -> %s", from, to)
+}
+
+func handleUpdateTicketStatus(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        id := mcp.StringArg(args, "id")
+        status := mcp.StringArg(args, "status")
+        reopen, _ := args["reopen"].(bool)
+        if id == "" || !isValidStatus(status) {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "id and a status of todo, pending, or done are required")
+        }
+
+        t, err := storeFrom(ctx).GetTicket(ctx, id)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        if mcpErr := checkVersion(args, t); mcpErr != nil {
+                return nil, mcpErr
+        }
+        if t.Deleted {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "Ticket %s is deleted; restore it first", id)
+        }
+        if t.Status == status {
+                return map[string]interface{}{"ticket": t}, nil
+        }
+        if err := statusMachineFrom(ctx).check(t.Status, status, reopen); err != nil {
+                return nil, mcp.Errorf(mcp.ErrInvalidArgument, "%v", err)
+        }
+
+        previous := t.Status
+        t.Status = status
+        updated, err := storeFrom(ctx).UpdateTicket(ctx, t)
+        if err != nil {
+                return nil, storeError(err)
+        }
+        ticketUpdated(ctx, updated, "status %s -> %s", previous, status)
+        return map[string]interface{}{"ticket": updated}, nil
+}
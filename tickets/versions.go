@@ -0,0 +1,38 @@
+package tickets
+
+import (
+        "slices"
+
+        "mcp-server/mcp"
+)
+
+// checkVersion enforces optimistic concurrency for update tools: the caller
+// must pass the version of the ticket it last read, and the update is
+// refused if the ticket has changed since.
+func checkVersion(args map[string]interface{}, t Ticket) error {
+        version, ok := args["version"].(float64)
+        if !ok {
+                return mcp.Errorf(mcp.ErrInvalidArgument, "version is required; read the ticket to get its current version")
+        }
+        if int(version) != t.Version {
+                return versionConflict(t.ID, int(version), t.Version)
+        }
+        return nil
+}
+
+func versionConflict(id string, expected, actual int) error {
+        return mcp.Errorf(mcp.ErrConflict, "Version conflict: ticket %s is at version %d, not %d; re-read it and retry", id, actual, expected)
+}
+
+// versionedSchema adds the required "version" argument to an update tool's
+// input schema.
+func versionedSchema(schema map[string]interface{}) map[string]interface{} {
+        properties := schema["properties"].(map[string]interface{})
+        properties["version"] = map[string]interface{}{
+                "type":        "integer",
+                "description": "Version of the ticket the change is based on; the update fails with a conflict if the ticket has changed since",
+        }
+        required, _ := schema["required"].([]string)
+        schema["required"] = append(slices.Clone(required), "version")
+        return schema
+}
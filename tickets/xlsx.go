@@ -0,0 +1,192 @@
+package tickets
+
+import (
+        "archive/zip"
+        "bytes"
+        "encoding/xml"
+        "fmt"
+        "io"
+        "path"
+        "strconv"
+        "strings"
+        "time"
+)
+
+// readXLSXRows returns the cell text of the first worksheet of an .xlsx
+// workbook, row by row. Only what a tabular import needs is supported:
+// shared, inline, and literal cell values; styles and formulas are ignored.
+func readXLSXRows(data []byte) ([][]string, error) {
+        zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+        if err != nil {
+                return nil, fmt.Errorf("not an xlsx workbook: %w", err)
+        }
+        files := make(map[string]*zip.File, len(zr.File))
+        for _, f := range zr.File {
+                files[f.Name] = f
+        }
+
+        var shared []string
+        if f, ok := files["xl/sharedStrings.xml"]; ok {
+                var sst struct {
+                        Items []xlsxText `xml:"si"`
+                }
+                if err := decodeZipXML(f, &sst); err != nil {
+                        return nil, err
+                }
+                for _, si := range sst.Items {
+                        shared = append(shared, si.String())
+                }
+        }
+
+        sheet, err := firstSheetPath(files)
+        if err != nil {
+                return nil, err
+        }
+        var ws struct {
+                Rows []struct {
+                        Cells []struct {
+                                Ref    string   `xml:"r,attr"`
+                                Type   string   `xml:"t,attr"`
+                                Value  string   `xml:"v"`
+                                Inline xlsxText `xml:"is"`
+                        } `xml:"c"`
+                } `xml:"sheetData>row"`
+        }
+        if err := decodeZipXML(files[sheet], &ws); err != nil {
+                return nil, err
+        }
+
+        rows := make([][]string, 0, len(ws.Rows))
+        for _, r := range ws.Rows {
+                var row []string
+                for i, c := range r.Cells {
+                        col := i
+                        if c.Ref != "" {
+                                if col, err = xlsxColumn(c.Ref); err != nil {
+                                        return nil, err
+                                }
+                        }
+                        if col >= xlsxMaxColumns {
+                                return nil, fmt.Errorf("row has more than %d cells", xlsxMaxColumns)
+                        }
+                        var text string
+                        switch c.Type {
+                        case "s":
+                                n, err := strconv.Atoi(c.Value)
+                                if err != nil || n < 0 || n >= len(shared) {
+                                        return nil, fmt.Errorf("cell %s refers to a missing shared string", c.Ref)
+                                }
+                                text = shared[n]
+                        case "inlineStr":
+                                text = c.Inline.String()
+                        default:
+                                text = c.Value
+                        }
+                        for len(row) <= col {
+                                row = append(row, "")
+                        }
+                        row[col] = text
+                }
+                rows = append(rows, row)
+        }
+        return rows, nil
+}
+
+// xlsxText is rich or plain cell text: either a single <t> or runs of <r><t>.
+type xlsxText struct {
+        T    string `xml:"t"`
+        Runs []struct {
+                T string `xml:"t"`
+        } `xml:"r"`
+}
+
+func (x xlsxText) String() string {
+        if len(x.Runs) == 0 {
+                return x.T
+        }
+        var b strings.Builder
+        for _, r := range x.Runs {
+                b.WriteString(r.T)
+        }
+        return b.String()
+}
+
+// firstSheetPath finds the part holding the workbook's first worksheet.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+        var workbook struct {
+                Sheets []struct {
+                        RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+                } `xml:"sheets>sheet"`
+        }
+        var rels struct {
+                Rels []struct {
+                        ID     string `xml:"Id,attr"`
+                        Target string `xml:"Target,attr"`
+                } `xml:"Relationship"`
+        }
+        wb, okWB := files["xl/workbook.xml"]
+        rf, okRels := files["xl/_rels/workbook.xml.rels"]
+        if okWB && okRels {
+                if err := decodeZipXML(wb, &workbook); err != nil {
+                        return "", err
+                }
+                if err := decodeZipXML(rf, &rels); err != nil {
+                        return "", err
+                }
+                for _, r := range rels.Rels {
+                        if len(workbook.Sheets) > 0 && r.ID == workbook.Sheets[0].RelID {
+                                target := strings.TrimPrefix(r.Target, "/")
+                                if !strings.HasPrefix(target, "xl/") {
+                                        target = path.Join("xl", target)
+                                }
+                                if _, ok := files[target]; ok {
+                                        return target, nil
+                                }
+                        }
+                }
+        }
+        if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+                return "xl/worksheets/sheet1.xml", nil
+        }
+        return "", fmt.Errorf("the workbook has no worksheets")
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+        r, err := f.Open()
+        if err != nil {
+                return err
+        }
+        defer r.Close()
+        if err := xml.NewDecoder(io.LimitReader(r, maxImportSize*10)).Decode(v); err != nil {
+                return fmt.Errorf("reading %s: %w", f.Name, err)
+        }
+        return nil
+}
+
+// xlsxMaxColumns is how many columns a worksheet may have, A to XFD.
+const xlsxMaxColumns = 16384
+
+// xlsxColumn returns the zero-based column of a cell reference like "C7".
+func xlsxColumn(ref string) (int, error) {
+        col := 0
+        for _, r := range ref {
+                if r < 'A' || r > 'Z' {
+                        break
+                }
+                col = col*26 + int(r-'A'+1)
+                if col > xlsxMaxColumns {
+                        return 0, fmt.Errorf("cell %.20s is beyond column XFD", ref)
+                }
+        }
+        if col == 0 {
+                return 0, fmt.Errorf("cell reference %.20q has no column", ref)
+        }
+        return col - 1, nil
+}
+
+// xlsxSerialDate converts an Excel date serial number, as stored in date
+// cells, to a date.
+func xlsxSerialDate(serial float64) time.Time {
+        epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+        return epoch.Add(time.Duration(serial * float64(24*time.Hour)))
+}
@@ -0,0 +1,48 @@
+package tickets
+
+import (
+        "archive/zip"
+        "bytes"
+        "slices"
+        "strings"
+        "testing"
+)
+
+// xlsxWorkbook returns a workbook whose only sheet has the given
+// <sheetData> rows.
+func xlsxWorkbook(t *testing.T, rows string) []byte {
+        t.Helper()
+        var buf bytes.Buffer
+        zw := zip.NewWriter(&buf)
+        for name, content := range map[string]string{
+                "xl/workbook.xml":            `<workbook><sheets><sheet name="Tickets" r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets></workbook>`,
+                "xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+                "xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`,
+        } {
+                w, err := zw.Create(name)
+                if err != nil {
+                        t.Fatal(err)
+                }
+                w.Write([]byte(content))
+        }
+        if err := zw.Close(); err != nil {
+                t.Fatal(err)
+        }
+        return buf.Bytes()
+}
+
+func TestReadXLSXRows(t *testing.T) {
+        rows, err := readXLSXRows(xlsxWorkbook(t, `<row><c r="A1" t="inlineStr"><is><t>title</t></is></c><c r="C1"><v>3</v></c></row>`))
+        if err != nil {
+                t.Fatal(err)
+        }
+        if len(rows) != 1 || !slices.Equal(rows[0], []string{"title", "", "3"}) {
+                t.Fatalf("got %q", rows)
+        }
+
+        for _, ref := range []string{"1", "ZZZZ1", strings.Repeat("A", 100) + "1"} {
+                if _, err := readXLSXRows(xlsxWorkbook(t, `<row><c r="`+ref+`"><v>x</v></c></row>`)); err == nil {
+                        t.Errorf("cell %.10s: got no error", ref)
+                }
+        }
+}
@@ -0,0 +1,164 @@
+package transport
+
+import (
+        "crypto/subtle"
+        "fmt"
+        "net/http"
+        "os"
+        "strings"
+
+        "gopkg.in/yaml.v3"
+        "mcp-server/mcp"
+)
+
+// Auth is how a Server authenticates its clients. The zero value accepts
+// unauthenticated connections.
+type Auth struct {
+        // APIKeys maps each accepted API key to the identity it
+        // authenticates as.
+        APIKeys map[string]string
+        // APIKeyQuery, set by -api-key-query, also accepts an API key in the
+        // api_key query parameter, for browsers, which can't set headers on
+        // WebSocket requests. It is off by default because URLs, and the key
+        // with them, end up in access logs.
+        APIKeyQuery bool
+        // JWT validates JWT access tokens when -jwt-issuer or -jwks-url is
+        // set.
+        JWT *jwtVerifier
+        // OAuth validates OAuth 2.1 access tokens when -oauth-issuer is set.
+        OAuth *oauthResourceServer
+        // ClientCerts authenticates clients by certificate when
+        // -tls-client-ca is set, in place of the other methods.
+        ClientCerts *clientCertAuth
+}
+
+// LoadAPIKeys reads keys from path, a YAML or JSON map of identity to key,
+// and from the environment: MCP_API_KEYS holds comma-separated
+// identity:key pairs and MCP_API_KEY a single key for the identity
+// "default".
+func LoadAPIKeys(path string) (map[string]string, error) {
+        keys := map[string]string{}
+        add := func(identity, key string) error {
+                identity, key = strings.TrimSpace(identity), strings.TrimSpace(key)
+                if identity == "" || key == "" {
+                        return fmt.Errorf("API key entries need an identity and a key")
+                }
+                if other, ok := keys[key]; ok && other != identity {
+                        return fmt.Errorf("identities %s and %s share an API key", other, identity)
+                }
+                keys[key] = identity
+                return nil
+        }
+
+        if path != "" {
+                data, err := os.ReadFile(path)
+                if err != nil {
+                        return nil, err
+                }
+                var byIdentity map[string]string
+                if err := yaml.Unmarshal(data, &byIdentity); err != nil {
+                        return nil, fmt.Errorf("parsing %s: %w", path, err)
+                }
+                for identity, key := range byIdentity {
+                        if err := add(identity, mcp.ExpandSecrets(key)); err != nil {
+                                return nil, fmt.Errorf("%s: %w", path, err)
+                        }
+                }
+        }
+        if env := mcp.Secret("MCP_API_KEYS"); env != "" {
+                for _, pair := range strings.Split(env, ",") {
+                        identity, key, _ := strings.Cut(pair, ":")
+                        if err := add(identity, key); err != nil {
+                                return nil, fmt.Errorf("MCP_API_KEYS: %w", err)
+                        }
+                }
+        }
+        if key := mcp.Secret("MCP_API_KEY"); key != "" {
+                if err := add("default", key); err != nil {
+                        return nil, fmt.Errorf("MCP_API_KEY: %w", err)
+                }
+        }
+        return keys, nil
+}
+
+// requestAPIKey returns the key a client presented, from an
+// "Authorization: Bearer" header, an X-API-Key header, or, with
+// APIKeyQuery, the api_key query parameter.
+func (a *Auth) requestAPIKey(r *http.Request) string {
+        if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+                return strings.TrimSpace(token)
+        }
+        if key := r.Header.Get("X-API-Key"); key != "" {
+                return key
+        }
+        if a.APIKeyQuery {
+                return r.URL.Query().Get("api_key")
+        }
+        return ""
+}
+
+// authFailure is why a request was refused authentication.
+type authFailure struct {
+        status int
+        // code is the OAuth error reported in WWW-Authenticate, if any, such
+        // as invalid_token or insufficient_scope.
+        code   string
+        reason string
+}
+
+// authenticate returns the client making r. A client certificate, when
+// they are required, identifies the client by itself; otherwise it tries
+// API keys, then JWTs, then OAuth token introspection. The principal is
+// empty when authentication is off.
+func (a *Auth) authenticate(r *http.Request) (mcp.Principal, *authFailure) {
+        if a.ClientCerts != nil {
+                return a.ClientCerts.authenticate(r)
+        }
+        if len(a.APIKeys) == 0 && a.JWT == nil && a.OAuth == nil {
+                return mcp.Principal{}, nil
+        }
+        presented := a.requestAPIKey(r)
+        if presented == "" {
+                return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, reason: "no credentials"}
+        }
+        if identity, ok := a.matchAPIKey(presented); ok {
+                return mcp.Principal{Identity: identity}, nil
+        }
+        if a.JWT != nil && looksLikeJWT(presented) {
+                return a.JWT.authenticate(presented)
+        }
+        if a.OAuth != nil {
+                return a.OAuth.authenticate(r.Context(), presented)
+        }
+        return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, reason: "invalid credentials"}
+}
+
+func (a *Auth) matchAPIKey(presented string) (identity string, ok bool) {
+        // Compare against every key so the time taken doesn't reveal which
+        // keys share a prefix with the one presented.
+        for key, id := range a.APIKeys {
+                if subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+                        identity, ok = id, true
+                }
+        }
+        return identity, ok
+}
+
+// rejectUnauthenticated answers a request that failed authentication.
+func (srv *Server) rejectUnauthenticated(w http.ResponseWriter, r *http.Request, failure *authFailure) {
+        if failure.status == http.StatusForbidden {
+                mcp.ConnectionsRejected.Add(1, "forbidden")
+        } else {
+                mcp.ConnectionsRejected.Add(1, "unauthenticated")
+        }
+        srv.logger().Warn("Rejected connection", "remote", r.RemoteAddr, "reason", failure.reason)
+        challenge := `Bearer realm="mcp-server"`
+        if srv.Auth.OAuth != nil {
+                challenge += srv.Auth.OAuth.challengeParams()
+        }
+        if failure.code != "" {
+                challenge += fmt.Sprintf(`, error=%q, error_description=%q`, failure.code, failure.reason)
+        }
+        w.Header().Set("WWW-Authenticate", challenge)
+        http.Error(w, http.StatusText(failure.status), failure.status)
+}
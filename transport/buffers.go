@@ -0,0 +1,33 @@
+package transport
+
+import (
+        "bytes"
+        "io"
+        "sync"
+)
+
+// maxPooledFrame is the capacity beyond which a frame's buffer is dropped
+// rather than pooled, so that one large message doesn't pin its memory for
+// good.
+const maxPooledFrame = 1 << 20
+
+// frames holds the buffers incoming messages are read into.
+var frames = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// readFrame reads a message from r into a pooled buffer, which the caller
+// hands back with releaseFrame once nothing refers to its bytes.
+func readFrame(r io.Reader) (*bytes.Buffer, error) {
+        buf := frames.Get().(*bytes.Buffer)
+        if _, err := buf.ReadFrom(r); err != nil {
+                releaseFrame(buf)
+                return nil, err
+        }
+        return buf, nil
+}
+
+func releaseFrame(buf *bytes.Buffer) {
+        if buf.Cap() <= maxPooledFrame {
+                buf.Reset()
+                frames.Put(buf)
+        }
+}
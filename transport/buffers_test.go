@@ -0,0 +1,35 @@
+package transport
+
+import (
+        "bytes"
+        "io"
+        "strings"
+        "testing"
+)
+
+// BenchmarkReadFrame compares reading messages into pooled buffers with
+// io.ReadAll, which allocates, and grows, a buffer for each.
+func BenchmarkReadFrame(b *testing.B) {
+        message := []byte(`{"jsonrpc":"2.0","id":"1","method":"tools/call","params":{"name":"create_ticket","arguments":{"title":"` +
+                strings.Repeat("x", 4096) + `"}}}`)
+        b.Run("io.ReadAll", func(b *testing.B) {
+                b.ReportAllocs()
+                b.SetBytes(int64(len(message)))
+                for i := 0; i < b.N; i++ {
+                        if _, err := io.ReadAll(bytes.NewReader(message)); err != nil {
+                                b.Fatal(err)
+                        }
+                }
+        })
+        b.Run("pooled", func(b *testing.B) {
+                b.ReportAllocs()
+                b.SetBytes(int64(len(message)))
+                for i := 0; i < b.N; i++ {
+                        frame, err := readFrame(bytes.NewReader(message))
+                        if err != nil {
+                                b.Fatal(err)
+                        }
+                        releaseFrame(frame)
+                }
+        })
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+        "bytes"
+        "context"
+        "encoding/json"
+        "testing"
+        "time"
+)
+
+// FuzzHandleMessage feeds arbitrary frames to a connection, as its read
+// loop would, and checks that everything the server sends back is a
+// well-formed JSON-RPC message.
+func FuzzHandleMessage(f *testing.F) {
+        for _, seed := range []string{
+                `{"jsonrpc":"2.0","id":"1","method":"initialize","params":{"protocolVersion":"2025-06-18","clientInfo":{"name":"fuzz","version":"1"}}}`,
+                `{"jsonrpc":"2.0","id":"2","method":"tools/list"}`,
+                `{"jsonrpc":"2.0","id":"3","method":"tools/call","params":{"name":"x","arguments":{"a":[1,{"b":null}]}}}`,
+                `{"jsonrpc":"2.0","id":"4","method":"resources/list","params":{"cursor":"NTA"}}`,
+                `{"jsonrpc":"2.0","id":"5","method":"logging/setLevel","params":{"level":"debug"}}`,
+                `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"1"}}`,
+                `{"jsonrpc":"2.0","id":"6","result":{}}`,
+                `{"jsonrpc":"2.0","id":"7","error":{"code":-1,"message":"no"}}`,
+                `{"id":8,"method":"ping"}`,
+                `[{"id":"9","method":"ping"}]`,
+                `{"method":null,"params":"x"}`,
+                `[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[[`,
+                `{not json`,
+                ``,
+        } {
+                f.Add([]byte(seed))
+        }
+        f.Fuzz(func(t *testing.T, message []byte) {
+                client, _ := Pipe()
+                defer client.Close()
+                if err := client.Send(message); err != nil {
+                        t.Fatal(err)
+                }
+                // The answer to a ping sent after the message marks the
+                // end of what the message produced, give or take requests
+                // still running.
+                client.Send([]byte(`{"jsonrpc":"2.0","id":"fuzz-ping","method":"ping"}`))
+
+                ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+                defer cancel()
+                for {
+                        data, err := client.Receive(ctx)
+                        if err != nil {
+                                t.Fatalf("no answer to ping after %q: %v", message, err)
+                        }
+                        var msg struct {
+                                JSONRPC string           `json:"jsonrpc"`
+                                ID      *json.RawMessage `json:"id"`
+                                Method  string           `json:"method"`
+                                Params  json.RawMessage  `json:"params"`
+                                Result  json.RawMessage  `json:"result"`
+                                Error   *struct {
+                                        Code    *int            `json:"code"`
+                                        Message *string         `json:"message"`
+                                        Data    json.RawMessage `json:"data"`
+                                } `json:"error"`
+                        }
+                        decoder := json.NewDecoder(bytes.NewReader(data))
+                        decoder.DisallowUnknownFields()
+                        if err := decoder.Decode(&msg); err != nil {
+                                t.Fatalf("after %q the server sent %s: %v", message, data, err)
+                        }
+                        if msg.JSONRPC != "2.0" {
+                                t.Fatalf("after %q the server sent %s without jsonrpc 2.0", message, data)
+                        }
+                        if msg.Method != "" {
+                                continue
+                        }
+                        if msg.ID == nil || (msg.Result == nil) == (msg.Error == nil) {
+                                t.Fatalf("after %q the server sent a response %s without an id and one of result or error", message, data)
+                        }
+                        if msg.Error != nil && (msg.Error.Code == nil || msg.Error.Message == nil) {
+                                t.Fatalf("after %q the server sent an error %s without a code and message", message, data)
+                        }
+                        if string(*msg.ID) == `"fuzz-ping"` {
+                                return
+                        }
+                }
+        })
+}
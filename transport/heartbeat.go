@@ -0,0 +1,121 @@
+package transport
+
+import (
+        "time"
+
+        "github.com/gorilla/websocket"
+        "mcp-server/mcp"
+)
+
+// Timeouts keep dead and stalled peers from holding a connection's
+// goroutines forever. Every Ping the server pings the client, which must
+// answer (or send anything) within Pong of the next ping being due, or the
+// connection is dropped as dead; Read bounds the wait for the next frame
+// from a client, and is Ping+Pong by default, or none with pings off.
+// Write bounds writing each message, Handshake reading a request's
+// headers and completing the WebSocket upgrade, and Body reading the body
+// of a Streamable HTTP POST. A connection that sends no messages for Idle
+// while no request is running is closed. Zero disables each of them.
+type Timeouts struct {
+        Ping      time.Duration
+        Pong      time.Duration
+        Read      time.Duration
+        Write     time.Duration
+        Handshake time.Duration
+        Body      time.Duration
+        Idle      time.Duration
+}
+
+// DefaultTimeouts are the timeouts the flags default to.
+var DefaultTimeouts = Timeouts{
+        Ping:      30 * time.Second,
+        Pong:      10 * time.Second,
+        Write:     10 * time.Second,
+        Handshake: 10 * time.Second,
+        Body:      30 * time.Second,
+}
+
+// controlWriteTimeout bounds writing a ping or close frame.
+const controlWriteTimeout = 5 * time.Second
+
+// readWindow is how long the server waits for the next frame from a
+// client, or zero to wait forever.
+func (t Timeouts) readWindow() time.Duration {
+        if t.Read > 0 {
+                return t.Read
+        }
+        if t.Ping > 0 {
+                return t.Ping + t.Pong
+        }
+        return 0
+}
+
+// startHeartbeat arms the read deadline that detects dead peers and starts
+// pinging the client and watching for idleness.
+func (t *wsTransport) startHeartbeat() {
+        t.touch()
+        if t.srv.Timeouts.readWindow() > 0 {
+                t.extendReadDeadline()
+                t.ws.SetPongHandler(func(string) error {
+                        t.extendReadDeadline()
+                        return nil
+                })
+        }
+        go t.heartbeat()
+}
+
+// touch records that the client sent a message, which keeps the
+// connection alive and resets the idle timer.
+func (t *wsTransport) touch() {
+        t.conn.Touch()
+        if t.srv.Timeouts.readWindow() > 0 {
+                t.extendReadDeadline()
+        }
+}
+
+func (t *wsTransport) extendReadDeadline() {
+        t.ws.SetReadDeadline(time.Now().Add(t.srv.Timeouts.readWindow()))
+}
+
+// idle reports whether the client has sent nothing for the idle timeout
+// and no request of its is running.
+func (t *wsTransport) idle() bool {
+        return time.Since(t.conn.LastSeen()) >= t.srv.Timeouts.Idle && !t.conn.Busy()
+}
+
+func (t *wsTransport) heartbeat() {
+        c := t.conn
+        timeouts := t.srv.Timeouts
+        var pings, idleChecks <-chan time.Time
+        if timeouts.Ping > 0 {
+                ticker := time.NewTicker(timeouts.Ping)
+                defer ticker.Stop()
+                pings = ticker.C
+        }
+        if timeouts.Idle > 0 {
+                ticker := time.NewTicker(max(timeouts.Idle/10, time.Second))
+                defer ticker.Stop()
+                idleChecks = ticker.C
+        }
+        for {
+                select {
+                case <-pings:
+                        if err := t.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(controlWriteTimeout)); err != nil {
+                                // The read deadline will expire and end the connection.
+                                c.Logger().Debug("Ping failed", "error", err)
+                        }
+                case <-idleChecks:
+                        if t.idle() {
+                                c.Logf(c.Context(), mcp.LevelInfo, "Closing connection idle for %s", timeouts.Idle)
+                                c.Disconnected(mcp.CloseIdleTimeout)
+                                t.ws.WriteControl(websocket.CloseMessage,
+                                        websocket.FormatCloseMessage(websocket.CloseNormalClosure, "idle timeout"),
+                                        time.Now().Add(controlWriteTimeout))
+                                t.ws.Close()
+                                return
+                        }
+                case <-c.Context().Done():
+                        return
+                }
+        }
+}
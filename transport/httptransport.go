@@ -0,0 +1,757 @@
+package transport
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "log/slog"
+        "net"
+        "net/http"
+        "os"
+        "strconv"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// SessionRetention is how long a Streamable HTTP session, and the messages
+// sent on its event stream, are kept after its client was last seen, set
+// by -session-retention. A client that reconnects within it resumes the
+// session and receives the messages it missed. It is the same for every
+// server in the process, as SharedSessions expire in Redis after it.
+var SessionRetention = 5 * time.Minute
+
+// MaxSessionsPerClient is the default of Server.MaxSessionsPerClient for
+// servers made by package server, set by -max-sessions-per-client.
+var MaxSessionsPerClient = 16
+
+// errTooManySessions is returned when a session would take its client
+// beyond its server's MaxSessionsPerClient.
+var errTooManySessions = errors.New("too many sessions")
+
+// maxRetainedEvents caps the messages kept for replay per session; the
+// oldest are dropped first.
+const maxRetainedEvents = 1000
+
+// sessionHeader carries the session ID on every request after initialize.
+const sessionHeader = "Mcp-Session-Id"
+
+// httpSessionTable maps session IDs to the Streamable HTTP sessions of
+// one Server.
+type httpSessionTable struct {
+        mu sync.Mutex
+        m  map[string]*httpStream
+        // perClient counts the sessions of each client, by sessionClient.
+        perClient map[string]int
+}
+
+// sessionTables holds the tables that have sessions in them, for cluster
+// messages, which name a session but not the Server serving it.
+var sessionTables = struct {
+        sync.Mutex
+        m map[*httpSessionTable]bool
+}{m: make(map[*httpSessionTable]bool)}
+
+func (t *httpSessionTable) get(id string) *httpStream {
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        return t.m[id]
+}
+
+// add adds s under id, unless there is already a session by that ID, which
+// it returns instead, or s's client already holds limit sessions.
+func (t *httpSessionTable) add(id string, s *httpStream, limit int) (existing *httpStream, err error) {
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        if existing := t.m[id]; existing != nil {
+                return existing, nil
+        }
+        if limit > 0 && t.perClient[s.client] >= limit {
+                return nil, errTooManySessions
+        }
+        if len(t.m) == 0 {
+                sessionTables.Lock()
+                sessionTables.m[t] = true
+                sessionTables.Unlock()
+        }
+        if t.m == nil {
+                t.m = make(map[string]*httpStream)
+                t.perClient = make(map[string]int)
+        }
+        t.m[id] = s
+        t.perClient[s.client]++
+        return nil, nil
+}
+
+// remove removes session id, reporting whether there was one.
+func (t *httpSessionTable) remove(id string) bool {
+        t.mu.Lock()
+        defer t.mu.Unlock()
+        s, ok := t.m[id]
+        if !ok {
+                return false
+        }
+        delete(t.m, id)
+        if t.perClient[s.client]--; t.perClient[s.client] == 0 {
+                delete(t.perClient, s.client)
+        }
+        if len(t.m) == 0 {
+                sessionTables.Lock()
+                delete(sessionTables.m, t)
+                sessionTables.Unlock()
+        }
+        return true
+}
+
+// streamEvent is a message sent on a session's event stream. IDs increase
+// by one per session, so a client resuming after one can be sent the rest.
+type streamEvent struct {
+        id   int64
+        at   time.Time
+        data []byte
+}
+
+// httpStream routes a Streamable HTTP session's outgoing messages. A
+// response goes back on the POST that carried its request; notifications
+// and server requests go on the event stream the client opens with GET,
+// and are kept for SessionRetention so a client can resume the stream.
+type httpStream struct {
+        conn *mcp.Conn
+        // sessions is the table of the Server serving the session.
+        sessions *httpSessionTable
+        // client is who the session counts against under
+        // MaxSessionsPerClient.
+        client string
+        // sharedID is the session's ID if it is shared with other replicas,
+        // in which case its events are kept in SharedSessions rather than
+        // in events.
+        sharedID string
+
+        mu sync.Mutex
+        // waiting holds, by request ID, the POSTs waiting for their response.
+        waiting map[string]chan []byte
+        events  []streamEvent
+        lastID  int64
+        // delivered is the last event written to an event stream.
+        delivered int64
+        // wake is closed, and replaced, when an event is added.
+        wake chan struct{}
+        // generation counts event streams opened; replaced is closed when the
+        // open one, if any, is superseded.
+        generation int64
+        replaced   chan struct{}
+        // lastSeen is when the last event stream closed.
+        lastSeen time.Time
+}
+
+func newHTTPStream(sessions *httpSessionTable, client string) *httpStream {
+        return &httpStream{
+                sessions: sessions,
+                client:   client,
+                waiting:  make(map[string]chan []byte),
+                wake:     make(chan struct{}),
+                lastSeen: time.Now(),
+        }
+}
+
+// Deliver sends data, the encoding of v, to the POST waiting for it if v
+// is a response, and to the event stream otherwise. Neither makes the
+// sender wait, so wait is ignored.
+func (s *httpStream) Deliver(v interface{}, data []byte, wait bool) error {
+        if s.sharedID != "" {
+                if _, ok := v.(mcp.Response); !ok {
+                        return SharedSessions.AppendEvent(s.sharedID, "", data)
+                }
+        }
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if resp, ok := v.(mcp.Response); ok {
+                ch, ok := s.waiting[resp.ID]
+                if !ok {
+                        // The client stopped waiting; the response has nowhere to go.
+                        return mcp.ErrClientDisconnected
+                }
+                delete(s.waiting, resp.ID)
+                ch <- data
+                return nil
+        }
+
+        now := time.Now()
+        s.lastID++
+        s.events = append(s.events, streamEvent{id: s.lastID, at: now, data: data})
+        s.prune(now)
+        s.wakeLocked()
+        return nil
+}
+
+// Shutdown ends the session's connection, which closes any open event
+// stream.
+func (s *httpStream) Shutdown() {
+        s.conn.Close()
+}
+
+// Shared reports whether the session is kept in SharedSessions.
+func (s *httpStream) Shared() bool {
+        return s.sharedID != ""
+}
+
+// wakeUp tells the open event stream, if any, that there are new events.
+func (s *httpStream) wakeUp() {
+        s.mu.Lock()
+        s.wakeLocked()
+        s.mu.Unlock()
+}
+
+func (s *httpStream) wakeLocked() {
+        close(s.wake)
+        s.wake = make(chan struct{})
+}
+
+// prune drops events older than SessionRetention or beyond
+// maxRetainedEvents.
+func (s *httpStream) prune(now time.Time) {
+        drop := max(len(s.events)-maxRetainedEvents, 0)
+        for drop < len(s.events) && now.Sub(s.events[drop].at) > SessionRetention {
+                drop++
+        }
+        s.events = s.events[drop:]
+}
+
+// since returns the retained events after the one with ID after, and a
+// channel closed when another is added. missed reports whether some were
+// dropped before they could be sent.
+func (s *httpStream) since(after int64) (events []streamEvent, wake <-chan struct{}, missed bool) {
+        if s.sharedID != "" {
+                // Take wake first so that no event added meanwhile is missed.
+                s.mu.Lock()
+                wake = s.wake
+                s.mu.Unlock()
+                events, missed, err := SharedSessions.events(s.sharedID, after)
+                if err != nil {
+                        slog.Warn("Reading shared session events", "session", s.sharedID, "error", err)
+                }
+                return events, wake, missed
+        }
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        first := s.lastID - int64(len(s.events)) + 1
+        missed = after < s.lastID && after+1 < first
+        for _, e := range s.events {
+                if e.id > after {
+                        events = append(events, e)
+                }
+        }
+        return events, s.wake, missed
+}
+
+func (s *httpStream) markDelivered(id int64) {
+        s.mu.Lock()
+        s.delivered = max(s.delivered, id)
+        s.mu.Unlock()
+        if s.sharedID != "" {
+                if err := SharedSessions.setDelivered(s.sharedID, id); err != nil {
+                        slog.Warn("Recording delivered shared session events", "session", s.sharedID, "error", err)
+                }
+        }
+}
+
+// await registers a POST waiting for the response to request id. ok is
+// false if a request with that ID is already waiting.
+func (s *httpStream) await(id string) (reply chan []byte, ok bool) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if _, dup := s.waiting[id]; dup {
+                return nil, false
+        }
+        reply = make(chan []byte, 1)
+        s.waiting[id] = reply
+        return reply, true
+}
+
+// abandon stops waiting for the response to request id.
+func (s *httpStream) abandon(id string) {
+        s.mu.Lock()
+        delete(s.waiting, id)
+        s.mu.Unlock()
+}
+
+// attach makes the caller the session's event stream, superseding any
+// other, which is closed so messages aren't sent twice. It returns the
+// stream's generation, for detach, a channel closed if it is superseded in
+// turn, and the last event already delivered.
+func (s *httpStream) attach() (generation int64, replaced <-chan struct{}, delivered int64) {
+        s.mu.Lock()
+        if s.replaced != nil {
+                close(s.replaced)
+        }
+        s.generation++
+        s.replaced = make(chan struct{})
+        generation, replaced, delivered = s.generation, s.replaced, s.delivered
+        s.mu.Unlock()
+        if s.sharedID != "" {
+                // The stream may have been open on another replica.
+                delivered = max(delivered, SharedSessions.delivered(s.sharedID))
+        }
+        return generation, replaced, delivered
+}
+
+func (s *httpStream) detach(generation int64) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if generation == s.generation {
+                s.replaced = nil
+                s.lastSeen = time.Now()
+        }
+}
+
+// idleFor is how long the session has had no event stream open, no request
+// waiting, and no message from the client.
+func (s *httpStream) idleFor() time.Duration {
+        sinceMessage := time.Since(s.conn.LastSeen())
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        if s.replaced != nil || len(s.waiting) > 0 || s.conn.Busy() {
+                return 0
+        }
+        return min(sinceMessage, time.Since(s.lastSeen))
+}
+
+// HandleStreamableHTTP serves the Streamable HTTP transport: clients POST
+// JSON-RPC messages, GET an event stream of the server's messages, and
+// DELETE their session when they are done with it.
+func (srv *Server) HandleStreamableHTTP(w http.ResponseWriter, r *http.Request) {
+        if !srv.Origins.check(r) {
+                http.Error(w, "Forbidden", http.StatusForbidden)
+                return
+        }
+        principal, failure := srv.Auth.authenticate(r)
+        if failure != nil {
+                srv.rejectUnauthenticated(w, r, failure)
+                return
+        }
+        switch r.Method {
+        case http.MethodPost:
+                srv.postMessage(w, r, principal)
+        case http.MethodGet:
+                srv.streamEvents(w, r, principal)
+        case http.MethodDelete:
+                if s, ok := srv.lookupHTTPSession(w, r, principal); ok {
+                        s.end(mcp.CloseByClient)
+                        if s.sharedID != "" {
+                                if err := SharedSessions.end(s.sharedID); err != nil {
+                                        s.conn.Logf(s.conn.Context(), mcp.LevelError, "Ending shared session: %v", err)
+                                }
+                        }
+                        w.WriteHeader(http.StatusNoContent)
+                }
+        default:
+                w.Header().Set("Allow", "GET, POST, DELETE")
+                http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+        }
+}
+
+// postMessage handles one JSON-RPC message. A request is answered in the
+// response body; anything else is accepted with no body.
+func (srv *Server) postMessage(w http.ResponseWriter, r *http.Request, p mcp.Principal) {
+        // The deadline covers reading the body only: the response may be a
+        // long-running tool call away.
+        controller := http.NewResponseController(w)
+        if timeout := srv.Timeouts.Body; timeout > 0 {
+                controller.SetReadDeadline(time.Now().Add(timeout))
+        }
+        frame, err := readFrame(http.MaxBytesReader(w, r.Body, mcp.MaxMessageSize))
+        if srv.Timeouts.Body > 0 {
+                controller.SetReadDeadline(time.Time{})
+        }
+        var tooBig *http.MaxBytesError
+        if errors.As(err, &tooBig) {
+                http.Error(w, fmt.Sprintf("Message exceeds the %d byte limit", mcp.MaxMessageSize), http.StatusRequestEntityTooLarge)
+                return
+        }
+        if errors.Is(err, os.ErrDeadlineExceeded) {
+                http.Error(w, "Request Timeout: the body took too long to arrive", http.StatusRequestTimeout)
+                return
+        }
+        if err != nil {
+                http.Error(w, "Bad Request", http.StatusBadRequest)
+                return
+        }
+        defer releaseFrame(frame)
+        body := frame.Bytes()
+        if mcp.JSONDepthExceeds(body, mcp.MaxJSONDepth) {
+                writeHTTPError(w, http.StatusBadRequest, -32600, fmt.Sprintf("Invalid Request: JSON is nested deeper than %d levels", mcp.MaxJSONDepth))
+                return
+        }
+        var msg mcp.IncomingMessage
+        if err := json.Unmarshal(body, &msg); err != nil {
+                writeHTTPError(w, http.StatusBadRequest, -32700, "Parse error")
+                return
+        }
+
+        var s *httpStream
+        starting := msg.Method == "initialize" && r.Header.Get(sessionHeader) == ""
+        if starting {
+                if s = srv.startHTTPSession(w, r, p); s == nil {
+                        return
+                }
+        } else {
+                var ok bool
+                if s, ok = srv.lookupHTTPSession(w, r, p); !ok {
+                        return
+                }
+        }
+        c := s.conn
+        c.Touch()
+        c.LogPayload("in", body)
+        w.Header().Set(sessionHeader, c.Session.ID)
+
+        if msg.IsResponse() || strings.HasPrefix(msg.Method, "notifications/") {
+                c.Handle(msg, len(body))
+                w.WriteHeader(http.StatusAccepted)
+                return
+        }
+
+        reply, ok := s.await(msg.ID)
+        if !ok {
+                writeHTTPError(w, http.StatusConflict, -32600, fmt.Sprintf("Invalid Request: request id=%s is already in progress", msg.ID))
+                return
+        }
+        defer s.abandon(msg.ID)
+        c.Handle(msg, len(body))
+        // Initialize is handled before Handle returns.
+        if starting {
+                if version, _ := c.Session.Negotiated(); version == "" {
+                        // The session never started, so it mustn't count
+                        // against its client until it expires. The error
+                        // is sent first.
+                        w.Header().Del(sessionHeader)
+                        defer s.end(mcp.CloseInitializeFailed)
+                } else if s.sharedID != "" {
+                        // Share the session before the client can use it
+                        // elsewhere.
+                        if err := SharedSessions.save(c); err != nil {
+                                c.Logf(c.Context(), mcp.LevelError, "Sharing session: %v", err)
+                        }
+                }
+        }
+        select {
+        case data := <-reply:
+                w.Header().Set("Content-Type", "application/json")
+                w.Write(data)
+        case <-r.Context().Done():
+                // The client went away. The request runs to completion, but
+                // its response is dropped.
+        case <-c.Context().Done():
+                http.Error(w, "Session ended", http.StatusNotFound)
+        }
+}
+
+// streamEvents sends the session's notifications and server requests as
+// server-sent events until the client disconnects. A client that sends
+// Last-Event-ID first receives the retained events after that one.
+func (srv *Server) streamEvents(w http.ResponseWriter, r *http.Request, p mcp.Principal) {
+        s, ok := srv.lookupHTTPSession(w, r, p)
+        if !ok {
+                return
+        }
+        c := s.conn
+        if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+                http.Error(w, "Not Acceptable: the event stream is text/event-stream", http.StatusNotAcceptable)
+                return
+        }
+        flusher, ok := w.(http.Flusher)
+        if !ok {
+                http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+                return
+        }
+        var resumeAfter int64
+        resuming := r.Header.Get("Last-Event-ID") != ""
+        if resuming {
+                id, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+                if err != nil {
+                        http.Error(w, "Bad Request: invalid Last-Event-ID", http.StatusBadRequest)
+                        return
+                }
+                resumeAfter = id
+        }
+
+        generation, replaced, after := s.attach()
+        defer s.detach(generation)
+        if resuming {
+                after = resumeAfter
+                c.Logf(c.Context(), mcp.LevelDebug, "Resuming event stream after event %d", after)
+        }
+
+        // A stalled client would otherwise block a write indefinitely.
+        controller := http.NewResponseController(w)
+        extendWriteDeadline := func() {
+                if timeout := srv.Timeouts.Write; timeout > 0 {
+                        controller.SetWriteDeadline(time.Now().Add(timeout))
+                }
+        }
+
+        h := w.Header()
+        h.Set("Content-Type", "text/event-stream")
+        h.Set("Cache-Control", "no-cache")
+        h.Set(sessionHeader, c.Session.ID)
+        w.WriteHeader(http.StatusOK)
+        flusher.Flush()
+
+        var pings <-chan time.Time
+        if srv.Timeouts.Ping > 0 {
+                // Comments keep proxies from timing out a quiet stream.
+                ticker := time.NewTicker(srv.Timeouts.Ping)
+                defer ticker.Stop()
+                pings = ticker.C
+        }
+        for {
+                events, wake, missed := s.since(after)
+                if missed {
+                        c.Logf(c.Context(), mcp.LevelWarning, "Events after %d expired before the client received them", after)
+                }
+                extendWriteDeadline()
+                for _, e := range events {
+                        if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.id, e.data); err != nil {
+                                return
+                        }
+                        after = e.id
+                }
+                if len(events) > 0 {
+                        flusher.Flush()
+                        s.markDelivered(after)
+                }
+
+                select {
+                case <-wake:
+                case <-pings:
+                        extendWriteDeadline()
+                        if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+                                return
+                        }
+                        flusher.Flush()
+                case <-replaced:
+                        return
+                case <-r.Context().Done():
+                        return
+                case <-c.Context().Done():
+                        return
+                }
+        }
+}
+
+// startHTTPSession creates the session for an initialize request, or
+// answers the request itself and returns nil if the server, or the
+// client's share of it, is full.
+func (srv *Server) startHTTPSession(w http.ResponseWriter, r *http.Request, p mcp.Principal) *httpStream {
+        if !srv.group().AcquireConnectionSlot() {
+                srv.group().RejectBusy(w, r)
+                return nil
+        }
+        s := newHTTPStream(&srv.sessions, sessionClient(r, p))
+        c := srv.newConn(mcp.NewSession(p), r.RemoteAddr, s)
+        s.conn = c
+        if SharedSessions != nil {
+                s.sharedID = c.Session.ID
+        }
+        c.Authorize(p)
+        if err := c.Admit(); err != nil {
+                c.Close()
+                srv.group().ReleaseConnectionSlot()
+                writeHTTPError(w, http.StatusForbidden, mcp.ToError(err).Code, err.Error())
+                return nil
+        }
+        if _, err := srv.sessions.add(c.Session.ID, s, srv.MaxSessionsPerClient); err != nil {
+                c.Close()
+                srv.group().ReleaseConnectionSlot()
+                srv.rejectTooManySessions(w, r)
+                return nil
+        }
+        mcp.AddClient(c)
+        c.StartWorkers()
+        c.Connected()
+        go s.expire()
+        return s
+}
+
+// lookupHTTPSession returns the session named by r's Mcp-Session-Id
+// header, or answers r with an error.
+func (srv *Server) lookupHTTPSession(w http.ResponseWriter, r *http.Request, p mcp.Principal) (*httpStream, bool) {
+        id := r.Header.Get(sessionHeader)
+        if id == "" {
+                http.Error(w, "Bad Request: missing "+sessionHeader+" header", http.StatusBadRequest)
+                return nil, false
+        }
+        s := srv.sessions.get(id)
+        if s == nil && SharedSessions != nil {
+                var err error
+                var refused *refusedError
+                if s, err = srv.resumeHTTPSession(r, id, p); err == mcp.ErrServerFull {
+                        srv.group().RejectBusy(w, r)
+                        return nil, false
+                } else if err == errTooManySessions {
+                        srv.rejectTooManySessions(w, r)
+                        return nil, false
+                } else if errors.As(err, &refused) {
+                        writeHTTPError(w, http.StatusForbidden, mcp.ToError(refused.err).Code, refused.err.Error())
+                        return nil, false
+                } else if err != nil {
+                        srv.logger().Error("Resuming shared session", "session", id, "error", err)
+                        http.Error(w, "Session store unavailable", http.StatusServiceUnavailable)
+                        return nil, false
+                }
+        }
+        // Another client's session is reported just like an expired one.
+        if s == nil || s.conn.Session.Identity != p.Identity {
+                http.Error(w, "Session not found", http.StatusNotFound)
+                return nil, false
+        }
+        if s.sharedID != "" {
+                if err := SharedSessions.refresh(id); err != nil {
+                        s.conn.Logf(s.conn.Context(), mcp.LevelWarning, "Refreshing shared session: %v", err)
+                }
+        }
+        return s, true
+}
+
+// refusedError reports that the OnConnect hook refused a session being
+// resumed.
+type refusedError struct{ err error }
+
+func (e *refusedError) Error() string { return "connection refused: " + e.err.Error() }
+
+// localHTTPSession returns this replica's copy of session id, if any,
+// whichever Server is serving it.
+func localHTTPSession(id string) *httpStream {
+        sessionTables.Lock()
+        tables := make([]*httpSessionTable, 0, len(sessionTables.m))
+        for t := range sessionTables.m {
+                tables = append(tables, t)
+        }
+        sessionTables.Unlock()
+        for _, t := range tables {
+                if s := t.get(id); s != nil {
+                        return s
+                }
+        }
+        return nil
+}
+
+// resumeHTTPSession rebuilds session id from its shared record, for a
+// session another replica started or this one let go idle. It returns nil
+// if there is no such session or it isn't p's, ErrServerFull or
+// errTooManySessions if there is no room for it, and a *refusedError if
+// the OnConnect hook refuses it.
+func (srv *Server) resumeHTTPSession(r *http.Request, id string, p mcp.Principal) (*httpStream, error) {
+        record, err := SharedSessions.load(id)
+        if err != nil || record == nil || record.Identity != p.Identity {
+                return nil, err
+        }
+        if !srv.group().AcquireConnectionSlot() {
+                return nil, mcp.ErrServerFull
+        }
+        s := mcp.NewSession(p)
+        s.ID, s.CreatedAt = id, record.CreatedAt
+        s.Initialized(record.ProtocolVersion, record.ClientInfo)
+        stream := newHTTPStream(&srv.sessions, sessionClient(r, p))
+        stream.sharedID = id
+        c := srv.newConn(s, r.RemoteAddr, stream)
+        stream.conn = c
+        c.SetCapabilities(record.Capabilities)
+        c.Authorize(p)
+        c.ApplyClientOverride(record.ClientInfo)
+        if err := c.Admit(); err != nil {
+                c.Close()
+                srv.group().ReleaseConnectionSlot()
+                return nil, &refusedError{err}
+        }
+
+        existing, err := srv.sessions.add(id, stream, srv.MaxSessionsPerClient)
+        if existing != nil || err != nil {
+                // Another request resumed it first, or the client has no
+                // room for it.
+                c.Close()
+                srv.group().ReleaseConnectionSlot()
+                return existing, err
+        }
+        mcp.AddClient(c)
+        c.StartWorkers()
+        c.Connected()
+        c.Logf(c.Context(), mcp.LevelDebug, "Resumed shared session")
+        go stream.expire()
+        return stream, nil
+}
+
+// sessionClient names the client a session started by r counts against
+// under MaxSessionsPerClient.
+func sessionClient(r *http.Request, p mcp.Principal) string {
+        if p.Identity != "" {
+                return "identity " + p.Identity
+        }
+        host, _, err := net.SplitHostPort(r.RemoteAddr)
+        if err != nil {
+                host = r.RemoteAddr
+        }
+        return "address " + host
+}
+
+// rejectTooManySessions refuses a session because its client already
+// holds MaxSessionsPerClient.
+func (srv *Server) rejectTooManySessions(w http.ResponseWriter, r *http.Request) {
+        mcp.ConnectionsRejected.Add(1, "too_many_sessions")
+        srv.logger().Warn(fmt.Sprintf("Rejected session: the client already has %d (see -max-sessions-per-client)", srv.MaxSessionsPerClient), "remote", r.RemoteAddr)
+        w.Header().Set("Retry-After", "5")
+        http.Error(w, "Too many sessions: end one with DELETE before starting another", http.StatusTooManyRequests)
+}
+
+// end forgets the session and records why it ended. Only the first call
+// has any effect.
+func (s *httpStream) end(cause string) {
+        c := s.conn
+        if !s.sessions.remove(c.Session.ID) {
+                return
+        }
+        c.Disconnected(cause)
+        mcp.RemoveClient(c)
+        c.Close()
+        c.Group().ReleaseConnectionSlot()
+}
+
+// expire ends the session once it has been idle for
+// SessionRetention, after which there is nothing left to resume. A shared
+// session is kept alive in the store while its event stream is open here,
+// and only this replica's copy of it ends; the store lets it expire once
+// no replica has been serving it.
+func (s *httpStream) expire() {
+        c := s.conn
+        ticker := time.NewTicker(max(SessionRetention/10, time.Second))
+        defer ticker.Stop()
+        for {
+                select {
+                case <-ticker.C:
+                        idle := s.idleFor()
+                        if idle == 0 && s.sharedID != "" {
+                                if err := SharedSessions.refresh(c.Session.ID); err != nil {
+                                        c.Logf(c.Context(), mcp.LevelWarning, "Refreshing shared session: %v", err)
+                                }
+                        }
+                        if idle >= SessionRetention {
+                                c.Logf(c.Context(), mcp.LevelInfo, "Ending session idle for %s", SessionRetention)
+                                s.end(mcp.CloseIdleTimeout)
+                                return
+                        }
+                case <-c.Context().Done():
+                        return
+                }
+        }
+}
+
+// writeHTTPError answers a POST that couldn't be handled as a request
+// with a JSON-RPC error.
+func writeHTTPError(w http.ResponseWriter, status, code int, message string) {
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(status)
+        json.NewEncoder(w).Encode(mcp.Response{Error: &mcp.Error{Code: code, Message: message}})
+}
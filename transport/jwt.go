@@ -0,0 +1,394 @@
+package transport
+
+import (
+        "crypto"
+        "crypto/ecdsa"
+        "crypto/ed25519"
+        "crypto/elliptic"
+        "crypto/rsa"
+        "crypto/sha256"
+        "crypto/sha512"
+        "encoding/base64"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "math/big"
+        "net/http"
+        "net/url"
+        "slices"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+const (
+        // jwksRefreshInterval is how long fetched signing keys are used before
+        // being fetched again.
+        jwksRefreshInterval = time.Hour
+        // jwksMissMinInterval throttles refetches triggered by tokens signed
+        // with an unknown key ID, e.g. right after the issuer rotates keys.
+        jwksMissMinInterval = 30 * time.Second
+        // jwtClockSkew is the leeway allowed on exp and nbf.
+        jwtClockSkew = time.Minute
+)
+
+// jwtVerifier validates signed JWTs against keys fetched from a JWKS
+// endpoint.
+type jwtVerifier struct {
+        JWKSURL  string
+        issuer   string
+        audience string
+        scopes   []string
+        client   *http.Client
+
+        mu      sync.Mutex
+        keys    map[string]crypto.PublicKey
+        fetched time.Time
+}
+
+// NewJWTVerifier configures JWT validation. Tokens must be issued for
+// audience, and by issuer when it is set; jwksURL defaults to the jwks_uri
+// in the issuer's metadata.
+func NewJWTVerifier(jwksURL, issuer, audience string, scopes []string) (*jwtVerifier, error) {
+        if audience == "" {
+                // Otherwise a token the issuer minted for any other service
+                // would do.
+                return nil, fmt.Errorf("set -jwt-audience or -oauth-resource")
+        }
+        v := &jwtVerifier{
+                JWKSURL:  jwksURL,
+                issuer:   issuer,
+                audience: audience,
+                scopes:   scopes,
+                client:   &http.Client{Timeout: mcp.RemoteRequestTimeout},
+        }
+        if v.JWKSURL == "" {
+                if issuer == "" {
+                        return nil, fmt.Errorf("set -jwks-url or -jwt-issuer")
+                }
+                var err error
+                if v.JWKSURL, err = v.discoverJWKS(); err != nil {
+                        return nil, err
+                }
+        }
+        if _, err := v.refresh(); err != nil {
+                return nil, err
+        }
+        return v, nil
+}
+
+func (v *jwtVerifier) discoverJWKS() (string, error) {
+        issuer := strings.TrimSuffix(v.issuer, "/")
+        u, err := url.Parse(issuer)
+        if err != nil {
+                return "", err
+        }
+        candidates := []string{
+                issuer + "/.well-known/openid-configuration",
+                u.Scheme + "://" + u.Host + "/.well-known/oauth-authorization-server" + u.Path,
+        }
+        for _, candidate := range candidates {
+                resp, err := v.client.Get(candidate)
+                if err != nil {
+                        return "", fmt.Errorf("fetching issuer metadata: %w", err)
+                }
+                var metadata struct {
+                        JWKS string `json:"jwks_uri"`
+                }
+                err = json.NewDecoder(resp.Body).Decode(&metadata)
+                resp.Body.Close()
+                if resp.StatusCode == http.StatusOK && err == nil && metadata.JWKS != "" {
+                        return metadata.JWKS, nil
+                }
+        }
+        return "", fmt.Errorf("%s publishes no jwks_uri; set -jwks-url", v.issuer)
+}
+
+// jsonWebKey is a public key in a JWK Set (RFC 7517).
+type jsonWebKey struct {
+        KeyType string `json:"kty"`
+        KeyID   string `json:"kid"`
+        Use     string `json:"use"`
+        Curve   string `json:"crv"`
+        N       string `json:"n"`
+        E       string `json:"e"`
+        X       string `json:"x"`
+        Y       string `json:"y"`
+}
+
+// refresh fetches the signing keys, replacing those held.
+func (v *jwtVerifier) refresh() (map[string]crypto.PublicKey, error) {
+        resp, err := v.client.Get(v.JWKSURL)
+        if err != nil {
+                return nil, fmt.Errorf("fetching JWKS: %w", err)
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+                return nil, fmt.Errorf("fetching JWKS: %s", resp.Status)
+        }
+        var set struct {
+                Keys []jsonWebKey `json:"keys"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+                return nil, fmt.Errorf("decoding JWKS: %w", err)
+        }
+        keys := map[string]crypto.PublicKey{}
+        for _, jwk := range set.Keys {
+                if jwk.Use != "" && jwk.Use != "sig" {
+                        continue
+                }
+                // Keys of unsupported types are skipped rather than failing the
+                // whole set.
+                if key, err := jwk.publicKey(); err == nil {
+                        keys[jwk.KeyID] = key
+                }
+        }
+        if len(keys) == 0 {
+                return nil, fmt.Errorf("JWKS at %s has no usable signing keys", v.JWKSURL)
+        }
+        v.mu.Lock()
+        v.keys, v.fetched = keys, time.Now()
+        v.mu.Unlock()
+        return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+        decode := func(s string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(s) }
+        switch k.KeyType {
+        case "RSA":
+                n, err := decode(k.N)
+                if err != nil {
+                        return nil, err
+                }
+                e, err := decode(k.E)
+                if err != nil {
+                        return nil, err
+                }
+                return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+        case "EC":
+                var curve elliptic.Curve
+                switch k.Curve {
+                case "P-256":
+                        curve = elliptic.P256()
+                case "P-384":
+                        curve = elliptic.P384()
+                case "P-521":
+                        curve = elliptic.P521()
+                default:
+                        return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+                }
+                x, err := decode(k.X)
+                if err != nil {
+                        return nil, err
+                }
+                y, err := decode(k.Y)
+                if err != nil {
+                        return nil, err
+                }
+                return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+        case "OKP":
+                if k.Curve != "Ed25519" {
+                        return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+                }
+                x, err := decode(k.X)
+                if err != nil || len(x) != ed25519.PublicKeySize {
+                        return nil, fmt.Errorf("invalid Ed25519 key")
+                }
+                return ed25519.PublicKey(x), nil
+        }
+        return nil, fmt.Errorf("unsupported key type %s", k.KeyType)
+}
+
+// key returns the signing key with ID kid, refetching the key set when it
+// is stale or doesn't have kid.
+func (v *jwtVerifier) key(kid string) (crypto.PublicKey, error) {
+        v.mu.Lock()
+        keys, fetched := v.keys, v.fetched
+        v.mu.Unlock()
+
+        stale := time.Since(fetched) > jwksRefreshInterval
+        key, ok := findKey(keys, kid)
+        if (stale || !ok) && time.Since(fetched) > jwksMissMinInterval {
+                if refreshed, err := v.refresh(); err == nil {
+                        key, ok = findKey(refreshed, kid)
+                } else if !ok {
+                        return nil, err
+                }
+        }
+        if !ok {
+                return nil, fmt.Errorf("unknown signing key %q", kid)
+        }
+        return key, nil
+}
+
+// findKey returns the key with ID kid, or, for a token that names no key,
+// the only key there is.
+func findKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+        key, ok := keys[kid]
+        if !ok && kid == "" && len(keys) == 1 {
+                for _, only := range keys {
+                        key, ok = only, true
+                }
+        }
+        return key, ok
+}
+
+// looksLikeJWT reports whether token has the three dot-separated parts of a
+// signed JWT, as opposed to an opaque token.
+func looksLikeJWT(token string) bool {
+        return strings.Count(token, ".") == 2
+}
+
+// authenticate validates token and returns the principal named by its
+// claims.
+func (v *jwtVerifier) authenticate(token string) (mcp.Principal, *authFailure) {
+        claims, err := v.verify(token)
+        if err != nil {
+                return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, code: "invalid_token", reason: err.Error()}
+        }
+        var granted []string
+        if scope, ok := claims["scope"].(string); ok {
+                granted = strings.Fields(scope)
+        }
+        if scp, ok := claims["scp"].([]interface{}); ok {
+                for _, s := range scp {
+                        if s, ok := s.(string); ok {
+                                granted = append(granted, s)
+                        }
+                }
+        }
+        if failure := checkScopes(granted, v.scopes); failure != nil {
+                return mcp.Principal{}, failure
+        }
+
+        p := mcp.Principal{Claims: claims}
+        for _, claim := range []string{"preferred_username", "email", "sub", "client_id"} {
+                if s, ok := claims[claim].(string); ok && s != "" {
+                        p.Identity = s
+                        break
+                }
+        }
+        return p, nil
+}
+
+// verify checks token's signature and registered claims and returns its
+// claims.
+func (v *jwtVerifier) verify(token string) (map[string]interface{}, error) {
+        parts := strings.Split(token, ".")
+        if len(parts) != 3 {
+                return nil, errors.New("malformed token")
+        }
+        var header struct {
+                Alg string `json:"alg"`
+                Kid string `json:"kid"`
+        }
+        if err := decodeJWTPart(parts[0], &header); err != nil {
+                return nil, fmt.Errorf("malformed token header: %w", err)
+        }
+        signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+        if err != nil {
+                return nil, errors.New("malformed token signature")
+        }
+        key, err := v.key(header.Kid)
+        if err != nil {
+                return nil, err
+        }
+        if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+                return nil, err
+        }
+
+        var claims map[string]interface{}
+        if err := decodeJWTPart(parts[1], &claims); err != nil {
+                return nil, fmt.Errorf("malformed token claims: %w", err)
+        }
+        now := time.Now()
+        if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(jwtClockSkew)) {
+                return nil, errors.New("token has expired")
+        }
+        if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtClockSkew).Before(time.Unix(int64(nbf), 0)) {
+                return nil, errors.New("token is not valid yet")
+        }
+        if v.issuer != "" && strings.TrimSuffix(fmt.Sprint(claims["iss"]), "/") != strings.TrimSuffix(v.issuer, "/") {
+                return nil, fmt.Errorf("token was not issued by %s", v.issuer)
+        }
+        var aud audience
+        if raw, err := json.Marshal(claims["aud"]); err == nil {
+                json.Unmarshal(raw, &aud)
+        }
+        if !slices.Contains(aud, v.audience) {
+                return nil, fmt.Errorf("token was not issued for %s", v.audience)
+        }
+        return claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+        data, err := base64.RawURLEncoding.DecodeString(part)
+        if err != nil {
+                return err
+        }
+        return json.Unmarshal(data, v)
+}
+
+// verifyJWTSignature checks signature over signed with key using alg. The
+// key's type must match the algorithm, so a token can't pick a weaker
+// algorithm than the issuer's key is meant for.
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+        var hash crypto.Hash
+        switch {
+        case strings.HasSuffix(alg, "256"):
+                hash = crypto.SHA256
+        case strings.HasSuffix(alg, "384"):
+                hash = crypto.SHA384
+        case strings.HasSuffix(alg, "512"):
+                hash = crypto.SHA512
+        }
+        digest := func() []byte {
+                switch hash {
+                case crypto.SHA384:
+                        sum := sha512.Sum384(signed)
+                        return sum[:]
+                case crypto.SHA512:
+                        sum := sha512.Sum512(signed)
+                        return sum[:]
+                }
+                sum := sha256.Sum256(signed)
+                return sum[:]
+        }
+
+        invalid := errors.New("invalid token signature")
+        switch k := key.(type) {
+        case *rsa.PublicKey:
+                switch {
+                case strings.HasPrefix(alg, "RS") && hash != 0:
+                        if rsa.VerifyPKCS1v15(k, hash, digest(), signature) != nil {
+                                return invalid
+                        }
+                        return nil
+                case strings.HasPrefix(alg, "PS") && hash != 0:
+                        if rsa.VerifyPSS(k, hash, digest(), signature, nil) != nil {
+                                return invalid
+                        }
+                        return nil
+                }
+        case *ecdsa.PublicKey:
+                size := (k.Curve.Params().BitSize + 7) / 8
+                if strings.HasPrefix(alg, "ES") && hash != 0 && len(signature) == 2*size {
+                        r := new(big.Int).SetBytes(signature[:size])
+                        s := new(big.Int).SetBytes(signature[size:])
+                        if !ecdsa.Verify(k, digest(), r, s) {
+                                return invalid
+                        }
+                        return nil
+                }
+        case ed25519.PublicKey:
+                if alg == "EdDSA" {
+                        if !ed25519.Verify(k, signed, signature) {
+                                return invalid
+                        }
+                        return nil
+                }
+        }
+        return fmt.Errorf("token algorithm %q doesn't match the signing key", alg)
+}
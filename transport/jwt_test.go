@@ -0,0 +1,66 @@
+package transport
+
+import (
+        "crypto/ed25519"
+        "encoding/base64"
+        "encoding/json"
+        "net/http"
+        "net/http/httptest"
+        "sync/atomic"
+        "testing"
+        "time"
+)
+
+func TestJWTAudienceAndKeys(t *testing.T) {
+        public, private, err := ed25519.GenerateKey(nil)
+        if err != nil {
+                t.Fatal(err)
+        }
+        var fetches atomic.Int32
+        jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                fetches.Add(1)
+                json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+                        "kty": "OKP", "crv": "Ed25519", "kid": "only",
+                        "x": base64.RawURLEncoding.EncodeToString(public),
+                }}})
+        }))
+        defer jwks.Close()
+        sign := func(claims map[string]interface{}) string {
+                header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA"}`))
+                payload, _ := json.Marshal(claims)
+                signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+                return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(private, []byte(signed)))
+        }
+
+        if _, err := NewJWTVerifier(jwks.URL, "", "", nil); err == nil {
+                t.Fatal("no audience: got no error")
+        }
+        v, err := NewJWTVerifier(jwks.URL, "", "https://mcp.example.com/ws", nil)
+        if err != nil {
+                t.Fatal(err)
+        }
+        exp := time.Now().Add(time.Hour).Unix()
+        if _, err := v.verify(sign(map[string]interface{}{"sub": "alice", "exp": exp, "aud": "https://other.example.com"})); err == nil {
+                t.Error("token for another audience: got no error")
+        }
+        if _, err := v.verify(sign(map[string]interface{}{"sub": "alice", "exp": exp})); err == nil {
+                t.Error("token without an audience: got no error")
+        }
+
+        // A token that names no key is checked against the only key, also
+        // once the keys have been refetched.
+        token := sign(map[string]interface{}{"sub": "alice", "exp": exp, "aud": []string{"https://mcp.example.com/ws"}})
+        if _, err := v.verify(token); err != nil {
+                t.Fatal(err)
+        }
+        v.mu.Lock()
+        v.fetched = time.Now().Add(-2 * jwksRefreshInterval)
+        v.mu.Unlock()
+        before := fetches.Load()
+        if _, err := v.verify(token); err != nil {
+                t.Errorf("after refetching the keys: %v", err)
+        }
+        if fetches.Load() == before {
+                t.Error("stale keys weren't refetched")
+        }
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+        "crypto/tls"
+        "crypto/x509"
+        "fmt"
+        "net/http"
+        "os"
+        "strings"
+
+        "gopkg.in/yaml.v3"
+        "mcp-server/mcp"
+)
+
+// clientCertAuth maps verified client certificates to identities.
+type clientCertAuth struct {
+        // identities maps a certificate subject DN, common name, or SAN to an
+        // identity. When empty, any certificate signed by the CA is accepted
+        // with its common name as the identity.
+        identities map[string]string
+}
+
+// LoadClientCertAuth reads the optional identity mapping, a YAML or JSON
+// map of certificate subject to identity.
+func LoadClientCertAuth(identitiesPath string) (*clientCertAuth, error) {
+        a := &clientCertAuth{identities: map[string]string{}}
+        if identitiesPath == "" {
+                return a, nil
+        }
+        data, err := os.ReadFile(identitiesPath)
+        if err != nil {
+                return nil, err
+        }
+        if err := yaml.Unmarshal(data, &a.identities); err != nil {
+                return nil, fmt.Errorf("parsing %s: %w", identitiesPath, err)
+        }
+        return a, nil
+}
+
+// ClientCertTLSConfig returns a server TLS config that requires client certificates
+// signed by a CA in the PEM bundle at caPath.
+func ClientCertTLSConfig(caPath string) (*tls.Config, error) {
+        pem, err := os.ReadFile(caPath)
+        if err != nil {
+                return nil, err
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+                return nil, fmt.Errorf("%s has no PEM certificates", caPath)
+        }
+        return &tls.Config{
+                ClientCAs:  pool,
+                ClientAuth: tls.RequireAndVerifyClientCert,
+                MinVersion: tls.VersionTLS12,
+        }, nil
+}
+
+// certificateSubjects lists the names a certificate can be mapped by, most
+// specific first: its subject DN, URI, DNS, and email SANs, and its common
+// name.
+func certificateSubjects(cert *x509.Certificate) []string {
+        subjects := []string{cert.Subject.String()}
+        for _, u := range cert.URIs {
+                subjects = append(subjects, u.String())
+        }
+        subjects = append(subjects, cert.DNSNames...)
+        subjects = append(subjects, cert.EmailAddresses...)
+        if cert.Subject.CommonName != "" {
+                subjects = append(subjects, cert.Subject.CommonName)
+        }
+        return subjects
+}
+
+// authenticate returns the principal for r's verified client certificate.
+func (a *clientCertAuth) authenticate(r *http.Request) (mcp.Principal, *authFailure) {
+        if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+                return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, reason: "no verified client certificate"}
+        }
+        cert := r.TLS.VerifiedChains[0][0]
+        if len(a.identities) == 0 {
+                if cert.Subject.CommonName == "" {
+                        return mcp.Principal{}, &authFailure{status: http.StatusForbidden, reason: "client certificate has no common name"}
+                }
+                return mcp.Principal{Identity: cert.Subject.CommonName}, nil
+        }
+        for _, subject := range certificateSubjects(cert) {
+                if identity, ok := a.identities[subject]; ok {
+                        return mcp.Principal{Identity: identity}, nil
+                }
+        }
+        return mcp.Principal{}, &authFailure{status: http.StatusForbidden, reason: fmt.Sprintf("client certificate %q is not mapped to an identity", strings.Join(certificateSubjects(cert), ", "))}
+}
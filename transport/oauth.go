@@ -0,0 +1,262 @@
+package transport
+
+import (
+        "context"
+        "crypto/sha256"
+        "encoding/json"
+        "fmt"
+        "net/http"
+        "net/url"
+        "slices"
+        "strings"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// oauthCacheTTL bounds how long an introspected token is trusted before the
+// authorization server is asked again.
+const oauthCacheTTL = time.Minute
+
+// oauthResourceServer implements the resource server side of MCP
+// authorization: it publishes protected resource metadata (RFC 9728) that
+// points clients at the authorization server, and validates the access
+// tokens they present by introspection (RFC 7662).
+type oauthResourceServer struct {
+        issuer string
+        // resource is the canonical URI of this server that tokens must be
+        // issued for. It is configured rather than taken from requests,
+        // whose Host and X-Forwarded-Proto the client controls.
+        resource         string
+        scopes           []string
+        introspectionURL string
+        clientID         mcp.SecretRef
+        clientSecret     mcp.SecretRef
+        client           *http.Client
+
+        mu    sync.Mutex
+        cache map[[sha256.Size]byte]cachedToken
+}
+
+type cachedToken struct {
+        principal mcp.Principal
+        expires   time.Time
+}
+
+// tokenIntrospection is an RFC 7662 introspection response.
+type tokenIntrospection struct {
+        Active   bool     `json:"active"`
+        Scope    string   `json:"scope"`
+        Subject  string   `json:"sub"`
+        Username string   `json:"username"`
+        ClientID string   `json:"client_id"`
+        Expires  int64    `json:"exp"`
+        Audience audience `json:"aud"`
+}
+
+// audience is a JWT-style aud claim, which may be a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+        var one string
+        if err := json.Unmarshal(data, &one); err == nil {
+                *a = audience{one}
+                return nil
+        }
+        var many []string
+        if err := json.Unmarshal(data, &many); err != nil {
+                return err
+        }
+        *a = many
+        return nil
+}
+
+// NewOAuthResourceServer configures token validation against issuer for
+// tokens issued for resource, discovering its introspection endpoint from
+// the authorization server metadata (RFC 8414) unless introspectionURL is
+// given.
+func NewOAuthResourceServer(issuer, resource, scopes, introspectionURL string, clientID, clientSecret mcp.SecretRef) (*oauthResourceServer, error) {
+        if u, err := url.Parse(resource); resource == "" || err != nil || u.Host == "" {
+                return nil, fmt.Errorf("-oauth-resource must be this server's URI, e.g. https://mcp.example.com/ws")
+        }
+        s := &oauthResourceServer{
+                issuer:           strings.TrimSuffix(issuer, "/"),
+                resource:         resource,
+                introspectionURL: introspectionURL,
+                clientID:         clientID,
+                clientSecret:     clientSecret,
+                client:           &http.Client{Timeout: mcp.RemoteRequestTimeout},
+                cache:            map[[sha256.Size]byte]cachedToken{},
+        }
+        s.scopes = OAuthScopeList(scopes)
+        if s.introspectionURL == "" {
+                var err error
+                if s.introspectionURL, err = s.discoverIntrospection(); err != nil {
+                        return nil, err
+                }
+        }
+        return s, nil
+}
+
+func (s *oauthResourceServer) discoverIntrospection() (string, error) {
+        u, err := url.Parse(s.issuer)
+        if err != nil {
+                return "", err
+        }
+        // RFC 8414 inserts the well-known suffix before any issuer path;
+        // OpenID Connect providers append it instead.
+        candidates := []string{
+                u.Scheme + "://" + u.Host + "/.well-known/oauth-authorization-server" + u.Path,
+                s.issuer + "/.well-known/openid-configuration",
+        }
+        for _, candidate := range candidates {
+                resp, err := s.client.Get(candidate)
+                if err != nil {
+                        return "", fmt.Errorf("fetching authorization server metadata: %w", err)
+                }
+                var metadata struct {
+                        Introspection string `json:"introspection_endpoint"`
+                }
+                err = json.NewDecoder(resp.Body).Decode(&metadata)
+                resp.Body.Close()
+                if resp.StatusCode == http.StatusOK && err == nil && metadata.Introspection != "" {
+                        return metadata.Introspection, nil
+                }
+        }
+        return "", fmt.Errorf("%s publishes no introspection_endpoint; set -oauth-introspection-url", s.issuer)
+}
+
+// OAuthScopeList parses the comma-separated -oauth-scopes value.
+func OAuthScopeList(scopes string) []string {
+        var list []string
+        for _, scope := range strings.Split(scopes, ",") {
+                if scope = strings.TrimSpace(scope); scope != "" {
+                        list = append(list, scope)
+                }
+        }
+        return list
+}
+
+// checkScopes refuses a token that doesn't grant every required scope.
+func checkScopes(granted, required []string) *authFailure {
+        for _, scope := range required {
+                if !slices.Contains(granted, scope) {
+                        return &authFailure{status: http.StatusForbidden, code: "insufficient_scope", reason: "token lacks scope " + scope}
+                }
+        }
+        return nil
+}
+
+// metadataURL is where the protected resource metadata is served, on the
+// resource's own host.
+func (s *oauthResourceServer) metadataURL() string {
+        u, _ := url.Parse(s.resource)
+        return u.Scheme + "://" + u.Host + "/.well-known/oauth-protected-resource"
+}
+
+// challengeParams returns the WWW-Authenticate parameters that tell a
+// client how to obtain a token.
+func (s *oauthResourceServer) challengeParams() string {
+        params := fmt.Sprintf(`, resource_metadata=%q`, s.metadataURL())
+        if len(s.scopes) > 0 {
+                params += fmt.Sprintf(`, scope=%q`, strings.Join(s.scopes, " "))
+        }
+        return params
+}
+
+// HandleMetadata serves the protected resource metadata.
+func (s *oauthResourceServer) HandleMetadata(w http.ResponseWriter, r *http.Request) {
+        metadata := map[string]interface{}{
+                "resource":                 s.resource,
+                "authorization_servers":    []string{s.issuer},
+                "bearer_methods_supported": []string{"header"},
+        }
+        if len(s.scopes) > 0 {
+                metadata["scopes_supported"] = s.scopes
+        }
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(metadata)
+}
+
+// authenticate validates token and returns who it was issued to, with the
+// introspection response as its claims.
+func (s *oauthResourceServer) authenticate(ctx context.Context, token string) (mcp.Principal, *authFailure) {
+        key := sha256.Sum256([]byte(token))
+        s.mu.Lock()
+        cached, ok := s.cache[key]
+        s.mu.Unlock()
+        if ok && time.Now().Before(cached.expires) {
+                return cached.principal, nil
+        }
+
+        info, claims, err := s.introspect(ctx, token)
+        if err != nil {
+                return mcp.Principal{}, &authFailure{status: http.StatusServiceUnavailable, reason: fmt.Sprintf("introspecting token: %v", err)}
+        }
+        if !info.Active {
+                return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, code: "invalid_token", reason: "token is not active"}
+        }
+        if !slices.Contains(info.Audience, s.resource) {
+                return mcp.Principal{}, &authFailure{status: http.StatusUnauthorized, code: "invalid_token", reason: "token was not issued for " + s.resource}
+        }
+        if failure := checkScopes(strings.Fields(info.Scope), s.scopes); failure != nil {
+                return mcp.Principal{}, failure
+        }
+
+        identity := info.Subject
+        if info.Username != "" {
+                identity = info.Username
+        }
+        if identity == "" {
+                identity = info.ClientID
+        }
+        expires := time.Now().Add(oauthCacheTTL)
+        if info.Expires > 0 && time.Unix(info.Expires, 0).Before(expires) {
+                expires = time.Unix(info.Expires, 0)
+        }
+        s.mu.Lock()
+        for k, t := range s.cache {
+                if time.Now().After(t.expires) {
+                        delete(s.cache, k)
+                }
+        }
+        p := mcp.Principal{Identity: identity, Claims: claims}
+        s.cache[key] = cachedToken{principal: p, expires: expires}
+        s.mu.Unlock()
+        return p, nil
+}
+
+func (s *oauthResourceServer) introspect(ctx context.Context, token string) (*tokenIntrospection, map[string]interface{}, error) {
+        form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+        req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.introspectionURL, strings.NewReader(form.Encode()))
+        if err != nil {
+                return nil, nil, err
+        }
+        req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+        req.Header.Set("Accept", "application/json")
+        if clientID := s.clientID.Value(); clientID != "" {
+                req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(s.clientSecret.Value()))
+        }
+        resp, err := s.client.Do(req)
+        if err != nil {
+                return nil, nil, err
+        }
+        defer resp.Body.Close()
+        if resp.StatusCode != http.StatusOK {
+                return nil, nil, fmt.Errorf("%s", resp.Status)
+        }
+        var raw json.RawMessage
+        if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+                return nil, nil, err
+        }
+        var info tokenIntrospection
+        var claims map[string]interface{}
+        if err := json.Unmarshal(raw, &info); err != nil {
+                return nil, nil, err
+        }
+        if err := json.Unmarshal(raw, &claims); err != nil {
+                return nil, nil, err
+        }
+        return &info, claims, nil
+}
@@ -0,0 +1,38 @@
+package transport
+
+import (
+        "context"
+        "encoding/json"
+        "net/http"
+        "net/http/httptest"
+        "testing"
+)
+
+func TestOAuthAudience(t *testing.T) {
+        const resource = "https://mcp.example.com/ws"
+        // The introspection endpoint answers with the token as its aud.
+        introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                info := map[string]interface{}{"active": true, "sub": "alice"}
+                if aud := r.FormValue("token"); aud != "none" {
+                        info["aud"] = aud
+                }
+                json.NewEncoder(w).Encode(info)
+        }))
+        defer introspection.Close()
+
+        if _, err := NewOAuthResourceServer("https://issuer.example.com", "", "", introspection.URL, "", ""); err == nil {
+                t.Fatal("no resource: got no error")
+        }
+        s, err := NewOAuthResourceServer("https://issuer.example.com", resource, "", introspection.URL, "", "")
+        if err != nil {
+                t.Fatal(err)
+        }
+        if p, failure := s.authenticate(context.Background(), resource); failure != nil || p.Identity != "alice" {
+                t.Errorf("token for %s: got %+v, %+v", resource, p, failure)
+        }
+        for _, token := range []string{"none", "https://other.example.com/ws"} {
+                if _, failure := s.authenticate(context.Background(), token); failure == nil {
+                        t.Errorf("token with audience %s: got no failure", token)
+                }
+        }
+}
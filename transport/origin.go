@@ -0,0 +1,88 @@
+package transport
+
+import (
+        "fmt"
+        "log/slog"
+        "net"
+        "net/http"
+        "net/url"
+        "strings"
+)
+
+// originPolicy decides which browser origins may open a WebSocket. Clients
+// that send no Origin header (anything that isn't a browser) are always
+// allowed; browsers are limited to the allowlist, or to the server's own
+// origin and localhost when the allowlist is empty.
+type originPolicy struct {
+        allowAny bool
+        allowed  map[string]bool
+        // loopbackOnly rejects requests whose Host header isn't a loopback name,
+        // which defeats DNS rebinding when the server listens on localhost.
+        loopbackOnly bool
+}
+
+// NewOriginPolicy parses a comma-separated origin allowlist; "*" allows any
+// origin.
+func NewOriginPolicy(list string, loopbackOnly bool) *originPolicy {
+        p := &originPolicy{allowed: map[string]bool{}, loopbackOnly: loopbackOnly}
+        for _, o := range strings.Split(list, ",") {
+                o = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(o)), "/")
+                switch o {
+                case "":
+                case "*":
+                        p.allowAny = true
+                default:
+                        p.allowed[o] = true
+                }
+        }
+        return p
+}
+
+// check is the upgrader's CheckOrigin; it logs why a request is rejected.
+// A nil policy admits the server's own origin and localhost.
+func (p *originPolicy) check(r *http.Request) bool {
+        if p == nil {
+                p = &originPolicy{}
+        }
+        if p.loopbackOnly && !IsLoopbackHost(r.Host) {
+                slog.Warn(fmt.Sprintf("Rejected connection: host %q is not localhost", r.Host), "remote", r.RemoteAddr)
+                return false
+        }
+        origin := r.Header.Get("Origin")
+        if origin == "" || p.allowAny {
+                return true
+        }
+        if p.allows(origin, r.Host) {
+                return true
+        }
+        slog.Warn(fmt.Sprintf("Rejected connection: origin %q is not allowed (see -allowed-origins)", origin), "remote", r.RemoteAddr)
+        return false
+}
+
+func (p *originPolicy) allows(origin, host string) bool {
+        if p.allowed[strings.ToLower(origin)] {
+                return true
+        }
+        if len(p.allowed) > 0 {
+                return false
+        }
+        u, err := url.Parse(origin)
+        if err != nil || u.Host == "" {
+                return false
+        }
+        return strings.EqualFold(u.Host, host) || IsLoopbackHost(u.Host)
+}
+
+// IsLoopbackHost reports whether host (with or without a port) names the
+// local machine.
+func IsLoopbackHost(host string) bool {
+        if h, _, err := net.SplitHostPort(host); err == nil {
+                host = h
+        }
+        host = strings.Trim(host, "[]")
+        if strings.EqualFold(host, "localhost") {
+                return true
+        }
+        ip := net.ParseIP(host)
+        return ip != nil && ip.IsLoopback()
+}
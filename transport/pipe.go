@@ -0,0 +1,128 @@
+package transport
+
+import (
+        "context"
+        "io"
+        "sync"
+
+        "mcp-server/mcp"
+)
+
+// PipeClient is the client end of an in-memory connection made by Pipe.
+// It sends and receives the same JSON-RPC messages a WebSocket client
+// would, without a network, so tests of handlers and the protocol run
+// fast and deterministically.
+type PipeClient struct {
+        conn     *mcp.Conn
+        messages chan []byte
+        // done is closed when the connection closes, from either end.
+        done      chan struct{}
+        closeOnce sync.Once
+}
+
+// Pipe connects a client to a server with the zero Server's settings,
+// returning the client's end and the server's connection.
+func Pipe() (*PipeClient, *mcp.Conn) {
+        return new(Server).Pipe()
+}
+
+// Pipe connects a client to srv in memory, returning the client's end and
+// the server's connection. The connection is admitted like any other, so
+// srv's handler and hooks apply, but it is unauthenticated and doesn't
+// count towards -max-connections. It panics if the OnConnect hook refuses
+// it.
+func (srv *Server) Pipe() (*PipeClient, *mcp.Conn) {
+        p := &PipeClient{messages: make(chan []byte, outboxSize), done: make(chan struct{})}
+        c := srv.newConn(mcp.NewSession(mcp.Principal{}), "pipe", pipeTransport{p})
+        p.conn = c
+        if err := c.Admit(); err != nil {
+                c.Close()
+                panic("transport: pipe refused: " + err.Error())
+        }
+        mcp.AddClient(c)
+        c.StartWorkers()
+        c.Connected()
+        return p, c
+}
+
+// Send hands the server a message from the client. It returns once the
+// message is handled, or queued if it is a request.
+func (p *PipeClient) Send(message []byte) error {
+        if p.conn.Closed() {
+                return errConnClosed
+        }
+        p.conn.Touch()
+        handleMessage(p.conn, message)
+        return nil
+}
+
+// Receive returns the next message the server sent the client, waiting
+// until there is one. It returns io.EOF once the connection has closed
+// and every message sent before has been received.
+func (p *PipeClient) Receive(ctx context.Context) ([]byte, error) {
+        select {
+        case data := <-p.messages:
+                return data, nil
+        default:
+        }
+        select {
+        case data := <-p.messages:
+                return data, nil
+        case <-p.done:
+                select {
+                case data := <-p.messages:
+                        return data, nil
+                default:
+                        return nil, io.EOF
+                }
+        case <-ctx.Done():
+                return nil, ctx.Err()
+        }
+}
+
+// Close disconnects the client, as if it had closed its socket.
+func (p *PipeClient) Close() error {
+        p.conn.Disconnected(mcp.CloseByClient)
+        p.close()
+        return nil
+}
+
+// close ends the connection; only the first call has any effect.
+func (p *PipeClient) close() {
+        p.closeOnce.Do(func() {
+                mcp.RemoveClient(p.conn)
+                p.conn.Close()
+                close(p.done)
+        })
+}
+
+// pipeTransport carries the server's messages to a PipeClient.
+type pipeTransport struct{ p *PipeClient }
+
+// Deliver queues data for Receive.
+func (t pipeTransport) Deliver(v interface{}, data []byte, wait bool) error {
+        p := t.p
+        if !wait {
+                select {
+                case p.messages <- data:
+                        return nil
+                case <-p.conn.Context().Done():
+                        return errConnClosed
+                default:
+                        return mcp.ErrOutboxFull
+                }
+        }
+        select {
+        case p.messages <- data:
+                return nil
+        case <-p.conn.Context().Done():
+                return errConnClosed
+        }
+}
+
+// Shutdown closes the connection; the client's Receive returns io.EOF
+// once it has read what was sent before.
+func (t pipeTransport) Shutdown() { t.p.close() }
+
+// Shared is false: a pipe lives and dies with its PipeClient.
+func (t pipeTransport) Shared() bool { return false }
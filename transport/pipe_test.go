@@ -0,0 +1,79 @@
+package transport
+
+import (
+        "context"
+        "encoding/json"
+        "errors"
+        "io"
+        "testing"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+func receive(t *testing.T, p *PipeClient) map[string]interface{} {
+        t.Helper()
+        ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        data, err := p.Receive(ctx)
+        if err != nil {
+                t.Fatal(err)
+        }
+        var msg map[string]interface{}
+        if err := json.Unmarshal(data, &msg); err != nil {
+                t.Fatal(err)
+        }
+        return msg
+}
+
+func TestPipe(t *testing.T) {
+        mux := mcp.NewMux()
+        mux.RegisterFunc("echo", func(_ context.Context, req mcp.Request) mcp.Response {
+                return mcp.Response{ID: req.ID, Result: req.Params}
+        })
+        srv := &Server{Handler: mux}
+        client, conn := srv.Pipe()
+        defer client.Close()
+        if conn.Closed() {
+                t.Fatal("connection closed before use")
+        }
+
+        if err := client.Send([]byte(`{"jsonrpc":"2.0","id":"1","method":"echo","params":{"n":1}}`)); err != nil {
+                t.Fatal(err)
+        }
+        msg := receive(t, client)
+        if msg["id"] != "1" || msg["result"].(map[string]interface{})["n"] != 1.0 {
+                t.Errorf("echo: got %v", msg)
+        }
+
+        client.Send([]byte(`{"jsonrpc":"2.0","id":"2","method":"missing"}`))
+        msg = receive(t, client)
+        if code := msg["error"].(map[string]interface{})["code"]; code != -32601.0 {
+                t.Errorf("unknown method: got code %v, want -32601", code)
+        }
+
+        client.Send([]byte(`{not json`))
+        msg = receive(t, client)
+        if code := msg["error"].(map[string]interface{})["code"]; code != -32700.0 {
+                t.Errorf("bad JSON: got code %v, want -32700", code)
+        }
+}
+
+func TestPipeClose(t *testing.T) {
+        var cause string
+        srv := &Server{Hooks: mcp.Hooks{OnDisconnect: func(_ *mcp.Conn, c string) { cause = c }}}
+        client, conn := srv.Pipe()
+        client.Close()
+        if !conn.Closed() {
+                t.Error("server connection still open after client closed")
+        }
+        if cause != mcp.CloseByClient {
+                t.Errorf("disconnect cause: got %q, want %q", cause, mcp.CloseByClient)
+        }
+        if _, err := client.Receive(context.Background()); !errors.Is(err, io.EOF) {
+                t.Errorf("Receive after close: got %v, want io.EOF", err)
+        }
+        if err := client.Send([]byte(`{}`)); err == nil {
+                t.Error("Send after close succeeded")
+        }
+}
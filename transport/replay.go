@@ -0,0 +1,187 @@
+package transport
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "sync"
+        "time"
+
+        "mcp-server/mcp"
+)
+
+// replayQuiet is how long Replay waits after the last frame for messages
+// the server sends on its own, such as notifications.
+const replayQuiet = 200 * time.Millisecond
+
+// replayMessage is the part of a message Replay looks at.
+type replayMessage struct {
+        ID     *string `json:"id"`
+        Method string  `json:"method"`
+}
+
+func parseReplayMessage(data []byte) replayMessage {
+        var msg replayMessage
+        json.Unmarshal(data, &msg)
+        return msg
+}
+
+// isResponse reports whether msg answers a request.
+func (msg replayMessage) isResponse() bool { return msg.ID != nil && msg.Method == "" }
+
+// isRequest reports whether msg is a request.
+func (msg replayMessage) isRequest() bool { return msg.ID != nil && msg.Method != "" }
+
+// Replay plays the client's side of a recorded session back to srv over a
+// pipe and returns the frames the server sends in reply. The client's
+// frames are sent in order, each once the server has answered the
+// requests it answered before that frame in the recording. Replies to the server's own requests are matched to them
+// by position, as their IDs differ from run to run. If ctx ends first, it
+// returns the frames so far with ctx's error.
+func (srv *Server) Replay(ctx context.Context, recorded []mcp.Frame) ([]mcp.Frame, error) {
+        p, _ := srv.Pipe()
+        r := &replay{ctx: ctx, changed: make(chan struct{}), answered: map[string]bool{}, done: make(chan struct{})}
+        go r.receive(p)
+        err := r.play(p, recorded)
+        p.Close()
+        <-r.done
+        return r.frames, err
+}
+
+// replay collects what the server sends during Replay.
+type replay struct {
+        ctx  context.Context
+        done chan struct{}
+
+        mu sync.Mutex
+        // changed is closed and replaced whenever a frame arrives.
+        changed  chan struct{}
+        frames   []mcp.Frame
+        answered map[string]bool
+        // requests are the IDs of the server's requests, in the order it
+        // sent them.
+        requests []string
+}
+
+func (r *replay) receive(p *PipeClient) {
+        defer close(r.done)
+        for {
+                data, err := p.Receive(context.Background())
+                if err != nil {
+                        return
+                }
+                msg := parseReplayMessage(data)
+                r.mu.Lock()
+                r.frames = append(r.frames, mcp.NewFrame(time.Now().UTC(), "out", data))
+                switch {
+                case msg.isResponse():
+                        r.answered[*msg.ID] = true
+                case msg.isRequest():
+                        r.requests = append(r.requests, *msg.ID)
+                }
+                close(r.changed)
+                r.changed = make(chan struct{})
+                r.mu.Unlock()
+        }
+}
+
+// waitFor waits until ok, called with r.mu held, holds.
+func (r *replay) waitFor(what string, ok func() bool) error {
+        for {
+                r.mu.Lock()
+                met, changed := ok(), r.changed
+                r.mu.Unlock()
+                if met {
+                        return nil
+                }
+                select {
+                case <-changed:
+                case <-r.done:
+                        return fmt.Errorf("connection closed waiting for %s", what)
+                case <-r.ctx.Done():
+                        return fmt.Errorf("waiting for %s: %w", what, r.ctx.Err())
+                }
+        }
+}
+
+func (r *replay) play(p *PipeClient, recorded []mcp.Frame) error {
+        // recordedRequests are the IDs the server's requests had in the
+        // recording, in order; answered are the client's requests answered
+        // in the recording since its last frame.
+        var recordedRequests, answered []string
+        for _, f := range recorded {
+                data := f.Data()
+                msg := parseReplayMessage(data)
+                if f.Direction == "out" {
+                        switch {
+                        case msg.isRequest():
+                                recordedRequests = append(recordedRequests, *msg.ID)
+                        case msg.isResponse():
+                                answered = append(answered, *msg.ID)
+                        }
+                        continue
+                }
+                if err := r.waitForResponses(answered); err != nil {
+                        return err
+                }
+                answered = nil
+                if msg.isResponse() {
+                        for n, id := range recordedRequests {
+                                if id != *msg.ID {
+                                        continue
+                                }
+                                var err error
+                                if data, err = r.renumber(data, n); err != nil {
+                                        return err
+                                }
+                                break
+                        }
+                }
+                if err := p.Send(data); err != nil {
+                        return err
+                }
+        }
+        if err := r.waitForResponses(answered); err != nil {
+                return err
+        }
+
+        for {
+                r.mu.Lock()
+                changed := r.changed
+                r.mu.Unlock()
+                select {
+                case <-changed:
+                case <-time.After(replayQuiet):
+                        return nil
+                case <-r.ctx.Done():
+                        return r.ctx.Err()
+                }
+        }
+}
+
+// waitForResponses waits until the server has answered the client's
+// requests ids, so that messages are sent in the order they were recorded.
+func (r *replay) waitForResponses(ids []string) error {
+        for _, id := range ids {
+                if err := r.waitFor("the response to request "+id, func() bool { return r.answered[id] }); err != nil {
+                        return err
+                }
+        }
+        return nil
+}
+
+// renumber gives reply, the client's reply to the server's nth request,
+// the ID that request has in this run, waiting for the server to send it.
+func (r *replay) renumber(reply []byte, n int) ([]byte, error) {
+        if err := r.waitFor(fmt.Sprintf("server request %d", n+1), func() bool { return len(r.requests) > n }); err != nil {
+                return nil, err
+        }
+        var fields map[string]json.RawMessage
+        if err := json.Unmarshal(reply, &fields); err != nil {
+                return reply, nil
+        }
+        r.mu.Lock()
+        fields["id"], _ = json.Marshal(r.requests[n])
+        r.mu.Unlock()
+        return json.Marshal(fields)
+}
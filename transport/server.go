@@ -0,0 +1,88 @@
+package transport
+
+import (
+        "context"
+        "log/slog"
+        "net/http"
+
+        "github.com/gorilla/websocket"
+        "mcp-server/mcp"
+)
+
+// Server serves MCP sessions over WebSocket and Streamable HTTP. Several
+// may serve in one process, each with its own settings; the zero value
+// serves with no timeouts.
+type Server struct {
+        // Upgrader upgrades WebSocket requests. Without a CheckOrigin it
+        // admits the origins Origins does, and without a HandshakeTimeout it
+        // uses Timeouts.Handshake.
+        Upgrader websocket.Upgrader
+        Timeouts Timeouts
+        // Auth authenticates the server's clients.
+        Auth Auth
+        // Origins decides which browser origins may connect, set by
+        // -allowed-origins; nil admits the server's own origin and
+        // localhost.
+        Origins *originPolicy
+        // Logger records the server's connections; nil means slog.Default().
+        Logger *slog.Logger
+        // BaseContext returns the context every connection's context derives
+        // from, carrying values its handlers need, such as the ticket store.
+        // Nil means context.Background().
+        BaseContext func() context.Context
+        // Handler answers the clients' requests; nil means mcp.DefaultMux.
+        Handler mcp.Handler
+        // Hooks run at points in each connection's life.
+        Hooks mcp.Hooks
+        // MaxSessionsPerClient caps the Streamable HTTP sessions one client
+        // may hold at once. A client is its identity if it authenticated
+        // and its IP address if not. Sessions outlive their requests by
+        // SessionRetention, so without a cap a client could pile them up
+        // with initialize requests. Zero means no limit.
+        MaxSessionsPerClient int
+
+        sessions httpSessionTable
+}
+
+func (srv *Server) logger() *slog.Logger {
+        if srv.Logger != nil {
+                return srv.Logger
+        }
+        return slog.Default()
+}
+
+func (srv *Server) baseContext() context.Context {
+        if srv.BaseContext != nil {
+                return srv.BaseContext()
+        }
+        return context.Background()
+}
+
+// group returns the group the server's connections are in, whose limits
+// they count against.
+func (srv *Server) group() *mcp.Group {
+        return mcp.GroupFrom(srv.baseContext())
+}
+
+// newConn returns the connection for session s of a client at
+// remoteAddr, whose messages t carries.
+func (srv *Server) newConn(s *mcp.Session, remoteAddr string, t mcp.Transport) *mcp.Conn {
+        c := mcp.NewConn(srv.baseContext(), srv.logger(), s, remoteAddr, t)
+        if srv.Handler != nil {
+                c.SetHandler(srv.Handler)
+        }
+        c.SetHooks(srv.Hooks)
+        return c
+}
+
+// upgrade upgrades r to a WebSocket connection.
+func (srv *Server) upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+        upgrader := srv.Upgrader
+        if upgrader.CheckOrigin == nil {
+                upgrader.CheckOrigin = srv.Origins.check
+        }
+        if upgrader.HandshakeTimeout == 0 {
+                upgrader.HandshakeTimeout = srv.Timeouts.Handshake
+        }
+        return upgrader.Upgrade(w, r, nil)
+}
@@ -0,0 +1,378 @@
+package transport
+
+import (
+        "context"
+        "encoding/json"
+        "fmt"
+        "log/slog"
+        "strconv"
+        "strings"
+        "time"
+
+        "github.com/redis/go-redis/v9"
+        "mcp-server/mcp"
+)
+
+// SharedSessions keeps Streamable HTTP sessions in Redis when
+// -session-store is set, so that any replica behind a load balancer can
+// serve a session's requests and event stream. It is nil when sessions
+// live only in this process. Every server in the process shares it.
+var SharedSessions *sessionStore
+
+// clusterChannel is the Redis channel replicas tell each other about
+// events, ended sessions, stray responses, resource updates, and
+// broadcasts on.
+const clusterChannel = "mcp:cluster"
+
+// broadcastDedupWindow is how long a session remembers the broadcasts it
+// was sent, so that each replica serving it doesn't send them again.
+const broadcastDedupWindow = time.Minute
+
+// sessionStore keeps, for each shared session, these keys:
+//
+//	mcp:session:<id>                the sessionRecord, as JSON
+//	mcp:session:<id>:seq            the ID of the last event
+//	mcp:session:<id>:events         the retained events, as "<id> <message>"
+//	mcp:session:<id>:delivered      the last event written to an event stream
+//	mcp:session:<id>:subscriptions  the resources it subscribed to
+//
+// and mcp:subscribers:<uri> holds the sessions subscribed to each
+// resource. A session's keys expire SessionRetention after its client was
+// last active on any replica.
+//
+// Each replica that serves a session keeps a local copy of it, rebuilt
+// from the record. A copy's messages go to the shared event list, and the
+// replica with the event stream open is told about them on clusterChannel.
+type sessionStore struct {
+        client *redis.Client
+        // replica identifies this process in cluster messages.
+        replica string
+}
+
+// sessionRecord is what a replica needs to serve a session it didn't
+// start. Values tool handlers keep in the session are not shared.
+type sessionRecord struct {
+        Identity        string                 `json:"identity"`
+        ProtocolVersion string                 `json:"protocolVersion"`
+        ClientInfo      mcp.ClientInfo         `json:"clientInfo"`
+        Capabilities    map[string]interface{} `json:"capabilities,omitempty"`
+        CreatedAt       time.Time              `json:"createdAt"`
+}
+
+// clusterMessage is sent on clusterChannel. Kind says which of the other
+// fields are set.
+type clusterMessage struct {
+        Replica string `json:"replica"`
+        Kind    string `json:"kind"`
+        Session string `json:"session,omitempty"`
+        // URI is the resource that changed, for "resource".
+        URI string `json:"uri,omitempty"`
+        // Response is a client's reply to a server request, for "response".
+        Response *mcp.ClientResponse `json:"response,omitempty"`
+        // ID and Broadcast are the broadcast to deliver, for "broadcast".
+        ID        string                `json:"id,omitempty"`
+        Broadcast *mcp.BroadcastRequest `json:"broadcast,omitempty"`
+}
+
+// Kinds of clusterMessage.
+const (
+        clusterEvent     = "event"
+        clusterEnd       = "end"
+        clusterResponse  = "response"
+        clusterResource  = "resource"
+        clusterBroadcast = "broadcast"
+)
+
+// appendEventScript adds an event to a session's list, numbering it and
+// keeping only the most recent, and returns its ID.
+var appendEventScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+redis.call('RPUSH', KEYS[2], id .. ' ' .. ARGV[1])
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return id
+`)
+
+func sessionKey(id string) string { return "mcp:session:" + id }
+
+// sessionKeys returns every key of the session other than subscriber sets.
+func sessionKeys(id string) []string {
+        base := sessionKey(id)
+        return []string{base, base + ":seq", base + ":events", base + ":delivered", base + ":subscriptions"}
+}
+
+func subscribersKey(uri string) string { return "mcp:subscribers:" + uri }
+
+func NewSessionStore(dsn string) (*sessionStore, error) {
+        client, err := mcp.NewRedisClient(dsn)
+        if err != nil {
+                return nil, err
+        }
+        return &sessionStore{client: client, replica: mcp.NewConnID()}, nil
+}
+
+func (s *sessionStore) queryContext() (context.Context, context.CancelFunc) {
+        return context.WithTimeout(context.Background(), mcp.RedisQueryTimeout)
+}
+
+// save records what c negotiated at initialize.
+func (s *sessionStore) save(c *mcp.Conn) error {
+        version, info := c.Session.Negotiated()
+        data, err := json.Marshal(sessionRecord{
+                Identity:        c.Session.Identity,
+                ProtocolVersion: version,
+                ClientInfo:      info,
+                Capabilities:    c.ClientCapabilities(),
+                CreatedAt:       c.Session.CreatedAt,
+        })
+        if err != nil {
+                return err
+        }
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        return s.client.Set(ctx, sessionKey(c.Session.ID), data, SessionRetention).Err()
+}
+
+// load returns the record of session id, or nil if there is none.
+func (s *sessionStore) load(id string) (*sessionRecord, error) {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        data, err := s.client.Get(ctx, sessionKey(id)).Bytes()
+        if err == redis.Nil {
+                return nil, nil
+        }
+        if err != nil {
+                return nil, err
+        }
+        var record sessionRecord
+        if err := json.Unmarshal(data, &record); err != nil {
+                return nil, fmt.Errorf("decoding session %s: %w", id, err)
+        }
+        return &record, nil
+}
+
+// refresh keeps session id from expiring for another SessionRetention.
+func (s *sessionStore) refresh(id string) error {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        _, err := s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+                for _, key := range sessionKeys(id) {
+                        p.PExpire(ctx, key, SessionRetention)
+                }
+                return nil
+        })
+        return err
+}
+
+// end deletes session id and has the other replicas end their copies.
+func (s *sessionStore) end(id string) error {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        uris, err := s.client.SMembers(ctx, sessionKey(id)+":subscriptions").Result()
+        if err != nil {
+                return err
+        }
+        _, err = s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+                for _, uri := range uris {
+                        p.SRem(ctx, subscribersKey(uri), id)
+                }
+                p.Del(ctx, sessionKeys(id)...)
+                return nil
+        })
+        if err != nil {
+                return err
+        }
+        s.publish(clusterMessage{Kind: clusterEnd, Session: id})
+        return nil
+}
+
+// AppendEvent adds data to session id's event stream. A non-empty
+// broadcast ID is added only once, however many replicas deliver it.
+func (s *sessionStore) AppendEvent(id, broadcast string, data []byte) error {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        if broadcast != "" {
+                first, err := s.client.SetNX(ctx, sessionKey(id)+":broadcast:"+broadcast, 1, broadcastDedupWindow).Result()
+                if err != nil || !first {
+                        return err
+                }
+        }
+        base := sessionKey(id)
+        keys := []string{base + ":seq", base + ":events"}
+        if err := appendEventScript.Run(ctx, s.client, keys, data, maxRetainedEvents, SessionRetention.Milliseconds()).Err(); err != nil {
+                return err
+        }
+        s.wake(id)
+        s.publish(clusterMessage{Kind: clusterEvent, Session: id})
+        return nil
+}
+
+// events returns session id's retained events after the one with ID
+// after. missed reports whether some were dropped before they could be
+// sent.
+func (s *sessionStore) events(id string, after int64) (events []streamEvent, missed bool, err error) {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        base := sessionKey(id)
+        var seq *redis.StringCmd
+        var list *redis.StringSliceCmd
+        _, err = s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+                seq = p.Get(ctx, base+":seq")
+                list = p.LRange(ctx, base+":events", 0, -1)
+                return nil
+        })
+        if err != nil && err != redis.Nil {
+                return nil, false, err
+        }
+        lastID, _ := seq.Int64()
+        entries := list.Val()
+        first := lastID - int64(len(entries)) + 1
+        missed = after < lastID && after+1 < first
+        for _, entry := range entries {
+                idText, data, _ := strings.Cut(entry, " ")
+                eventID, err := strconv.ParseInt(idText, 10, 64)
+                if err != nil || eventID <= after {
+                        continue
+                }
+                events = append(events, streamEvent{id: eventID, data: []byte(data)})
+        }
+        return events, missed, nil
+}
+
+// delivered returns the last event of session id written to an event
+// stream on any replica.
+func (s *sessionStore) delivered(id string) int64 {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        n, _ := s.client.Get(ctx, sessionKey(id)+":delivered").Int64()
+        return n
+}
+
+func (s *sessionStore) setDelivered(id string, eventID int64) error {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        return s.client.Set(ctx, sessionKey(id)+":delivered", eventID, SessionRetention).Err()
+}
+
+// Subscribe adds or removes session id's subscription to uri.
+func (s *sessionStore) Subscribe(id, uri string, subscribe bool) error {
+        ctx, cancel := s.queryContext()
+        defer cancel()
+        _, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+                if subscribe {
+                        p.SAdd(ctx, subscribersKey(uri), id)
+                        p.SAdd(ctx, sessionKey(id)+":subscriptions", uri)
+                        p.PExpire(ctx, sessionKey(id)+":subscriptions", SessionRetention)
+                } else {
+                        p.SRem(ctx, subscribersKey(uri), id)
+                        p.SRem(ctx, sessionKey(id)+":subscriptions", uri)
+                }
+                return nil
+        })
+        return err
+}
+
+// ResourceUpdated tells the shared sessions subscribed to uri that it
+// changed, forgetting those that have expired, and has the other replicas
+// tell their own subscribers.
+func (s *sessionStore) ResourceUpdated(uri string) error {
+        s.publish(clusterMessage{Kind: clusterResource, URI: uri})
+        ctx, cancel := s.queryContext()
+        ids, err := s.client.SMembers(ctx, subscribersKey(uri)).Result()
+        cancel()
+        if err != nil {
+                return err
+        }
+        data, err := json.Marshal(mcp.Notification{
+                Method: "notifications/resources/updated",
+                Params: map[string]interface{}{"uri": uri},
+        })
+        if err != nil {
+                return err
+        }
+        for _, id := range ids {
+                ctx, cancel := s.queryContext()
+                live, err := s.client.Exists(ctx, sessionKey(id)).Result()
+                if err == nil && live == 0 {
+                        err = s.client.SRem(ctx, subscribersKey(uri), id).Err()
+                }
+                cancel()
+                if err != nil {
+                        return err
+                }
+                if live > 0 {
+                        if err := s.AppendEvent(id, "", data); err != nil {
+                                return err
+                        }
+                }
+        }
+        return nil
+}
+
+// RelayResponse passes a client's reply to a server request on to the
+// replica that sent the request.
+func (s *sessionStore) RelayResponse(id string, resp mcp.ClientResponse) {
+        s.publish(clusterMessage{Kind: clusterResponse, Session: id, Response: &resp})
+}
+
+// PublishBroadcast has the other replicas deliver a broadcast.
+func (s *sessionStore) PublishBroadcast(id string, req mcp.BroadcastRequest) {
+        s.publish(clusterMessage{Kind: clusterBroadcast, ID: id, Broadcast: &req})
+}
+
+func (s *sessionStore) publish(msg clusterMessage) {
+        msg.Replica = s.replica
+        data, err := json.Marshal(msg)
+        if err == nil {
+                ctx, cancel := s.queryContext()
+                err = s.client.Publish(ctx, clusterChannel, data).Err()
+                cancel()
+        }
+        if err != nil {
+                slog.Warn("Publishing to other replicas failed", "kind", msg.Kind, "error", err)
+        }
+}
+
+// Listen acts on the other replicas' cluster messages until the
+// connection to Redis is closed.
+func (s *sessionStore) Listen() {
+        sub := s.client.Subscribe(context.Background(), clusterChannel)
+        defer sub.Close()
+        for m := range sub.Channel() {
+                var msg clusterMessage
+                if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+                        slog.Warn("Ignoring malformed cluster message", "error", err)
+                        continue
+                }
+                if msg.Replica == s.replica {
+                        continue
+                }
+                switch msg.Kind {
+                case clusterEvent:
+                        s.wake(msg.Session)
+                case clusterEnd:
+                        if stream := localHTTPSession(msg.Session); stream != nil {
+                                stream.end(mcp.CloseByClient)
+                        }
+                case clusterResponse:
+                        if stream := localHTTPSession(msg.Session); stream != nil && msg.Response != nil {
+                                stream.conn.DeliverResponse(*msg.Response)
+                        }
+                case clusterResource:
+                        mcp.LocalResourceUpdated(msg.URI)
+                case clusterBroadcast:
+                        if msg.Broadcast != nil {
+                                mcp.Broadcast(msg.ID, *msg.Broadcast)
+                        }
+                }
+        }
+}
+
+// wake tells this replica's copy of session id, if it has one, that there
+// are new events.
+func (s *sessionStore) wake(id string) {
+        if stream := localHTTPSession(id); stream != nil {
+                stream.wakeUp()
+        }
+}
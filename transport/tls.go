@@ -0,0 +1,131 @@
+package transport
+
+import (
+        "crypto/tls"
+        "crypto/x509"
+        "fmt"
+        "log/slog"
+        "net/http"
+        "os"
+        "sync"
+        "time"
+
+        "golang.org/x/crypto/acme"
+        "golang.org/x/crypto/acme/autocert"
+)
+
+// certReloadInterval is how often the -tls-cert and -tls-key files are
+// checked for changes.
+const certReloadInterval = 10 * time.Second
+
+// certReloader serves the certificate in a pair of PEM files and reloads it
+// when they change, so a certificate renewed by e.g. certbot is picked up
+// without a restart or dropping connections.
+type certReloader struct {
+        certPath, keyPath string
+
+        mu   sync.RWMutex
+        cert *tls.Certificate
+        // signature identifies the versions of the files cert was loaded from.
+        signature string
+}
+
+func NewCertReloader(certPath, keyPath string) (*certReloader, error) {
+        r := &certReloader{certPath: certPath, keyPath: keyPath}
+        if err := r.load(); err != nil {
+                return nil, err
+        }
+        return r, nil
+}
+
+func (r *certReloader) load() error {
+        signature := r.fileSignature()
+        cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+        if err != nil {
+                return err
+        }
+        if cert.Leaf == nil {
+                if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+                        return err
+                }
+        }
+        r.mu.Lock()
+        r.cert, r.signature = &cert, signature
+        r.mu.Unlock()
+        slog.Info("Loaded TLS certificate", "subject", cert.Leaf.Subject.String(), "expires", cert.Leaf.NotAfter.UTC())
+        return nil
+}
+
+// fileSignature changes whenever either file is replaced or modified.
+// Stat follows symlinks, so it sees certbot's renewals too.
+func (r *certReloader) fileSignature() string {
+        signature := ""
+        for _, path := range []string{r.certPath, r.keyPath} {
+                if info, err := os.Stat(path); err == nil {
+                        signature += fmt.Sprintf("%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+                }
+        }
+        return signature
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        return r.cert, nil
+}
+
+// Watch reloads the certificate when its files change. If the new files
+// don't load, say because the key has been replaced but the certificate
+// not yet, the old certificate stays in use and loading is retried.
+func (r *certReloader) Watch() {
+        for range time.Tick(certReloadInterval) {
+                r.mu.RLock()
+                unchanged := r.fileSignature() == r.signature
+                r.mu.RUnlock()
+                if unchanged {
+                        continue
+                }
+                if err := r.load(); err != nil {
+                        slog.Warn("Reloading TLS certificate failed; keeping the current one", "error", err)
+                }
+        }
+}
+
+// NewACMEManager obtains and renews certificates for domains from Let's
+// Encrypt, caching them in cacheDir.
+func NewACMEManager(domains []string, cacheDir, email string) *autocert.Manager {
+        return &autocert.Manager{
+                Prompt:     autocert.AcceptTOS,
+                HostPolicy: autocert.HostWhitelist(domains...),
+                Cache:      autocert.DirCache(cacheDir),
+                Email:      email,
+        }
+}
+
+// ServerTLSConfig returns the TLS settings for the server: certificates
+// from ACME if manager is set and from certs otherwise, and client
+// certificates required if base (from -tls-client-ca) says so.
+func ServerTLSConfig(base *tls.Config, certs *certReloader, manager *autocert.Manager) *tls.Config {
+        config := base
+        if config == nil {
+                config = &tls.Config{MinVersion: tls.VersionTLS12}
+        }
+        if manager != nil {
+                config.GetCertificate = manager.GetCertificate
+                // Answer TLS-ALPN-01 challenges on the server's own port.
+                config.NextProtos = append(config.NextProtos, "h2", "http/1.1", acme.ALPNProto)
+        } else {
+                config.GetCertificate = certs.getCertificate
+        }
+        return config
+}
+
+// ServeACMEChallenges answers HTTP-01 challenges on addr, which must be
+// reachable as port 80 of each domain, and redirects other requests to
+// HTTPS.
+func ServeACMEChallenges(manager *autocert.Manager, addr string) {
+        go func() {
+                err := http.ListenAndServe(addr, manager.HTTPHandler(nil))
+                slog.Error("ACME challenge server stopped", "error", err)
+        }()
+}
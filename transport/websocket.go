@@ -0,0 +1,231 @@
+// Package transport serves MCP sessions over WebSocket and Streamable HTTP,
+// authenticating clients with API keys, OAuth, JWTs, or client
+// certificates, and can share Streamable HTTP sessions between replicas
+// through Redis.
+package transport
+
+import (
+        "bytes"
+        "encoding/json"
+        "errors"
+        "fmt"
+        "net"
+        "net/http"
+        "time"
+
+        "github.com/gorilla/websocket"
+        "mcp-server/mcp"
+)
+
+// Endpoint paths, set by -ws-path and -http-path, of servers made by
+// package server without other paths.
+var (
+        WSPath   = "/ws"
+        HTTPPath = "/mcp"
+)
+
+// outboxSize is how many outgoing messages a connection buffers before
+// senders wait for the write pump.
+const outboxSize = 64
+
+// outboxFlushTimeout bounds writing out what is left in the outbox once a
+// connection closes.
+const outboxFlushTimeout = time.Second
+
+var errConnClosed = errors.New("connection closed")
+
+// wsTransport carries a WebSocket client's messages. They are queued in
+// the outbox and written by the write pump.
+type wsTransport struct {
+        srv    *Server
+        ws     *websocket.Conn
+        conn   *mcp.Conn
+        outbox chan []byte
+        // pumped is closed once the write pump has stopped.
+        pumped chan struct{}
+}
+
+// HandleWebSocket serves the WebSocket transport: each connection is one
+// session, with messages in both directions as text frames.
+func (srv *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+        principal, failure := srv.Auth.authenticate(r)
+        if failure != nil {
+                srv.rejectUnauthenticated(w, r, failure)
+                return
+        }
+        if !srv.group().AcquireConnectionSlot() {
+                srv.group().RejectBusy(w, r)
+                return
+        }
+        defer srv.group().ReleaseConnectionSlot()
+
+        conn, err := srv.upgrade(w, r)
+        if err != nil {
+                mcp.ConnectionsRejected.Add(1, "upgrade_failed")
+                srv.logger().Warn("WebSocket upgrade error", "remote", r.RemoteAddr, "error", err)
+                return
+        }
+        defer conn.Close()
+        conn.SetReadLimit(mcp.MaxMessageSize)
+
+        t := &wsTransport{srv: srv, ws: conn, outbox: make(chan []byte, outboxSize), pumped: make(chan struct{})}
+        client := srv.newConn(mcp.NewSession(principal), conn.RemoteAddr().String(), t)
+        t.conn = client
+        client.Authorize(principal)
+        if err := client.Admit(); err != nil {
+                conn.WriteControl(websocket.CloseMessage,
+                        websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()),
+                        time.Now().Add(controlWriteTimeout))
+                client.Close()
+                return
+        }
+        mcp.AddClient(client)
+        defer mcp.RemoveClient(client)
+        go t.writePump()
+        // The socket stays open until the write pump has flushed the
+        // outbox.
+        defer func() { <-t.pumped }()
+        defer client.Close()
+        client.StartWorkers()
+        t.startHeartbeat()
+        client.Connected()
+
+        for {
+                var frame *bytes.Buffer
+                _, r, err := conn.NextReader()
+                if err == nil {
+                        frame, err = readFrame(r)
+                }
+                if errors.Is(err, websocket.ErrReadLimit) {
+                        // The connection has already been closed with
+                        // status 1009 (message too big).
+                        client.Logf(client.Context(), mcp.LevelWarning, "Closing connection: message exceeds the %d byte limit", mcp.MaxMessageSize)
+                }
+                if err != nil {
+                        cause := readCloseCause(err)
+                        // A connection the server closed itself fails
+                        // with a read error that isn't worth logging.
+                        if cause == mcp.CloseReadError && !client.Closed() {
+                                client.Logf(client.Context(), mcp.LevelInfo, "Read error: %v", err)
+                        }
+                        client.Disconnected(cause)
+                        break
+                }
+                t.touch()
+                handleMessage(client, frame.Bytes())
+                releaseFrame(frame)
+        }
+}
+
+// handleMessage acts on a message a client sent over a connection that
+// carries one message at a time, answering it with an error if it isn't
+// valid JSON-RPC. Nothing keeps message once it returns, so its buffer may
+// be reused.
+func handleMessage(c *mcp.Conn, message []byte) {
+        c.LogPayload("in", message)
+
+        if mcp.JSONDepthExceeds(message, mcp.MaxJSONDepth) {
+                c.Logf(c.Context(), mcp.LevelWarning, "Rejecting message nested deeper than %d levels", mcp.MaxJSONDepth)
+                mcp.SendError(c, "", -32600, fmt.Sprintf("Invalid Request: JSON is nested deeper than %d levels", mcp.MaxJSONDepth))
+                return
+        }
+
+        var msg mcp.IncomingMessage
+        if err := json.Unmarshal(message, &msg); err != nil {
+                c.Logf(c.Context(), mcp.LevelWarning, "JSON unmarshal error: %v", err)
+                mcp.SendError(c, "", -32700, "Parse error")
+                return
+        }
+        c.Handle(msg, len(message))
+}
+
+// Deliver queues data for the write pump.
+func (t *wsTransport) Deliver(v interface{}, data []byte, wait bool) error {
+        if !wait {
+                select {
+                case t.outbox <- data:
+                        return nil
+                case <-t.conn.Context().Done():
+                        return errConnClosed
+                default:
+                        return mcp.ErrOutboxFull
+                }
+        }
+        select {
+        case t.outbox <- data:
+                return nil
+        case <-t.conn.Context().Done():
+                return errConnClosed
+        }
+}
+
+// Shutdown sends a going-away close frame; the client closing the socket
+// in reply ends the read loop.
+func (t *wsTransport) Shutdown() {
+        t.ws.WriteControl(websocket.CloseMessage,
+                websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+                time.Now().Add(time.Second))
+}
+
+// Shared is false: a WebSocket session lives and dies with its socket.
+func (t *wsTransport) Shared() bool { return false }
+
+// writePump writes queued messages to the socket in the order they were
+// sent. It is the only writer of data frames, as gorilla/websocket allows
+// one concurrent writer per connection.
+func (t *wsTransport) writePump() {
+        defer close(t.pumped)
+        c := t.conn
+        for {
+                select {
+                case data := <-t.outbox:
+                        if timeout := t.srv.Timeouts.Write; timeout > 0 {
+                                t.ws.SetWriteDeadline(time.Now().Add(timeout))
+                        }
+                        if err := t.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+                                c.Logger().Error("Write error", "error", err)
+                                c.Disconnected(mcp.CloseWriteError)
+                                // Closing the socket ends the read loop.
+                                t.ws.Close()
+                                return
+                        }
+                case <-c.Context().Done():
+                        t.flush()
+                        return
+                }
+        }
+}
+
+// flush writes what is left in the outbox once the connection is closing,
+// so responses already sent reach a client that is still reading. It
+// stops at the first write that fails.
+func (t *wsTransport) flush() {
+        t.ws.SetWriteDeadline(time.Now().Add(outboxFlushTimeout))
+        for {
+                select {
+                case data := <-t.outbox:
+                        if t.ws.WriteMessage(websocket.TextMessage, data) != nil {
+                                return
+                        }
+                default:
+                        return
+                }
+        }
+}
+
+// readCloseCause classifies the error that ended a connection's read loop.
+func readCloseCause(err error) string {
+        var closeErr *websocket.CloseError
+        var netErr net.Error
+        switch {
+        case errors.Is(err, websocket.ErrReadLimit):
+                return mcp.CloseMessageTooBig
+        case errors.As(err, &closeErr) && (closeErr.Code == websocket.CloseNormalClosure || closeErr.Code == websocket.CloseGoingAway):
+                return mcp.CloseByClient
+        case errors.As(err, &netErr) && netErr.Timeout():
+                // The read deadline, pushed back by every message and pong,
+                // expired.
+                return mcp.CloseDeadPeer
+        }
+        return mcp.CloseReadError
+}